@@ -0,0 +1,361 @@
+// Package archive moves cold vectors out of the vectors table into S3 shards, trading read
+// latency for storage cost. A vector is cold when it is older than the minimum age and its
+// paper has not been read within the idle window; the search service still indexes and
+// serves archived vectors by reading the shards.
+package archive
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"shared/awsclient"
+	"shared/dynamo"
+	"shared/logger"
+	"shared/vectorarchive"
+)
+
+// Defaults of the archive job
+const (
+	DefaultMinAge    = 365 * 24 * time.Hour
+	DefaultIdleFor   = 90 * 24 * time.Hour
+	DefaultShardSize = 5000
+)
+
+// DynamoDB request limits
+const (
+	maxBatchGetKeys = 100
+)
+
+// vectorStatusPending marks vectors whose paper is still being written; they are never archived
+const vectorStatusPending = "pending"
+
+// Config names the tables and bucket of the archive and selects the vectors to move
+type Config struct {
+	VectorsTable string
+	PapersTable  string
+	PartitionKey string // vectors table partition key, the paper ID
+	SortKey      string // vectors table sort key; empty for partition-key-only tables
+	Bucket       string
+	Prefix       string
+	MinAge       time.Duration // vectors created more recently stay in DynamoDB
+	IdleFor      time.Duration // papers read more recently keep their vectors in DynamoDB
+	ShardSize    int           // vectors per shard
+	DryRun       bool
+}
+
+// Report summarizes an archive run
+type Report struct {
+	RunID            string   `json:"run_id"`
+	Scanned          int      `json:"scanned"`           // vectors older than the minimum age
+	RecentlyAccessed int      `json:"recently_accessed"` // of those, kept because their paper was read recently
+	Archived         int      `json:"archived"`          // written to a shard and deleted from the table
+	Skipped          int      `json:"skipped"`           // rewritten since the scan, so kept in the table
+	Shards           []string `json:"shards,omitempty"`
+	ShardBytes       int64    `json:"shard_bytes"`
+	ConsumedCapacity float64  `json:"consumed_capacity"` // read capacity units of the scan
+	DryRun           bool     `json:"dry_run"`
+	Errors           []string `json:"errors,omitempty"`
+}
+
+// Archiver moves cold vectors from the vectors table to S3 shards
+type Archiver struct {
+	dynamoClient dynamodbiface.DynamoDBAPI
+	s3Client     s3iface.S3API
+	config       Config
+	logger       *logger.Logger
+	now          func() time.Time
+}
+
+// NewArchiver creates an archiver for the configured tables and bucket
+func NewArchiver(config Config) *Archiver {
+	sess := awsclient.MustSession()
+	return NewArchiverWithClients(dynamodb.New(sess), s3.New(sess), config)
+}
+
+// NewArchiverWithClients creates an archiver with custom clients (for testing)
+func NewArchiverWithClients(dynamoClient dynamodbiface.DynamoDBAPI, s3Client s3iface.S3API, config Config) *Archiver {
+	if config.Prefix == "" {
+		config.Prefix = vectorarchive.DefaultPrefix
+	}
+	if config.ShardSize <= 0 {
+		config.ShardSize = DefaultShardSize
+	}
+	return &Archiver{
+		dynamoClient: dynamoClient,
+		s3Client:     s3Client,
+		config:       config,
+		logger:       logger.New("vector-archive"),
+		now:          time.Now,
+	}
+}
+
+// Run scans the vectors table for cold vectors and moves them in shards. Each shard is
+// uploaded and recorded on its papers before its vectors are deleted, so an interrupted run
+// leaves vectors in both tiers rather than in neither; readers prefer the table.
+func (a *Archiver) Run(ctx context.Context) (*Report, error) {
+	if a.config.VectorsTable == "" || a.config.PapersTable == "" || a.config.Bucket == "" || a.config.PartitionKey == "" {
+		return nil, fmt.Errorf("vectors table, papers table, partition key and bucket are required")
+	}
+
+	now := a.now().UTC()
+	report := &Report{
+		RunID:  "archive-" + now.Format("20060102-150405"),
+		DryRun: a.config.DryRun,
+	}
+	createdBefore := now.Add(-a.config.MinAge).Format(time.RFC3339)
+	accessedBefore := now.Add(-a.config.IdleFor)
+
+	input := &dynamodb.ScanInput{
+		TableName:        aws.String(a.config.VectorsTable),
+		FilterExpression: aws.String("#pi.#ca < :cutoff AND (attribute_not_exists(#st) OR #st <> :pending)"),
+		ExpressionAttributeNames: map[string]*string{
+			"#pi": aws.String("processing_info"),
+			"#ca": aws.String("created_at"),
+			"#st": aws.String("status"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":cutoff":  {S: aws.String(createdBefore)},
+			":pending": {S: aws.String(vectorStatusPending)},
+		},
+	}
+
+	var shard []map[string]*dynamodb.AttributeValue
+	result, err := dynamo.Paginate(ctx, dynamo.Scan(a.dynamoClient, input), dynamo.Options{}, func(page dynamo.Page) error {
+		report.Scanned += len(page.Items)
+		accessed, err := a.accessedAt(ctx, page.Items)
+		if err != nil {
+			return err
+		}
+		for _, item := range page.Items {
+			if at, ok := accessed[a.paperID(item)]; ok && at.After(accessedBefore) {
+				report.RecentlyAccessed++
+				continue
+			}
+			shard = append(shard, item)
+			if len(shard) >= a.config.ShardSize {
+				if err := a.flush(ctx, report, shard); err != nil {
+					return err
+				}
+				shard = nil
+			}
+		}
+
+		a.logger.Info("Vector archive scan progress", map[string]interface{}{
+			"page":              page.Number,
+			"scanned":           report.Scanned,
+			"archived":          report.Archived,
+			"recently_accessed": report.RecentlyAccessed,
+		})
+		return nil
+	})
+	report.ConsumedCapacity = result.ConsumedCapacity
+	if err != nil {
+		var fetchErr *dynamo.FetchError
+		if errors.As(err, &fetchErr) {
+			return report, fmt.Errorf("failed to scan vectors: %w", err)
+		}
+		return report, err
+	}
+	if len(shard) > 0 {
+		if err := a.flush(ctx, report, shard); err != nil {
+			return report, err
+		}
+	}
+
+	return report, nil
+}
+
+// paperID reads the paper ID of a vectors table item
+func (a *Archiver) paperID(item map[string]*dynamodb.AttributeValue) string {
+	if value := item[a.config.PartitionKey]; value != nil {
+		return aws.StringValue(value.S)
+	}
+	return ""
+}
+
+// accessedAt reads when the papers of the items were last read. Papers without the
+// attribute, or that no longer exist, are missing from the result and count as idle.
+func (a *Archiver) accessedAt(ctx context.Context, items []map[string]*dynamodb.AttributeValue) (map[string]time.Time, error) {
+	seen := make(map[string]bool)
+	var keys []map[string]*dynamodb.AttributeValue
+	for _, item := range items {
+		if paperID := a.paperID(item); paperID != "" && !seen[paperID] {
+			seen[paperID] = true
+			keys = append(keys, map[string]*dynamodb.AttributeValue{"paper_id": {S: aws.String(paperID)}})
+		}
+	}
+
+	accessed := make(map[string]time.Time)
+	for start := 0; start < len(keys); start += maxBatchGetKeys {
+		end := start + maxBatchGetKeys
+		if end > len(keys) {
+			end = len(keys)
+		}
+		requestItems := map[string]*dynamodb.KeysAndAttributes{
+			a.config.PapersTable: {
+				Keys:                     keys[start:end],
+				ProjectionExpression:     aws.String("paper_id, #acc"),
+				ExpressionAttributeNames: map[string]*string{"#acc": aws.String(vectorarchive.AccessedAtAttribute)},
+			},
+		}
+		for len(requestItems) > 0 {
+			output, err := a.dynamoClient.BatchGetItemWithContext(ctx, &dynamodb.BatchGetItemInput{RequestItems: requestItems})
+			if err != nil {
+				return nil, fmt.Errorf("failed to read paper access times: %w", err)
+			}
+			var papers []struct {
+				PaperID    string `dynamodbav:"paper_id"`
+				AccessedAt string `dynamodbav:"vectors_accessed_at"`
+			}
+			if err := dynamodbattribute.UnmarshalListOfMaps(output.Responses[a.config.PapersTable], &papers); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal paper access times: %w", err)
+			}
+			for _, paper := range papers {
+				if at, err := time.Parse(time.RFC3339, paper.AccessedAt); err == nil {
+					accessed[paper.PaperID] = at
+				}
+			}
+			requestItems = output.UnprocessedKeys
+		}
+	}
+	return accessed, nil
+}
+
+// flush writes one shard, records its key on the shard's papers and deletes its vectors from
+// the table. A failed upload stops the run; failed deletes are reported and leave the vector
+// in both tiers, and vectors rewritten since the scan stay in the table as the fresher copy.
+func (a *Archiver) flush(ctx context.Context, report *Report, items []map[string]*dynamodb.AttributeValue) error {
+	if a.config.DryRun {
+		report.Archived += len(items)
+		return nil
+	}
+
+	key := vectorarchive.ShardKey(a.config.Prefix, report.RunID, len(report.Shards))
+	var buf bytes.Buffer
+	writer := vectorarchive.NewShardWriter(&buf)
+	for _, item := range items {
+		if err := writer.Write(item); err != nil {
+			return fmt.Errorf("failed to write shard %s: %w", key, err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to write shard %s: %w", key, err)
+	}
+
+	_, err := a.s3Client.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(a.config.Bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(buf.Bytes()),
+		ContentType: aws.String("application/gzip"),
+		Metadata: map[string]*string{
+			"vector-count": aws.String(fmt.Sprintf("%d", len(items))),
+			"run-id":       aws.String(report.RunID),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload shard %s: %w", key, err)
+	}
+	report.Shards = append(report.Shards, key)
+	report.ShardBytes += int64(buf.Len())
+
+	// Papers whose pointer could not be recorded keep their vectors in the table
+	recorded := make(map[string]bool)
+	for _, item := range items {
+		paperID := a.paperID(item)
+		if _, done := recorded[paperID]; done {
+			continue
+		}
+		recorded[paperID] = a.recordShard(ctx, report, paperID, key)
+	}
+
+	for _, item := range items {
+		if recorded[a.paperID(item)] {
+			a.deleteVector(ctx, report, item)
+		}
+	}
+
+	a.logger.Info("Archived vector shard", map[string]interface{}{
+		"shard":   key,
+		"vectors": len(items),
+		"bytes":   buf.Len(),
+	})
+	return nil
+}
+
+// recordShard adds the shard key to the paper's archive keys. Vectors of papers that no
+// longer exist are archived all the same, as nothing reads them through the paper.
+func (a *Archiver) recordShard(ctx context.Context, report *Report, paperID, key string) bool {
+	_, err := a.dynamoClient.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName:                aws.String(a.config.PapersTable),
+		Key:                      map[string]*dynamodb.AttributeValue{"paper_id": {S: aws.String(paperID)}},
+		UpdateExpression:         aws.String("ADD #keys :key"),
+		ConditionExpression:      aws.String("attribute_exists(paper_id)"),
+		ExpressionAttributeNames: map[string]*string{"#keys": aws.String(vectorarchive.ShardKeysAttribute)},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":key": {SS: []*string{aws.String(key)}},
+		},
+	})
+	if err == nil {
+		return true
+	}
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return true
+	}
+	report.Errors = append(report.Errors, fmt.Sprintf("%s: failed to record shard: %v", paperID, err))
+	return false
+}
+
+// itemKey extracts the table key of a vectors table item
+func (a *Archiver) itemKey(item map[string]*dynamodb.AttributeValue) map[string]*dynamodb.AttributeValue {
+	key := map[string]*dynamodb.AttributeValue{a.config.PartitionKey: item[a.config.PartitionKey]}
+	if a.config.SortKey != "" {
+		key[a.config.SortKey] = item[a.config.SortKey]
+	}
+	return key
+}
+
+// deleteVector deletes an archived vector unless it was rewritten since the scan: the delete
+// is conditioned on the created_at the shard holds, so a vector re-embedded or rerun in the
+// meantime stays in the table and a failed condition counts as skipped
+func (a *Archiver) deleteVector(ctx context.Context, report *Report, item map[string]*dynamodb.AttributeValue) {
+	var createdAt *dynamodb.AttributeValue
+	if info := item["processing_info"]; info != nil {
+		createdAt = info.M["created_at"]
+	}
+	if createdAt == nil {
+		report.Skipped++
+		return
+	}
+
+	_, err := a.dynamoClient.DeleteItemWithContext(ctx, &dynamodb.DeleteItemInput{
+		TableName:           aws.String(a.config.VectorsTable),
+		Key:                 a.itemKey(item),
+		ConditionExpression: aws.String("#pi.#ca = :created"),
+		ExpressionAttributeNames: map[string]*string{
+			"#pi": aws.String("processing_info"),
+			"#ca": aws.String("created_at"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":created": createdAt,
+		},
+	})
+	if err == nil {
+		report.Archived++
+		return
+	}
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		report.Skipped++
+		return
+	}
+	report.Errors = append(report.Errors, fmt.Sprintf("%s: failed to delete archived vector: %v", a.paperID(item), err))
+}
@@ -0,0 +1,225 @@
+package authors
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"shared/dynamo"
+	"shared/logger"
+)
+
+// NameKeyIndex is the Authors table GSI on name_key, written by the batch processor
+const NameKeyIndex = "name-key-index"
+
+// maxBatchGetKeys is the DynamoDB limit on keys per BatchGetItem request
+const maxBatchGetKeys = 100
+
+// Author is an author entity as stored by the batch processor
+type Author struct {
+	AuthorID     string   `dynamodbav:"author_id" json:"author_id"`
+	NameKey      string   `dynamodbav:"name_key" json:"name_key"`
+	DisplayName  string   `dynamodbav:"display_name" json:"display_name"`
+	NameVariants []string `dynamodbav:"name_variants,stringset" json:"name_variants"`
+	PaperIDs     []string `dynamodbav:"paper_ids,stringset" json:"paper_ids"`
+	Categories   []string `dynamodbav:"categories,stringset" json:"categories,omitempty"`
+	UpdatedAt    string   `dynamodbav:"updated_at" json:"updated_at"`
+}
+
+// Paper is the summary of a paper listed for an author
+type Paper struct {
+	PaperID       string `dynamodbav:"paper_id" json:"paper_id"`
+	Title         string `dynamodbav:"title" json:"title"`
+	Source        string `dynamodbav:"source" json:"source"`
+	PublishedDate string `dynamodbav:"published_date" json:"published_date"`
+	Deleted       bool   `dynamodbav:"deleted" json:"deleted,omitempty"`
+}
+
+// AuthorPapers is an author entity with its papers, newest first
+type AuthorPapers struct {
+	Author Author  `json:"author"`
+	Papers []Paper `json:"papers"`
+}
+
+// Lookup answers "papers by this author" queries against the Authors and Papers tables
+type Lookup struct {
+	client       dynamodbiface.DynamoDBAPI
+	authorsTable string
+	papersTable  string
+	logger       *logger.Logger
+}
+
+// NewLookup creates a lookup over the given tables
+func NewLookup(authorsTable, papersTable string) *Lookup {
+	sess := session.Must(session.NewSession())
+	return NewLookupWithClient(dynamodb.New(sess), authorsTable, papersTable)
+}
+
+// NewLookupWithClient creates a lookup with a custom DynamoDB client (for testing)
+func NewLookupWithClient(client dynamodbiface.DynamoDBAPI, authorsTable, papersTable string) *Lookup {
+	return &Lookup{
+		client:       client,
+		authorsTable: authorsTable,
+		papersTable:  papersTable,
+		logger:       logger.New("author-lookup"),
+	}
+}
+
+// FindByName returns the author entities whose name key matches name. Several entities
+// mean the name was disambiguated into different people.
+func (l *Lookup) FindByName(ctx context.Context, name string) ([]Author, error) {
+	key := NameKey(name)
+	if key == "" {
+		return nil, fmt.Errorf("name %q has no letters", name)
+	}
+
+	var authors []Author
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(l.authorsTable),
+		IndexName:              aws.String(NameKeyIndex),
+		KeyConditionExpression: aws.String("#nk = :nk"),
+		ExpressionAttributeNames: map[string]*string{
+			"#nk": aws.String("name_key"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":nk": {S: aws.String(key)},
+		},
+	}
+	_, err := dynamo.Paginate(ctx, dynamo.Query(l.client, input), dynamo.Options{}, func(page dynamo.Page) error {
+		var pageAuthors []Author
+		if err := dynamodbattribute.UnmarshalListOfMaps(page.Items, &pageAuthors); err != nil {
+			l.logger.Warn("Skipping unreadable author page", map[string]interface{}{
+				"name_key": key,
+				"page":     page.Number,
+				"error":    err.Error(),
+			})
+			return nil
+		}
+		authors = append(authors, pageAuthors...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query authors for %q: %w", key, err)
+	}
+
+	sort.Slice(authors, func(i, j int) bool {
+		return len(authors[i].PaperIDs) > len(authors[j].PaperIDs)
+	})
+	return authors, nil
+}
+
+// Papers returns an author entity and its papers. Soft-deleted papers are left out.
+func (l *Lookup) Papers(ctx context.Context, authorID string) (*AuthorPapers, error) {
+	result, err := l.client.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(l.authorsTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"author_id": {S: aws.String(authorID)},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get author %s: %w", authorID, err)
+	}
+	if len(result.Item) == 0 {
+		return nil, fmt.Errorf("author %s not found", authorID)
+	}
+
+	var author Author
+	if err := dynamodbattribute.UnmarshalMap(result.Item, &author); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal author %s: %w", authorID, err)
+	}
+
+	papers, err := l.fetchPapers(ctx, author.PaperIDs)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(papers, func(i, j int) bool {
+		return papers[i].PublishedDate > papers[j].PublishedDate
+	})
+	return &AuthorPapers{Author: author, Papers: papers}, nil
+}
+
+// fetchPapers batch-gets paper summaries, skipping missing and soft-deleted papers
+func (l *Lookup) fetchPapers(ctx context.Context, paperIDs []string) ([]Paper, error) {
+	papers := make([]Paper, 0, len(paperIDs))
+	for start := 0; start < len(paperIDs); start += maxBatchGetKeys {
+		end := start + maxBatchGetKeys
+		if end > len(paperIDs) {
+			end = len(paperIDs)
+		}
+
+		keys := make([]map[string]*dynamodb.AttributeValue, 0, end-start)
+		for _, id := range paperIDs[start:end] {
+			keys = append(keys, map[string]*dynamodb.AttributeValue{
+				"paper_id": {S: aws.String(id)},
+			})
+		}
+		requestItems := map[string]*dynamodb.KeysAndAttributes{
+			l.papersTable: {
+				Keys:                 keys,
+				ProjectionExpression: aws.String("#pid, #t, #src, #pub, #del"),
+				ExpressionAttributeNames: map[string]*string{
+					"#pid": aws.String("paper_id"),
+					"#t":   aws.String("title"),
+					"#src": aws.String("source"),
+					"#pub": aws.String("published_date"),
+					"#del": aws.String("deleted"),
+				},
+			},
+		}
+
+		maxRetries := 3
+		for attempt := 0; attempt < maxRetries && len(requestItems) > 0; attempt++ {
+			result, err := l.client.BatchGetItemWithContext(ctx, &dynamodb.BatchGetItemInput{
+				RequestItems: requestItems,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("batch get failed on attempt %d: %w", attempt+1, err)
+			}
+
+			var batch []Paper
+			if err := dynamodbattribute.UnmarshalListOfMaps(result.Responses[l.papersTable], &batch); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal papers: %w", err)
+			}
+			for _, paper := range batch {
+				if !paper.Deleted {
+					papers = append(papers, paper)
+				}
+			}
+			requestItems = result.UnprocessedKeys
+		}
+		if len(requestItems) > 0 {
+			return nil, fmt.Errorf("unprocessed keys remained after %d retries", maxRetries)
+		}
+	}
+	return papers, nil
+}
+
+// NameKey mirrors the batch processor's author blocking key: lowercased surname and
+// first initial, e.g. "smith_j" for "John Smith" and "Smith, J."
+func NameKey(name string) string {
+	surnameFirst := strings.Contains(name, ",")
+	tokens := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '-' && r != '\''
+	})
+	if len(tokens) == 0 {
+		return ""
+	}
+	if len(tokens) == 1 {
+		return tokens[0]
+	}
+
+	surname, given := tokens[len(tokens)-1], tokens[0]
+	if surnameFirst {
+		surname, given = tokens[0], tokens[1]
+	}
+	initial, _ := utf8.DecodeRuneInString(given)
+	return surname + "_" + string(initial)
+}
@@ -0,0 +1,365 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"admin-cli/archive"
+	"admin-cli/authors"
+	"admin-cli/fixtures"
+	"admin-cli/statemachine"
+	"admin-cli/takedown"
+	"admin-cli/traceindex"
+	"admin-cli/triage"
+	"shared/logger"
+)
+
+var appLogger = logger.New("admin-cli")
+
+func main() {
+	if len(os.Args) < 2 {
+		printUsage()
+		os.Exit(2)
+	}
+
+	ctx := context.Background()
+	command, args := os.Args[1], os.Args[2:]
+
+	var err error
+	switch command {
+	case "soft-delete":
+		err = runSoftDelete(ctx, args)
+	case "restore":
+		err = runRestore(ctx, args)
+	case "purge":
+		err = runPurge(ctx, args)
+	case "purge-source":
+		err = runPurgeSource(ctx, args)
+	case "author-papers":
+		err = runAuthorPapers(ctx, args)
+	case "repair-trace-index":
+		err = runRepairTraceIndex(ctx, args)
+	case "archive-vectors":
+		err = runArchiveVectors(ctx, args)
+	case "failures":
+		err = runFailures(ctx, args)
+	case "testdata":
+		err = runTestdata(args)
+	case "render-state-machine":
+		err = runRenderStateMachine(args)
+	case "deploy-state-machine":
+		err = runDeployStateMachine(ctx, args)
+	case "help", "-h", "--help":
+		printUsage()
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", command)
+		printUsage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		appLogger.Error(fmt.Sprintf("%s failed", command), err)
+		os.Exit(1)
+	}
+}
+
+func printUsage() {
+	fmt.Fprintln(os.Stderr, "Pipeline Admin CLI")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "Usage: admin-cli <command> [flags]")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "Commands:")
+	fmt.Fprintln(os.Stderr, "  soft-delete  Tombstone a paper for a takedown request")
+	fmt.Fprintln(os.Stderr, "  restore      Lift the tombstone from a paper that has not been purged")
+	fmt.Fprintln(os.Stderr, "  purge        Permanently remove tombstoned papers, vectors and raw-data entries")
+	fmt.Fprintln(os.Stderr, "  purge-source  Permanently remove every paper of a source or trace, with its vectors, full text and raw-data entries")
+	fmt.Fprintln(os.Stderr, "  author-papers  List an author's papers, or the author entities matching a name")
+	fmt.Fprintln(os.Stderr, "  repair-trace-index  Verify the trace-id GSI and backfill missing trace_id/batch_timestamp attributes")
+	fmt.Fprintln(os.Stderr, "  archive-vectors  Move old, idle vectors from DynamoDB into compressed S3 shards")
+	fmt.Fprintln(os.Stderr, "  failures     List the most recent failed records with their stage, error code, run outcome and replay command")
+	fmt.Fprintln(os.Stderr, "  testdata     Generate deterministic payload, NDJSON batch and embedding fixtures for integration and load tests")
+	fmt.Fprintln(os.Stderr, "  render-state-machine  Print the Step Functions definition generated from the pipeline config")
+	fmt.Fprintln(os.Stderr, "  deploy-state-machine  Create or update the Step Functions state machine from the pipeline config")
+}
+
+// takedownConfig registers the shared table flags, defaulting to the services' environment variables
+func takedownConfig(fs *flag.FlagSet) *takedown.Config {
+	cfg := &takedown.Config{}
+	fs.StringVar(&cfg.PapersTable, "papers-table", getEnvOrDefault("PAPERS_TABLE_NAME", "Papers"), "papers table name")
+	fs.StringVar(&cfg.VectorsTable, "vectors-table", getEnvOrDefault("VECTORS_TABLE_NAME", "Vectors"), "vectors table name")
+	return cfg
+}
+
+func runSoftDelete(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("soft-delete", flag.ExitOnError)
+	cfg := takedownConfig(fs)
+	paperID := fs.String("paper-id", "", "paper to tombstone (required)")
+	reason := fs.String("reason", "takedown request", "reason recorded on the tombstone")
+	fs.Parse(args)
+
+	if *paperID == "" {
+		return fmt.Errorf("-paper-id is required")
+	}
+	return takedown.NewManager(*cfg).SoftDelete(ctx, *paperID, *reason)
+}
+
+func runRestore(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	cfg := takedownConfig(fs)
+	paperID := fs.String("paper-id", "", "paper to restore (required)")
+	fs.Parse(args)
+
+	if *paperID == "" {
+		return fmt.Errorf("-paper-id is required")
+	}
+	return takedown.NewManager(*cfg).Restore(ctx, *paperID)
+}
+
+func runPurge(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("purge", flag.ExitOnError)
+	cfg := takedownConfig(fs)
+	grace := fs.Duration("grace", 7*24*time.Hour, "only purge papers tombstoned longer than this")
+	dryRun := fs.Bool("dry-run", false, "report what would be purged without deleting")
+	fs.Parse(args)
+
+	result, err := takedown.NewManager(*cfg).Purge(ctx, *grace, *dryRun)
+	if err != nil {
+		return err
+	}
+	if err := printJSON(result); err != nil {
+		return err
+	}
+	if len(result.Errors) > 0 {
+		return fmt.Errorf("%d papers failed to purge", len(result.Errors))
+	}
+	return nil
+}
+
+func runPurgeSource(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("purge-source", flag.ExitOnError)
+	cfg := takedownConfig(fs)
+	fs.StringVar(&cfg.AuthorsTable, "authors-table", getEnvOrDefault("AUTHORS_TABLE_NAME", "Authors"), "authors table whose paper links are removed, empty to skip")
+	fs.StringVar(&cfg.TraceIDIndex, "index", getEnvOrDefault("TRACE_ID_INDEX_NAME", "trace-id-index"), "trace ID index name")
+	var selector takedown.Selector
+	fs.StringVar(&selector.Source, "source", "", "purge every paper of this source")
+	fs.StringVar(&selector.TraceID, "trace-id", "", "purge every paper of this trace")
+	dryRun := fs.Bool("dry-run", false, "list the papers that would be purged without deleting")
+	fs.Parse(args)
+
+	if err := selector.Validate(); err != nil {
+		return fmt.Errorf("-source or -trace-id is required, but not both")
+	}
+	result, err := takedown.NewManager(*cfg).PurgeSource(ctx, selector, *dryRun)
+	if err != nil {
+		return err
+	}
+	if err := printJSON(result); err != nil {
+		return err
+	}
+	if len(result.Errors) > 0 {
+		return fmt.Errorf("%d errors during source purge", len(result.Errors))
+	}
+	return nil
+}
+
+func runAuthorPapers(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("author-papers", flag.ExitOnError)
+	authorsTable := fs.String("authors-table", getEnvOrDefault("AUTHORS_TABLE_NAME", "Authors"), "authors table name")
+	papersTable := fs.String("papers-table", getEnvOrDefault("PAPERS_TABLE_NAME", "Papers"), "papers table name")
+	authorID := fs.String("author-id", "", "author entity whose papers to list")
+	name := fs.String("name", "", "list the author entities matching this name instead")
+	fs.Parse(args)
+
+	lookup := authors.NewLookup(*authorsTable, *papersTable)
+	switch {
+	case *authorID != "":
+		result, err := lookup.Papers(ctx, *authorID)
+		if err != nil {
+			return err
+		}
+		return printJSON(result)
+	case *name != "":
+		result, err := lookup.FindByName(ctx, *name)
+		if err != nil {
+			return err
+		}
+		return printJSON(result)
+	default:
+		return fmt.Errorf("-author-id or -name is required")
+	}
+}
+
+func runRepairTraceIndex(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("repair-trace-index", flag.ExitOnError)
+	cfg := traceindex.Config{}
+	fs.StringVar(&cfg.PapersTable, "papers-table", getEnvOrDefault("PAPERS_TABLE_NAME", "Papers"), "papers table name")
+	fs.StringVar(&cfg.IndexName, "index", getEnvOrDefault("TRACE_ID_INDEX_NAME", "trace-id-index"), "trace ID index name")
+	fs.StringVar(&cfg.BackfillTraceID, "backfill-trace-id", "", "trace ID written to papers without one (default backfill-<timestamp>)")
+	fs.BoolVar(&cfg.DryRun, "dry-run", false, "report the papers that need repair without updating them")
+	fs.Parse(args)
+
+	report, err := traceindex.NewRepairer(cfg).Run(ctx)
+	if err != nil {
+		return err
+	}
+	if err := printJSON(report); err != nil {
+		return err
+	}
+	if len(report.Errors) > 0 {
+		return fmt.Errorf("%d papers failed to repair", len(report.Errors))
+	}
+	return nil
+}
+
+func runArchiveVectors(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("archive-vectors", flag.ExitOnError)
+	cfg := archive.Config{}
+	fs.StringVar(&cfg.VectorsTable, "vectors-table", getEnvOrDefault("VECTORS_TABLE_NAME", "Vectors"), "vectors table name")
+	fs.StringVar(&cfg.PapersTable, "papers-table", getEnvOrDefault("PAPERS_TABLE_NAME", "Papers"), "papers table name")
+	fs.StringVar(&cfg.PartitionKey, "partition-key", "paper_id", "vectors table partition key")
+	fs.StringVar(&cfg.SortKey, "sort-key", "vector_type", "vectors table sort key, empty for partition-key-only tables")
+	fs.StringVar(&cfg.Bucket, "bucket", os.Getenv("VECTOR_ARCHIVE_BUCKET"), "archive bucket")
+	fs.StringVar(&cfg.Prefix, "prefix", getEnvOrDefault("VECTOR_ARCHIVE_PREFIX", "vector-archive"), "archive key prefix")
+	minAgeDays := fs.Int("min-age-days", int(archive.DefaultMinAge/(24*time.Hour)), "archive vectors created more than this many days ago")
+	idleDays := fs.Int("idle-days", int(archive.DefaultIdleFor/(24*time.Hour)), "keep vectors of papers read within this many days")
+	fs.IntVar(&cfg.ShardSize, "shard-size", archive.DefaultShardSize, "vectors per shard")
+	fs.BoolVar(&cfg.DryRun, "dry-run", false, "count the vectors that would be archived without moving them")
+	fs.Parse(args)
+
+	if *minAgeDays <= 0 || *idleDays < 0 {
+		return fmt.Errorf("-min-age-days must be positive and -idle-days must not be negative")
+	}
+	cfg.MinAge = time.Duration(*minAgeDays) * 24 * time.Hour
+	cfg.IdleFor = time.Duration(*idleDays) * 24 * time.Hour
+
+	report, err := archive.NewArchiver(cfg).Run(ctx)
+	if report != nil {
+		if printErr := printJSON(report); printErr != nil && err == nil {
+			err = printErr
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if len(report.Errors) > 0 {
+		return fmt.Errorf("%d archive errors", len(report.Errors))
+	}
+	return nil
+}
+
+func runFailures(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("failures", flag.ExitOnError)
+	cfg := triage.Config{}
+	fs.StringVar(&cfg.FailedItemsTable, "failed-items-table", getEnvOrDefault("FAILED_ITEMS_TABLE_NAME", "FailedItems"), "FailedItems table name")
+	fs.StringVar(&cfg.RunsTable, "runs-table", getEnvOrDefault("PROCESSING_RUNS_TABLE_NAME", "ProcessingRuns"), "ProcessingRuns table name, empty to skip the run lookup")
+	fs.IntVar(&cfg.Limit, "limit", 20, "failures listed")
+	since := fs.Duration("since", 0, "only list failures within this long, e.g. 24h; 0 lists any age")
+	fs.StringVar(&cfg.Stage, "stage", "", "only list this stage: collection, ingestion, vectorization or pdf_extraction")
+	codes := fs.String("codes", "", "only list these comma-separated error codes")
+	fs.StringVar(&cfg.CollectorFunction, "collector-function", getEnvOrDefault("DATA_COLLECTOR_FUNCTION_NAME", "data-collector"), "data collector Lambda function named in collection replay commands")
+	fs.Parse(args)
+
+	if cfg.Limit <= 0 || *since < 0 {
+		return fmt.Errorf("-limit must be positive and -since must not be negative")
+	}
+	if *since > 0 {
+		cfg.Since = time.Now().Add(-*since)
+	}
+	for _, code := range strings.Split(*codes, ",") {
+		if code = strings.TrimSpace(code); code != "" {
+			cfg.Codes = append(cfg.Codes, code)
+		}
+	}
+
+	report, err := triage.NewLister(cfg).Recent(ctx)
+	if report != nil {
+		if printErr := printJSON(report); printErr != nil && err == nil {
+			err = printErr
+		}
+	}
+	return err
+}
+
+func runTestdata(args []string) error {
+	fs := flag.NewFlagSet("testdata", flag.ExitOnError)
+	cfg := fixtures.Config{}
+	fs.StringVar(&cfg.OutputDir, "output", "testdata", "directory the fixtures are written to")
+	fs.Int64Var(&cfg.Seed, "seed", fixtures.DefaultSeed, "random seed; the same seed and sizes produce identical files")
+	fs.IntVar(&cfg.Payloads, "payloads", fixtures.DefaultPayloads, "number of collection payloads")
+	fs.IntVar(&cfg.Papers, "papers", fixtures.DefaultPapers, "papers per payload")
+	fs.IntVar(&cfg.BatchSize, "batch-size", fixtures.DefaultBatchSize, "papers per NDJSON batch and embeddings file")
+	fs.IntVar(&cfg.Dimension, "dimension", fixtures.DefaultDimension, "embedding dimension")
+	fs.StringVar(&cfg.Source, "source", fixtures.DefaultSource, "source name of the papers")
+	fs.StringVar(&cfg.Format, "format", fixtures.FormatJSON, "payload format: json (schema 1) or lines (schema 2)")
+	fs.StringVar(&cfg.ModelVersion, "model-version", fixtures.DefaultModelVersion, "model version of the embedding fixtures")
+	start := fs.String("start", fixtures.DefaultStart.Format(time.RFC3339), "collection time of the first payload (RFC 3339)")
+	fs.Parse(args)
+
+	var err error
+	if cfg.Start, err = time.Parse(time.RFC3339, *start); err != nil {
+		return fmt.Errorf("invalid -start: %w", err)
+	}
+	report, err := fixtures.Generate(cfg)
+	if err != nil {
+		return err
+	}
+	return printJSON(report)
+}
+
+func runRenderStateMachine(args []string) error {
+	fs := flag.NewFlagSet("render-state-machine", flag.ExitOnError)
+	configPath := fs.String("config", "config/pipeline-config.yaml", "pipeline configuration file")
+	output := fs.String("output", "", "write the definition to this file instead of stdout")
+	fs.Parse(args)
+
+	cfg, err := statemachine.LoadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	body, err := statemachine.Render(cfg).JSON()
+	if err != nil {
+		return err
+	}
+
+	if *output == "" {
+		_, err = fmt.Println(string(body))
+		return err
+	}
+	return os.WriteFile(*output, append(body, '\n'), 0o644)
+}
+
+func runDeployStateMachine(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("deploy-state-machine", flag.ExitOnError)
+	configPath := fs.String("config", "config/pipeline-config.yaml", "pipeline configuration file")
+	roleArn := fs.String("role-arn", os.Getenv("STATE_MACHINE_ROLE_ARN"), "execution role, required when creating the state machine")
+	fs.Parse(args)
+
+	cfg, err := statemachine.LoadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	result, err := statemachine.NewDeployer().Deploy(ctx, cfg.StateMachineName, *roleArn, statemachine.Render(cfg))
+	if err != nil {
+		return err
+	}
+	return printJSON(result)
+}
+
+func printJSON(value interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,125 @@
+package statemachine
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// pipelineConfig is the subset of config/pipeline-config.yaml the state machine is rendered from
+type pipelineConfig struct {
+	AWS struct {
+		S3 struct {
+			RawDataBucket string `yaml:"raw_data_bucket"`
+		} `yaml:"s3"`
+	} `yaml:"aws"`
+	Orchestration Config `yaml:"orchestration"`
+}
+
+// Config describes the orchestration section of the pipeline configuration
+type Config struct {
+	StateMachineName string      `yaml:"state_machine_name"`
+	Functions        Functions   `yaml:"functions"`
+	Retry            []RetryRule `yaml:"retry"`
+	FailOn           []string    `yaml:"fail_on"` // error types that end the run without retrying
+	RawDataBucket    string      `yaml:"-"`
+}
+
+// Functions names the Lambda functions invoked by each step
+type Functions struct {
+	Collector   string `yaml:"collector"`
+	Processor   string `yaml:"processor"`
+	Coordinator string `yaml:"coordinator"`
+}
+
+// RetryRule retries the listed error types with exponential backoff
+type RetryRule struct {
+	ErrorTypes      []string `yaml:"error_types"`
+	MaxAttempts     int      `yaml:"max_attempts"`
+	IntervalSeconds int      `yaml:"interval_seconds"`
+	BackoffRate     float64  `yaml:"backoff_rate"`
+}
+
+// LoadConfig reads the orchestration settings from a pipeline configuration file
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+	return LoadConfigFromBytes(data)
+}
+
+// LoadConfigFromBytes parses the orchestration settings, applying defaults for omitted fields
+func LoadConfigFromBytes(data []byte) (*Config, error) {
+	pipeline := pipelineConfig{Orchestration: DefaultConfig()}
+	if err := yaml.Unmarshal(data, &pipeline); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	cfg := pipeline.Orchestration
+	cfg.RawDataBucket = pipeline.AWS.S3.RawDataBucket
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid orchestration config: %w", err)
+	}
+	return &cfg, nil
+}
+
+// DefaultConfig returns the orchestration defaults
+func DefaultConfig() Config {
+	return Config{
+		StateMachineName: "paper-pipeline",
+		Functions: Functions{
+			Collector:   "data-collector",
+			Processor:   "batch-processor",
+			Coordinator: "vector-coordinator",
+		},
+		Retry: []RetryRule{
+			{
+				ErrorTypes:      []string{"API_ERROR", "S3_ERROR", "INTERNAL_ERROR", "RetryableProcessingError"},
+				MaxAttempts:     3,
+				IntervalSeconds: 5,
+				BackoffRate:     2.0,
+			},
+		},
+		FailOn: []string{"CONFIG_ERROR", "DATA_ERROR", "QUOTA_ERROR", "PAUSED", "TerminalProcessingError"},
+	}
+}
+
+// Validate checks that every step can be rendered
+func (c Config) Validate() error {
+	if c.StateMachineName == "" {
+		return fmt.Errorf("state_machine_name is required")
+	}
+	if c.Functions.Collector == "" || c.Functions.Processor == "" || c.Functions.Coordinator == "" {
+		return fmt.Errorf("collector, processor and coordinator functions are required")
+	}
+	if c.RawDataBucket == "" {
+		return fmt.Errorf("aws.s3.raw_data_bucket is required")
+	}
+
+	failOn := make(map[string]bool, len(c.FailOn))
+	for _, errorType := range c.FailOn {
+		failOn[errorType] = true
+	}
+	for i, rule := range c.Retry {
+		if len(rule.ErrorTypes) == 0 {
+			return fmt.Errorf("retry rule %d has no error_types", i)
+		}
+		for _, errorType := range rule.ErrorTypes {
+			if failOn[errorType] {
+				return fmt.Errorf("error type %s is listed in both retry and fail_on", errorType)
+			}
+		}
+		if rule.MaxAttempts < 1 {
+			return fmt.Errorf("retry rule %d: max_attempts must be at least 1", i)
+		}
+		if rule.IntervalSeconds < 1 {
+			return fmt.Errorf("retry rule %d: interval_seconds must be at least 1", i)
+		}
+		if rule.BackoffRate != 0 && rule.BackoffRate < 1 {
+			return fmt.Errorf("retry rule %d: backoff_rate must be at least 1", i)
+		}
+	}
+	return nil
+}
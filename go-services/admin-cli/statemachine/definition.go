@@ -0,0 +1,198 @@
+package statemachine
+
+import (
+	"encoding/json"
+)
+
+// State names used in the rendered definition
+const (
+	StateCollect          = "Collect"
+	StateCheckCollection  = "CheckCollection"
+	StateProcessBatch     = "ProcessBatch"
+	StateCheckProcessing  = "CheckProcessing"
+	StateVectorize        = "Vectorize"
+	StateNothingCollected = "NothingCollected"
+	StateNothingProcessed = "NothingProcessed"
+	StatePipelineFailed   = "PipelineFailed"
+)
+
+// lambdaServiceErrors are transient Lambda invocation failures that are always retried
+var lambdaServiceErrors = []string{
+	"Lambda.ServiceException",
+	"Lambda.AWSLambdaException",
+	"Lambda.SdkClientException",
+	"Lambda.TooManyRequestsException",
+}
+
+// Definition is an Amazon States Language document
+type Definition struct {
+	Comment string           `json:"Comment"`
+	StartAt string           `json:"StartAt"`
+	States  map[string]State `json:"States"`
+}
+
+// State is a single ASL state; only the fields used by the pipeline are modelled
+type State struct {
+	Type           string                 `json:"Type"`
+	Comment        string                 `json:"Comment,omitempty"`
+	Resource       string                 `json:"Resource,omitempty"`
+	Parameters     map[string]interface{} `json:"Parameters,omitempty"`
+	ResultSelector map[string]interface{} `json:"ResultSelector,omitempty"`
+	ResultPath     string                 `json:"ResultPath,omitempty"`
+	Retry          []Retrier              `json:"Retry,omitempty"`
+	Catch          []Catcher              `json:"Catch,omitempty"`
+	Choices        []Choice               `json:"Choices,omitempty"`
+	Default        string                 `json:"Default,omitempty"`
+	Next           string                 `json:"Next,omitempty"`
+	End            bool                   `json:"End,omitempty"`
+	Error          string                 `json:"Error,omitempty"`
+	Cause          string                 `json:"Cause,omitempty"`
+}
+
+// Retrier is an ASL retry policy
+type Retrier struct {
+	ErrorEquals     []string `json:"ErrorEquals"`
+	IntervalSeconds int      `json:"IntervalSeconds"`
+	MaxAttempts     int      `json:"MaxAttempts"`
+	BackoffRate     float64  `json:"BackoffRate,omitempty"`
+}
+
+// Catcher is an ASL catch policy
+type Catcher struct {
+	ErrorEquals []string `json:"ErrorEquals"`
+	ResultPath  string   `json:"ResultPath"`
+	Next        string   `json:"Next"`
+}
+
+// Choice is an ASL choice rule
+type Choice struct {
+	Variable      string `json:"Variable"`
+	NumericEquals *int   `json:"NumericEquals,omitempty"`
+	StringEquals  string `json:"StringEquals,omitempty"`
+	Next          string `json:"Next"`
+}
+
+// Render builds the pipeline state machine: the collector uploads a batch, the processor is
+// invoked on that object and waited for, and the coordinator vectorizes the processor's trace ID.
+// Retries and catches are keyed to the AppError types the services report as Lambda error types.
+func Render(cfg *Config) *Definition {
+	zero := 0
+
+	return &Definition{
+		Comment: "Paper pipeline: collect, process and vectorize (generated by admin-cli render-state-machine)",
+		StartAt: StateCollect,
+		States: map[string]State{
+			StateCollect: cfg.lambdaTask(cfg.Functions.Collector, map[string]interface{}{
+				"Payload.$": "$",
+			}, map[string]interface{}{
+				"s3_key.$": "$.Payload.s3_key",
+				"count.$":  "$.Payload.count",
+			}, "$.collection", StateCheckCollection),
+			StateCheckCollection: {
+				Type: "Choice",
+				Choices: []Choice{
+					{Variable: "$.collection.count", NumericEquals: &zero, Next: StateNothingCollected},
+				},
+				Default: StateProcessBatch,
+			},
+			StateProcessBatch: cfg.lambdaTask(cfg.Functions.Processor, map[string]interface{}{
+				"Payload": map[string]interface{}{
+					"Records": []interface{}{
+						map[string]interface{}{
+							"s3": map[string]interface{}{
+								"bucket": map[string]interface{}{"name": cfg.RawDataBucket},
+								"object": map[string]interface{}{"key.$": "$.collection.s3_key"},
+							},
+						},
+					},
+				},
+			}, map[string]interface{}{
+				"trace_id.$":        "$.Payload.trace_id",
+				"status.$":          "$.Payload.status",
+				"processed_count.$": "$.Payload.processed_count",
+			}, "$.processing", StateCheckProcessing),
+			StateCheckProcessing: {
+				Type: "Choice",
+				Choices: []Choice{
+					{Variable: "$.processing.status", StringEquals: "failed", Next: StatePipelineFailed},
+					{Variable: "$.processing.processed_count", NumericEquals: &zero, Next: StateNothingProcessed},
+				},
+				Default: StateVectorize,
+			},
+			StateVectorize: cfg.lambdaTask(cfg.Functions.Coordinator, map[string]interface{}{
+				"Payload": map[string]interface{}{
+					"trace_id.$": "$.processing.trace_id",
+				},
+			}, map[string]interface{}{
+				"status.$":         "$.Payload.status",
+				"vectors_stored.$": "$.Payload.vectors_stored",
+			}, "$.vectorization", ""),
+			StateNothingCollected: {Type: "Succeed", Comment: "The collector found no new papers"},
+			StateNothingProcessed: {Type: "Succeed", Comment: "Every collected paper was a duplicate or failed validation"},
+			StatePipelineFailed: {
+				Type:  "Fail",
+				Error: "PipelineFailed",
+				Cause: "A pipeline step failed; see the execution history for the error type",
+			},
+		},
+	}
+}
+
+// lambdaTask builds a Lambda invoke task with the configured retry and catch policies.
+// An empty next marks the task as the end of the execution.
+func (c *Config) lambdaTask(function string, parameters, resultSelector map[string]interface{}, resultPath, next string) State {
+	parameters["FunctionName"] = function
+
+	return State{
+		Type:           "Task",
+		Resource:       "arn:aws:states:::lambda:invoke",
+		Parameters:     parameters,
+		ResultSelector: resultSelector,
+		ResultPath:     resultPath,
+		Retry:          c.retriers(),
+		Catch:          c.catchers(),
+		Next:           next,
+		End:            next == "",
+	}
+}
+
+// retriers converts the configured retry rules, always retrying transient Lambda service errors first
+func (c *Config) retriers() []Retrier {
+	retriers := []Retrier{{
+		ErrorEquals:     lambdaServiceErrors,
+		IntervalSeconds: 2,
+		MaxAttempts:     6,
+		BackoffRate:     2.0,
+	}}
+	for _, rule := range c.Retry {
+		retriers = append(retriers, Retrier{
+			ErrorEquals:     rule.ErrorTypes,
+			IntervalSeconds: rule.IntervalSeconds,
+			MaxAttempts:     rule.MaxAttempts,
+			BackoffRate:     rule.BackoffRate,
+		})
+	}
+	return retriers
+}
+
+// catchers route fail-fast error types and anything left after retries to the failure state
+func (c *Config) catchers() []Catcher {
+	var catchers []Catcher
+	if len(c.FailOn) > 0 {
+		catchers = append(catchers, Catcher{
+			ErrorEquals: c.FailOn,
+			ResultPath:  "$.error",
+			Next:        StatePipelineFailed,
+		})
+	}
+	return append(catchers, Catcher{
+		ErrorEquals: []string{"States.ALL"},
+		ResultPath:  "$.error",
+		Next:        StatePipelineFailed,
+	})
+}
+
+// JSON renders the definition as indented JSON
+func (d *Definition) JSON() ([]byte, error) {
+	return json.MarshalIndent(d, "", "  ")
+}
@@ -0,0 +1,106 @@
+package statemachine
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sfn"
+	"github.com/aws/aws-sdk-go/service/sfn/sfniface"
+	"shared/logger"
+)
+
+// DeployResult reports what the deployer did
+type DeployResult struct {
+	StateMachineArn string `json:"state_machine_arn"`
+	Created         bool   `json:"created"`
+}
+
+// Deployer creates or updates the pipeline state machine
+type Deployer struct {
+	client sfniface.SFNAPI
+	logger *logger.Logger
+}
+
+// NewDeployer creates a new state machine deployer
+func NewDeployer() *Deployer {
+	sess := session.Must(session.NewSession())
+	return &Deployer{
+		client: sfn.New(sess),
+		logger: logger.New("statemachine"),
+	}
+}
+
+// NewDeployerWithClient creates a deployer with a custom client (for testing)
+func NewDeployerWithClient(client sfniface.SFNAPI) *Deployer {
+	return &Deployer{
+		client: client,
+		logger: logger.New("statemachine"),
+	}
+}
+
+// Deploy updates the named state machine in place, or creates it with roleArn when it does not exist
+func (d *Deployer) Deploy(ctx context.Context, name, roleArn string, definition *Definition) (*DeployResult, error) {
+	body, err := definition.JSON()
+	if err != nil {
+		return nil, fmt.Errorf("failed to render definition: %w", err)
+	}
+
+	arn, err := d.findStateMachine(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if arn != "" {
+		input := &sfn.UpdateStateMachineInput{
+			StateMachineArn: aws.String(arn),
+			Definition:      aws.String(string(body)),
+		}
+		if roleArn != "" {
+			input.RoleArn = aws.String(roleArn)
+		}
+		if _, err := d.client.UpdateStateMachineWithContext(ctx, input); err != nil {
+			return nil, fmt.Errorf("failed to update state machine %s: %w", name, err)
+		}
+		d.logger.Info("State machine updated", map[string]interface{}{
+			"state_machine_arn": arn,
+		})
+		return &DeployResult{StateMachineArn: arn}, nil
+	}
+
+	if roleArn == "" {
+		return nil, fmt.Errorf("state machine %s does not exist and no role ARN was given to create it", name)
+	}
+	output, err := d.client.CreateStateMachineWithContext(ctx, &sfn.CreateStateMachineInput{
+		Name:       aws.String(name),
+		Definition: aws.String(string(body)),
+		RoleArn:    aws.String(roleArn),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create state machine %s: %w", name, err)
+	}
+
+	d.logger.Info("State machine created", map[string]interface{}{
+		"state_machine_arn": aws.StringValue(output.StateMachineArn),
+	})
+	return &DeployResult{StateMachineArn: aws.StringValue(output.StateMachineArn), Created: true}, nil
+}
+
+// findStateMachine returns the ARN of the named state machine, or "" when none exists
+func (d *Deployer) findStateMachine(ctx context.Context, name string) (string, error) {
+	var arn string
+	err := d.client.ListStateMachinesPagesWithContext(ctx, &sfn.ListStateMachinesInput{}, func(page *sfn.ListStateMachinesOutput, lastPage bool) bool {
+		for _, machine := range page.StateMachines {
+			if aws.StringValue(machine.Name) == name {
+				arn = aws.StringValue(machine.StateMachineArn)
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to list state machines: %w", err)
+	}
+	return arn, nil
+}
@@ -0,0 +1,328 @@
+package takedown
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"shared/dynamo"
+	"shared/logger"
+)
+
+const (
+	// StatusDeleted is the processing_status written on soft-deleted papers
+	StatusDeleted = "deleted"
+	// StatusRestored is the processing_status written when a tombstone is lifted
+	StatusRestored = "processed"
+)
+
+// Config holds the table and bucket names touched by takedowns
+type Config struct {
+	PapersTable  string
+	VectorsTable string
+	// AuthorsTable and TraceIDIndex are only used by source purges; an empty AuthorsTable
+	// leaves author entities untouched
+	AuthorsTable string
+	TraceIDIndex string
+}
+
+// Tombstone represents a soft-deleted paper awaiting purge
+type Tombstone struct {
+	PaperID        string `dynamodbav:"paper_id"`
+	DeletedAt      string `dynamodbav:"deleted_at"`
+	DeletionReason string `dynamodbav:"deletion_reason"`
+	RawDataBucket  string `dynamodbav:"raw_data_bucket"`
+	RawDataKey     string `dynamodbav:"raw_data_key"`
+}
+
+// PurgeResult summarizes a purge run
+type PurgeResult struct {
+	Candidates      int      `json:"candidates"`
+	PapersPurged    int      `json:"papers_purged"`
+	VectorsDeleted  int      `json:"vectors_deleted"`
+	RawDataRewrites int      `json:"raw_data_rewrites"`
+	Skipped         int      `json:"skipped"`
+	DryRun          bool     `json:"dry_run"`
+	Errors          []string `json:"errors,omitempty"`
+}
+
+// Manager performs soft deletes, restores and purges of papers
+type Manager struct {
+	dynamoClient dynamodbiface.DynamoDBAPI
+	s3Client     s3iface.S3API
+	config       Config
+	logger       *logger.Logger
+}
+
+// NewManager creates a new takedown manager
+func NewManager(config Config) *Manager {
+	sess := session.Must(session.NewSession())
+	return &Manager{
+		dynamoClient: dynamodb.New(sess),
+		s3Client:     s3.New(sess),
+		config:       config,
+		logger:       logger.New("takedown"),
+	}
+}
+
+// NewManagerWithClients creates a takedown manager with custom clients (for testing)
+func NewManagerWithClients(dynamoClient dynamodbiface.DynamoDBAPI, s3Client s3iface.S3API, config Config) *Manager {
+	return &Manager{
+		dynamoClient: dynamoClient,
+		s3Client:     s3Client,
+		config:       config,
+		logger:       logger.New("takedown"),
+	}
+}
+
+// SoftDelete marks a paper as deleted without removing any data
+func (m *Manager) SoftDelete(ctx context.Context, paperID, reason string) error {
+	if paperID == "" {
+		return fmt.Errorf("paperID cannot be empty")
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String(m.config.PapersTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"paper_id": {S: aws.String(paperID)},
+		},
+		UpdateExpression:    aws.String("SET #del = :true, #dat = :now, #reason = :reason, #status = :status, #uat = :now"),
+		ConditionExpression: aws.String("attribute_exists(paper_id)"),
+		ExpressionAttributeNames: map[string]*string{
+			"#del":    aws.String("deleted"),
+			"#dat":    aws.String("deleted_at"),
+			"#reason": aws.String("deletion_reason"),
+			"#status": aws.String("processing_status"),
+			"#uat":    aws.String("updated_at"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":true":   {BOOL: aws.Bool(true)},
+			":now":    {S: aws.String(now)},
+			":reason": {S: aws.String(reason)},
+			":status": {S: aws.String(StatusDeleted)},
+		},
+	}
+
+	if _, err := m.dynamoClient.UpdateItemWithContext(ctx, input); err != nil {
+		return fmt.Errorf("failed to soft delete paper %s: %w", paperID, err)
+	}
+
+	m.logger.Info("Paper soft deleted", map[string]interface{}{
+		"paper_id": paperID,
+		"reason":   reason,
+	})
+	return nil
+}
+
+// Restore lifts the tombstone from a soft-deleted paper that has not been purged yet
+func (m *Manager) Restore(ctx context.Context, paperID string) error {
+	if paperID == "" {
+		return fmt.Errorf("paperID cannot be empty")
+	}
+
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String(m.config.PapersTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"paper_id": {S: aws.String(paperID)},
+		},
+		UpdateExpression:    aws.String("REMOVE #del, #dat, #reason SET #status = :status, #uat = :now"),
+		ConditionExpression: aws.String("#del = :true"),
+		ExpressionAttributeNames: map[string]*string{
+			"#del":    aws.String("deleted"),
+			"#dat":    aws.String("deleted_at"),
+			"#reason": aws.String("deletion_reason"),
+			"#status": aws.String("processing_status"),
+			"#uat":    aws.String("updated_at"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":true":   {BOOL: aws.Bool(true)},
+			":status": {S: aws.String(StatusRestored)},
+			":now":    {S: aws.String(time.Now().UTC().Format(time.RFC3339))},
+		},
+	}
+
+	if _, err := m.dynamoClient.UpdateItemWithContext(ctx, input); err != nil {
+		return fmt.Errorf("failed to restore paper %s: %w", paperID, err)
+	}
+
+	m.logger.Info("Paper restored", map[string]interface{}{
+		"paper_id": paperID,
+	})
+	return nil
+}
+
+// Purge permanently removes papers that were soft deleted more than gracePeriod ago,
+// together with their vectors and their entries in the raw-data objects
+func (m *Manager) Purge(ctx context.Context, gracePeriod time.Duration, dryRun bool) (*PurgeResult, error) {
+	tombstones, err := m.listTombstones(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PurgeResult{
+		Candidates: len(tombstones),
+		DryRun:     dryRun,
+	}
+	cutoff := time.Now().UTC().Add(-gracePeriod)
+
+	for _, tombstone := range tombstones {
+		deletedAt, err := time.Parse(time.RFC3339, tombstone.DeletedAt)
+		if err == nil && deletedAt.After(cutoff) {
+			result.Skipped++
+			continue
+		}
+
+		if dryRun {
+			m.logger.Info("Would purge paper", map[string]interface{}{
+				"paper_id":     tombstone.PaperID,
+				"deleted_at":   tombstone.DeletedAt,
+				"raw_data_key": tombstone.RawDataKey,
+			})
+			result.PapersPurged++
+			continue
+		}
+
+		if err := m.purgePaper(ctx, tombstone, result); err != nil {
+			m.logger.Error("Failed to purge paper", err, map[string]interface{}{
+				"paper_id": tombstone.PaperID,
+			})
+			result.Errors = append(result.Errors, err.Error())
+			continue
+		}
+		result.PapersPurged++
+	}
+
+	m.logger.Info("Purge completed", map[string]interface{}{
+		"candidates":        result.Candidates,
+		"papers_purged":     result.PapersPurged,
+		"vectors_deleted":   result.VectorsDeleted,
+		"raw_data_rewrites": result.RawDataRewrites,
+		"skipped":           result.Skipped,
+		"error_count":       len(result.Errors),
+		"dry_run":           dryRun,
+	})
+
+	return result, nil
+}
+
+// purgePaper removes dependent data first so a failed purge can be retried from the tombstone
+func (m *Manager) purgePaper(ctx context.Context, tombstone Tombstone, result *PurgeResult) error {
+	vectorsDeleted, err := m.deleteVectors(ctx, tombstone.PaperID)
+	result.VectorsDeleted += vectorsDeleted
+	if err != nil {
+		return err
+	}
+
+	if tombstone.RawDataBucket != "" && tombstone.RawDataKey != "" {
+		removed, err := m.removeFromRawData(ctx, tombstone.RawDataBucket, tombstone.RawDataKey, map[string]bool{tombstone.PaperID: true})
+		if err != nil {
+			return err
+		}
+		if removed > 0 {
+			result.RawDataRewrites++
+		}
+	}
+
+	_, err = m.dynamoClient.DeleteItemWithContext(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(m.config.PapersTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"paper_id": {S: aws.String(tombstone.PaperID)},
+		},
+		ConditionExpression: aws.String("deleted = :true"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":true": {BOOL: aws.Bool(true)},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete paper %s: %w", tombstone.PaperID, err)
+	}
+
+	m.logger.Info("Paper purged", map[string]interface{}{
+		"paper_id":     tombstone.PaperID,
+		"raw_data_key": tombstone.RawDataKey,
+	})
+	return nil
+}
+
+// listTombstones scans the papers table for soft-deleted items
+func (m *Manager) listTombstones(ctx context.Context) ([]Tombstone, error) {
+	var tombstones []Tombstone
+	input := &dynamodb.ScanInput{
+		TableName:            aws.String(m.config.PapersTable),
+		FilterExpression:     aws.String("#del = :true"),
+		ProjectionExpression: aws.String("paper_id, deleted_at, deletion_reason, raw_data_bucket, raw_data_key"),
+		ExpressionAttributeNames: map[string]*string{
+			"#del": aws.String("deleted"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":true": {BOOL: aws.Bool(true)},
+		},
+	}
+
+	_, err := dynamo.Paginate(ctx, dynamo.Scan(m.dynamoClient, input), dynamo.Options{}, func(page dynamo.Page) error {
+		var items []Tombstone
+		if err := dynamodbattribute.UnmarshalListOfMaps(page.Items, &items); err != nil {
+			return fmt.Errorf("failed to unmarshal tombstones: %w", err)
+		}
+		tombstones = append(tombstones, items...)
+		return nil
+	})
+	if err != nil {
+		var fetchErr *dynamo.FetchError
+		if errors.As(err, &fetchErr) {
+			return nil, fmt.Errorf("failed to scan for soft-deleted papers: %w", err)
+		}
+		return nil, err
+	}
+
+	return tombstones, nil
+}
+
+// deleteVectors removes every vector record stored for a paper
+func (m *Manager) deleteVectors(ctx context.Context, paperID string) (int, error) {
+	if m.config.VectorsTable == "" {
+		return 0, nil
+	}
+
+	deleted := 0
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(m.config.VectorsTable),
+		KeyConditionExpression: aws.String("paper_id = :paper_id"),
+		ProjectionExpression:   aws.String("paper_id, vector_type"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":paper_id": {S: aws.String(paperID)},
+		},
+	}
+
+	_, err := dynamo.Paginate(ctx, dynamo.Query(m.dynamoClient, input), dynamo.Options{}, func(page dynamo.Page) error {
+		for _, key := range page.Items {
+			_, err := m.dynamoClient.DeleteItemWithContext(ctx, &dynamodb.DeleteItemInput{
+				TableName: aws.String(m.config.VectorsTable),
+				Key:       key,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to delete vector for paper %s: %w", paperID, err)
+			}
+			deleted++
+		}
+		return nil
+	})
+	if err != nil {
+		var fetchErr *dynamo.FetchError
+		if errors.As(err, &fetchErr) {
+			return deleted, fmt.Errorf("failed to query vectors for paper %s: %w", paperID, err)
+		}
+		return deleted, err
+	}
+
+	return deleted, nil
+}
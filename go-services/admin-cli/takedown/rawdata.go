@@ -0,0 +1,384 @@
+package takedown
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/klauspost/compress/zstd"
+)
+
+// paperRef extracts the identifier fields shared by collector and processor payloads
+type paperRef struct {
+	ID      string `json:"id"`
+	PaperID string `json:"paper_id"`
+}
+
+// removeFromRawData rewrites a raw-data object without the given papers and returns how many
+// entries it removed. Raw objects hold whole collection batches, so the object is filtered
+// rather than deleted.
+func (m *Manager) removeFromRawData(ctx context.Context, bucket, key string, paperIDs map[string]bool) (int, error) {
+	output, err := m.s3Client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		if isNotFoundError(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to download raw data %s/%s: %w", bucket, key, err)
+	}
+	defer output.Body.Close()
+
+	raw, err := io.ReadAll(output.Body)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read raw data %s/%s: %w", bucket, key, err)
+	}
+
+	codec := compressionOf(raw)
+	data := raw
+	switch codec {
+	case compressionGzip:
+		data, err = gunzip(raw)
+	case compressionZstd:
+		data, err = unzstd(raw)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to decompress raw data %s/%s: %w", bucket, key, err)
+	}
+
+	filter := filterPayload
+	if metadataValue(output.Metadata, schemaVersionMetadataKey) == collectionLinesSchemaVersion {
+		filter = filterCollectionLines
+	}
+	filtered, removed, remaining, err := filter(data, paperIDs)
+	if err != nil {
+		return 0, fmt.Errorf("failed to filter raw data %s/%s: %w", bucket, key, err)
+	}
+	if removed == 0 {
+		return 0, nil
+	}
+
+	body := filtered
+	switch codec {
+	case compressionGzip:
+		body, err = gzipBytes(filtered)
+	case compressionZstd:
+		body, err = zstdBytes(filtered)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to compress raw data %s/%s: %w", bucket, key, err)
+	}
+
+	metadata := output.Metadata
+	if metadata == nil {
+		metadata = map[string]*string{}
+	}
+	setMetadata(metadata, "paper-count", fmt.Sprintf("%d", remaining))
+	// Keep the payload checksum in step with the rewritten payload so the batch processor's
+	// integrity check still passes on reprocessing
+	if hasMetadata(metadata, payloadChecksumMetadataKey) {
+		checksum := sha256.Sum256(filtered)
+		setMetadata(metadata, payloadChecksumMetadataKey, hex.EncodeToString(checksum[:]))
+	}
+
+	tagging, err := m.objectTagging(ctx, bucket, key)
+	if err != nil {
+		return 0, err
+	}
+
+	// The rewrite replaces the object, so it carries over the original's encryption, storage
+	// class and tags; otherwise a takedown would leave the remaining papers unencrypted
+	_, err = m.s3Client.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket:               aws.String(bucket),
+		Key:                  aws.String(key),
+		Body:                 bytes.NewReader(body),
+		ContentType:          output.ContentType,
+		ContentEncoding:      output.ContentEncoding,
+		Metadata:             metadata,
+		ServerSideEncryption: output.ServerSideEncryption,
+		SSEKMSKeyId:          output.SSEKMSKeyId,
+		BucketKeyEnabled:     output.BucketKeyEnabled,
+		StorageClass:         output.StorageClass,
+		Tagging:              tagging,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to rewrite raw data %s/%s: %w", bucket, key, err)
+	}
+
+	m.logger.Info("Removed papers from raw data", map[string]interface{}{
+		"papers_removed":   removed,
+		"bucket":           bucket,
+		"key":              key,
+		"remaining_papers": remaining,
+	})
+	return removed, nil
+}
+
+// objectTagging returns the URL-encoded tag set of an object, nil when it has no tags
+func (m *Manager) objectTagging(ctx context.Context, bucket, key string) (*string, error) {
+	output, err := m.s3Client.GetObjectTaggingWithContext(ctx, &s3.GetObjectTaggingInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tags of raw data %s/%s: %w", bucket, key, err)
+	}
+	if len(output.TagSet) == 0 {
+		return nil, nil
+	}
+	tags := url.Values{}
+	for _, tag := range output.TagSet {
+		tags.Set(aws.StringValue(tag.Key), aws.StringValue(tag.Value))
+	}
+	return aws.String(tags.Encode()), nil
+}
+
+// payloadChecksumMetadataKey holds the hex SHA-256 of the uncompressed payload, set by the data collector
+const payloadChecksumMetadataKey = "payload-sha256"
+
+// schemaVersionMetadataKey names the payload schema; collectionLinesSchemaVersion is the
+// JSON-lines stream the data collector writes for large results, whose checksum is in its
+// trailer line rather than in the metadata
+const (
+	schemaVersionMetadataKey     = "schema-version"
+	collectionLinesSchemaVersion = "2"
+)
+
+// metadataValue looks up a metadata value case-insensitively, "" when it is not set
+func metadataValue(metadata map[string]*string, key string) string {
+	for existing, value := range metadata {
+		if strings.EqualFold(existing, key) {
+			return strings.TrimSpace(aws.StringValue(value))
+		}
+	}
+	return ""
+}
+
+// hasMetadata reports whether the metadata holds the key in any case; S3 returns
+// canonicalized keys (e.g. "Paper-Count")
+func hasMetadata(metadata map[string]*string, key string) bool {
+	for existing := range metadata {
+		if strings.EqualFold(existing, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// setMetadata replaces every case variant of the key with a single value
+func setMetadata(metadata map[string]*string, key, value string) {
+	for existing := range metadata {
+		if strings.EqualFold(existing, key) {
+			delete(metadata, existing)
+		}
+	}
+	metadata[key] = aws.String(value)
+}
+
+// filterPayload drops the papers from a collection result object, a JSON array or NDJSON
+func filterPayload(data []byte, paperIDs map[string]bool) ([]byte, int, int, error) {
+	trimmed := bytes.TrimSpace(data)
+
+	// Collection result object with a "papers" array
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(trimmed, &object); err == nil {
+			if papersData, ok := object["papers"]; ok {
+				var papers []json.RawMessage
+				if err := json.Unmarshal(papersData, &papers); err != nil {
+					return nil, 0, 0, fmt.Errorf("invalid papers array: %w", err)
+				}
+				kept, removed := filterEntries(papers, paperIDs)
+				if removed == 0 {
+					return data, 0, len(kept), nil
+				}
+				if object["papers"], err = json.Marshal(kept); err != nil {
+					return nil, 0, 0, err
+				}
+				if _, ok := object["count"]; ok {
+					object["count"] = json.RawMessage(fmt.Sprintf("%d", len(kept)))
+				}
+				out, err := json.Marshal(object)
+				return out, removed, len(kept), err
+			}
+		}
+	}
+
+	// Plain JSON array
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		var papers []json.RawMessage
+		if err := json.Unmarshal(trimmed, &papers); err != nil {
+			return nil, 0, 0, fmt.Errorf("invalid JSON array: %w", err)
+		}
+		kept, removed := filterEntries(papers, paperIDs)
+		if removed == 0 {
+			return data, 0, len(kept), nil
+		}
+		out, err := json.Marshal(kept)
+		return out, removed, len(kept), err
+	}
+
+	// Newline-delimited JSON
+	var lines []string
+	removed := 0
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if matchesPaper(json.RawMessage(line), paperIDs) {
+			removed++
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if removed == 0 {
+		return data, 0, len(lines), nil
+	}
+	return []byte(strings.Join(lines, "\n") + "\n"), removed, len(lines), nil
+}
+
+// filterCollectionLines drops the papers from a schema 2 payload, a header line, one paper
+// per line and a trailer, rewriting the header count and the trailer checksum to match
+func filterCollectionLines(data []byte, paperIDs map[string]bool) ([]byte, int, int, error) {
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	if len(lines) < 2 {
+		return nil, 0, 0, fmt.Errorf("collection stream has no header and trailer")
+	}
+
+	var papers []json.RawMessage
+	for _, line := range lines[1 : len(lines)-1] {
+		papers = append(papers, json.RawMessage(line))
+	}
+	kept, removed := filterEntries(papers, paperIDs)
+	if removed == 0 {
+		return data, 0, len(kept), nil
+	}
+
+	var header map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(lines[0]), &header); err != nil {
+		return nil, 0, 0, fmt.Errorf("invalid stream header: %w", err)
+	}
+	header["count"] = json.RawMessage(fmt.Sprintf("%d", len(kept)))
+	headerLine, err := json.Marshal(header)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	var buf bytes.Buffer
+	buf.Write(headerLine)
+	buf.WriteByte('\n')
+	for _, paper := range kept {
+		buf.Write(paper)
+		buf.WriteByte('\n')
+	}
+	checksum := sha256.Sum256(buf.Bytes())
+	trailer, err := json.Marshal(map[string]interface{}{
+		"paper_count":    len(kept),
+		"payload_sha256": hex.EncodeToString(checksum[:]),
+	})
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	buf.Write(trailer)
+	buf.WriteByte('\n')
+	return buf.Bytes(), removed, len(kept), nil
+}
+
+// filterEntries returns the entries that do not belong to any of paperIDs
+func filterEntries(entries []json.RawMessage, paperIDs map[string]bool) ([]json.RawMessage, int) {
+	kept := make([]json.RawMessage, 0, len(entries))
+	removed := 0
+	for _, entry := range entries {
+		if matchesPaper(entry, paperIDs) {
+			removed++
+			continue
+		}
+		kept = append(kept, entry)
+	}
+	return kept, removed
+}
+
+// matchesPaper checks whether a raw entry carries one of the paper identifiers
+func matchesPaper(entry json.RawMessage, paperIDs map[string]bool) bool {
+	var ref paperRef
+	if err := json.Unmarshal(entry, &ref); err != nil {
+		return false
+	}
+	return paperIDs[ref.PaperID] || paperIDs[ref.ID]
+}
+
+// Codecs the data collector writes raw data with
+const (
+	compressionNone = ""
+	compressionGzip = "gzip"
+	compressionZstd = "zstd"
+)
+
+// compressionOf detects the codec of a raw-data object from its magic bytes
+func compressionOf(data []byte) string {
+	switch {
+	case len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b:
+		return compressionGzip
+	case len(data) >= 4 && data[0] == 0x28 && data[1] == 0xb5 && data[2] == 0x2f && data[3] == 0xfd:
+		return compressionZstd
+	}
+	return compressionNone
+}
+
+// unzstd decompresses zstd data
+func unzstd(data []byte) ([]byte, error) {
+	decoder, err := zstd.NewReader(nil)
+	if err != nil {
+		return nil, err
+	}
+	defer decoder.Close()
+	return decoder.DecodeAll(data, nil)
+}
+
+// zstdBytes compresses data using zstd
+func zstdBytes(data []byte) ([]byte, error) {
+	encoder, err := zstd.NewWriter(nil)
+	if err != nil {
+		return nil, err
+	}
+	defer encoder.Close()
+	return encoder.EncodeAll(data, nil), nil
+}
+
+// gunzip decompresses gzip data
+func gunzip(data []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// gzipBytes compresses data using gzip
+func gzipBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// isNotFoundError checks if the error is a NoSuchKey error
+func isNotFoundError(err error) bool {
+	return err != nil && (strings.Contains(err.Error(), "NoSuchKey") || strings.Contains(err.Error(), "NotFound"))
+}
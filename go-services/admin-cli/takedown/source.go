@@ -0,0 +1,350 @@
+package takedown
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"shared/dynamo"
+)
+
+// Selector picks the papers of a bulk purge: every paper of a source, or of one trace
+type Selector struct {
+	Source  string
+	TraceID string
+}
+
+// Validate checks that exactly one of source and trace ID is set
+func (s Selector) Validate() error {
+	if (s.Source == "") == (s.TraceID == "") {
+		return fmt.Errorf("exactly one of source and trace ID is required")
+	}
+	return nil
+}
+
+// SourcePaper is a paper selected by a bulk purge
+type SourcePaper struct {
+	PaperID       string   `dynamodbav:"paper_id" json:"paper_id"`
+	Title         string   `dynamodbav:"title" json:"title"`
+	Source        string   `dynamodbav:"source" json:"source"`
+	TraceID       string   `dynamodbav:"trace_id" json:"trace_id"`
+	RawDataBucket string   `dynamodbav:"raw_data_bucket" json:"raw_data_bucket,omitempty"`
+	RawDataKey    string   `dynamodbav:"raw_data_key" json:"raw_data_key,omitempty"`
+	FullTextKey   string   `dynamodbav:"full_text_key" json:"full_text_key,omitempty"`
+	AuthorIDs     []string `dynamodbav:"author_ids" json:"author_ids,omitempty"`
+}
+
+// SourcePurgeResult summarizes a bulk purge. The search index is built from the vectors
+// table, so purged papers leave search results at its next rebuild.
+type SourcePurgeResult struct {
+	Source          string        `json:"source,omitempty"`
+	TraceID         string        `json:"trace_id,omitempty"`
+	Candidates      int           `json:"candidates"`
+	PapersPurged    int           `json:"papers_purged"`
+	VectorsDeleted  int           `json:"vectors_deleted"`
+	RawDataRewrites int           `json:"raw_data_rewrites"`
+	RawDataRemoved  int           `json:"raw_data_entries_removed"`
+	FullTextDeleted int           `json:"full_text_deleted"`
+	AuthorsUpdated  int           `json:"authors_updated"`
+	DryRun          bool          `json:"dry_run"`
+	Papers          []SourcePaper `json:"papers,omitempty"` // the selected papers, listed on dry runs
+	Errors          []string      `json:"errors,omitempty"`
+}
+
+// sourcePaperAttributes are the paper attributes a bulk purge reads
+var sourcePaperAttributes = []string{"paper_id", "title", "source", "trace_id", "raw_data_bucket", "raw_data_key", "full_text_key", "author_ids"}
+
+// PurgeSource permanently removes the selected papers together with their vectors, full
+// text, author links and raw-data entries, for junk ingested by a misconfigured source.
+// Unlike Purge it does not wait for a tombstone. Raw-data objects are rewritten once for all
+// of their selected papers, and each paper item is deleted last, so a failed run can be
+// repeated with the same selector.
+func (m *Manager) PurgeSource(ctx context.Context, selector Selector, dryRun bool) (*SourcePurgeResult, error) {
+	if err := selector.Validate(); err != nil {
+		return nil, err
+	}
+
+	papers, err := m.selectPapers(ctx, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SourcePurgeResult{
+		Source:     selector.Source,
+		TraceID:    selector.TraceID,
+		Candidates: len(papers),
+		DryRun:     dryRun,
+	}
+	if dryRun {
+		result.Papers = papers
+		m.logger.Info("Would purge papers", map[string]interface{}{
+			"source":     selector.Source,
+			"trace_id":   selector.TraceID,
+			"candidates": len(papers),
+		})
+		return result, nil
+	}
+
+	// Raw-data entries go first and per object; a paper whose object failed to rewrite is kept,
+	// so the selector still finds it on the next run
+	failedObjects := m.removeSelectedFromRawData(ctx, papers, result)
+
+	for _, paper := range papers {
+		if failedObjects[rawDataObject(paper)] {
+			continue
+		}
+		if err := m.purgeSelectedPaper(ctx, paper, result); err != nil {
+			m.logger.Error("Failed to purge paper", err, map[string]interface{}{
+				"paper_id": paper.PaperID,
+			})
+			result.Errors = append(result.Errors, err.Error())
+			continue
+		}
+		result.PapersPurged++
+	}
+
+	m.logger.Info("Source purge completed", map[string]interface{}{
+		"source":                   selector.Source,
+		"trace_id":                 selector.TraceID,
+		"candidates":               result.Candidates,
+		"papers_purged":            result.PapersPurged,
+		"vectors_deleted":          result.VectorsDeleted,
+		"raw_data_rewrites":        result.RawDataRewrites,
+		"raw_data_entries_removed": result.RawDataRemoved,
+		"full_text_deleted":        result.FullTextDeleted,
+		"authors_updated":          result.AuthorsUpdated,
+		"error_count":              len(result.Errors),
+	})
+	return result, nil
+}
+
+// removeSelectedFromRawData rewrites each raw-data object without its selected papers and
+// returns the objects that failed
+func (m *Manager) removeSelectedFromRawData(ctx context.Context, papers []SourcePaper, result *SourcePurgeResult) map[string]bool {
+	byObject := make(map[string]map[string]bool)
+	for _, paper := range papers {
+		object := rawDataObject(paper)
+		if object == "" {
+			continue
+		}
+		if byObject[object] == nil {
+			byObject[object] = make(map[string]bool)
+		}
+		byObject[object][paper.PaperID] = true
+	}
+
+	objects := make([]string, 0, len(byObject))
+	for object := range byObject {
+		objects = append(objects, object)
+	}
+	sort.Strings(objects)
+
+	failed := make(map[string]bool)
+	for _, object := range objects {
+		bucket, key, _ := strings.Cut(object, "/")
+		removed, err := m.removeFromRawData(ctx, bucket, key, byObject[object])
+		if err != nil {
+			m.logger.Error("Failed to remove papers from raw data", err, map[string]interface{}{
+				"bucket": bucket,
+				"key":    key,
+				"papers": len(byObject[object]),
+			})
+			result.Errors = append(result.Errors, err.Error())
+			failed[object] = true
+			continue
+		}
+		if removed > 0 {
+			result.RawDataRewrites++
+			result.RawDataRemoved += removed
+		}
+	}
+	return failed
+}
+
+// rawDataObject returns bucket/key of the paper's raw-data object, empty when unknown
+func rawDataObject(paper SourcePaper) string {
+	if paper.RawDataBucket == "" || paper.RawDataKey == "" {
+		return ""
+	}
+	return paper.RawDataBucket + "/" + paper.RawDataKey
+}
+
+// purgeSelectedPaper removes a paper's dependent data, then the paper item
+func (m *Manager) purgeSelectedPaper(ctx context.Context, paper SourcePaper, result *SourcePurgeResult) error {
+	vectorsDeleted, err := m.deleteVectors(ctx, paper.PaperID)
+	result.VectorsDeleted += vectorsDeleted
+	if err != nil {
+		return err
+	}
+
+	if paper.FullTextKey != "" {
+		if err := m.deleteFullText(ctx, paper.FullTextKey); err != nil {
+			return fmt.Errorf("paper %s: %w", paper.PaperID, err)
+		}
+		result.FullTextDeleted++
+	}
+
+	updated, err := m.unlinkAuthors(ctx, paper)
+	result.AuthorsUpdated += updated
+	if err != nil {
+		return err
+	}
+
+	_, err = m.dynamoClient.DeleteItemWithContext(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(m.config.PapersTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"paper_id": {S: aws.String(paper.PaperID)},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete paper %s: %w", paper.PaperID, err)
+	}
+
+	m.logger.Info("Paper purged", map[string]interface{}{
+		"paper_id":     paper.PaperID,
+		"source":       paper.Source,
+		"trace_id":     paper.TraceID,
+		"raw_data_key": paper.RawDataKey,
+	})
+	return nil
+}
+
+// deleteFullText deletes the extracted text at an s3://bucket/key location
+func (m *Manager) deleteFullText(ctx context.Context, location string) error {
+	bucket, key, ok := strings.Cut(strings.TrimPrefix(location, "s3://"), "/")
+	if !ok || !strings.HasPrefix(location, "s3://") || bucket == "" || key == "" {
+		return fmt.Errorf("invalid full text location %q", location)
+	}
+	_, err := m.s3Client.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete full text %s: %w", location, err)
+	}
+	return nil
+}
+
+// unlinkAuthors removes the paper from the paper_ids of its author entities
+func (m *Manager) unlinkAuthors(ctx context.Context, paper SourcePaper) (int, error) {
+	if m.config.AuthorsTable == "" {
+		return 0, nil
+	}
+
+	updated := 0
+	for _, authorID := range uniqueStrings(paper.AuthorIDs) {
+		_, err := m.dynamoClient.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+			TableName: aws.String(m.config.AuthorsTable),
+			Key: map[string]*dynamodb.AttributeValue{
+				"author_id": {S: aws.String(authorID)},
+			},
+			UpdateExpression:    aws.String("DELETE #pids :pids"),
+			ConditionExpression: aws.String("attribute_exists(author_id)"),
+			ExpressionAttributeNames: map[string]*string{
+				"#pids": aws.String("paper_ids"),
+			},
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":pids": {SS: aws.StringSlice([]string{paper.PaperID})},
+			},
+		})
+		if err != nil {
+			if isConditionalCheckFailed(err) {
+				continue
+			}
+			return updated, fmt.Errorf("failed to unlink paper %s from author %s: %w", paper.PaperID, authorID, err)
+		}
+		updated++
+	}
+	return updated, nil
+}
+
+// selectPapers lists the papers of a source with a filtered scan, or of a trace through the
+// trace-id index
+func (m *Manager) selectPapers(ctx context.Context, selector Selector) ([]SourcePaper, error) {
+	names := make(map[string]*string, len(sourcePaperAttributes))
+	placeholders := make([]string, len(sourcePaperAttributes))
+	for i, attribute := range sourcePaperAttributes {
+		placeholder := fmt.Sprintf("#a%d", i)
+		names[placeholder] = aws.String(attribute)
+		placeholders[i] = placeholder
+	}
+	projection := aws.String(strings.Join(placeholders, ", "))
+
+	var papers []SourcePaper
+	appendPage := func(page dynamo.Page) error {
+		var items []SourcePaper
+		if err := dynamodbattribute.UnmarshalListOfMaps(page.Items, &items); err != nil {
+			return fmt.Errorf("failed to unmarshal papers: %w", err)
+		}
+		papers = append(papers, items...)
+		return nil
+	}
+
+	if selector.TraceID != "" {
+		if m.config.TraceIDIndex == "" {
+			return nil, fmt.Errorf("trace ID index name is required to purge by trace")
+		}
+		names["#tid"] = aws.String("trace_id")
+		input := &dynamodb.QueryInput{
+			TableName:                aws.String(m.config.PapersTable),
+			IndexName:                aws.String(m.config.TraceIDIndex),
+			KeyConditionExpression:   aws.String("#tid = :tid"),
+			ProjectionExpression:     projection,
+			ExpressionAttributeNames: names,
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":tid": {S: aws.String(selector.TraceID)},
+			},
+		}
+		if _, err := dynamo.Paginate(ctx, dynamo.Query(m.dynamoClient, input), dynamo.Options{}, appendPage); err != nil {
+			var fetchErr *dynamo.FetchError
+			if errors.As(err, &fetchErr) {
+				return nil, fmt.Errorf("failed to query papers of trace %s: %w", selector.TraceID, err)
+			}
+			return nil, err
+		}
+		return papers, nil
+	}
+
+	names["#src"] = aws.String("source")
+	input := &dynamodb.ScanInput{
+		TableName:                aws.String(m.config.PapersTable),
+		FilterExpression:         aws.String("#src = :src"),
+		ProjectionExpression:     projection,
+		ExpressionAttributeNames: names,
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":src": {S: aws.String(selector.Source)},
+		},
+	}
+	if _, err := dynamo.Paginate(ctx, dynamo.Scan(m.dynamoClient, input), dynamo.Options{}, appendPage); err != nil {
+		var fetchErr *dynamo.FetchError
+		if errors.As(err, &fetchErr) {
+			return nil, fmt.Errorf("failed to scan for papers of source %s: %w", selector.Source, err)
+		}
+		return nil, err
+	}
+	return papers, nil
+}
+
+// uniqueStrings returns values without duplicates or empty strings, in order
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	unique := make([]string, 0, len(values))
+	for _, value := range values {
+		if value != "" && !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
+
+// isConditionalCheckFailed reports whether the update's condition did not hold
+func isConditionalCheckFailed(err error) bool {
+	return err != nil && strings.Contains(err.Error(), dynamodb.ErrCodeConditionalCheckFailedException)
+}
@@ -0,0 +1,281 @@
+// Package traceindex verifies and repairs the Papers attributes keyed by the trace-id GSI.
+// Records ingested before trace_id and batch_timestamp existed are missing from the index,
+// so the coordinator's trace queries silently skip them until the attributes are backfilled.
+package traceindex
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"shared/dynamo"
+	"shared/logger"
+)
+
+// Key attributes of the trace-id GSI, as written by the batch processor
+const (
+	TraceIDAttribute        = "trace_id"
+	BatchTimestampAttribute = "batch_timestamp"
+)
+
+// maxSamplePaperIDs limits the paper IDs listed per problem in a report
+const maxSamplePaperIDs = 20
+
+// Config names the table and index to repair and the values used for backfills
+type Config struct {
+	PapersTable string
+	IndexName   string
+	// BackfillTraceID is written to papers without a trace ID, so they can be vectorized as one trace
+	BackfillTraceID string
+	DryRun          bool
+}
+
+// IndexStatus describes the GSI as reported by DescribeTable
+type IndexStatus struct {
+	Name           string `json:"name"`
+	Status         string `json:"status"`
+	ProjectionType string `json:"projection_type"`
+	ItemCount      int64  `json:"item_count"`
+	KeySchemaValid bool   `json:"key_schema_valid"` // partition key trace_id, sort key batch_timestamp
+}
+
+// Report summarizes a verify/repair run
+type Report struct {
+	Index                  IndexStatus `json:"index"`
+	Scanned                int         `json:"scanned"`
+	MissingTraceID         int         `json:"missing_trace_id"`
+	MissingBatchTimestamp  int         `json:"missing_batch_timestamp"`
+	InvalidBatchTimestamp  int         `json:"invalid_batch_timestamp"` // not an RFC 3339 string, so unordered or unindexed
+	Repaired               int         `json:"repaired"`
+	Conflicts              int         `json:"conflicts"` // papers rewritten by the pipeline during the scan; left untouched
+	BackfillTraceID        string      `json:"backfill_trace_id"`
+	DryRun                 bool        `json:"dry_run"`
+	SampleMissingTraceID   []string    `json:"sample_missing_trace_id,omitempty"`
+	SampleInvalidTimestamp []string    `json:"sample_invalid_timestamp,omitempty"`
+	Errors                 []string    `json:"errors,omitempty"`
+}
+
+// Repairer scans the Papers table and backfills the GSI key attributes
+type Repairer struct {
+	client dynamodbiface.DynamoDBAPI
+	config Config
+	logger *logger.Logger
+	now    func() time.Time
+}
+
+// NewRepairer creates a repairer for the configured table
+func NewRepairer(config Config) *Repairer {
+	sess := session.Must(session.NewSession())
+	return NewRepairerWithClient(dynamodb.New(sess), config)
+}
+
+// NewRepairerWithClient creates a repairer with a custom DynamoDB client (for testing)
+func NewRepairerWithClient(client dynamodbiface.DynamoDBAPI, config Config) *Repairer {
+	return &Repairer{
+		client: client,
+		config: config,
+		logger: logger.New("trace-index-repair"),
+		now:    time.Now,
+	}
+}
+
+// Run checks the index definition, then scans every paper and repairs missing or invalid
+// trace_id and batch_timestamp attributes. Each update is conditioned on the attributes
+// still holding the values scanned, so papers re-ingested meanwhile are not overwritten.
+func (r *Repairer) Run(ctx context.Context) (*Report, error) {
+	if r.config.PapersTable == "" || r.config.IndexName == "" {
+		return nil, fmt.Errorf("papers table and index name are required")
+	}
+
+	report := &Report{
+		BackfillTraceID: r.config.BackfillTraceID,
+		DryRun:          r.config.DryRun,
+	}
+	if report.BackfillTraceID == "" {
+		report.BackfillTraceID = "backfill-" + r.now().UTC().Format("20060102-150405")
+	}
+
+	index, err := r.describeIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+	report.Index = *index
+	if !index.KeySchemaValid {
+		r.logger.Warn("Trace index keys do not match trace_id/batch_timestamp", map[string]interface{}{
+			"index": index.Name,
+		})
+	}
+
+	input := &dynamodb.ScanInput{
+		TableName:            aws.String(r.config.PapersTable),
+		ProjectionExpression: aws.String("paper_id, #tid, #bts, created_at, updated_at"),
+		ExpressionAttributeNames: map[string]*string{
+			"#tid": aws.String(TraceIDAttribute),
+			"#bts": aws.String(BatchTimestampAttribute),
+		},
+	}
+
+	result, err := dynamo.Paginate(ctx, dynamo.Scan(r.client, input), dynamo.Options{}, func(page dynamo.Page) error {
+		for _, item := range page.Items {
+			report.Scanned++
+			r.checkItem(ctx, item, report)
+		}
+
+		r.logger.Info("Trace index scan progress", map[string]interface{}{
+			"page":     page.Number,
+			"scanned":  report.Scanned,
+			"repaired": report.Repaired,
+		})
+		return nil
+	})
+	if err != nil {
+		return report, fmt.Errorf("failed to scan papers: %w", err)
+	}
+	r.logger.Info("Trace index scan completed", map[string]interface{}{
+		"pages":             result.Pages,
+		"consumed_capacity": result.ConsumedCapacity,
+	})
+
+	return report, nil
+}
+
+// describeIndex reads the GSI definition and status
+func (r *Repairer) describeIndex(ctx context.Context) (*IndexStatus, error) {
+	output, err := r.client.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{
+		TableName: aws.String(r.config.PapersTable),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe table %s: %w", r.config.PapersTable, err)
+	}
+
+	for _, index := range output.Table.GlobalSecondaryIndexes {
+		if aws.StringValue(index.IndexName) != r.config.IndexName {
+			continue
+		}
+		status := &IndexStatus{
+			Name:      r.config.IndexName,
+			Status:    aws.StringValue(index.IndexStatus),
+			ItemCount: aws.Int64Value(index.ItemCount),
+		}
+		if index.Projection != nil {
+			status.ProjectionType = aws.StringValue(index.Projection.ProjectionType)
+		}
+		keys := make(map[string]string)
+		for _, key := range index.KeySchema {
+			keys[aws.StringValue(key.KeyType)] = aws.StringValue(key.AttributeName)
+		}
+		status.KeySchemaValid = keys[dynamodb.KeyTypeHash] == TraceIDAttribute && keys[dynamodb.KeyTypeRange] == BatchTimestampAttribute
+		return status, nil
+	}
+	return nil, fmt.Errorf("table %s has no global secondary index %s", r.config.PapersTable, r.config.IndexName)
+}
+
+// checkItem classifies one scanned paper and repairs it unless this is a dry run
+func (r *Repairer) checkItem(ctx context.Context, item map[string]*dynamodb.AttributeValue, report *Report) {
+	paperID := aws.StringValue(item["paper_id"].S)
+	traceID := item[TraceIDAttribute]
+	batchTimestamp := item[BatchTimestampAttribute]
+
+	fixTraceID := !validTraceID(traceID)
+	fixTimestamp := false
+	switch {
+	case batchTimestamp == nil || (batchTimestamp.S != nil && *batchTimestamp.S == ""):
+		report.MissingBatchTimestamp++
+		fixTimestamp = true
+	case !validTimestamp(batchTimestamp):
+		report.InvalidBatchTimestamp++
+		fixTimestamp = true
+		if len(report.SampleInvalidTimestamp) < maxSamplePaperIDs {
+			report.SampleInvalidTimestamp = append(report.SampleInvalidTimestamp, paperID)
+		}
+	}
+	if fixTraceID {
+		report.MissingTraceID++
+		if len(report.SampleMissingTraceID) < maxSamplePaperIDs {
+			report.SampleMissingTraceID = append(report.SampleMissingTraceID, paperID)
+		}
+	}
+	if (!fixTraceID && !fixTimestamp) || r.config.DryRun {
+		return
+	}
+
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String(r.config.PapersTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"paper_id": {S: aws.String(paperID)},
+		},
+		ExpressionAttributeNames:  map[string]*string{},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{},
+	}
+	var sets, conditions []string
+	if fixTraceID {
+		input.ExpressionAttributeNames["#tid"] = aws.String(TraceIDAttribute)
+		input.ExpressionAttributeValues[":tid"] = &dynamodb.AttributeValue{S: aws.String(report.BackfillTraceID)}
+		sets = append(sets, "#tid = :tid")
+		conditions = append(conditions, unchangedCondition("#tid", ":old_tid", traceID, input))
+	}
+	if fixTimestamp {
+		input.ExpressionAttributeNames["#bts"] = aws.String(BatchTimestampAttribute)
+		input.ExpressionAttributeValues[":bts"] = &dynamodb.AttributeValue{S: aws.String(r.backfillTimestamp(item))}
+		sets = append(sets, "#bts = :bts")
+		conditions = append(conditions, unchangedCondition("#bts", ":old_bts", batchTimestamp, input))
+	}
+	input.UpdateExpression = aws.String("SET " + strings.Join(sets, ", "))
+	input.ConditionExpression = aws.String(strings.Join(conditions, " AND "))
+
+	if _, err := r.client.UpdateItemWithContext(ctx, input); err != nil {
+		if isConditionalCheckFailed(err) {
+			report.Conflicts++
+			return
+		}
+		report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", paperID, err))
+		return
+	}
+	report.Repaired++
+}
+
+// backfillTimestamp picks the paper's created_at, then updated_at, then the current time
+func (r *Repairer) backfillTimestamp(item map[string]*dynamodb.AttributeValue) string {
+	for _, name := range []string{"created_at", "updated_at"} {
+		if value := item[name]; validTimestamp(value) {
+			parsed, _ := time.Parse(time.RFC3339, *value.S)
+			return parsed.UTC().Format(time.RFC3339)
+		}
+	}
+	return r.now().UTC().Format(time.RFC3339)
+}
+
+// unchangedCondition requires the attribute to still hold the scanned value (or still be absent)
+func unchangedCondition(name, placeholder string, scanned *dynamodb.AttributeValue, input *dynamodb.UpdateItemInput) string {
+	if scanned == nil {
+		return fmt.Sprintf("attribute_not_exists(%s)", name)
+	}
+	input.ExpressionAttributeValues[placeholder] = scanned
+	return fmt.Sprintf("%s = %s", name, placeholder)
+}
+
+// validTraceID reports whether a trace_id can key the index: a non-empty string
+func validTraceID(value *dynamodb.AttributeValue) bool {
+	return value != nil && value.S != nil && *value.S != ""
+}
+
+// validTimestamp reports whether a value is an RFC 3339 string, which sorts chronologically
+func validTimestamp(value *dynamodb.AttributeValue) bool {
+	if value == nil || value.S == nil {
+		return false
+	}
+	_, err := time.Parse(time.RFC3339, *value.S)
+	return err == nil
+}
+
+// isConditionalCheckFailed reports whether an update lost its condition
+func isConditionalCheckFailed(err error) bool {
+	aerr, ok := err.(awserr.Error)
+	return ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException
+}
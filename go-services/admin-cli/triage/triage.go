@@ -0,0 +1,310 @@
+// Package triage lists the most recent pipeline failures for on-call. Each failed record in
+// the FailedItems table is reported with its stage, error code, the outcome of its run from
+// the ProcessingRuns table and the command that replays it, so triage starts from one query
+// instead of a search through every service's logs.
+package triage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"shared/dynamo"
+	"shared/failures"
+	"shared/logger"
+)
+
+// FailedItems attributes, as shared/failures records them
+const (
+	attributeStage      = "stage"
+	attributeCode       = "code"
+	attributeLastFailed = "last_failed_at"
+)
+
+// ProcessingRuns key attribute, as the batch processor records runs
+const runsAttributeTraceID = "trace_id"
+
+// maxPagesPerCode bounds the code-index pages read for one code when a stage filter skips
+// most of its items
+const maxPagesPerCode = 20
+
+// errEnough stops a pagination once a code has contributed enough items
+var errEnough = errors.New("enough items")
+
+// Config selects the failures to list
+type Config struct {
+	FailedItemsTable string
+	RunsTable        string // ProcessingRuns table; empty skips the run lookup
+	Limit            int
+	Since            time.Time // zero lists failures of any age
+	Stage            string    // empty lists every stage
+	Codes            []string  // empty lists every code
+	// CollectorFunction is the data collector's Lambda function, named in collection replays
+	CollectorFunction string
+}
+
+// Failure is one failed record, newest failure first in a report
+type Failure struct {
+	Stage         string `json:"stage" dynamodbav:"stage"`
+	RecordID      string `json:"record_id" dynamodbav:"record_id"`
+	TraceID       string `json:"trace_id,omitempty" dynamodbav:"trace_id"`
+	Code          string `json:"code" dynamodbav:"code"`
+	Message       string `json:"message,omitempty" dynamodbav:"message"`
+	RetryCount    int    `json:"retry_count" dynamodbav:"retry_count"`
+	FirstFailedAt string `json:"first_failed_at" dynamodbav:"first_failed_at"`
+	LastFailedAt  string `json:"last_failed_at" dynamodbav:"last_failed_at"`
+	Run           *Run   `json:"run,omitempty" dynamodbav:"-"`
+	Replay        string `json:"replay,omitempty" dynamodbav:"-"` // suggested command that retries the record
+}
+
+// Run is the batch processor's recorded outcome of a failure's trace
+type Run struct {
+	Status         string `json:"status" dynamodbav:"status"`
+	ProcessedCount int    `json:"processed_count" dynamodbav:"processed_count"`
+	ErrorMessage   string `json:"error_message,omitempty" dynamodbav:"error_message"`
+	RecordedAt     string `json:"recorded_at" dynamodbav:"recorded_at"`
+}
+
+// Report lists the recent failures
+type Report struct {
+	Failures          []Failure `json:"failures"`
+	CodesQueried      int       `json:"codes_queried"`
+	ConsumedReadUnits float64   `json:"consumed_read_units"`
+	Errors            []string  `json:"errors,omitempty"` // run lookups that failed; their failures are still listed
+}
+
+// Lister reads the FailedItems and ProcessingRuns tables
+type Lister struct {
+	client dynamodbiface.DynamoDBAPI
+	config Config
+	logger *logger.Logger
+}
+
+// NewLister creates a lister using the default AWS session
+func NewLister(config Config) *Lister {
+	sess := session.Must(session.NewSession())
+	return NewListerWithClient(dynamodb.New(sess), config)
+}
+
+// NewListerWithClient creates a lister with a custom DynamoDB client (for testing)
+func NewListerWithClient(client dynamodbiface.DynamoDBAPI, config Config) *Lister {
+	if config.Limit <= 0 {
+		config.Limit = 20
+	}
+	if config.CollectorFunction == "" {
+		config.CollectorFunction = "data-collector"
+	}
+	return &Lister{
+		client: client,
+		config: config,
+		logger: logger.New("failure-triage"),
+	}
+}
+
+// Recent returns the most recent failures, newest first. The table is keyed by record, so
+// the code-index is queried newest first for each code and the results merged; no code can
+// contribute more than the limit, which bounds the read.
+func (l *Lister) Recent(ctx context.Context) (*Report, error) {
+	if l.config.FailedItemsTable == "" {
+		return nil, fmt.Errorf("FailedItems table is required")
+	}
+	codes := l.config.Codes
+	if len(codes) == 0 {
+		for _, code := range failures.Codes {
+			codes = append(codes, string(code))
+		}
+	}
+
+	report := &Report{}
+	for _, code := range codes {
+		items, units, err := l.recentForCode(ctx, code)
+		report.CodesQueried++
+		report.ConsumedReadUnits += units
+		if err != nil {
+			return report, fmt.Errorf("failed to query failures with code %s: %w", code, err)
+		}
+		report.Failures = append(report.Failures, items...)
+	}
+
+	// RFC 3339 timestamps in UTC sort as strings
+	sort.SliceStable(report.Failures, func(i, j int) bool {
+		return report.Failures[i].LastFailedAt > report.Failures[j].LastFailedAt
+	})
+	if len(report.Failures) > l.config.Limit {
+		report.Failures = report.Failures[:l.config.Limit]
+	}
+
+	runs := make(map[string]*Run)
+	for i := range report.Failures {
+		failure := &report.Failures[i]
+		failure.Replay = l.replayCommand(*failure)
+		if l.config.RunsTable == "" || failure.TraceID == "" {
+			continue
+		}
+		run, cached := runs[failure.TraceID]
+		if !cached {
+			var units float64
+			var err error
+			run, units, err = l.run(ctx, failure.TraceID)
+			report.ConsumedReadUnits += units
+			if err != nil {
+				report.Errors = append(report.Errors, err.Error())
+			}
+			runs[failure.TraceID] = run
+		}
+		failure.Run = run
+	}
+
+	l.logger.Info("Listed recent failures", map[string]interface{}{
+		"failures":            len(report.Failures),
+		"codes_queried":       report.CodesQueried,
+		"consumed_read_units": report.ConsumedReadUnits,
+	})
+	return report, nil
+}
+
+// recentForCode reads the newest failures of one code, up to the limit
+func (l *Lister) recentForCode(ctx context.Context, code string) ([]Failure, float64, error) {
+	names := map[string]*string{
+		"#code": aws.String(attributeCode),
+	}
+	values := map[string]*dynamodb.AttributeValue{
+		":code": {S: aws.String(code)},
+	}
+	condition := "#code = :code"
+	if !l.config.Since.IsZero() {
+		names["#last"] = aws.String(attributeLastFailed)
+		values[":since"] = &dynamodb.AttributeValue{S: aws.String(l.config.Since.UTC().Format(time.RFC3339))}
+		condition += " AND #last >= :since"
+	}
+	input := &dynamodb.QueryInput{
+		TableName:                 aws.String(l.config.FailedItemsTable),
+		IndexName:                 aws.String(failures.CodeIndex),
+		KeyConditionExpression:    aws.String(condition),
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
+		ScanIndexForward:          aws.Bool(false),
+		Limit:                     aws.Int64(int64(l.config.Limit)),
+	}
+	if l.config.Stage != "" {
+		names["#stage"] = aws.String(attributeStage)
+		values[":stage"] = &dynamodb.AttributeValue{S: aws.String(l.config.Stage)}
+		input.FilterExpression = aws.String("#stage = :stage")
+	}
+
+	var items []Failure
+	result, err := dynamo.Paginate(ctx, dynamo.Query(l.client, input), dynamo.Options{MaxPages: maxPagesPerCode}, func(page dynamo.Page) error {
+		for _, item := range page.Items {
+			var failure Failure
+			if err := dynamodbattribute.UnmarshalMap(item, &failure); err != nil {
+				return fmt.Errorf("failed to unmarshal failed item: %w", err)
+			}
+			items = append(items, failure)
+			if len(items) >= l.config.Limit {
+				return errEnough
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, errEnough) {
+		err = nil
+	}
+	return items, result.ConsumedCapacity, err
+}
+
+// run reads the recorded run of a trace; nil when the batch processor recorded none, as for
+// collection failures and traces older than the runs table
+func (l *Lister) run(ctx context.Context, traceID string) (*Run, float64, error) {
+	output, err := l.client.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName:                aws.String(l.config.RunsTable),
+		Key:                      map[string]*dynamodb.AttributeValue{runsAttributeTraceID: {S: aws.String(traceID)}},
+		ProjectionExpression:     aws.String("#status, processed_count, error_message, recorded_at"),
+		ExpressionAttributeNames: map[string]*string{"#status": aws.String("status")},
+		ReturnConsumedCapacity:   aws.String(dynamodb.ReturnConsumedCapacityTotal),
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read the processing run of %s: %w", traceID, err)
+	}
+	var units float64
+	if output.ConsumedCapacity != nil {
+		units = aws.Float64Value(output.ConsumedCapacity.CapacityUnits)
+	}
+	if len(output.Item) == 0 {
+		return nil, units, nil
+	}
+	var run Run
+	if err := dynamodbattribute.UnmarshalMap(output.Item, &run); err != nil {
+		return nil, units, fmt.Errorf("failed to unmarshal the processing run of %s: %w", traceID, err)
+	}
+	return &run, units, nil
+}
+
+// scheduleName matches the names of configured schedules, told apart from search queries in
+// collection record IDs, which hold either
+var scheduleName = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// replayCommand suggests the command that retries the failed record, following the record
+// IDs each stage writes (see the FailedItems table in the README)
+func (l *Lister) replayCommand(failure Failure) string {
+	switch failures.Stage(failure.Stage) {
+	case failures.StageIngestion:
+		// <bucket>/<key>
+		return "batch-processor " + shellQuote("s3://"+failure.RecordID)
+	case failures.StageVectorization:
+		// <paper_id>#<vector_type>
+		paperID, vectorType, found := cutLast(failure.RecordID, "#")
+		if !found {
+			return "vector-coordinator reembed " + shellQuote(failure.RecordID)
+		}
+		return fmt.Sprintf("vector-coordinator reembed --vector-type %s %s", shellQuote(vectorType), shellQuote(paperID))
+	case failures.StageExtraction:
+		// <paper_id>, or the PDF's s3:// key when the request had no paper ID
+		if strings.HasPrefix(failure.RecordID, "s3://") {
+			return "pdf-extractor <paper-id> " + shellQuote(failure.RecordID)
+		}
+		return "pdf-extractor " + shellQuote(failure.RecordID) + " <pdf-s3-uri>"
+	case failures.StageCollection:
+		// <data_source>#<schedule | search_query | default>
+		source, rest, _ := strings.Cut(failure.RecordID, "#")
+		request := map[string]string{"data_source": source}
+		switch {
+		case rest == "" || rest == "default":
+		case scheduleName.MatchString(rest):
+			request["schedule"] = rest
+		default:
+			request["search_query"] = rest
+		}
+		payload, _ := json.Marshal(request)
+		return fmt.Sprintf("aws lambda invoke --function-name %s --cli-binary-format raw-in-base64-out --payload %s /dev/stdout",
+			shellQuote(l.config.CollectorFunction), shellQuote(string(payload)))
+	}
+	return ""
+}
+
+// cutLast splits s around the last instance of sep; paper IDs may themselves hold sep
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
+// shellQuote quotes s for a POSIX shell when it holds anything but safe characters
+func shellQuote(s string) string {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./:=@", r))
+	}) < 0 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,361 @@
+package authors
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"batch-processor/processor"
+	"shared/awsclient"
+	"shared/dynamo"
+	"shared/logger"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// NameKeyIndex is the Authors table GSI on name_key used to find candidate entities
+const (
+	NameKeyIndex     = "name-key-index"
+	NameKeyAttribute = "name_key"
+)
+
+// Evidence weights for matching a mention to a candidate entity with the same name key.
+// A mention joins the best candidate scoring at least MinMatchScore; otherwise it starts
+// a new entity, so ambiguous mentions split rather than merge.
+const (
+	coauthorWeight = 0.6
+	categoryWeight = 0.25
+	fullNameWeight = 0.15
+	MinMatchScore  = 0.3
+)
+
+// Author is an author entity in the Authors table. Set attributes only grow, so
+// concurrent runs updating the same entity merge instead of overwriting each other.
+type Author struct {
+	AuthorID     string   `dynamodbav:"author_id" json:"author_id"`
+	NameKey      string   `dynamodbav:"name_key" json:"name_key"`
+	DisplayName  string   `dynamodbav:"display_name" json:"display_name"`
+	NameVariants []string `dynamodbav:"name_variants,stringset" json:"name_variants"`
+	PaperIDs     []string `dynamodbav:"paper_ids,stringset" json:"paper_ids"`
+	CoauthorKeys []string `dynamodbav:"coauthor_keys,stringset" json:"coauthor_keys,omitempty"`
+	Categories   []string `dynamodbav:"categories,stringset" json:"categories,omitempty"`
+	CreatedAt    string   `dynamodbav:"created_at" json:"created_at"`
+	UpdatedAt    string   `dynamodbav:"updated_at" json:"updated_at"`
+}
+
+// Resolver clusters author mentions into author entities: mentions sharing a name key are
+// compared by co-author overlap, category overlap and exact full name
+type Resolver struct {
+	client    dynamodbiface.DynamoDBAPI
+	tableName string
+	logger    *logger.Logger
+}
+
+// NewResolver creates a resolver for the given Authors table whose client uses clientConfig's retries and timeouts
+func NewResolver(tableName string, clientConfig awsclient.ClientConfig) *Resolver {
+	sess := awsclient.MustClientSession(clientConfig)
+	return NewResolverWithClient(dynamodb.New(sess), tableName)
+}
+
+// NewResolverWithClient creates a resolver with a custom DynamoDB client (for testing)
+func NewResolverWithClient(client dynamodbiface.DynamoDBAPI, tableName string) *Resolver {
+	return &Resolver{
+		client:    client,
+		tableName: tableName,
+		logger:    logger.New("author-resolver"),
+	}
+}
+
+// entity is an Author being resolved, with set lookups for scoring
+type entity struct {
+	author     *Author
+	papers     map[string]bool
+	coauthors  map[string]bool
+	categories map[string]bool
+	variants   map[string]bool
+	touched    bool
+}
+
+// ResolveAuthors sets AuthorIDs on every paper and writes the touched author entities.
+// Papers already linked to an entity resolve to it again, so reprocessing is stable.
+func (r *Resolver) ResolveAuthors(ctx context.Context, papers []processor.Paper) (*processor.AuthorStats, error) {
+	stats := &processor.AuthorStats{}
+
+	candidates := make(map[string][]*entity)
+	for _, paper := range papers {
+		for _, name := range paper.Authors {
+			key := processor.AuthorNameKey(name)
+			if key == "" {
+				continue
+			}
+			if _, loaded := candidates[key]; loaded {
+				continue
+			}
+			existing, err := r.queryByNameKey(ctx, key)
+			if err != nil {
+				return stats, fmt.Errorf("failed to load authors for %q: %w", key, err)
+			}
+			candidates[key] = existing
+		}
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	for i := range papers {
+		paper := &papers[i]
+		keys := make([]string, len(paper.Authors))
+		for j, name := range paper.Authors {
+			keys[j] = processor.AuthorNameKey(name)
+		}
+
+		paper.AuthorIDs = paper.AuthorIDs[:0]
+		used := make(map[*entity]bool)
+		for j, name := range paper.Authors {
+			key := keys[j]
+			if key == "" {
+				continue
+			}
+			stats.Mentions++
+
+			coauthors := make([]string, 0, len(keys)-1)
+			for k, other := range keys {
+				if k != j && other != "" {
+					coauthors = append(coauthors, other)
+				}
+			}
+
+			match := bestMatch(candidates[key], paper.PaperID, name, coauthors, paper.Categories, used)
+			if match != nil {
+				stats.Matched++
+			} else {
+				match = newEntity(key, name, paper.PaperID, now)
+				candidates[key] = append(candidates[key], match)
+				stats.Created++
+			}
+			used[match] = true
+			match.add(paper.PaperID, name, coauthors, paper.Categories)
+			paper.AuthorIDs = append(paper.AuthorIDs, match.author.AuthorID)
+		}
+	}
+
+	for _, entities := range candidates {
+		for _, e := range entities {
+			if !e.touched {
+				continue
+			}
+			if err := r.writeAuthor(ctx, e, now); err != nil {
+				stats.FailedWrites++
+				r.logger.WithContext(ctx).Warn("Failed to update author", map[string]interface{}{
+					"author_id": e.author.AuthorID,
+					"error":     err.Error(),
+				})
+			}
+		}
+	}
+
+	if stats.FailedWrites > 0 {
+		return stats, fmt.Errorf("%d author entities failed to update", stats.FailedWrites)
+	}
+	return stats, nil
+}
+
+// bestMatch returns the candidate a mention belongs to, or nil if none scores MinMatchScore.
+// Candidates already used by another mention on the same paper are skipped, since one
+// person does not appear twice in an author list.
+func bestMatch(candidates []*entity, paperID, name string, coauthors, categories []string, used map[*entity]bool) *entity {
+	var best *entity
+	bestScore := 0.0
+	for _, candidate := range candidates {
+		if used[candidate] {
+			continue
+		}
+		if candidate.papers[paperID] {
+			return candidate
+		}
+		score := coauthorWeight*overlap(coauthors, candidate.coauthors) +
+			categoryWeight*overlap(categories, candidate.categories)
+		if candidate.variants[normalizeName(name)] {
+			score += fullNameWeight
+		}
+		if score >= MinMatchScore && score > bestScore {
+			best, bestScore = candidate, score
+		}
+	}
+	return best
+}
+
+// overlap is the overlap coefficient |a ∩ b| / min(|a|, |b|), 0 when either set is empty
+func overlap(values []string, set map[string]bool) float64 {
+	if len(values) == 0 || len(set) == 0 {
+		return 0
+	}
+	seen := make(map[string]bool, len(values))
+	shared := 0
+	for _, value := range values {
+		if seen[value] {
+			continue
+		}
+		seen[value] = true
+		if set[value] {
+			shared++
+		}
+	}
+	smaller := len(seen)
+	if len(set) < smaller {
+		smaller = len(set)
+	}
+	return float64(shared) / float64(smaller)
+}
+
+// normalizeName folds case and whitespace so name variants compare equal
+func normalizeName(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+// newEntity starts an author entity. The ID derives from the name key and first paper,
+// so a retried batch recreates the same entity instead of a duplicate.
+func newEntity(key, name, paperID, now string) *entity {
+	sum := sha256.Sum256([]byte(key + "|" + paperID))
+	return wrap(&Author{
+		AuthorID:    "author-" + hex.EncodeToString(sum[:8]),
+		NameKey:     key,
+		DisplayName: name,
+		CreatedAt:   now,
+	})
+}
+
+func wrap(author *Author) *entity {
+	e := &entity{
+		author:     author,
+		papers:     toSet(author.PaperIDs),
+		coauthors:  toSet(author.CoauthorKeys),
+		categories: toSet(author.Categories),
+		variants:   make(map[string]bool, len(author.NameVariants)),
+	}
+	for _, variant := range author.NameVariants {
+		e.variants[normalizeName(variant)] = true
+	}
+	return e
+}
+
+// add records a mention on the entity
+func (e *entity) add(paperID, name string, coauthors, categories []string) {
+	e.touched = true
+	addTo(e.papers, &e.author.PaperIDs, paperID)
+	for _, coauthor := range coauthors {
+		addTo(e.coauthors, &e.author.CoauthorKeys, coauthor)
+	}
+	for _, category := range categories {
+		addTo(e.categories, &e.author.Categories, category)
+	}
+	if normalized := normalizeName(name); !e.variants[normalized] {
+		e.variants[normalized] = true
+		e.author.NameVariants = append(e.author.NameVariants, name)
+	}
+}
+
+func addTo(set map[string]bool, values *[]string, value string) {
+	if value == "" || set[value] {
+		return
+	}
+	set[value] = true
+	*values = append(*values, value)
+}
+
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+	return set
+}
+
+// queryByNameKey loads every author entity sharing a name key
+func (r *Resolver) queryByNameKey(ctx context.Context, key string) ([]*entity, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(r.tableName),
+		IndexName:              aws.String(NameKeyIndex),
+		KeyConditionExpression: aws.String("#nk = :nk"),
+		ExpressionAttributeNames: map[string]*string{
+			"#nk": aws.String(NameKeyAttribute),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":nk": {S: aws.String(key)},
+		},
+	}
+
+	var entities []*entity
+	_, err := dynamo.Paginate(ctx, dynamo.Query(r.client, input), dynamo.Options{}, func(page dynamo.Page) error {
+		var authors []Author
+		if err := dynamodbattribute.UnmarshalListOfMaps(page.Items, &authors); err != nil {
+			return fmt.Errorf("failed to unmarshal authors: %w", err)
+		}
+		for i := range authors {
+			entities = append(entities, wrap(&authors[i]))
+		}
+		return nil
+	})
+	if err != nil {
+		var fetchErr *dynamo.FetchError
+		if errors.As(err, &fetchErr) {
+			return nil, fetchErr.Err
+		}
+		return nil, err
+	}
+
+	// Oldest first, so ties go to the established entity
+	sort.SliceStable(entities, func(i, j int) bool {
+		return entities[i].author.CreatedAt < entities[j].author.CreatedAt
+	})
+	return entities, nil
+}
+
+// writeAuthor merges the entity into its stored item with set additions
+func (r *Resolver) writeAuthor(ctx context.Context, e *entity, now string) error {
+	update := "SET #nk = :nk, #dn = if_not_exists(#dn, :dn), #ca = if_not_exists(#ca, :ca), #ua = :ua ADD #pids :pids, #nv :nv"
+	names := map[string]*string{
+		"#nk":   aws.String("name_key"),
+		"#dn":   aws.String("display_name"),
+		"#ca":   aws.String("created_at"),
+		"#ua":   aws.String("updated_at"),
+		"#pids": aws.String("paper_ids"),
+		"#nv":   aws.String("name_variants"),
+	}
+	values := map[string]*dynamodb.AttributeValue{
+		":nk":   {S: aws.String(e.author.NameKey)},
+		":dn":   {S: aws.String(e.author.DisplayName)},
+		":ca":   {S: aws.String(e.author.CreatedAt)},
+		":ua":   {S: aws.String(now)},
+		":pids": {SS: aws.StringSlice(e.author.PaperIDs)},
+		":nv":   {SS: aws.StringSlice(e.author.NameVariants)},
+	}
+	// DynamoDB rejects empty sets, so optional sets are only added when present
+	if len(e.author.CoauthorKeys) > 0 {
+		update += ", #co :co"
+		names["#co"] = aws.String("coauthor_keys")
+		values[":co"] = &dynamodb.AttributeValue{SS: aws.StringSlice(e.author.CoauthorKeys)}
+	}
+	if len(e.author.Categories) > 0 {
+		update += ", #cat :cat"
+		names["#cat"] = aws.String("categories")
+		values[":cat"] = &dynamodb.AttributeValue{SS: aws.StringSlice(e.author.Categories)}
+	}
+
+	_, err := r.client.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(r.tableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			"author_id": {S: aws.String(e.author.AuthorID)},
+		},
+		UpdateExpression:          aws.String(update),
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
+	})
+	return err
+}
@@ -0,0 +1,82 @@
+// Package categories applies the configured category allowlist and skip list at ingestion,
+// mirroring the collector's filter so papers collected before a list changed are caught too.
+// Patterns are exact categories ("cs.AI") or archive wildcards ("q-bio.*"), compared
+// case-insensitively.
+package categories
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Filter decides which papers to keep by their categories. Denied categories are ignored,
+// and a paper is kept when one of its remaining categories is allowed (any, when the
+// allowlist is empty). A cross-listed paper therefore survives a denied secondary category.
+type Filter struct {
+	allow []string
+	deny  []string
+}
+
+// NewFilter creates a filter from allow and deny patterns; an empty filter keeps every paper
+func NewFilter(allow, deny []string) *Filter {
+	return &Filter{allow: normalize(allow), deny: normalize(deny)}
+}
+
+// Validate rejects wildcards anywhere but at the end of a pattern
+func Validate(patterns []string) error {
+	for _, pattern := range patterns {
+		if i := strings.Index(pattern, "*"); i >= 0 && i != len(pattern)-1 {
+			return fmt.Errorf("category pattern %q may only end with '*'", pattern)
+		}
+	}
+	return nil
+}
+
+// Active reports whether the filter restricts anything
+func (f *Filter) Active() bool {
+	return f != nil && (len(f.allow) > 0 || len(f.deny) > 0)
+}
+
+// Allows reports whether a paper with these categories is kept. Papers without
+// categories are kept unless an allowlist is configured.
+func (f *Filter) Allows(categories []string) bool {
+	if !f.Active() {
+		return true
+	}
+	if len(categories) == 0 {
+		return len(f.allow) == 0
+	}
+	for _, category := range categories {
+		category = strings.ToLower(strings.TrimSpace(category))
+		if matchesAny(f.deny, category) {
+			continue
+		}
+		if len(f.allow) == 0 || matchesAny(f.allow, category) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesAny(patterns []string, category string) bool {
+	for _, pattern := range patterns {
+		if prefix, wildcard := strings.CutSuffix(pattern, "*"); wildcard {
+			if strings.HasPrefix(category, prefix) {
+				return true
+			}
+		} else if category == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+func normalize(patterns []string) []string {
+	result := make([]string, 0, len(patterns))
+	for _, pattern := range patterns {
+		if pattern = strings.ToLower(strings.TrimSpace(pattern)); pattern != "" {
+			result = append(result, pattern)
+		}
+	}
+	return result
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"batch-processor/processor"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// DefaultCoalesceMaxRecords flushes a coalesced batch early once it holds this many S3 records
+const DefaultCoalesceMaxRecords = 100
+
+// coalescer buffers S3 events arriving within a short window and processes them together,
+// so many tiny objects (e.g. per-category sharded runs) share one dedup and upsert pass
+// instead of each paying for its own
+type coalescer struct {
+	window     time.Duration
+	maxRecords int
+	shutdown   <-chan struct{}
+	process    func(ctx context.Context, s3Event events.S3Event, shutdown <-chan struct{}) (*processor.ProcessResult, error)
+
+	mu      sync.Mutex
+	pending *coalescedBatch
+}
+
+// coalescedBatch is the events buffered for one processing pass
+type coalescedBatch struct {
+	event    events.S3Event
+	requests int
+	once     sync.Once
+	done     chan struct{}
+	result   *processor.ProcessResult
+	err      error
+}
+
+// newCoalescerFromEnv reads COALESCE_WINDOW_MS and COALESCE_MAX_RECORDS; a zero window
+// (the default) disables coalescing and returns nil
+func newCoalescerFromEnv(shutdown <-chan struct{}) (*coalescer, error) {
+	value := os.Getenv("COALESCE_WINDOW_MS")
+	if value == "" {
+		return nil, nil
+	}
+	windowMs, err := strconv.Atoi(value)
+	if err != nil || windowMs < 0 {
+		return nil, fmt.Errorf("invalid COALESCE_WINDOW_MS %q", value)
+	}
+	if windowMs == 0 {
+		return nil, nil
+	}
+
+	maxRecords := DefaultCoalesceMaxRecords
+	if value := os.Getenv("COALESCE_MAX_RECORDS"); value != "" {
+		if maxRecords, err = strconv.Atoi(value); err != nil || maxRecords < 1 {
+			return nil, fmt.Errorf("invalid COALESCE_MAX_RECORDS %q", value)
+		}
+	}
+	return &coalescer{
+		window:     time.Duration(windowMs) * time.Millisecond,
+		maxRecords: maxRecords,
+		shutdown:   shutdown,
+		process:    processS3Event,
+	}, nil
+}
+
+// submit adds the event to the current batch and waits for the batch to be processed.
+// The returned result carries the batch's merged stats and this event's own record results.
+func (c *coalescer) submit(s3Event events.S3Event) (*processor.ProcessResult, error) {
+	c.mu.Lock()
+	batch := c.pending
+	if batch == nil {
+		batch = &coalescedBatch{done: make(chan struct{})}
+		c.pending = batch
+		time.AfterFunc(c.window, func() { c.flush(batch) })
+	}
+	offset := len(batch.event.Records)
+	batch.event.Records = append(batch.event.Records, s3Event.Records...)
+	batch.requests++
+	full := len(batch.event.Records) >= c.maxRecords
+	c.mu.Unlock()
+
+	if full {
+		c.flush(batch)
+	}
+	<-batch.done
+
+	if batch.err != nil {
+		return nil, batch.err
+	}
+	result := *batch.result
+	result.CoalescedRequests = batch.requests
+	if end := offset + len(s3Event.Records); end <= len(batch.result.RecordResults) {
+		result.RecordResults = batch.result.RecordResults[offset:end]
+	}
+	return &result, nil
+}
+
+// flush processes the batch once, detaching it so later events start a new batch
+func (c *coalescer) flush(batch *coalescedBatch) {
+	batch.once.Do(func() {
+		c.mu.Lock()
+		if c.pending == batch {
+			c.pending = nil
+		}
+		c.mu.Unlock()
+
+		batch.result, batch.err = c.process(context.Background(), batch.event, c.shutdown)
+		close(batch.done)
+	})
+}
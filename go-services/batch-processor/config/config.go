@@ -0,0 +1,204 @@
+package config
+
+import (
+	"batch-processor/categories"
+	"context"
+	"fmt"
+	"io"
+	"shared/awsclient"
+	"shared/pauseflags"
+	"shared/slo"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"gopkg.in/yaml.v3"
+)
+
+// Config represents the parts of the pipeline configuration used by the batch processor
+type Config struct {
+	Processing ProcessingConfig  `yaml:"processing"`
+	AWS        AWSConfig         `yaml:"aws"`
+	Pause      pauseflags.Config `yaml:"pause"`
+	SLO        slo.Config        `yaml:"slo"`
+}
+
+// AWSConfig represents the AWS settings used by the batch processor
+type AWSConfig struct {
+	DynamoDB DynamoDBConfig `yaml:"dynamodb"`
+}
+
+// DynamoDBConfig represents DynamoDB client settings; table names come from the environment
+type DynamoDBConfig struct {
+	// Client tunes SDK retries and call timeouts for throttling-prone tables
+	Client awsclient.ClientConfig `yaml:"client"`
+}
+
+// ProcessingConfig represents processing configuration
+type ProcessingConfig struct {
+	BatchSize       int                  `yaml:"batch_size"`
+	Compression     string               `yaml:"compression"`
+	RetryAttempts   int                  `yaml:"retry_attempts"`
+	RetryDelay      int                  `yaml:"retry_delay"`
+	DedupStrategies []string             `yaml:"dedup_strategies"`
+	MergePolicy     MergePolicyConfig    `yaml:"merge_policy"`
+	CategoryFilter  CategoryFilterConfig `yaml:"category_filter"`
+	// EventDedupWindow is how long, in seconds, a processed object version suppresses duplicate
+	// S3 notifications when EVENT_DEDUP_TABLE_NAME is set
+	EventDedupWindow int `yaml:"event_dedup_window_seconds"`
+	// TraceIDMode is "random" (default) for a new UUID per batch, or "deterministic" to derive
+	// it from the batch's S3 objects so replays produce the same trace
+	TraceIDMode string `yaml:"trace_id_mode"`
+}
+
+// CategoryFilterConfig lists category patterns to keep and to skip, applied at collection
+// (query construction) and at ingestion. Patterns are exact ("cs.AI") or archive wildcards
+// ("q-bio.*"); an empty allowlist allows every category not skipped.
+type CategoryFilterConfig struct {
+	Allow []string `yaml:"allow"`
+	Deny  []string `yaml:"deny"`
+}
+
+// MergePolicyConfig controls how duplicates from different sources are combined.
+// When disabled, the first record seen is kept and the others are dropped.
+type MergePolicyConfig struct {
+	Enabled         bool     `yaml:"enabled"`
+	SourcePriority  []string `yaml:"source_priority"` // most trusted first; its record supplies the paper ID and scalar fields
+	Abstract        string   `yaml:"abstract"`        // "longest" (default) or "priority"
+	UnionCategories bool     `yaml:"union_categories"`
+	UnionAuthors    bool     `yaml:"union_authors"`
+}
+
+// Abstract selection modes of the merge policy
+const (
+	MergeAbstractLongest  = "longest"
+	MergeAbstractPriority = "priority"
+)
+
+// Trace ID modes of the processing config
+const (
+	TraceIDRandom        = "random"
+	TraceIDDeterministic = "deterministic"
+)
+
+// MaxDynamoDBBatchSize is the AWS ceiling on items per BatchWriteItem request
+const MaxDynamoDBBatchSize = 25
+
+// Validate checks processing settings against service limits
+func (p ProcessingConfig) Validate() error {
+	if p.BatchSize < 1 || p.BatchSize > MaxDynamoDBBatchSize {
+		return fmt.Errorf("processing.batch_size must be between 1 and %d, got %d", MaxDynamoDBBatchSize, p.BatchSize)
+	}
+	switch p.MergePolicy.Abstract {
+	case MergeAbstractLongest, MergeAbstractPriority:
+	default:
+		return fmt.Errorf("processing.merge_policy.abstract must be %q or %q, got %q", MergeAbstractLongest, MergeAbstractPriority, p.MergePolicy.Abstract)
+	}
+	if p.EventDedupWindow < 0 {
+		return fmt.Errorf("processing.event_dedup_window_seconds must not be negative, got %d", p.EventDedupWindow)
+	}
+	switch p.TraceIDMode {
+	case "", TraceIDRandom, TraceIDDeterministic:
+	default:
+		return fmt.Errorf("processing.trace_id_mode must be %q or %q, got %q", TraceIDRandom, TraceIDDeterministic, p.TraceIDMode)
+	}
+	for _, patterns := range [][]string{p.CategoryFilter.Allow, p.CategoryFilter.Deny} {
+		if err := categories.Validate(patterns); err != nil {
+			return fmt.Errorf("processing.category_filter: %w", err)
+		}
+	}
+	return nil
+}
+
+// Manager handles configuration loading and management
+type Manager struct {
+	s3Client *s3.S3
+}
+
+// NewManager creates a new configuration manager
+func NewManager() (*Manager, error) {
+	sess, err := awsclient.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"), // Default region
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AWS session: %w", err)
+	}
+
+	return &Manager{
+		s3Client: s3.New(sess),
+	}, nil
+}
+
+// LoadFromS3 loads configuration from S3
+func (m *Manager) LoadFromS3(ctx context.Context, bucket, key string) (*Config, error) {
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}
+
+	result, err := m.s3Client.GetObjectWithContext(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config from S3: %w", err)
+	}
+	defer result.Body.Close()
+
+	data, err := io.ReadAll(result.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config data: %w", err)
+	}
+
+	return m.LoadFromBytes(data)
+}
+
+// LoadFromBytes loads configuration from byte data, filling unset values from the defaults
+func (m *Manager) LoadFromBytes(data []byte) (*Config, error) {
+	config := GetDefaultConfig()
+	if err := yaml.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("failed to parse YAML config: %w", err)
+	}
+
+	if len(config.Processing.DedupStrategies) == 0 {
+		config.Processing.DedupStrategies = GetDefaultConfig().Processing.DedupStrategies
+	}
+	if config.Processing.MergePolicy.Abstract == "" {
+		config.Processing.MergePolicy.Abstract = MergeAbstractLongest
+	}
+
+	if err := config.Processing.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid processing config: %w", err)
+	}
+	if err := config.AWS.DynamoDB.Client.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid aws.dynamodb.client config: %w", err)
+	}
+	if err := config.SLO.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid slo config: %w", err)
+	}
+
+	return config, nil
+}
+
+// GetDefaultConfig returns a default configuration for fallback scenarios
+func GetDefaultConfig() *Config {
+	return &Config{
+		Processing: ProcessingConfig{
+			BatchSize:       25,
+			Compression:     "gzip",
+			RetryAttempts:   3,
+			RetryDelay:      1,
+			DedupStrategies: []string{"exact_id"},
+			MergePolicy: MergePolicyConfig{
+				Enabled:         false,
+				SourcePriority:  []string{"crossref", "pubmed", "arxiv"},
+				Abstract:        MergeAbstractLongest,
+				UnionCategories: true,
+				UnionAuthors:    true,
+			},
+			EventDedupWindow: 900,
+			TraceIDMode:      TraceIDRandom,
+		},
+		Pause: pauseflags.Config{
+			ParameterPrefix: "/paper-pipeline/pause",
+			RefreshSeconds:  30,
+		},
+		SLO: slo.DefaultConfig(),
+	}
+}
@@ -2,68 +2,99 @@ package deduplicator
 
 import (
 	"batch-processor/processor"
+	"fmt"
 	"shared/logger"
 )
 
+// MaxDuplicateGroups caps the duplicate groups reported per run to keep results small
+const MaxDuplicateGroups = 100
+
 // Deduplicator handles data deduplication logic
-type Deduplicator struct{
-	logger *logger.Logger
+type Deduplicator struct {
+	logger     *logger.Logger
+	strategies []Strategy
+	merge      *MergePolicy
 }
 
-// NewDeduplicator creates a new deduplicator instance
+// NewDeduplicator creates a new deduplicator instance using exact paper_id matching
 func NewDeduplicator() *Deduplicator {
 	return &Deduplicator{
-		logger: logger.New("deduplicator"),
+		logger:     logger.New("deduplicator"),
+		strategies: []Strategy{exactIDStrategy{}},
 	}
 }
 
-// Deduplicate removes duplicate papers based on paper_id
-func (d *Deduplicator) Deduplicate(papers []processor.Paper) []processor.Paper {
-	if len(papers) == 0 {
-		return papers
+// NewDeduplicatorWithStrategies creates a deduplicator that applies the named strategies in order
+func NewDeduplicatorWithStrategies(names []string) (*Deduplicator, error) {
+	if len(names) == 0 {
+		return NewDeduplicator(), nil
 	}
 
+	strategies := make([]Strategy, 0, len(names))
 	seen := make(map[string]bool)
-	var deduplicated []processor.Paper
-	duplicateCount := 0
-
-	for _, paper := range papers {
-		if paper.PaperID == "" {
-			d.logger.Warn("Skipping paper with empty paper_id")
-			continue
+	for _, name := range names {
+		strategy, err := NewStrategy(name)
+		if err != nil {
+			return nil, err
 		}
-
-		if !seen[paper.PaperID] {
-			seen[paper.PaperID] = true
-			deduplicated = append(deduplicated, paper)
-		} else {
-			duplicateCount++
-			d.logger.Debug("Duplicate paper found and removed", map[string]interface{}{
-				"paper_id": paper.PaperID,
-			})
+		if seen[strategy.Name()] {
+			return nil, fmt.Errorf("deduplication strategy '%s' configured more than once", strategy.Name())
 		}
+		seen[strategy.Name()] = true
+		strategies = append(strategies, strategy)
 	}
 
-	d.logger.Info("Deduplication completed", map[string]interface{}{
-		"original_count":   len(papers),
-		"unique_count":     len(deduplicated),
-		"duplicates_removed": duplicateCount,
-	})
+	return &Deduplicator{
+		logger:     logger.New("deduplicator"),
+		strategies: strategies,
+	}, nil
+}
+
+// SetMergePolicy merges cross-source duplicates into the kept record instead of dropping them
+func (d *Deduplicator) SetMergePolicy(policy *MergePolicy) {
+	d.merge = policy
+}
+
+// Strategies returns the names of the configured strategies in evaluation order
+func (d *Deduplicator) Strategies() []string {
+	names := make([]string, len(d.strategies))
+	for i, strategy := range d.strategies {
+		names[i] = strategy.Name()
+	}
+	return names
+}
 
+// Deduplicate removes duplicate papers using the configured strategy chain
+func (d *Deduplicator) Deduplicate(papers []processor.Paper) []processor.Paper {
+	deduplicated, _ := d.DeduplicateWithStats(papers)
 	return deduplicated
 }
 
-// DeduplicateWithStats returns deduplicated papers along with statistics
+// DeduplicateWithStats returns deduplicated papers along with statistics.
+// Each paper is checked against every strategy in order; the first strategy
+// whose key was already seen claims the duplicate in the per-strategy counts,
+// and the dropped record is reported in the group of the paper that was kept.
+// With a merge policy, a duplicate from another source is merged into the kept record;
+// the record whose paper ID was given up is reported as dropped with Merged set.
 func (d *Deduplicator) DeduplicateWithStats(papers []processor.Paper) ([]processor.Paper, processor.DeduplicationStats) {
 	stats := processor.DeduplicationStats{
-		OriginalCount: len(papers),
+		OriginalCount:  len(papers),
+		StrategyCounts: make(map[string]int, len(d.strategies)),
+	}
+	for _, strategy := range d.strategies {
+		stats.StrategyCounts[strategy.Name()] = 0
 	}
 
 	if len(papers) == 0 {
 		return papers, stats
 	}
 
-	seen := make(map[string]bool)
+	// seen maps each strategy key to the index of the kept paper that produced it
+	seen := make([]map[string]int, len(d.strategies))
+	for i := range seen {
+		seen[i] = make(map[string]int)
+	}
+	groupIndex := make(map[int]int)
 	var deduplicated []processor.Paper
 
 	for _, paper := range papers {
@@ -73,15 +104,73 @@ func (d *Deduplicator) DeduplicateWithStats(papers []processor.Paper) ([]process
 			continue
 		}
 
-		if !seen[paper.PaperID] {
-			seen[paper.PaperID] = true
-			deduplicated = append(deduplicated, paper)
-		} else {
+		keys := make([]string, len(d.strategies))
+		matchedBy := -1
+		winner := 0
+		for i, strategy := range d.strategies {
+			keys[i] = strategy.Key(paper)
+			if keys[i] == "" || matchedBy >= 0 {
+				continue
+			}
+			if index, found := seen[i][keys[i]]; found {
+				matchedBy = i
+				winner = index
+			}
+		}
+
+		if matchedBy >= 0 {
+			strategyName := d.strategies[matchedBy].Name()
 			stats.DuplicateCount++
+			stats.StrategyCounts[strategyName]++
+
+			dropped := processor.DroppedRecord{
+				PaperID:  paper.PaperID,
+				Source:   paper.Source,
+				Strategy: strategyName,
+				MatchKey: keys[matchedBy],
+			}
+			if d.merge != nil && d.merge.applies(deduplicated[winner], paper) {
+				merged, replaced := d.merge.Merge(deduplicated[winner], paper)
+				d.recordDuplicate(&stats, groupIndex, deduplicated[winner], winner, processor.DroppedRecord{
+					PaperID:  replaced.PaperID,
+					Source:   replaced.Source,
+					Strategy: strategyName,
+					MatchKey: keys[matchedBy],
+					Merged:   true,
+				})
+				d.replaceWinner(&stats, groupIndex, winner, merged)
+				deduplicated[winner] = merged
+				stats.MergedCount++
+
+				// Later duplicates may only match the merged-in record's keys
+				for i, key := range keys {
+					if _, found := seen[i][key]; key != "" && !found {
+						seen[i][key] = winner
+					}
+				}
+				d.logger.Debug("Cross-source duplicate merged", map[string]interface{}{
+					"paper_id":  paper.PaperID,
+					"winner_id": merged.PaperID,
+					"strategy":  strategyName,
+				})
+				continue
+			}
+
+			d.recordDuplicate(&stats, groupIndex, deduplicated[winner], winner, dropped)
 			d.logger.Debug("Duplicate paper found and removed", map[string]interface{}{
-				"paper_id": paper.PaperID,
+				"paper_id":  paper.PaperID,
+				"winner_id": deduplicated[winner].PaperID,
+				"strategy":  strategyName,
 			})
+			continue
 		}
+
+		for i, key := range keys {
+			if key != "" {
+				seen[i][key] = len(deduplicated)
+			}
+		}
+		deduplicated = append(deduplicated, paper)
 	}
 
 	stats.UniqueCount = len(deduplicated)
@@ -91,8 +180,37 @@ func (d *Deduplicator) DeduplicateWithStats(papers []processor.Paper) ([]process
 		"unique_count":     stats.UniqueCount,
 		"duplicate_count":  stats.DuplicateCount,
 		"invalid_count":    stats.InvalidCount,
+		"merged_count":     stats.MergedCount,
+		"strategy_counts":  stats.StrategyCounts,
+		"duplicate_groups": len(stats.DuplicateGroups),
 	})
 
 	return deduplicated, stats
 }
 
+// replaceWinner points the group of a kept paper at the merged record, whose ID may have changed
+func (d *Deduplicator) replaceWinner(stats *processor.DeduplicationStats, groupIndex map[int]int, winnerIndex int, merged processor.Paper) {
+	if index, exists := groupIndex[winnerIndex]; exists {
+		stats.DuplicateGroups[index].WinnerID = merged.PaperID
+		stats.DuplicateGroups[index].WinnerSource = merged.Source
+	}
+}
+
+// recordDuplicate adds a dropped record to the group of the paper that was kept
+func (d *Deduplicator) recordDuplicate(stats *processor.DeduplicationStats, groupIndex map[int]int, winner processor.Paper, winnerIndex int, dropped processor.DroppedRecord) {
+	index, exists := groupIndex[winnerIndex]
+	if !exists {
+		if len(stats.DuplicateGroups) >= MaxDuplicateGroups {
+			stats.DuplicateGroupsTruncated = true
+			return
+		}
+		stats.DuplicateGroups = append(stats.DuplicateGroups, processor.DuplicateGroup{
+			WinnerID:     winner.PaperID,
+			WinnerSource: winner.Source,
+		})
+		index = len(stats.DuplicateGroups) - 1
+		groupIndex[winnerIndex] = index
+	}
+
+	stats.DuplicateGroups[index].Dropped = append(stats.DuplicateGroups[index].Dropped, dropped)
+}
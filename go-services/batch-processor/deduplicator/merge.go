@@ -0,0 +1,145 @@
+package deduplicator
+
+import (
+	"batch-processor/processor"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Abstract selection modes
+const (
+	AbstractLongest  = "longest"  // keep the richer abstract, whichever source it came from
+	AbstractPriority = "priority" // keep the preferred source's abstract unless it is empty
+)
+
+// MergePolicy combines duplicates from different sources into one record instead of
+// dropping all but the first. Same-source duplicates are still dropped outright.
+type MergePolicy struct {
+	sourceRank      map[string]int
+	abstract        string
+	unionCategories bool
+	unionAuthors    bool
+}
+
+// NewMergePolicy creates a merge policy. sourcePriority lists the most trusted source
+// first; its record supplies the paper ID and scalar fields of the merged paper.
+func NewMergePolicy(sourcePriority []string, abstract string, unionCategories, unionAuthors bool) (*MergePolicy, error) {
+	switch abstract {
+	case "":
+		abstract = AbstractLongest
+	case AbstractLongest, AbstractPriority:
+	default:
+		return nil, fmt.Errorf("unknown abstract merge mode '%s'", abstract)
+	}
+
+	rank := make(map[string]int, len(sourcePriority))
+	for i, source := range sourcePriority {
+		source = strings.ToLower(strings.TrimSpace(source))
+		if _, exists := rank[source]; !exists {
+			rank[source] = i
+		}
+	}
+	return &MergePolicy{
+		sourceRank:      rank,
+		abstract:        abstract,
+		unionCategories: unionCategories,
+		unionAuthors:    unionAuthors,
+	}, nil
+}
+
+// applies reports whether a duplicate should be merged rather than dropped: only when no
+// record from its source has been merged into the kept paper yet
+func (m *MergePolicy) applies(kept, duplicate processor.Paper) bool {
+	if strings.EqualFold(kept.Source, duplicate.Source) {
+		return false
+	}
+	for source := range kept.SourceIDs {
+		if strings.EqualFold(source, duplicate.Source) {
+			return false
+		}
+	}
+	return true
+}
+
+// rank orders sources by priority; unlisted sources come last
+func (m *MergePolicy) rank(source string) int {
+	if rank, ok := m.sourceRank[strings.ToLower(source)]; ok {
+		return rank
+	}
+	return len(m.sourceRank)
+}
+
+// Merge combines two records of the same paper. It returns the merged paper and the record
+// whose paper ID was given up, which is kept only under the merged paper's SourceIDs.
+func (m *MergePolicy) Merge(kept, duplicate processor.Paper) (processor.Paper, processor.Paper) {
+	primary, secondary := kept, duplicate
+	if m.rank(duplicate.Source) < m.rank(kept.Source) {
+		primary, secondary = duplicate, kept
+	}
+
+	merged := primary
+	if merged.Title == "" {
+		merged.Title = secondary.Title
+	}
+	if merged.PublishedDate == "" {
+		merged.PublishedDate = secondary.PublishedDate
+	}
+	if merged.DOI == "" {
+		merged.DOI = secondary.DOI
+	}
+
+	switch {
+	case merged.Abstract == "":
+		merged.Abstract = secondary.Abstract
+	case m.abstract == AbstractLongest && textLength(secondary.Abstract) > textLength(merged.Abstract):
+		merged.Abstract = secondary.Abstract
+	}
+
+	if m.unionCategories {
+		merged.Categories = unionStrings(primary.Categories, secondary.Categories, func(s string) string {
+			return strings.ToLower(strings.TrimSpace(s))
+		})
+	}
+	if m.unionAuthors {
+		merged.Authors = unionStrings(primary.Authors, secondary.Authors, processor.AuthorNameKey)
+	}
+
+	merged.SourceIDs = make(map[string]string)
+	for _, paper := range []processor.Paper{secondary, primary} {
+		for source, id := range paper.SourceIDs {
+			merged.SourceIDs[source] = id
+		}
+		merged.SourceIDs[paper.Source] = paper.PaperID
+	}
+
+	merged.TitleHash = processor.ContentHash(merged.Title)
+	merged.AbstractHash = processor.ContentHash(merged.Abstract)
+	return merged, secondary
+}
+
+// unionStrings appends the values of extra whose key is not already present, keeping the
+// order of base; values with an empty key are compared verbatim
+func unionStrings(base, extra []string, key func(string) string) []string {
+	result := make([]string, 0, len(base)+len(extra))
+	seen := make(map[string]bool, len(base)+len(extra))
+	for _, values := range [][]string{base, extra} {
+		for _, value := range values {
+			k := key(value)
+			if k == "" {
+				k = value
+			}
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// textLength counts the characters of text ignoring surrounding whitespace
+func textLength(text string) int {
+	return utf8.RuneCountInString(strings.TrimSpace(text))
+}
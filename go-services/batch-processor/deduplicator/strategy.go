@@ -0,0 +1,137 @@
+package deduplicator
+
+import (
+	"batch-processor/processor"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	StrategyExactID      = "exact_id"
+	StrategyNormalizedID = "normalized_id"
+	StrategyDOI          = "doi"
+	StrategyFuzzyTitle   = "fuzzy_title"
+	StrategyTitleAuthors = "title_authors"
+)
+
+// Strategy derives the comparison key used to detect duplicates.
+// An empty key means the strategy does not apply to the paper.
+type Strategy interface {
+	Name() string
+	Key(paper processor.Paper) string
+}
+
+// NewStrategy creates a strategy by its configured name
+func NewStrategy(name string) (Strategy, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case StrategyExactID:
+		return exactIDStrategy{}, nil
+	case StrategyNormalizedID:
+		return normalizedIDStrategy{}, nil
+	case StrategyDOI:
+		return doiStrategy{}, nil
+	case StrategyFuzzyTitle:
+		return fuzzyTitleStrategy{}, nil
+	case StrategyTitleAuthors:
+		return titleAuthorsStrategy{}, nil
+	default:
+		return nil, fmt.Errorf("unknown deduplication strategy '%s'", name)
+	}
+}
+
+// exactIDStrategy matches papers with identical paper_id
+type exactIDStrategy struct{}
+
+func (exactIDStrategy) Name() string { return StrategyExactID }
+
+func (exactIDStrategy) Key(paper processor.Paper) string {
+	return paper.PaperID
+}
+
+// normalizedIDStrategy matches paper IDs that differ only in case, URL/scheme prefix or arXiv version suffix
+type normalizedIDStrategy struct{}
+
+var (
+	idPrefixPattern  = regexp.MustCompile(`^(https?://[^/]+/(abs|pdf)/|arxiv:)`)
+	idVersionPattern = regexp.MustCompile(`v\d+$`)
+)
+
+func (normalizedIDStrategy) Name() string { return StrategyNormalizedID }
+
+func (normalizedIDStrategy) Key(paper processor.Paper) string {
+	id := strings.ToLower(strings.TrimSpace(paper.PaperID))
+	id = idPrefixPattern.ReplaceAllString(id, "")
+	id = strings.TrimSuffix(id, ".pdf")
+	return idVersionPattern.ReplaceAllString(id, "")
+}
+
+// doiStrategy matches papers sharing a DOI
+type doiStrategy struct{}
+
+func (doiStrategy) Name() string { return StrategyDOI }
+
+func (doiStrategy) Key(paper processor.Paper) string {
+	doi := strings.ToLower(strings.TrimSpace(paper.DOI))
+	for _, prefix := range []string{"https://doi.org/", "http://doi.org/", "https://dx.doi.org/", "http://dx.doi.org/", "doi:"} {
+		doi = strings.TrimPrefix(doi, prefix)
+	}
+	return doi
+}
+
+// fuzzyTitleStrategy matches titles that are equal after case folding and punctuation removal
+type fuzzyTitleStrategy struct{}
+
+// minFuzzyTitleLength avoids collapsing short generic titles such as "Introduction"
+const minFuzzyTitleLength = 20
+
+func (fuzzyTitleStrategy) Name() string { return StrategyFuzzyTitle }
+
+func (fuzzyTitleStrategy) Key(paper processor.Paper) string {
+	title := normalizeTitle(paper.Title)
+	if len(title) < minFuzzyTitleLength {
+		return ""
+	}
+	return title
+}
+
+// titleAuthorsStrategy matches papers with the same normalized title and the same author
+// name keys, so short titles are still matched once the author lists agree
+type titleAuthorsStrategy struct{}
+
+func (titleAuthorsStrategy) Name() string { return StrategyTitleAuthors }
+
+func (titleAuthorsStrategy) Key(paper processor.Paper) string {
+	title := normalizeTitle(paper.Title)
+	if title == "" {
+		return ""
+	}
+
+	keys := make([]string, 0, len(paper.Authors))
+	for _, author := range paper.Authors {
+		if key := processor.AuthorNameKey(author); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return ""
+	}
+	sort.Strings(keys)
+	return title + "|" + strings.Join(keys, ",")
+}
+
+// normalizeTitle lowercases a title and collapses punctuation and whitespace to single spaces
+func normalizeTitle(title string) string {
+	var builder strings.Builder
+	for _, word := range strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if builder.Len() > 0 {
+			builder.WriteByte(' ')
+		}
+		builder.WriteString(word)
+	}
+	return builder.String()
+}
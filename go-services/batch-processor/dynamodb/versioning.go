@@ -0,0 +1,265 @@
+package dynamodb
+
+import (
+	"batch-processor/processor"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+const (
+	// MaxVersionHistory caps the number of superseded revisions kept per paper
+	MaxVersionHistory = 20
+
+	// lookupRetryDelay is the first pause before re-requesting unprocessed keys; it doubles per attempt
+	lookupRetryDelay = 50 * time.Millisecond
+)
+
+// existingPaper holds the stored fields needed to detect content changes
+type existingPaper struct {
+	PaperID        string                   `dynamodbav:"paper_id"`
+	Title          string                   `dynamodbav:"title"`
+	Abstract       string                   `dynamodbav:"abstract"`
+	Source         string                   `dynamodbav:"source"`
+	TraceID        string                   `dynamodbav:"trace_id"`
+	CreatedAt      string                   `dynamodbav:"created_at"`
+	UpdatedAt      string                   `dynamodbav:"updated_at"`
+	TitleHash      string                   `dynamodbav:"title_hash"`
+	AbstractHash   string                   `dynamodbav:"abstract_hash"`
+	Version        int                      `dynamodbav:"version"`
+	VersionHistory []processor.PaperVersion `dynamodbav:"version_history"`
+	Deleted        bool                     `dynamodbav:"deleted"`
+	// Metadata that is rewritten without a version bump when it changes
+	Authors       []string          `dynamodbav:"authors"`
+	AuthorIDs     []string          `dynamodbav:"author_ids"`
+	Categories    []string          `dynamodbav:"categories"`
+	DOI           string            `dynamodbav:"doi"`
+	Journal       string            `dynamodbav:"journal"`
+	CitationCount *int              `dynamodbav:"citation_count"`
+	SourceIDs     map[string]string `dynamodbav:"source_ids"`
+}
+
+// paperClassification counts how the pre-write lookup sorted a batch
+type paperClassification struct {
+	New        int
+	Changed    int
+	Refreshed  int // same content, different metadata; rewritten under the same version
+	Unchanged  int
+	Tombstoned int
+	newIDs     []string // the papers not stored before, in batch order
+}
+
+// classifyPapers batch-gets the stored content hashes of the incoming papers and returns
+// the papers that need writing. New papers are written as-is; changed papers get the previous
+// revision appended to their history; papers whose title and abstract are unchanged but whose
+// metadata (authors, categories, DOI, journal, citations, source IDs) differs are rewritten
+// under the same version; fully unchanged papers are skipped. Soft-deleted papers are
+// dropped so a re-ingest cannot resurrect a takedown. A failed lookup fails the whole batch:
+// writing blind would replace soft-deleted items and the history of stored ones.
+func (w *Writer) classifyPapers(ctx context.Context, papers []processor.Paper) ([]processor.Paper, paperClassification, error) {
+	var counts paperClassification
+
+	existing, err := w.fetchExistingPapers(ctx, papers)
+	if err != nil {
+		return nil, counts, fmt.Errorf("failed to fetch existing papers for versioning: %w", err)
+	}
+
+	toWrite := make([]processor.Paper, 0, len(papers))
+	for _, paper := range papers {
+		previous, found := existing[paper.PaperID]
+		switch {
+		case !found:
+			counts.New++
+			counts.newIDs = append(counts.newIDs, paper.PaperID)
+		case previous.Deleted:
+			counts.Tombstoned++
+			continue
+		case mergeVersionHistory(&paper, previous):
+			counts.Changed++
+		case metadataChanged(paper, previous):
+			counts.Refreshed++
+		default:
+			counts.Unchanged++
+			continue
+		}
+		toWrite = append(toWrite, paper)
+	}
+
+	w.logger.WithContext(ctx).Info("Classified papers before write", map[string]interface{}{
+		"batch_size":       len(papers),
+		"new_count":        counts.New,
+		"changed_count":    counts.Changed,
+		"refreshed_count":  counts.Refreshed,
+		"unchanged_count":  counts.Unchanged,
+		"tombstoned_count": counts.Tombstoned,
+	})
+
+	return toWrite, counts, nil
+}
+
+// mergeVersionHistory updates paper from its stored revision and reports whether the content changed
+func mergeVersionHistory(paper *processor.Paper, previous existingPaper) bool {
+	previousTitleHash := previous.TitleHash
+	if previousTitleHash == "" {
+		previousTitleHash = processor.ContentHash(previous.Title)
+	}
+	previousAbstractHash := previous.AbstractHash
+	if previousAbstractHash == "" {
+		previousAbstractHash = processor.ContentHash(previous.Abstract)
+	}
+
+	previousVersion := previous.Version
+	if previousVersion == 0 {
+		previousVersion = 1
+	}
+
+	if previous.CreatedAt != "" {
+		paper.CreatedAt = previous.CreatedAt
+	}
+	paper.VersionHistory = previous.VersionHistory
+	paper.Version = previousVersion
+
+	if previousTitleHash == paper.TitleHash && previousAbstractHash == paper.AbstractHash {
+		return false
+	}
+
+	history := append(paper.VersionHistory, processor.PaperVersion{
+		Version:      previousVersion,
+		TitleHash:    previousTitleHash,
+		AbstractHash: previousAbstractHash,
+		Source:       previous.Source,
+		TraceID:      previous.TraceID,
+		UpdatedAt:    previous.UpdatedAt,
+	})
+	if len(history) > MaxVersionHistory {
+		history = history[len(history)-MaxVersionHistory:]
+	}
+
+	paper.VersionHistory = history
+	paper.Version = previousVersion + 1
+	return true
+}
+
+// metadataChanged reports whether the fields stored outside the content hashes differ from
+// the stored revision
+func metadataChanged(paper processor.Paper, previous existingPaper) bool {
+	if !equalStrings(paper.Authors, previous.Authors) ||
+		!equalStrings(paper.AuthorIDs, previous.AuthorIDs) ||
+		!equalStrings(paper.Categories, previous.Categories) ||
+		paper.DOI != previous.DOI || paper.Journal != previous.Journal ||
+		len(paper.SourceIDs) != len(previous.SourceIDs) {
+		return true
+	}
+	if (paper.CitationCount == nil) != (previous.CitationCount == nil) ||
+		(paper.CitationCount != nil && *paper.CitationCount != *previous.CitationCount) {
+		return true
+	}
+	for source, id := range paper.SourceIDs {
+		if stored, ok := previous.SourceIDs[source]; !ok || stored != id {
+			return true
+		}
+	}
+	return false
+}
+
+// equalStrings compares two lists element by element; nil and empty lists are equal
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// fetchExistingPapers loads the stored revision of each paper in the batch, keyed by paper_id
+func (w *Writer) fetchExistingPapers(ctx context.Context, papers []processor.Paper) (map[string]existingPaper, error) {
+	keys := make([]map[string]*dynamodb.AttributeValue, 0, len(papers))
+	seen := make(map[string]bool)
+	for _, paper := range papers {
+		if paper.PaperID == "" || seen[paper.PaperID] {
+			continue
+		}
+		seen[paper.PaperID] = true
+		keys = append(keys, map[string]*dynamodb.AttributeValue{
+			"paper_id": {S: aws.String(paper.PaperID)},
+		})
+	}
+
+	existing := make(map[string]existingPaper)
+	if len(keys) == 0 {
+		return existing, nil
+	}
+
+	requestItems := map[string]*dynamodb.KeysAndAttributes{
+		w.tableName: {
+			Keys:                 keys,
+			ProjectionExpression: aws.String("#pid, #t, #a, #src, #tid, #ca, #ua, #th, #ah, #v, #vh, #del, #au, #aid, #cat, #doi, #jn, #cc, #sid"),
+			ExpressionAttributeNames: map[string]*string{
+				"#pid": aws.String("paper_id"),
+				"#t":   aws.String("title"),
+				"#a":   aws.String("abstract"),
+				"#src": aws.String("source"),
+				"#tid": aws.String("trace_id"),
+				"#ca":  aws.String("created_at"),
+				"#ua":  aws.String("updated_at"),
+				"#th":  aws.String("title_hash"),
+				"#ah":  aws.String("abstract_hash"),
+				"#v":   aws.String("version"),
+				"#vh":  aws.String("version_history"),
+				"#del": aws.String("deleted"),
+				"#au":  aws.String("authors"),
+				"#aid": aws.String("author_ids"),
+				"#cat": aws.String("categories"),
+				"#doi": aws.String("doi"),
+				"#jn":  aws.String("journal"),
+				"#cc":  aws.String("citation_count"),
+				"#sid": aws.String("source_ids"),
+			},
+		},
+	}
+
+	maxRetries := 3
+	delay := lookupRetryDelay
+	for attempt := 0; attempt < maxRetries && len(requestItems) > 0; attempt++ {
+		if attempt > 0 {
+			// Unprocessed keys mean the table is throttling; back off before asking again
+			select {
+			case <-ctx.Done():
+				return nil, fmt.Errorf("batch get canceled: %w", ctx.Err())
+			case <-time.After(delay):
+			}
+			delay *= 2
+		}
+
+		result, err := w.client.BatchGetItemWithContext(ctx, &dynamodb.BatchGetItemInput{
+			RequestItems: requestItems,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("batch get failed on attempt %d: %w", attempt+1, err)
+		}
+
+		for _, item := range result.Responses[w.tableName] {
+			var paper existingPaper
+			if err := dynamodbattribute.UnmarshalMap(item, &paper); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal existing paper: %w", err)
+			}
+			existing[paper.PaperID] = paper
+		}
+
+		requestItems = result.UnprocessedKeys
+	}
+
+	if len(requestItems) > 0 {
+		return nil, fmt.Errorf("unprocessed keys remained after %d retries", maxRetries)
+	}
+
+	return existing, nil
+}
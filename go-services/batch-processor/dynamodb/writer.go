@@ -4,9 +4,9 @@ import (
 	"batch-processor/processor"
 	"context"
 	"fmt"
+	"shared/awsclient"
 	"shared/logger"
 
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
@@ -21,15 +21,19 @@ const (
 type Writer struct {
 	client    dynamodbiface.DynamoDBAPI
 	tableName string
+	batchSize int
 	logger    *logger.Logger
+	// pauseCheck runs before every batch after the first; an error stops the upsert
+	pauseCheck func(ctx context.Context) error
 }
 
-// NewWriter creates a new DynamoDB writer instance
-func NewWriter(tableName string) *Writer {
-	sess := session.Must(session.NewSession())
+// NewWriter creates a new DynamoDB writer instance whose client uses clientConfig's retries and timeouts
+func NewWriter(tableName string, clientConfig awsclient.ClientConfig) *Writer {
+	sess := awsclient.MustClientSession(clientConfig)
 	return &Writer{
 		client:    dynamodb.New(sess),
 		tableName: tableName,
+		batchSize: MaxBatchSize,
 		logger:    logger.New("dynamodb-writer"),
 	}
 }
@@ -39,65 +43,115 @@ func NewWriterWithClient(client dynamodbiface.DynamoDBAPI, tableName string) *Wr
 	return &Writer{
 		client:    client,
 		tableName: tableName,
+		batchSize: MaxBatchSize,
 		logger:    logger.New("dynamodb-writer"),
 	}
 }
 
+// SetBatchSize sets the number of items per batch write; smaller batches smooth WCU consumption
+func (w *Writer) SetBatchSize(size int) error {
+	if size < 1 || size > MaxBatchSize {
+		return fmt.Errorf("batch size must be between 1 and %d, got %d", MaxBatchSize, size)
+	}
+	w.batchSize = size
+	return nil
+}
+
+// SetPauseCheck installs a check run between batches of BatchUpsertWithStats. When it returns
+// an error the remaining papers are reported as failed, so their records are retried later.
+func (w *Writer) SetPauseCheck(check func(ctx context.Context) error) {
+	w.pauseCheck = check
+}
+
 // BatchUpsert performs batch upsert operations on papers
 func (w *Writer) BatchUpsert(ctx context.Context, papers []processor.Paper) error {
 	if len(papers) == 0 {
-		w.logger.Info("No papers to upsert")
+		w.logger.WithContext(ctx).Info("No papers to upsert")
 		return nil
 	}
 
-	w.logger.InfoWithCount("Starting batch upsert", len(papers), map[string]interface{}{
+	w.logger.WithContext(ctx).InfoWithCount("Starting batch upsert", len(papers), map[string]interface{}{
 		"table_name": w.tableName,
 	})
 
-	// Process papers in batches of MaxBatchSize
-	for i := 0; i < len(papers); i += MaxBatchSize {
-		end := i + MaxBatchSize
+	// Process papers in batches of the configured size
+	for i := 0; i < len(papers); i += w.batchSize {
+		end := i + w.batchSize
 		if end > len(papers) {
 			end = len(papers)
 		}
 
 		batch := papers[i:end]
-		if err := w.processBatch(ctx, batch); err != nil {
+		if _, err := w.processBatch(ctx, batch); err != nil {
 			return fmt.Errorf("failed to process batch %d-%d: %w", i, end-1, err)
 		}
 
-		w.logger.Info("Successfully processed batch", map[string]interface{}{
+		w.logger.WithContext(ctx).Info("Successfully processed batch", map[string]interface{}{
 			"batch_start": i,
 			"batch_end":   end - 1,
 			"batch_size":  len(batch),
 		})
 	}
 
-	w.logger.InfoWithCount("Completed batch upsert", len(papers))
+	w.logger.WithContext(ctx).InfoWithCount("Completed batch upsert", len(papers))
 	return nil
 }
 
-// processBatch processes a single batch of papers
-func (w *Writer) processBatch(ctx context.Context, papers []processor.Paper) error {
+// batchOutcome reports how a batch was classified, which papers were written as new and
+// which did not land
+type batchOutcome struct {
+	paperClassification
+	written       int
+	writtenNewIDs []string
+	failedIDs     []string
+}
+
+// processBatch classifies a single batch of papers and writes the new and changed ones.
+// A non-nil error always comes with the failed IDs it applies to.
+func (w *Writer) processBatch(ctx context.Context, papers []processor.Paper) (*batchOutcome, error) {
+	outcome := &batchOutcome{}
 	if len(papers) == 0 {
-		return nil
+		return outcome, nil
 	}
 
-	if len(papers) > MaxBatchSize {
-		return fmt.Errorf("batch size %d exceeds maximum %d", len(papers), MaxBatchSize)
+	if len(papers) > w.batchSize {
+		outcome.failedIDs = paperIDs(papers)
+		return outcome, fmt.Errorf("batch size %d exceeds maximum %d", len(papers), w.batchSize)
 	}
 
+	// Skip unchanged papers and carry forward version history for changed ones
+	toWrite, classification, err := w.classifyPapers(ctx, papers)
+	if err != nil {
+		outcome.failedIDs = paperIDs(papers)
+		return outcome, err
+	}
+	outcome.paperClassification = classification
+	if len(toWrite) == 0 {
+		return outcome, nil
+	}
+
+	failedIDs, err := w.writeBatch(ctx, toWrite)
+	outcome.failedIDs = failedIDs
+	outcome.written = len(toWrite) - len(failedIDs)
+	outcome.writtenNewIDs = excludeIDs(classification.newIDs, failedIDs)
+	return outcome, err
+}
+
+// writeBatch writes papers with a single BatchWriteItem call and returns the IDs that did not land
+func (w *Writer) writeBatch(ctx context.Context, papers []processor.Paper) ([]string, error) {
 	// Convert papers to DynamoDB write requests
 	writeRequests := make([]*dynamodb.WriteRequest, 0, len(papers))
+	var failedIDs []string
 
 	for _, paper := range papers {
 		// Convert paper to DynamoDB item
 		item, err := dynamodbattribute.MarshalMap(paper)
 		if err != nil {
-			w.logger.Warn("Failed to marshal paper", map[string]interface{}{
+			w.logger.WithContext(ctx).Warn("Failed to marshal paper", map[string]interface{}{
 				"paper_id": paper.PaperID,
 				"error":    err.Error(),
 			})
+			failedIDs = append(failedIDs, paper.PaperID)
 			continue
 		}
 
@@ -112,21 +166,33 @@ func (w *Writer) processBatch(ctx context.Context, papers []processor.Paper) err
 	}
 
 	if len(writeRequests) == 0 {
-		return fmt.Errorf("no valid write requests generated from batch")
+		return failedIDs, fmt.Errorf("no valid write requests generated from batch")
 	}
 
 	// Execute batch write with retry logic
-	return w.executeBatchWriteWithRetry(ctx, writeRequests)
+	remaining, err := w.executeBatchWriteWithRetry(ctx, writeRequests)
+	for _, request := range remaining {
+		failedIDs = append(failedIDs, requestPaperID(request))
+	}
+
+	if err != nil {
+		return failedIDs, err
+	}
+	if len(failedIDs) > 0 {
+		return failedIDs, fmt.Errorf("%d papers in batch failed to marshal", len(failedIDs))
+	}
+	return nil, nil
 }
 
-// executeBatchWriteWithRetry executes batch write with retry for unprocessed items
-func (w *Writer) executeBatchWriteWithRetry(ctx context.Context, writeRequests []*dynamodb.WriteRequest) error {
+// executeBatchWriteWithRetry executes batch write with retry for unprocessed items.
+// On failure it returns the requests that were not written.
+func (w *Writer) executeBatchWriteWithRetry(ctx context.Context, writeRequests []*dynamodb.WriteRequest) ([]*dynamodb.WriteRequest, error) {
 	maxRetries := 3
 	currentRequests := writeRequests
 
 	for attempt := 0; attempt < maxRetries && len(currentRequests) > 0; attempt++ {
 		if attempt > 0 {
-			w.logger.Info("Retrying batch write", map[string]interface{}{
+			w.logger.WithContext(ctx).Info("Retrying batch write", map[string]interface{}{
 				"attempt":         attempt + 1,
 				"max_retries":     maxRetries,
 				"items_remaining": len(currentRequests),
@@ -141,65 +207,143 @@ func (w *Writer) executeBatchWriteWithRetry(ctx context.Context, writeRequests [
 
 		result, err := w.client.BatchWriteItemWithContext(ctx, input)
 		if err != nil {
-			return fmt.Errorf("batch write failed on attempt %d: %w", attempt+1, err)
+			return currentRequests, fmt.Errorf("batch write failed on attempt %d: %w", attempt+1, err)
 		}
 
 		// Check for unprocessed items
 		if unprocessedItems, exists := result.UnprocessedItems[w.tableName]; exists && len(unprocessedItems) > 0 {
 			currentRequests = unprocessedItems
-			w.logger.Info("Batch write partially succeeded", map[string]interface{}{
+			w.logger.WithContext(ctx).Info("Batch write partially succeeded", map[string]interface{}{
 				"unprocessed_items": len(unprocessedItems),
 			})
 		} else {
 			// All items processed successfully
-			w.logger.Info("Batch write completed successfully")
-			return nil
+			w.logger.WithContext(ctx).Info("Batch write completed successfully")
+			return nil, nil
 		}
 	}
 
 	// If we reach here, we still have unprocessed items after max retries
-	return fmt.Errorf("failed to process %d items after %d retries", len(currentRequests), maxRetries)
+	return currentRequests, fmt.Errorf("failed to process %d items after %d retries", len(currentRequests), maxRetries)
+}
+
+// requestPaperID extracts the paper_id from a put request
+func requestPaperID(request *dynamodb.WriteRequest) string {
+	if request.PutRequest == nil {
+		return ""
+	}
+	if id, ok := request.PutRequest.Item["paper_id"]; ok && id.S != nil {
+		return *id.S
+	}
+	return ""
+}
+
+// excludeIDs returns ids without the excluded ones
+func excludeIDs(ids, excluded []string) []string {
+	if len(excluded) == 0 {
+		return ids
+	}
+	skip := make(map[string]bool, len(excluded))
+	for _, id := range excluded {
+		skip[id] = true
+	}
+	kept := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if !skip[id] {
+			kept = append(kept, id)
+		}
+	}
+	return kept
+}
+
+// paperIDs returns the IDs of the given papers
+func paperIDs(papers []processor.Paper) []string {
+	ids := make([]string, len(papers))
+	for i, paper := range papers {
+		ids[i] = paper.PaperID
+	}
+	return ids
 }
 
 // BatchUpsertWithStats performs batch upsert and returns statistics
 func (w *Writer) BatchUpsertWithStats(ctx context.Context, papers []processor.Paper) (*processor.UpsertStats, error) {
 	stats := &processor.UpsertStats{
-		TotalItems:    len(papers),
-		BatchCount:    (len(papers) + MaxBatchSize - 1) / MaxBatchSize, // Ceiling division
-		SuccessItems:  0,
-		FailedItems:   0,
+		TotalItems:   len(papers),
+		BatchCount:   (len(papers) + w.batchSize - 1) / w.batchSize, // Ceiling division
+		SuccessItems: 0,
+		FailedItems:  0,
 	}
 
 	if len(papers) == 0 {
 		return stats, nil
 	}
 
-	w.logger.InfoWithCount("Starting batch upsert with stats tracking", len(papers))
+	w.logger.WithContext(ctx).InfoWithCount("Starting batch upsert with stats tracking", len(papers))
 
 	// Process papers in batches
-	for i := 0; i < len(papers); i += MaxBatchSize {
-		end := i + MaxBatchSize
+	for i := 0; i < len(papers); i += w.batchSize {
+		end := i + w.batchSize
 		if end > len(papers) {
 			end = len(papers)
 		}
 
 		batch := papers[i:end]
-		if err := w.processBatch(ctx, batch); err != nil {
-			w.logger.Error("Batch failed", err, map[string]interface{}{
-				"batch_number": i/MaxBatchSize + 1,
+		if i > 0 && w.pauseCheck != nil {
+			if err := w.pauseCheck(ctx); err != nil {
+				remaining := papers[i:]
+				w.logger.WithContext(ctx).Warn("Batch upsert paused", map[string]interface{}{
+					"batch_number":  i/w.batchSize + 1,
+					"skipped_items": len(remaining),
+					"error":         err.Error(),
+				})
+				stats.FailedItems += len(remaining)
+				stats.FailedPaperIDs = append(stats.FailedPaperIDs, paperIDs(remaining)...)
+				stats.FailedBatches += stats.BatchCount - i/w.batchSize
+				stats.Paused = true
+				break
+			}
+		}
+		outcome, err := w.processBatch(ctx, batch)
+		failedIDs := outcome.failedIDs
+		stats.NewItems += outcome.New
+		stats.ChangedItems += outcome.Changed
+		stats.RefreshedItems += outcome.Refreshed
+		stats.UnchangedItems += outcome.Unchanged
+		stats.TombstonedItems += outcome.Tombstoned
+		stats.NewPaperIDs = append(stats.NewPaperIDs, outcome.writtenNewIDs...)
+		switch {
+		case err == nil:
+			stats.SuccessItems += outcome.written
+			stats.SuccessBatches++
+		case len(failedIDs) < len(batch):
+			w.logger.WithContext(ctx).Warn("Batch partially failed", map[string]interface{}{
+				"batch_number": i/w.batchSize + 1,
+				"failed_items": len(failedIDs),
+				"batch_size":   len(batch),
+				"error":        err.Error(),
 			})
-			stats.FailedItems += len(batch)
+			stats.SuccessItems += outcome.written
+			stats.FailedItems += len(failedIDs)
+			stats.FailedPaperIDs = append(stats.FailedPaperIDs, failedIDs...)
+			stats.PartialBatches++
+		default:
+			w.logger.WithContext(ctx).Error("Batch failed", err, map[string]interface{}{
+				"batch_number": i/w.batchSize + 1,
+			})
+			stats.FailedItems += len(failedIDs)
+			stats.FailedPaperIDs = append(stats.FailedPaperIDs, failedIDs...)
 			stats.FailedBatches++
-		} else {
-			stats.SuccessItems += len(batch)
-			stats.SuccessBatches++
 		}
 	}
 
-	w.logger.Info("Batch upsert completed", map[string]interface{}{
-		"success_items": stats.SuccessItems,
-		"failed_items":  stats.FailedItems,
+	w.logger.WithContext(ctx).Info("Batch upsert completed", map[string]interface{}{
+		"success_items":   stats.SuccessItems,
+		"failed_items":    stats.FailedItems,
+		"partial_batches": stats.PartialBatches,
+		"new_items":       stats.NewItems,
+		"changed_items":   stats.ChangedItems,
+		"refreshed_items": stats.RefreshedItems,
+		"unchanged_items": stats.UnchangedItems,
 	})
 	return stats, nil
 }
-
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"batch-processor/config"
+	"batch-processor/processor"
+	"shared/failures"
+	"shared/logger"
+)
+
+// recordFailedItems stores every failed S3 object of the run in the FailedItems table, when
+// FAILED_ITEMS_TABLE_NAME is set. A failed write only loses the entries, like the run record.
+func recordFailedItems(ctx context.Context, contextLogger *logger.Logger, cfg *config.Config, result *processor.ProcessResult) {
+	tableName := os.Getenv(failures.TableEnv)
+	if tableName == "" {
+		return
+	}
+
+	var failed []failures.Failure
+	for _, record := range result.RecordResults {
+		if record.Status != processor.RecordStatusFailed {
+			continue
+		}
+		failed = append(failed, failures.Failure{
+			Stage:    failures.StageIngestion,
+			RecordID: record.Bucket + "/" + record.Key,
+			TraceID:  result.TraceID,
+			Code:     failures.Code(record.Code),
+			Message:  record.Error,
+		})
+	}
+	if len(failed) == 0 {
+		return
+	}
+
+	if err := failures.NewRecorder(tableName, cfg.AWS.DynamoDB.Client).Record(ctx, failed); err != nil {
+		contextLogger.Warn("Failed to record failed items", map[string]interface{}{
+			"table": tableName,
+			"error": err.Error(),
+		})
+	}
+}
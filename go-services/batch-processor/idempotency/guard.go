@@ -0,0 +1,174 @@
+// Package idempotency suppresses duplicate S3 notifications. S3 may deliver several
+// notifications for the same object during eventual-consistency windows; the first delivery
+// claims the object version in a DynamoDB table with a conditional put, and later deliveries
+// are skipped instead of being processed again.
+//
+// A claim has two states. It starts in_progress with a lease that ends with the invocation,
+// so an invocation that times out or crashes does not hold the object: the redelivered
+// notification takes the expired lease over. Once the object is written the claim is
+// completed and suppresses duplicates for the whole window.
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"shared/awsclient"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// DefaultWindow is how long a completed claim suppresses duplicates when no window is configured
+const DefaultWindow = 15 * time.Minute
+
+// DefaultLease is how long an in-progress claim is held when the context has no deadline
+const DefaultLease = 5 * time.Minute
+
+// Table attributes; expires_at is the table's TTL attribute
+const (
+	attributeEventKey    = "event_key"
+	attributeExpiresAt   = "expires_at"
+	attributeClaimedAt   = "claimed_at"
+	attributeStatus      = "status"
+	attributeCompletedAt = "completed_at"
+)
+
+// Claim states
+const (
+	StatusInProgress = "in_progress"
+	StatusCompleted  = "completed"
+)
+
+// Guard claims object versions in the idempotency table
+type Guard struct {
+	client    dynamodbiface.DynamoDBAPI
+	tableName string
+	window    time.Duration
+	now       func() time.Time
+}
+
+// NewGuard creates a guard for the given table whose client uses clientConfig's retries and timeouts
+func NewGuard(tableName string, window time.Duration, clientConfig awsclient.ClientConfig) *Guard {
+	sess := awsclient.MustClientSession(clientConfig)
+	return NewGuardWithClient(dynamodb.New(sess), tableName, window)
+}
+
+// NewGuardWithClient creates a guard with a custom DynamoDB client (for testing)
+func NewGuardWithClient(client dynamodbiface.DynamoDBAPI, tableName string, window time.Duration) *Guard {
+	if window <= 0 {
+		window = DefaultWindow
+	}
+	return &Guard{
+		client:    client,
+		tableName: tableName,
+		window:    window,
+		now:       time.Now,
+	}
+}
+
+// EventKey identifies one version of an object. The ETag is part of the key, so an object
+// overwritten with new content is processed again.
+func EventKey(bucket, key, etag string) string {
+	return bucket + "/" + key + "#" + strings.Trim(etag, `"`)
+}
+
+// Claim records that the object version is being processed, leased until the context's
+// deadline (the invocation's timeout) or DefaultLease without one. It returns false when a
+// completed claim or a live lease already exists, i.e. the notification is a duplicate.
+// Expired claims and leases are taken over, since DynamoDB TTL deletes items only eventually.
+func (g *Guard) Claim(ctx context.Context, bucket, key, etag string) (bool, error) {
+	eventKey := EventKey(bucket, key, etag)
+	now := g.now()
+	_, err := g.client.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(g.tableName),
+		Item: map[string]*dynamodb.AttributeValue{
+			attributeEventKey:  {S: aws.String(eventKey)},
+			attributeStatus:    {S: aws.String(StatusInProgress)},
+			attributeExpiresAt: {N: aws.String(strconv.FormatInt(now.Add(g.lease(ctx, now)).Unix(), 10))},
+			attributeClaimedAt: {S: aws.String(now.UTC().Format(time.RFC3339))},
+		},
+		ConditionExpression: aws.String("attribute_not_exists(#key) OR #expires < :now"),
+		ExpressionAttributeNames: map[string]*string{
+			"#key":     aws.String(attributeEventKey),
+			"#expires": aws.String(attributeExpiresAt),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":now": {N: aws.String(strconv.FormatInt(now.Unix(), 10))},
+		},
+	})
+	if err != nil {
+		var aerr awserr.Error
+		if errors.As(err, &aerr) && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to claim %s: %w", eventKey, err)
+	}
+	return true, nil
+}
+
+// Complete marks a claimed object version as processed, so duplicates are skipped for the
+// whole window rather than only while the lease lasts
+func (g *Guard) Complete(ctx context.Context, bucket, key, etag string) error {
+	eventKey := EventKey(bucket, key, etag)
+	now := g.now()
+	_, err := g.client.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(g.tableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			attributeEventKey: {S: aws.String(eventKey)},
+		},
+		UpdateExpression: aws.String("SET #status = :completed, #expires = :expires, #completed = :now"),
+		ExpressionAttributeNames: map[string]*string{
+			"#status":    aws.String(attributeStatus),
+			"#expires":   aws.String(attributeExpiresAt),
+			"#completed": aws.String(attributeCompletedAt),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":completed": {S: aws.String(StatusCompleted)},
+			":expires":   {N: aws.String(strconv.FormatInt(now.Add(g.window).Unix(), 10))},
+			":now":       {S: aws.String(now.UTC().Format(time.RFC3339))},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to complete %s: %w", eventKey, err)
+	}
+	return nil
+}
+
+// lease is how long a new claim stays in progress: until the context's deadline, never longer
+// than the window
+func (g *Guard) lease(ctx context.Context, now time.Time) time.Duration {
+	lease := DefaultLease
+	if deadline, ok := ctx.Deadline(); ok {
+		lease = deadline.Sub(now)
+	}
+	if lease > g.window {
+		lease = g.window
+	}
+	if lease < time.Second {
+		lease = time.Second
+	}
+	return lease
+}
+
+// Release removes a claim, so a retried notification for an object that failed to process
+// is not mistaken for a duplicate
+func (g *Guard) Release(ctx context.Context, bucket, key, etag string) error {
+	eventKey := EventKey(bucket, key, etag)
+	_, err := g.client.DeleteItemWithContext(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(g.tableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			attributeEventKey: {S: aws.String(eventKey)},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to release %s: %w", eventKey, err)
+	}
+	return nil
+}
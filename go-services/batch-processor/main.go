@@ -3,62 +3,267 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
+	"batch-processor/authors"
+	"batch-processor/categories"
+	"batch-processor/config"
 	"batch-processor/deduplicator"
 	"batch-processor/dynamodb"
+	"batch-processor/idempotency"
 	"batch-processor/processor"
+	"batch-processor/runs"
 	"batch-processor/s3"
+	"batch-processor/webhook"
 	"shared/logger"
+	"shared/pauseflags"
+	"shared/shutdown"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-lambda-go/lambda/messages"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 )
 
 func main() {
 	if os.Getenv("AWS_LAMBDA_FUNCTION_NAME") != "" {
-		lambda.Start(handleS3Event)
+		lambda.Start(handleEvent)
 	} else {
+		serveAddr := flag.String("serve", "", "run an HTTP server on this address (e.g. :8080) instead of processing arguments")
+		flag.Parse()
+
+		appLogger := logger.New("batch-processor")
+		watcher := shutdown.Watch(appLogger)
+		if *serveAddr != "" {
+			watcher.Exit(runServer(*serveAddr, watcher, appLogger), false)
+		}
+
 		fmt.Println("Batch Processor Service - Local Development Mode")
+		if args := flag.Args(); len(args) > 0 && args[0] == replayCommand {
+			runReplay(args[1:], watcher)
+			return
+		}
+		runLocal(flag.Args(), watcher)
 	}
 }
 
+// runLocal processes the s3://bucket/key objects given on the command line.
+// SIGINT/SIGTERM stop further objects from being read while already-read papers are written.
+func runLocal(args []string, watcher *shutdown.Watcher) {
+
+	s3Event, err := buildLocalEvent(args)
+	if err != nil {
+		watcher.Exit(err, false)
+	}
+	if len(s3Event.Records) == 0 {
+		fmt.Println("Usage: batch-processor [--serve addr] s3://bucket/key [s3://bucket/key ...]")
+		fmt.Println("       batch-processor replay s3://bucket/run-manifest.json [...]")
+		return
+	}
+
+	result, err := processS3Event(context.Background(), s3Event, watcher.Done())
+	watcher.Exit(err, result != nil && result.Interrupted)
+}
+
+// buildLocalEvent converts s3://bucket/key arguments into an S3 event
+func buildLocalEvent(args []string) (events.S3Event, error) {
+	var s3Event events.S3Event
+	for _, arg := range args {
+		location := strings.TrimPrefix(arg, "s3://")
+		bucket, key, found := strings.Cut(location, "/")
+		if !found || bucket == "" || key == "" {
+			return s3Event, fmt.Errorf("invalid S3 location %q, expected s3://bucket/key", arg)
+		}
+		record := events.S3EventRecord{}
+		record.S3.Bucket.Name = bucket
+		record.S3.Object.Key = key
+		s3Event.Records = append(s3Event.Records, record)
+	}
+	return s3Event, nil
+}
+
 func handleS3Event(ctx context.Context, s3Event events.S3Event) (*processor.ProcessResult, error) {
+	result, err := processS3Event(ctx, s3Event, nil)
+	if err != nil {
+		return nil, lambdaError(err)
+	}
+	return result, nil
+}
+
+// lambdaError reports AppErrors to Lambda with their error type (e.g. CONFIG_ERROR) as the
+// errorType, so Step Functions retry and catch policies can match on it
+func lambdaError(err error) error {
+	var appErr *logger.AppError
+	if errors.As(err, &appErr) {
+		return messages.InvokeResponse_Error{
+			Type:    string(appErr.Type),
+			Message: appErr.Error(),
+		}
+	}
+	return err
+}
+
+// processS3Event wires the pipeline components and processes the event; shutdown may be nil
+func processS3Event(ctx context.Context, s3Event events.S3Event, shutdown <-chan struct{}) (*processor.ProcessResult, error) {
 	// Create shared logger
 	appLogger := logger.New("batch-processor")
 	contextLogger := appLogger.WithContext(ctx)
-	
+
 	contextLogger.InfoWithCount("Processing S3 records", len(s3Event.Records))
-	
+
 	// Create S3 downloader
 	downloader := s3.NewDownloader()
-	
-	// Create deduplicator
-	dedup := deduplicator.NewDeduplicator()
-	
+
+	// Load pipeline configuration
+	cfg, err := loadConfiguration(ctx, contextLogger)
+	if err != nil {
+		return nil, err
+	}
+
+	// Operators halt ingestion writes through the pause flags; SQS messages stay queued meanwhile
+	validateOnly := os.Getenv("RUN_MODE") == "validate"
+	pauseChecker, err := pauseflags.NewChecker(cfg.Pause)
+	if err != nil {
+		return nil, logger.WrapError(err, logger.ErrorTypeConfig, "failed to initialize pause flags")
+	}
+	if !validateOnly {
+		if err := checkPause(ctx, contextLogger, pauseChecker, pauseflags.IngestionWrites); err != nil {
+			return nil, err
+		}
+	}
+
+	// Create deduplicator with the configured strategy chain
+	dedup, err := deduplicator.NewDeduplicatorWithStrategies(cfg.Processing.DedupStrategies)
+	if err != nil {
+		contextLogger.Error("Invalid deduplication configuration", err)
+		return nil, logger.WrapError(err, logger.ErrorTypeConfig, "invalid deduplication strategies")
+	}
+	if policy := cfg.Processing.MergePolicy; policy.Enabled {
+		mergePolicy, err := deduplicator.NewMergePolicy(policy.SourcePriority, policy.Abstract, policy.UnionCategories, policy.UnionAuthors)
+		if err != nil {
+			contextLogger.Error("Invalid merge policy configuration", err)
+			return nil, logger.WrapError(err, logger.ErrorTypeConfig, "invalid merge policy")
+		}
+		dedup.SetMergePolicy(mergePolicy)
+	}
+
+	// Fail fast when a table or bucket is missing, before any object is read
+	if err := runPreflight(ctx, cfg, s3Event); err != nil {
+		contextLogger.Error("Pre-flight check failed", err)
+		return nil, err
+	}
+
 	// Create DynamoDB writer (table name from environment variable)
-	tableName := os.Getenv("PAPERS_TABLE_NAME")
-	if tableName == "" {
-		tableName = "Papers" // Default table name
+	tableName := papersTableName()
+	dynamoWriter := dynamodb.NewWriter(tableName, cfg.AWS.DynamoDB.Client)
+	if err := dynamoWriter.SetBatchSize(cfg.Processing.BatchSize); err != nil {
+		contextLogger.Error("Invalid batch size configuration", err)
+		return nil, logger.WrapError(err, logger.ErrorTypeConfig, "invalid processing batch size")
 	}
-	dynamoWriter := dynamodb.NewWriter(tableName)
-	
+	dynamoWriter.SetPauseCheck(func(ctx context.Context) error {
+		return checkPause(ctx, contextLogger, pauseChecker, pauseflags.IngestionWrites)
+	})
+
 	// Create processor
 	eventProcessor := processor.NewS3EventProcessor(downloader, dedup, dynamoWriter, contextLogger)
-	
+	eventProcessor.SetValidateOnly(validateOnly)
+	eventProcessor.SetShutdown(shutdown)
+	eventProcessor.SetDeterministicTraceID(cfg.Processing.TraceIDMode == config.TraceIDDeterministic)
+
+	// Enable new-paper webhooks when URLs are configured (comma-separated)
+	if webhookURLs := parseList(os.Getenv("WEBHOOK_URLS")); len(webhookURLs) > 0 {
+		eventProcessor.SetWebhookEmitter(webhook.NewEmitter(webhookURLs, os.Getenv("WEBHOOK_SECRET")))
+	}
+
+	// Skip papers in unwanted categories so they never consume storage or embedding budget
+	categoryFilter := categories.NewFilter(cfg.Processing.CategoryFilter.Allow, cfg.Processing.CategoryFilter.Deny)
+	if categoryFilter.Active() {
+		eventProcessor.SetCategoryFilter(categoryFilter)
+	}
+
+	// Skip duplicate S3 notifications for an object version that was already claimed
+	if dedupTable := os.Getenv("EVENT_DEDUP_TABLE_NAME"); dedupTable != "" {
+		window := time.Duration(cfg.Processing.EventDedupWindow) * time.Second
+		eventProcessor.SetEventGuard(idempotency.NewGuard(dedupTable, window, cfg.AWS.DynamoDB.Client))
+	}
+
+	// Enable author disambiguation when an Authors table is configured
+	if authorsTable := os.Getenv("AUTHORS_TABLE_NAME"); authorsTable != "" {
+		eventProcessor.SetAuthorResolver(authors.NewResolver(authorsTable, cfg.AWS.DynamoDB.Client))
+	}
+
 	// Process the S3 event
 	result, err := eventProcessor.ProcessS3Event(ctx, s3Event)
 	if err != nil {
 		contextLogger.Error("Error processing S3 event", err)
 		return nil, err
 	}
-	
+
+	// Persist the run for the status API and digest generator; a failed write only loses the record
+	if runsTable := os.Getenv("PROCESSING_RUNS_TABLE_NAME"); runsTable != "" && !validateOnly {
+		if err := runs.NewRecorder(runsTable, cfg.AWS.DynamoDB.Client).RecordRun(ctx, result); err != nil {
+			contextLogger.Warn("Failed to record processing run", map[string]interface{}{
+				"table": runsTable,
+				"error": err.Error(),
+			})
+		}
+	}
+
+	if !validateOnly {
+		recordFailedItems(ctx, contextLogger, cfg, result)
+		emitSLO(contextLogger, cfg.SLO, result)
+	}
+
 	// Log the result
 	resultJSON, _ := json.Marshal(result)
 	contextLogger.Info("Processing completed successfully", map[string]interface{}{
 		"result": string(resultJSON),
 	})
-	
+
 	return result, nil
-}
\ No newline at end of file
+}
+
+// loadConfiguration loads the pipeline configuration from S3. Defaults apply only when no
+// config location is set: a config that cannot be read, parsed or validated fails the run
+// rather than silently dropping its category filter and dedup chain.
+func loadConfiguration(ctx context.Context, contextLogger *logger.Logger) (*config.Config, error) {
+	configBucket := os.Getenv("CONFIG_BUCKET")
+	configKey := os.Getenv("CONFIG_KEY")
+	if configBucket == "" || configKey == "" {
+		return config.GetDefaultConfig(), nil
+	}
+
+	configManager, err := config.NewManager()
+	if err != nil {
+		return nil, logger.WrapError(err, logger.ErrorTypeConfig, "failed to create config manager")
+	}
+	cfg, err := configManager.LoadFromS3(ctx, configBucket, configKey)
+	if err != nil {
+		contextLogger.Error("Failed to load config from S3", err, map[string]interface{}{
+			"bucket": configBucket,
+			"key":    configKey,
+		})
+		var awsErr awserr.Error
+		if errors.As(err, &awsErr) {
+			return nil, logger.WrapError(err, logger.ErrorTypeS3, "failed to read pipeline config")
+		}
+		return nil, logger.WrapError(err, logger.ErrorTypeConfig, "invalid pipeline config")
+	}
+	return cfg, nil
+}
+
+// parseList splits a comma-separated environment value into trimmed, non-empty entries
+func parseList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"shared/logger"
+	"shared/pauseflags"
+)
+
+// checkPause fails with a PAUSED error while stage is paused; a flag that cannot be read
+// is logged and does not stop the run
+func checkPause(ctx context.Context, contextLogger *logger.Logger, checker *pauseflags.Checker, stage pauseflags.Stage) error {
+	err := checker.Check(ctx, stage)
+	if errors.Is(err, pauseflags.ErrPaused) {
+		contextLogger.Warn("Stage paused by operator flag, stopping", map[string]interface{}{
+			"stage": string(stage),
+		})
+		return logger.WrapError(err, logger.ErrorTypePaused, "ingestion writes paused")
+	}
+	if err != nil {
+		contextLogger.Warn("Failed to read pause flags, continuing", map[string]interface{}{
+			"stage": string(stage),
+			"error": err.Error(),
+		})
+	}
+	return nil
+}
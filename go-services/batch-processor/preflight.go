@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"os"
+	"sort"
+
+	"batch-processor/authors"
+	"batch-processor/config"
+	"shared/awsclient"
+	"shared/failures"
+	"shared/preflight"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// preflightGuard verifies the processor's resources on the first invocation of a container
+var preflightGuard preflight.Guard
+
+// papersTableName is the Papers table from PAPERS_TABLE_NAME, "Papers" by default
+func papersTableName() string {
+	if tableName := os.Getenv("PAPERS_TABLE_NAME"); tableName != "" {
+		return tableName
+	}
+	return "Papers"
+}
+
+// runPreflight checks that the Papers table, the optional Authors, ProcessingRuns, event dedup and FailedItems tables
+// and the buckets the event reads from exist, failing with a CONFIG_ERROR listing the rest
+func runPreflight(ctx context.Context, cfg *config.Config, s3Event events.S3Event) error {
+	sess := awsclient.MustClientSession(cfg.AWS.DynamoDB.Client)
+	dynamoClient := dynamodb.New(sess)
+	checks := []preflight.Check{
+		preflight.Table(dynamoClient, papersTableName()),
+	}
+	if authorsTable := os.Getenv("AUTHORS_TABLE_NAME"); authorsTable != "" {
+		checks = append(checks, preflight.TableWithIndexes(dynamoClient, authorsTable, preflight.Index{
+			Name:         authors.NameKeyIndex,
+			PartitionKey: authors.NameKeyAttribute,
+		}))
+	}
+	if runsTable := os.Getenv("PROCESSING_RUNS_TABLE_NAME"); runsTable != "" {
+		checks = append(checks, preflight.Table(dynamoClient, runsTable))
+	}
+	if dedupTable := os.Getenv("EVENT_DEDUP_TABLE_NAME"); dedupTable != "" {
+		checks = append(checks, preflight.Table(dynamoClient, dedupTable))
+	}
+	if failedTable := os.Getenv(failures.TableEnv); failedTable != "" {
+		checks = append(checks, preflight.TableWithIndexes(dynamoClient, failedTable, preflight.Index{
+			Name:         failures.CodeIndex,
+			PartitionKey: failures.CodeIndexPartitionKey,
+			SortKey:      failures.CodeIndexSortKey,
+		}))
+	}
+
+	buckets := make(map[string]bool)
+	for _, record := range s3Event.Records {
+		buckets[record.S3.Bucket.Name] = true
+	}
+	names := make([]string, 0, len(buckets))
+	for bucket := range buckets {
+		names = append(names, bucket)
+	}
+	sort.Strings(names)
+	s3Client := s3.New(sess)
+	for _, bucket := range names {
+		checks = append(checks, preflight.Bucket(s3Client, bucket))
+	}
+
+	return preflightGuard.Run(ctx, checks)
+}
@@ -0,0 +1,131 @@
+package processor
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// collectionLinesHeader is the first line of a schema 2 payload, the collection result
+// without its papers; Count paper lines follow it
+type collectionLinesHeader struct {
+	Count *int `json:"count"`
+}
+
+// collectionLinesTrailer is the last line of a schema 2 payload. The collector streams these
+// objects, so their checksum is written here rather than in the object metadata.
+type collectionLinesTrailer struct {
+	PaperCount    int    `json:"paper_count"`
+	PayloadSHA256 string `json:"payload_sha256"`
+}
+
+// streamIntegrityError reports a schema 2 payload that is truncated or does not match its
+// trailer
+type streamIntegrityError struct {
+	reason string
+}
+
+func (e *streamIntegrityError) Error() string {
+	return "corrupted collection stream: " + e.reason
+}
+
+// IntegrityFailure marks the error as corruption rather than a parse failure
+func (e *streamIntegrityError) IntegrityFailure() bool { return true }
+
+// parseCollectionLines parses a schema 2 payload: a header line, one paper per line and a
+// trailer line holding the paper count and the SHA-256 of everything before it. Papers that
+// fail to parse are skipped as in other line streams; a missing or mismatched trailer fails
+// the object.
+func (p *S3EventProcessor) parseCollectionLines(reader io.Reader, traceID string, batchTimestamp time.Time) ([]Paper, error) {
+	tracedLogger := p.logger.WithTraceID(traceID)
+	buffered := bufio.NewReader(reader)
+	checksum := sha256.New()
+
+	readLine := func() ([]byte, error) {
+		line, err := buffered.ReadBytes('\n')
+		if err == io.EOF && len(line) > 0 {
+			err = nil
+		}
+		return line, err
+	}
+
+	line, err := readLine()
+	if err == io.EOF {
+		return nil, &streamIntegrityError{reason: "missing header"}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stream header: %w", err)
+	}
+	checksum.Write(line)
+	var header collectionLinesHeader
+	if err := json.Unmarshal(line, &header); err != nil || header.Count == nil || *header.Count < 0 {
+		return nil, &streamIntegrityError{reason: "invalid header"}
+	}
+
+	var papers []Paper
+	for index := 0; index < *header.Count; index++ {
+		lineNumber := index + 2
+		line, err := readLine()
+		if err == io.EOF {
+			return nil, &streamIntegrityError{reason: fmt.Sprintf("stream ends after %d of %d papers", index, *header.Count)}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read line %d: %w", lineNumber, err)
+		}
+		checksum.Write(line)
+
+		var paperData map[string]interface{}
+		if err := json.Unmarshal(line, &paperData); err != nil {
+			tracedLogger.Warn("Failed to parse line as JSON", map[string]interface{}{
+				"event":        "warning",
+				"warning_type": "json_parsing",
+				"context": map[string]interface{}{
+					"line_number": lineNumber,
+					"error":       err.Error(),
+				},
+			})
+			continue
+		}
+		paper, err := p.convertMapToPaper(paperData, traceID, batchTimestamp)
+		if err != nil {
+			tracedLogger.Warn("Failed to convert paper data from line", map[string]interface{}{
+				"event":        "warning",
+				"warning_type": "data_conversion",
+				"context": map[string]interface{}{
+					"line_number": lineNumber,
+					"error":       err.Error(),
+				},
+			})
+			continue
+		}
+		papers = append(papers, paper)
+	}
+
+	line, err = readLine()
+	if err == io.EOF {
+		return nil, &streamIntegrityError{reason: "missing trailer"}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stream trailer: %w", err)
+	}
+	var trailer collectionLinesTrailer
+	if err := json.Unmarshal(line, &trailer); err != nil {
+		return nil, &streamIntegrityError{reason: "invalid trailer"}
+	}
+	if trailer.PaperCount != *header.Count {
+		return nil, &streamIntegrityError{reason: fmt.Sprintf("trailer counts %d papers, header %d", trailer.PaperCount, *header.Count)}
+	}
+	if actual := hex.EncodeToString(checksum.Sum(nil)); actual != strings.ToLower(trailer.PayloadSHA256) {
+		return nil, &streamIntegrityError{reason: fmt.Sprintf("expected sha256 %s, got %s", trailer.PayloadSHA256, actual)}
+	}
+
+	if len(papers) == 0 {
+		return nil, fmt.Errorf("no valid papers found in data")
+	}
+	return papers, nil
+}
@@ -1,14 +1,19 @@
 package processor
 
 import (
+	"bytes"
 	"context"
-	"encoding/json"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"path"
 	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/aws/aws-lambda-go/events"
-	"github.com/google/uuid"
+	"shared/failures"
 	"shared/logger"
 )
 
@@ -19,37 +24,135 @@ type Paper struct {
 	Title         string    `json:"title"`
 	Abstract      string    `json:"abstract"`
 	Authors       []string  `json:"authors"`
+	AuthorIDs     []string  `json:"author_ids,omitempty"` // resolved author entities, in Authors order
 	PublishedDate string    `json:"published_date"`
 	Categories    []string  `json:"categories"`
+	DOI           string    `json:"doi,omitempty"`
+	Journal       string    `json:"journal,omitempty"`        // from the collector's CrossRef enrichment
+	CitationCount *int      `json:"citation_count,omitempty"` // as of the collection run
+	SourceIDs     map[string]string `json:"source_ids,omitempty"` // source -> paper ID of every record merged into this one
 	RawXML        string    `json:"raw_xml,omitempty"`
 	TraceID       string    `json:"trace_id"`
 	BatchTimestamp string   `json:"batch_timestamp"`
 	ProcessingStatus string `json:"processing_status"`
 	CreatedAt     string    `json:"created_at"`
 	UpdatedAt     string    `json:"updated_at"`
+	TitleHash     string    `json:"title_hash,omitempty"`
+	AbstractHash  string    `json:"abstract_hash,omitempty"`
+	Version       int       `json:"version,omitempty"`
+	VersionHistory []PaperVersion `json:"version_history,omitempty"`
+	RawDataBucket string    `json:"raw_data_bucket,omitempty"`
+	RawDataKey    string    `json:"raw_data_key,omitempty"`
+	CollectionRunID string  `json:"collection_run_id,omitempty"`
+	CollectedAt   string    `json:"collected_at,omitempty"` // when the collection run that fetched the paper started
+	IngestedAt    string    `json:"ingested_at,omitempty"`  // when this processor parsed the paper
+	Deleted       bool      `json:"deleted,omitempty"`
+	DeletedAt     string    `json:"deleted_at,omitempty"`
+}
+
+// PaperVersion records the content hashes of a superseded paper revision
+type PaperVersion struct {
+	Version      int    `json:"version"`
+	TitleHash    string `json:"title_hash"`
+	AbstractHash string `json:"abstract_hash"`
+	Source       string `json:"source"`
+	TraceID      string `json:"trace_id"`
+	UpdatedAt    string `json:"updated_at"`
 }
 
 // ProcessResult represents the result of batch processing
 type ProcessResult struct {
 	TraceID            string              `json:"trace_id"`
-	ProcessedCount     int                 `json:"processed_count"`
+	ProcessedCount     int                 `json:"processed_count"` // papers upserted without error, whether written or unchanged
+	WrittenCount       int                 `json:"written_count"` // new, changed and refreshed papers written under this trace ID
 	Timestamp          time.Time           `json:"timestamp"`
 	Status             string              `json:"status"`
 	ErrorMessage       string              `json:"error_message,omitempty"`
 	DeduplicationStats *DeduplicationStats `json:"deduplication_stats,omitempty"`
 	UpsertStats        *UpsertStats        `json:"upsert_stats,omitempty"`
+	AuthorStats        *AuthorStats        `json:"author_stats,omitempty"`
+	Validation         *ValidationReport   `json:"validation,omitempty"`
+	Interrupted        bool                `json:"interrupted,omitempty"`
+	SkippedObjects     []string            `json:"skipped_objects,omitempty"`
+	RecordResults      []RecordResult      `json:"record_results,omitempty"`
+	CategoryFiltered   int                 `json:"category_filtered,omitempty"` // papers dropped by the category filter before deduplication
+	CoalescedRequests  int                 `json:"coalesced_requests,omitempty"` // events merged into this processing pass; stats cover all of them
+	DuplicateObjects   int                 `json:"duplicate_objects,omitempty"`  // notifications skipped because the object version was already claimed
+}
+
+// Record outcomes reported per S3 event record
+const (
+	RecordStatusProcessed = "processed"
+	RecordStatusFailed    = "failed"
+	RecordStatusSkipped   = "skipped"
+	RecordStatusDuplicate = "duplicate"
+	RecordStatusIgnored   = "ignored" // a parts manifest, whose parts raise their own events
+)
+
+// partsManifestName is the key name of the manifest the data collector writes next to the
+// part objects of a split result
+const partsManifestName = "manifest.json"
+
+// RecordResult is the outcome of a single S3 event record, in event order.
+// A record fails when its object cannot be read or any of its papers fail to upsert,
+// so callers such as the SQS handler can retry just that object.
+type RecordResult struct {
+	Bucket     string         `json:"bucket"`
+	Key        string         `json:"key"`
+	Status     string         `json:"status"`
+	PaperCount int            `json:"paper_count"`
+	Error      string         `json:"error,omitempty"`
+	Code       string         `json:"code,omitempty"` // normalized failure code (failures.Code) of a failed record
+	Timings    *ObjectTimings `json:"timings,omitempty"`
 }
 
+// ObjectTimings breaks down the time spent on one record's object, so hot spots in
+// multi-record events can be found rather than only the end-to-end duration
+type ObjectTimings struct {
+	DownloadMs        int64 `json:"download_ms"`
+	DecompressMs      int64 `json:"decompress_ms"`
+	ParseMs           int64 `json:"parse_ms"`
+	DedupMs           int64 `json:"dedup_ms"`                     // the object's share of deduplication, by papers contributed
+	DuplicatesRemoved int   `json:"duplicates_removed,omitempty"` // papers of the object dropped by deduplication
+}
+
+// Failed reports whether the record should be retried; a duplicate notification was
+// handled by the delivery that claimed its object, and an ignored object holds no papers
+func (r RecordResult) Failed() bool {
+	return r.Status != RecordStatusProcessed && r.Status != RecordStatusDuplicate && r.Status != RecordStatusIgnored
+}
+
+// ValidationReport describes what a validate-mode run would have written
+type ValidationReport struct {
+	Mode           string   `json:"mode"`
+	ObjectsRead    int      `json:"objects_read"`
+	PapersParsed   int      `json:"papers_parsed"`
+	PapersToUpsert int      `json:"papers_to_upsert"`
+	SamplePaperIDs []string `json:"sample_paper_ids,omitempty"`
+	SkippedWrites  []string `json:"skipped_writes"`
+}
+
+// maxValidationSamples limits the paper IDs listed in a validation report
+const maxValidationSamples = 10
+
 // S3EventProcessor handles S3 event processing
 type S3EventProcessor struct {
 	downloader    S3Downloader
 	deduplicator  Deduplicator
 	dynamoWriter  DynamoWriter
 	logger        Logger
+	webhook       WebhookEmitter
+	authors       AuthorResolver
+	categories    CategoryFilter
+	eventGuard    EventGuard
+	validateOnly  bool
+	deterministicTraceID bool
+	shutdown      <-chan struct{}
 }
 
 // Logger interface for structured logging - using shared logger
 type Logger interface {
+	WithContext(ctx context.Context) *logger.Logger
 	WithTraceID(traceID string) *logger.Logger
 	Info(message string, metadata ...map[string]interface{})
 	InfoWithCount(message string, count int, metadata ...map[string]interface{})
@@ -74,22 +177,84 @@ type DynamoWriter interface {
 	BatchUpsertWithStats(ctx context.Context, papers []Paper) (*UpsertStats, error)
 }
 
+// WebhookEmitter interface for new-paper notifications
+type WebhookEmitter interface {
+	NotifyNewPapers(ctx context.Context, papers []Paper) error
+}
+
+// CategoryFilter decides which papers are ingested by their categories
+type CategoryFilter interface {
+	Allows(categories []string) bool
+}
+
+// EventGuard claims object versions so duplicate notifications for the same object are
+// skipped. Complete marks a processed object's claim done; Release gives up a claim so a
+// retry of a failed object is processed again.
+type EventGuard interface {
+	Claim(ctx context.Context, bucket, key, etag string) (bool, error)
+	Complete(ctx context.Context, bucket, key, etag string) error
+	Release(ctx context.Context, bucket, key, etag string) error
+}
+
+// AuthorResolver links author mentions to author entities, setting each paper's AuthorIDs
+type AuthorResolver interface {
+	ResolveAuthors(ctx context.Context, papers []Paper) (*AuthorStats, error)
+}
+
+// AuthorStats contains statistics about author disambiguation
+type AuthorStats struct {
+	Mentions     int `json:"mentions"`
+	Matched      int `json:"matched"`       // mentions linked to an existing author entity
+	Created      int `json:"created"`       // mentions that started a new author entity
+	FailedWrites int `json:"failed_writes"` // author entities that could not be updated
+}
+
 // DeduplicationStats contains statistics about the deduplication process
 type DeduplicationStats struct {
 	OriginalCount  int `json:"original_count"`
 	UniqueCount    int `json:"unique_count"`
 	DuplicateCount int `json:"duplicate_count"`
 	InvalidCount   int `json:"invalid_count"`
+	MergedCount    int `json:"merged_count,omitempty"` // cross-source duplicates merged into the kept record
+	StrategyCounts map[string]int `json:"strategy_counts,omitempty"`
+	DuplicateGroups []DuplicateGroup `json:"duplicate_groups,omitempty"`
+	DuplicateGroupsTruncated bool `json:"duplicate_groups_truncated,omitempty"`
+}
+
+// DuplicateGroup records the paper kept by deduplication and the records dropped in its favor
+type DuplicateGroup struct {
+	WinnerID     string          `json:"winner_id"`
+	WinnerSource string          `json:"winner_source"`
+	Dropped      []DroppedRecord `json:"dropped"`
+}
+
+// DroppedRecord describes a record removed as a duplicate
+type DroppedRecord struct {
+	PaperID  string `json:"paper_id"`
+	Source   string `json:"source"`
+	Strategy string `json:"strategy"`
+	MatchKey string `json:"match_key"`
+	Merged   bool   `json:"merged,omitempty"` // fields were merged into the kept record rather than discarded
 }
 
 // UpsertStats contains statistics about the upsert operation
 type UpsertStats struct {
 	TotalItems     int `json:"total_items"`
-	SuccessItems   int `json:"success_items"`
+	SuccessItems   int `json:"success_items"` // papers written; unchanged and tombstoned papers are not counted
 	FailedItems    int `json:"failed_items"`
 	BatchCount     int `json:"batch_count"`
 	SuccessBatches int `json:"success_batches"`
 	FailedBatches  int `json:"failed_batches"`
+	PartialBatches int `json:"partial_batches"`
+	FailedPaperIDs []string `json:"failed_paper_ids,omitempty"`
+	NewItems        int `json:"new_items"`
+	ChangedItems    int `json:"changed_items"`
+	RefreshedItems  int `json:"refreshed_items"` // same content hashes but changed metadata; rewritten without a version bump
+	UnchangedItems  int `json:"unchanged_items"` // already stored with the same content hashes; not rewritten
+	TombstonedItems int `json:"tombstoned_items"`
+	Paused          bool `json:"paused,omitempty"` // ingestion writes were paused mid-upsert; unwritten papers count as failed
+	// NewPaperIDs are the papers written that were not stored before; kept out of logs and run items
+	NewPaperIDs []string `json:"-"`
 }
 
 // NewS3EventProcessor creates a new S3 event processor
@@ -102,6 +267,52 @@ func NewS3EventProcessor(downloader S3Downloader, deduplicator Deduplicator, dyn
 	}
 }
 
+// SetWebhookEmitter enables new-paper notifications after successful upserts
+func (p *S3EventProcessor) SetWebhookEmitter(emitter WebhookEmitter) {
+	p.webhook = emitter
+}
+
+// SetAuthorResolver enables author disambiguation before papers are upserted
+func (p *S3EventProcessor) SetAuthorResolver(resolver AuthorResolver) {
+	p.authors = resolver
+}
+
+// SetCategoryFilter drops papers outside the category allowlist or in the skip list before
+// deduplication, so they are never stored or embedded
+func (p *S3EventProcessor) SetCategoryFilter(filter CategoryFilter) {
+	p.categories = filter
+}
+
+// SetEventGuard enables duplicate-notification suppression for records carrying an ETag.
+// Validate-mode runs never claim objects.
+func (p *S3EventProcessor) SetEventGuard(guard EventGuard) {
+	p.eventGuard = guard
+}
+
+// SetValidateOnly enables validate mode: every step runs except DynamoDB writes and webhooks
+func (p *S3EventProcessor) SetValidateOnly(validateOnly bool) {
+	p.validateOnly = validateOnly
+}
+
+// SetShutdown registers a channel that, once closed, stops the processor from reading further
+// S3 objects. Papers already read are still deduplicated and written so no batch is left half-done.
+func (p *S3EventProcessor) SetShutdown(shutdown <-chan struct{}) {
+	p.shutdown = shutdown
+}
+
+// shutdownRequested reports whether the shutdown channel has been closed
+func (p *S3EventProcessor) shutdownRequested() bool {
+	if p.shutdown == nil {
+		return false
+	}
+	select {
+	case <-p.shutdown:
+		return true
+	default:
+		return false
+	}
+}
+
 // ProcessS3Event processes an S3 event and returns processing results
 func (p *S3EventProcessor) ProcessS3Event(ctx context.Context, s3Event events.S3Event) (*ProcessResult, error) {
 	if len(s3Event.Records) == 0 {
@@ -109,23 +320,97 @@ func (p *S3EventProcessor) ProcessS3Event(ctx context.Context, s3Event events.S3
 	}
 
 	// Generate trace ID for this batch
-	traceID := uuid.New().String()
-	batchTimestamp := time.Now()
+	traceID := p.newTraceID(s3Event.Records)
+	batchTimestamp := time.Now().UTC()
 	startTime := time.Now()
 	
+	// Carry the trace ID in the context so the writer, resolver and webhook log under it
+	ctx = logger.ContextWithTraceID(ctx, traceID)
+
 	// Log processing start
-	tracedLogger := p.logger.WithTraceID(traceID)
+	tracedLogger := p.logger.WithContext(ctx)
 	tracedLogger.InfoWithCount("Starting batch processing", len(s3Event.Records), map[string]interface{}{
 		"event": "processing_start",
 	})
 
 	var allPapers []Paper
 	var lastError error
+	var skippedObjects []string
+	objectsRead := 0
+	categoryFiltered := 0
+	duplicates := 0
+	ignored := 0
+	claimed := make([]bool, len(s3Event.Records))
+	contributed := make([]int, len(s3Event.Records)) // papers each record passed to deduplication
+
+	recordResults := make([]RecordResult, len(s3Event.Records))
+	for i, record := range s3Event.Records {
+		recordResults[i] = RecordResult{
+			Bucket: record.S3.Bucket.Name,
+			Key:    record.S3.Object.Key,
+			Status: RecordStatusSkipped,
+		}
+	}
 
 	// Process each S3 record
-	for _, record := range s3Event.Records {
+	for i, record := range s3Event.Records {
 		bucket := record.S3.Bucket.Name
 		key := record.S3.Object.Key
+
+		// Stop reading new objects once shutdown is requested; what was read is still written
+		if p.shutdownRequested() {
+			for _, skipped := range s3Event.Records[i:] {
+				skippedObjects = append(skippedObjects, skipped.S3.Bucket.Name+"/"+skipped.S3.Object.Key)
+			}
+			tracedLogger.Warn("Shutdown requested, skipping remaining S3 objects", map[string]interface{}{
+				"event":        "warning",
+				"warning_type": "shutdown",
+				"context": map[string]interface{}{
+					"skipped_objects": len(skippedObjects),
+				},
+			})
+			break
+		}
+
+		if path.Base(key) == partsManifestName {
+			ignored++
+			recordResults[i].Status = RecordStatusIgnored
+			tracedLogger.Info("Skipping parts manifest, its parts are processed on their own", map[string]interface{}{
+				"event":  "parts_manifest",
+				"bucket": bucket,
+				"key":    key,
+			})
+			continue
+		}
+
+		if p.eventGuard != nil && !p.validateOnly && record.S3.Object.ETag != "" {
+			isNew, err := p.eventGuard.Claim(ctx, bucket, key, record.S3.Object.ETag)
+			switch {
+			case err != nil:
+				// Without the guard a duplicate is only reprocessed, which the upsert tolerates
+				tracedLogger.Warn("Failed to claim S3 object, processing without duplicate check", map[string]interface{}{
+					"event":        "warning",
+					"warning_type": "event_guard",
+					"context": map[string]interface{}{
+						"bucket": bucket,
+						"key":    key,
+						"error":  err.Error(),
+					},
+				})
+			case !isNew:
+				duplicates++
+				recordResults[i].Status = RecordStatusDuplicate
+				tracedLogger.Info("Skipping duplicate S3 notification", map[string]interface{}{
+					"event":  "duplicate_notification",
+					"bucket": bucket,
+					"key":    key,
+					"etag":   record.S3.Object.ETag,
+				})
+				continue
+			default:
+				claimed[i] = true
+			}
+		}
 		
 		// Log S3 processing (file size is not available from S3 event, so we use 0)
 		tracedLogger.Info("Processing S3 object", map[string]interface{}{
@@ -135,10 +420,16 @@ func (p *S3EventProcessor) ProcessS3Event(ctx context.Context, s3Event events.S3
 			"file_size": 0,
 		})
 
-		// Download and decompress file
-		data, err := p.downloader.DownloadAndDecompress(ctx, bucket, key)
+		// Open the object as a stream so large files are parsed incrementally
+		objectStart := time.Now()
+		reader, err := p.objectReader(ctx, bucket, key)
+		opened := time.Since(objectStart)
 		if err != nil {
 			lastError = fmt.Errorf("failed to download/decompress %s/%s: %w", bucket, key, err)
+			recordResults[i].Timings = &ObjectTimings{DownloadMs: opened.Milliseconds()}
+			recordResults[i].Status = RecordStatusFailed
+			recordResults[i].Error = lastError.Error()
+			recordResults[i].Code = string(failures.CodeS3Read)
 			tracedLogger.Error("Error occurred during processing", lastError, map[string]interface{}{
 				"event":      "error",
 				"error_type": "s3_download",
@@ -150,27 +441,89 @@ func (p *S3EventProcessor) ProcessS3Event(ctx context.Context, s3Event events.S3
 			continue
 		}
 
+		// Pick the parser for the object's schema version; unknown versions fail the object
+		// rather than being misread by an older parser
+		schemaVersion := schemaVersionOf(reader)
+		collectionRunID := collectionRunOf(reader)
+		collectedAt := collectionTimeOf(reader)
+		parse, err := parserFor(schemaVersion)
+		if err != nil {
+			reader.Close()
+			lastError = fmt.Errorf("cannot parse %s/%s: %w", bucket, key, err)
+			recordResults[i].Status = RecordStatusFailed
+			recordResults[i].Error = lastError.Error()
+			recordResults[i].Code = string(failures.CodeUnsupportedSchema)
+			tracedLogger.Error("Error occurred during processing", lastError, map[string]interface{}{
+				"event":      "error",
+				"error_type": "unsupported_schema",
+				"context": map[string]interface{}{
+					"bucket":         bucket,
+					"key":            key,
+					"schema_version": schemaVersion,
+				},
+			})
+			continue
+		}
+
 		// Parse batch data
-		papers, err := p.parseBatchData(data, traceID, batchTimestamp)
+		counter := &countingReader{reader: reader}
+		papers, err := p.parseAndVerify(parse, counter, traceID, batchTimestamp)
+		recordResults[i].Timings = objectTimings(reader, opened, time.Since(objectStart))
+		reader.Close()
 		if err != nil {
+			// A checksum mismatch means the object is corrupted; nothing parsed from it is kept
+			errorType, code := "data_parsing", failures.CodeParse
+			if isIntegrityError(err) {
+				errorType, code = "data_integrity", failures.CodeIntegrity
+			}
 			lastError = fmt.Errorf("failed to parse batch data from %s/%s: %w", bucket, key, err)
+			recordResults[i].Status = RecordStatusFailed
+			recordResults[i].Error = lastError.Error()
+			recordResults[i].Code = string(code)
 			tracedLogger.Error("Error occurred during processing", lastError, map[string]interface{}{
 				"event":      "error",
-				"error_type": "data_parsing",
+				"error_type": errorType,
 				"context": map[string]interface{}{
 					"bucket":    bucket,
 					"key":       key,
-					"data_size": len(data),
+					"data_size": counter.count,
 				},
 			})
 			continue
 		}
 
+		objectsRead++
+		recordResults[i].Status = RecordStatusProcessed
+		recordResults[i].PaperCount = len(papers)
+
+		// Keep a reference to the raw object and the run that collected it, so takedowns can
+		// locate it later and vectors can be attributed to their input; the collection time
+		// starts the paper's end-to-end latency
+		for j := range papers {
+			papers[j].RawDataBucket = bucket
+			papers[j].RawDataKey = key
+			papers[j].CollectionRunID = collectionRunID
+			papers[j].CollectedAt = collectedAt
+		}
+
 		// Log data parsing success
 		tracedLogger.InfoWithCount("Data parsing completed", len(papers), map[string]interface{}{
-			"event":  "data_parsing",
-			"source": "s3_batch",
+			"event":          "data_parsing",
+			"source":         "s3_batch",
+			"schema_version": schemaVersion,
 		})
+
+		if p.categories != nil {
+			kept := papers[:0]
+			for _, paper := range papers {
+				if p.categories.Allows(paper.Categories) {
+					kept = append(kept, paper)
+				}
+			}
+			categoryFiltered += len(papers) - len(kept)
+			papers = kept
+		}
+		contributed[i] = len(papers)
 		allPapers = append(allPapers, papers...)
 	}
 
@@ -180,10 +533,26 @@ func (p *S3EventProcessor) ProcessS3Event(ctx context.Context, s3Event events.S3
 		Timestamp: batchTimestamp,
 		Status:    "success",
 	}
+	if len(skippedObjects) > 0 {
+		result.Interrupted = true
+		result.SkippedObjects = skippedObjects
+	}
+	// Shares the backing array, so upsert failures marked below are reflected in the result
+	result.RecordResults = recordResults
+	result.DuplicateObjects = duplicates
+	if categoryFiltered > 0 {
+		result.CategoryFiltered = categoryFiltered
+		tracedLogger.Info("Category filter applied", map[string]interface{}{
+			"event":           "category_filter",
+			"papers_filtered": categoryFiltered,
+		})
+	}
 
 	// Deduplicate papers
 	if len(allPapers) > 0 {
+		dedupStart := time.Now()
 		uniquePapers, dedupStats := p.deduplicator.DeduplicateWithStats(allPapers)
+		attributeDedup(recordResults, contributed, uniquePapers, time.Since(dedupStart))
 		result.DeduplicationStats = &dedupStats
 		
 		// Log deduplication results
@@ -195,8 +564,19 @@ func (p *S3EventProcessor) ProcessS3Event(ctx context.Context, s3Event events.S3
 		// uniquePapers is already []Paper from the interface
 		papers := uniquePapers
 		
-		// Upsert to DynamoDB
-		if len(papers) > 0 {
+		// In validate mode, report the planned writes instead of performing them
+		if p.validateOnly {
+			result.Validation = buildValidationReport(objectsRead, len(allPapers), papers)
+			result.Status = "validated"
+			result.ProcessedCount = len(papers)
+			tracedLogger.Info("Validation run completed, DynamoDB upsert skipped", map[string]interface{}{
+				"event":      "validation",
+				"validation": result.Validation,
+			})
+		} else if len(papers) > 0 {
+			p.resolveAuthors(ctx, tracedLogger, papers, result)
+
+			// Upsert to DynamoDB
 			upsertStats, err := p.dynamoWriter.BatchUpsertWithStats(ctx, papers)
 			if err != nil {
 				lastError = fmt.Errorf("failed to upsert papers to DynamoDB: %w", err)
@@ -209,6 +589,7 @@ func (p *S3EventProcessor) ProcessS3Event(ctx context.Context, s3Event events.S3
 				})
 				result.Status = "failed"
 				result.ErrorMessage = lastError.Error()
+				markUpsertFailures(recordResults, papers, nil, lastError, failures.CodeUpsert)
 			} else {
 				result.UpsertStats = upsertStats
 				
@@ -218,12 +599,23 @@ func (p *S3EventProcessor) ProcessS3Event(ctx context.Context, s3Event events.S3
 					"upsert_stats": upsertStats,
 				})
 				
-				// Extract success count from upsert stats directly
-				result.ProcessedCount = upsertStats.SuccessItems
+				// Unchanged and tombstoned papers count as processed but keep their earlier trace ID
+				result.ProcessedCount = upsertStats.TotalItems - upsertStats.FailedItems
+				result.WrittenCount = upsertStats.SuccessItems
 				if upsertStats.FailedItems > 0 {
 					result.Status = "partial_success"
 					result.ErrorMessage = fmt.Sprintf("%d items failed to upsert", upsertStats.FailedItems)
+					if upsertStats.Paused {
+						result.ErrorMessage = fmt.Sprintf("ingestion writes paused, %d items not written", upsertStats.FailedItems)
+					}
+					code := failures.CodeUpsert
+					if upsertStats.Paused {
+						code = failures.CodePaused
+					}
+					markUpsertFailures(recordResults, papers, upsertStats.FailedPaperIDs, nil, code)
 				}
+
+				p.notifyNewPapers(ctx, tracedLogger, papers, upsertStats)
 			}
 		} else {
 			tracedLogger.Warn("No unique papers to upsert after deduplication", map[string]interface{}{
@@ -235,6 +627,13 @@ func (p *S3EventProcessor) ProcessS3Event(ctx context.Context, s3Event events.S3
 			})
 			result.ProcessedCount = 0
 		}
+	} else if duplicates+ignored == len(s3Event.Records) {
+		tracedLogger.Info("All S3 notifications were duplicates or parts manifests, nothing to process", map[string]interface{}{
+			"event":      "duplicate_notification",
+			"duplicates": duplicates,
+			"ignored":    ignored,
+		})
+		result.ProcessedCount = 0
 	} else {
 		tracedLogger.Warn("No papers parsed from S3 objects", map[string]interface{}{
 			"event":        "warning",
@@ -257,9 +656,15 @@ func (p *S3EventProcessor) ProcessS3Event(ctx context.Context, s3Event events.S3
 		if result.ErrorMessage == "" {
 			result.ErrorMessage = lastError.Error()
 		}
+	} else if result.Interrupted && result.Status == "success" {
+		result.Status = "partial_success"
+		result.ErrorMessage = fmt.Sprintf("shutdown requested, %d S3 objects skipped", len(skippedObjects))
 	}
 
-	// Log performance metrics
+	p.settleClaims(ctx, tracedLogger, s3Event, claimed, recordResults)
+
+	// Log performance metrics, per object and for the whole event
+	logObjectTimings(tracedLogger, recordResults)
 	processingTime := time.Since(startTime)
 	tracedLogger.Info("Performance metrics", map[string]interface{}{
 		"event":   "metrics",
@@ -280,74 +685,149 @@ func (p *S3EventProcessor) ProcessS3Event(ctx context.Context, s3Event events.S3
 	return result, nil
 }
 
-// parseBatchData parses raw data into Paper structs
-func (p *S3EventProcessor) parseBatchData(data []byte, traceID string, batchTimestamp time.Time) ([]Paper, error) {
-	var papers []Paper
-	
-	// Try to parse as JSON array first
-	var jsonPapers []map[string]interface{}
-	if err := json.Unmarshal(data, &jsonPapers); err == nil {
-		// Successfully parsed as JSON array
-		for _, paperData := range jsonPapers {
-			paper, err := p.convertMapToPaper(paperData, traceID, batchTimestamp)
-			if err != nil {
-				tracedLogger := p.logger.WithTraceID(traceID)
-				tracedLogger.Warn("Failed to convert paper data", map[string]interface{}{
+// settleClaims completes the claims of processed records and gives up the claims of the
+// others, so their redelivered notifications are retried rather than skipped as duplicates.
+// A claim left in progress expires with its lease.
+func (p *S3EventProcessor) settleClaims(ctx context.Context, tracedLogger *logger.Logger, s3Event events.S3Event, claimed []bool, recordResults []RecordResult) {
+	for i, record := range s3Event.Records {
+		if !claimed[i] {
+			continue
+		}
+		if recordResults[i].Status == RecordStatusProcessed {
+			if err := p.eventGuard.Complete(ctx, record.S3.Bucket.Name, record.S3.Object.Key, record.S3.Object.ETag); err != nil {
+				tracedLogger.Warn("Failed to complete S3 object claim, duplicates are reprocessed once its lease expires", map[string]interface{}{
 					"event":        "warning",
-					"warning_type": "data_conversion",
+					"warning_type": "event_guard",
 					"context": map[string]interface{}{
-						"error": err.Error(),
+						"bucket": record.S3.Bucket.Name,
+						"key":    record.S3.Object.Key,
+						"error":  err.Error(),
 					},
 				})
-				continue
 			}
-			papers = append(papers, paper)
-		}
-		return papers, nil
-	}
-
-	// Try to parse as newline-delimited JSON
-	lines := splitLines(string(data))
-	for i, line := range lines {
-		if len(line) == 0 {
 			continue
 		}
-		
-		var paperData map[string]interface{}
-		if err := json.Unmarshal([]byte(line), &paperData); err != nil {
-			tracedLogger := p.logger.WithTraceID(traceID)
-			tracedLogger.Warn("Failed to parse line as JSON", map[string]interface{}{
+		if err := p.eventGuard.Release(ctx, record.S3.Bucket.Name, record.S3.Object.Key, record.S3.Object.ETag); err != nil {
+			tracedLogger.Warn("Failed to release S3 object claim, retries are skipped until its lease expires", map[string]interface{}{
 				"event":        "warning",
-				"warning_type": "json_parsing",
+				"warning_type": "event_guard",
 				"context": map[string]interface{}{
-					"line_number": i + 1,
-					"error":       err.Error(),
+					"bucket": record.S3.Bucket.Name,
+					"key":    record.S3.Object.Key,
+					"error":  err.Error(),
 				},
 			})
-			continue
 		}
-		
-		paper, err := p.convertMapToPaper(paperData, traceID, batchTimestamp)
-		if err != nil {
-			tracedLogger := p.logger.WithTraceID(traceID)
-			tracedLogger.Warn("Failed to convert paper data from line", map[string]interface{}{
-				"event":        "warning",
-				"warning_type": "data_conversion",
-				"context": map[string]interface{}{
-					"line_number": i + 1,
-					"error":       err.Error(),
-				},
-			})
+	}
+}
+
+// markUpsertFailures fails the records whose papers did not land. A whole-upsert error
+// fails every record that contributed papers; otherwise only the records owning failedIDs fail.
+// Failed records carry code.
+func markUpsertFailures(recordResults []RecordResult, papers []Paper, failedIDs []string, upsertErr error, code failures.Code) {
+	failed := make(map[string]bool, len(failedIDs))
+	for _, id := range failedIDs {
+		failed[id] = true
+	}
+
+	failedObjects := make(map[string]int)
+	for _, paper := range papers {
+		if upsertErr != nil || failed[paper.PaperID] {
+			failedObjects[paper.RawDataBucket+"/"+paper.RawDataKey]++
+		}
+	}
+
+	for i := range recordResults {
+		count, ok := failedObjects[recordResults[i].Bucket+"/"+recordResults[i].Key]
+		if !ok || recordResults[i].Status != RecordStatusProcessed {
 			continue
 		}
-		papers = append(papers, paper)
+		recordResults[i].Status = RecordStatusFailed
+		recordResults[i].Code = string(code)
+		if upsertErr != nil {
+			recordResults[i].Error = upsertErr.Error()
+		} else {
+			recordResults[i].Error = fmt.Sprintf("%d papers failed to upsert", count)
+		}
+	}
+}
+
+// buildValidationReport summarizes the writes a validate-mode run skipped
+func buildValidationReport(objectsRead, papersParsed int, papers []Paper) *ValidationReport {
+	report := &ValidationReport{
+		Mode:           "validate",
+		ObjectsRead:    objectsRead,
+		PapersParsed:   papersParsed,
+		PapersToUpsert: len(papers),
+		SkippedWrites:  []string{"dynamodb:BatchWriteItem", "webhook:POST"},
+	}
+	for i := 0; i < len(papers) && i < maxValidationSamples; i++ {
+		report.SamplePaperIDs = append(report.SamplePaperIDs, papers[i].PaperID)
+	}
+	return report
+}
+
+// resolveAuthors links the papers' authors to author entities so the upsert stores their
+// author_ids; failures are logged and the papers are written without them
+func (p *S3EventProcessor) resolveAuthors(ctx context.Context, tracedLogger *logger.Logger, papers []Paper, result *ProcessResult) {
+	if p.authors == nil {
+		return
+	}
+
+	stats, err := p.authors.ResolveAuthors(ctx, papers)
+	result.AuthorStats = stats
+	if err != nil {
+		tracedLogger.Error("Error occurred during processing", err, map[string]interface{}{
+			"event":      "error",
+			"error_type": "author_resolution",
+			"context": map[string]interface{}{
+				"paper_count": len(papers),
+			},
+		})
+		return
+	}
+
+	tracedLogger.Info("Author resolution completed", map[string]interface{}{
+		"event":        "author_resolution",
+		"author_stats": stats,
+	})
+}
+
+// notifyNewPapers emits webhook notifications for the papers the upsert wrote as new;
+// failures are logged, not returned
+func (p *S3EventProcessor) notifyNewPapers(ctx context.Context, tracedLogger *logger.Logger, papers []Paper, upsertStats *UpsertStats) {
+	if p.webhook == nil || len(upsertStats.NewPaperIDs) == 0 {
+		return
+	}
+
+	// Only announce papers that landed and were not stored before: unchanged papers were
+	// announced when first written, and soft-deleted ones were deliberately skipped
+	written := make(map[string]bool, len(upsertStats.NewPaperIDs))
+	for _, id := range upsertStats.NewPaperIDs {
+		written[id] = true
+	}
+	newPapers := make([]Paper, 0, len(upsertStats.NewPaperIDs))
+	for _, paper := range papers {
+		if written[paper.PaperID] {
+			newPapers = append(newPapers, paper)
+		}
 	}
+	papers = newPapers
 
-	if len(papers) == 0 {
-		return nil, fmt.Errorf("no valid papers found in data")
+	if err := p.webhook.NotifyNewPapers(ctx, papers); err != nil {
+		tracedLogger.Error("Error occurred during processing", err, map[string]interface{}{
+			"event":      "error",
+			"error_type": "webhook_notification",
+			"context": map[string]interface{}{
+				"paper_count": len(papers),
+			},
+		})
 	}
+}
 
-	return papers, nil
+// parseBatchData parses raw data into Paper structs
+func (p *S3EventProcessor) parseBatchData(data []byte, traceID string, batchTimestamp time.Time) ([]Paper, error) {
+	return p.parseBatchStream(bytes.NewReader(data), traceID, batchTimestamp)
 }
 
 // convertMapToPaper converts a map to Paper struct
@@ -356,10 +836,11 @@ func (p *S3EventProcessor) convertMapToPaper(data map[string]interface{}, traceI
 	
 	paper := Paper{
 		TraceID:          traceID,
-		BatchTimestamp:   batchTimestamp.Format(time.RFC3339),
+		BatchTimestamp:   batchTimestamp.UTC().Format(time.RFC3339),
 		ProcessingStatus: "processed",
 		CreatedAt:        now,
 		UpdatedAt:        now,
+		IngestedAt:       time.Now().UTC().Format(time.RFC3339),
 	}
 
 	// Extract paper_id (required)
@@ -412,17 +893,55 @@ func (p *S3EventProcessor) convertMapToPaper(data map[string]interface{}, traceI
 		}
 	}
 
+	if doi, ok := data["doi"].(string); ok {
+		paper.DOI = doi
+	}
+
+	if journal, ok := data["journal"].(string); ok {
+		paper.Journal = journal
+	}
+
+	if citations, ok := data["citation_count"].(float64); ok {
+		count := int(citations)
+		paper.CitationCount = &count
+	}
+
 	if rawXML, ok := data["raw_xml"].(string); ok {
 		paper.RawXML = rawXML
 	}
 
+	paper.TitleHash = ContentHash(paper.Title)
+	paper.AbstractHash = ContentHash(paper.Abstract)
+	paper.Version = 1
+
 	return paper, nil
 }
 
-// splitLines splits text into lines, handling different line endings
-func splitLines(text string) []string {
-	// Replace \r\n with \n, then \r with \n
-	text = strings.ReplaceAll(text, "\r\n", "\n")
-	text = strings.ReplaceAll(text, "\r", "\n")
-	return strings.Split(text, "\n")
-}
\ No newline at end of file
+// AuthorNameKey returns the blocking key of an author name: the lowercased surname and first
+// initial, e.g. "smith_j" for both "John Smith" and "Smith, J.". Names that differ only in
+// given-name spelling share a key; telling their authors apart is left to the resolver.
+func AuthorNameKey(name string) string {
+	surnameFirst := strings.Contains(name, ",")
+	tokens := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '-' && r != '\''
+	})
+	if len(tokens) == 0 {
+		return ""
+	}
+	if len(tokens) == 1 {
+		return tokens[0]
+	}
+
+	surname, given := tokens[len(tokens)-1], tokens[0]
+	if surnameFirst {
+		surname, given = tokens[0], tokens[1]
+	}
+	initial, _ := utf8.DecodeRuneInString(given)
+	return surname + "_" + string(initial)
+}
+
+// ContentHash returns a compact, whitespace-insensitive hash of a text field
+func ContentHash(text string) string {
+	sum := sha256.Sum256([]byte(strings.Join(strings.Fields(text), " ")))
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,71 @@
+package processor
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// SchemaVersionedReader is implemented by object readers that know the schema-version tag
+// the data collector stored on the object
+type SchemaVersionedReader interface {
+	SchemaVersion() string
+}
+
+// CollectionRunReader is implemented by object readers that know the collection run that
+// wrote the object
+type CollectionRunReader interface {
+	CollectionRunID() string
+}
+
+// CollectionTimeReader is implemented by object readers that know when the collection run
+// that wrote the object started
+type CollectionTimeReader interface {
+	CollectedAt() string
+}
+
+// batchParser parses one raw-data object payload into papers
+type batchParser func(p *S3EventProcessor, reader io.Reader, traceID string, batchTimestamp time.Time) ([]Paper, error)
+
+// schemaParsers maps payload schema versions to their parser. Untagged objects predate
+// versioning and use the original JSON parser; version 2 is the JSON-lines stream the
+// collector writes for large results. New formats (e.g. Parquet) register here before the
+// collector starts writing them, so objects already in flight keep parsing.
+var schemaParsers = map[string]batchParser{
+	"":  (*S3EventProcessor).parseBatchStream,
+	"1": (*S3EventProcessor).parseBatchStream,
+	"2": (*S3EventProcessor).parseCollectionLines,
+}
+
+// schemaVersionOf returns the reader's schema version, or "" when it is untagged
+func schemaVersionOf(reader io.Reader) string {
+	if versioned, ok := reader.(SchemaVersionedReader); ok {
+		return versioned.SchemaVersion()
+	}
+	return ""
+}
+
+// collectionRunOf returns the reader's collection run ID, or "" when it is unknown
+func collectionRunOf(reader io.Reader) string {
+	if run, ok := reader.(CollectionRunReader); ok {
+		return run.CollectionRunID()
+	}
+	return ""
+}
+
+// collectionTimeOf returns the reader's collection time, or "" when it is unknown
+func collectionTimeOf(reader io.Reader) string {
+	if timed, ok := reader.(CollectionTimeReader); ok {
+		return timed.CollectedAt()
+	}
+	return ""
+}
+
+// parserFor selects the parser for a schema version, failing on versions this build does not know
+func parserFor(version string) (batchParser, error) {
+	parser, ok := schemaParsers[version]
+	if !ok {
+		return nil, fmt.Errorf("unsupported payload schema version %q", version)
+	}
+	return parser, nil
+}
@@ -0,0 +1,275 @@
+package processor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"shared/logger"
+)
+
+// StreamingS3Downloader is implemented by downloaders that can stream decompressed objects.
+// The processor prefers it over DownloadAndDecompress so large files are never fully buffered.
+type StreamingS3Downloader interface {
+	OpenDecompressed(ctx context.Context, bucket, key string) (io.ReadCloser, error)
+}
+
+// objectReader opens an S3 object for parsing, streaming when the downloader supports it
+func (p *S3EventProcessor) objectReader(ctx context.Context, bucket, key string) (io.ReadCloser, error) {
+	if streaming, ok := p.downloader.(StreamingS3Downloader); ok {
+		return streaming.OpenDecompressed(ctx, bucket, key)
+	}
+
+	data, err := p.downloader.DownloadAndDecompress(ctx, bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// TimedReader is implemented by object readers that time their own download and
+// decompression, which are otherwise interleaved with parsing when the object is streamed
+type TimedReader interface {
+	ReadTimings() (download, decompress time.Duration)
+}
+
+// objectTimings breaks down the elapsed time of an object read through reader, of which
+// opening took opened. Readers that cannot tell them apart count download and decompression
+// as download; parsing is the remainder.
+func objectTimings(reader io.Reader, opened, elapsed time.Duration) *ObjectTimings {
+	download, decompress := opened, time.Duration(0)
+	if timed, ok := reader.(TimedReader); ok {
+		download, decompress = timed.ReadTimings()
+	}
+	parse := elapsed - download - decompress
+	if parse < 0 {
+		parse = 0
+	}
+	return &ObjectTimings{
+		DownloadMs:   download.Milliseconds(),
+		DecompressMs: decompress.Milliseconds(),
+		ParseMs:      parse.Milliseconds(),
+	}
+}
+
+// attributeDedup gives each record its share of the deduplication time, in proportion to the
+// papers it contributed, and counts its papers that did not survive
+func attributeDedup(recordResults []RecordResult, contributed []int, unique []Paper, elapsed time.Duration) {
+	total := 0
+	for _, count := range contributed {
+		total += count
+	}
+	if total == 0 {
+		return
+	}
+
+	survivors := make(map[string]int)
+	for _, paper := range unique {
+		survivors[paper.RawDataBucket+"/"+paper.RawDataKey]++
+	}
+
+	for i := range recordResults {
+		if contributed[i] == 0 || recordResults[i].Timings == nil {
+			continue
+		}
+		share := elapsed * time.Duration(contributed[i]) / time.Duration(total)
+		recordResults[i].Timings.DedupMs = share.Milliseconds()
+		// survivors is keyed by object, so a key listed twice in one event shares its count
+		if removed := contributed[i] - survivors[recordResults[i].Bucket+"/"+recordResults[i].Key]; removed > 0 {
+			recordResults[i].Timings.DuplicatesRemoved = removed
+		}
+	}
+}
+
+// logObjectTimings emits one metrics entry per object that was read, so the slow objects of a
+// multi-record event stand out
+func logObjectTimings(tracedLogger *logger.Logger, recordResults []RecordResult) {
+	for _, record := range recordResults {
+		if record.Timings == nil {
+			continue
+		}
+		tracedLogger.Info("S3 object metrics", map[string]interface{}{
+			"event":       "object_metrics",
+			"bucket":      record.Bucket,
+			"key":         record.Key,
+			"status":      record.Status,
+			"paper_count": record.PaperCount,
+			"timings":     record.Timings,
+		})
+	}
+}
+
+// integrityError is implemented by downloader errors reporting a corrupted or truncated
+// object, such as a payload checksum mismatch
+type integrityError interface {
+	IntegrityFailure() bool
+}
+
+// isIntegrityError reports whether err, or any error it wraps, is an integrity failure
+func isIntegrityError(err error) bool {
+	var integrity integrityError
+	return errors.As(err, &integrity) && integrity.IntegrityFailure()
+}
+
+// parseAndVerify parses the object and then reads any bytes the parser left unread (e.g.
+// trailing whitespace after a JSON array), so a checksum-verifying reader always reaches EOF
+func (p *S3EventProcessor) parseAndVerify(parse batchParser, reader io.Reader, traceID string, batchTimestamp time.Time) ([]Paper, error) {
+	papers, err := parse(p, reader, traceID, batchTimestamp)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(io.Discard, reader); err != nil {
+		return nil, err
+	}
+	return papers, nil
+}
+
+// countingReader tracks how many bytes have been consumed from the underlying reader
+type countingReader struct {
+	reader io.Reader
+	count  int64
+}
+
+func (r *countingReader) Read(buf []byte) (int, error) {
+	n, err := r.reader.Read(buf)
+	r.count += int64(n)
+	return n, err
+}
+
+// parseBatchStream parses a JSON array or newline-delimited JSON stream into Paper structs
+// without holding the whole payload in memory
+func (p *S3EventProcessor) parseBatchStream(reader io.Reader, traceID string, batchTimestamp time.Time) ([]Paper, error) {
+	buffered := bufio.NewReader(reader)
+
+	first, err := peekNonSpace(buffered)
+	if err == io.EOF {
+		return nil, fmt.Errorf("no valid papers found in data")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read data: %w", err)
+	}
+
+	if first == '[' {
+		return p.parseArrayStream(buffered, traceID, batchTimestamp)
+	}
+	return p.parseLineStream(buffered, traceID, batchTimestamp)
+}
+
+// parseArrayStream decodes a JSON array one element at a time
+func (p *S3EventProcessor) parseArrayStream(reader io.Reader, traceID string, batchTimestamp time.Time) ([]Paper, error) {
+	tracedLogger := p.logger.WithTraceID(traceID)
+	decoder := json.NewDecoder(reader)
+
+	if _, err := decoder.Token(); err != nil {
+		return nil, fmt.Errorf("failed to read JSON array start: %w", err)
+	}
+
+	var papers []Paper
+	for index := 0; decoder.More(); index++ {
+		var paperData map[string]interface{}
+		if err := decoder.Decode(&paperData); err != nil {
+			var typeErr *json.UnmarshalTypeError
+			if !errors.As(err, &typeErr) {
+				return nil, fmt.Errorf("failed to decode JSON array element %d: %w", index, err)
+			}
+			tracedLogger.Warn("Failed to parse array element as JSON object", map[string]interface{}{
+				"event":        "warning",
+				"warning_type": "json_parsing",
+				"context": map[string]interface{}{
+					"element_index": index,
+					"error":         err.Error(),
+				},
+			})
+			continue
+		}
+
+		paper, err := p.convertMapToPaper(paperData, traceID, batchTimestamp)
+		if err != nil {
+			tracedLogger.Warn("Failed to convert paper data", map[string]interface{}{
+				"event":        "warning",
+				"warning_type": "data_conversion",
+				"context": map[string]interface{}{
+					"error": err.Error(),
+				},
+			})
+			continue
+		}
+		papers = append(papers, paper)
+	}
+
+	if _, err := decoder.Token(); err != nil {
+		return nil, fmt.Errorf("failed to read JSON array end: %w", err)
+	}
+
+	return papers, nil
+}
+
+// parseLineStream decodes newline-delimited JSON, skipping lines that fail to parse
+func (p *S3EventProcessor) parseLineStream(reader *bufio.Reader, traceID string, batchTimestamp time.Time) ([]Paper, error) {
+	tracedLogger := p.logger.WithTraceID(traceID)
+	var papers []Paper
+
+	for lineNumber := 1; ; lineNumber++ {
+		line, readErr := reader.ReadBytes('\n')
+		if readErr != nil && readErr != io.EOF {
+			return nil, fmt.Errorf("failed to read line %d: %w", lineNumber, readErr)
+		}
+
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 {
+			var paperData map[string]interface{}
+			if err := json.Unmarshal(line, &paperData); err != nil {
+				tracedLogger.Warn("Failed to parse line as JSON", map[string]interface{}{
+					"event":        "warning",
+					"warning_type": "json_parsing",
+					"context": map[string]interface{}{
+						"line_number": lineNumber,
+						"error":       err.Error(),
+					},
+				})
+			} else if paper, err := p.convertMapToPaper(paperData, traceID, batchTimestamp); err != nil {
+				tracedLogger.Warn("Failed to convert paper data from line", map[string]interface{}{
+					"event":        "warning",
+					"warning_type": "data_conversion",
+					"context": map[string]interface{}{
+						"line_number": lineNumber,
+						"error":       err.Error(),
+					},
+				})
+			} else {
+				papers = append(papers, paper)
+			}
+		}
+
+		if readErr == io.EOF {
+			break
+		}
+	}
+
+	if len(papers) == 0 {
+		return nil, fmt.Errorf("no valid papers found in data")
+	}
+
+	return papers, nil
+}
+
+// peekNonSpace skips leading whitespace and returns the next byte without consuming it
+func peekNonSpace(reader *bufio.Reader) (byte, error) {
+	for {
+		next, err := reader.Peek(1)
+		if err != nil {
+			return 0, err
+		}
+		switch next[0] {
+		case ' ', '\t', '\r', '\n':
+			reader.ReadByte()
+		default:
+			return next[0], nil
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package processor
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/google/uuid"
+)
+
+// SetDeterministicTraceID derives each batch's trace ID from its S3 objects instead of
+// generating a random one, so replaying the same objects reuses the same trace
+func (p *S3EventProcessor) SetDeterministicTraceID(deterministic bool) {
+	p.deterministicTraceID = deterministic
+}
+
+// newTraceID returns the trace ID of a batch: a UUIDv5 of the event's objects in
+// deterministic mode, otherwise a random UUID
+func (p *S3EventProcessor) newTraceID(records []events.S3EventRecord) string {
+	if !p.deterministicTraceID {
+		return uuid.New().String()
+	}
+	return DeriveTraceID(records)
+}
+
+// DeriveTraceID is the UUIDv5, in the URL namespace, of the event's object URIs
+// (s3://bucket/key) sorted and newline-joined. The ETag is left out so a replay of an object
+// without one still derives the same trace ID; neither record order nor repeated
+// notifications for the same object change it.
+func DeriveTraceID(records []events.S3EventRecord) string {
+	seen := make(map[string]bool, len(records))
+	objects := make([]string, 0, len(records))
+	for _, record := range records {
+		uri := "s3://" + record.S3.Bucket.Name + "/" + record.S3.Object.Key
+		if !seen[uri] {
+			seen[uri] = true
+			objects = append(objects, uri)
+		}
+	}
+	sort.Strings(objects)
+	return uuid.NewSHA1(uuid.NameSpaceURL, []byte(strings.Join(objects, "\n"))).String()
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"batch-processor/processor"
+	"batch-processor/s3"
+	"shared/logger"
+	"shared/shutdown"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// replayCommand is the local subcommand that replays a collection run from its manifest
+const replayCommand = "replay"
+
+// runManifest mirrors the run manifest the data collector writes under its run-history prefix
+type runManifest struct {
+	RunID       string    `json:"run_id"`
+	Source      string    `json:"source"`
+	Bucket      string    `json:"bucket"`
+	Keys        []string  `json:"keys"`
+	PaperCount  int       `json:"paper_count"`
+	CollectedAt time.Time `json:"collected_at"`
+	CompletedAt time.Time `json:"completed_at"`
+}
+
+// runReplay replays the collection runs whose manifests are given as s3://bucket/key arguments
+func runReplay(args []string, watcher *shutdown.Watcher) {
+	if len(args) == 0 {
+		fmt.Println("Usage: batch-processor replay s3://bucket/run-history/YYYY-MM-DD/run-id.json [...]")
+		return
+	}
+
+	manifests, err := buildLocalEvent(args)
+	if err != nil {
+		watcher.Exit(err, false)
+	}
+
+	ctx := context.Background()
+	var s3Event events.S3Event
+	for _, record := range manifests.Records {
+		replayed, err := loadReplayEvent(ctx, record.S3.Bucket.Name, record.S3.Object.Key)
+		if err != nil {
+			watcher.Exit(err, false)
+		}
+		s3Event.Records = append(s3Event.Records, replayed.Records...)
+	}
+
+	result, err := processS3Event(ctx, s3Event, watcher.Done())
+	watcher.Exit(err, result != nil && result.Interrupted)
+}
+
+// handleReplay replays one run from a Lambda payload of the form {"replay_manifest": "s3://bucket/key"}
+func handleReplay(ctx context.Context, location string) (*processor.ProcessResult, error) {
+	manifest, err := buildLocalEvent([]string{location})
+	if err != nil {
+		return nil, lambdaError(logger.WrapError(err, logger.ErrorTypeConfig, "invalid replay manifest location"))
+	}
+	record := manifest.Records[0]
+	s3Event, err := loadReplayEvent(ctx, record.S3.Bucket.Name, record.S3.Object.Key)
+	if err != nil {
+		return nil, lambdaError(err)
+	}
+	return handleS3Event(ctx, s3Event)
+}
+
+// loadReplayEvent reads a run manifest and builds the S3 event its objects would have
+// produced, so replayed objects go through exactly the same processing path
+func loadReplayEvent(ctx context.Context, bucket, key string) (events.S3Event, error) {
+	var s3Event events.S3Event
+	contextLogger := logger.New("batch-processor").WithContext(ctx)
+
+	data, err := s3.NewDownloader().DownloadAndDecompress(ctx, bucket, key)
+	if err != nil {
+		return s3Event, logger.WrapError(err, logger.ErrorTypeS3, "failed to read run manifest")
+	}
+
+	var manifest runManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return s3Event, logger.WrapError(err, logger.ErrorTypeData, "failed to parse run manifest")
+	}
+	if len(manifest.Keys) == 0 {
+		return s3Event, logger.NewAppError(logger.ErrorTypeData, fmt.Sprintf("run manifest s3://%s/%s lists no objects", bucket, key), nil)
+	}
+
+	// Objects live in the raw-data bucket, which need not be the manifest's bucket
+	objectBucket := manifest.Bucket
+	if objectBucket == "" {
+		objectBucket = bucket
+	}
+	for _, objectKey := range manifest.Keys {
+		if strings.TrimSpace(objectKey) == "" {
+			continue
+		}
+		record := events.S3EventRecord{}
+		record.S3.Bucket.Name = objectBucket
+		record.S3.Object.Key = objectKey
+		s3Event.Records = append(s3Event.Records, record)
+	}
+
+	contextLogger.Info("Replaying collection run", map[string]interface{}{
+		"event":        "run_replay",
+		"run_id":       manifest.RunID,
+		"source":       manifest.Source,
+		"manifest":     fmt.Sprintf("s3://%s/%s", bucket, key),
+		"object_count": len(s3Event.Records),
+		"paper_count":  manifest.PaperCount,
+		"collected_at": manifest.CollectedAt.Format(time.RFC3339),
+	})
+	return s3Event, nil
+}
@@ -0,0 +1,108 @@
+// Package runs persists the final ProcessResult of every batch-processor run to the
+// ProcessingRuns table, keyed by trace_id, so status and digest consumers can read run
+// outcomes without parsing logs.
+package runs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"batch-processor/processor"
+	"shared/awsclient"
+	"shared/logger"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// maxStoredEntries caps the failed paper IDs, duplicate groups and record results kept on a
+// run item, so a large run with many failures stays well under the 400 KB item limit
+const maxStoredEntries = 100
+
+// Run is a ProcessingRuns item: the run's ProcessResult plus when it was recorded
+type Run struct {
+	processor.ProcessResult
+	RecordedAt string `json:"recorded_at"`
+	// Truncated is set when failed paper IDs, duplicate groups or record results were cut to fit the item
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// Recorder writes run results to the ProcessingRuns table
+type Recorder struct {
+	client    dynamodbiface.DynamoDBAPI
+	tableName string
+	logger    *logger.Logger
+}
+
+// NewRecorder creates a recorder for the given table whose client uses clientConfig's retries and timeouts
+func NewRecorder(tableName string, clientConfig awsclient.ClientConfig) *Recorder {
+	sess := awsclient.MustClientSession(clientConfig)
+	return NewRecorderWithClient(dynamodb.New(sess), tableName)
+}
+
+// NewRecorderWithClient creates a recorder with a custom DynamoDB client (for testing)
+func NewRecorderWithClient(client dynamodbiface.DynamoDBAPI, tableName string) *Recorder {
+	return &Recorder{
+		client:    client,
+		tableName: tableName,
+		logger:    logger.New("run-recorder"),
+	}
+}
+
+// RecordRun stores result under its trace ID, replacing an earlier record of the same trace
+func (r *Recorder) RecordRun(ctx context.Context, result *processor.ProcessResult) error {
+	if result == nil || result.TraceID == "" {
+		return fmt.Errorf("run result has no trace ID")
+	}
+
+	run := newRun(result)
+	item, err := dynamodbattribute.MarshalMap(run)
+	if err != nil {
+		return fmt.Errorf("failed to marshal run %s: %w", result.TraceID, err)
+	}
+
+	_, err = r.client.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(r.tableName),
+		Item:      item,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record run %s: %w", result.TraceID, err)
+	}
+
+	r.logger.WithContext(ctx).Info("Processing run recorded", map[string]interface{}{
+		"table":     r.tableName,
+		"status":    result.Status,
+		"truncated": run.Truncated,
+	})
+	return nil
+}
+
+// newRun copies result into a run item, trimming the lists that grow with the batch
+func newRun(result *processor.ProcessResult) *Run {
+	run := &Run{
+		ProcessResult: *result,
+		RecordedAt:    time.Now().UTC().Format(time.RFC3339),
+	}
+
+	if stats := result.UpsertStats; stats != nil && len(stats.FailedPaperIDs) > maxStoredEntries {
+		trimmed := *stats
+		trimmed.FailedPaperIDs = stats.FailedPaperIDs[:maxStoredEntries]
+		run.UpsertStats = &trimmed
+		run.Truncated = true
+	}
+	if stats := result.DeduplicationStats; stats != nil && len(stats.DuplicateGroups) > maxStoredEntries {
+		trimmed := *stats
+		trimmed.DuplicateGroups = stats.DuplicateGroups[:maxStoredEntries]
+		trimmed.DuplicateGroupsTruncated = true
+		run.DeduplicationStats = &trimmed
+		run.Truncated = true
+	}
+	if len(result.RecordResults) > maxStoredEntries {
+		run.RecordResults = result.RecordResults[:maxStoredEntries]
+		run.Truncated = true
+	}
+	return run
+}
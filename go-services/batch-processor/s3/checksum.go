@@ -0,0 +1,90 @@
+package s3
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// PayloadChecksumMetadataKey is the S3 user metadata key the data collector sets to the hex
+// SHA-256 of the uncompressed payload
+const PayloadChecksumMetadataKey = "payload-sha256"
+
+// SchemaVersionMetadataKey is the S3 user metadata key naming the payload schema version
+const SchemaVersionMetadataKey = "schema-version"
+
+// RunIDMetadataKey is the S3 user metadata key naming the collection run that wrote the object
+const RunIDMetadataKey = "run-id"
+
+// CollectionTimeMetadataKey is the S3 user metadata key holding the RFC 3339 time the
+// collection run started
+const CollectionTimeMetadataKey = "collection-time"
+
+// ChecksumMismatchError reports an object whose decompressed payload does not match the
+// checksum recorded at upload, i.e. a truncated or corrupted object
+type ChecksumMismatchError struct {
+	Bucket   string
+	Key      string
+	Expected string
+	Actual   string
+}
+
+func (e *ChecksumMismatchError) Error() string {
+	return fmt.Sprintf("payload checksum mismatch for %s/%s: expected sha256 %s, got %s", e.Bucket, e.Key, e.Expected, e.Actual)
+}
+
+// IntegrityFailure marks the error as corruption rather than a transient read failure
+func (e *ChecksumMismatchError) IntegrityFailure() bool { return true }
+
+// payloadChecksum returns the expected checksum from object metadata, or "" when the object
+// predates checksums
+func payloadChecksum(metadata map[string]*string) string {
+	return strings.ToLower(metadataValue(metadata, PayloadChecksumMetadataKey))
+}
+
+// metadataValue looks up a user metadata value case-insensitively, since the SDK
+// canonicalizes metadata keys (e.g. "Payload-Sha256")
+func metadataValue(metadata map[string]*string, key string) string {
+	for name, value := range metadata {
+		if strings.EqualFold(name, key) {
+			return strings.TrimSpace(aws.StringValue(value))
+		}
+	}
+	return ""
+}
+
+// verifyingReader hashes everything read through it and, at EOF, fails the read instead of
+// reporting EOF when the hash does not match the expected checksum
+type verifyingReader struct {
+	reader   io.Reader
+	hash     hash.Hash
+	expected string
+	bucket   string
+	key      string
+	err      error
+}
+
+func newVerifyingReader(reader io.Reader, expected, bucket, key string) *verifyingReader {
+	return &verifyingReader{reader: reader, hash: sha256.New(), expected: expected, bucket: bucket, key: key}
+}
+
+func (r *verifyingReader) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	n, err := r.reader.Read(p)
+	r.hash.Write(p[:n])
+	if err == io.EOF {
+		if actual := hex.EncodeToString(r.hash.Sum(nil)); actual != r.expected {
+			r.err = &ChecksumMismatchError{Bucket: r.bucket, Key: r.key, Expected: r.expected, Actual: actual}
+			return n, r.err
+		}
+		r.err = io.EOF
+	}
+	return n, err
+}
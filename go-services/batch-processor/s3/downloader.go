@@ -1,16 +1,19 @@
 package s3
 
 import (
+	"bufio"
 	"compress/gzip"
 	"context"
 	"fmt"
 	"io"
+	"shared/awsclient"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/klauspost/compress/zstd"
 )
 
 // Downloader handles S3 file downloads and decompression
@@ -20,44 +23,191 @@ type Downloader struct {
 
 // NewDownloader creates a new S3 downloader instance
 func NewDownloader() *Downloader {
-	sess := session.Must(session.NewSession())
+	sess := awsclient.MustSession()
 	return &Downloader{
 		s3Client: s3.New(sess),
 	}
 }
 
-// DownloadAndDecompress downloads a file from S3 and decompresses it if it's gzipped
+// DownloadAndDecompress downloads a file from S3 and decompresses it if it's gzip or zstd compressed
 func (d *Downloader) DownloadAndDecompress(ctx context.Context, bucket, key string) ([]byte, error) {
+	reader, err := d.OpenDecompressed(ctx, bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	// Read all content
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read content from %s/%s: %w", bucket, key, err)
+	}
+
+	return data, nil
+}
+
+// OpenDecompressed opens a streaming reader over an S3 object, decompressing it if it's gzip
+// or zstd compressed.
+// When the object carries a payload checksum, reading to EOF returns a *ChecksumMismatchError
+// instead of io.EOF if the decompressed bytes do not match it.
+// The caller must close the returned reader to release the underlying connection.
+func (d *Downloader) OpenDecompressed(ctx context.Context, bucket, key string) (io.ReadCloser, error) {
 	// Download file from S3
 	input := &s3.GetObjectInput{
 		Bucket: aws.String(bucket),
 		Key:    aws.String(key),
 	}
 
+	requestStart := time.Now()
 	result, err := d.s3Client.GetObjectWithContext(ctx, input)
 	if err != nil {
 		return nil, fmt.Errorf("failed to download S3 object %s/%s: %w", bucket, key, err)
 	}
-	defer result.Body.Close()
+	// Time spent reading the body is download time; the rest of a read is decompression
+	body := &timedReader{reader: result.Body, elapsed: time.Since(requestStart)}
 
-	// Read the content
-	var reader io.Reader = result.Body
+	// Buffer the body so the magic bytes can be inspected without consuming them
+	bufferedBody := bufio.NewReader(body)
 
-	// Check if file is gzipped based on extension or content type
-	if strings.HasSuffix(key, ".gz") || strings.HasSuffix(key, ".gzip") {
-		gzipReader, err := gzip.NewReader(result.Body)
+	readerFor := func(decompressed io.Reader, closers ...io.Closer) *decompressedReader {
+		return &decompressedReader{Reader: verifyPayload(decompressed, result.Metadata, bucket, key), closers: append(closers, result.Body), schemaVersion: metadataValue(result.Metadata, SchemaVersionMetadataKey), runID: metadataValue(result.Metadata, RunIDMetadataKey), collectedAt: metadataValue(result.Metadata, CollectionTimeMetadataKey), body: body, sniffed: body.elapsed}
+	}
+	// Pick the codec from the magic bytes, or the extension, content type or encoding
+	switch detectCompression(key, aws.StringValue(result.ContentType), aws.StringValue(result.ContentEncoding), bufferedBody) {
+	case compressionGzip:
+		gzipReader, err := gzip.NewReader(bufferedBody)
 		if err != nil {
+			result.Body.Close()
 			return nil, fmt.Errorf("failed to create gzip reader for %s/%s: %w", bucket, key, err)
 		}
-		defer gzipReader.Close()
-		reader = gzipReader
+		return readerFor(gzipReader, gzipReader), nil
+	case compressionZstd:
+		zstdReader, err := zstd.NewReader(bufferedBody)
+		if err != nil {
+			result.Body.Close()
+			return nil, fmt.Errorf("failed to create zstd reader for %s/%s: %w", bucket, key, err)
+		}
+		decompressed := zstdReader.IOReadCloser()
+		return readerFor(decompressed, decompressed), nil
 	}
 
-	// Read all content
-	data, err := io.ReadAll(reader)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read content from %s/%s: %w", bucket, key, err)
+	return readerFor(bufferedBody), nil
+}
+
+// timedReader accumulates the time spent in Read calls of the underlying reader
+type timedReader struct {
+	reader  io.Reader
+	elapsed time.Duration
+}
+
+func (r *timedReader) Read(buf []byte) (int, error) {
+	start := time.Now()
+	n, err := r.reader.Read(buf)
+	r.elapsed += time.Since(start)
+	return n, err
+}
+
+// verifyPayload wraps the decompressed stream so reading it to EOF checks the checksum
+// recorded at upload; objects uploaded without one are read unverified
+func verifyPayload(reader io.Reader, metadata map[string]*string, bucket, key string) io.Reader {
+	expected := payloadChecksum(metadata)
+	if expected == "" {
+		return reader
+	}
+	return newVerifyingReader(reader, expected, bucket, key)
+}
+
+// decompressedReader closes the decompressor and the S3 body together
+type decompressedReader struct {
+	io.Reader
+	closers       []io.Closer
+	schemaVersion string
+	runID         string
+	collectedAt   string
+	body          *timedReader  // the S3 body, timing the download
+	sniffed       time.Duration // body time spent before the first Read, sniffing the compression
+	reading       time.Duration // time spent in Read, download and decompression together
+}
+
+// Read reads decompressed bytes, timing the call so decompression can be told apart from
+// the download
+func (r *decompressedReader) Read(buf []byte) (int, error) {
+	start := time.Now()
+	n, err := r.Reader.Read(buf)
+	r.reading += time.Since(start)
+	return n, err
+}
+
+// ReadTimings returns the time spent so far downloading the object, from the GetObject
+// request on, and decompressing and verifying it
+func (r *decompressedReader) ReadTimings() (download, decompress time.Duration) {
+	download = r.body.elapsed
+	decompress = r.reading - (download - r.sniffed)
+	if decompress < 0 {
+		decompress = 0
 	}
+	return download, decompress
+}
 
-	return data, nil
-}
\ No newline at end of file
+// SchemaVersion returns the object's schema-version tag, or "" for untagged objects
+func (r *decompressedReader) SchemaVersion() string {
+	return r.schemaVersion
+}
+
+// CollectionRunID returns the run-id of the collection run that wrote the object, or "" for
+// objects uploaded before run IDs were recorded
+func (r *decompressedReader) CollectionRunID() string {
+	return r.runID
+}
+
+// CollectedAt returns the collection-time tag of the object, or "" for objects uploaded
+// without one
+func (r *decompressedReader) CollectedAt() string {
+	return r.collectedAt
+}
+
+// Close closes every underlying reader, returning the first error
+func (r *decompressedReader) Close() error {
+	var firstErr error
+	for _, closer := range r.closers {
+		if err := closer.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Codecs an object can be compressed with
+const (
+	compressionNone = ""
+	compressionGzip = "gzip"
+	compressionZstd = "zstd"
+)
+
+// detectCompression decides which codec an object needs decompressing with.
+// The magic bytes take precedence so mislabelled objects are still read correctly.
+func detectCompression(key, contentType, contentEncoding string, body *bufio.Reader) string {
+	if magic, err := body.Peek(4); err == nil && magic[0] == 0x28 && magic[1] == 0xb5 && magic[2] == 0x2f && magic[3] == 0xfd {
+		return compressionZstd
+	}
+	if magic, err := body.Peek(2); err == nil {
+		if magic[0] == 0x1f && magic[1] == 0x8b {
+			return compressionGzip
+		}
+		return compressionNone
+	}
+
+	// Too short to sniff; fall back to the object's metadata
+	contentType = strings.ToLower(contentType)
+	contentEncoding = strings.ToLower(contentEncoding)
+	switch {
+	case strings.HasSuffix(key, ".gz") || strings.HasSuffix(key, ".gzip") ||
+		contentType == "application/gzip" || contentType == "application/x-gzip" ||
+		contentEncoding == "gzip":
+		return compressionGzip
+	case strings.HasSuffix(key, ".zst") || strings.HasSuffix(key, ".zstd") ||
+		contentType == "application/zstd" || contentEncoding == "zstd":
+		return compressionZstd
+	}
+	return compressionNone
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"batch-processor/processor"
+	"shared/awsclient"
+	"shared/httpserver"
+	"shared/logger"
+	"shared/shutdown"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// serverShutdownTimeout bounds how long in-flight requests may drain after a shutdown signal
+const serverShutdownTimeout = 5 * time.Minute
+
+// runServer exposes S3 event processing over HTTP until a shutdown signal drains it
+func runServer(addr string, watcher *shutdown.Watcher, appLogger *logger.Logger) error {
+	batcher, err := newCoalescerFromEnv(watcher.Done())
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", httpserver.Healthz(watcher, appLogger))
+	mux.HandleFunc("/process", processHandler(watcher, batcher, appLogger))
+	mux.HandleFunc("/upload", uploadHandler(watcher, appLogger))
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           awsclient.Handler("batch-processor", mux),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	fields := map[string]interface{}{}
+	if batcher != nil {
+		fields["coalesce_window_ms"] = batcher.window.Milliseconds()
+		fields["coalesce_max_records"] = batcher.maxRecords
+	}
+	return httpserver.Serve(server, watcher, serverShutdownTimeout, appLogger, fields)
+}
+
+// processHandler processes an S3 event notification body; POST /process. With a coalescer,
+// events arriving within its window are processed in one pass.
+func processHandler(watcher *shutdown.Watcher, batcher *coalescer, appLogger *logger.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			httpserver.WriteJSON(w, appLogger, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+			return
+		}
+
+		var s3Event events.S3Event
+		if err := json.NewDecoder(r.Body).Decode(&s3Event); err != nil {
+			httpserver.WriteJSON(w, appLogger, http.StatusBadRequest, map[string]string{"error": "invalid S3 event: " + err.Error()})
+			return
+		}
+
+		var result *processor.ProcessResult
+		var err error
+		if batcher != nil && len(s3Event.Records) > 0 {
+			result, err = batcher.submit(s3Event)
+		} else {
+			// Detach from the request so a dropped client does not abort a batch write midway
+			result, err = processS3Event(context.WithoutCancel(r.Context()), s3Event, watcher.Done())
+		}
+		if err != nil {
+			httpserver.WriteJSON(w, appLogger, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+
+		status := http.StatusOK
+		if result.Status == "failed" {
+			status = http.StatusInternalServerError
+		}
+		httpserver.WriteJSON(w, appLogger, status, result)
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"batch-processor/processor"
+	"shared/logger"
+	"shared/slo"
+)
+
+// emitSLO reports the run's ingestion_success events: every paper upserted is good and every
+// paper that failed to upsert is bad. An object that could not be read or parsed yields no
+// papers, so it counts as one bad event.
+func emitSLO(contextLogger *logger.Logger, cfg slo.Config, result *processor.ProcessResult) {
+	if !cfg.Enabled || result == nil {
+		return
+	}
+	good, total := 0, 0
+	if stats := result.UpsertStats; stats != nil {
+		good = stats.SuccessItems
+		total = stats.SuccessItems + stats.FailedItems
+	}
+	for _, record := range result.RecordResults {
+		if record.Status == processor.RecordStatusFailed && record.PaperCount == 0 {
+			total++
+		}
+	}
+	slo.Emit(contextLogger, slo.Ratio(slo.IngestionSuccess, cfg.IngestionSuccess, good, total))
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"batch-processor/processor"
+	"shared/logger"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// eventSourceSQS identifies SQS records in a Lambda event
+const eventSourceSQS = "aws:sqs"
+
+// handleEvent dispatches a Lambda invocation: S3 notifications delivered through SQS get
+// per-message failure reporting, a replay_manifest payload replays a collection run, an
+// upload payload ingests an uploaded file, and anything else is treated as a direct S3 event
+func handleEvent(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+	var probe struct {
+		Records []struct {
+			EventSource string `json:"eventSource"`
+		} `json:"Records"`
+		ReplayManifest string         `json:"replay_manifest"`
+		Upload         *uploadRequest `json:"upload"`
+	}
+	if err := json.Unmarshal(payload, &probe); err != nil {
+		return nil, fmt.Errorf("failed to decode event: %w", err)
+	}
+
+	if len(probe.Records) > 0 && probe.Records[0].EventSource == eventSourceSQS {
+		var sqsEvent events.SQSEvent
+		if err := json.Unmarshal(payload, &sqsEvent); err != nil {
+			return nil, fmt.Errorf("failed to decode SQS event: %w", err)
+		}
+		return handleSQSEvent(ctx, sqsEvent)
+	}
+
+	if probe.ReplayManifest != "" {
+		return handleReplay(ctx, probe.ReplayManifest)
+	}
+
+	if probe.Upload != nil {
+		return handleUpload(ctx, probe.Upload)
+	}
+
+	var s3Event events.S3Event
+	if err := json.Unmarshal(payload, &s3Event); err != nil {
+		return nil, fmt.Errorf("failed to decode S3 event: %w", err)
+	}
+	return handleS3Event(ctx, s3Event)
+}
+
+// handleSQSEvent processes the S3 notifications carried by an SQS batch and reports
+// batchItemFailures, so only messages whose objects failed are redelivered
+func handleSQSEvent(ctx context.Context, sqsEvent events.SQSEvent) (events.SQSEventResponse, error) {
+	contextLogger := logger.New("batch-processor").WithContext(ctx)
+	response := events.SQSEventResponse{}
+
+	// Flatten every message's S3 records, remembering which message each came from
+	var s3Event events.S3Event
+	var recordMessages []string
+	for _, message := range sqsEvent.Records {
+		var notification events.S3Event
+		if err := json.Unmarshal([]byte(message.Body), &notification); err != nil {
+			contextLogger.Warn("Skipping unreadable SQS message", map[string]interface{}{
+				"message_id": message.MessageId,
+				"error":      err.Error(),
+			})
+			response.BatchItemFailures = append(response.BatchItemFailures, events.SQSBatchItemFailure{
+				ItemIdentifier: message.MessageId,
+			})
+			continue
+		}
+
+		// S3 test events and other bodies without records have nothing to process
+		for _, record := range notification.Records {
+			s3Event.Records = append(s3Event.Records, record)
+			recordMessages = append(recordMessages, message.MessageId)
+		}
+	}
+
+	if len(s3Event.Records) == 0 {
+		return response, nil
+	}
+
+	var recordResults []processor.RecordResult
+	result, err := processS3Event(ctx, s3Event, nil)
+	if err != nil {
+		// Nothing is known to have landed, so every message with records is retried
+		contextLogger.Error("Error processing SQS batch", err)
+	} else {
+		recordResults = result.RecordResults
+	}
+
+	response.BatchItemFailures = appendFailedMessages(response.BatchItemFailures, recordMessages, recordResults)
+	contextLogger.Info("SQS batch processed", map[string]interface{}{
+		"messages":        len(sqsEvent.Records),
+		"s3_records":      len(s3Event.Records),
+		"failed_messages": len(response.BatchItemFailures),
+	})
+	return response, nil
+}
+
+// appendFailedMessages adds each message owning a failed record once. Without record
+// results every message is considered failed.
+func appendFailedMessages(failures []events.SQSBatchItemFailure, recordMessages []string, recordResults []processor.RecordResult) []events.SQSBatchItemFailure {
+	reported := make(map[string]bool)
+	for i, messageID := range recordMessages {
+		if reported[messageID] {
+			continue
+		}
+		if recordResults != nil && i < len(recordResults) && !recordResults[i].Failed() {
+			continue
+		}
+		reported[messageID] = true
+		failures = append(failures, events.SQSBatchItemFailure{ItemIdentifier: messageID})
+	}
+	return failures
+}
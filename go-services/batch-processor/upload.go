@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+
+	"batch-processor/processor"
+	"batch-processor/upload"
+	"shared/awsclient"
+	"shared/httpserver"
+	"shared/logger"
+	"shared/shutdown"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	lambdaservice "github.com/aws/aws-sdk-go/service/lambda"
+)
+
+// maxUploadBytes bounds the size of an uploaded file
+const maxUploadBytes = 10 << 20
+
+// uploadRequest is the Lambda payload {"upload": {...}} carrying an uploaded file
+type uploadRequest struct {
+	Format    string `json:"format"` // json, csv or bibtex; inferred from filename when empty
+	Filename  string `json:"filename"`
+	Source    string `json:"source"` // recorded on papers that name no source; "upload" by default
+	Content   string `json:"content"`
+	Base64    bool   `json:"base64"`    // content is base64 encoded
+	Vectorize *bool  `json:"vectorize"` // start vectorization of the upload's trace; true by default
+}
+
+// uploadResponse reports what happened to an uploaded file
+type uploadResponse struct {
+	Upload              *upload.Batch            `json:"upload"`
+	Object              string                   `json:"object,omitempty"`
+	Processing          *processor.ProcessResult `json:"processing,omitempty"`
+	VectorizationQueued bool                     `json:"vectorization_queued"`
+	Error               string                   `json:"error,omitempty"`
+}
+
+// processUpload validates an uploaded file, stages its papers in UPLOAD_BUCKET as a raw-data
+// object and processes that object like any collected one, so uploads share deduplication,
+// versioning and upserts. When the papers land and VECTORIZE_FUNCTION_NAME is set, the
+// coordinator is invoked asynchronously for the upload's trace.
+// The response carries the parse report even when the upload is rejected.
+func processUpload(ctx context.Context, format string, data []byte, source string, vectorize bool, shutdown <-chan struct{}) (*uploadResponse, error) {
+	contextLogger := logger.New("batch-processor").WithContext(ctx)
+	response := &uploadResponse{}
+
+	bucket := os.Getenv("UPLOAD_BUCKET")
+	if bucket == "" {
+		return response, logger.NewAppError(logger.ErrorTypeConfig, "UPLOAD_BUCKET is not set", nil)
+	}
+
+	batch, err := upload.Parse(format, data, source)
+	response.Upload = batch
+	if err != nil {
+		return response, logger.WrapError(err, logger.ErrorTypeData, "invalid upload")
+	}
+
+	key, err := upload.NewStager(bucket, os.Getenv("UPLOAD_PREFIX")).Stage(ctx, batch)
+	if err != nil {
+		return response, logger.WrapError(err, logger.ErrorTypeS3, "failed to stage upload")
+	}
+	response.Object = fmt.Sprintf("s3://%s/%s", bucket, key)
+	contextLogger.Info("Upload staged", map[string]interface{}{
+		"event":    "upload_staged",
+		"format":   batch.Format,
+		"source":   batch.Source,
+		"accepted": batch.Accepted,
+		"rejected": batch.RejectedCount,
+		"object":   response.Object,
+	})
+
+	record := events.S3EventRecord{}
+	record.S3.Bucket.Name = bucket
+	record.S3.Object.Key = key
+	result, err := processS3Event(ctx, events.S3Event{Records: []events.S3EventRecord{record}}, shutdown)
+	if err != nil {
+		return response, err
+	}
+	response.Processing = result
+
+	functionName := os.Getenv("VECTORIZE_FUNCTION_NAME")
+	if !vectorize || functionName == "" || result.Validation != nil || result.Status == "failed" || result.WrittenCount == 0 {
+		return response, nil
+	}
+	if err := startVectorization(ctx, functionName, result.TraceID); err != nil {
+		// The papers are stored; the trace can still be vectorized by invoking the coordinator
+		contextLogger.Warn("Failed to start vectorization of upload", map[string]interface{}{
+			"function": functionName,
+			"trace_id": result.TraceID,
+			"error":    err.Error(),
+		})
+		return response, nil
+	}
+	response.VectorizationQueued = true
+	return response, nil
+}
+
+// startVectorization invokes the vector coordinator asynchronously with the payload the
+// state machine's Vectorize step sends
+func startVectorization(ctx context.Context, functionName, traceID string) error {
+	payload, err := json.Marshal(map[string]string{"trace_id": traceID})
+	if err != nil {
+		return err
+	}
+	client := lambdaservice.New(awsclient.MustSession())
+	_, err = client.InvokeWithContext(ctx, &lambdaservice.InvokeInput{
+		FunctionName:   aws.String(functionName),
+		InvocationType: aws.String(lambdaservice.InvocationTypeEvent),
+		Payload:        payload,
+	})
+	return err
+}
+
+// handleUpload processes an upload from a Lambda payload of the form {"upload": {...}}
+func handleUpload(ctx context.Context, request *uploadRequest) (*uploadResponse, error) {
+	data := []byte(request.Content)
+	if request.Base64 {
+		decoded, err := base64.StdEncoding.DecodeString(request.Content)
+		if err != nil {
+			return nil, lambdaError(logger.WrapError(err, logger.ErrorTypeData, "upload content is not valid base64"))
+		}
+		data = decoded
+	}
+	format, err := upload.FormatFor(request.Format, request.Filename, "")
+	if err != nil {
+		return nil, lambdaError(logger.WrapError(err, logger.ErrorTypeData, "invalid upload format"))
+	}
+
+	response, err := processUpload(ctx, format, data, request.Source, request.Vectorize == nil || *request.Vectorize, nil)
+	if err != nil {
+		return nil, lambdaError(err)
+	}
+	return response, nil
+}
+
+// uploadHandler ingests an uploaded file sent as the request body; POST /upload. The format
+// comes from the format query parameter, the filename parameter's extension or the content
+// type; source and vectorize=false are optional parameters.
+func uploadHandler(watcher *shutdown.Watcher, appLogger *logger.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			httpserver.WriteJSON(w, appLogger, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+			return
+		}
+
+		query := r.URL.Query()
+		format, err := upload.FormatFor(query.Get("format"), query.Get("filename"), r.Header.Get("Content-Type"))
+		if err != nil {
+			httpserver.WriteJSON(w, appLogger, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		vectorize := true
+		if value := query.Get("vectorize"); value != "" {
+			if vectorize, err = strconv.ParseBool(value); err != nil {
+				httpserver.WriteJSON(w, appLogger, http.StatusBadRequest, map[string]string{"error": "invalid vectorize parameter: " + err.Error()})
+				return
+			}
+		}
+
+		data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxUploadBytes))
+		if err != nil {
+			status := http.StatusBadRequest
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				status = http.StatusRequestEntityTooLarge
+			}
+			httpserver.WriteJSON(w, appLogger, status, map[string]string{"error": "failed to read upload: " + err.Error()})
+			return
+		}
+
+		// Detach from the request so a dropped client does not abort a batch write midway
+		response, err := processUpload(context.WithoutCancel(r.Context()), format, data, query.Get("source"), vectorize, watcher.Done())
+		if err != nil {
+			response.Error = err.Error()
+			status := http.StatusInternalServerError
+			var appErr *logger.AppError
+			if errors.As(err, &appErr) && appErr.Type == logger.ErrorTypeData {
+				status = http.StatusUnprocessableEntity
+			}
+			httpserver.WriteJSON(w, appLogger, status, response)
+			return
+		}
+
+		status := http.StatusOK
+		if response.Processing.Status == "failed" {
+			status = http.StatusInternalServerError
+		}
+		httpserver.WriteJSON(w, appLogger, status, response)
+	}
+}
@@ -0,0 +1,255 @@
+package upload
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// bibtexMonths maps BibTeX month macros and names to two-digit months
+var bibtexMonths = map[string]string{
+	"jan": "01", "feb": "02", "mar": "03", "apr": "04", "may": "05", "jun": "06",
+	"jul": "07", "aug": "08", "sep": "09", "oct": "10", "nov": "11", "dec": "12",
+}
+
+// parseBibTeX reads the entries of a BibTeX file into paper fields. The citation key stands
+// in for paper_id when the entry has no DOI; @comment, @preamble and @string blocks are skipped.
+func parseBibTeX(data []byte) ([]map[string]interface{}, error) {
+	scanner := &bibtexScanner{input: string(data)}
+	var entries []map[string]interface{}
+	for {
+		entryType, ok := scanner.nextEntry()
+		if !ok {
+			break
+		}
+		open := scanner.peek()
+		if open != '{' && open != '(' {
+			return nil, fmt.Errorf("bibtex entry @%s at offset %d: expected { or (", entryType, scanner.pos)
+		}
+		scanner.pos++
+
+		switch strings.ToLower(entryType) {
+		case "comment", "preamble", "string":
+			if err := scanner.skipBlock(open); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		key, fields, err := scanner.entryBody(open)
+		if err != nil {
+			return nil, fmt.Errorf("bibtex entry @%s: %w", entryType, err)
+		}
+		entries = append(entries, bibtexPaper(key, fields))
+	}
+	return entries, nil
+}
+
+// bibtexPaper maps BibTeX fields onto the upload field names
+func bibtexPaper(key string, fields map[string]string) map[string]interface{} {
+	entry := map[string]interface{}{
+		"title":    fields["title"],
+		"abstract": fields["abstract"],
+		"doi":      fields["doi"],
+	}
+	if entry["doi"] == "" {
+		entry["paper_id"] = key
+	}
+	if authors := fields["author"]; authors != "" {
+		var names []interface{}
+		for _, name := range strings.Split(authors, " and ") {
+			names = append(names, name)
+		}
+		entry["authors"] = names
+	}
+	if keywords := fields["keywords"]; keywords != "" {
+		entry["categories"] = strings.ReplaceAll(keywords, ",", ";")
+	}
+	if year := fields["year"]; year != "" {
+		entry["year"] = year
+		if month := bibtexMonth(fields["month"]); month != "" {
+			entry["year"] = year + "-" + month
+		}
+	}
+	return entry
+}
+
+// bibtexMonth reads a month given as a macro or name ("mar", "March") or a number, returning "" when absent
+func bibtexMonth(value string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if len(value) >= 3 {
+		if month, ok := bibtexMonths[value[:3]]; ok {
+			return month
+		}
+	}
+	if number, err := strconv.Atoi(value); err == nil && number >= 1 && number <= 12 {
+		return fmt.Sprintf("%02d", number)
+	}
+	return ""
+}
+
+// bibtexScanner walks a BibTeX document
+type bibtexScanner struct {
+	input string
+	pos   int
+}
+
+// nextEntry advances past the next "@type" and returns the type; text between entries is ignored
+func (s *bibtexScanner) nextEntry() (string, bool) {
+	at := strings.IndexByte(s.input[s.pos:], '@')
+	if at < 0 {
+		return "", false
+	}
+	s.pos += at + 1
+	start := s.pos
+	for s.pos < len(s.input) && isBibtexNameChar(s.input[s.pos]) {
+		s.pos++
+	}
+	entryType := s.input[start:s.pos]
+	s.skipSpace()
+	return entryType, true
+}
+
+// entryBody reads "key, name = value, ..." up to the closing delimiter
+func (s *bibtexScanner) entryBody(open byte) (string, map[string]string, error) {
+	closer := byte('}')
+	if open == '(' {
+		closer = ')'
+	}
+
+	s.skipSpace()
+	start := s.pos
+	for s.pos < len(s.input) && s.input[s.pos] != ',' && s.input[s.pos] != closer {
+		s.pos++
+	}
+	key := strings.TrimSpace(s.input[start:s.pos])
+
+	fields := make(map[string]string)
+	for {
+		s.skipSpace()
+		if s.pos >= len(s.input) {
+			return key, nil, fmt.Errorf("entry %q is not terminated", key)
+		}
+		switch s.input[s.pos] {
+		case closer:
+			s.pos++
+			return key, fields, nil
+		case ',':
+			s.pos++
+			continue
+		}
+
+		start := s.pos
+		for s.pos < len(s.input) && isBibtexNameChar(s.input[s.pos]) {
+			s.pos++
+		}
+		name := strings.ToLower(s.input[start:s.pos])
+		s.skipSpace()
+		if name == "" || s.peek() != '=' {
+			return key, nil, fmt.Errorf("entry %q: expected field name and = at offset %d", key, s.pos)
+		}
+		s.pos++
+
+		value, err := s.value()
+		if err != nil {
+			return key, nil, fmt.Errorf("entry %q field %s: %w", key, name, err)
+		}
+		fields[name] = collapseSpace(value)
+	}
+}
+
+// value reads a field value: braced or quoted text, a number or macro, joined with #
+func (s *bibtexScanner) value() (string, error) {
+	var parts []string
+	for {
+		s.skipSpace()
+		switch s.peek() {
+		case '{':
+			s.pos++
+			start := s.pos
+			if err := s.skipBlock('{'); err != nil {
+				return "", err
+			}
+			parts = append(parts, stripBraces(s.input[start:s.pos-1]))
+		case '"':
+			s.pos++
+			start, depth := s.pos, 0
+			for ; s.pos < len(s.input); s.pos++ {
+				c := s.input[s.pos]
+				if c == '{' {
+					depth++
+				} else if c == '}' {
+					depth--
+				} else if c == '"' && depth == 0 {
+					break
+				}
+			}
+			if s.pos >= len(s.input) {
+				return "", fmt.Errorf("unterminated quoted value")
+			}
+			parts = append(parts, stripBraces(s.input[start:s.pos]))
+			s.pos++
+		default:
+			start := s.pos
+			for s.pos < len(s.input) && isBibtexNameChar(s.input[s.pos]) {
+				s.pos++
+			}
+			if start == s.pos {
+				return "", fmt.Errorf("missing value at offset %d", s.pos)
+			}
+			parts = append(parts, s.input[start:s.pos])
+		}
+
+		s.skipSpace()
+		if s.peek() != '#' {
+			return strings.Join(parts, ""), nil
+		}
+		s.pos++
+	}
+}
+
+// skipBlock advances past the delimiter closing an already opened block, honouring nested braces
+func (s *bibtexScanner) skipBlock(open byte) error {
+	closer := byte('}')
+	if open == '(' {
+		closer = ')'
+	}
+	depth := 0
+	for ; s.pos < len(s.input); s.pos++ {
+		switch c := s.input[s.pos]; {
+		case c == closer && depth == 0:
+			s.pos++
+			return nil
+		case c == '{':
+			depth++
+		case c == '}':
+			depth--
+		}
+	}
+	return fmt.Errorf("unterminated block")
+}
+
+// peek returns the current byte, or 0 at the end of input
+func (s *bibtexScanner) peek() byte {
+	if s.pos >= len(s.input) {
+		return 0
+	}
+	return s.input[s.pos]
+}
+
+// skipSpace advances past whitespace
+func (s *bibtexScanner) skipSpace() {
+	for s.pos < len(s.input) && strings.IndexByte(" \t\r\n", s.input[s.pos]) >= 0 {
+		s.pos++
+	}
+}
+
+// isBibtexNameChar reports whether c can appear in an entry type, field name or macro
+func isBibtexNameChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.IndexByte("_-:.+/", c) >= 0
+}
+
+// stripBraces removes the case-protecting braces BibTeX values use, e.g. "{BERT} models"
+func stripBraces(value string) string {
+	return strings.NewReplacer("{", "", "}", "").Replace(value)
+}
@@ -0,0 +1,305 @@
+// Package upload turns user-uploaded JSON, CSV or BibTeX files of papers into the raw-data
+// object format the processor reads, so uploaded papers are deduplicated, upserted and
+// vectorized exactly like collected ones.
+package upload
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+// Accepted upload formats
+const (
+	FormatJSON   = "json"
+	FormatCSV    = "csv"
+	FormatBibTeX = "bibtex"
+)
+
+// DefaultSource is recorded on uploaded papers when the request names no source
+const DefaultSource = "upload"
+
+// MaxPapers bounds the papers accepted from one file
+const MaxPapers = 5000
+
+// maxRejections limits the rejected entries listed in a batch
+const maxRejections = 100
+
+// Paper is one uploaded paper in the raw-data field layout the processor parses
+type Paper struct {
+	PaperID       string   `json:"paper_id"`
+	Source        string   `json:"source"`
+	Title         string   `json:"title"`
+	Abstract      string   `json:"abstract,omitempty"`
+	Authors       []string `json:"authors,omitempty"`
+	PublishedDate string   `json:"published_date,omitempty"`
+	Categories    []string `json:"categories,omitempty"`
+	DOI           string   `json:"doi,omitempty"`
+}
+
+// Rejection explains why one entry of the file was not accepted; Entry is its 1-based
+// position (the data row for CSV)
+type Rejection struct {
+	Entry   int    `json:"entry"`
+	PaperID string `json:"paper_id,omitempty"`
+	Reason  string `json:"reason"`
+}
+
+// Batch is the result of parsing an upload
+type Batch struct {
+	Format        string      `json:"format"`
+	Source        string      `json:"source"`
+	Papers        []Paper     `json:"-"`
+	Accepted      int         `json:"accepted"`
+	RejectedCount int         `json:"rejected_count"`
+	Rejected      []Rejection `json:"rejected,omitempty"` // the first 100 rejections
+}
+
+// FormatFor resolves the upload format from an explicit name, falling back to the file
+// name's extension and then the content type
+func FormatFor(format, filename, contentType string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case FormatJSON:
+		return FormatJSON, nil
+	case FormatCSV:
+		return FormatCSV, nil
+	case FormatBibTeX, "bib":
+		return FormatBibTeX, nil
+	case "":
+	default:
+		return "", fmt.Errorf("unsupported upload format %q, expected json, csv or bibtex", format)
+	}
+
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".json":
+		return FormatJSON, nil
+	case ".csv":
+		return FormatCSV, nil
+	case ".bib", ".bibtex":
+		return FormatBibTeX, nil
+	}
+	switch mediaType, _, _ := strings.Cut(strings.ToLower(contentType), ";"); strings.TrimSpace(mediaType) {
+	case "application/json":
+		return FormatJSON, nil
+	case "text/csv":
+		return FormatCSV, nil
+	case "application/x-bibtex", "text/x-bibtex":
+		return FormatBibTeX, nil
+	}
+	return "", fmt.Errorf("upload format is required when it cannot be inferred from the file name or content type")
+}
+
+// Parse reads an uploaded file in format and validates each paper. Invalid entries are
+// rejected individually; an error is returned only when the file itself cannot be read,
+// holds more than MaxPapers entries or yields no valid paper.
+func Parse(format string, data []byte, source string) (*Batch, error) {
+	if source = strings.TrimSpace(source); source == "" {
+		source = DefaultSource
+	}
+
+	var entries []map[string]interface{}
+	var err error
+	switch format {
+	case FormatJSON:
+		entries, err = parseJSON(data)
+	case FormatCSV:
+		entries, err = parseCSV(data)
+	case FormatBibTeX:
+		entries, err = parseBibTeX(data)
+	default:
+		err = fmt.Errorf("unsupported upload format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) > MaxPapers {
+		return nil, fmt.Errorf("upload holds %d entries, more than the %d allowed per file", len(entries), MaxPapers)
+	}
+
+	batch := &Batch{Format: format, Source: source}
+	seen := make(map[string]int)
+	for i, entry := range entries {
+		paper, err := toPaper(entry, source)
+		if err == nil {
+			if first, duplicate := seen[paper.PaperID]; duplicate {
+				err = fmt.Errorf("duplicate paper_id, first given in entry %d", first)
+			}
+		}
+		if err != nil {
+			batch.reject(Rejection{Entry: i + 1, PaperID: paper.PaperID, Reason: err.Error()})
+			continue
+		}
+		seen[paper.PaperID] = i + 1
+		batch.Papers = append(batch.Papers, paper)
+	}
+	batch.Accepted = len(batch.Papers)
+
+	if batch.Accepted == 0 {
+		return batch, fmt.Errorf("no valid papers in upload (%d entries rejected)", batch.RejectedCount)
+	}
+	return batch, nil
+}
+
+// reject counts a rejected entry, listing it while under the limit
+func (b *Batch) reject(rejection Rejection) {
+	b.RejectedCount++
+	if len(b.Rejected) < maxRejections {
+		b.Rejected = append(b.Rejected, rejection)
+	}
+}
+
+// parseJSON reads a JSON array of paper objects, or an object holding one under "papers"
+func parseJSON(data []byte) ([]map[string]interface{}, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '{' {
+		var wrapper struct {
+			Papers []map[string]interface{} `json:"papers"`
+		}
+		if err := json.Unmarshal(data, &wrapper); err != nil {
+			return nil, fmt.Errorf("failed to parse JSON upload: %w", err)
+		}
+		return wrapper.Papers, nil
+	}
+
+	var entries []map[string]interface{}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON upload: %w", err)
+	}
+	return entries, nil
+}
+
+// parseCSV reads a CSV file with a header row. Column names are matched case-insensitively
+// against the paper fields; authors and categories hold ";"-separated lists.
+func parseCSV(data []byte) ([]map[string]interface{}, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\ufeff"))))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	for i := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(header[i]))
+	}
+
+	var entries []map[string]interface{}
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV row %d: %w", row, err)
+		}
+		entry := make(map[string]interface{}, len(header))
+		for i, value := range record {
+			if i < len(header) && header[i] != "" {
+				entry[header[i]] = value
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// toPaper validates one entry and converts it to the raw-data layout. The paper ID is the
+// entry's paper_id or id, then its DOI; a title is required and dates must parse.
+func toPaper(entry map[string]interface{}, source string) (Paper, error) {
+	paper := Paper{
+		PaperID:    firstString(entry, "paper_id", "id"),
+		Source:     firstString(entry, "source"),
+		Title:      collapseSpace(firstString(entry, "title")),
+		Abstract:   collapseSpace(firstString(entry, "abstract", "summary")),
+		Authors:    stringList(entry["authors"], ";"),
+		Categories: stringList(entry["categories"], ";"),
+		DOI:        normalizeDOI(firstString(entry, "doi")),
+	}
+	if paper.Source == "" {
+		paper.Source = source
+	}
+	if paper.PaperID == "" && paper.DOI != "" {
+		paper.PaperID = "doi:" + paper.DOI
+	}
+	if paper.PaperID == "" {
+		return paper, fmt.Errorf("missing paper_id and doi")
+	}
+	if paper.Title == "" {
+		return paper, fmt.Errorf("missing title")
+	}
+
+	if date := firstString(entry, "published_date", "date", "year"); date != "" {
+		published, err := parseDate(date)
+		if err != nil {
+			return paper, err
+		}
+		paper.PublishedDate = published.Format(time.RFC3339)
+	}
+	return paper, nil
+}
+
+// dateLayouts are the accepted published_date forms, most specific first
+var dateLayouts = []string{time.RFC3339, "2006-01-02", "2006-01", "2006"}
+
+// parseDate reads a published date given as RFC 3339, a date, a year-month or a year
+func parseDate(value string) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return parsed.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid published_date %q, expected YYYY, YYYY-MM, YYYY-MM-DD or RFC 3339", value)
+}
+
+// firstString returns the first of keys holding a non-empty string, trimmed
+func firstString(entry map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		if value, ok := entry[key].(string); ok {
+			if value = strings.TrimSpace(value); value != "" {
+				return value
+			}
+		}
+	}
+	return ""
+}
+
+// stringList reads a JSON array of strings or a string of separator-delimited values
+func stringList(value interface{}, separator string) []string {
+	var items []string
+	switch v := value.(type) {
+	case []interface{}:
+		for _, element := range v {
+			if item, ok := element.(string); ok {
+				if item = collapseSpace(item); item != "" {
+					items = append(items, item)
+				}
+			}
+		}
+	case string:
+		for _, item := range strings.Split(v, separator) {
+			if item = collapseSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	}
+	return items
+}
+
+// normalizeDOI strips resolver prefixes and lowercases a DOI
+func normalizeDOI(doi string) string {
+	doi = strings.ToLower(strings.TrimSpace(doi))
+	for _, prefix := range []string{"https://doi.org/", "http://doi.org/", "https://dx.doi.org/", "http://dx.doi.org/", "doi:"} {
+		doi = strings.TrimPrefix(doi, prefix)
+	}
+	return doi
+}
+
+// collapseSpace trims a value and collapses internal runs of whitespace
+func collapseSpace(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
@@ -0,0 +1,106 @@
+package upload
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"batch-processor/s3"
+	"shared/awsclient"
+
+	"github.com/aws/aws-sdk-go/aws"
+	awss3 "github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/google/uuid"
+)
+
+// DefaultPrefix keeps staged uploads apart from the collector's raw-data prefix, so bucket
+// notifications on raw-data/ do not process an upload a second time
+const DefaultPrefix = "uploads"
+
+// payloadSchemaVersion is the raw-data schema staged objects are written in
+const payloadSchemaVersion = "1"
+
+// Stager writes validated uploads to S3 as raw-data objects
+type Stager struct {
+	client s3iface.S3API
+	bucket string
+	prefix string
+	now    func() time.Time
+}
+
+// NewStager creates a stager writing to bucket under prefix
+func NewStager(bucket, prefix string) *Stager {
+	return NewStagerWithClient(awss3.New(awsclient.MustSession()), bucket, prefix)
+}
+
+// NewStagerWithClient creates a stager with a custom S3 client (for testing)
+func NewStagerWithClient(client s3iface.S3API, bucket, prefix string) *Stager {
+	if prefix = strings.Trim(prefix, "/"); prefix == "" {
+		prefix = DefaultPrefix
+	}
+	return &Stager{client: client, bucket: bucket, prefix: prefix, now: time.Now}
+}
+
+// Stage writes the batch's papers as a gzipped JSON array carrying the payload checksum and
+// schema version the downloader verifies, returning the object key.
+// Keys follow prefix/YYYY-MM-DD/source-upload-YYYYMMDD-HHMMSS-id.json.gz
+func (s *Stager) Stage(ctx context.Context, batch *Batch) (string, error) {
+	payload, err := json.Marshal(batch.Papers)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal uploaded papers: %w", err)
+	}
+
+	var compressed bytes.Buffer
+	gzipWriter := gzip.NewWriter(&compressed)
+	if _, err := gzipWriter.Write(payload); err != nil {
+		return "", fmt.Errorf("failed to compress uploaded papers: %w", err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return "", fmt.Errorf("failed to compress uploaded papers: %w", err)
+	}
+
+	now := s.now().UTC()
+	key := fmt.Sprintf("%s/%s/%s-upload-%s-%s.json.gz", s.prefix, now.Format("2006-01-02"),
+		keySafe(batch.Source), now.Format("20060102-150405"), uuid.New().String()[:8])
+	checksum := sha256.Sum256(payload)
+	_, err = s.client.PutObjectWithContext(ctx, &awss3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(compressed.Bytes()),
+		ContentType: aws.String("application/gzip"),
+		Metadata: map[string]*string{
+			s3.PayloadChecksumMetadataKey: aws.String(hex.EncodeToString(checksum[:])),
+			s3.SchemaVersionMetadataKey:   aws.String(payloadSchemaVersion),
+			"upload-format":               aws.String(batch.Format),
+			"paper-count":                 aws.String(fmt.Sprintf("%d", len(batch.Papers))),
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to stage upload to s3://%s/%s: %w", s.bucket, key, err)
+	}
+	return key, nil
+}
+
+// keySafe reduces a user-supplied source name to characters safe in an object key
+func keySafe(name string) string {
+	safe := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + ('a' - 'A')
+		}
+		return '-'
+	}, name)
+	if safe == "" {
+		return DefaultSource
+	}
+	return safe
+}
@@ -0,0 +1,233 @@
+package webhook
+
+import (
+	"batch-processor/processor"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"shared/awsclient"
+	"shared/logger"
+	"time"
+)
+
+const (
+	// DefaultBatchSize is the maximum number of papers per webhook notification
+	DefaultBatchSize = 100
+	// DefaultMaxRetries is the number of delivery attempts per webhook URL
+	DefaultMaxRetries = 3
+	// SignatureHeader carries the HMAC-SHA256 signature of the request body
+	SignatureHeader = "X-Pipeline-Signature"
+	// EventTypeNewPapers identifies new-paper notifications
+	EventTypeNewPapers = "papers.new"
+)
+
+// HTTPClient interface for making HTTP requests (allows mocking)
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// NewPaperEvent represents a single paper in a new-paper notification
+type NewPaperEvent struct {
+	PaperID    string   `json:"paper_id"`
+	Title      string   `json:"title"`
+	Categories []string `json:"categories"`
+	TraceID    string   `json:"trace_id"`
+}
+
+// Notification represents the payload POSTed to webhook URLs
+type Notification struct {
+	EventType string          `json:"event_type"`
+	TraceID   string          `json:"trace_id"`
+	Timestamp string          `json:"timestamp"`
+	Count     int             `json:"count"`
+	Papers    []NewPaperEvent `json:"papers"`
+}
+
+// Emitter posts new-paper notifications to configured webhook URLs
+type Emitter struct {
+	httpClient HTTPClient
+	urls       []string
+	secret     string
+	batchSize  int
+	maxRetries int
+	retryDelay time.Duration
+	logger     *logger.Logger
+}
+
+// NewEmitter creates a new webhook emitter for the given URLs and signing secret
+func NewEmitter(urls []string, secret string) *Emitter {
+	return &Emitter{
+		httpClient: awsclient.HTTPClient(&http.Client{
+			Timeout: 10 * time.Second,
+		}),
+		urls:       urls,
+		secret:     secret,
+		batchSize:  DefaultBatchSize,
+		maxRetries: DefaultMaxRetries,
+		retryDelay: 500 * time.Millisecond,
+		logger:     logger.New("webhook-emitter"),
+	}
+}
+
+// NewEmitterWithHTTPClient creates a webhook emitter with a custom HTTP client (for testing)
+func NewEmitterWithHTTPClient(urls []string, secret string, httpClient HTTPClient) *Emitter {
+	emitter := NewEmitter(urls, secret)
+	emitter.httpClient = httpClient
+	return emitter
+}
+
+// NotifyNewPapers sends batched new-paper notifications to every configured URL
+func (e *Emitter) NotifyNewPapers(ctx context.Context, papers []processor.Paper) error {
+	if len(e.urls) == 0 || len(papers) == 0 {
+		return nil
+	}
+
+	contextLogger := e.logger.WithContext(ctx)
+	var lastError error
+	failedDeliveries := 0
+
+	for i := 0; i < len(papers); i += e.batchSize {
+		end := i + e.batchSize
+		if end > len(papers) {
+			end = len(papers)
+		}
+
+		body, err := json.Marshal(e.buildNotification(papers[i:end]))
+		if err != nil {
+			return fmt.Errorf("failed to marshal webhook notification: %w", err)
+		}
+
+		for _, url := range e.urls {
+			if err := e.deliverWithRetry(ctx, url, body); err != nil {
+				lastError = err
+				failedDeliveries++
+				contextLogger.Error("Webhook delivery failed", err, map[string]interface{}{
+					"url":         url,
+					"batch_start": i,
+					"batch_size":  end - i,
+				})
+			}
+		}
+	}
+
+	contextLogger.InfoWithCount("Webhook notifications sent", len(papers), map[string]interface{}{
+		"url_count":         len(e.urls),
+		"failed_deliveries": failedDeliveries,
+	})
+
+	if lastError != nil {
+		return fmt.Errorf("%d webhook deliveries failed: %w", failedDeliveries, lastError)
+	}
+	return nil
+}
+
+// buildNotification converts a batch of papers into a notification payload
+func (e *Emitter) buildNotification(papers []processor.Paper) Notification {
+	events := make([]NewPaperEvent, 0, len(papers))
+	for _, paper := range papers {
+		events = append(events, NewPaperEvent{
+			PaperID:    paper.PaperID,
+			Title:      paper.Title,
+			Categories: paper.Categories,
+			TraceID:    paper.TraceID,
+		})
+	}
+
+	traceID := ""
+	if len(papers) > 0 {
+		traceID = papers[0].TraceID
+	}
+
+	return Notification{
+		EventType: EventTypeNewPapers,
+		TraceID:   traceID,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Count:     len(events),
+		Papers:    events,
+	}
+}
+
+// statusError is a webhook response outside 2xx
+type statusError struct {
+	statusCode int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("webhook returned status %d", e.statusCode)
+}
+
+// retryable reports whether a failed delivery is worth repeating: network errors, 429 and
+// 5xx are, other 4xx responses reject the request itself and would fail again
+func retryable(err error) bool {
+	var statusErr *statusError
+	if errors.As(err, &statusErr) {
+		return statusErr.statusCode == http.StatusTooManyRequests || statusErr.statusCode >= http.StatusInternalServerError
+	}
+	return true
+}
+
+// deliverWithRetry posts the body to a URL, retrying transient failures with exponential backoff
+func (e *Emitter) deliverWithRetry(ctx context.Context, url string, body []byte) error {
+	var lastError error
+	delay := e.retryDelay
+
+	for attempt := 0; attempt < e.maxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("webhook delivery canceled: %w", ctx.Err())
+			case <-time.After(delay):
+			}
+			delay *= 2
+		}
+
+		lastError = e.deliver(ctx, url, body)
+		if lastError == nil {
+			return nil
+		}
+		if !retryable(lastError) {
+			return lastError
+		}
+	}
+
+	return fmt.Errorf("failed after %d attempts: %w", e.maxRetries, lastError)
+}
+
+// deliver performs a single signed POST request
+func (e *Emitter) deliver(ctx context.Context, url string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if e.secret != "" {
+		req.Header.Set(SignatureHeader, "sha256="+Sign(e.secret, body))
+	}
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &statusError{statusCode: resp.StatusCode}
+	}
+	return nil
+}
+
+// Sign computes the hex-encoded HMAC-SHA256 signature of body using secret
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,102 @@
+// Package anomaly flags collection runs whose paper count breaks from the recent runs of the
+// same source and schedule, so a silently broken query or an upstream API change is caught
+// on the run it happens instead of by an empty search index days later.
+package anomaly
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Kinds of anomalous run
+const (
+	KindZero = "zero" // the run collected no papers
+	KindLow  = "low"  // far fewer papers than the baseline
+	KindHigh = "high" // far more papers than the baseline
+)
+
+// Config holds the detection thresholds
+type Config struct {
+	WindowRuns int     `yaml:"window_runs"` // recent runs kept in the history; the baseline is their median
+	MinRuns    int     `yaml:"min_runs"`    // runs needed before low and high counts are flagged
+	LowRatio   float64 `yaml:"low_ratio"`   // counts below baseline*LowRatio are low
+	HighRatio  float64 `yaml:"high_ratio"`  // counts above baseline*HighRatio are high
+}
+
+// Validate checks the window and ratios
+func (c Config) Validate() error {
+	if c.WindowRuns < 1 {
+		return fmt.Errorf("window_runs must be positive, got %d", c.WindowRuns)
+	}
+	if c.MinRuns < 1 || c.MinRuns > c.WindowRuns {
+		return fmt.Errorf("min_runs must be between 1 and window_runs (%d), got %d", c.WindowRuns, c.MinRuns)
+	}
+	if c.LowRatio <= 0 || c.LowRatio >= 1 {
+		return fmt.Errorf("low_ratio must be in (0, 1), got %v", c.LowRatio)
+	}
+	if c.HighRatio <= 1 {
+		return fmt.Errorf("high_ratio must be greater than 1, got %v", c.HighRatio)
+	}
+	return nil
+}
+
+// Verdict is the outcome of checking one run against its history
+type Verdict struct {
+	Kind     string  `json:"kind,omitempty"` // one of the Kind* values; empty when the count is normal
+	Count    int     `json:"count"`
+	Baseline float64 `json:"baseline"` // median count of the recent runs
+	Runs     int     `json:"runs"`     // runs in the baseline
+	Ratio    float64 `json:"ratio,omitempty"`
+}
+
+// Anomalous reports whether the run should alert
+func (v Verdict) Anomalous() bool {
+	return v.Kind != ""
+}
+
+// Check compares a run's count with the counts of the recent runs, oldest first. A zero count
+// is always flagged; low and high counts only once MinRuns runs are known.
+func Check(cfg Config, history []int, count int) Verdict {
+	verdict := Verdict{Count: count, Runs: len(history)}
+	if len(history) > 0 {
+		verdict.Baseline = median(history)
+	}
+	if verdict.Baseline > 0 {
+		verdict.Ratio = float64(count) / verdict.Baseline
+	}
+
+	switch {
+	case count == 0:
+		verdict.Kind = KindZero
+	case len(history) < cfg.MinRuns:
+	case verdict.Ratio < cfg.LowRatio:
+		verdict.Kind = KindLow
+	case verdict.Ratio > cfg.HighRatio:
+		verdict.Kind = KindHigh
+	}
+	return verdict
+}
+
+// Record appends a run's count to the history, keeping the last WindowRuns. Zero counts are
+// left out, so a query that broke keeps alerting instead of becoming the new baseline.
+func Record(cfg Config, history []int, count int) []int {
+	if count == 0 {
+		return history
+	}
+	history = append(history, count)
+	if len(history) > cfg.WindowRuns {
+		history = history[len(history)-cfg.WindowRuns:]
+	}
+	return history
+}
+
+// median returns the median of counts, which a single outlier run cannot drag
+func median(counts []int) float64 {
+	sorted := append([]int(nil), counts...)
+	sort.Ints(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 1 {
+		return float64(sorted[mid])
+	}
+	return float64(sorted[mid-1]+sorted[mid]) / 2
+}
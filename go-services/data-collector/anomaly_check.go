@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sns"
+
+	"data-collector/anomaly"
+	"data-collector/config"
+	"data-collector/s3"
+	"data-collector/types"
+	"shared/awsclient"
+	"shared/logger"
+)
+
+// alertTopicEnv names the SNS topic anomalous runs are published to; unset, they are only logged
+const alertTopicEnv = "COLLECTION_ALERT_TOPIC_ARN"
+
+// maxSubjectLength is the SNS limit on a message subject
+const maxSubjectLength = 100
+
+// checkCollectionAnomaly compares the run's paper count with the recent runs of its source
+// and schedule (or query), logs the count as a metric and alerts on a zero or anomalous
+// count. With record set the count joins the baseline; validate runs only check. Failures
+// to read or write the baseline or to publish the alert are logged and never fail the run.
+func checkCollectionAnomaly(ctx context.Context, contextLogger *logger.Logger, cfg *config.Config, request types.CollectRequest, result *types.CollectionResult, record bool) {
+	settings := cfg.Anomaly
+	if !settings.Enabled {
+		return
+	}
+	runKey := collectionRecordID(request)
+
+	store, err := s3.NewBaselineStore(cfg.AWS.S3.RawDataBucket, settings.BaselinePrefix)
+	if err != nil {
+		contextLogger.Warn("Failed to create collection baseline store, skipping anomaly check", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+	store.SetKMSKey(cfg.AWS.S3.KMSKeyARN)
+	history, err := store.LoadCounts(ctx, runKey)
+	if err != nil {
+		contextLogger.Warn("Failed to load collection baseline, skipping anomaly check", map[string]interface{}{
+			"run_key": runKey,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	verdict := anomaly.Check(settings.Config, history, result.Count)
+	result.Anomaly = &verdict
+	contextLogger.Info("Collection count", map[string]interface{}{
+		"metric_type": "collection",
+		"metric_name": "collection_papers",
+		"value":       result.Count,
+		"run_key":     runKey,
+		"baseline":    verdict.Baseline,
+		"anomaly":     verdict.Kind,
+	})
+	if verdict.Anomalous() {
+		contextLogger.Warn("Anomalous collection run", map[string]interface{}{
+			"metric_type":   "collection",
+			"metric_name":   "collection_anomaly",
+			"value":         1,
+			"run_key":       runKey,
+			"anomaly":       verdict.Kind,
+			"count":         verdict.Count,
+			"baseline":      verdict.Baseline,
+			"baseline_runs": verdict.Runs,
+		})
+		publishAnomalyAlert(ctx, contextLogger, runKey, result, verdict)
+	}
+
+	if !record {
+		return
+	}
+	if err := store.SaveCounts(ctx, runKey, anomaly.Record(settings.Config, history, result.Count)); err != nil {
+		contextLogger.Warn("Failed to save collection baseline", map[string]interface{}{
+			"run_key": runKey,
+			"error":   err.Error(),
+		})
+	}
+}
+
+// anomalyAlert is the SNS message of an anomalous run
+type anomalyAlert struct {
+	RunKey  string          `json:"run_key"`
+	Source  string          `json:"source"`
+	TraceID string          `json:"trace_id,omitempty"`
+	S3Key   string          `json:"s3_key,omitempty"`
+	Query   string          `json:"query,omitempty"`
+	Verdict anomaly.Verdict `json:"verdict"`
+}
+
+// publishAnomalyAlert sends the anomalous run to the alert topic, when one is configured
+func publishAnomalyAlert(ctx context.Context, contextLogger *logger.Logger, runKey string, result *types.CollectionResult, verdict anomaly.Verdict) {
+	topicARN := os.Getenv(alertTopicEnv)
+	if topicARN == "" {
+		return
+	}
+
+	alert := anomalyAlert{
+		RunKey:  runKey,
+		Source:  result.Source,
+		TraceID: logger.TraceIDFromContext(ctx),
+		S3Key:   result.S3Key,
+		Verdict: verdict,
+	}
+	if result.Metadata != nil {
+		alert.Query = result.Metadata.Query
+	}
+	message, err := json.Marshal(alert)
+	if err != nil {
+		contextLogger.Warn("Failed to marshal collection anomaly alert", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+	subject := alertSubject("Anomalous collection run (" + verdict.Kind + "): " + runKey)
+
+	sess, err := awsclient.NewSession()
+	if err == nil {
+		_, err = sns.New(sess).PublishWithContext(ctx, &sns.PublishInput{
+			TopicArn: aws.String(topicARN),
+			Subject:  aws.String(subject),
+			Message:  aws.String(string(message)),
+		})
+	}
+	if err != nil {
+		contextLogger.Warn("Failed to publish collection anomaly alert", map[string]interface{}{
+			"topic_arn": topicARN,
+			"error":     err.Error(),
+		})
+	}
+}
+
+// alertSubject fits a subject to SNS, which takes at most 100 printable ASCII characters
+func alertSubject(subject string) string {
+	printable := strings.Map(func(r rune) rune {
+		if r < ' ' || r > '~' {
+			return '?'
+		}
+		return r
+	}, subject)
+	if len(printable) > maxSubjectLength {
+		printable = printable[:maxSubjectLength]
+	}
+	return printable
+}
@@ -0,0 +1,40 @@
+package apiclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// RequestGate admits one API request, returning the function to call with its outcome; the
+// collector passes its per-source scheduler so every page counts against the limits
+type RequestGate func(ctx context.Context) (func(error), error)
+
+// Fetch performs one GET request admitted through gate when one is set, waiting on limiter
+// before each attempt and retrying per policy. The gate admits the request once and is told
+// its final outcome, so retries neither count against the daily quota nor trip the cooldown
+// before they are exhausted. latency is the time the last attempt took.
+func Fetch(ctx context.Context, gate RequestGate, limiter *RateLimiter, policy RetryPolicy, httpClient *http.Client, requestURL string, header http.Header) (body []byte, latency time.Duration, err error) {
+	done := func(error) {}
+	if gate != nil {
+		if done, err = gate(ctx); err != nil {
+			return nil, 0, err
+		}
+	}
+	err = policy.Do(ctx, func() error {
+		if err := limiter.Wait(ctx); err != nil {
+			return fmt.Errorf("rate limit wait failed: %w", err)
+		}
+		requestStart := time.Now()
+		var err error
+		body, err = Get(ctx, httpClient, requestURL, header)
+		latency = time.Since(requestStart)
+		return err
+	})
+	done(err)
+	if err != nil {
+		return nil, 0, err
+	}
+	return body, latency, nil
+}
@@ -0,0 +1,51 @@
+package apiclient
+
+import (
+	"context"
+	"time"
+)
+
+// RateLimiter spaces a client's requests evenly. It is not safe for concurrent use; each
+// client pages through its results one request at a time.
+type RateLimiter struct {
+	interval    time.Duration
+	lastRequest time.Time
+}
+
+// NewRateLimiter allows requestsPerSecond requests per second; values below 1 allow one
+func NewRateLimiter(requestsPerSecond int) *RateLimiter {
+	if requestsPerSecond <= 0 {
+		requestsPerSecond = 1
+	}
+	return &RateLimiter{interval: time.Second / time.Duration(requestsPerSecond)}
+}
+
+// Wait blocks until the next request may be sent, returning early if the context is canceled
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	now := time.Now()
+	if l.lastRequest.IsZero() {
+		l.lastRequest = now
+		return nil
+	}
+
+	if elapsed := now.Sub(l.lastRequest); elapsed < l.interval {
+		if err := Sleep(ctx, l.interval-elapsed); err != nil {
+			return err
+		}
+	}
+
+	l.lastRequest = time.Now()
+	return nil
+}
+
+// Sleep waits for d, returning early if the context is canceled
+func Sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,145 @@
+// Package apiclient holds what the data source API clients share: a per-client rate limiter,
+// retries with exponential backoff that honor Retry-After, GET requests that report
+// unexpected statuses as *StatusError, and the request gate that admits each page.
+package apiclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how a failed request is retried: network errors, 429 and 5xx
+// responses are retried with exponential backoff and jitter, other 4xx responses are not.
+// The zero policy makes a single attempt.
+type RetryPolicy struct {
+	Attempts  int           // per request, including the first; values below 1 make one attempt
+	BaseDelay time.Duration // before the first retry, doubling after each one
+	MaxDelay  time.Duration // cap of the backoff; 0 leaves it uncapped
+	Jitter    float64       // fraction of each backoff delay that is randomized, 0 to 1
+}
+
+// StatusError is a response with an unexpected status. RetryAfter is the wait the API asked
+// for in a Retry-After header, zero when it sent none.
+type StatusError struct {
+	StatusCode int
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("API returned status %d", e.StatusCode)
+}
+
+// throttled reports whether the API is shedding load rather than failing
+func (e *StatusError) throttled() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode == http.StatusServiceUnavailable
+}
+
+// retryable reports whether a failed attempt is worth repeating
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.throttled() || statusErr.StatusCode >= http.StatusInternalServerError
+	}
+	// Network errors and bodies cut off mid-read
+	return true
+}
+
+// Do runs attempt until it succeeds, fails permanently or the attempts run out
+func (p RetryPolicy) Do(ctx context.Context, attempt func() error) error {
+	attempts := p.Attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	for n := 1; ; n++ {
+		err := attempt()
+		if err == nil || n >= attempts || !retryable(ctx, err) {
+			if err != nil && n > 1 {
+				return fmt.Errorf("failed after %d attempts: %w", n, err)
+			}
+			return err
+		}
+		if err := Sleep(ctx, p.delay(n, err)); err != nil {
+			return fmt.Errorf("retry wait failed: %w", err)
+		}
+	}
+}
+
+// delay is the wait after the n-th failed attempt. A Retry-After header is honored as sent;
+// a throttling response without one waits the longest backoff, as retrying sooner would
+// only be throttled again.
+func (p RetryPolicy) delay(n int, err error) time.Duration {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.RetryAfter > 0 {
+		return statusErr.RetryAfter
+	}
+
+	delay := p.BaseDelay
+	for i := 1; i < n && (p.MaxDelay <= 0 || delay < p.MaxDelay); i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && (delay > p.MaxDelay || (statusErr != nil && statusErr.throttled())) {
+		delay = p.MaxDelay
+	}
+
+	if p.Jitter > 0 {
+		delay -= time.Duration(p.Jitter * rand.Float64() * float64(delay))
+	}
+	return delay
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(date); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
+
+// Get performs one GET request with the given headers and reads its body; unexpected
+// statuses are *StatusError
+func Get(ctx context.Context, httpClient *http.Client, requestURL string, header http.Header) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", requestURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{
+			StatusCode: resp.StatusCode,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		}
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	return body, nil
+}
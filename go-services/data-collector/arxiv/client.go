@@ -4,35 +4,44 @@ import (
 	"context"
 	"encoding/xml"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
 	"time"
 
+	"data-collector/apiclient"
 	"data-collector/types"
+	"shared/awsclient"
 )
 
 // Client represents an arXiv API client
 type Client struct {
-	httpClient  *http.Client
-	baseURL     string
-	rateLimit   time.Duration
-	lastRequest time.Time
+	httpClient *http.Client
+	baseURL    string
+	limiter    *apiclient.RateLimiter
+	retry      apiclient.RetryPolicy
 }
 
 // NewClient creates a new arXiv API client
 func NewClient(baseURL string, rateLimitPerSecond int) *Client {
 	return &Client{
-		httpClient: &http.Client{
+		httpClient: awsclient.HTTPClient(&http.Client{
 			Timeout: 30 * time.Second,
-		},
-		baseURL:   baseURL,
-		rateLimit: time.Second / time.Duration(rateLimitPerSecond),
+		}),
+		baseURL: baseURL,
+		limiter: apiclient.NewRateLimiter(rateLimitPerSecond),
 	}
 }
 
+// SetRetryPolicy sets how failed page requests are retried; by default they are not
+func (c *Client) SetRetryPolicy(policy apiclient.RetryPolicy) {
+	c.retry = policy
+}
+
+// maxPageSize is the most entries arXiv returns for one request
+const maxPageSize = 2000
+
 // SearchParams represents search parameters for arXiv API
 type SearchParams struct {
 	Query      string
@@ -40,41 +49,91 @@ type SearchParams struct {
 	StartIndex int
 	DateFrom   *time.Time // Optional: search from this date (inclusive)
 	DateTo     *time.Time // Optional: search to this date (inclusive)
+	// PageSize is the entries requested per page, at most 2000; 0 requests up to MaxResults
+	// in pages of 2000
+	PageSize  int
+	PageDelay time.Duration // pause between pages, on top of the rate limit
+	Gate      apiclient.RequestGate
 }
 
-// Search performs a search query against arXiv API
+// searchPage is one response of the query API
+type searchPage struct {
+	feed     types.ArxivFeed
+	papers   []types.Paper
+	queryURL string
+	latency  time.Duration
+}
+
+// Search pages through the query API with start/max_results until MaxResults papers are
+// collected or the results run out, and stitches the pages into one result. arXiv may return
+// fewer entries than a page asked for, so each page starts after the entries actually read.
 func (c *Client) Search(ctx context.Context, params SearchParams) (*types.CollectionResult, error) {
-	// Rate limiting
-	if err := c.waitForRateLimit(); err != nil {
-		return nil, fmt.Errorf("rate limit wait failed: %w", err)
+	pageSize := params.PageSize
+	if pageSize <= 0 || pageSize > maxPageSize {
+		pageSize = maxPageSize
 	}
 
-	// Build query URL
-	queryURL, err := c.buildQueryURL(params)
-	if err != nil {
-		return nil, fmt.Errorf("failed to build query URL: %w", err)
-	}
+	var (
+		papers  []types.Paper
+		pages   []searchPage
+		entries int
+		last    searchPage
+	)
+	start := params.StartIndex
+	for {
+		want := pageSize
+		if remaining := params.MaxResults - entries; params.MaxResults > 0 && remaining < want {
+			want = remaining
+		}
 
-	// Make HTTP request
-	req, err := http.NewRequestWithContext(ctx, "GET", queryURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
+		if len(pages) > 0 && params.PageDelay > 0 {
+			if err := apiclient.Sleep(ctx, params.PageDelay); err != nil {
+				return nil, fmt.Errorf("page delay failed: %w", err)
+			}
+		}
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("HTTP request failed: %w", err)
+		pageParams := params
+		pageParams.StartIndex = start
+		pageParams.MaxResults = want
+		page, err := c.fetchPage(ctx, pageParams)
+		if err != nil {
+			if len(pages) > 0 {
+				return nil, fmt.Errorf("page %d (start %d): %w", len(pages)+1, start, err)
+			}
+			return nil, err
+		}
+		pages = append(pages, *page)
+		last = *page
+		papers = append(papers, page.papers...)
+		entries += len(page.feed.Entries)
+		start += len(page.feed.Entries)
+
+		if len(page.feed.Entries) == 0 || start >= page.feed.TotalResults ||
+			(params.MaxResults > 0 && entries >= params.MaxResults) {
+			break
+		}
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API returned status %d", resp.StatusCode)
+	return &types.CollectionResult{
+		Papers:    papers,
+		Source:    "arxiv",
+		Count:     len(papers),
+		Timestamp: time.Now(),
+		Metadata:  c.buildMetadata(params, pageSize, pages, last, start, entries-len(papers)),
+	}, nil
+}
+
+// fetchPage requests and converts one page, admitted through the gate when one is set
+func (c *Client) fetchPage(ctx context.Context, params SearchParams) (*searchPage, error) {
+	// Build query URL
+	queryURL, err := c.buildQueryURL(params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query URL: %w", err)
 	}
 
-	// Read response body
-	body, err := io.ReadAll(resp.Body)
+	body, apiLatency, err := apiclient.Fetch(ctx, params.Gate, c.limiter, c.retry, c.httpClient, queryURL, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return nil, err
 	}
 
 	// Parse XML response
@@ -89,30 +148,36 @@ func (c *Client) Search(ctx context.Context, params SearchParams) (*types.Collec
 		return nil, fmt.Errorf("failed to convert entries to papers: %w", err)
 	}
 
-	return &types.CollectionResult{
-		Papers:    papers,
-		Source:    "arxiv",
-		Count:     len(papers),
-		Timestamp: time.Now(),
-	}, nil
+	return &searchPage{feed: feed, papers: papers, queryURL: queryURL, latency: apiLatency}, nil
 }
 
-// waitForRateLimit implements rate limiting
-func (c *Client) waitForRateLimit() error {
-	now := time.Now()
-	if c.lastRequest.IsZero() {
-		c.lastRequest = now
-		return nil
+// buildMetadata records the query and pagination details of a search; the request URL is the
+// first page's, and the totals and next index come from the last page
+func (c *Client) buildMetadata(params SearchParams, pageSize int, pages []searchPage, last searchPage, nextIndex, skipped int) *types.CollectionMetadata {
+	metadata := &types.CollectionMetadata{
+		Query:          params.Query,
+		DateFrom:       params.DateFrom,
+		DateTo:         params.DateTo,
+		StartIndex:     params.StartIndex,
+		MaxResults:     params.MaxResults,
+		TotalResults:   last.feed.TotalResults,
+		ItemsPerPage:   pageSize,
+		Pages:          len(pages),
+		SkippedEntries: skipped,
+	}
+	if len(pages) > 0 {
+		metadata.RequestURL = pages[0].queryURL
+	}
+	for _, page := range pages {
+		metadata.APILatencyMs += page.latency.Milliseconds()
 	}
 
-	elapsed := now.Sub(c.lastRequest)
-	if elapsed < c.rateLimit {
-		waitTime := c.rateLimit - elapsed
-		time.Sleep(waitTime)
+	if len(last.feed.Entries) > 0 && nextIndex < last.feed.TotalResults {
+		metadata.HasMore = true
+		metadata.NextIndex = nextIndex
 	}
 
-	c.lastRequest = time.Now()
-	return nil
+	return metadata
 }
 
 // buildQueryURL constructs the query URL for arXiv API
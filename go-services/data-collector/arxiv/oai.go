@@ -0,0 +1,271 @@
+package arxiv
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"data-collector/apiclient"
+	"data-collector/types"
+	"shared/awsclient"
+)
+
+// OAI-PMH metadata format with structured authors and categories
+const oaiMetadataPrefix = "arXiv"
+
+// Harvester is an OAI-PMH client for bulk harvesting arXiv. Unlike the search API it has no
+// result limit: ListRecords pages through a whole set with resumption tokens.
+type Harvester struct {
+	httpClient *http.Client
+	baseURL    string
+	limiter    *apiclient.RateLimiter
+	retry      apiclient.RetryPolicy
+}
+
+// NewHarvester creates an OAI-PMH client for the endpoint at baseURL
+func NewHarvester(baseURL string, rateLimitPerSecond int) *Harvester {
+	return &Harvester{
+		httpClient: awsclient.HTTPClient(&http.Client{
+			Timeout: 60 * time.Second,
+		}),
+		baseURL: baseURL,
+		limiter: apiclient.NewRateLimiter(rateLimitPerSecond),
+	}
+}
+
+// SetRetryPolicy sets how failed ListRecords requests are retried; by default they are not
+func (h *Harvester) SetRetryPolicy(policy apiclient.RetryPolicy) {
+	h.retry = policy
+}
+
+// HarvestParams selects the records of a harvest
+type HarvestParams struct {
+	Set        string     // OAI set, e.g. "cs" or "physics:hep-th"; empty harvests every set
+	From       *time.Time // Optional: records updated on or after this date
+	Until      *time.Time // Optional: records updated on or before this date
+	MaxResults int        // 0 harvests the whole list
+	// ResumptionToken continues an earlier harvest that stopped at MaxResults
+	ResumptionToken string
+	PageDelay       time.Duration // pause between pages, on top of the rate limit
+	Gate            apiclient.RequestGate
+}
+
+// oaiResponse is one ListRecords response
+type oaiResponse struct {
+	XMLName xml.Name `xml:"OAI-PMH"`
+	Error   *struct {
+		Code    string `xml:"code,attr"`
+		Message string `xml:",chardata"`
+	} `xml:"error"`
+	Records         []oaiRecord `xml:"ListRecords>record"`
+	ResumptionToken struct {
+		Token            string `xml:",chardata"`
+		CompleteListSize string `xml:"completeListSize,attr"`
+	} `xml:"ListRecords>resumptionToken"`
+}
+
+type oaiRecord struct {
+	Header struct {
+		Status string `xml:"status,attr"`
+	} `xml:"header"`
+	Metadata oaiArxiv `xml:"metadata>arXiv"`
+}
+
+// oaiArxiv is a record in the arXiv metadata format
+type oaiArxiv struct {
+	ID      string `xml:"id"`
+	Created string `xml:"created"`
+	Authors []struct {
+		Keyname   string `xml:"keyname"`
+		Forenames string `xml:"forenames"`
+	} `xml:"authors>author"`
+	Title      string `xml:"title"`
+	Categories string `xml:"categories"`
+	DOI        string `xml:"doi"`
+	Abstract   string `xml:"abstract"`
+}
+
+// Harvest lists the records of params.Set with ListRecords, following resumption tokens until
+// the list is exhausted or MaxResults papers are collected. Deleted records are skipped.
+// Records carry their arXiv ID without a version suffix, which the batch processor's
+// normalized_id deduplication matches against search results.
+func (h *Harvester) Harvest(ctx context.Context, params HarvestParams) (*types.CollectionResult, error) {
+	var (
+		papers     []types.Paper
+		token      = params.ResumptionToken
+		total      int
+		pages      int
+		skipped    int
+		apiLatency time.Duration
+		firstURL   string
+	)
+
+	for {
+		requestURL, err := h.buildListURL(params, token)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build ListRecords URL: %w", err)
+		}
+		if firstURL == "" {
+			firstURL = requestURL
+		}
+
+		if pages > 0 && params.PageDelay > 0 {
+			if err := apiclient.Sleep(ctx, params.PageDelay); err != nil {
+				return nil, fmt.Errorf("page delay failed: %w", err)
+			}
+		}
+
+		requestStart := time.Now()
+		response, err := h.fetchPage(ctx, params.Gate, requestURL)
+		if err != nil {
+			return nil, fmt.Errorf("page %d: %w", pages+1, err)
+		}
+		apiLatency += time.Since(requestStart)
+		pages++
+
+		if response.Error != nil {
+			// An empty selection is reported as an error by OAI-PMH, not as an empty list
+			if response.Error.Code == "noRecordsMatch" {
+				token = ""
+				break
+			}
+			return nil, fmt.Errorf("OAI-PMH error %s: %s", response.Error.Code, strings.TrimSpace(response.Error.Message))
+		}
+
+		if size, err := strconv.Atoi(response.ResumptionToken.CompleteListSize); err == nil {
+			total = size
+		}
+		for _, record := range response.Records {
+			if record.Header.Status == "deleted" {
+				continue
+			}
+			paper, err := convertOAIRecord(record.Metadata)
+			if err != nil {
+				skipped++
+				continue
+			}
+			papers = append(papers, paper)
+		}
+
+		token = strings.TrimSpace(response.ResumptionToken.Token)
+		if token == "" || (params.MaxResults > 0 && len(papers) >= params.MaxResults) {
+			break
+		}
+	}
+
+	if params.MaxResults > 0 && len(papers) > params.MaxResults {
+		papers = papers[:params.MaxResults]
+	}
+	if total == 0 {
+		total = len(papers) + skipped
+	}
+
+	return &types.CollectionResult{
+		Papers:    papers,
+		Source:    "arxiv",
+		Count:     len(papers),
+		Timestamp: time.Now(),
+		Metadata: &types.CollectionMetadata{
+			Query:           "oai_pmh:" + params.Set,
+			RequestURL:      firstURL,
+			DateFrom:        params.From,
+			DateTo:          params.Until,
+			MaxResults:      params.MaxResults,
+			TotalResults:    total,
+			HasMore:         token != "",
+			APILatencyMs:    apiLatency.Milliseconds(),
+			SkippedEntries:  skipped,
+			Pages:           pages,
+			ResumptionToken: token,
+		},
+	}, nil
+}
+
+// fetchPage requests one ListRecords page, admitted through gate when one is set. arXiv
+// answers 503 with Retry-After while it throttles harvesters; the retry policy waits as asked.
+func (h *Harvester) fetchPage(ctx context.Context, gate apiclient.RequestGate, requestURL string) (*oaiResponse, error) {
+	body, _, err := apiclient.Fetch(ctx, gate, h.limiter, h.retry, h.httpClient, requestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var response oaiResponse
+	if err := xml.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse OAI-PMH response: %w", err)
+	}
+	return &response, nil
+}
+
+// buildListURL constructs a ListRecords URL. A resumption token is exclusive: it replaces
+// every other argument of the request.
+func (h *Harvester) buildListURL(params HarvestParams, token string) (string, error) {
+	baseURL, err := url.Parse(h.baseURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid base URL: %w", err)
+	}
+
+	query := url.Values{}
+	query.Set("verb", "ListRecords")
+	if token != "" {
+		query.Set("resumptionToken", token)
+	} else {
+		query.Set("metadataPrefix", oaiMetadataPrefix)
+		if params.Set != "" {
+			query.Set("set", params.Set)
+		}
+		if params.From != nil {
+			query.Set("from", params.From.Format("2006-01-02"))
+		}
+		if params.Until != nil {
+			query.Set("until", params.Until.Format("2006-01-02"))
+		}
+	}
+
+	baseURL.RawQuery = query.Encode()
+	return baseURL.String(), nil
+}
+
+// convertOAIRecord converts an arXiv-format record to a Paper; the publication date is the
+// record's creation date, that of its first version
+func convertOAIRecord(record oaiArxiv) (types.Paper, error) {
+	id := strings.TrimSpace(record.ID)
+	title := normalizeWhitespace(record.Title)
+	if id == "" || title == "" {
+		return types.Paper{}, fmt.Errorf("record is missing its ID or title")
+	}
+
+	publishedDate, err := time.Parse("2006-01-02", strings.TrimSpace(record.Created))
+	if err != nil {
+		return types.Paper{}, fmt.Errorf("failed to parse created date: %w", err)
+	}
+
+	authors := make([]string, 0, len(record.Authors))
+	for _, author := range record.Authors {
+		name := strings.TrimSpace(strings.TrimSpace(author.Forenames) + " " + strings.TrimSpace(author.Keyname))
+		if name != "" {
+			authors = append(authors, name)
+		}
+	}
+
+	return types.Paper{
+		ID:            id,
+		Source:        "arxiv",
+		Title:         title,
+		Abstract:      normalizeWhitespace(record.Abstract),
+		Authors:       authors,
+		PublishedDate: publishedDate,
+		Categories:    strings.Fields(record.Categories),
+		URL:           "https://arxiv.org/abs/" + id,
+		DOI:           strings.TrimSpace(record.DOI),
+	}, nil
+}
+
+// normalizeWhitespace joins the lines of a wrapped OAI-PMH text field
+func normalizeWhitespace(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
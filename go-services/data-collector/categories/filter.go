@@ -0,0 +1,101 @@
+// Package categories applies the configured category allowlist and skip list.
+// Patterns are exact categories ("cs.AI") or archive wildcards ("q-bio.*"), compared
+// case-insensitively.
+package categories
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Filter decides which papers to keep by their categories. Denied categories are ignored,
+// and a paper is kept when one of its remaining categories is allowed (any, when the
+// allowlist is empty). A cross-listed paper therefore survives a denied secondary category.
+type Filter struct {
+	allow      []string
+	deny       []string
+	queryTerms []string // allow patterns as configured, since arXiv matches categories case-sensitively
+}
+
+// NewFilter creates a filter from allow and deny patterns; an empty filter keeps every paper
+func NewFilter(allow, deny []string) *Filter {
+	filter := &Filter{allow: normalize(allow), deny: normalize(deny)}
+	for _, pattern := range allow {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			filter.queryTerms = append(filter.queryTerms, "cat:"+pattern)
+		}
+	}
+	return filter
+}
+
+// Validate rejects wildcards anywhere but at the end of a pattern
+func Validate(patterns []string) error {
+	for _, pattern := range patterns {
+		if i := strings.Index(pattern, "*"); i >= 0 && i != len(pattern)-1 {
+			return fmt.Errorf("category pattern %q may only end with '*'", pattern)
+		}
+	}
+	return nil
+}
+
+// Active reports whether the filter restricts anything
+func (f *Filter) Active() bool {
+	return f != nil && (len(f.allow) > 0 || len(f.deny) > 0)
+}
+
+// Allows reports whether a paper with these categories is kept. Papers without
+// categories are kept unless an allowlist is configured.
+func (f *Filter) Allows(categories []string) bool {
+	if !f.Active() {
+		return true
+	}
+	if len(categories) == 0 {
+		return len(f.allow) == 0
+	}
+	for _, category := range categories {
+		category = strings.ToLower(strings.TrimSpace(category))
+		if matchesAny(f.deny, category) {
+			continue
+		}
+		if len(f.allow) == 0 || matchesAny(f.allow, category) {
+			return true
+		}
+	}
+	return false
+}
+
+// ApplyToQuery narrows an arXiv search query to the allowlist. The skip list is not added
+// to the query, as ANDNOT would also drop cross-listed papers that Allows keeps.
+func (f *Filter) ApplyToQuery(query string) string {
+	if f == nil || len(f.queryTerms) == 0 {
+		return query
+	}
+	clause := "(" + strings.Join(f.queryTerms, " OR ") + ")"
+	if strings.TrimSpace(query) == "" {
+		return clause
+	}
+	return fmt.Sprintf("(%s) AND %s", query, clause)
+}
+
+func matchesAny(patterns []string, category string) bool {
+	for _, pattern := range patterns {
+		if prefix, wildcard := strings.CutSuffix(pattern, "*"); wildcard {
+			if strings.HasPrefix(category, prefix) {
+				return true
+			}
+		} else if category == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+func normalize(patterns []string) []string {
+	result := make([]string, 0, len(patterns))
+	for _, pattern := range patterns {
+		if pattern = strings.ToLower(strings.TrimSpace(pattern)); pattern != "" {
+			result = append(result, pattern)
+		}
+	}
+	return result
+}
@@ -2,11 +2,16 @@ package config
 
 import (
 	"context"
+	"data-collector/anomaly"
+	"data-collector/categories"
+	"data-collector/cron"
 	"fmt"
 	"io"
+	"shared/awsclient"
+	"shared/pauseflags"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"gopkg.in/yaml.v3"
 )
@@ -18,18 +23,144 @@ type Config struct {
 	Processing    ProcessingConfig            `yaml:"processing"`
 	Vectorization VectorizationConfig         `yaml:"vectorization"`
 	Logging       LoggingConfig               `yaml:"logging"`
+	Pause         pauseflags.Config           `yaml:"pause"`
+	Scheduling    SchedulingConfig            `yaml:"scheduling"`
+	Anomaly       CollectionAnomalyConfig     `yaml:"collection_anomaly"`
+	Enrichment    EnrichmentConfig            `yaml:"enrichment"`
 }
 
 // DataSourceConfig represents configuration for a data source
 type DataSourceConfig struct {
-	APIEndpoint   string            `yaml:"api_endpoint"`
-	FieldsMapping map[string]string `yaml:"fields_mapping"`
-	RateLimit     int               `yaml:"rate_limit"`
-	MaxResults    int               `yaml:"max_results"`
-	SearchQuery   string            `yaml:"search_query"`
-	DateFrom      string            `yaml:"date_from,omitempty"` // Format: YYYY-MM-DD
-	DateTo        string            `yaml:"date_to,omitempty"`   // Format: YYYY-MM-DD
-	Enabled       bool              `yaml:"enabled"`
+	APIEndpoint   string             `yaml:"api_endpoint"`
+	APIKey        string             `yaml:"api_key,omitempty"` // sent by sources that support keys; SEMANTIC_SCHOLAR_API_KEY and OPENALEX_API_KEY override it
+	Mailto        string             `yaml:"mailto,omitempty"`  // contact address for polite-pool access (openalex); OPENALEX_MAILTO overrides it
+	Filter        string             `yaml:"filter,omitempty"`  // source filter expression (openalex), combined with date_from/date_to
+	FieldsMapping map[string]string  `yaml:"fields_mapping"`
+	RateLimit     int                `yaml:"rate_limit"`
+	MaxResults    int                `yaml:"max_results"`
+	PageSize      int                `yaml:"page_size,omitempty"`     // results per request when a run pages through a source; 0 uses the source's maximum
+	PageDelayMs   int                `yaml:"page_delay_ms,omitempty"` // pause between page requests, on top of rate_limit
+	Mode          string             `yaml:"mode,omitempty"`          // ModeSearch (default) or ModeOAIPMH, arxiv only
+	OAIEndpoint   string             `yaml:"oai_endpoint,omitempty"`  // OAI-PMH base URL of ModeOAIPMH
+	OAISet        string             `yaml:"oai_set,omitempty"`       // OAI set harvested by ModeOAIPMH, e.g. "cs"; empty harvests all sets
+	SearchQuery   string             `yaml:"search_query"`
+	DateFrom      string             `yaml:"date_from,omitempty"` // Format: YYYY-MM-DD
+	DateTo        string             `yaml:"date_to,omitempty"`   // Format: YYYY-MM-DD
+	Enabled       bool               `yaml:"enabled"`
+	Limits        SourceLimitsConfig `yaml:"limits"`
+}
+
+// Collection modes of a data source
+const (
+	ModeSearch = "search"  // query the search API, limited to max_results matches of search_query
+	ModeOAIPMH = "oai_pmh" // harvest an OAI set with ListRecords, for complete category harvests
+)
+
+// SourceLimitsConfig caps how hard the collector may use a source's API; zero disables each limit
+type SourceLimitsConfig struct {
+	MaxConcurrentRequests int `yaml:"max_concurrent_requests"`
+	DailyQuota            int `yaml:"daily_quota"`      // requests per UTC day
+	CooldownSeconds       int `yaml:"cooldown_seconds"` // pause after a failed request
+}
+
+// SchedulingConfig drives the collection dispatcher, which is invoked every TickMinutes and
+// starts a collection run for each schedule whose cron expression fired since the last tick
+type SchedulingConfig struct {
+	TickMinutes int              `yaml:"tick_minutes"` // interval of the rule invoking the dispatcher
+	Timezone    string           `yaml:"timezone"`     // IANA zone cron expressions are read in; UTC when empty
+	Schedules   []ScheduleConfig `yaml:"schedules"`
+}
+
+// ScheduleConfig is one scheduled collection; empty fields fall back to the data source's config
+type ScheduleConfig struct {
+	Name         string `yaml:"name"`
+	Source       string `yaml:"source"`
+	Cron         string `yaml:"cron"` // minute hour day-of-month month day-of-week
+	SearchQuery  string `yaml:"search_query,omitempty"`
+	MaxResults   int    `yaml:"max_results,omitempty"`
+	LookbackDays int    `yaml:"lookback_days,omitempty"` // collect papers published in the last N days; 0 uses the source's dates
+	Disabled     bool   `yaml:"disabled,omitempty"`
+}
+
+// Validate checks the schedules against the configured data sources
+func (s SchedulingConfig) Validate(sources map[string]DataSourceConfig) error {
+	if s.TickMinutes < 0 {
+		return fmt.Errorf("tick_minutes must not be negative, got %d", s.TickMinutes)
+	}
+	if _, err := time.LoadLocation(s.Timezone); err != nil {
+		return fmt.Errorf("invalid timezone %q: %w", s.Timezone, err)
+	}
+	names := make(map[string]bool)
+	for i, schedule := range s.Schedules {
+		if schedule.Name == "" {
+			return fmt.Errorf("schedules[%d]: name is required", i)
+		}
+		if names[schedule.Name] {
+			return fmt.Errorf("schedules[%d]: duplicate name %q", i, schedule.Name)
+		}
+		names[schedule.Name] = true
+		if _, ok := sources[schedule.Source]; !ok {
+			return fmt.Errorf("schedule %s: data source %q is not configured", schedule.Name, schedule.Source)
+		}
+		if _, err := cron.Parse(schedule.Cron); err != nil {
+			return fmt.Errorf("schedule %s: %w", schedule.Name, err)
+		}
+		if schedule.MaxResults < 0 || schedule.LookbackDays < 0 {
+			return fmt.Errorf("schedule %s: max_results and lookback_days must not be negative", schedule.Name)
+		}
+	}
+	return nil
+}
+
+// CollectionAnomalyConfig flags collection runs whose paper count is zero or far from the
+// median of the recent runs with the same source and schedule (or query)
+type CollectionAnomalyConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// BaselinePrefix is where each run key's recent counts are kept, in the raw-data bucket
+	// outside the raw-data prefix
+	BaselinePrefix string `yaml:"baseline_prefix"`
+	anomaly.Config `yaml:",inline"`
+}
+
+// Validate checks the baseline location and thresholds
+func (c CollectionAnomalyConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.BaselinePrefix == "" {
+		return fmt.Errorf("baseline_prefix is required")
+	}
+	return c.Config.Validate()
+}
+
+// EnrichmentConfig controls the CrossRef lookup of DOIs, journals and citation counts for
+// collected papers that are missing a DOI
+type EnrichmentConfig struct {
+	Enabled     bool   `yaml:"enabled"`
+	APIEndpoint string `yaml:"api_endpoint"`
+	Mailto      string `yaml:"mailto"`      // contact address for CrossRef's polite pool; CROSSREF_MAILTO overrides it
+	RateLimit   int    `yaml:"rate_limit"`  // requests per second
+	MaxLookups  int    `yaml:"max_lookups"` // lookups per run, 0 for no limit
+	// MaxConsecutiveFailures stops the lookups of a run after this many failed in a row;
+	// 0 never stops
+	MaxConsecutiveFailures int `yaml:"max_consecutive_failures"`
+}
+
+// Validate checks the endpoint and limits
+func (c EnrichmentConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.APIEndpoint == "" {
+		return fmt.Errorf("api_endpoint is required")
+	}
+	if c.RateLimit < 1 {
+		return fmt.Errorf("rate_limit must be positive, got %d", c.RateLimit)
+	}
+	if c.MaxLookups < 0 || c.MaxConsecutiveFailures < 0 {
+		return fmt.Errorf("max_lookups and max_consecutive_failures must not be negative")
+	}
+	return nil
 }
 
 // AWSConfig represents AWS service configuration
@@ -41,16 +172,49 @@ type AWSConfig struct {
 
 // S3Config represents S3 configuration
 type S3Config struct {
-	RawDataBucket string `yaml:"raw_data_bucket"`
-	ConfigBucket  string `yaml:"config_bucket"`
-	RawDataPrefix string `yaml:"raw_data_prefix"`
+	RawDataBucket   string `yaml:"raw_data_bucket"`
+	ConfigBucket    string `yaml:"config_bucket"`
+	RawDataPrefix   string `yaml:"raw_data_prefix"`
+	PresignedURLTTL int    `yaml:"presigned_url_ttl"` // seconds, 0 disables presigned URLs
+	// RunHistoryPrefix is where run manifests are written, outside the raw-data prefix so
+	// they do not trigger processing; empty disables run manifests
+	RunHistoryPrefix string `yaml:"run_history_prefix"`
+	// QuotaStatePrefix is where per-source daily request counts are kept, so quotas hold
+	// across invocations; empty keeps counts in memory for the life of the process
+	QuotaStatePrefix string `yaml:"quota_state_prefix"`
+	// KeyLayout is the raw-data key layout: "legacy" (default, prefix/YYYY-MM-DD/...) or
+	// "hive" (prefix/source=arxiv/dt=YYYY-MM-DD/...)
+	KeyLayout string `yaml:"key_layout"`
+	// StreamingThreshold is the paper count from which results are streamed to S3 as JSON
+	// lines in a multipart upload instead of being marshaled in memory; 0 never streams
+	StreamingThreshold int `yaml:"streaming_threshold"`
+	// MaxPapersPerObject splits larger results into part objects plus a parts manifest, so
+	// object sizes stay predictable and parts are processed in parallel; 0 never splits
+	MaxPapersPerObject int `yaml:"max_papers_per_object"`
+	// KMSKeyARN encrypts every raw-data object the collector writes with SSE-KMS under this
+	// key; empty leaves encryption to the bucket default
+	KMSKeyARN string `yaml:"kms_key_arn"`
+	// StorageClass of raw-data payloads, e.g. STANDARD_IA; empty is STANDARD
+	StorageClass string `yaml:"storage_class"`
+	// ObjectTagging tags payloads with their source, collection date and trace ID
+	ObjectTagging bool `yaml:"object_tagging"`
 }
 
 // DynamoDBConfig represents DynamoDB configuration
 type DynamoDBConfig struct {
-	PapersTable  string `yaml:"papers_table"`
-	VectorsTable string `yaml:"vectors_table"`
-	Region       string `yaml:"region"`
+	PapersTable  string          `yaml:"papers_table"`
+	VectorsTable string          `yaml:"vectors_table"`
+	Region       string          `yaml:"region"`
+	TraceIDIndex string          `yaml:"trace_id_index"`
+	VectorKeys   VectorKeyConfig `yaml:"vector_keys"`
+	// Client tunes SDK retries and call timeouts for throttling-prone tables
+	Client awsclient.ClientConfig `yaml:"client"`
+}
+
+// VectorKeyConfig names the key attributes of the vectors table
+type VectorKeyConfig struct {
+	PartitionKey string `yaml:"partition_key"`
+	SortKey      string `yaml:"sort_key"`
 }
 
 // LambdaConfig represents Lambda configuration
@@ -61,10 +225,32 @@ type LambdaConfig struct {
 
 // ProcessingConfig represents processing configuration
 type ProcessingConfig struct {
-	BatchSize     int    `yaml:"batch_size"`
-	Compression   string `yaml:"compression"`
-	RetryAttempts int    `yaml:"retry_attempts"`
-	RetryDelay    int    `yaml:"retry_delay"`
+	BatchSize       int                  `yaml:"batch_size"`
+	Compression     string               `yaml:"compression"`
+	RetryAttempts   int                  `yaml:"retry_attempts"`   // per API request, including the first
+	RetryDelay      int                  `yaml:"retry_delay"`      // seconds before the first retry, doubling after each
+	RetryMaxDelay   int                  `yaml:"retry_max_delay"`  // seconds the backoff is capped at; 0 leaves it uncapped
+	RetryJitter     float64              `yaml:"retry_jitter"`     // fraction of each backoff randomized, 0 to 1
+	DedupStrategies []string             `yaml:"dedup_strategies"` // exact_id, normalized_id, doi, fuzzy_title, title_authors
+	MergePolicy     MergePolicyConfig    `yaml:"merge_policy"`
+	CategoryFilter  CategoryFilterConfig `yaml:"category_filter"`
+}
+
+// CategoryFilterConfig lists category patterns to keep and to skip, applied at collection
+// (query construction) and at ingestion. Patterns are exact ("cs.AI") or archive wildcards
+// ("q-bio.*"); an empty allowlist allows every category not skipped.
+type CategoryFilterConfig struct {
+	Allow []string `yaml:"allow"`
+	Deny  []string `yaml:"deny"`
+}
+
+// MergePolicyConfig controls how the batch processor combines cross-source duplicates
+type MergePolicyConfig struct {
+	Enabled         bool     `yaml:"enabled"`
+	SourcePriority  []string `yaml:"source_priority"`
+	Abstract        string   `yaml:"abstract"`
+	UnionCategories bool     `yaml:"union_categories"`
+	UnionAuthors    bool     `yaml:"union_authors"`
 }
 
 // VectorizationConfig represents vectorization configuration
@@ -74,6 +260,14 @@ type VectorizationConfig struct {
 	BatchSize     int      `yaml:"batch_size"`
 	TextFields    []string `yaml:"text_fields"`
 	MaxTextLength int      `yaml:"max_text_length"`
+	// WeightedEmbedding is read by the vector coordinator
+	WeightedEmbedding WeightedEmbeddingConfig `yaml:"weighted_embedding"`
+}
+
+// WeightedEmbeddingConfig controls the additional per-field weighted vector
+type WeightedEmbeddingConfig struct {
+	Enabled bool               `yaml:"enabled"`
+	Weights map[string]float64 `yaml:"weights"`
 }
 
 // LoggingConfig represents logging configuration
@@ -90,7 +284,7 @@ type Manager struct {
 
 // NewManager creates a new configuration manager
 func NewManager() (*Manager, error) {
-	sess, err := session.NewSession(&aws.Config{
+	sess, err := awsclient.NewSession(&aws.Config{
 		Region: aws.String("us-east-1"), // Default region
 	})
 	if err != nil {
@@ -142,9 +336,57 @@ func (m *Manager) parseConfig(data []byte) (*Config, error) {
 		return nil, fmt.Errorf("failed to parse YAML config: %w", err)
 	}
 
+	for name, source := range config.DataSources {
+		if source.PageSize < 0 || source.PageDelayMs < 0 {
+			return nil, fmt.Errorf("invalid data_sources.%s: page_size and page_delay_ms must not be negative", name)
+		}
+		switch source.Mode {
+		case "", ModeSearch:
+		case ModeOAIPMH:
+			if name != "arxiv" {
+				return nil, fmt.Errorf("invalid data_sources.%s.mode: %q is only supported for arxiv", name, source.Mode)
+			}
+			if source.OAIEndpoint == "" {
+				return nil, fmt.Errorf("invalid data_sources.%s: oai_endpoint is required in %q mode", name, ModeOAIPMH)
+			}
+		default:
+			return nil, fmt.Errorf("invalid data_sources.%s.mode: expected %q or %q, got %q", name, ModeSearch, ModeOAIPMH, source.Mode)
+		}
+		limits := source.Limits
+		if limits.MaxConcurrentRequests < 0 || limits.DailyQuota < 0 || limits.CooldownSeconds < 0 {
+			return nil, fmt.Errorf("invalid data_sources.%s.limits: values must not be negative", name)
+		}
+	}
+
+	processing := config.Processing
+	if processing.RetryAttempts < 0 || processing.RetryDelay < 0 || processing.RetryMaxDelay < 0 {
+		return nil, fmt.Errorf("invalid processing: retry_attempts, retry_delay and retry_max_delay must not be negative")
+	}
+	if processing.RetryJitter < 0 || processing.RetryJitter > 1 {
+		return nil, fmt.Errorf("invalid processing.retry_jitter: must be between 0 and 1, got %v", processing.RetryJitter)
+	}
+
+	for _, patterns := range [][]string{config.Processing.CategoryFilter.Allow, config.Processing.CategoryFilter.Deny} {
+		if err := categories.Validate(patterns); err != nil {
+			return nil, fmt.Errorf("invalid processing.category_filter: %w", err)
+		}
+	}
+
+	if err := config.Scheduling.Validate(config.DataSources); err != nil {
+		return nil, fmt.Errorf("invalid scheduling: %w", err)
+	}
+
+	if err := config.Anomaly.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid collection_anomaly: %w", err)
+	}
+
+	if err := config.Enrichment.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid enrichment: %w", err)
+	}
+
 	// Set default values for enabled flag if not specified
 	for name, source := range config.DataSources {
-		if name != "semantic_scholar" { // semantic_scholar is disabled by default
+		if name != "semantic_scholar" && name != "openalex" { // semantic_scholar and openalex are disabled by default
 			source.Enabled = true
 			config.DataSources[name] = source
 		}
@@ -183,20 +425,37 @@ func GetDefaultConfig() *Config {
 				},
 				RateLimit:   3,
 				MaxResults:  1000,
+				PageSize:    500,
+				PageDelayMs: 3000,
 				SearchQuery: "cat:cs.AI OR cat:cs.LG OR cat:cs.CL",
 				Enabled:     true,
+				Limits: SourceLimitsConfig{
+					MaxConcurrentRequests: 1,
+					DailyQuota:            500,
+					CooldownSeconds:       60,
+				},
 			},
 		},
 		AWS: AWSConfig{
 			S3: S3Config{
-				RawDataBucket: "pipeline-raw-data",
-				ConfigBucket:  "pipeline-config",
-				RawDataPrefix: "raw-data",
+				RawDataBucket:    "pipeline-raw-data",
+				ConfigBucket:     "pipeline-config",
+				RawDataPrefix:    "raw-data",
+				PresignedURLTTL:  3600,
+				RunHistoryPrefix: "run-history",
+				QuotaStatePrefix: "collector-quota",
+				// Large harvests otherwise hold the whole JSON payload and its gzip in memory
+				StreamingThreshold: 10000,
 			},
 			DynamoDB: DynamoDBConfig{
 				PapersTable:  "Papers",
 				VectorsTable: "Vectors",
 				Region:       "us-east-1",
+				TraceIDIndex: "trace-id-index",
+				VectorKeys: VectorKeyConfig{
+					PartitionKey: "paper_id",
+					SortKey:      "vector_type",
+				},
 			},
 			Lambda: LambdaConfig{
 				Timeout: 900,
@@ -204,10 +463,20 @@ func GetDefaultConfig() *Config {
 			},
 		},
 		Processing: ProcessingConfig{
-			BatchSize:     25,
-			Compression:   "gzip",
-			RetryAttempts: 3,
-			RetryDelay:    1,
+			BatchSize:       25,
+			Compression:     "gzip",
+			RetryAttempts:   3,
+			RetryDelay:      1,
+			RetryMaxDelay:   30,
+			RetryJitter:     0.2,
+			DedupStrategies: []string{"exact_id"},
+			MergePolicy: MergePolicyConfig{
+				Enabled:         false,
+				SourcePriority:  []string{"crossref", "pubmed", "arxiv"},
+				Abstract:        "longest",
+				UnionCategories: true,
+				UnionAuthors:    true,
+			},
 		},
 		Vectorization: VectorizationConfig{
 			ModelName:     "sentence-transformers/all-MiniLM-L6-v2",
@@ -215,11 +484,40 @@ func GetDefaultConfig() *Config {
 			BatchSize:     10,
 			TextFields:    []string{"title", "abstract"},
 			MaxTextLength: 1024,
+			WeightedEmbedding: WeightedEmbeddingConfig{
+				Enabled: false,
+				Weights: map[string]float64{"title": 0.3, "abstract": 0.7},
+			},
 		},
 		Logging: LoggingConfig{
 			Level:          "INFO",
 			Structured:     true,
 			IncludeTraceID: true,
 		},
+		Pause: pauseflags.Config{
+			ParameterPrefix: "/paper-pipeline/pause",
+			RefreshSeconds:  30,
+		},
+		Scheduling: SchedulingConfig{
+			TickMinutes: 5,
+			Timezone:    "UTC",
+		},
+		Anomaly: CollectionAnomalyConfig{
+			Enabled:        true,
+			BaselinePrefix: "collection-baseline",
+			Config: anomaly.Config{
+				WindowRuns: 14,
+				MinRuns:    5,
+				LowRatio:   0.3,
+				HighRatio:  3,
+			},
+		},
+		Enrichment: EnrichmentConfig{
+			Enabled:                false,
+			APIEndpoint:            "https://api.crossref.org/works",
+			RateLimit:              5,
+			MaxLookups:             500,
+			MaxConsecutiveFailures: 10,
+		},
 	}
 }
@@ -0,0 +1,195 @@
+// Package cron parses five-field cron expressions (minute hour day-of-month month
+// day-of-week) and finds the times they fire, for the collection dispatcher.
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// macros are the supported shorthand expressions
+var macros = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+}
+
+var (
+	monthNames = map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}
+	weekdayNames = map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}
+)
+
+// maxSearch bounds how far ahead Next looks, so an expression that never fires (e.g. 30 Feb)
+// does not loop forever
+const maxSearch = 5 * 366 * 24 * time.Hour
+
+// Schedule is a parsed cron expression
+type Schedule struct {
+	minutes  [60]bool
+	hours    [24]bool
+	days     [32]bool // 1-31
+	months   [13]bool // 1-12
+	weekdays [7]bool  // 0 = Sunday
+	// Like classic cron, when both day fields are restricted a day matches either of them
+	daysRestricted     bool
+	weekdaysRestricted bool
+}
+
+// Parse reads an expression such as "30 6 * * mon-fri" or "*/15 * * * *". Fields accept
+// *, values, ranges (a-b), steps (*/n, a-b/n) and comma lists; months and weekdays also
+// accept three-letter names, and 7 means Sunday. @hourly, @daily, @weekly, @monthly and
+// @yearly are accepted as shorthands.
+func Parse(expression string) (*Schedule, error) {
+	expression = strings.TrimSpace(expression)
+	if macro, ok := macros[strings.ToLower(expression)]; ok {
+		expression = macro
+	}
+	fields := strings.Fields(expression)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q must have 5 fields (minute hour day month weekday), got %d", expression, len(fields))
+	}
+
+	schedule := &Schedule{
+		daysRestricted:     fields[2] != "*" && fields[2] != "?",
+		weekdaysRestricted: fields[4] != "*" && fields[4] != "?",
+	}
+	if err := parseField(fields[0], 0, 59, nil, schedule.minutes[:]); err != nil {
+		return nil, fmt.Errorf("cron minute field: %w", err)
+	}
+	if err := parseField(fields[1], 0, 23, nil, schedule.hours[:]); err != nil {
+		return nil, fmt.Errorf("cron hour field: %w", err)
+	}
+	if err := parseField(fields[2], 1, 31, nil, schedule.days[:]); err != nil {
+		return nil, fmt.Errorf("cron day-of-month field: %w", err)
+	}
+	if err := parseField(fields[3], 1, 12, monthNames, schedule.months[:]); err != nil {
+		return nil, fmt.Errorf("cron month field: %w", err)
+	}
+	var weekdays [8]bool
+	if err := parseField(fields[4], 0, 7, weekdayNames, weekdays[:]); err != nil {
+		return nil, fmt.Errorf("cron weekday field: %w", err)
+	}
+	copy(schedule.weekdays[:], weekdays[:7])
+	schedule.weekdays[0] = schedule.weekdays[0] || weekdays[7]
+	return schedule, nil
+}
+
+// parseField marks the values a field selects in set, which is indexed by value
+func parseField(field string, min, max int, names map[string]int, set []bool) error {
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			parsed, err := strconv.Atoi(stepPart)
+			if err != nil || parsed <= 0 {
+				return fmt.Errorf("invalid step %q", stepPart)
+			}
+			step = parsed
+		}
+
+		low, high := min, max
+		switch {
+		case rangePart == "*" || rangePart == "?":
+		case strings.Contains(rangePart, "-"):
+			from, to, _ := strings.Cut(rangePart, "-")
+			var err error
+			if low, err = fieldValue(from, min, max, names); err != nil {
+				return err
+			}
+			if high, err = fieldValue(to, min, max, names); err != nil {
+				return err
+			}
+			if low > high {
+				return fmt.Errorf("invalid range %q", rangePart)
+			}
+		default:
+			value, err := fieldValue(rangePart, min, max, names)
+			if err != nil {
+				return err
+			}
+			low, high = value, value
+			if hasStep {
+				// "5/15" means every 15 starting at 5
+				high = max
+			}
+		}
+
+		for value := low; value <= high; value += step {
+			set[value] = true
+		}
+	}
+	return nil
+}
+
+// fieldValue reads one number or name, checking it is within [min, max]
+func fieldValue(token string, min, max int, names map[string]int) (int, error) {
+	if value, ok := names[strings.ToLower(token)]; ok {
+		return value, nil
+	}
+	value, err := strconv.Atoi(token)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", token)
+	}
+	if value < min || value > max {
+		return 0, fmt.Errorf("value %d out of range %d-%d", value, min, max)
+	}
+	return value, nil
+}
+
+// Next returns the first time after t, to the minute and in t's location, that the schedule
+// fires; the zero time when it never fires within five years
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(maxSearch)
+
+	for t.Before(limit) {
+		if !s.months[t.Month()] {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.hours[t.Hour()] {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.minutes[t.Minute()] {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// Between returns the times in (from, to] the schedule fires
+func (s *Schedule) Between(from, to time.Time) []time.Time {
+	var times []time.Time
+	for next := s.Next(from); !next.IsZero() && !next.After(to); next = s.Next(next) {
+		times = append(times, next)
+	}
+	return times
+}
+
+// dayMatches applies the day-of-month and weekday fields
+func (s *Schedule) dayMatches(t time.Time) bool {
+	dayOfMonth := s.days[t.Day()]
+	weekday := s.weekdays[t.Weekday()]
+	if s.daysRestricted && s.weekdaysRestricted {
+		return dayOfMonth || weekday
+	}
+	return dayOfMonth && weekday
+}
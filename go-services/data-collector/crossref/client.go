@@ -0,0 +1,154 @@
+package crossref
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+
+	"data-collector/apiclient"
+	"shared/awsclient"
+)
+
+// DefaultEndpoint is the CrossRef REST API works endpoint
+const DefaultEndpoint = "https://api.crossref.org/works"
+
+// MailtoEnv overrides the mailto of the enrichment config, which puts requests in CrossRef's
+// polite pool
+const MailtoEnv = "CROSSREF_MAILTO"
+
+// selectFields are the work fields requested from the works endpoint
+const selectFields = "DOI,title,container-title,is-referenced-by-count"
+
+// candidateRows is how many works a lookup compares against the paper's title
+const candidateRows = 3
+
+// Client represents a CrossRef REST API client
+type Client struct {
+	httpClient *http.Client
+	baseURL    string
+	mailto     string
+	limiter    *apiclient.RateLimiter
+	retry      apiclient.RetryPolicy
+}
+
+// NewClient creates a new CrossRef client for the works endpoint at baseURL. A mailto
+// address identifies the caller, as CrossRef asks of API users.
+func NewClient(baseURL, mailto string, rateLimitPerSecond int) *Client {
+	return &Client{
+		httpClient: awsclient.HTTPClient(&http.Client{
+			Timeout: 30 * time.Second,
+		}),
+		baseURL: baseURL,
+		mailto:  mailto,
+		limiter: apiclient.NewRateLimiter(rateLimitPerSecond),
+	}
+}
+
+// SetRetryPolicy sets how failed lookups are retried; by default they are not
+func (c *Client) SetRetryPolicy(policy apiclient.RetryPolicy) {
+	c.retry = policy
+}
+
+// Work is the bibliographic record of a paper registered with CrossRef
+type Work struct {
+	DOI           string
+	Title         string
+	Journal       string
+	CitationCount int
+}
+
+// worksResponse is the works endpoint response
+type worksResponse struct {
+	Message struct {
+		Items []work `json:"items"`
+	} `json:"message"`
+}
+
+type work struct {
+	DOI            string   `json:"DOI"`
+	Title          []string `json:"title"`
+	ContainerTitle []string `json:"container-title"`
+	ReferencedBy   int      `json:"is-referenced-by-count"`
+}
+
+// Lookup searches the works matching a paper's title and first author and returns the one
+// whose title matches the paper's, or nil when none does. Only exact matches of the
+// normalized title are accepted, so a paper is never given another work's DOI.
+func (c *Client) Lookup(ctx context.Context, title string, authors []string) (*Work, error) {
+	queryURL, err := c.buildQueryURL(title, authors)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query URL: %w", err)
+	}
+	var body []byte
+	err = c.retry.Do(ctx, func() error {
+		if err := c.limiter.Wait(ctx); err != nil {
+			return fmt.Errorf("rate limit wait failed: %w", err)
+		}
+		var err error
+		body, err = apiclient.Get(ctx, c.httpClient, queryURL, nil)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	var response worksResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON response: %w", err)
+	}
+
+	want := normalizeTitle(title)
+	for _, item := range response.Message.Items {
+		if item.DOI == "" || len(item.Title) == 0 || normalizeTitle(item.Title[0]) != want {
+			continue
+		}
+		matched := &Work{
+			DOI:           strings.ToLower(item.DOI),
+			Title:         item.Title[0],
+			CitationCount: item.ReferencedBy,
+		}
+		if len(item.ContainerTitle) > 0 {
+			matched.Journal = item.ContainerTitle[0]
+		}
+		return matched, nil
+	}
+	return nil, nil
+}
+
+// buildQueryURL constructs the bibliographic query of a paper
+func (c *Client) buildQueryURL(title string, authors []string) (string, error) {
+	baseURL, err := url.Parse(c.baseURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid base URL: %w", err)
+	}
+
+	query := baseURL.Query()
+	query.Set("query.bibliographic", title)
+	if len(authors) > 0 {
+		query.Set("query.author", authors[0])
+	}
+	query.Set("rows", fmt.Sprintf("%d", candidateRows))
+	query.Set("select", selectFields)
+	if c.mailto != "" {
+		query.Set("mailto", c.mailto)
+	}
+
+	baseURL.RawQuery = query.Encode()
+	return baseURL.String(), nil
+}
+
+// normalizeTitle lowercases a title and keeps only its letters and digits, so titles differing
+// in punctuation, markup spacing or case compare equal
+func normalizeTitle(title string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(title) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
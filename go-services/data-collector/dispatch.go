@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"data-collector/dispatch"
+	"shared/logger"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// handleDispatch is the scheduled dispatcher: one EventBridge rule invokes it every tick,
+// and it starts the collection runs the scheduling config says are due
+func handleDispatch(ctx context.Context, event events.CloudWatchEvent) (*dispatch.Result, error) {
+	contextLogger := appLogger.WithContext(ctx)
+
+	tick := event.Time
+	if tick.IsZero() {
+		tick = time.Now()
+	}
+
+	cfg, err := loadConfiguration(ctx)
+	if err != nil {
+		return nil, lambdaError(logger.WrapError(err, logger.ErrorTypeConfig, "failed to load configuration"))
+	}
+	starter, err := newRunStarter()
+	if err != nil {
+		return nil, lambdaError(err)
+	}
+	dispatcher, err := dispatch.NewDispatcher(cfg.Scheduling, starter)
+	if err != nil {
+		return nil, lambdaError(logger.WrapError(err, logger.ErrorTypeConfig, "failed to create dispatcher"))
+	}
+
+	contextLogger.Info("Dispatching scheduled collections", map[string]interface{}{
+		"tick":      tick.Format(time.RFC3339),
+		"schedules": len(cfg.Scheduling.Schedules),
+	})
+	result, err := dispatcher.Dispatch(ctx, tick)
+	if err != nil {
+		return result, lambdaError(logger.WrapError(err, logger.ErrorTypeAPI, "scheduled collection dispatch failed"))
+	}
+	return result, nil
+}
+
+// newRunStarter starts runs through the pipeline state machine when STATE_MACHINE_ARN is
+// set, so scheduled runs get the same processing and vectorization as any other, and
+// otherwise invokes the collector function named by COLLECTOR_FUNCTION_NAME directly
+func newRunStarter() (dispatch.Starter, error) {
+	if arn := os.Getenv("STATE_MACHINE_ARN"); arn != "" {
+		return dispatch.NewStateMachineStarter(arn), nil
+	}
+	if functionName := os.Getenv("COLLECTOR_FUNCTION_NAME"); functionName != "" {
+		return dispatch.NewFunctionStarter(functionName), nil
+	}
+	return nil, logger.NewAppError(logger.ErrorTypeConfig, "STATE_MACHINE_ARN or COLLECTOR_FUNCTION_NAME must be set for the dispatcher", nil)
+}
@@ -0,0 +1,266 @@
+// Package dispatch starts scheduled collection runs. A single rule invokes the dispatcher
+// every tick; each schedule whose cron expression fired within the tick gets a run started
+// with its collection payload, replacing one hand-maintained EventBridge rule per query.
+package dispatch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"data-collector/config"
+	"data-collector/cron"
+	"data-collector/types"
+	"shared/awsclient"
+	"shared/logger"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/aws/aws-sdk-go/service/lambda/lambdaiface"
+	"github.com/aws/aws-sdk-go/service/sfn"
+	"github.com/aws/aws-sdk-go/service/sfn/sfniface"
+)
+
+// DefaultTickMinutes is the dispatcher interval assumed when the config names none
+const DefaultTickMinutes = 5
+
+// maxRunNameLength is the Step Functions limit on execution names
+const maxRunNameLength = 80
+
+// Run statuses
+const (
+	StatusStarted        = "started"
+	StatusAlreadyStarted = "already_started" // a redelivered tick; the run was started before
+	StatusFailed         = "failed"
+)
+
+// ErrAlreadyStarted is returned by a Starter when a run with the same name exists
+var ErrAlreadyStarted = errors.New("run already started")
+
+// Starter starts one collection run with the given payload. Name identifies the run, so
+// starters that support it can reject a second start of the same scheduled run.
+type Starter interface {
+	Start(ctx context.Context, name string, payload []byte) (string, error)
+}
+
+// Run is one scheduled run the dispatcher found due
+type Run struct {
+	Schedule    string               `json:"schedule"`
+	ScheduledAt time.Time            `json:"scheduled_at"`
+	Name        string               `json:"name"`
+	Status      string               `json:"status"`
+	RunID       string               `json:"run_id,omitempty"` // execution ARN when started through the state machine
+	Request     types.CollectRequest `json:"request"`
+	Error       string               `json:"error,omitempty"`
+}
+
+// Result summarizes one tick
+type Result struct {
+	WindowStart time.Time `json:"window_start"`
+	WindowEnd   time.Time `json:"window_end"`
+	Runs        []Run     `json:"runs"`
+	Started     int       `json:"started"`
+	Failed      int       `json:"failed"`
+}
+
+// Dispatcher starts the runs of the configured schedules
+type Dispatcher struct {
+	config   config.SchedulingConfig
+	starter  Starter
+	location *time.Location
+	logger   *logger.Logger
+}
+
+// NewDispatcher creates a dispatcher for the scheduling config
+func NewDispatcher(cfg config.SchedulingConfig, starter Starter) (*Dispatcher, error) {
+	location, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid scheduling timezone %q: %w", cfg.Timezone, err)
+	}
+	if cfg.TickMinutes == 0 {
+		cfg.TickMinutes = DefaultTickMinutes
+	}
+	return &Dispatcher{
+		config:   cfg,
+		starter:  starter,
+		location: location,
+		logger:   logger.New("collection-dispatcher"),
+	}, nil
+}
+
+// Dispatch starts every run due in the tick ending at tick, i.e. in (tick - interval, tick].
+// The tick is truncated to the minute, so consecutive invocations cover contiguous windows
+// whatever second the rule fires at. A failed start is reported in the result and logged
+// without stopping the other schedules; the error is non-nil when any start failed.
+func (d *Dispatcher) Dispatch(ctx context.Context, tick time.Time) (*Result, error) {
+	end := tick.In(d.location).Truncate(time.Minute)
+	result := &Result{
+		WindowStart: end.Add(-time.Duration(d.config.TickMinutes) * time.Minute),
+		WindowEnd:   end,
+		Runs:        []Run{},
+	}
+
+	for _, schedule := range d.config.Schedules {
+		if schedule.Disabled {
+			continue
+		}
+		expression, err := cron.Parse(schedule.Cron)
+		if err != nil {
+			return result, fmt.Errorf("schedule %s: %w", schedule.Name, err)
+		}
+		for _, scheduledAt := range expression.Between(result.WindowStart, result.WindowEnd) {
+			run := d.start(ctx, schedule, scheduledAt)
+			switch run.Status {
+			case StatusStarted:
+				result.Started++
+			case StatusFailed:
+				result.Failed++
+			}
+			result.Runs = append(result.Runs, run)
+		}
+	}
+
+	d.logger.WithContext(ctx).Info("Collection dispatch completed", map[string]interface{}{
+		"window_start": result.WindowStart.Format(time.RFC3339),
+		"window_end":   result.WindowEnd.Format(time.RFC3339),
+		"due":          len(result.Runs),
+		"started":      result.Started,
+		"failed":       result.Failed,
+	})
+	if result.Failed > 0 {
+		return result, fmt.Errorf("%d of %d scheduled runs failed to start", result.Failed, len(result.Runs))
+	}
+	return result, nil
+}
+
+// start starts one scheduled run
+func (d *Dispatcher) start(ctx context.Context, schedule config.ScheduleConfig, scheduledAt time.Time) Run {
+	run := Run{
+		Schedule:    schedule.Name,
+		ScheduledAt: scheduledAt,
+		Name:        runName(schedule.Name, scheduledAt),
+		Request:     Request(schedule, scheduledAt),
+	}
+
+	payload, err := json.Marshal(run.Request)
+	if err == nil {
+		run.RunID, err = d.starter.Start(ctx, run.Name, payload)
+	}
+	switch {
+	case errors.Is(err, ErrAlreadyStarted):
+		run.Status = StatusAlreadyStarted
+	case err != nil:
+		run.Status = StatusFailed
+		run.Error = err.Error()
+		d.logger.WithContext(ctx).Warn("Failed to start scheduled collection", map[string]interface{}{
+			"schedule":     schedule.Name,
+			"scheduled_at": scheduledAt.Format(time.RFC3339),
+			"error":        err.Error(),
+		})
+	default:
+		run.Status = StatusStarted
+	}
+	return run
+}
+
+// Request builds the collection payload of a schedule's run at scheduledAt; a lookback
+// window becomes a date_from relative to the scheduled day
+func Request(schedule config.ScheduleConfig, scheduledAt time.Time) types.CollectRequest {
+	request := types.CollectRequest{
+		DataSource:  schedule.Source,
+		SearchQuery: schedule.SearchQuery,
+		MaxResults:  schedule.MaxResults,
+		Schedule:    schedule.Name,
+		ScheduledAt: scheduledAt.Format(time.RFC3339),
+	}
+	if schedule.LookbackDays > 0 {
+		request.DateFrom = scheduledAt.AddDate(0, 0, -schedule.LookbackDays).Format("2006-01-02")
+	}
+	return request
+}
+
+// runName is the unique name of a schedule's run: the schedule name reduced to the
+// characters execution names allow, followed by the scheduled minute in UTC
+func runName(schedule string, scheduledAt time.Time) string {
+	suffix := "-" + scheduledAt.UTC().Format("20060102T1504Z")
+	name := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' {
+			return r
+		}
+		return '-'
+	}, schedule)
+	if len(name) > maxRunNameLength-len(suffix) {
+		name = name[:maxRunNameLength-len(suffix)]
+	}
+	return name + suffix
+}
+
+// StateMachineStarter starts runs as executions of the pipeline state machine, whose
+// Collect step passes the payload to the collector. Execution names make redelivered ticks
+// harmless: a second start of the same run fails with ExecutionAlreadyExists.
+type StateMachineStarter struct {
+	client sfniface.SFNAPI
+	arn    string
+}
+
+// NewStateMachineStarter creates a starter for the state machine
+func NewStateMachineStarter(arn string) *StateMachineStarter {
+	return NewStateMachineStarterWithClient(sfn.New(awsclient.MustSession()), arn)
+}
+
+// NewStateMachineStarterWithClient creates a starter with a custom Step Functions client (for testing)
+func NewStateMachineStarterWithClient(client sfniface.SFNAPI, arn string) *StateMachineStarter {
+	return &StateMachineStarter{client: client, arn: arn}
+}
+
+// Start starts an execution named name, returning its ARN
+func (s *StateMachineStarter) Start(ctx context.Context, name string, payload []byte) (string, error) {
+	output, err := s.client.StartExecutionWithContext(ctx, &sfn.StartExecutionInput{
+		StateMachineArn: aws.String(s.arn),
+		Name:            aws.String(name),
+		Input:           aws.String(string(payload)),
+	})
+	if err != nil {
+		var aerr awserr.Error
+		if errors.As(err, &aerr) && aerr.Code() == sfn.ErrCodeExecutionAlreadyExists {
+			return "", ErrAlreadyStarted
+		}
+		return "", fmt.Errorf("failed to start execution %s: %w", name, err)
+	}
+	return aws.StringValue(output.ExecutionArn), nil
+}
+
+// FunctionStarter starts runs by invoking the collector Lambda asynchronously, for
+// deployments without the state machine. Lambda has no run names, so a redelivered tick
+// starts the run again.
+type FunctionStarter struct {
+	client       lambdaiface.LambdaAPI
+	functionName string
+}
+
+// NewFunctionStarter creates a starter for the collector function
+func NewFunctionStarter(functionName string) *FunctionStarter {
+	return NewFunctionStarterWithClient(lambda.New(awsclient.MustSession()), functionName)
+}
+
+// NewFunctionStarterWithClient creates a starter with a custom Lambda client (for testing)
+func NewFunctionStarterWithClient(client lambdaiface.LambdaAPI, functionName string) *FunctionStarter {
+	return &FunctionStarter{client: client, functionName: functionName}
+}
+
+// Start invokes the collector with the payload
+func (s *FunctionStarter) Start(ctx context.Context, name string, payload []byte) (string, error) {
+	_, err := s.client.InvokeWithContext(ctx, &lambda.InvokeInput{
+		FunctionName:   aws.String(s.functionName),
+		InvocationType: aws.String(lambda.InvocationTypeEvent),
+		Payload:        payload,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to invoke %s for %s: %w", s.functionName, name, err)
+	}
+	return "", nil
+}
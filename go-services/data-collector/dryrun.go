@@ -0,0 +1,79 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	"data-collector/s3"
+	"data-collector/types"
+	"shared/logger"
+)
+
+// defaultDryRunSample is the number of papers a dry run returns for checking field mappings
+const defaultDryRunSample = 3
+
+// dryRunOutputDir is where dry runs write the objects they would have uploaded; empty only
+// measures them. Set by DRY_RUN_OUTPUT_DIR, or -dry-run-output in local mode.
+var dryRunOutputDir = os.Getenv("DRY_RUN_OUTPUT_DIR")
+
+// isDryRun reports whether the run should search but keep the payload out of S3, either for
+// every run (RUN_MODE=dry-run) or for one request
+func isDryRun(request types.CollectRequest) bool {
+	return request.DryRun || os.Getenv("RUN_MODE") == "dry-run"
+}
+
+// dryRunSampleSize returns DRY_RUN_SAMPLE_SIZE, or the default when unset or invalid
+func dryRunSampleSize() int {
+	if size, err := strconv.Atoi(os.Getenv("DRY_RUN_SAMPLE_SIZE")); err == nil && size >= 0 {
+		return size
+	}
+	return defaultDryRunSample
+}
+
+// skippedUpload reports what uploading the result would have written, without writing to S3.
+// A dry run also samples the papers and, with an output directory, writes the objects there
+// at their S3 keys; otherwise the payload is only encoded to measure it.
+func skippedUpload(uploader *s3.Uploader, result *types.CollectionResult, dryRun bool) (*types.ValidationReport, error) {
+	prepare, skippedWrite := uploader.PrepareUpload, "s3:PutObject"
+	if uploader.Splits(result) {
+		prepare = uploader.MeasureParts
+	} else if uploader.Streams(result) {
+		prepare, skippedWrite = uploader.MeasureStreamed, "s3:CreateMultipartUpload"
+	}
+
+	report := &types.ValidationReport{
+		Mode:            "validate",
+		Source:          result.Source,
+		PapersCollected: result.Count,
+		SkippedWrites:   []string{skippedWrite},
+	}
+	if dryRun && dryRunOutputDir != "" {
+		written, err := uploader.WriteLocal(dryRunOutputDir, result)
+		if err != nil {
+			return nil, logger.WrapError(err, logger.ErrorTypeData, "failed to write dry-run output")
+		}
+		report.S3Key = written.S3Key
+		report.OriginalSize = written.OriginalSize
+		report.CompressedSize = written.CompressedSize
+		for _, key := range append(written.PartKeys, written.S3Key) {
+			report.OutputFiles = append(report.OutputFiles, s3.LocalPath(dryRunOutputDir, key))
+		}
+	} else {
+		prepared, err := prepare(result)
+		if err != nil {
+			return nil, logger.WrapError(err, logger.ErrorTypeData, "failed to prepare upload payload")
+		}
+		report.S3Key = prepared.S3Key
+		report.OriginalSize = prepared.OriginalSize
+		report.CompressedSize = prepared.CompressedSize
+	}
+	report.S3Bucket = uploader.Bucket()
+	report.Timestamp = time.Now().UTC()
+
+	if dryRun {
+		report.Mode = "dry-run"
+		report.Sample = result.Papers[:min(len(result.Papers), dryRunSampleSize())]
+	}
+	return report, nil
+}
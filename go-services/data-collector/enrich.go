@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"data-collector/apiclient"
+	"data-collector/config"
+	"data-collector/crossref"
+	"data-collector/types"
+	"shared/logger"
+)
+
+// enrichPapers looks up the papers of result that are missing a DOI on CrossRef and attaches
+// the DOI, journal and citation count of the work whose title matches. At most max_lookups
+// papers are looked up per run, and the lookups stop after max_consecutive_failures failed in
+// a row; lookup failures are logged and never fail the run.
+func enrichPapers(ctx context.Context, contextLogger *logger.Logger, settings config.EnrichmentConfig, retry apiclient.RetryPolicy, result *types.CollectionResult) {
+	if !settings.Enabled {
+		return
+	}
+	start := time.Now()
+	mailto := os.Getenv(crossref.MailtoEnv)
+	if mailto == "" {
+		mailto = settings.Mailto
+	}
+	client := crossref.NewClient(settings.APIEndpoint, mailto, settings.RateLimit)
+	client.SetRetryPolicy(retry)
+
+	stats := &types.EnrichmentStats{}
+	consecutiveFailures := 0
+	for i := range result.Papers {
+		paper := &result.Papers[i]
+		if paper.DOI != "" {
+			continue
+		}
+		stats.MissingDOI++
+		if stats.Stopped || (settings.MaxLookups > 0 && stats.Looked >= settings.MaxLookups) || ctx.Err() != nil {
+			continue
+		}
+
+		stats.Looked++
+		work, err := client.Lookup(ctx, paper.Title, paper.Authors)
+		if err != nil {
+			stats.Failed++
+			consecutiveFailures++
+			contextLogger.Warn("CrossRef lookup failed", map[string]interface{}{
+				"paper_id": paper.ID,
+				"error":    err.Error(),
+			})
+			if settings.MaxConsecutiveFailures > 0 && consecutiveFailures >= settings.MaxConsecutiveFailures {
+				stats.Stopped = true
+			}
+			continue
+		}
+		consecutiveFailures = 0
+		if work == nil {
+			continue
+		}
+
+		stats.Matched++
+		citations := work.CitationCount
+		paper.DOI = work.DOI
+		paper.Journal = work.Journal
+		paper.CitationCount = &citations
+	}
+	stats.DurationMs = time.Since(start).Milliseconds()
+	result.Enrichment = stats
+
+	contextLogger.Info("CrossRef enrichment completed", map[string]interface{}{
+		"metric_type": "enrichment",
+		"metric_name": "crossref_matched",
+		"value":       stats.Matched,
+		"enrichment":  stats,
+	})
+}
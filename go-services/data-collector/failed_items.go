@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"data-collector/types"
+	"shared/awsclient"
+	"shared/failures"
+	"shared/logger"
+)
+
+// recordFailedRun stores a failed collection run in the FailedItems table, when
+// FAILED_ITEMS_TABLE_NAME is set. The run is keyed by data source and schedule, or search
+// query for ad-hoc runs, so a schedule failing on every tick accumulates one item's retry
+// count. A failed write is only logged.
+func recordFailedRun(ctx context.Context, contextLogger *logger.Logger, request types.CollectRequest, runErr error) {
+	tableName := os.Getenv(failures.TableEnv)
+	if tableName == "" {
+		return
+	}
+
+	code := failures.CodeUnknown
+	var appErr *logger.AppError
+	if errors.As(runErr, &appErr) {
+		code = failures.CodeForAppError(appErr.Type)
+	}
+
+	failure := failures.Failure{
+		Stage:    failures.StageCollection,
+		RecordID: collectionRecordID(request),
+		TraceID:  logger.TraceIDFromContext(ctx),
+		Code:     code,
+		Message:  runErr.Error(),
+	}
+	if err := failures.NewRecorder(tableName, awsclient.ClientConfig{}).Record(ctx, []failures.Failure{failure}); err != nil {
+		contextLogger.Warn("Failed to record failed collection run", map[string]interface{}{
+			"table": tableName,
+			"error": err.Error(),
+		})
+	}
+}
+
+// collectionRecordID identifies a run as <data source>#<schedule | search query | default>
+func collectionRecordID(request types.CollectRequest) string {
+	source := request.DataSource
+	if source == "" {
+		source = "arxiv"
+	}
+	switch {
+	case request.Schedule != "":
+		return source + "#" + request.Schedule
+	case request.SearchQuery != "":
+		return source + "#" + request.SearchQuery
+	}
+	return source + "#default"
+}
@@ -6,9 +6,41 @@ require (
 	github.com/aws/aws-lambda-go v1.47.0
 	github.com/aws/aws-sdk-go v1.55.5
 	gopkg.in/yaml.v3 v3.0.1
+	shared/awsclient v0.0.0
+	shared/failures v0.0.0
+	shared/httpserver v0.0.0
 	shared/logger v0.0.0
+	shared/preflight v0.0.0
+	shared/shutdown v0.0.0
 )
 
-require github.com/jmespath/go-jmespath v0.4.0 // indirect
+require (
+	github.com/andybalholm/brotli v1.1.0 // indirect
+	github.com/aws/aws-xray-sdk-go v1.8.5 // indirect
+	github.com/jmespath/go-jmespath v0.4.0 // indirect
+	github.com/klauspost/compress v1.17.6
+	github.com/pkg/errors v0.9.1 // indirect
+	github.com/valyala/bytebufferpool v1.0.0 // indirect
+	github.com/valyala/fasthttp v1.52.0 // indirect
+	golang.org/x/net v0.26.0 // indirect
+	golang.org/x/sys v0.21.0 // indirect
+	golang.org/x/text v0.16.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20240318140521-94a12d6c2237 // indirect
+	google.golang.org/grpc v1.64.1 // indirect
+	google.golang.org/protobuf v1.33.0 // indirect
+	shared/pauseflags v0.0.0
+)
 
 replace shared/logger => ../shared/logger
+
+replace shared/awsclient => ../shared/awsclient
+
+replace shared/pauseflags => ../shared/pauseflags
+
+replace shared/preflight => ../shared/preflight
+
+replace shared/failures => ../shared/failures
+
+replace shared/shutdown => ../shared/shutdown
+
+replace shared/httpserver => ../shared/httpserver
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"data-collector/config"
+	"data-collector/s3"
+	"data-collector/scheduler"
+	"shared/logger"
+)
+
+// requestScheduler enforces per-source request limits across concurrent server requests and
+// warm Lambda invocations
+var requestScheduler = scheduler.New(nil)
+
+var (
+	quotaStoreMu  sync.Mutex
+	quotaStoreKey string // bucket/prefix and KMS key the scheduler's usage store writes with
+)
+
+// acquireSourceRequest applies the configured limits of source and admits one request to it.
+// Quota and cooldown rejections are QUOTA_ERRORs, which the state machine does not retry.
+func acquireSourceRequest(ctx context.Context, cfg *config.Config, source string, limits config.SourceLimitsConfig) (*scheduler.Permit, error) {
+	if err := useQuotaStore(cfg.AWS.S3.RawDataBucket, cfg.AWS.S3.QuotaStatePrefix, cfg.AWS.S3.KMSKeyARN); err != nil {
+		return nil, logger.WrapError(err, logger.ErrorTypeS3, "failed to initialize quota store")
+	}
+	requestScheduler.Configure(source, scheduler.Limits{
+		MaxConcurrent: limits.MaxConcurrentRequests,
+		DailyQuota:    limits.DailyQuota,
+		Cooldown:      time.Duration(limits.CooldownSeconds) * time.Second,
+	})
+
+	permit, err := requestScheduler.Acquire(ctx, source)
+	switch {
+	case err == nil:
+		return permit, nil
+	case errors.Is(err, scheduler.ErrQuotaExhausted), errors.Is(err, scheduler.ErrCoolingDown):
+		return nil, logger.WrapError(err, logger.ErrorTypeQuota, "request to "+source+" not admitted")
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return nil, logger.WrapError(err, logger.ErrorTypeAPI, "timed out waiting for a request slot")
+	default:
+		return nil, logger.WrapError(err, logger.ErrorTypeS3, "failed to track request quota")
+	}
+}
+
+// useQuotaStore points the scheduler at the usage store for bucket and prefix, encrypted under
+// kmsKeyARN, replacing it only when these change; an empty prefix keeps usage in memory
+func useQuotaStore(bucket, prefix, kmsKeyARN string) error {
+	quotaStoreMu.Lock()
+	defer quotaStoreMu.Unlock()
+
+	key := ""
+	if prefix != "" {
+		key = bucket + "/" + prefix + "#" + kmsKeyARN
+	}
+	if key == quotaStoreKey {
+		return nil
+	}
+
+	var store scheduler.UsageStore
+	if key != "" {
+		quotaStore, err := s3.NewQuotaStore(bucket, prefix)
+		if err != nil {
+			return err
+		}
+		quotaStore.SetKMSKey(kmsKeyARN)
+		store = quotaStore
+	}
+	requestScheduler.SetUsageStore(store)
+	quotaStoreKey = key
+	return nil
+}
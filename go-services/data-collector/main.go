@@ -2,17 +2,25 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"os"
 	"time"
 
-	"data-collector/arxiv"
+	"data-collector/categories"
 	"data-collector/config"
+	"data-collector/openalex"
 	"data-collector/s3"
+	"data-collector/semanticscholar"
 	"data-collector/types"
 	"shared/logger"
+	"shared/pauseflags"
+	"shared/shutdown"
 
 	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-lambda-go/lambda/messages"
 )
 
 var (
@@ -27,17 +35,35 @@ func init() {
 
 func main() {
 	if os.Getenv("AWS_LAMBDA_FUNCTION_NAME") != "" {
-		lambda.Start(handleLambda)
+		// The same binary runs the schedule dispatcher
+		if os.Getenv("SERVICE_ROLE") == "dispatcher" {
+			lambda.Start(handleDispatch)
+		} else {
+			lambda.Start(handleLambda)
+		}
 	} else {
-		fmt.Println("Data Collector Service - Local Development Mode")
-		if err := runLocalTest(); err != nil {
-			appLogger.Error("Local test failed", err)
-			os.Exit(1)
+		serveAddr := flag.String("serve", "", "run an HTTP server on this address (e.g. :8080) instead of a single collection")
+		dryRun := flag.Bool("dry-run", false, "search without uploading to S3, printing a sample of the papers")
+		flag.StringVar(&dryRunOutputDir, "dry-run-output", dryRunOutputDir, "directory dry runs write the objects to, at their S3 keys")
+		flag.Parse()
+
+		watcher := shutdown.Watch(appLogger)
+		if *serveAddr != "" {
+			watcher.Exit(runServer(*serveAddr, watcher), false)
 		}
+
+		fmt.Println("Data Collector Service - Local Development Mode")
+		// A single collection run is one upload, so a shutdown request lets it complete
+		watcher.Exit(runLocalTest(types.CollectRequest{DryRun: *dryRun}), false)
 	}
 }
 
-func handleLambda(ctx context.Context) error {
+// isValidateMode reports whether the service should run every step except writes
+func isValidateMode() bool {
+	return os.Getenv("RUN_MODE") == "validate"
+}
+
+func handleLambda(ctx context.Context, request types.CollectRequest) (*collectResponse, error) {
 	defer func() {
 		if err := errorHandler.HandleWithRecovery("lambda handler"); err != nil {
 			appLogger.Error("Lambda handler panic recovered", err)
@@ -50,19 +76,34 @@ func handleLambda(ctx context.Context) error {
 	contextLogger.Info("Data collector lambda handler started")
 
 	// Execute the complete data collection pipeline
-	result, err := executeDataCollection(ctx, contextLogger)
+	result, err := executeDataCollection(ctx, contextLogger, request)
 	if err != nil {
-		return errorHandler.Handle(err, "data collection pipeline")
+		recordFailedRun(ctx, contextLogger, request, err)
+		return nil, lambdaError(errorHandler.Handle(err, "data collection pipeline"))
 	}
 
 	contextLogger.InfoWithDuration("Lambda handler completed successfully", time.Since(start))
 	contextLogger.InfoWithCount("Papers collected and uploaded", result.Count)
 
-	return nil
+	return newCollectResponse(result), nil
 }
 
-// executeDataCollection performs the complete data collection pipeline
-func executeDataCollection(ctx context.Context, contextLogger *logger.Logger) (*types.CollectionResult, error) {
+// lambdaError reports AppErrors to Lambda with their error type (e.g. API_ERROR) as the
+// errorType, so Step Functions retry and catch policies can match on it
+func lambdaError(err error) error {
+	var appErr *logger.AppError
+	if errors.As(err, &appErr) {
+		return messages.InvokeResponse_Error{
+			Type:    string(appErr.Type),
+			Message: appErr.Error(),
+		}
+	}
+	return err
+}
+
+// executeDataCollection performs the complete data collection pipeline, with the request's
+// overrides applied to the data source configuration
+func executeDataCollection(ctx context.Context, contextLogger *logger.Logger, request types.CollectRequest) (*types.CollectionResult, error) {
 	start := time.Now()
 
 	// 1. Load configuration
@@ -72,83 +113,229 @@ func executeDataCollection(ctx context.Context, contextLogger *logger.Logger) (*
 		return nil, logger.WrapError(err, logger.ErrorTypeConfig, "failed to load configuration")
 	}
 
-	// 2. Get arXiv data source configuration
-	arxivConfig, err := cfg.GetDataSourceConfig("arxiv")
+	// Operators halt collection through the pause flags without disabling the schedule
+	pauseChecker, err := pauseflags.NewChecker(cfg.Pause)
+	if err != nil {
+		return nil, logger.WrapError(err, logger.ErrorTypeConfig, "failed to initialize pause flags")
+	}
+	if err := checkPause(ctx, contextLogger, pauseChecker, pauseflags.Collection); err != nil {
+		return nil, err
+	}
+
+	// 2. Get the data source configuration
+	source := request.DataSource
+	if source == "" {
+		source = "arxiv"
+	}
+	if source != "arxiv" && source != semanticscholar.Source && source != openalex.Source {
+		return nil, logger.NewAppError(logger.ErrorTypeConfig, fmt.Sprintf("no collector is implemented for data source %q", source), nil)
+	}
+	sourceConfig, err := cfg.GetDataSourceConfig(source)
 	if err != nil {
-		return nil, logger.WrapError(err, logger.ErrorTypeConfig, "failed to get arXiv configuration")
+		return nil, logger.WrapError(err, logger.ErrorTypeConfig, "failed to get "+source+" configuration")
 	}
+	sourceConfig = applyCollectRequest(request, sourceConfig)
 
 	contextLogger.Info("Configuration loaded successfully", map[string]interface{}{
-		"api_endpoint": arxivConfig.APIEndpoint,
-		"max_results":  arxivConfig.MaxResults,
-		"rate_limit":   arxivConfig.RateLimit,
+		"data_source":  source,
+		"api_endpoint": sourceConfig.APIEndpoint,
+		"max_results":  sourceConfig.MaxResults,
+		"rate_limit":   sourceConfig.RateLimit,
+		"schedule":     request.Schedule,
+		"scheduled_at": request.ScheduledAt,
 	})
 
-	// 3. Initialize arXiv client
-	arxivClient := arxiv.NewClient(arxivConfig.APIEndpoint, arxivConfig.RateLimit)
+	// Parse date range if provided
+	dateFrom, dateTo := parseDateRange(contextLogger, sourceConfig)
 
-	// 4. Perform arXiv search
-	contextLogger.Info("Starting arXiv API search")
-	searchParams := arxiv.SearchParams{
-		Query:      arxivConfig.SearchQuery,
-		MaxResults: arxivConfig.MaxResults,
-		StartIndex: 0,
+	// Category patterns are arXiv categories, so the filter only applies to arXiv runs
+	categoryFilter := categories.NewFilter(nil, nil)
+	if source == "arxiv" {
+		categoryFilter = categories.NewFilter(cfg.Processing.CategoryFilter.Allow, cfg.Processing.CategoryFilter.Deny)
 	}
 
-	// Parse date range if provided
-	if arxivConfig.DateFrom != "" {
-		if dateFrom, err := time.Parse("2006-01-02", arxivConfig.DateFrom); err == nil {
-			searchParams.DateFrom = &dateFrom
-		} else {
-			contextLogger.Warn("Invalid date_from format, ignoring", map[string]interface{}{
-				"date_from": arxivConfig.DateFrom,
-				"error":     err.Error(),
-			})
+	// Fail fast when the raw data bucket is missing, before any API quota is spent; a dry run
+	// never writes to it, so it can check queries without AWS access
+	if !isDryRun(request) {
+		if err := runPreflight(ctx, cfg); err != nil {
+			return nil, err
 		}
 	}
 
-	if arxivConfig.DateTo != "" {
-		if dateTo, err := time.Parse("2006-01-02", arxivConfig.DateTo); err == nil {
-			searchParams.DateTo = &dateTo
-		} else {
-			contextLogger.Warn("Invalid date_to format, ignoring", map[string]interface{}{
-				"date_to": arxivConfig.DateTo,
-				"error":   err.Error(),
-			})
-		}
+	// 3-4. Initialize the source client and search
+	var result *types.CollectionResult
+	if source == semanticscholar.Source {
+		result, err = searchSemanticScholar(ctx, contextLogger, cfg, sourceConfig, dateFrom, dateTo)
+	} else if source == openalex.Source {
+		result, err = searchOpenAlex(ctx, contextLogger, cfg, sourceConfig, dateFrom, dateTo)
+	} else if sourceConfig.Mode == config.ModeOAIPMH {
+		result, err = harvestArxiv(ctx, contextLogger, cfg, sourceConfig, dateFrom, dateTo)
+	} else {
+		result, err = searchArxiv(ctx, contextLogger, cfg, sourceConfig, categoryFilter, dateFrom, dateTo)
 	}
-
-	result, err := arxivClient.Search(ctx, searchParams)
 	if err != nil {
-		return nil, logger.WrapError(err, logger.ErrorTypeAPI, "arXiv API search failed")
+		return nil, err
+	}
+	contextLogger.InfoWithDuration("Data source search completed", time.Since(start), map[string]interface{}{
+		"data_source": source,
+	})
+
+	// Drop papers whose categories are all skipped or outside the allowlist before upload
+	if categoryFilter.Active() {
+		kept := result.Papers[:0]
+		for _, paper := range result.Papers {
+			if categoryFilter.Allows(paper.Categories) {
+				kept = append(kept, paper)
+			}
+		}
+		result.CategoryFiltered = len(result.Papers) - len(kept)
+		result.Papers = kept
+		result.Count = len(kept)
+		contextLogger.Info("Category filter applied", map[string]interface{}{
+			"papers_kept":     result.Count,
+			"papers_filtered": result.CategoryFiltered,
+		})
 	}
 
-	contextLogger.InfoWithCount("Papers retrieved from arXiv", result.Count)
-	contextLogger.InfoWithDuration("arXiv API search completed", time.Since(start))
+	// Papers the source returned without a DOI get one from CrossRef when enrichment is on;
+	// only kept papers are looked up
+	enrichPapers(ctx, contextLogger, cfg.Enrichment, retryPolicy(cfg.Processing), result)
+
+	// The breakdown travels with the uploaded payload and the response, for coverage dashboards
+	result.Stats = types.BuildCollectionStats(result)
+	contextLogger.Info("Collection statistics", map[string]interface{}{
+		"collection_stats": result.Stats,
+	})
 
 	// 5. Initialize S3 uploader
 	uploader, err := s3.NewUploader(cfg.AWS.S3.RawDataBucket, cfg.AWS.S3.RawDataPrefix)
 	if err != nil {
 		return nil, logger.WrapError(err, logger.ErrorTypeS3, "failed to initialize S3 uploader")
 	}
+	if err := uploader.SetKeyLayout(cfg.AWS.S3.KeyLayout); err != nil {
+		return nil, logger.WrapError(err, logger.ErrorTypeConfig, "invalid aws.s3.key_layout")
+	}
+	if err := uploader.SetStreamingThreshold(cfg.AWS.S3.StreamingThreshold); err != nil {
+		return nil, logger.WrapError(err, logger.ErrorTypeConfig, "invalid aws.s3.streaming_threshold")
+	}
+	if err := uploader.SetMaxPapersPerObject(cfg.AWS.S3.MaxPapersPerObject); err != nil {
+		return nil, logger.WrapError(err, logger.ErrorTypeConfig, "invalid aws.s3.max_papers_per_object")
+	}
+	if err := uploader.SetStorageClass(cfg.AWS.S3.StorageClass); err != nil {
+		return nil, logger.WrapError(err, logger.ErrorTypeConfig, "invalid aws.s3.storage_class")
+	}
+	uploader.SetKMSKey(cfg.AWS.S3.KMSKeyARN)
+	uploader.SetObjectTagging(cfg.AWS.S3.ObjectTagging)
+	if err := uploader.SetCompression(cfg.Processing.Compression); err != nil {
+		return nil, logger.WrapError(err, logger.ErrorTypeConfig, "invalid processing.compression")
+	}
+
+	// A pause flipped during the search stops the run before anything is written
+	if err := checkPause(ctx, contextLogger, pauseChecker, pauseflags.Collection); err != nil {
+		return nil, err
+	}
+
+	// In validate and dry-run modes, build the payload but skip the S3 write
+	if dryRun := isDryRun(request); dryRun || isValidateMode() {
+		result.Validation, err = skippedUpload(uploader, result, dryRun)
+		if err != nil {
+			return nil, err
+		}
+
+		checkCollectionAnomaly(ctx, contextLogger, cfg, request, result, false)
+
+		contextLogger.Info("Validation run completed, S3 upload skipped", map[string]interface{}{
+			"mode":         result.Validation.Mode,
+			"s3_key":       result.Validation.S3Key,
+			"output_files": result.Validation.OutputFiles,
+		})
+		return result, nil
+	}
 
 	// 6. Upload to S3
 	contextLogger.Info("Uploading data to S3")
 	uploadStart := time.Now()
 
-	uploadResult, err := uploader.UploadCompressedData(ctx, result)
+	upload := uploader.UploadCompressedData
+	if uploader.Splits(result) {
+		contextLogger.Info("Splitting large result into S3 part objects", map[string]interface{}{
+			"paper_count":           result.Count,
+			"max_papers_per_object": cfg.AWS.S3.MaxPapersPerObject,
+		})
+		upload = uploader.UploadParts
+	} else if uploader.Streams(result) {
+		contextLogger.Info("Streaming large result to S3 as JSON lines", map[string]interface{}{
+			"paper_count": result.Count,
+			"threshold":   cfg.AWS.S3.StreamingThreshold,
+		})
+		upload = uploader.UploadStreamed
+	}
+	uploadResult, err := upload(ctx, result)
 	if err != nil {
 		return nil, logger.WrapError(err, logger.ErrorTypeS3, "S3 upload failed")
 	}
 
+	result.S3Key = uploadResult.S3Key
+	result.S3PartKeys = uploadResult.PartKeys
+	result.CompressedSize = uploadResult.CompressedSize
+
+	// Presigned URL lets dashboards and QA fetch the object without bucket-wide permissions
+	if cfg.AWS.S3.PresignedURLTTL > 0 {
+		ttl := time.Duration(cfg.AWS.S3.PresignedURLTTL) * time.Second
+		presignedURL, err := uploader.GeneratePresignedURL(uploadResult.S3Key, ttl)
+		if err != nil {
+			contextLogger.Warn("Failed to generate presigned URL", map[string]interface{}{
+				"s3_key": uploadResult.S3Key,
+				"error":  err.Error(),
+			})
+		} else {
+			expiresAt := time.Now().UTC().Add(ttl)
+			result.PresignedURL = presignedURL
+			result.PresignedURLExpiresAt = &expiresAt
+		}
+	}
+
+	// The run manifest lets the batch processor replay this run after S3 events are lost
+	// or parsing is fixed; a failed write does not fail an otherwise complete collection
+	if prefix := cfg.AWS.S3.RunHistoryPrefix; prefix != "" {
+		keys := []string{uploadResult.S3Key}
+		if len(uploadResult.PartKeys) > 0 {
+			keys = uploadResult.PartKeys
+		}
+		manifest := &types.RunManifest{
+			RunID:       types.CollectionRunID(result.Source, result.Timestamp),
+			Source:      result.Source,
+			Bucket:      cfg.AWS.S3.RawDataBucket,
+			Keys:        keys,
+			PaperCount:  result.Count,
+			CollectedAt: result.Timestamp,
+			CompletedAt: time.Now().UTC(),
+		}
+		manifestKey, err := uploader.WriteRunManifest(ctx, prefix, manifest)
+		if err != nil {
+			contextLogger.Warn("Failed to write run manifest", map[string]interface{}{
+				"run_id": manifest.RunID,
+				"error":  err.Error(),
+			})
+		} else {
+			result.RunManifestKey = manifestKey
+		}
+	}
+
 	contextLogger.InfoWithDuration("S3 upload completed", time.Since(uploadStart))
 	contextLogger.Info("Data uploaded successfully", map[string]interface{}{
-		"s3_key":            uploadResult.S3Key,
-		"compressed_size":   uploadResult.CompressedSize,
-		"original_size":     uploadResult.OriginalSize,
-		"compression_ratio": float64(uploadResult.CompressedSize) / float64(uploadResult.OriginalSize),
+		"s3_key":                   uploadResult.S3Key,
+		"part_count":               len(uploadResult.PartKeys),
+		"compressed_size":          uploadResult.CompressedSize,
+		"original_size":            uploadResult.OriginalSize,
+		"compression_ratio":        float64(uploadResult.CompressedSize) / float64(uploadResult.OriginalSize),
+		"presigned_url_expires_at": result.PresignedURLExpiresAt,
+		"run_manifest_key":         result.RunManifestKey,
 	})
 
+	// A zero or anomalous count alerts; only uploaded runs count towards the baseline
+	checkCollectionAnomaly(ctx, contextLogger, cfg, request, result, true)
+
 	contextLogger.InfoWithDuration("Complete data collection pipeline finished", time.Since(start))
 
 	return result, nil
@@ -183,17 +370,24 @@ func loadConfiguration(ctx context.Context) (*config.Config, error) {
 	return config.GetDefaultConfig(), nil
 }
 
-func runLocalTest() error {
+func runLocalTest(request types.CollectRequest) error {
 	appLogger.Info("Starting local development test")
 
 	// Execute the complete data collection pipeline in local mode
 	ctx := context.Background()
 	contextLogger := appLogger.WithContext(ctx)
 
-	result, err := executeDataCollection(ctx, contextLogger)
+	result, err := executeDataCollection(ctx, contextLogger, request)
 	if err != nil {
 		return fmt.Errorf("local test failed: %w", err)
 	}
+	if result.Validation != nil && result.Validation.Mode == "dry-run" {
+		report, err := json.MarshalIndent(result.Validation, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal dry-run report: %w", err)
+		}
+		fmt.Println(string(report))
+	}
 
 	appLogger.Info("Local development test completed successfully", map[string]interface{}{
 		"papers_collected": result.Count,
@@ -0,0 +1,356 @@
+package openalex
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"data-collector/apiclient"
+	"data-collector/types"
+	"shared/awsclient"
+)
+
+// Source is the data source name of OpenAlex papers
+const Source = "openalex"
+
+// MailtoEnv overrides the mailto of the openalex data source
+const MailtoEnv = "OPENALEX_MAILTO"
+
+// APIKeyEnv overrides the api_key of the openalex data source
+const APIKeyEnv = "OPENALEX_API_KEY"
+
+// selectFields are the work fields requested from the works endpoint
+const selectFields = "id,doi,display_name,publication_date,publication_year,authorships,abstract_inverted_index,primary_location,topics,cited_by_count"
+
+// maxPageSize is the most works the works endpoint returns per request
+const maxPageSize = 200
+
+// Client represents an OpenAlex works API client
+type Client struct {
+	httpClient *http.Client
+	baseURL    string
+	mailto     string
+	apiKey     string
+	limiter    *apiclient.RateLimiter
+	retry      apiclient.RetryPolicy
+}
+
+// NewClient creates a new OpenAlex client for the works endpoint at baseURL. A mailto
+// address puts requests in the polite pool, which OpenAlex serves faster and more reliably;
+// an apiKey is only needed for premium limits.
+func NewClient(baseURL, mailto, apiKey string, rateLimitPerSecond int) *Client {
+	return &Client{
+		httpClient: awsclient.HTTPClient(&http.Client{
+			Timeout: 30 * time.Second,
+		}),
+		baseURL: baseURL,
+		mailto:  mailto,
+		apiKey:  apiKey,
+		limiter: apiclient.NewRateLimiter(rateLimitPerSecond),
+	}
+}
+
+// SetRetryPolicy sets how failed page requests are retried; by default they are not
+func (c *Client) SetRetryPolicy(policy apiclient.RetryPolicy) {
+	c.retry = policy
+}
+
+// SearchParams represents search parameters for the works endpoint
+type SearchParams struct {
+	Query      string // full-text search of titles, abstracts and fulltext; empty lists every work matching Filter
+	Filter     string // OpenAlex filter expression, e.g. "type:article,primary_topic.field.id:17"
+	MaxResults int
+	PageSize   int        // works per request, 0 or above 200 uses 200
+	DateFrom   *time.Time // Optional: publication date from (inclusive)
+	DateTo     *time.Time // Optional: publication date to (inclusive)
+	PageDelay  time.Duration
+	Gate       apiclient.RequestGate
+}
+
+// worksResponse is one page of the works endpoint
+type worksResponse struct {
+	Meta struct {
+		Count      int    `json:"count"`
+		NextCursor string `json:"next_cursor"`
+	} `json:"meta"`
+	Results []work `json:"results"`
+}
+
+type work struct {
+	ID                    string           `json:"id"`
+	DOI                   string           `json:"doi"`
+	DisplayName           string           `json:"display_name"`
+	PublicationDate       string           `json:"publication_date"`
+	PublicationYear       int              `json:"publication_year"`
+	Authorships           []authorship     `json:"authorships"`
+	AbstractInvertedIndex map[string][]int `json:"abstract_inverted_index"`
+	PrimaryLocation       *location        `json:"primary_location"`
+	Topics                []topic          `json:"topics"`
+	CitedByCount          *int             `json:"cited_by_count"`
+}
+
+type authorship struct {
+	Author struct {
+		DisplayName string `json:"display_name"`
+	} `json:"author"`
+}
+
+type location struct {
+	LandingPageURL string `json:"landing_page_url"`
+	Source         *struct {
+		DisplayName string `json:"display_name"`
+		Type        string `json:"type"`
+	} `json:"source"`
+}
+
+type topic struct {
+	Subfield struct {
+		DisplayName string `json:"display_name"`
+	} `json:"subfield"`
+}
+
+// Search pages through the works endpoint with cursor pagination until MaxResults papers are
+// collected or the results run out
+func (c *Client) Search(ctx context.Context, params SearchParams) (*types.CollectionResult, error) {
+	var (
+		papers     []types.Paper
+		cursor     = "*"
+		total      int
+		pages      int
+		apiLatency time.Duration
+		firstURL   string
+		skipped    int
+	)
+
+	pageSize := params.PageSize
+	if pageSize <= 0 || pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+
+	for {
+		queryURL, err := c.buildQueryURL(params, pageSize, cursor)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build query URL: %w", err)
+		}
+		if firstURL == "" {
+			firstURL = queryURL
+		}
+
+		if pages > 0 && params.PageDelay > 0 {
+			if err := apiclient.Sleep(ctx, params.PageDelay); err != nil {
+				return nil, fmt.Errorf("page delay failed: %w", err)
+			}
+		}
+
+		requestStart := time.Now()
+		page, err := c.fetchPage(ctx, params.Gate, queryURL)
+		if err != nil {
+			return nil, fmt.Errorf("page %d: %w", pages+1, err)
+		}
+		apiLatency += time.Since(requestStart)
+		pages++
+		total = page.Meta.Count
+
+		for _, entry := range page.Results {
+			converted, err := convertWork(entry)
+			if err != nil {
+				// Skip works without an ID or title rather than failing the run
+				skipped++
+				continue
+			}
+			papers = append(papers, converted)
+		}
+
+		cursor = page.Meta.NextCursor
+		if cursor == "" || len(page.Results) == 0 || (params.MaxResults > 0 && len(papers) >= params.MaxResults) {
+			break
+		}
+	}
+
+	if params.MaxResults > 0 && len(papers) > params.MaxResults {
+		papers = papers[:params.MaxResults]
+	}
+
+	query := params.Query
+	if params.Filter != "" {
+		query = strings.TrimSpace(query + " filter=" + params.Filter)
+	}
+	return &types.CollectionResult{
+		Papers:    papers,
+		Source:    Source,
+		Count:     len(papers),
+		Timestamp: time.Now(),
+		Metadata: &types.CollectionMetadata{
+			Query:          query,
+			RequestURL:     firstURL,
+			DateFrom:       params.DateFrom,
+			DateTo:         params.DateTo,
+			MaxResults:     params.MaxResults,
+			TotalResults:   total,
+			ItemsPerPage:   pageSize,
+			HasMore:        cursor != "",
+			APILatencyMs:   apiLatency.Milliseconds(),
+			SkippedEntries: skipped,
+			Pages:          pages,
+		},
+	}, nil
+}
+
+// fetchPage requests one page, admitted through gate when one is set
+func (c *Client) fetchPage(ctx context.Context, gate apiclient.RequestGate, queryURL string) (*worksResponse, error) {
+	body, _, err := apiclient.Fetch(ctx, gate, c.limiter, c.retry, c.httpClient, queryURL, c.header())
+	if err != nil {
+		return nil, err
+	}
+
+	var page worksResponse
+	if err := json.Unmarshal(body, &page); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON response: %w", err)
+	}
+	return &page, nil
+}
+
+// header carries the mailto address in the User-Agent, which OpenAlex accepts for the polite
+// pool, so it stays out of logged URLs
+func (c *Client) header() http.Header {
+	header := http.Header{}
+	if c.mailto != "" {
+		header.Set("User-Agent", fmt.Sprintf("paper-pipeline (mailto:%s)", c.mailto))
+	}
+	return header
+}
+
+// buildQueryURL constructs the works URL of the page at cursor. The date range is added to
+// the configured filter expression.
+func (c *Client) buildQueryURL(params SearchParams, pageSize int, cursor string) (string, error) {
+	baseURL, err := url.Parse(c.baseURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid base URL: %w", err)
+	}
+
+	query := baseURL.Query()
+	if params.Query != "" {
+		query.Set("search", params.Query)
+	}
+	if filter := buildFilter(params.Filter, params.DateFrom, params.DateTo); filter != "" {
+		query.Set("filter", filter)
+	}
+	query.Set("select", selectFields)
+	query.Set("sort", "publication_date:desc")
+	query.Set("per-page", strconv.Itoa(pageSize))
+	query.Set("cursor", cursor)
+	if c.apiKey != "" {
+		query.Set("api_key", c.apiKey)
+	}
+
+	baseURL.RawQuery = query.Encode()
+	return baseURL.String(), nil
+}
+
+// buildFilter appends the publication date range to a filter expression; OpenAlex filters
+// are comma-separated and all must match
+func buildFilter(filter string, dateFrom, dateTo *time.Time) string {
+	var filters []string
+	if filter = strings.Trim(strings.TrimSpace(filter), ","); filter != "" {
+		filters = append(filters, filter)
+	}
+	if dateFrom != nil {
+		filters = append(filters, "from_publication_date:"+dateFrom.Format("2006-01-02"))
+	}
+	if dateTo != nil {
+		filters = append(filters, "to_publication_date:"+dateTo.Format("2006-01-02"))
+	}
+	return strings.Join(filters, ",")
+}
+
+// convertWork normalizes an OpenAlex work into a Paper. IDs are the short work IDs
+// ("W2741809807"), the publication year stands in for works without a publication date, and
+// categories are the subfields of the work's topics.
+func convertWork(entry work) (types.Paper, error) {
+	id := strings.TrimPrefix(entry.ID, "https://openalex.org/")
+	if id == "" || strings.TrimSpace(entry.DisplayName) == "" {
+		return types.Paper{}, fmt.Errorf("work is missing its ID or title")
+	}
+
+	var publishedDate time.Time
+	if entry.PublicationDate != "" {
+		parsed, err := time.Parse("2006-01-02", entry.PublicationDate)
+		if err != nil {
+			return types.Paper{}, fmt.Errorf("failed to parse publication date: %w", err)
+		}
+		publishedDate = parsed
+	} else if entry.PublicationYear > 0 {
+		publishedDate = time.Date(entry.PublicationYear, time.January, 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	authors := make([]string, 0, len(entry.Authorships))
+	for _, a := range entry.Authorships {
+		if name := strings.TrimSpace(a.Author.DisplayName); name != "" {
+			authors = append(authors, name)
+		}
+	}
+
+	paper := types.Paper{
+		ID:            id,
+		Source:        Source,
+		Title:         strings.TrimSpace(entry.DisplayName),
+		Abstract:      rebuildAbstract(entry.AbstractInvertedIndex),
+		Authors:       authors,
+		PublishedDate: publishedDate,
+		Categories:    topicSubfields(entry.Topics),
+		URL:           entry.ID,
+		DOI:           strings.ToLower(strings.TrimPrefix(entry.DOI, "https://doi.org/")),
+		CitationCount: entry.CitedByCount,
+	}
+	if location := entry.PrimaryLocation; location != nil {
+		if location.LandingPageURL != "" {
+			paper.URL = location.LandingPageURL
+		}
+		// Repositories such as arXiv host preprints; only venues count as the journal
+		if location.Source != nil && location.Source.Type != "repository" {
+			paper.Journal = location.Source.DisplayName
+		}
+	}
+	return paper, nil
+}
+
+// rebuildAbstract restores the abstract OpenAlex ships as an inverted index of word positions
+func rebuildAbstract(index map[string][]int) string {
+	type placed struct {
+		position int
+		word     string
+	}
+	var words []placed
+	for word, positions := range index {
+		for _, position := range positions {
+			words = append(words, placed{position: position, word: word})
+		}
+	}
+	sort.Slice(words, func(i, j int) bool { return words[i].position < words[j].position })
+
+	parts := make([]string, len(words))
+	for i, w := range words {
+		parts[i] = w.word
+	}
+	return strings.Join(parts, " ")
+}
+
+// topicSubfields returns the subfields of the work's topics, without duplicates
+func topicSubfields(topics []topic) []string {
+	seen := make(map[string]bool)
+	subfields := make([]string, 0, len(topics))
+	for _, t := range topics {
+		name := t.Subfield.DisplayName
+		if name != "" && !seen[name] {
+			seen[name] = true
+			subfields = append(subfields, name)
+		}
+	}
+	return subfields
+}
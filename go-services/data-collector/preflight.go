@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/s3"
+
+	"data-collector/config"
+	"shared/awsclient"
+	"shared/failures"
+	"shared/logger"
+	"shared/preflight"
+)
+
+// preflightGuard verifies the collector's resources on the first invocation of a container
+var preflightGuard preflight.Guard
+
+// runPreflight checks that the raw data bucket, and the FailedItems table when one is set,
+// exist before the source API is queried, so a collection never spends API quota on papers
+// it cannot upload. The source APIs themselves
+// are not probed: an extra request would count against their rate limits.
+func runPreflight(ctx context.Context, cfg *config.Config) error {
+	sess, err := awsclient.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"), // Default region
+	})
+	if err != nil {
+		return logger.WrapError(err, logger.ErrorTypeConfig, "failed to create AWS session")
+	}
+	checks := []preflight.Check{
+		preflight.Bucket(s3.New(sess), cfg.AWS.S3.RawDataBucket),
+	}
+	if failedTable := os.Getenv(failures.TableEnv); failedTable != "" {
+		checks = append(checks, preflight.TableWithIndexes(dynamodb.New(sess), failedTable, preflight.Index{
+			Name:         failures.CodeIndex,
+			PartitionKey: failures.CodeIndexPartitionKey,
+			SortKey:      failures.CodeIndexSortKey,
+		}))
+	}
+	return preflightGuard.Run(ctx, checks)
+}
@@ -0,0 +1,113 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+
+	"shared/awsclient"
+)
+
+// BaselineStore keeps the paper counts of each run key's recent collection runs as a small
+// JSON object, the rolling baseline anomalous runs are detected against. It is
+// last-writer-wins: overlapping runs of the same key may drop one of their counts.
+type BaselineStore struct {
+	s3Client  *s3.S3
+	bucket    string
+	prefix    string
+	kmsKeyARN string // SSE-KMS key of the baseline objects; empty uses the bucket default
+}
+
+// runBaseline is the stored count history of one run key
+type runBaseline struct {
+	RunKey    string    `json:"run_key"`
+	Counts    []int     `json:"counts"` // oldest first
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// NewBaselineStore creates a store writing prefix/<escaped run key>.json objects to bucket
+func NewBaselineStore(bucket, prefix string) (*BaselineStore, error) {
+	sess, err := awsclient.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"), // Default region
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AWS session: %w", err)
+	}
+
+	return &BaselineStore{
+		s3Client: s3.New(sess),
+		bucket:   bucket,
+		prefix:   prefix,
+	}, nil
+}
+
+// LoadCounts returns the recent counts of runKey, oldest first; none when nothing is stored
+func (b *BaselineStore) LoadCounts(ctx context.Context, runKey string) ([]int, error) {
+	result, err := b.s3Client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(b.key(runKey)),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchKey {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get collection baseline: %w", err)
+	}
+	defer result.Body.Close()
+
+	data, err := io.ReadAll(result.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read collection baseline: %w", err)
+	}
+
+	var baseline runBaseline
+	if err := json.Unmarshal(data, &baseline); err != nil {
+		return nil, fmt.Errorf("failed to parse collection baseline: %w", err)
+	}
+	return baseline.Counts, nil
+}
+
+// SetKMSKey encrypts the baseline objects with SSE-KMS under keyARN, as every other raw-data
+// bucket write; empty leaves encryption to the bucket default
+func (b *BaselineStore) SetKMSKey(keyARN string) {
+	b.kmsKeyARN = keyARN
+}
+
+// SaveCounts stores the recent counts of runKey
+func (b *BaselineStore) SaveCounts(ctx context.Context, runKey string, counts []int) error {
+	data, err := json.Marshal(&runBaseline{
+		RunKey:    runKey,
+		Counts:    counts,
+		UpdatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal collection baseline: %w", err)
+	}
+
+	input := &s3.PutObjectInput{
+		Bucket:      aws.String(b.bucket),
+		Key:         aws.String(b.key(runKey)),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String("application/json"),
+	}
+	input.ServerSideEncryption, input.SSEKMSKeyId = sseKMS(b.kmsKeyARN)
+	_, err = b.s3Client.PutObjectWithContext(ctx, input)
+	if err != nil {
+		return fmt.Errorf("failed to upload collection baseline: %w", err)
+	}
+	return nil
+}
+
+// key returns the object key of one run key's baseline; run keys of ad-hoc runs hold the
+// search query, so they are escaped into a single path segment
+func (b *BaselineStore) key(runKey string) string {
+	return fmt.Sprintf("%s/%s.json", b.prefix, url.PathEscape(runKey))
+}
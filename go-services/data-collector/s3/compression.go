@@ -0,0 +1,94 @@
+package s3
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Raw-data compression codecs, selected by processing.compression
+const (
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+	CompressionNone = "none"
+)
+
+// SetCompression selects the codec of uploaded payloads; empty keeps gzip
+func (u *Uploader) SetCompression(codec string) error {
+	switch codec {
+	case "":
+		u.compression = CompressionGzip
+	case CompressionGzip, CompressionZstd, CompressionNone:
+		u.compression = codec
+	default:
+		return fmt.Errorf("unknown compression %q (expected %q, %q or %q)", codec, CompressionGzip, CompressionZstd, CompressionNone)
+	}
+	return nil
+}
+
+// keyExtension is the raw-data key extension of the configured codec
+func (u *Uploader) keyExtension() string {
+	switch u.compression {
+	case CompressionZstd:
+		return ".zst"
+	case CompressionNone:
+		return ".json"
+	default:
+		return ".gz"
+	}
+}
+
+// contentHeaders returns the Content-Type and Content-Encoding of an upload. Gzip objects keep
+// the application/gzip type they have always had and no encoding, so HTTP clients of presigned
+// URLs are not handed transparently decompressed bytes.
+func (u *Uploader) contentHeaders() (contentType, contentEncoding *string) {
+	switch u.compression {
+	case CompressionZstd:
+		return aws.String("application/json"), aws.String(CompressionZstd)
+	case CompressionNone:
+		return aws.String("application/json"), nil
+	default:
+		return aws.String("application/gzip"), nil
+	}
+}
+
+// newCompressor wraps w in the configured codec; closing it flushes the codec but not w
+func (u *Uploader) newCompressor(w io.Writer) (io.WriteCloser, error) {
+	switch u.compression {
+	case CompressionZstd:
+		return zstd.NewWriter(w)
+	case CompressionNone:
+		return nopWriteCloser{w}, nil
+	default:
+		return gzip.NewWriter(w), nil
+	}
+}
+
+// nopWriteCloser writes uncompressed payloads
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// compressData compresses data with the configured codec
+func (u *Uploader) compressData(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	writer, err := u.newCompressor(&buf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s writer: %w", u.compression, err)
+	}
+
+	if _, err := writer.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to write to %s writer: %w", u.compression, err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close %s writer: %w", u.compression, err)
+	}
+
+	return buf.Bytes(), nil
+}
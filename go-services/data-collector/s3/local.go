@@ -0,0 +1,85 @@
+package s3
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"data-collector/types"
+)
+
+// WriteLocal writes the objects an upload of the result would create under dir instead of S3,
+// each at its S3 key: the payload, or the parts and parts manifest of a split result. The
+// bucket is neither read nor written; object metadata and tags are not kept.
+func (u *Uploader) WriteLocal(dir string, result *types.CollectionResult) (*UploadResult, error) {
+	if u.Splits(result) {
+		writePart := func(key string, part *types.CollectionResult, _ map[string]*string, streamed bool) (*UploadResult, error) {
+			return u.writeLocalPayload(dir, key, part, streamed)
+		}
+		writeManifest := func(key string, data []byte, _ int) error {
+			return writeLocalFile(dir, key, data)
+		}
+		return u.writeParts(result, writePart, writeManifest)
+	}
+	return u.writeLocalPayload(dir, u.generateS3Key(result.Source, result.Timestamp), result, u.Streams(result))
+}
+
+// LocalPath is the file WriteLocal writes the object key to
+func LocalPath(dir, key string) string {
+	return filepath.Join(dir, filepath.FromSlash(key))
+}
+
+// writeLocalPayload encodes the result as the buffered or streamed upload would, into the
+// file of key
+func (u *Uploader) writeLocalPayload(dir, key string, result *types.CollectionResult, streamed bool) (*UploadResult, error) {
+	if !streamed {
+		prepared, err := u.PrepareUpload(result)
+		if err != nil {
+			return nil, err
+		}
+		if err := writeLocalFile(dir, key, prepared.Data); err != nil {
+			return nil, err
+		}
+		return &UploadResult{
+			S3Key:          key,
+			CompressedSize: prepared.CompressedSize,
+			OriginalSize:   prepared.OriginalSize,
+			Timestamp:      time.Now(),
+		}, nil
+	}
+
+	path := LocalPath(dir, key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output file: %w", err)
+	}
+	written, err := u.writeStream(file, result)
+	if closeErr := file.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to close output file: %w", closeErr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &UploadResult{
+		S3Key:          key,
+		CompressedSize: written.compressed,
+		OriginalSize:   written.original,
+		Timestamp:      time.Now(),
+	}, nil
+}
+
+// writeLocalFile writes data to the file of key, creating its directories
+func writeLocalFile(dir, key string, data []byte) error {
+	path := LocalPath(dir, key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	return nil
+}
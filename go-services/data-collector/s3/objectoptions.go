@@ -0,0 +1,87 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+
+	"data-collector/types"
+	"shared/logger"
+)
+
+// Object tag keys of raw-data payloads, for lifecycle rules and cost allocation
+const (
+	TagSource  = "source"
+	TagDate    = "date"     // collection date, YYYY-MM-DD
+	TagTraceID = "trace-id" // the context's trace ID, or the collection run ID without one
+)
+
+// SetKMSKey encrypts every object the uploader writes with SSE-KMS under keyARN (a key ARN,
+// ID or alias); empty leaves encryption to the bucket default
+func (u *Uploader) SetKMSKey(keyARN string) {
+	u.kmsKeyARN = keyARN
+}
+
+// SetStorageClass writes payloads in the given S3 storage class; empty keeps STANDARD.
+// Manifests stay in STANDARD, being small and read soon after they are written.
+func (u *Uploader) SetStorageClass(class string) error {
+	if class == "" {
+		u.storageClass = ""
+		return nil
+	}
+	for _, known := range s3.StorageClass_Values() {
+		if class == known {
+			u.storageClass = class
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown storage class %q (expected one of %v)", class, s3.StorageClass_Values())
+}
+
+// SetObjectTagging tags payloads and parts manifests with their source, collection date and
+// trace ID
+func (u *Uploader) SetObjectTagging(enabled bool) {
+	u.objectTagging = enabled
+}
+
+// encryption returns the ServerSideEncryption and SSEKMSKeyId of an upload
+func (u *Uploader) encryption() (serverSideEncryption, keyID *string) {
+	return sseKMS(u.kmsKeyARN)
+}
+
+// sseKMS returns the ServerSideEncryption and SSEKMSKeyId of a raw-data bucket write under
+// keyARN; both are nil without a key, so the bucket default applies
+func sseKMS(keyARN string) (serverSideEncryption, keyID *string) {
+	if keyARN == "" {
+		return nil, nil
+	}
+	return aws.String(s3.ServerSideEncryptionAwsKms), aws.String(keyARN)
+}
+
+// payloadStorageClass returns the StorageClass of a payload upload, nil for the default
+func (u *Uploader) payloadStorageClass() *string {
+	if u.storageClass == "" {
+		return nil
+	}
+	return aws.String(u.storageClass)
+}
+
+// tagging returns the URL-encoded tag set of an object holding the result, nil when tagging
+// is off
+func (u *Uploader) tagging(ctx context.Context, result *types.CollectionResult) *string {
+	if !u.objectTagging {
+		return nil
+	}
+	tags := url.Values{}
+	tags.Set(TagSource, result.Source)
+	tags.Set(TagDate, result.Timestamp.Format("2006-01-02"))
+	traceID := logger.TraceIDFromContext(ctx)
+	if traceID == "" {
+		traceID = types.CollectionRunID(result.Source, result.Timestamp)
+	}
+	tags.Set(TagTraceID, traceID)
+	return aws.String(tags.Encode())
+}
@@ -0,0 +1,204 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+
+	"data-collector/types"
+)
+
+// PartsManifestName is the key name of the manifest written next to the parts of a split
+// result. The batch processor skips it, since every part raises its own S3 event.
+const PartsManifestName = "manifest.json"
+
+// S3 user metadata keys of part objects
+const (
+	PartNumberMetadataKey = "part-number"
+	PartCountMetadataKey  = "part-count"
+)
+
+// PartsManifest lists the part objects of a split result. It is written after every part, so
+// a manifest is only ever found next to a complete set of parts.
+type PartsManifest struct {
+	RunID            string                    `json:"run_id"`
+	Source           string                    `json:"source"`
+	Timestamp        time.Time                 `json:"timestamp"`
+	PaperCount       int                       `json:"paper_count"`
+	CategoryFiltered int                       `json:"category_filtered,omitempty"`
+	Metadata         *types.CollectionMetadata `json:"metadata,omitempty"`
+	Stats            *types.CollectionStats    `json:"stats,omitempty"` // of the whole result; parts carry none
+	Parts            []ManifestPart            `json:"parts"`
+}
+
+// ManifestPart describes one part object
+type ManifestPart struct {
+	Key            string `json:"key"`
+	PaperCount     int    `json:"paper_count"`
+	OriginalSize   int64  `json:"original_size"`
+	CompressedSize int64  `json:"compressed_size"`
+	Streamed       bool   `json:"streamed,omitempty"` // written as schema-version 2 JSON lines
+}
+
+// SetMaxPapersPerObject splits results of more than papers papers into part objects of at
+// most papers papers each; 0 never splits
+func (u *Uploader) SetMaxPapersPerObject(papers int) error {
+	if papers < 0 {
+		return fmt.Errorf("max papers per object must not be negative, got %d", papers)
+	}
+	u.maxPapersPerObject = papers
+	return nil
+}
+
+// Splits reports whether the result is large enough to be uploaded with UploadParts
+func (u *Uploader) Splits(result *types.CollectionResult) bool {
+	return u.maxPapersPerObject > 0 && len(result.Papers) > u.maxPapersPerObject
+}
+
+// SplitResult divides the result into parts of at most the configured paper count. Parts keep
+// the source, timestamp and query metadata, so they share the result's run ID; the collection
+// stats describe the whole result and travel in the manifest instead.
+func (u *Uploader) SplitResult(result *types.CollectionResult) []*types.CollectionResult {
+	size := u.maxPapersPerObject
+	if size <= 0 || len(result.Papers) <= size {
+		return []*types.CollectionResult{result}
+	}
+
+	var parts []*types.CollectionResult
+	for start := 0; start < len(result.Papers); start += size {
+		end := start + size
+		if end > len(result.Papers) {
+			end = len(result.Papers)
+		}
+		parts = append(parts, &types.CollectionResult{
+			Papers:    result.Papers[start:end],
+			Source:    result.Source,
+			Count:     end - start,
+			Timestamp: result.Timestamp,
+			Metadata:  result.Metadata,
+		})
+	}
+	return parts
+}
+
+// UploadParts writes each part of the result as its own object, streamed when the part alone
+// reaches the streaming threshold, then the parts manifest. The result's S3Key is the
+// manifest's. A failed part stops the upload before the manifest is written; parts already
+// written are processed like any other object and are harmless to write again.
+func (u *Uploader) UploadParts(ctx context.Context, result *types.CollectionResult) (*UploadResult, error) {
+	writePart := func(key string, part *types.CollectionResult, metadata map[string]*string, streamed bool) (*UploadResult, error) {
+		if streamed {
+			return u.uploadStreamed(ctx, key, part, metadata)
+		}
+		return u.uploadBuffered(ctx, key, part, metadata)
+	}
+	writeManifest := func(key string, data []byte, partCount int) error {
+		input := &s3.PutObjectInput{
+			Bucket:      aws.String(u.bucket),
+			Key:         aws.String(key),
+			Body:        bytes.NewReader(data),
+			ContentType: aws.String("application/json"),
+			Metadata:    map[string]*string{PartCountMetadataKey: aws.String(fmt.Sprintf("%d", partCount))},
+			Tagging:     u.tagging(ctx, result),
+		}
+		input.ServerSideEncryption, input.SSEKMSKeyId = u.encryption()
+		if _, err := u.s3Client.PutObjectWithContext(ctx, input); err != nil {
+			return fmt.Errorf("failed to upload parts manifest: %w", err)
+		}
+		return nil
+	}
+	return u.writeParts(result, writePart, writeManifest)
+}
+
+// writeParts splits the result and hands each part, then the marshaled manifest, to the
+// writers, so S3 uploads and dry runs lay out parts the same way
+func (u *Uploader) writeParts(
+	result *types.CollectionResult,
+	writePart func(key string, part *types.CollectionResult, metadata map[string]*string, streamed bool) (*UploadResult, error),
+	writeManifest func(key string, data []byte, partCount int) error,
+) (*UploadResult, error) {
+	parts := u.SplitResult(result)
+	manifest := &PartsManifest{
+		RunID:            types.CollectionRunID(result.Source, result.Timestamp),
+		Source:           result.Source,
+		Timestamp:        result.Timestamp,
+		PaperCount:       len(result.Papers),
+		CategoryFiltered: result.CategoryFiltered,
+		Metadata:         result.Metadata,
+		Stats:            result.Stats,
+	}
+	uploadResult := &UploadResult{S3Key: u.partsManifestKey(result.Source, result.Timestamp)}
+
+	for i, part := range parts {
+		key := u.partKey(result.Source, result.Timestamp, i+1)
+		metadata := buildObjectMetadata(part)
+		metadata[PartNumberMetadataKey] = aws.String(fmt.Sprintf("%d", i+1))
+		metadata[PartCountMetadataKey] = aws.String(fmt.Sprintf("%d", len(parts)))
+
+		streamed := u.Streams(part)
+		written, err := writePart(key, part, metadata, streamed)
+		if err != nil {
+			return nil, fmt.Errorf("part %d of %d: %w", i+1, len(parts), err)
+		}
+
+		manifest.Parts = append(manifest.Parts, ManifestPart{
+			Key:            key,
+			PaperCount:     part.Count,
+			OriginalSize:   written.OriginalSize,
+			CompressedSize: written.CompressedSize,
+			Streamed:       streamed,
+		})
+		uploadResult.PartKeys = append(uploadResult.PartKeys, key)
+		uploadResult.OriginalSize += written.OriginalSize
+		uploadResult.CompressedSize += written.CompressedSize
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal parts manifest: %w", err)
+	}
+	if err := writeManifest(uploadResult.S3Key, data, len(parts)); err != nil {
+		return nil, err
+	}
+
+	uploadResult.Timestamp = time.Now()
+	return uploadResult, nil
+}
+
+// MeasureParts encodes every part as UploadParts would, without writing to S3, and reports
+// the manifest key and the summed sizes of the parts
+func (u *Uploader) MeasureParts(result *types.CollectionResult) (*PreparedUpload, error) {
+	measured := &PreparedUpload{
+		Bucket: u.bucket,
+		S3Key:  u.partsManifestKey(result.Source, result.Timestamp),
+	}
+	for i, part := range u.SplitResult(result) {
+		prepare := u.PrepareUpload
+		if u.Streams(part) {
+			prepare = u.MeasureStreamed
+		}
+		prepared, err := prepare(part)
+		if err != nil {
+			return nil, fmt.Errorf("part %d: %w", i+1, err)
+		}
+		measured.OriginalSize += prepared.OriginalSize
+		measured.CompressedSize += prepared.CompressedSize
+	}
+	return measured, nil
+}
+
+// partKey is the key of part n, counted from 1: the result's key stem as a directory holding
+// part-0001.gz, part-0002.gz, ... in the configured codec
+func (u *Uploader) partKey(source string, timestamp time.Time, n int) string {
+	return fmt.Sprintf("%s/part-%04d%s", u.keyStem(source, timestamp), n, u.keyExtension())
+}
+
+// partsManifestKey is the key of the manifest next to the parts
+func (u *Uploader) partsManifestKey(source string, timestamp time.Time) string {
+	return u.keyStem(source, timestamp) + "/" + PartsManifestName
+}
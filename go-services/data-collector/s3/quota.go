@@ -0,0 +1,190 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+
+	"shared/awsclient"
+)
+
+// QuotaStore keeps each source's daily request count as a small JSON object, so the
+// collector's quotas hold across Lambda invocations. Every change re-reads the object and
+// writes it back conditioned on the ETag it read (If-None-Match for a new day), retrying on
+// conflict, so concurrent and back-to-back collections never overwrite each other's counts.
+type QuotaStore struct {
+	s3Client  *s3.S3
+	bucket    string
+	prefix    string
+	kmsKeyARN string // SSE-KMS key of the usage objects; empty uses the bucket default
+}
+
+// maxQuotaUpdateAttempts bounds the conditional writes of one count change under contention
+const maxQuotaUpdateAttempts = 10
+
+// quotaUsage is the stored request count of one source on one UTC day
+type quotaUsage struct {
+	Source    string    `json:"source"`
+	Day       string    `json:"day"`
+	Requests  int       `json:"requests"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// NewQuotaStore creates a store writing prefix/source/YYYY-MM-DD.json objects to bucket
+func NewQuotaStore(bucket, prefix string) (*QuotaStore, error) {
+	sess, err := awsclient.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"), // Default region
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AWS session: %w", err)
+	}
+
+	return &QuotaStore{
+		s3Client: s3.New(sess),
+		bucket:   bucket,
+		prefix:   prefix,
+	}, nil
+}
+
+// SetKMSKey encrypts the usage objects with SSE-KMS under keyARN, as every other raw-data
+// bucket write; empty leaves encryption to the bucket default
+func (q *QuotaStore) SetKMSKey(keyARN string) {
+	q.kmsKeyARN = keyARN
+}
+
+// Reserve counts one request of source on day unless quota requests are already counted
+func (q *QuotaStore) Reserve(ctx context.Context, source, day string, quota int) (int, bool, error) {
+	var (
+		used     int
+		reserved bool
+	)
+	err := q.update(ctx, source, day, func(requests int) (int, bool) {
+		used, reserved = requests, requests < quota
+		if reserved {
+			used++
+		}
+		return used, reserved
+	})
+	if err != nil {
+		return 0, false, err
+	}
+	return used, reserved, nil
+}
+
+// Refund takes back a reserved request of source on day that was never sent
+func (q *QuotaStore) Refund(ctx context.Context, source, day string) error {
+	return q.update(ctx, source, day, func(requests int) (int, bool) {
+		if requests <= 0 {
+			return requests, false
+		}
+		return requests - 1, true
+	})
+}
+
+// update applies change to the stored count of source on day with a compare-and-swap:
+// the count is written only if the object is unchanged since it was read. change reports
+// false to leave the count as it is.
+func (q *QuotaStore) update(ctx context.Context, source, day string, change func(requests int) (int, bool)) error {
+	for attempt := 1; attempt <= maxQuotaUpdateAttempts; attempt++ {
+		requests, etag, err := q.load(ctx, source, day)
+		if err != nil {
+			return err
+		}
+		updated, write := change(requests)
+		if !write {
+			return nil
+		}
+		err = q.save(ctx, source, day, updated, etag)
+		if err == nil {
+			return nil
+		}
+		if !isWriteConflict(err) {
+			return err
+		}
+	}
+	return fmt.Errorf("quota usage of %s on %s kept changing after %d attempts", source, day, maxQuotaUpdateAttempts)
+}
+
+// load returns the request count of source on day and the ETag it was read at; both are
+// empty when none is stored
+func (q *QuotaStore) load(ctx context.Context, source, day string) (int, string, error) {
+	result, err := q.s3Client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(q.bucket),
+		Key:    aws.String(q.key(source, day)),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchKey {
+			return 0, "", nil
+		}
+		return 0, "", fmt.Errorf("failed to get quota usage: %w", err)
+	}
+	defer result.Body.Close()
+
+	data, err := io.ReadAll(result.Body)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to read quota usage: %w", err)
+	}
+
+	var usage quotaUsage
+	if err := json.Unmarshal(data, &usage); err != nil {
+		return 0, "", fmt.Errorf("failed to parse quota usage: %w", err)
+	}
+	return usage.Requests, aws.StringValue(result.ETag), nil
+}
+
+// save stores the request count of source on day if the object still has etag, or does not
+// exist yet when etag is empty
+func (q *QuotaStore) save(ctx context.Context, source, day string, requests int, etag string) error {
+	data, err := json.Marshal(&quotaUsage{
+		Source:    source,
+		Day:       day,
+		Requests:  requests,
+		UpdatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal quota usage: %w", err)
+	}
+
+	input := &s3.PutObjectInput{
+		Bucket:      aws.String(q.bucket),
+		Key:         aws.String(q.key(source, day)),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String("application/json"),
+	}
+	input.ServerSideEncryption, input.SSEKMSKeyId = sseKMS(q.kmsKeyARN)
+	req, _ := q.s3Client.PutObjectRequest(input)
+	req.SetContext(ctx)
+	// The SDK's PutObjectInput predates S3 conditional writes, so the headers are set directly
+	if etag == "" {
+		req.HTTPRequest.Header.Set("If-None-Match", "*")
+	} else {
+		req.HTTPRequest.Header.Set("If-Match", etag)
+	}
+	if err := req.Send(); err != nil {
+		return fmt.Errorf("failed to upload quota usage: %w", err)
+	}
+	return nil
+}
+
+// isWriteConflict reports whether a conditional write lost to a concurrent one
+func isWriteConflict(err error) bool {
+	var failure awserr.RequestFailure
+	if !errors.As(err, &failure) {
+		return false
+	}
+	return failure.StatusCode() == http.StatusPreconditionFailed || failure.StatusCode() == http.StatusConflict
+}
+
+// key returns the object key of one source's usage on day
+func (q *QuotaStore) key(source, day string) string {
+	return fmt.Sprintf("%s/%s/%s.json", q.prefix, source, day)
+}
@@ -0,0 +1,187 @@
+package s3
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+
+	"data-collector/types"
+)
+
+// StreamedSchemaVersion is the schema of objects written by UploadStreamed: JSON lines of a
+// header, one paper per line, and a trailer, compressed with the uploader's codec
+const StreamedSchemaVersion = "2"
+
+// Multipart settings of streamed uploads. At most streamPartSize * streamConcurrency bytes
+// of compressed payload are buffered, however many papers the result holds.
+const (
+	streamPartSize    = 8 * 1024 * 1024
+	streamConcurrency = 2
+)
+
+// StreamHeader is the first line of a streamed payload: the collection result without its
+// papers. Count is the number of paper lines that follow.
+type StreamHeader struct {
+	Source           string                    `json:"source"`
+	Count            int                       `json:"count"`
+	Timestamp        time.Time                 `json:"timestamp"`
+	CategoryFiltered int                       `json:"category_filtered,omitempty"`
+	Metadata         *types.CollectionMetadata `json:"metadata,omitempty"`
+	Stats            *types.CollectionStats    `json:"stats,omitempty"`
+}
+
+// StreamTrailer is the last line of a streamed payload. The checksum of a streamed object
+// is not known when its metadata is written, so it travels in the payload instead:
+// PayloadSHA256 covers every byte before the trailer line.
+type StreamTrailer struct {
+	PaperCount    int    `json:"paper_count"`
+	PayloadSHA256 string `json:"payload_sha256"`
+}
+
+// SetStreamingThreshold streams results of at least papers papers with UploadStreamed
+// instead of buffering them; 0 never streams
+func (u *Uploader) SetStreamingThreshold(papers int) error {
+	if papers < 0 {
+		return fmt.Errorf("streaming threshold must not be negative, got %d", papers)
+	}
+	u.streamingThreshold = papers
+	return nil
+}
+
+// Streams reports whether the result is large enough to be uploaded with UploadStreamed
+func (u *Uploader) Streams(result *types.CollectionResult) bool {
+	return u.streamingThreshold > 0 && len(result.Papers) >= u.streamingThreshold
+}
+
+// UploadStreamed encodes the result as JSON lines and compresses it straight into a multipart
+// upload, so memory stays bounded by the part buffers rather than the payload size
+func (u *Uploader) UploadStreamed(ctx context.Context, result *types.CollectionResult) (*UploadResult, error) {
+	return u.uploadStreamed(ctx, u.generateS3Key(result.Source, result.Timestamp), result, buildObjectMetadata(result))
+}
+
+// uploadStreamed streams the result to key
+func (u *Uploader) uploadStreamed(ctx context.Context, s3Key string, result *types.CollectionResult, metadata map[string]*string) (*UploadResult, error) {
+	metadata[SchemaVersionMetadataKey] = aws.String(StreamedSchemaVersion)
+
+	pipeReader, pipeWriter := io.Pipe()
+	sizes := make(chan streamSizes, 1)
+	go func() {
+		written, err := u.writeStream(pipeWriter, result)
+		sizes <- written
+		pipeWriter.CloseWithError(err)
+	}()
+
+	uploader := s3manager.NewUploaderWithClient(u.s3Client, func(uploader *s3manager.Uploader) {
+		uploader.PartSize = streamPartSize
+		uploader.Concurrency = streamConcurrency
+	})
+	input := &s3manager.UploadInput{
+		Bucket:       aws.String(u.bucket),
+		Key:          aws.String(s3Key),
+		Body:         pipeReader,
+		Metadata:     metadata,
+		StorageClass: u.payloadStorageClass(),
+		Tagging:      u.tagging(ctx, result),
+	}
+	input.ContentType, input.ContentEncoding = u.contentHeaders()
+	input.ServerSideEncryption, input.SSEKMSKeyId = u.encryption()
+	_, err := uploader.UploadWithContext(ctx, input)
+	// Unblock the encoder if the upload gave up before reading everything
+	pipeReader.CloseWithError(fmt.Errorf("upload stopped"))
+	written := <-sizes
+	if err != nil {
+		return nil, fmt.Errorf("failed to stream upload to S3: %w", err)
+	}
+
+	return &UploadResult{
+		S3Key:          s3Key,
+		CompressedSize: written.compressed,
+		OriginalSize:   written.original,
+		Timestamp:      time.Now(),
+	}, nil
+}
+
+// MeasureStreamed encodes the result as UploadStreamed would, without writing to S3 or
+// holding the payload, and reports its sizes; Data is left empty
+func (u *Uploader) MeasureStreamed(result *types.CollectionResult) (*PreparedUpload, error) {
+	written, err := u.writeStream(io.Discard, result)
+	if err != nil {
+		return nil, err
+	}
+	return &PreparedUpload{
+		Bucket:         u.bucket,
+		S3Key:          u.generateS3Key(result.Source, result.Timestamp),
+		OriginalSize:   written.original,
+		CompressedSize: written.compressed,
+		PayloadSHA256:  written.checksum,
+	}, nil
+}
+
+// streamSizes are the byte counts of an encoded stream and the checksum of its trailer
+type streamSizes struct {
+	original   int64
+	compressed int64
+	checksum   string
+}
+
+// writeStream writes the compressed JSON lines of the result to w
+func (u *Uploader) writeStream(w io.Writer, result *types.CollectionResult) (streamSizes, error) {
+	var written streamSizes
+	compressedCounter := &countingWriter{writer: w}
+	compressor, err := u.newCompressor(compressedCounter)
+	if err != nil {
+		return written, fmt.Errorf("failed to create %s writer: %w", u.compression, err)
+	}
+	originalCounter := &countingWriter{writer: compressor}
+	checksum := sha256.New()
+	encoder := json.NewEncoder(io.MultiWriter(originalCounter, checksum))
+
+	header := StreamHeader{
+		Source:           result.Source,
+		Count:            len(result.Papers),
+		Timestamp:        result.Timestamp,
+		CategoryFiltered: result.CategoryFiltered,
+		Metadata:         result.Metadata,
+		Stats:            result.Stats,
+	}
+	if err := encoder.Encode(header); err != nil {
+		return written, fmt.Errorf("failed to encode stream header: %w", err)
+	}
+	for i := range result.Papers {
+		if err := encoder.Encode(&result.Papers[i]); err != nil {
+			return written, fmt.Errorf("failed to encode paper %s: %w", result.Papers[i].ID, err)
+		}
+	}
+
+	written.checksum = hex.EncodeToString(checksum.Sum(nil))
+	trailer := StreamTrailer{PaperCount: len(result.Papers), PayloadSHA256: written.checksum}
+	if err := json.NewEncoder(originalCounter).Encode(trailer); err != nil {
+		return written, fmt.Errorf("failed to encode stream trailer: %w", err)
+	}
+	if err := compressor.Close(); err != nil {
+		return written, fmt.Errorf("failed to close %s writer: %w", u.compression, err)
+	}
+
+	written.original = originalCounter.count
+	written.compressed = compressedCounter.count
+	return written, nil
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	writer io.Writer
+	count  int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.writer.Write(p)
+	w.count += int64(n)
+	return n, err
+}
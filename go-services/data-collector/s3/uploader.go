@@ -4,27 +4,48 @@ import (
 	"bytes"
 	"compress/gzip"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"time"
+	"unicode/utf8"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 
 	"data-collector/types"
+	"shared/awsclient"
 )
 
 // Uploader handles S3 upload operations
 type Uploader struct {
-	s3Client *s3.S3
-	bucket   string
-	prefix   string
+	s3Client    *s3.S3
+	bucket      string
+	prefix      string
+	keyLayout   string
+	compression string
+	// streamingThreshold is the paper count from which results are streamed; 0 never streams
+	streamingThreshold int
+	// maxPapersPerObject splits larger results into part objects; 0 never splits
+	maxPapersPerObject int
+	kmsKeyARN          string // SSE-KMS key of every object written; empty uses the bucket default
+	storageClass       string // of payloads; empty is STANDARD
+	objectTagging      bool
 }
 
+// Raw-data key layouts
+const (
+	// KeyLayoutLegacy is prefix/YYYY-MM-DD/source-papers-YYYYMMDD-HHMMSS.gz
+	KeyLayoutLegacy = "legacy"
+	// KeyLayoutHive is prefix/source=arxiv/dt=YYYY-MM-DD/source-papers-YYYYMMDD-HHMMSS.gz,
+	// partitions Athena and lifecycle rules can target directly
+	KeyLayoutHive = "hive"
+)
+
 // NewUploader creates a new S3 uploader
 func NewUploader(bucket, prefix string) (*Uploader, error) {
-	sess, err := session.NewSession(&aws.Config{
+	sess, err := awsclient.NewSession(&aws.Config{
 		Region: aws.String("us-east-1"), // Default region
 	})
 	if err != nil {
@@ -32,22 +53,70 @@ func NewUploader(bucket, prefix string) (*Uploader, error) {
 	}
 
 	return &Uploader{
-		s3Client: s3.New(sess),
-		bucket:   bucket,
-		prefix:   prefix,
+		s3Client:    s3.New(sess),
+		bucket:      bucket,
+		prefix:      prefix,
+		keyLayout:   KeyLayoutLegacy,
+		compression: CompressionGzip,
 	}, nil
 }
 
+// Bucket returns the bucket the uploader writes to
+func (u *Uploader) Bucket() string {
+	return u.bucket
+}
+
+// SetKeyLayout selects the raw-data key layout; empty keeps the legacy layout
+func (u *Uploader) SetKeyLayout(layout string) error {
+	switch layout {
+	case "":
+		u.keyLayout = KeyLayoutLegacy
+	case KeyLayoutLegacy, KeyLayoutHive:
+		u.keyLayout = layout
+	default:
+		return fmt.Errorf("unknown key layout %q (expected %q or %q)", layout, KeyLayoutLegacy, KeyLayoutHive)
+	}
+	return nil
+}
+
 // UploadResult represents the result of an S3 upload operation
 type UploadResult struct {
 	S3Key          string    `json:"s3_key"`
 	CompressedSize int64     `json:"compressed_size"`
 	OriginalSize   int64     `json:"original_size"`
 	Timestamp      time.Time `json:"timestamp"`
+	// PartKeys are the part objects of a split result, whose S3Key is the parts manifest
+	PartKeys []string `json:"part_keys,omitempty"`
 }
 
-// UploadCompressedData uploads compressed data to S3 with timestamp-based naming
-func (u *Uploader) UploadCompressedData(ctx context.Context, result *types.CollectionResult) (*UploadResult, error) {
+// PayloadChecksumMetadataKey is the S3 user metadata key holding the hex SHA-256 of the
+// uncompressed payload; the batch processor verifies it after decompression
+const PayloadChecksumMetadataKey = "payload-sha256"
+
+// SchemaVersionMetadataKey tags each raw-data object with the payload schema it was written in,
+// so the batch processor can pick the matching parser while format migrations roll out
+const SchemaVersionMetadataKey = "schema-version"
+
+// RunIDMetadataKey tags each raw-data object with its collection run ID, which the batch
+// processor carries onto the papers so every vector can be traced back to its run
+const RunIDMetadataKey = "run-id"
+
+// PayloadSchemaVersion is the schema of objects written by PrepareUpload: one JSON-encoded
+// CollectionResult
+const PayloadSchemaVersion = "1"
+
+// PreparedUpload holds a compressed payload and its destination, ready to be uploaded
+type PreparedUpload struct {
+	Bucket         string
+	S3Key          string
+	Data           []byte
+	OriginalSize   int64
+	CompressedSize int64
+	PayloadSHA256  string // of the uncompressed JSON
+}
+
+// PrepareUpload serializes and compresses a collection result without writing to S3
+func (u *Uploader) PrepareUpload(result *types.CollectionResult) (*PreparedUpload, error) {
 	// Generate S3 key with timestamp
 	s3Key := u.generateS3Key(result.Source, result.Timestamp)
 
@@ -63,18 +132,42 @@ func (u *Uploader) UploadCompressedData(ctx context.Context, result *types.Colle
 		return nil, fmt.Errorf("failed to compress data: %w", err)
 	}
 
+	checksum := sha256.Sum256(jsonData)
+	return &PreparedUpload{
+		Bucket:         u.bucket,
+		S3Key:          s3Key,
+		Data:           compressedData,
+		OriginalSize:   int64(len(jsonData)),
+		CompressedSize: int64(len(compressedData)),
+		PayloadSHA256:  hex.EncodeToString(checksum[:]),
+	}, nil
+}
+
+// UploadCompressedData uploads compressed data to S3 with timestamp-based naming
+func (u *Uploader) UploadCompressedData(ctx context.Context, result *types.CollectionResult) (*UploadResult, error) {
+	return u.uploadBuffered(ctx, u.generateS3Key(result.Source, result.Timestamp), result, buildObjectMetadata(result))
+}
+
+// uploadBuffered marshals and compresses the result in memory and writes it to key
+func (u *Uploader) uploadBuffered(ctx context.Context, s3Key string, result *types.CollectionResult, metadata map[string]*string) (*UploadResult, error) {
+	prepared, err := u.PrepareUpload(result)
+	if err != nil {
+		return nil, err
+	}
+
 	// Upload to S3
+	metadata[PayloadChecksumMetadataKey] = aws.String(prepared.PayloadSHA256)
+	metadata[SchemaVersionMetadataKey] = aws.String(PayloadSchemaVersion)
 	input := &s3.PutObjectInput{
-		Bucket:      aws.String(u.bucket),
-		Key:         aws.String(s3Key),
-		Body:        bytes.NewReader(compressedData),
-		ContentType: aws.String("application/gzip"),
-		Metadata: map[string]*string{
-			"source":         aws.String(result.Source),
-			"paper-count":    aws.String(fmt.Sprintf("%d", result.Count)),
-			"collection-time": aws.String(result.Timestamp.Format(time.RFC3339)),
-		},
+		Bucket:       aws.String(u.bucket),
+		Key:          aws.String(s3Key),
+		Body:         bytes.NewReader(prepared.Data),
+		Metadata:     metadata,
+		StorageClass: u.payloadStorageClass(),
+		Tagging:      u.tagging(ctx, result),
 	}
+	input.ContentType, input.ContentEncoding = u.contentHeaders()
+	input.ServerSideEncryption, input.SSEKMSKeyId = u.encryption()
 
 	_, err = u.s3Client.PutObjectWithContext(ctx, input)
 	if err != nil {
@@ -83,37 +176,111 @@ func (u *Uploader) UploadCompressedData(ctx context.Context, result *types.Colle
 
 	return &UploadResult{
 		S3Key:          s3Key,
-		CompressedSize: int64(len(compressedData)),
-		OriginalSize:   int64(len(jsonData)),
+		CompressedSize: prepared.CompressedSize,
+		OriginalSize:   prepared.OriginalSize,
 		Timestamp:      time.Now(),
 	}, nil
 }
 
-// generateS3Key generates a timestamp-based S3 key
-func (u *Uploader) generateS3Key(source string, timestamp time.Time) string {
-	// Format: raw-data/YYYY-MM-DD/source-papers-YYYYMMDD-HHMMSS.gz
-	dateStr := timestamp.Format("2006-01-02")
-	timestampStr := timestamp.Format("20060102-150405")
-	
-	return fmt.Sprintf("%s/%s/%s-papers-%s.gz", u.prefix, dateStr, source, timestampStr)
-}
+// WriteRunManifest writes a run manifest as JSON under prefix and returns its key.
+// Keys follow the raw-data layout: prefix/YYYY-MM-DD/run-id.json
+func (u *Uploader) WriteRunManifest(ctx context.Context, prefix string, manifest *types.RunManifest) (string, error) {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal run manifest: %w", err)
+	}
 
-// compressData compresses data using gzip
-func (u *Uploader) compressData(data []byte) ([]byte, error) {
-	var buf bytes.Buffer
-	gzipWriter := gzip.NewWriter(&buf)
-	
-	_, err := gzipWriter.Write(data)
+	key := fmt.Sprintf("%s/%s/%s.json", prefix, manifest.CollectedAt.Format("2006-01-02"), manifest.RunID)
+	input := &s3.PutObjectInput{
+		Bucket:      aws.String(u.bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String("application/json"),
+	}
+	input.ServerSideEncryption, input.SSEKMSKeyId = u.encryption()
+	_, err = u.s3Client.PutObjectWithContext(ctx, input)
 	if err != nil {
-		return nil, fmt.Errorf("failed to write to gzip writer: %w", err)
+		return "", fmt.Errorf("failed to upload run manifest: %w", err)
+	}
+
+	return key, nil
+}
+
+// maxMetadataQueryLength keeps user metadata well under the 2 KB S3 header limit
+const maxMetadataQueryLength = 512
+
+// buildObjectMetadata converts a collection result into S3 user metadata
+func buildObjectMetadata(result *types.CollectionResult) map[string]*string {
+	metadata := map[string]*string{
+		"source":          aws.String(result.Source),
+		"paper-count":     aws.String(fmt.Sprintf("%d", result.Count)),
+		"collection-time": aws.String(result.Timestamp.Format(time.RFC3339)),
+		RunIDMetadataKey:  aws.String(types.CollectionRunID(result.Source, result.Timestamp)),
+	}
+
+	if info := result.Metadata; info != nil {
+		query := info.Query
+		if len(query) > maxMetadataQueryLength {
+			// Cut at a rune boundary so a multi-byte character is never split
+			cut := maxMetadataQueryLength
+			for cut > 0 && !utf8.RuneStart(query[cut]) {
+				cut--
+			}
+			query = query[:cut]
+		}
+		metadata["query"] = aws.String(query)
+		metadata["start-index"] = aws.String(fmt.Sprintf("%d", info.StartIndex))
+		metadata["max-results"] = aws.String(fmt.Sprintf("%d", info.MaxResults))
+		metadata["total-results"] = aws.String(fmt.Sprintf("%d", info.TotalResults))
+		metadata["has-more"] = aws.String(fmt.Sprintf("%t", info.HasMore))
+		metadata["api-latency-ms"] = aws.String(fmt.Sprintf("%d", info.APILatencyMs))
+		if info.DateFrom != nil {
+			metadata["date-from"] = aws.String(info.DateFrom.Format("2006-01-02"))
+		}
+		if info.DateTo != nil {
+			metadata["date-to"] = aws.String(info.DateTo.Format("2006-01-02"))
+		}
+	}
+
+	return metadata
+}
+
+// GeneratePresignedURL returns a time-limited GET URL for an object in the uploader's bucket
+func (u *Uploader) GeneratePresignedURL(key string, ttl time.Duration) (string, error) {
+	if ttl <= 0 {
+		return "", fmt.Errorf("presigned URL TTL must be positive, got %s", ttl)
 	}
-	
-	err = gzipWriter.Close()
+
+	req, _ := u.s3Client.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: aws.String(u.bucket),
+		Key:    aws.String(key),
+	})
+
+	url, err := req.Presign(ttl)
 	if err != nil {
-		return nil, fmt.Errorf("failed to close gzip writer: %w", err)
+		return "", fmt.Errorf("failed to presign URL for %s: %w", key, err)
 	}
-	
-	return buf.Bytes(), nil
+
+	return url, nil
+}
+
+// generateS3Key generates a timestamp-based S3 key in the configured layout
+func (u *Uploader) generateS3Key(source string, timestamp time.Time) string {
+	// Format: raw-data/YYYY-MM-DD/source-papers-YYYYMMDD-HHMMSS.gz (.zst for zstd, .json uncompressed)
+	return u.keyStem(source, timestamp) + u.keyExtension()
+}
+
+// keyStem is the raw-data key of a result without its codec extension
+func (u *Uploader) keyStem(source string, timestamp time.Time) string {
+	dateStr := timestamp.Format("2006-01-02")
+	timestampStr := timestamp.Format("20060102-150405")
+
+	if u.keyLayout == KeyLayoutHive {
+		// Format: raw-data/source=arxiv/dt=YYYY-MM-DD/source-papers-YYYYMMDD-HHMMSS
+		return fmt.Sprintf("%s/source=%s/dt=%s/%s-papers-%s", u.prefix, source, dateStr, source, timestampStr)
+	}
+	// Format: raw-data/YYYY-MM-DD/source-papers-YYYYMMDD-HHMMSS
+	return fmt.Sprintf("%s/%s/%s-papers-%s", u.prefix, dateStr, source, timestampStr)
 }
 
 // DecompressData decompresses gzip data (utility function for testing)
@@ -155,4 +322,4 @@ func (u *Uploader) CheckS3KeyExists(ctx context.Context, key string) (bool, erro
 // isNoSuchKeyError checks if the error is a NoSuchKey error
 func isNoSuchKeyError(err error) bool {
 	return err != nil && (err.Error() == "NoSuchKey" || err.Error() == "NotFound")
-}
\ No newline at end of file
+}
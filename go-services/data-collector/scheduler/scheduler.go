@@ -0,0 +1,201 @@
+// Package scheduler gates collector requests per data source: a cap on concurrent requests,
+// a daily request quota and a cooldown after a failed request, so aggressive backfills cannot
+// exhaust an API's goodwill.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrQuotaExhausted is returned once a source has used its daily request quota
+	ErrQuotaExhausted = errors.New("daily request quota exhausted")
+	// ErrCoolingDown is returned while a source is in its cooldown period after a failed request
+	ErrCoolingDown = errors.New("source is cooling down")
+)
+
+// Limits are the request controls of one data source; zero values disable each control
+type Limits struct {
+	MaxConcurrent int
+	DailyQuota    int
+	Cooldown      time.Duration
+}
+
+// UsageStore counts daily requests shared by every process, so a quota holds across Lambda
+// invocations and server restarts. Reservations must be atomic against concurrent callers.
+// Day is a UTC date (YYYY-MM-DD).
+type UsageStore interface {
+	// Reserve counts one request of source on day unless quota requests are already
+	// counted, and returns the count
+	Reserve(ctx context.Context, source, day string, quota int) (used int, ok bool, err error)
+	// Refund takes back a reserved request that was never sent
+	Refund(ctx context.Context, source, day string) error
+}
+
+// Scheduler hands out request permits per source. It is safe for concurrent use and is
+// meant to live for the whole process, so limits apply across concurrent collections.
+type Scheduler struct {
+	mu      sync.Mutex
+	store   UsageStore
+	sources map[string]*sourceState
+	now     func() time.Time
+}
+
+// sourceState is the scheduling state of one source
+type sourceState struct {
+	limits        Limits
+	slots         chan struct{} // nil when concurrency is unlimited
+	day           string
+	used          int // requests counted on day; only tracked here without a usage store
+	cooldownUntil time.Time
+}
+
+// New creates a scheduler; a nil store keeps daily usage in memory only
+func New(store UsageStore) *Scheduler {
+	return &Scheduler{
+		store:   store,
+		sources: make(map[string]*sourceState),
+		now:     time.Now,
+	}
+}
+
+// SetUsageStore replaces the usage store, which counts every request from then on
+func (s *Scheduler) SetUsageStore(store UsageStore) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.store = store
+}
+
+// Configure sets the limits of a source. Changing the concurrency cap takes effect for new
+// permits; permits already held release into the slots they were taken from.
+func (s *Scheduler) Configure(source string, limits Limits) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state := s.state(source)
+	if limits.MaxConcurrent != state.limits.MaxConcurrent {
+		state.slots = nil
+		if limits.MaxConcurrent > 0 {
+			state.slots = make(chan struct{}, limits.MaxConcurrent)
+		}
+	}
+	state.limits = limits
+}
+
+// Permit is one admitted request; Release must be called once the request finishes
+type Permit struct {
+	scheduler *Scheduler
+	source    string
+	slots     chan struct{}
+}
+
+// Acquire admits one request to source. It fails fast with ErrCoolingDown or
+// ErrQuotaExhausted, and otherwise waits for a free concurrency slot until ctx is done.
+// An admitted request counts against the daily quota whether or not it succeeds.
+func (s *Scheduler) Acquire(ctx context.Context, source string) (*Permit, error) {
+	s.mu.Lock()
+	state := s.state(source)
+	now := s.now()
+
+	if now.Before(state.cooldownUntil) {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("%w: %s until %s", ErrCoolingDown, source, state.cooldownUntil.UTC().Format(time.RFC3339))
+	}
+
+	day := now.UTC().Format("2006-01-02")
+	quota := state.limits.DailyQuota
+	store := s.store
+	slots := state.slots
+	if quota > 0 && store == nil {
+		if state.day != day {
+			state.day = day
+			state.used = 0
+		}
+		if state.used >= quota {
+			s.mu.Unlock()
+			return nil, quotaExhausted(source, state.used, quota, day)
+		}
+		state.used++
+	}
+	s.mu.Unlock()
+
+	// Reserve the quota before waiting, so concurrent callers cannot overshoot it
+	if quota > 0 && store != nil {
+		used, ok, err := store.Reserve(ctx, source, day, quota)
+		if err != nil {
+			return nil, fmt.Errorf("failed to reserve request quota for %s: %w", source, err)
+		}
+		if !ok {
+			return nil, quotaExhausted(source, used, quota, day)
+		}
+	}
+
+	if slots != nil {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			if quota > 0 {
+				s.refund(context.WithoutCancel(ctx), store, source, day)
+			}
+			return nil, ctx.Err()
+		}
+	}
+
+	return &Permit{scheduler: s, source: source, slots: slots}, nil
+}
+
+// quotaExhausted describes a rejected request of source
+func quotaExhausted(source string, used, quota int, day string) error {
+	return fmt.Errorf("%w: %s used %d of %d requests on %s", ErrQuotaExhausted, source, used, quota, day)
+}
+
+// Release frees the permit's concurrency slot. A non-nil err other than a context
+// cancellation starts the source's cooldown period.
+func (p *Permit) Release(err error) {
+	if p.slots != nil {
+		<-p.slots
+	}
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return
+	}
+
+	s := p.scheduler
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state := s.state(p.source)
+	if state.limits.Cooldown > 0 {
+		state.cooldownUntil = s.now().Add(state.limits.Cooldown)
+	}
+}
+
+// state returns the state of source, creating it on first use; callers hold s.mu
+func (s *Scheduler) state(source string) *sourceState {
+	state, ok := s.sources[source]
+	if !ok {
+		state = &sourceState{}
+		s.sources[source] = state
+	}
+	return state
+}
+
+// refund returns a reserved request that was never sent, unless the day has rolled over. A
+// failed refund leaves the request counted, erring on the side of the quota.
+func (s *Scheduler) refund(ctx context.Context, store UsageStore, source, day string) {
+	if store != nil {
+		_ = store.Refund(ctx, source, day)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state := s.state(source)
+	if state.day == day && state.used > 0 {
+		state.used--
+	}
+}
@@ -0,0 +1,301 @@
+package semanticscholar
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"data-collector/apiclient"
+	"data-collector/types"
+	"shared/awsclient"
+)
+
+// Source is the data source name of Semantic Scholar papers
+const Source = "semantic_scholar"
+
+// APIKeyEnv overrides the api_key of the semantic_scholar data source
+const APIKeyEnv = "SEMANTIC_SCHOLAR_API_KEY"
+
+// searchFields are the paper fields requested from the bulk search endpoint
+const searchFields = "paperId,externalIds,url,title,abstract,year,publicationDate,authors,fieldsOfStudy,s2FieldsOfStudy"
+
+// maxPageSize is the most papers the bulk search endpoint returns per request
+const maxPageSize = 1000
+
+// Client represents a Semantic Scholar Academic Graph API client
+type Client struct {
+	httpClient *http.Client
+	baseURL    string
+	apiKey     string
+	limiter    *apiclient.RateLimiter
+	retry      apiclient.RetryPolicy
+}
+
+// NewClient creates a new Semantic Scholar client for the bulk search endpoint at baseURL.
+// An empty apiKey uses the shared unauthenticated rate limit.
+func NewClient(baseURL, apiKey string, rateLimitPerSecond int) *Client {
+	return &Client{
+		httpClient: awsclient.HTTPClient(&http.Client{
+			Timeout: 30 * time.Second,
+		}),
+		baseURL: baseURL,
+		apiKey:  apiKey,
+		limiter: apiclient.NewRateLimiter(rateLimitPerSecond),
+	}
+}
+
+// SetRetryPolicy sets how failed page requests are retried; by default they are not
+func (c *Client) SetRetryPolicy(policy apiclient.RetryPolicy) {
+	c.retry = policy
+}
+
+// SearchParams represents search parameters for the bulk search endpoint
+type SearchParams struct {
+	Query      string
+	MaxResults int
+	DateFrom   *time.Time    // Optional: publication date from (inclusive)
+	DateTo     *time.Time    // Optional: publication date to (inclusive)
+	PageDelay  time.Duration // pause between pages, on top of the rate limit; the page size is fixed by the API
+	Gate       apiclient.RequestGate
+}
+
+// searchResponse is one page of the bulk search endpoint
+type searchResponse struct {
+	Total int     `json:"total"`
+	Token string  `json:"token"`
+	Data  []paper `json:"data"`
+}
+
+type paper struct {
+	PaperID         string         `json:"paperId"`
+	ExternalIDs     map[string]any `json:"externalIds"`
+	URL             string         `json:"url"`
+	Title           string         `json:"title"`
+	Abstract        string         `json:"abstract"`
+	Year            int            `json:"year"`
+	PublicationDate string         `json:"publicationDate"`
+	Authors         []author       `json:"authors"`
+	FieldsOfStudy   []string       `json:"fieldsOfStudy"`
+	S2FieldsOfStudy []fieldOfStudy `json:"s2FieldsOfStudy"`
+}
+
+type author struct {
+	Name string `json:"name"`
+}
+
+type fieldOfStudy struct {
+	Category string `json:"category"`
+}
+
+// Search pages through the bulk search endpoint until MaxResults papers are collected or the
+// results run out
+func (c *Client) Search(ctx context.Context, params SearchParams) (*types.CollectionResult, error) {
+	var (
+		papers     []types.Paper
+		token      string
+		total      int
+		pages      int
+		apiLatency time.Duration
+		firstURL   string
+		skipped    int
+	)
+
+	for {
+		queryURL, err := c.buildQueryURL(params, token)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build query URL: %w", err)
+		}
+		if firstURL == "" {
+			firstURL = queryURL
+		}
+
+		if pages > 0 && params.PageDelay > 0 {
+			if err := apiclient.Sleep(ctx, params.PageDelay); err != nil {
+				return nil, fmt.Errorf("page delay failed: %w", err)
+			}
+		}
+
+		requestStart := time.Now()
+		page, err := c.fetchPage(ctx, params.Gate, queryURL)
+		if err != nil {
+			return nil, fmt.Errorf("page %d: %w", pages+1, err)
+		}
+		apiLatency += time.Since(requestStart)
+		pages++
+		total = page.Total
+
+		for _, entry := range page.Data {
+			converted, err := convertPaper(entry)
+			if err != nil {
+				// Skip papers without an ID or title rather than failing the run
+				skipped++
+				continue
+			}
+			papers = append(papers, converted)
+		}
+
+		token = page.Token
+		if token == "" || len(page.Data) == 0 || (params.MaxResults > 0 && len(papers) >= params.MaxResults) {
+			break
+		}
+	}
+
+	if params.MaxResults > 0 && len(papers) > params.MaxResults {
+		papers = papers[:params.MaxResults]
+	}
+
+	return &types.CollectionResult{
+		Papers:    papers,
+		Source:    Source,
+		Count:     len(papers),
+		Timestamp: time.Now(),
+		Metadata: &types.CollectionMetadata{
+			Query:          params.Query,
+			RequestURL:     firstURL,
+			DateFrom:       params.DateFrom,
+			DateTo:         params.DateTo,
+			MaxResults:     params.MaxResults,
+			TotalResults:   total,
+			ItemsPerPage:   maxPageSize,
+			HasMore:        token != "",
+			APILatencyMs:   apiLatency.Milliseconds(),
+			SkippedEntries: skipped,
+			Pages:          pages,
+		},
+	}, nil
+}
+
+// fetchPage requests one page, admitted through gate when one is set
+func (c *Client) fetchPage(ctx context.Context, gate apiclient.RequestGate, queryURL string) (*searchResponse, error) {
+	body, _, err := apiclient.Fetch(ctx, gate, c.limiter, c.retry, c.httpClient, queryURL, c.header())
+	if err != nil {
+		return nil, err
+	}
+
+	var page searchResponse
+	if err := json.Unmarshal(body, &page); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON response: %w", err)
+	}
+	return &page, nil
+}
+
+// header carries the API key, when one is set
+func (c *Client) header() http.Header {
+	header := http.Header{}
+	if c.apiKey != "" {
+		header.Set("x-api-key", c.apiKey)
+	}
+	return header
+}
+
+// buildQueryURL constructs the bulk search URL, continuing after token when it is set
+func (c *Client) buildQueryURL(params SearchParams, token string) (string, error) {
+	baseURL, err := url.Parse(c.baseURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid base URL: %w", err)
+	}
+
+	query := baseURL.Query()
+	query.Set("query", params.Query)
+	query.Set("fields", searchFields)
+	query.Set("sort", "publicationDate:desc")
+	if dateRange := buildDateRange(params.DateFrom, params.DateTo); dateRange != "" {
+		query.Set("publicationDateOrYear", dateRange)
+	}
+	if token != "" {
+		query.Set("token", token)
+	}
+
+	baseURL.RawQuery = query.Encode()
+	return baseURL.String(), nil
+}
+
+// buildDateRange formats the publicationDateOrYear filter, an open-ended range when only one
+// date is set
+func buildDateRange(dateFrom, dateTo *time.Time) string {
+	if dateFrom == nil && dateTo == nil {
+		return ""
+	}
+	var from, to string
+	if dateFrom != nil {
+		from = dateFrom.Format("2006-01-02")
+	}
+	if dateTo != nil {
+		to = dateTo.Format("2006-01-02")
+	}
+	return from + ":" + to
+}
+
+// convertPaper normalizes a Semantic Scholar paper into a Paper. The publication year stands
+// in for papers without a publication date, and categories are the fields of study.
+func convertPaper(entry paper) (types.Paper, error) {
+	if entry.PaperID == "" || strings.TrimSpace(entry.Title) == "" {
+		return types.Paper{}, fmt.Errorf("paper is missing its ID or title")
+	}
+
+	var publishedDate time.Time
+	if entry.PublicationDate != "" {
+		parsed, err := time.Parse("2006-01-02", entry.PublicationDate)
+		if err != nil {
+			return types.Paper{}, fmt.Errorf("failed to parse publication date: %w", err)
+		}
+		publishedDate = parsed
+	} else if entry.Year > 0 {
+		publishedDate = time.Date(entry.Year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	authors := make([]string, 0, len(entry.Authors))
+	for _, a := range entry.Authors {
+		if name := strings.TrimSpace(a.Name); name != "" {
+			authors = append(authors, name)
+		}
+	}
+
+	return types.Paper{
+		ID:            entry.PaperID,
+		Source:        Source,
+		Title:         strings.TrimSpace(entry.Title),
+		Abstract:      strings.TrimSpace(entry.Abstract),
+		Authors:       authors,
+		PublishedDate: publishedDate,
+		Categories:    fieldsOfStudy(entry),
+		URL:           entry.URL,
+		DOI:           externalID(entry.ExternalIDs, "DOI"),
+	}, nil
+}
+
+// fieldsOfStudy merges the paper's fields of study with the model-assigned ones, without
+// duplicates
+func fieldsOfStudy(entry paper) []string {
+	seen := make(map[string]bool)
+	fields := make([]string, 0, len(entry.FieldsOfStudy)+len(entry.S2FieldsOfStudy))
+	add := func(field string) {
+		if field != "" && !seen[field] {
+			seen[field] = true
+			fields = append(fields, field)
+		}
+	}
+	for _, field := range entry.FieldsOfStudy {
+		add(field)
+	}
+	for _, field := range entry.S2FieldsOfStudy {
+		add(field.Category)
+	}
+	return fields
+}
+
+// externalID returns one of the paper's external IDs; CorpusId is a number, the rest strings
+func externalID(ids map[string]any, name string) string {
+	switch id := ids[name].(type) {
+	case string:
+		return id
+	case float64:
+		return strconv.FormatFloat(id, 'f', -1, 64)
+	}
+	return ""
+}
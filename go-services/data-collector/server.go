@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"data-collector/anomaly"
+	"data-collector/config"
+	"data-collector/types"
+	"shared/awsclient"
+	"shared/httpserver"
+	"shared/shutdown"
+)
+
+// serverShutdownTimeout bounds how long in-flight requests may drain after a shutdown signal
+const serverShutdownTimeout = 5 * time.Minute
+
+// collectResponse summarizes a collection run without echoing every paper back to the caller.
+// It is both the HTTP response and the Lambda output consumed by the state machine.
+type collectResponse struct {
+	Source         string                    `json:"source"`
+	Count          int                       `json:"count"`
+	Timestamp      time.Time                 `json:"timestamp"`
+	S3Key          string                    `json:"s3_key"`
+	CompressedSize int64                     `json:"compressed_size,omitempty"`
+	PresignedURL   string                    `json:"presigned_url,omitempty"`
+	Metadata       *types.CollectionMetadata `json:"metadata,omitempty"`
+	Validation     *types.ValidationReport   `json:"validation,omitempty"`
+	Anomaly        *anomaly.Verdict          `json:"anomaly,omitempty"`
+	Stats          *types.CollectionStats    `json:"stats,omitempty"`
+	Enrichment     *types.EnrichmentStats    `json:"enrichment,omitempty"`
+}
+
+// applyCollectRequest overrides the data source settings with the request's non-empty fields
+func applyCollectRequest(r types.CollectRequest, source config.DataSourceConfig) config.DataSourceConfig {
+	if r.SearchQuery != "" {
+		source.SearchQuery = r.SearchQuery
+	}
+	if r.MaxResults > 0 {
+		source.MaxResults = r.MaxResults
+	}
+	if r.DateFrom != "" {
+		source.DateFrom = r.DateFrom
+	}
+	if r.DateTo != "" {
+		source.DateTo = r.DateTo
+	}
+	return source
+}
+
+// runServer exposes the collection pipeline over HTTP until a shutdown signal drains it
+func runServer(addr string, watcher *shutdown.Watcher) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", httpserver.Healthz(watcher, appLogger))
+	mux.HandleFunc("/collect", handleCollect)
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           awsclient.Handler("data-collector", mux),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	return httpserver.Serve(server, watcher, serverShutdownTimeout, appLogger, nil)
+}
+
+// handleCollect runs one collection pass; POST /collect with an optional types.CollectRequest body
+func handleCollect(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httpserver.WriteJSON(w, appLogger, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	var request types.CollectRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil && !errors.Is(err, io.EOF) {
+		httpserver.WriteJSON(w, appLogger, http.StatusBadRequest, map[string]string{"error": "invalid collect request: " + err.Error()})
+		return
+	}
+
+	// Detach from the request so a dropped client does not abort an upload midway
+	ctx := context.WithoutCancel(r.Context())
+	result, err := executeDataCollection(ctx, appLogger.WithContext(ctx), request)
+	if err != nil {
+		httpserver.WriteJSON(w, appLogger, http.StatusInternalServerError, map[string]string{"error": errorHandler.Handle(err, "data collection pipeline").Error()})
+		return
+	}
+
+	httpserver.WriteJSON(w, appLogger, http.StatusOK, newCollectResponse(result))
+}
+
+// newCollectResponse builds the summary of a collection result
+func newCollectResponse(result *types.CollectionResult) *collectResponse {
+	return &collectResponse{
+		Source:         result.Source,
+		Count:          result.Count,
+		Timestamp:      result.Timestamp,
+		S3Key:          result.S3Key,
+		CompressedSize: result.CompressedSize,
+		PresignedURL:   result.PresignedURL,
+		Metadata:       result.Metadata,
+		Validation:     result.Validation,
+		Anomaly:        result.Anomaly,
+		Stats:          result.Stats,
+		Enrichment:     result.Enrichment,
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	"data-collector/apiclient"
+	"data-collector/arxiv"
+	"data-collector/categories"
+	"data-collector/config"
+	"data-collector/openalex"
+	"data-collector/semanticscholar"
+	"data-collector/types"
+	"shared/logger"
+)
+
+// parseDateRange parses the source's optional YYYY-MM-DD dates, ignoring invalid ones
+func parseDateRange(contextLogger *logger.Logger, sourceConfig config.DataSourceConfig) (*time.Time, *time.Time) {
+	var dateFrom, dateTo *time.Time
+	if sourceConfig.DateFrom != "" {
+		if parsed, err := time.Parse("2006-01-02", sourceConfig.DateFrom); err == nil {
+			dateFrom = &parsed
+		} else {
+			contextLogger.Warn("Invalid date_from format, ignoring", map[string]interface{}{
+				"date_from": sourceConfig.DateFrom,
+				"error":     err.Error(),
+			})
+		}
+	}
+
+	if sourceConfig.DateTo != "" {
+		if parsed, err := time.Parse("2006-01-02", sourceConfig.DateTo); err == nil {
+			dateTo = &parsed
+		} else {
+			contextLogger.Warn("Invalid date_to format, ignoring", map[string]interface{}{
+				"date_to": sourceConfig.DateTo,
+				"error":   err.Error(),
+			})
+		}
+	}
+	return dateFrom, dateTo
+}
+
+// searchArxiv pages through the arXiv search; every page is a request admitted by the
+// scheduler
+func searchArxiv(ctx context.Context, contextLogger *logger.Logger, cfg *config.Config, sourceConfig config.DataSourceConfig, categoryFilter *categories.Filter, dateFrom, dateTo *time.Time) (*types.CollectionResult, error) {
+	arxivClient := arxiv.NewClient(sourceConfig.APIEndpoint, sourceConfig.RateLimit)
+	arxivClient.SetRetryPolicy(retryPolicy(cfg.Processing))
+
+	contextLogger.Info("Starting arXiv API search")
+	searchParams := arxiv.SearchParams{
+		Query:      categoryFilter.ApplyToQuery(sourceConfig.SearchQuery),
+		MaxResults: sourceConfig.MaxResults,
+		StartIndex: 0,
+		DateFrom:   dateFrom,
+		DateTo:     dateTo,
+		PageSize:   sourceConfig.PageSize,
+		PageDelay:  time.Duration(sourceConfig.PageDelayMs) * time.Millisecond,
+		// The scheduler caps concurrent requests, the daily quota and the cooldown after failures
+		Gate: sourceGate(cfg, "arxiv", sourceConfig.Limits),
+	}
+
+	result, err := arxivClient.Search(ctx, searchParams)
+	if err != nil {
+		return nil, logger.WrapError(err, gateErrorType(err), "arXiv API search failed")
+	}
+
+	contextLogger.InfoWithCount("Papers retrieved from arXiv", result.Count, map[string]interface{}{
+		"collection_metadata": result.Metadata,
+	})
+	return result, nil
+}
+
+// harvestArxiv harvests the data source's OAI set through OAI-PMH; every page is a request
+// admitted by the scheduler. The date range selects records by their last update.
+func harvestArxiv(ctx context.Context, contextLogger *logger.Logger, cfg *config.Config, sourceConfig config.DataSourceConfig, dateFrom, dateTo *time.Time) (*types.CollectionResult, error) {
+	harvester := arxiv.NewHarvester(sourceConfig.OAIEndpoint, sourceConfig.RateLimit)
+	harvester.SetRetryPolicy(retryPolicy(cfg.Processing))
+
+	contextLogger.Info("Starting arXiv OAI-PMH harvest", map[string]interface{}{
+		"oai_set": sourceConfig.OAISet,
+	})
+	result, err := harvester.Harvest(ctx, arxiv.HarvestParams{
+		Set:        sourceConfig.OAISet,
+		From:       dateFrom,
+		Until:      dateTo,
+		MaxResults: sourceConfig.MaxResults,
+		PageDelay:  time.Duration(sourceConfig.PageDelayMs) * time.Millisecond,
+		Gate:       sourceGate(cfg, "arxiv", sourceConfig.Limits),
+	})
+	if err != nil {
+		return nil, logger.WrapError(err, gateErrorType(err), "arXiv OAI-PMH harvest failed")
+	}
+
+	contextLogger.InfoWithCount("Papers harvested from arXiv", result.Count, map[string]interface{}{
+		"collection_metadata": result.Metadata,
+	})
+	return result, nil
+}
+
+// searchSemanticScholar pages through the Semantic Scholar bulk search; every page is a
+// request admitted by the scheduler. The API key comes from SEMANTIC_SCHOLAR_API_KEY, or
+// the data source's api_key.
+func searchSemanticScholar(ctx context.Context, contextLogger *logger.Logger, cfg *config.Config, sourceConfig config.DataSourceConfig, dateFrom, dateTo *time.Time) (*types.CollectionResult, error) {
+	apiKey := os.Getenv(semanticscholar.APIKeyEnv)
+	if apiKey == "" {
+		apiKey = sourceConfig.APIKey
+	}
+	client := semanticscholar.NewClient(sourceConfig.APIEndpoint, apiKey, sourceConfig.RateLimit)
+	client.SetRetryPolicy(retryPolicy(cfg.Processing))
+
+	contextLogger.Info("Starting Semantic Scholar bulk search", map[string]interface{}{
+		"authenticated": apiKey != "",
+	})
+	result, err := client.Search(ctx, semanticscholar.SearchParams{
+		Query:      sourceConfig.SearchQuery,
+		MaxResults: sourceConfig.MaxResults,
+		DateFrom:   dateFrom,
+		DateTo:     dateTo,
+		PageDelay:  time.Duration(sourceConfig.PageDelayMs) * time.Millisecond,
+		Gate:       sourceGate(cfg, semanticscholar.Source, sourceConfig.Limits),
+	})
+	if err != nil {
+		return nil, logger.WrapError(err, gateErrorType(err), "Semantic Scholar search failed")
+	}
+
+	contextLogger.InfoWithCount("Papers retrieved from Semantic Scholar", result.Count, map[string]interface{}{
+		"collection_metadata": result.Metadata,
+	})
+	return result, nil
+}
+
+// searchOpenAlex pages through the OpenAlex works endpoint with its cursor; every page is a
+// request admitted by the scheduler. The mailto address comes from OPENALEX_MAILTO, or the
+// data source's mailto, and the optional API key from OPENALEX_API_KEY or api_key.
+func searchOpenAlex(ctx context.Context, contextLogger *logger.Logger, cfg *config.Config, sourceConfig config.DataSourceConfig, dateFrom, dateTo *time.Time) (*types.CollectionResult, error) {
+	mailto := os.Getenv(openalex.MailtoEnv)
+	if mailto == "" {
+		mailto = sourceConfig.Mailto
+	}
+	apiKey := os.Getenv(openalex.APIKeyEnv)
+	if apiKey == "" {
+		apiKey = sourceConfig.APIKey
+	}
+	client := openalex.NewClient(sourceConfig.APIEndpoint, mailto, apiKey, sourceConfig.RateLimit)
+	client.SetRetryPolicy(retryPolicy(cfg.Processing))
+
+	contextLogger.Info("Starting OpenAlex works search", map[string]interface{}{
+		"polite_pool": mailto != "",
+		"filter":      sourceConfig.Filter,
+	})
+	result, err := client.Search(ctx, openalex.SearchParams{
+		Query:      sourceConfig.SearchQuery,
+		Filter:     sourceConfig.Filter,
+		MaxResults: sourceConfig.MaxResults,
+		PageSize:   sourceConfig.PageSize,
+		DateFrom:   dateFrom,
+		DateTo:     dateTo,
+		PageDelay:  time.Duration(sourceConfig.PageDelayMs) * time.Millisecond,
+		Gate:       sourceGate(cfg, openalex.Source, sourceConfig.Limits),
+	})
+	if err != nil {
+		return nil, logger.WrapError(err, gateErrorType(err), "OpenAlex search failed")
+	}
+
+	contextLogger.InfoWithCount("Papers retrieved from OpenAlex", result.Count, map[string]interface{}{
+		"collection_metadata": result.Metadata,
+	})
+	return result, nil
+}
+
+// retryPolicy builds the retry policy of source API requests from the processing settings
+func retryPolicy(processing config.ProcessingConfig) apiclient.RetryPolicy {
+	return apiclient.RetryPolicy{
+		Attempts:  processing.RetryAttempts,
+		BaseDelay: time.Duration(processing.RetryDelay) * time.Second,
+		MaxDelay:  time.Duration(processing.RetryMaxDelay) * time.Second,
+		Jitter:    processing.RetryJitter,
+	}
+}
+
+// sourceGate admits each page request of a search through the scheduler
+func sourceGate(cfg *config.Config, source string, limits config.SourceLimitsConfig) apiclient.RequestGate {
+	return func(ctx context.Context) (func(error), error) {
+		permit, err := acquireSourceRequest(ctx, cfg, source, limits)
+		if err != nil {
+			return nil, err
+		}
+		return permit.Release, nil
+	}
+}
+
+// gateErrorType keeps the type of a scheduler rejection in a failed search, so quota errors
+// are not retried; other failures are API errors
+func gateErrorType(err error) logger.ErrorType {
+	var appErr *logger.AppError
+	if errors.As(err, &appErr) {
+		return appErr.Type
+	}
+	return logger.ErrorTypeAPI
+}
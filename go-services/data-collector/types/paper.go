@@ -2,7 +2,10 @@ package types
 
 import (
 	"encoding/xml"
+	"fmt"
 	"time"
+
+	"data-collector/anomaly"
 )
 
 // Paper represents a research paper from any data source
@@ -16,12 +19,18 @@ type Paper struct {
 	Categories   []string  `json:"categories"`
 	RawXML       string    `json:"raw_xml,omitempty"`
 	URL          string    `json:"url,omitempty"`
+	DOI          string    `json:"doi,omitempty"` // used by the batch processor's doi dedup strategy
+	Journal       string   `json:"journal,omitempty"`        // set by CrossRef enrichment
+	CitationCount *int     `json:"citation_count,omitempty"` // set by CrossRef enrichment, as of the run
 }
 
 // ArxivFeed represents the root element of arXiv API response
 type ArxivFeed struct {
-	XMLName xml.Name     `xml:"feed"`
-	Entries []ArxivEntry `xml:"entry"`
+	XMLName      xml.Name     `xml:"feed"`
+	TotalResults int          `xml:"http://a9.com/-/spec/opensearch/1.1/ totalResults"`
+	StartIndex   int          `xml:"http://a9.com/-/spec/opensearch/1.1/ startIndex"`
+	ItemsPerPage int          `xml:"http://a9.com/-/spec/opensearch/1.1/ itemsPerPage"`
+	Entries      []ArxivEntry `xml:"entry"`
 }
 
 // ArxivEntry represents a single paper entry from arXiv API
@@ -59,5 +68,93 @@ type CollectionResult struct {
 	Count       int       `json:"count"`
 	Timestamp   time.Time `json:"timestamp"`
 	S3Key       string    `json:"s3_key,omitempty"`
+	S3PartKeys  []string  `json:"s3_part_keys,omitempty"` // part objects of a split result, whose S3Key is the parts manifest
 	CompressedSize int64  `json:"compressed_size,omitempty"`
-}
\ No newline at end of file
+	PresignedURL   string    `json:"presigned_url,omitempty"`
+	PresignedURLExpiresAt *time.Time `json:"presigned_url_expires_at,omitempty"`
+	RunManifestKey string `json:"run_manifest_key,omitempty"`
+	CategoryFiltered int `json:"category_filtered,omitempty"` // papers dropped by the category filter
+	Metadata    *CollectionMetadata `json:"metadata,omitempty"`
+	Validation  *ValidationReport `json:"validation,omitempty"`
+	Anomaly     *anomaly.Verdict `json:"anomaly,omitempty"` // the count checked against recent runs, when anomaly detection is enabled
+	Stats       *CollectionStats `json:"stats,omitempty"` // per-query and per-category breakdown of the kept papers
+	Enrichment  *EnrichmentStats `json:"enrichment,omitempty"` // CrossRef lookups, when enrichment is enabled
+}
+
+// EnrichmentStats counts the CrossRef lookups of a run's papers that were missing a DOI
+type EnrichmentStats struct {
+	MissingDOI int   `json:"missing_doi"`       // kept papers without a DOI after the search
+	Looked     int   `json:"looked_up"`         // lookups made, at most max_lookups
+	Matched    int   `json:"matched"`           // papers given a DOI
+	Failed     int   `json:"failed,omitempty"`  // lookups that errored
+	Stopped    bool  `json:"stopped,omitempty"` // lookups stopped after repeated failures
+	DurationMs int64 `json:"duration_ms"`
+}
+
+// CollectionMetadata describes how a collection result was produced
+type CollectionMetadata struct {
+	Query        string     `json:"query"`
+	RequestURL   string     `json:"request_url"`
+	DateFrom     *time.Time `json:"date_from,omitempty"`
+	DateTo       *time.Time `json:"date_to,omitempty"`
+	StartIndex   int        `json:"start_index"`
+	MaxResults   int        `json:"max_results"`
+	TotalResults int        `json:"total_results"`
+	ItemsPerPage int        `json:"items_per_page"`
+	NextIndex    int        `json:"next_index,omitempty"`
+	HasMore      bool       `json:"has_more"`
+	APILatencyMs int64      `json:"api_latency_ms"`
+	SkippedEntries int      `json:"skipped_entries,omitempty"` // entries that could not be converted to papers
+	Pages        int        `json:"pages,omitempty"` // API requests the result was stitched from
+	ResumptionToken string  `json:"resumption_token,omitempty"` // continues an OAI-PMH harvest that stopped at max_results
+}
+
+// ValidationReport describes what a validate-mode or dry run would have written
+type ValidationReport struct {
+	Mode            string    `json:"mode"`
+	Source          string    `json:"source"`
+	PapersCollected int       `json:"papers_collected"`
+	S3Bucket        string    `json:"s3_bucket"`
+	S3Key           string    `json:"s3_key"`
+	OriginalSize    int64     `json:"original_size"`
+	CompressedSize  int64     `json:"compressed_size"`
+	SkippedWrites   []string  `json:"skipped_writes"`
+	Timestamp       time.Time `json:"timestamp"`
+	// Dry runs only: the local files holding the objects, and the first papers collected
+	OutputFiles []string `json:"output_files,omitempty"`
+	Sample      []Paper  `json:"sample,omitempty"`
+}
+
+// CollectionRunID identifies the collection run of a source started at collectedAt; it names
+// the run manifest and tags the run's raw-data objects
+func CollectionRunID(source string, collectedAt time.Time) string {
+	return fmt.Sprintf("%s-%s", source, collectedAt.Format("20060102-150405"))
+}
+
+// RunManifest records the raw-data objects written by one collection run, so the run can
+// be replayed through the batch processor without relying on S3 events
+type RunManifest struct {
+	RunID       string    `json:"run_id"`
+	Source      string    `json:"source"`
+	Bucket      string    `json:"bucket"`
+	Keys        []string  `json:"keys"`
+	PaperCount  int       `json:"paper_count"`
+	CollectedAt time.Time `json:"collected_at"`
+	CompletedAt time.Time `json:"completed_at"`
+}
+
+// CollectRequest optionally overrides the configured data source settings for one run. It is
+// the collector's Lambda payload (passed through by the state machine) and the optional
+// /collect body; the dispatcher fills it in from a schedule. DataSource is not named
+// "source" because EventBridge events, which may reach the collector unchanged, carry
+// "source": "aws.events".
+type CollectRequest struct {
+	DataSource  string `json:"data_source,omitempty"` // "arxiv" when empty
+	SearchQuery string `json:"search_query,omitempty"`
+	MaxResults  int    `json:"max_results,omitempty"`
+	DateFrom    string `json:"date_from,omitempty"` // YYYY-MM-DD
+	DateTo      string `json:"date_to,omitempty"`   // YYYY-MM-DD
+	Schedule    string `json:"schedule,omitempty"`  // name of the schedule that started the run
+	ScheduledAt string `json:"scheduled_at,omitempty"`
+	DryRun      bool   `json:"dry_run,omitempty"` // search, but return a sample instead of uploading
+}
@@ -0,0 +1,88 @@
+package types
+
+import "time"
+
+// CollectionStats breaks a collection run down by query and by category, so dashboards can
+// show which parts of the corpus a run covered and not only how many papers it returned
+type CollectionStats struct {
+	Queries    []QueryStats             `json:"queries"`
+	Categories map[string]CoverageStats `json:"categories,omitempty"`
+	Coverage   CoverageStats            `json:"coverage"` // every paper kept by the run
+}
+
+// QueryStats describes what one query returned and what the run kept of it
+type QueryStats struct {
+	Query            string        `json:"query"`
+	Returned         int           `json:"returned"`                    // entries the API returned
+	Skipped          int           `json:"skipped,omitempty"`           // entries that could not be converted to papers
+	CategoryFiltered int           `json:"category_filtered,omitempty"` // papers dropped by the category filter
+	Kept             int           `json:"kept"`
+	Coverage         CoverageStats `json:"coverage"`
+}
+
+// CoverageStats counts papers and the publication dates they actually span, which can be
+// narrower than the requested date range
+type CoverageStats struct {
+	Count    int        `json:"count"`
+	Undated  int        `json:"undated,omitempty"` // papers without a publication date, outside the range
+	Earliest *time.Time `json:"earliest_published,omitempty"`
+	Latest   *time.Time `json:"latest_published,omitempty"`
+}
+
+// add counts one paper published at published, zero when unknown
+func (c *CoverageStats) add(published time.Time) {
+	c.Count++
+	if published.IsZero() {
+		c.Undated++
+		return
+	}
+	if c.Earliest == nil || published.Before(*c.Earliest) {
+		earliest := published
+		c.Earliest = &earliest
+	}
+	if c.Latest == nil || published.After(*c.Latest) {
+		latest := published
+		c.Latest = &latest
+	}
+}
+
+// BuildCollectionStats summarizes the papers a run kept. The run issues a single query, so
+// the query breakdown has one entry; skipped entries come from the result's metadata.
+func BuildCollectionStats(result *CollectionResult) *CollectionStats {
+	query := QueryStats{
+		CategoryFiltered: result.CategoryFiltered,
+		Kept:             len(result.Papers),
+	}
+	if result.Metadata != nil {
+		query.Query = result.Metadata.Query
+		query.Skipped = result.Metadata.SkippedEntries
+	}
+	query.Returned = query.Kept + query.Skipped + query.CategoryFiltered
+
+	stats := &CollectionStats{Categories: make(map[string]CoverageStats)}
+	for _, paper := range result.Papers {
+		stats.Coverage.add(paper.PublishedDate)
+		for _, category := range uniqueCategories(paper.Categories) {
+			coverage := stats.Categories[category]
+			coverage.add(paper.PublishedDate)
+			stats.Categories[category] = coverage
+		}
+	}
+	query.Coverage = stats.Coverage
+	stats.Queries = []QueryStats{query}
+	return stats
+}
+
+// uniqueCategories returns categories without duplicates, so a paper listing a category twice
+// is counted once
+func uniqueCategories(categories []string) []string {
+	seen := make(map[string]bool, len(categories))
+	unique := make([]string, 0, len(categories))
+	for _, category := range categories {
+		if category != "" && !seen[category] {
+			seen[category] = true
+			unique = append(unique, category)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,12 @@
+module embedding-stub
+
+go 1.23
+
+require (
+	shared/embeddingstub v0.0.0
+	shared/logger v0.0.0
+)
+
+replace shared/logger => ../shared/logger
+
+replace shared/embeddingstub => ../shared/embeddingstub
@@ -0,0 +1,68 @@
+// Command embedding-stub serves the embedding API contract locally with deterministic
+// vectors, so the vector coordinator and search service can run without the Python API:
+//
+//	embedding-stub -addr :8000
+//	EMBEDDING_API_URL=http://localhost:8000/embed vector-coordinator -serve :8080
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"shared/embeddingstub"
+	"shared/logger"
+)
+
+var appLogger = logger.New("embedding-stub")
+
+func main() {
+	addr := flag.String("addr", getEnvOrDefault("EMBEDDING_STUB_ADDR", ":8000"), "listen address")
+	cfg := embeddingstub.Config{}
+	flag.IntVar(&cfg.Dimension, "dimension", getEnvInt("EMBEDDING_STUB_DIMENSION", 384), "embedding dimension; match the coordinator's expected dimension")
+	flag.StringVar(&cfg.ModelName, "model-name", "stub-embedding", "model name reported in responses")
+	flag.StringVar(&cfg.ModelVersion, "model-version", "sentence-transformers/all-MiniLM-L6-v2", "model version reported in responses and stored on vectors")
+	flag.DurationVar(&cfg.Latency, "latency", 0, "delay added to every request")
+	flag.DurationVar(&cfg.PerText, "per-text", 0, "delay added per text of a request")
+	flag.Float64Var(&cfg.Jitter, "jitter", 0, "fraction of the delay randomized, 0 to 1")
+	flag.Float64Var(&cfg.ErrorRate, "error-rate", 0, "fraction of requests answered 503, 0 to 1")
+	flag.Parse()
+
+	if cfg.Dimension <= 0 || cfg.Jitter < 0 || cfg.Jitter > 1 || cfg.ErrorRate < 0 || cfg.ErrorRate > 1 {
+		fmt.Fprintln(os.Stderr, "-dimension must be positive and -jitter and -error-rate between 0 and 1")
+		os.Exit(2)
+	}
+
+	appLogger.Info("Stub embedding API listening", map[string]interface{}{
+		"addr":          *addr,
+		"dimension":     cfg.Dimension,
+		"model_version": cfg.ModelVersion,
+		"latency":       cfg.Latency.String(),
+	})
+	server := &http.Server{
+		Addr:              *addr,
+		Handler:           embeddingstub.NewServer(cfg).Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	if err := server.ListenAndServe(); err != nil {
+		appLogger.Error("Stub embedding API stopped", err)
+		os.Exit(1)
+	}
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
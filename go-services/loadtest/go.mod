@@ -0,0 +1,18 @@
+module loadtest
+
+go 1.23
+
+require (
+	github.com/aws/aws-sdk-go v1.55.5
+	shared/dynamo v0.0.0
+	shared/embeddingstub v0.0.0
+	shared/logger v0.0.0
+)
+
+require github.com/jmespath/go-jmespath v0.4.0 // indirect
+
+replace shared/logger => ../shared/logger
+
+replace shared/dynamo => ../shared/dynamo
+
+replace shared/embeddingstub => ../shared/embeddingstub
@@ -0,0 +1,422 @@
+// Package harness drives the vector coordinator under load. Each run synthesizes papers for a
+// fresh trace in the papers table, asks the coordinator to vectorize the trace over HTTP and
+// times it; runs execute with a configurable concurrency so the coordinator's concurrency
+// settings can be validated against throughput, latency and DynamoDB consumption.
+package harness
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"shared/dynamo"
+	"shared/logger"
+)
+
+// DynamoDB request limits
+const (
+	maxBatchWriteItems = 25
+	maxWriteAttempts   = 8
+)
+
+// Config describes a load test
+type Config struct {
+	PapersTable        string
+	VectorsTable       string
+	VectorPartitionKey string // vectors table partition key, the paper ID
+	VectorSortKey      string // vectors table sort key; empty for partition-key-only tables
+	CoordinatorURL     string // base URL of a coordinator started with -serve
+	StubURL            string // base URL of the stub embedding API, for its stats; empty to skip
+	Papers             int    // papers per trace
+	Runs               int    // traces vectorized
+	Concurrency        int    // traces vectorized at once
+	Seed               int64
+	Cleanup            bool // delete the synthesized papers and their vectors afterwards
+	RunTimeout         time.Duration
+}
+
+// RunResult is the outcome of vectorizing one trace
+type RunResult struct {
+	TraceID               string           `json:"trace_id"`
+	Status                string           `json:"status"`
+	LatencyMs             int64            `json:"latency_ms"` // request to response, as the caller sees it
+	TotalPapers           int              `json:"total_papers"`
+	VectorsStored         int              `json:"vectors_stored"`
+	FailedEmbeddings      int              `json:"failed_embeddings"`
+	ConsumedWriteCapacity float64          `json:"consumed_write_capacity"`
+	StageTimings          map[string]int64 `json:"stage_timings,omitempty"`
+	Error                 string           `json:"error,omitempty"`
+}
+
+// Report summarizes a load test
+type Report struct {
+	Runs        int         `json:"runs"`
+	Concurrency int         `json:"concurrency"`
+	Papers      int         `json:"papers"` // synthesized, across every trace
+	WallTimeMs  int64       `json:"wall_time_ms"`
+	Throughput  float64     `json:"throughput_papers_per_sec"` // papers vectorized per second of wall time
+	VectorsPerS float64     `json:"throughput_vectors_per_sec"`
+	LatencyP50  int64       `json:"run_latency_p50_ms"`
+	LatencyP95  int64       `json:"run_latency_p95_ms"`
+	LatencyMax  int64       `json:"run_latency_max_ms"`
+	PaperP95    int64       `json:"paper_p95_ms"` // highest per-paper embedding p95 the coordinator reported
+	FailedRuns  int         `json:"failed_runs"`
+	Capacity    Capacity    `json:"dynamodb"`
+	Embedding   interface{} `json:"embedding_stub,omitempty"` // the stub's own stats for the test
+	Results     []RunResult `json:"results"`
+}
+
+// Capacity is the DynamoDB capacity the test consumed
+type Capacity struct {
+	SeedWriteUnits        float64 `json:"seed_write_units"`        // writing the synthesized papers
+	CoordinatorWriteUnits float64 `json:"coordinator_write_units"` // vector writes, as the coordinator reported them
+	CleanupWriteUnits     float64 `json:"cleanup_write_units,omitempty"`
+	CleanupReadUnits      float64 `json:"cleanup_read_units,omitempty"`
+}
+
+// Harness runs load tests against a coordinator
+type Harness struct {
+	client     dynamodbiface.DynamoDBAPI
+	httpClient *http.Client
+	config     Config
+	logger     *logger.Logger
+}
+
+// New creates a harness using the default AWS session
+func New(config Config) *Harness {
+	sess := session.Must(session.NewSession())
+	return NewWithClient(dynamodb.New(sess), config)
+}
+
+// NewWithClient creates a harness with a custom DynamoDB client (for testing)
+func NewWithClient(client dynamodbiface.DynamoDBAPI, config Config) *Harness {
+	if config.VectorPartitionKey == "" {
+		config.VectorPartitionKey = "paper_id"
+	}
+	if config.Concurrency <= 0 {
+		config.Concurrency = 1
+	}
+	if config.RunTimeout <= 0 {
+		config.RunTimeout = 15 * time.Minute
+	}
+	return &Harness{
+		client:     client,
+		httpClient: &http.Client{Timeout: config.RunTimeout},
+		config:     config,
+		logger:     logger.New("load-test"),
+	}
+}
+
+// Run seeds every trace, then vectorizes them with the configured concurrency. Seeding
+// happens first so the timed phase measures the coordinator alone.
+func (h *Harness) Run(ctx context.Context) (*Report, error) {
+	if h.config.PapersTable == "" || h.config.CoordinatorURL == "" {
+		return nil, fmt.Errorf("papers table and coordinator URL are required")
+	}
+	if h.config.Papers <= 0 || h.config.Runs <= 0 {
+		return nil, fmt.Errorf("papers and runs must be positive")
+	}
+
+	report := &Report{Runs: h.config.Runs, Concurrency: h.config.Concurrency}
+	stamp := time.Now().UTC().Format("20060102-150405")
+	rng := rand.New(rand.NewSource(h.config.Seed))
+
+	traces := make([]string, h.config.Runs)
+	seeded := make(map[string][]string, h.config.Runs)
+	for i := range traces {
+		traces[i] = fmt.Sprintf("loadtest-%s-%03d", stamp, i)
+		paperIDs, units, err := h.seed(ctx, rng, traces[i])
+		report.Capacity.SeedWriteUnits += units
+		seeded[traces[i]] = paperIDs
+		if err != nil {
+			h.cleanup(ctx, report, seeded)
+			return report, err
+		}
+		report.Papers += len(paperIDs)
+	}
+	h.logger.Info("Seeded load test traces", map[string]interface{}{
+		"traces":           len(traces),
+		"papers":           report.Papers,
+		"seed_write_units": report.Capacity.SeedWriteUnits,
+	})
+
+	if h.config.StubURL != "" {
+		// Start the stub's stats from zero so they only cover the timed phase
+		if _, err := h.stubStats(ctx, http.MethodDelete); err != nil {
+			h.logger.Warn("Failed to reset stub embedding stats", map[string]interface{}{"error": err.Error()})
+		}
+	}
+
+	results := make([]RunResult, len(traces))
+	start := time.Now()
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, h.config.Concurrency)
+	for i, traceID := range traces {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int, traceID string) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			results[i] = h.vectorize(ctx, traceID)
+		}(i, traceID)
+	}
+	wg.Wait()
+	wall := time.Since(start)
+
+	summarize(report, results, wall)
+	if h.config.StubURL != "" {
+		if stats, err := h.stubStats(ctx, http.MethodGet); err == nil {
+			report.Embedding = stats
+		}
+	}
+	if h.config.Cleanup {
+		h.cleanup(ctx, report, seeded)
+	}
+	return report, nil
+}
+
+// seed writes the synthesized papers of one trace and returns their IDs and the write
+// capacity consumed
+func (h *Harness) seed(ctx context.Context, rng *rand.Rand, traceID string) ([]string, float64, error) {
+	batchTimestamp := time.Now().UTC().Format(time.RFC3339)
+	var requests []*dynamodb.WriteRequest
+	paperIDs := make([]string, 0, h.config.Papers)
+	for i := 0; i < h.config.Papers; i++ {
+		paper := synthesizePaper(rng, traceID, i, batchTimestamp)
+		item, err := dynamodbattribute.MarshalMap(paper)
+		if err != nil {
+			return paperIDs, 0, fmt.Errorf("failed to marshal paper: %w", err)
+		}
+		requests = append(requests, &dynamodb.WriteRequest{PutRequest: &dynamodb.PutRequest{Item: item}})
+		paperIDs = append(paperIDs, paper.PaperID)
+	}
+	units, err := h.batchWrite(ctx, h.config.PapersTable, requests)
+	if err != nil {
+		return paperIDs, units, fmt.Errorf("failed to seed trace %s: %w", traceID, err)
+	}
+	return paperIDs, units, nil
+}
+
+// batchWrite writes requests in batches, retrying unprocessed items with backoff, and returns
+// the write capacity consumed
+func (h *Harness) batchWrite(ctx context.Context, table string, requests []*dynamodb.WriteRequest) (float64, error) {
+	var units float64
+	for start := 0; start < len(requests); start += maxBatchWriteItems {
+		end := start + maxBatchWriteItems
+		if end > len(requests) {
+			end = len(requests)
+		}
+		pending := requests[start:end]
+		for attempt := 1; len(pending) > 0; attempt++ {
+			output, err := h.client.BatchWriteItemWithContext(ctx, &dynamodb.BatchWriteItemInput{
+				RequestItems:           map[string][]*dynamodb.WriteRequest{table: pending},
+				ReturnConsumedCapacity: aws.String(dynamodb.ReturnConsumedCapacityTotal),
+			})
+			if err != nil {
+				return units, err
+			}
+			for _, capacity := range output.ConsumedCapacity {
+				units += aws.Float64Value(capacity.CapacityUnits)
+			}
+			pending = output.UnprocessedItems[table]
+			if len(pending) == 0 {
+				break
+			}
+			if attempt >= maxWriteAttempts {
+				return units, fmt.Errorf("%d items unprocessed after %d attempts", len(pending), attempt)
+			}
+			time.Sleep(time.Duration(1<<uint(attempt-1)) * 100 * time.Millisecond)
+		}
+	}
+	return units, nil
+}
+
+// vectorize asks the coordinator to vectorize one trace and times the request
+func (h *Harness) vectorize(ctx context.Context, traceID string) RunResult {
+	result := RunResult{TraceID: traceID}
+	body, _ := json.Marshal(map[string]string{"trace_id": traceID})
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(h.config.CoordinatorURL, "/")+"/vectorize", bytes.NewReader(body))
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	start := time.Now()
+	response, err := h.httpClient.Do(request)
+	result.LatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer response.Body.Close()
+
+	var output struct {
+		Status                string           `json:"status"`
+		TotalPapers           int              `json:"total_papers"`
+		VectorsStored         int              `json:"vectors_stored"`
+		FailedEmbeddings      int              `json:"failed_embeddings"`
+		ConsumedWriteCapacity float64          `json:"consumed_write_capacity"`
+		StageTimings          map[string]int64 `json:"stage_timings"`
+		ErrorMessage          string           `json:"error_message"`
+		Error                 string           `json:"error"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&output); err != nil {
+		result.Error = fmt.Sprintf("HTTP %d: invalid response: %v", response.StatusCode, err)
+		return result
+	}
+	result.Status = output.Status
+	result.TotalPapers = output.TotalPapers
+	result.VectorsStored = output.VectorsStored
+	result.FailedEmbeddings = output.FailedEmbeddings
+	result.ConsumedWriteCapacity = output.ConsumedWriteCapacity
+	result.StageTimings = output.StageTimings
+	if response.StatusCode != http.StatusOK {
+		result.Error = fmt.Sprintf("HTTP %d: %s%s", response.StatusCode, output.Error, output.ErrorMessage)
+	}
+
+	h.logger.Info("Load test run completed", map[string]interface{}{
+		"trace_id":       traceID,
+		"status":         result.Status,
+		"latency_ms":     result.LatencyMs,
+		"vectors_stored": result.VectorsStored,
+	})
+	return result
+}
+
+// summarize fills in the throughput, latency and capacity figures of the report
+func summarize(report *Report, results []RunResult, wall time.Duration) {
+	report.Results = results
+	report.WallTimeMs = wall.Milliseconds()
+
+	var papers, vectors int
+	latencies := make([]int64, 0, len(results))
+	for _, result := range results {
+		if result.Error != "" {
+			report.FailedRuns++
+		}
+		papers += result.TotalPapers
+		vectors += result.VectorsStored
+		report.Capacity.CoordinatorWriteUnits += result.ConsumedWriteCapacity
+		latencies = append(latencies, result.LatencyMs)
+		if p95 := result.StageTimings["paper_p95_ms"]; p95 > report.PaperP95 {
+			report.PaperP95 = p95
+		}
+	}
+	if seconds := wall.Seconds(); seconds > 0 {
+		report.Throughput = float64(papers) / seconds
+		report.VectorsPerS = float64(vectors) / seconds
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	report.LatencyP50 = percentile(latencies, 50)
+	report.LatencyP95 = percentile(latencies, 95)
+	if len(latencies) > 0 {
+		report.LatencyMax = latencies[len(latencies)-1]
+	}
+}
+
+// percentile returns the nearest-rank percentile of sorted values
+func percentile(sorted []int64, p float64) int64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// stubStats reads, or with DELETE resets, the stub embedding API's stats
+func (h *Harness) stubStats(ctx context.Context, method string) (map[string]interface{}, error) {
+	request, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(h.config.StubURL, "/")+"/stats", nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := h.httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	var stats map[string]interface{}
+	if err := json.NewDecoder(response.Body).Decode(&stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// cleanup deletes the synthesized papers and every vector stored for them. Failures are
+// logged rather than returned, so the report of a finished test is not lost.
+func (h *Harness) cleanup(ctx context.Context, report *Report, seeded map[string][]string) {
+	var paperDeletes, vectorDeletes []*dynamodb.WriteRequest
+	for _, paperIDs := range seeded {
+		for _, paperID := range paperIDs {
+			paperDeletes = append(paperDeletes, &dynamodb.WriteRequest{DeleteRequest: &dynamodb.DeleteRequest{
+				Key: map[string]*dynamodb.AttributeValue{"paper_id": {S: aws.String(paperID)}},
+			}})
+			if h.config.VectorsTable == "" {
+				continue
+			}
+			keys, units, err := h.vectorKeys(ctx, paperID)
+			report.Capacity.CleanupReadUnits += units
+			if err != nil {
+				h.logger.Warn("Failed to list load test vectors", map[string]interface{}{"paper_id": paperID, "error": err.Error()})
+				continue
+			}
+			for _, key := range keys {
+				vectorDeletes = append(vectorDeletes, &dynamodb.WriteRequest{DeleteRequest: &dynamodb.DeleteRequest{Key: key}})
+			}
+		}
+	}
+
+	if len(vectorDeletes) > 0 {
+		units, err := h.batchWrite(ctx, h.config.VectorsTable, vectorDeletes)
+		report.Capacity.CleanupWriteUnits += units
+		if err != nil {
+			h.logger.Warn("Failed to delete load test vectors", map[string]interface{}{"error": err.Error()})
+		}
+	}
+	units, err := h.batchWrite(ctx, h.config.PapersTable, paperDeletes)
+	report.Capacity.CleanupWriteUnits += units
+	if err != nil {
+		h.logger.Warn("Failed to delete load test papers", map[string]interface{}{"error": err.Error()})
+	}
+}
+
+// vectorKeys returns the table keys of the vectors stored for a paper
+func (h *Harness) vectorKeys(ctx context.Context, paperID string) ([]map[string]*dynamodb.AttributeValue, float64, error) {
+	names := map[string]*string{"#pk": aws.String(h.config.VectorPartitionKey)}
+	projection := "#pk"
+	if h.config.VectorSortKey != "" {
+		names["#sk"] = aws.String(h.config.VectorSortKey)
+		projection += ", #sk"
+	}
+	input := &dynamodb.QueryInput{
+		TableName:                 aws.String(h.config.VectorsTable),
+		KeyConditionExpression:    aws.String("#pk = :pid"),
+		ProjectionExpression:      aws.String(projection),
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":pid": {S: aws.String(paperID)}},
+	}
+
+	var keys []map[string]*dynamodb.AttributeValue
+	result, err := dynamo.Paginate(ctx, dynamo.Query(h.client, input), dynamo.Options{}, func(page dynamo.Page) error {
+		keys = append(keys, page.Items...)
+		return nil
+	})
+	return keys, result.ConsumedCapacity, err
+}
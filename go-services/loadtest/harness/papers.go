@@ -0,0 +1,69 @@
+package harness
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// LoadTestSource is the source of synthesized papers, so they are easy to find and purge
+const LoadTestSource = "loadtest"
+
+// paperItem has the papers table attributes the coordinator reads
+type paperItem struct {
+	PaperID          string   `dynamodbav:"paper_id"`
+	Source           string   `dynamodbav:"source"`
+	Title            string   `dynamodbav:"title"`
+	Abstract         string   `dynamodbav:"abstract"`
+	Authors          []string `dynamodbav:"authors"`
+	PublishedDate    string   `dynamodbav:"published_date"`
+	Categories       []string `dynamodbav:"categories"`
+	TraceID          string   `dynamodbav:"trace_id"`
+	BatchTimestamp   string   `dynamodbav:"batch_timestamp"`
+	ProcessingStatus string   `dynamodbav:"processing_status"`
+	CreatedAt        string   `dynamodbav:"created_at"`
+	UpdatedAt        string   `dynamodbav:"updated_at"`
+}
+
+// synthesizePaper builds paper n of a trace. Abstracts run 120 to 250 words, the range of real
+// arXiv abstracts, so embedding requests carry realistic payloads.
+func synthesizePaper(rng *rand.Rand, traceID string, n int, batchTimestamp string) paperItem {
+	title := make([]string, 6+rng.Intn(8))
+	for i := range title {
+		title[i] = words[rng.Intn(len(words))]
+	}
+	abstract := make([]string, 120+rng.Intn(131))
+	for i := range abstract {
+		abstract[i] = words[rng.Intn(len(words))]
+	}
+	authors := make([]string, 1+rng.Intn(5))
+	for i := range authors {
+		authors[i] = fmt.Sprintf("Load Tester %d", rng.Intn(1000))
+	}
+
+	return paperItem{
+		PaperID:          fmt.Sprintf("%s-%06d", traceID, n),
+		Source:           LoadTestSource,
+		Title:            strings.Join(title, " "),
+		Abstract:         strings.Join(abstract, " ") + ".",
+		Authors:          authors,
+		PublishedDate:    time.Now().UTC().AddDate(0, 0, -rng.Intn(365)).Format("2006-01-02"),
+		Categories:       []string{categories[rng.Intn(len(categories))]},
+		TraceID:          traceID,
+		BatchTimestamp:   batchTimestamp,
+		ProcessingStatus: "processed",
+		CreatedAt:        batchTimestamp,
+		UpdatedAt:        batchTimestamp,
+	}
+}
+
+var (
+	words = strings.Fields(`learning model neural network training data representation attention
+		transformer graph retrieval embedding language vision benchmark evaluation robust efficient
+		scalable sparse dense optimization gradient loss objective generalization inference latency
+		throughput distributed federated contrastive supervised unsupervised reinforcement policy
+		reward agent sequence token encoder decoder pretraining fine-tuning dataset corpus annotation
+		theorem bound convergence stochastic variance estimator kernel manifold spectral diffusion`)
+	categories = []string{"cs.LG", "cs.CL", "cs.CV", "cs.IR", "cs.AI", "stat.ML"}
+)
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"loadtest/harness"
+	"shared/embeddingstub"
+	"shared/logger"
+)
+
+var appLogger = logger.New("loadtest")
+
+func main() {
+	if len(os.Args) < 2 {
+		printUsage()
+		os.Exit(2)
+	}
+
+	ctx := context.Background()
+	command, args := os.Args[1], os.Args[2:]
+
+	var err error
+	switch command {
+	case "stub-embedding":
+		err = runStubEmbedding(args)
+	case "run":
+		err = runLoadTest(ctx, args)
+	case "help", "-h", "--help":
+		printUsage()
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", command)
+		printUsage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		appLogger.Error(fmt.Sprintf("%s failed", command), err)
+		os.Exit(1)
+	}
+}
+
+func printUsage() {
+	fmt.Fprintln(os.Stderr, "Vector Pipeline Load Test")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "Usage: loadtest <command> [flags]")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "Commands:")
+	fmt.Fprintln(os.Stderr, "  stub-embedding  Serve a stub embedding API with deterministic vectors and configurable latency")
+	fmt.Fprintln(os.Stderr, "  run             Seed traces of synthetic papers and drive a coordinator started with -serve")
+}
+
+func runStubEmbedding(args []string) error {
+	fs := flag.NewFlagSet("stub-embedding", flag.ExitOnError)
+	addr := fs.String("addr", ":8090", "listen address")
+	cfg := embeddingstub.Config{}
+	fs.IntVar(&cfg.Dimension, "dimension", 384, "embedding dimension")
+	fs.StringVar(&cfg.ModelName, "model-name", "stub-embedding", "model name reported in responses")
+	fs.StringVar(&cfg.ModelVersion, "model-version", "v1.0", "model version reported in responses")
+	fs.DurationVar(&cfg.Latency, "latency", 20*time.Millisecond, "delay added to every request")
+	fs.DurationVar(&cfg.PerText, "per-text", 5*time.Millisecond, "delay added per text of a request")
+	fs.Float64Var(&cfg.Jitter, "jitter", 0.2, "fraction of the delay randomized, 0 to 1")
+	fs.Float64Var(&cfg.ErrorRate, "error-rate", 0, "fraction of requests answered 503, 0 to 1")
+	fs.Parse(args)
+
+	if cfg.Dimension <= 0 || cfg.Jitter < 0 || cfg.Jitter > 1 || cfg.ErrorRate < 0 || cfg.ErrorRate > 1 {
+		return fmt.Errorf("-dimension must be positive and -jitter and -error-rate between 0 and 1")
+	}
+
+	appLogger.Info("Stub embedding API listening", map[string]interface{}{
+		"addr":      *addr,
+		"dimension": cfg.Dimension,
+		"latency":   cfg.Latency.String(),
+	})
+	server := &http.Server{
+		Addr:              *addr,
+		Handler:           embeddingstub.NewServer(cfg).Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return server.ListenAndServe()
+}
+
+func runLoadTest(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	cfg := harness.Config{}
+	fs.StringVar(&cfg.PapersTable, "papers-table", getEnvOrDefault("PAPERS_TABLE_NAME", "Papers"), "papers table the traces are seeded into")
+	fs.StringVar(&cfg.VectorsTable, "vectors-table", getEnvOrDefault("VECTORS_TABLE_NAME", "Vectors"), "vectors table, cleaned up with -cleanup")
+	fs.StringVar(&cfg.VectorPartitionKey, "vector-partition-key", "paper_id", "vectors table partition key")
+	fs.StringVar(&cfg.VectorSortKey, "vector-sort-key", "vector_type", "vectors table sort key, empty for partition-key-only tables")
+	fs.StringVar(&cfg.CoordinatorURL, "coordinator", "http://localhost:8080", "base URL of the vector coordinator HTTP server")
+	fs.StringVar(&cfg.StubURL, "stub", "http://localhost:8090", "base URL of the stub embedding API for its stats, empty to skip")
+	fs.IntVar(&cfg.Papers, "papers", 1000, "papers per trace")
+	fs.IntVar(&cfg.Runs, "runs", 1, "traces vectorized")
+	fs.IntVar(&cfg.Concurrency, "concurrency", 1, "traces vectorized at once")
+	fs.Int64Var(&cfg.Seed, "seed", 1, "random seed of the synthesized papers")
+	fs.BoolVar(&cfg.Cleanup, "cleanup", true, "delete the synthesized papers and their vectors afterwards")
+	fs.DurationVar(&cfg.RunTimeout, "run-timeout", 15*time.Minute, "timeout of one vectorization request")
+	fs.Parse(args)
+
+	report, err := harness.New(cfg).Run(ctx)
+	if report != nil {
+		if printErr := printJSON(report); printErr != nil && err == nil {
+			err = printErr
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if report.FailedRuns > 0 {
+		return fmt.Errorf("%d of %d runs failed", report.FailedRuns, report.Runs)
+	}
+	return nil
+}
+
+func printJSON(value interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
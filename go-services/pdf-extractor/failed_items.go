@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"pdf-extractor/pdftext"
+	"shared/awsclient"
+	"shared/failures"
+	"shared/logger"
+)
+
+// extractionFailureCode classifies an extraction error: a PDF with no text layer, or one the
+// extractors could not read
+func extractionFailureCode(err error) failures.Code {
+	if errors.Is(err, pdftext.ErrNoText) {
+		return failures.CodeNoText
+	}
+	return failures.CodeParse
+}
+
+// recordFailedItems stores every failed item of the invocation in the FailedItems table, when
+// FAILED_ITEMS_TABLE_NAME is set. Items are keyed by paper ID, or by PDF key when the paper ID
+// is missing; a failed write is only logged.
+func recordFailedItems(ctx context.Context, contextLogger *logger.Logger, request ExtractRequest, result *ExtractResult) {
+	tableName := os.Getenv(failures.TableEnv)
+	if tableName == "" || result.Failed == 0 {
+		return
+	}
+
+	traceID := logger.TraceIDFromContext(ctx)
+	var failed []failures.Failure
+	for i, item := range result.Items {
+		if item.Error == "" {
+			continue
+		}
+		recordID := item.PaperID
+		if recordID == "" {
+			recordID = request.Items[i].PDFKey
+		}
+		failed = append(failed, failures.Failure{
+			Stage:    failures.StageExtraction,
+			RecordID: recordID,
+			TraceID:  traceID,
+			Code:     failures.Code(item.Code),
+			Message:  item.Error,
+		})
+	}
+
+	if err := failures.NewRecorder(tableName, awsclient.ClientConfig{}).Record(ctx, failed); err != nil {
+		contextLogger.Warn("Failed to record failed items", map[string]interface{}{
+			"table": tableName,
+			"error": err.Error(),
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"pdf-extractor/pdftext"
+	"pdf-extractor/remote"
+	"pdf-extractor/store"
+	"shared/failures"
+	"shared/logger"
+)
+
+// ExtractRequest lists the stored PDFs to extract text from
+type ExtractRequest struct {
+	Items []ExtractItem `json:"items"`
+}
+
+// ExtractItem is one paper's PDF
+type ExtractItem struct {
+	PaperID string `json:"paper_id"`
+	PDFKey  string `json:"pdf_key"` // s3://bucket/key
+}
+
+// ExtractResult reports the outcome of every item
+type ExtractResult struct {
+	Extracted        int          `json:"extracted"`
+	Failed           int          `json:"failed"`
+	RemoteExtracted  int          `json:"remote_extracted,omitempty"` // items the external service extracted after the built-in extractor found no text
+	Items            []ItemResult `json:"items"`
+	ProcessingTimeMs int64        `json:"processing_time_ms"`
+}
+
+// ItemResult is the outcome of one item
+type ItemResult struct {
+	PaperID     string `json:"paper_id"`
+	FullTextKey string `json:"full_text_key,omitempty"`
+	PageCount   int    `json:"page_count,omitempty"`
+	Extractor   string `json:"extractor,omitempty"` // "builtin" or "remote"
+	Error       string `json:"error,omitempty"`
+	Code        string `json:"code,omitempty"` // normalized failure code, set with Error
+}
+
+// Extractor names reported in ItemResult.Extractor
+const (
+	ExtractorBuiltin = "builtin"
+	ExtractorRemote  = "remote"
+)
+
+// RemoteExtractor is implemented by external extraction services
+type RemoteExtractor interface {
+	Extract(ctx context.Context, pdf []byte) (*pdftext.Document, error)
+}
+
+var appLogger = logger.New("pdf-extractor")
+
+func main() {
+	if os.Getenv("AWS_LAMBDA_FUNCTION_NAME") != "" {
+		lambda.Start(handleExtract)
+		return
+	}
+
+	fmt.Println("PDF Extractor - Local Development Mode")
+	args := os.Args[1:]
+	if len(args) == 0 || len(args)%2 != 0 {
+		fmt.Println("Usage: pdf-extractor <paper-id> s3://bucket/paper.pdf [<paper-id> s3://bucket/paper.pdf ...]")
+		return
+	}
+	var request ExtractRequest
+	for i := 0; i < len(args); i += 2 {
+		request.Items = append(request.Items, ExtractItem{PaperID: args[i], PDFKey: args[i+1]})
+	}
+	result, err := handleExtract(context.Background(), request)
+	if err != nil {
+		appLogger.Error("PDF extraction failed", err)
+		os.Exit(1)
+	}
+	if result.Failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// handleExtract extracts each PDF's text, stores it under the full-text prefix and points
+// the paper at it. Items fail individually; the invocation only fails on invalid input.
+func handleExtract(ctx context.Context, request ExtractRequest) (*ExtractResult, error) {
+	start := time.Now()
+	contextLogger := appLogger.WithContext(ctx)
+	if len(request.Items) == 0 {
+		return nil, fmt.Errorf("items cannot be empty")
+	}
+
+	papersTable := getEnvOrDefault("PAPERS_TABLE_NAME", "papers-table")
+	textBucket := getEnvOrDefault("FULL_TEXT_BUCKET", "pipeline-full-text")
+	if err := runPreflight(ctx, papersTable, textBucket); err != nil {
+		return nil, err
+	}
+
+	textStore := store.NewStore(papersTable, textBucket, getEnvOrDefault("FULL_TEXT_PREFIX", store.DefaultPrefix))
+	var remoteExtractor RemoteExtractor
+	if url := os.Getenv("EXTRACTOR_URL"); url != "" {
+		remoteExtractor = remote.NewClient(url)
+	}
+
+	result := &ExtractResult{Items: make([]ItemResult, 0, len(request.Items))}
+	for _, item := range request.Items {
+		itemResult := extractItem(ctx, textStore, remoteExtractor, item)
+		if itemResult.Error != "" {
+			result.Failed++
+			contextLogger.Warn("Failed to extract PDF text", map[string]interface{}{
+				"paper_id": item.PaperID,
+				"pdf_key":  item.PDFKey,
+				"error":    itemResult.Error,
+			})
+		} else {
+			result.Extracted++
+			if itemResult.Extractor == ExtractorRemote {
+				result.RemoteExtracted++
+			}
+		}
+		result.Items = append(result.Items, itemResult)
+	}
+	result.ProcessingTimeMs = time.Since(start).Milliseconds()
+	recordFailedItems(ctx, contextLogger, request, result)
+
+	contextLogger.InfoWithCount("PDF extraction completed", result.Extracted, map[string]interface{}{
+		"failed":             result.Failed,
+		"remote_extracted":   result.RemoteExtracted,
+		"processing_time_ms": result.ProcessingTimeMs,
+	})
+	return result, nil
+}
+
+// extractItem runs one item through download, extraction, text upload and paper update
+func extractItem(ctx context.Context, textStore *store.Store, remoteExtractor RemoteExtractor, item ExtractItem) ItemResult {
+	itemResult := ItemResult{PaperID: item.PaperID}
+	if item.PaperID == "" {
+		itemResult.Error = "paper_id is required"
+		itemResult.Code = string(failures.CodeInvalidInput)
+		return itemResult
+	}
+
+	pdf, err := textStore.GetPDF(ctx, item.PDFKey)
+	if err != nil {
+		itemResult.Error = err.Error()
+		itemResult.Code = string(failures.CodeS3Read)
+		return itemResult
+	}
+
+	doc, extractor, err := extractText(ctx, remoteExtractor, pdf)
+	if err != nil {
+		itemResult.Error = err.Error()
+		itemResult.Code = string(extractionFailureCode(err))
+		return itemResult
+	}
+
+	location, err := textStore.PutText(ctx, item.PaperID, doc.Text)
+	if err != nil {
+		itemResult.Error = err.Error()
+		itemResult.Code = string(failures.CodeS3Write)
+		return itemResult
+	}
+	if err := textStore.UpdatePaper(ctx, item.PaperID, location, doc.PageCount); err != nil {
+		itemResult.Error = err.Error()
+		itemResult.Code = string(failures.CodeStoreWrite)
+		return itemResult
+	}
+
+	itemResult.FullTextKey = location
+	itemResult.PageCount = doc.PageCount
+	itemResult.Extractor = extractor
+	return itemResult
+}
+
+// extractText uses the built-in extractor and falls back to the external service, when
+// configured, for PDFs it cannot read (scanned pages, custom font encodings)
+func extractText(ctx context.Context, remoteExtractor RemoteExtractor, pdf []byte) (*pdftext.Document, string, error) {
+	doc, err := pdftext.Extract(pdf)
+	if err == nil {
+		return doc, ExtractorBuiltin, nil
+	}
+	if remoteExtractor == nil || !errors.Is(err, pdftext.ErrNoText) {
+		return nil, "", err
+	}
+
+	remoteDoc, remoteErr := remoteExtractor.Extract(ctx, pdf)
+	if remoteErr != nil {
+		return nil, "", fmt.Errorf("built-in extractor: %v; external extractor: %w", err, remoteErr)
+	}
+	// The external service may not report pages; the built-in count is still valid
+	if remoteDoc.PageCount == 0 {
+		remoteDoc.PageCount = pdftext.CountPages(pdf)
+	}
+	return remoteDoc, ExtractorRemote, nil
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
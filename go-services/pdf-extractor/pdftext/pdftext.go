@@ -0,0 +1,526 @@
+// Package pdftext extracts plain text from PDFs without external dependencies. It reads
+// the text-showing operators of Flate-compressed or uncompressed content streams, which
+// covers most born-digital papers. Scanned PDFs and composite (Type0/CID) fonts without a
+// ToUnicode map yield no usable text and return ErrNoText, so callers can fall back to an
+// external extractor.
+package pdftext
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ErrNoText reports a PDF whose content streams held no readable text
+var ErrNoText = errors.New("no extractable text")
+
+// ErrTooLarge reports a PDF whose Flate streams inflate past MaxStreamBytes or MaxInflatedBytes
+var ErrTooLarge = errors.New("inflated content too large")
+
+// MinLetters is the number of letters below which extracted text is treated as unreadable
+const MinLetters = 50
+
+// Inflation limits, so a crafted stream cannot exhaust memory: one stream may inflate to
+// MaxStreamBytes and all streams of a document together to MaxInflatedBytes
+const (
+	MaxStreamBytes   = 32 << 20
+	MaxInflatedBytes = 128 << 20
+)
+
+// Document is the text extracted from a PDF
+type Document struct {
+	Text      string
+	PageCount int
+}
+
+var (
+	pageObjectPattern = regexp.MustCompile(`/Type\s*/Page\b`)
+	// Streams that never hold page text: images, fonts, metadata, cross-reference data
+	skippedStreamPattern = regexp.MustCompile(`/(Image|XRef|Length1|Length2|Length3|Type1C|CIDFontType0C|OpenType|Metadata|EmbeddedFile)\b`)
+	objectStreamPattern  = regexp.MustCompile(`/Type\s*/ObjStm\b`)
+	filterPattern        = regexp.MustCompile(`/Filter\s*\[?\s*/(\w+)`)
+	type0FontPattern     = regexp.MustCompile(`/Subtype\s*/Type0\b`)
+	// A direct /Length; an indirect one ("12 0 R") is not resolved
+	lengthPattern = regexp.MustCompile(`/Length\s+(\d+)(\s+\d+\s+R\b)?`)
+)
+
+// Extract returns the text and page count of a PDF
+func Extract(data []byte) (*Document, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), []byte("%PDF-")) {
+		return nil, fmt.Errorf("not a PDF: missing %%PDF- header")
+	}
+
+	doc := &Document{PageCount: countPageObjects(data)}
+	// Strings shown in a composite font are glyph IDs that only a ToUnicode map turns into text
+	unmapped := hasUnmappedType0Font(data)
+	budget := int64(MaxInflatedBytes)
+	var text strings.Builder
+	for _, stream := range findStreams(data) {
+		if skippedStreamPattern.Match(stream.dict) {
+			continue
+		}
+		content, ok, err := decodeStream(stream, &budget)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		// Pages and fonts of PDF 1.5+ files can live in compressed object streams
+		if objectStreamPattern.Match(stream.dict) {
+			doc.PageCount += countPageObjects(content)
+			unmapped = unmapped || hasUnmappedType0Font(content)
+			continue
+		}
+		extractText(&text, content)
+	}
+	if unmapped {
+		return nil, ErrNoText
+	}
+
+	doc.Text = normalize(text.String())
+	if countLetters(doc.Text) < MinLetters {
+		return nil, ErrNoText
+	}
+	return doc, nil
+}
+
+// CountPages returns the number of pages of a PDF, including pages in compressed object
+// streams; object streams that cannot be inflated within the limits are not counted
+func CountPages(data []byte) int {
+	count := countPageObjects(data)
+	budget := int64(MaxInflatedBytes)
+	for _, stream := range findStreams(data) {
+		if !objectStreamPattern.Match(stream.dict) {
+			continue
+		}
+		if content, ok, err := decodeStream(stream, &budget); err == nil && ok {
+			count += countPageObjects(content)
+		}
+	}
+	return count
+}
+
+// hasUnmappedType0Font reports whether data holds a Type0 font dictionary without a
+// ToUnicode entry
+func hasUnmappedType0Font(data []byte) bool {
+	for _, match := range type0FontPattern.FindAllIndex(data, -1) {
+		if !bytes.Contains(enclosingDict(data, match[0]), []byte("/ToUnicode")) {
+			return true
+		}
+	}
+	return false
+}
+
+// enclosingDict returns the innermost << ... >> dictionary around data[pos]; the rest of
+// data is returned when the dictionary is not closed
+func enclosingDict(data []byte, pos int) []byte {
+	start, depth := 0, 0
+	for i := pos - 1; i > 0; i-- {
+		if data[i-1] == '>' && data[i] == '>' {
+			depth++
+			i--
+		} else if data[i-1] == '<' && data[i] == '<' {
+			if depth == 0 {
+				start = i - 1
+				break
+			}
+			depth--
+			i--
+		}
+	}
+	depth = 0
+	for i := pos; i+1 < len(data); i++ {
+		if data[i] == '<' && data[i+1] == '<' {
+			depth++
+			i++
+		} else if data[i] == '>' && data[i+1] == '>' {
+			if depth == 0 {
+				return data[start : i+2]
+			}
+			depth--
+			i++
+		}
+	}
+	return data[start:]
+}
+
+// countPageObjects counts page objects (/Type /Page but not /Pages)
+func countPageObjects(data []byte) int {
+	return len(pageObjectPattern.FindAll(data, -1))
+}
+
+// rawStream is a stream's dictionary and undecoded bytes
+type rawStream struct {
+	dict []byte
+	data []byte
+}
+
+// findStreams locates every "stream ... endstream" section with the dictionary before it
+func findStreams(data []byte) []rawStream {
+	var streams []rawStream
+	offset := 0
+	for {
+		start := bytes.Index(data[offset:], []byte("stream"))
+		if start < 0 {
+			return streams
+		}
+		start += offset
+		// "endstream" also contains "stream"; skip it
+		if start >= 3 && string(data[start-3:start]) == "end" {
+			offset = start + len("stream")
+			continue
+		}
+
+		body := start + len("stream")
+		if body < len(data) && data[body] == '\r' {
+			body++
+		}
+		if body < len(data) && data[body] == '\n' {
+			body++
+		}
+		end := bytes.Index(data[body:], []byte("endstream"))
+		if end < 0 {
+			return streams
+		}
+		end += body
+
+		dictStart := bytes.LastIndex(data[offset:start], []byte("obj"))
+		dict := data[start:start]
+		if dictStart >= 0 {
+			dict = data[offset+dictStart : start]
+		}
+		streams = append(streams, rawStream{
+			dict: dict,
+			data: streamData(dict, data[body:end]),
+		})
+		offset = end + len("endstream")
+	}
+}
+
+// streamData cuts a stream's bytes to its direct /Length when it has one. Otherwise only the
+// end-of-line marker before "endstream" is dropped: compressed data may itself end in CR or
+// LF bytes, and trimming those would corrupt the stream.
+func streamData(dict, data []byte) []byte {
+	if match := lengthPattern.FindSubmatch(dict); match != nil && len(match[2]) == 0 {
+		if length, err := strconv.Atoi(string(match[1])); err == nil && length <= len(data) {
+			return data[:length]
+		}
+	}
+	switch {
+	case bytes.HasSuffix(data, []byte("\r\n")):
+		return data[:len(data)-2]
+	case bytes.HasSuffix(data, []byte("\n")), bytes.HasSuffix(data, []byte("\r")):
+		return data[:len(data)-1]
+	}
+	return data
+}
+
+// decodeStream inflates Flate streams and passes unfiltered streams through; streams
+// with other filters (e.g. image codecs) are skipped. Inflated bytes are taken from budget,
+// the allowance left for the document; going over it or MaxStreamBytes is ErrTooLarge.
+func decodeStream(stream rawStream, budget *int64) ([]byte, bool, error) {
+	match := filterPattern.FindSubmatch(stream.dict)
+	if match == nil {
+		return stream.data, true, nil
+	}
+	if filter := string(match[1]); filter != "FlateDecode" && filter != "Fl" {
+		return nil, false, nil
+	}
+	reader, err := zlib.NewReader(bytes.NewReader(stream.data))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to inflate stream: %w", err)
+	}
+	defer reader.Close()
+
+	limit := int64(MaxStreamBytes)
+	if *budget < limit {
+		limit = *budget
+	}
+	content, err := io.ReadAll(io.LimitReader(reader, limit+1))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to inflate stream: %w", err)
+	}
+	if int64(len(content)) > limit {
+		return nil, false, fmt.Errorf("%w: a stream inflates past %d bytes (stream limit %d, document limit %d)", ErrTooLarge, limit, MaxStreamBytes, MaxInflatedBytes)
+	}
+	*budget -= int64(len(content))
+	return content, len(content) > 0, nil
+}
+
+// operand is a content-stream operand: a string, number or array of both
+type operand struct {
+	str      []byte
+	num      float64
+	isString bool
+	isNumber bool
+	array    []operand
+}
+
+// extractText interprets the text operators of one content stream
+func extractText(out *strings.Builder, content []byte) {
+	var operands []operand
+	var arrayStarts []int
+	for i := 0; i < len(content); {
+		c := content[i]
+		switch {
+		case isSpace(c):
+			i++
+		case c == '%':
+			for i < len(content) && content[i] != '\n' && content[i] != '\r' {
+				i++
+			}
+		case c == '(':
+			str, next := readLiteral(content, i)
+			operands = append(operands, operand{str: str, isString: true})
+			i = next
+		case c == '<' && i+1 < len(content) && content[i+1] == '<', c == '>' && i+1 < len(content) && content[i+1] == '>':
+			i += 2
+		case c == '<':
+			str, next := readHex(content, i)
+			operands = append(operands, operand{str: str, isString: true})
+			i = next
+		case c == '[':
+			arrayStarts = append(arrayStarts, len(operands))
+			i++
+		case c == ']':
+			if n := len(arrayStarts); n > 0 {
+				start := arrayStarts[n-1]
+				arrayStarts = arrayStarts[:n-1]
+				array := append([]operand(nil), operands[start:]...)
+				operands = append(operands[:start], operand{array: array})
+			}
+			i++
+		case c == '/':
+			i++
+			for i < len(content) && !isSpace(content[i]) && !isDelimiter(content[i]) {
+				i++
+			}
+			operands = append(operands, operand{})
+		case c == '-' || c == '+' || c == '.' || (c >= '0' && c <= '9'):
+			start := i
+			i++
+			for i < len(content) && (content[i] == '.' || (content[i] >= '0' && content[i] <= '9')) {
+				i++
+			}
+			var num float64
+			fmt.Sscanf(string(content[start:i]), "%g", &num)
+			operands = append(operands, operand{num: num, isNumber: true})
+		default:
+			start := i
+			for i < len(content) && !isSpace(content[i]) && !isDelimiter(content[i]) {
+				i++
+			}
+			if i == start {
+				i++ // stray delimiter
+				continue
+			}
+			operator := string(content[start:i])
+			if operator == "BI" {
+				i = skipInlineImage(content, i)
+			}
+			applyOperator(out, operator, operands)
+			operands = operands[:0]
+			arrayStarts = arrayStarts[:0]
+		}
+	}
+}
+
+// applyOperator writes the text shown or the line breaks implied by one operator
+func applyOperator(out *strings.Builder, operator string, operands []operand) {
+	last := func() *operand {
+		if len(operands) == 0 {
+			return nil
+		}
+		return &operands[len(operands)-1]
+	}
+	switch operator {
+	case "Tj":
+		if op := last(); op != nil && op.isString {
+			writeString(out, op.str)
+		}
+	case "'", "\"":
+		newline(out)
+		if op := last(); op != nil && op.isString {
+			writeString(out, op.str)
+		}
+	case "TJ":
+		if op := last(); op != nil {
+			for _, element := range op.array {
+				if element.isString {
+					writeString(out, element.str)
+				} else if element.isNumber && element.num < -250 {
+					// A large negative adjustment is a word gap
+					space(out)
+				}
+			}
+		}
+	case "Td", "TD":
+		if len(operands) >= 2 && operands[len(operands)-1].isNumber && operands[len(operands)-1].num != 0 {
+			newline(out)
+		} else {
+			space(out)
+		}
+	case "T*", "Tm", "ET":
+		newline(out)
+	}
+}
+
+// writeString appends string bytes, decoding UTF-16BE strings and treating the rest as Latin-1
+func writeString(out *strings.Builder, str []byte) {
+	if len(str) >= 2 && str[0] == 0xfe && str[1] == 0xff {
+		for i := 2; i+1 < len(str); i += 2 {
+			if r := rune(str[i])<<8 | rune(str[i+1]); unicode.IsPrint(r) {
+				out.WriteRune(r)
+			}
+		}
+		return
+	}
+	for _, b := range str {
+		if b == '\t' || (b >= 0x20 && b < 0x7f) || b >= 0xa0 {
+			out.WriteRune(rune(b))
+		}
+	}
+}
+
+func space(out *strings.Builder) {
+	if s := out.String(); s != "" && !strings.HasSuffix(s, " ") && !strings.HasSuffix(s, "\n") {
+		out.WriteByte(' ')
+	}
+}
+
+func newline(out *strings.Builder) {
+	if s := out.String(); s != "" && !strings.HasSuffix(s, "\n") {
+		out.WriteByte('\n')
+	}
+}
+
+// readLiteral reads a (...) string starting at content[start], handling nesting and escapes
+func readLiteral(content []byte, start int) ([]byte, int) {
+	var str []byte
+	depth := 0
+	i := start
+	for ; i < len(content); i++ {
+		c := content[i]
+		switch c {
+		case '(':
+			depth++
+			if depth == 1 {
+				continue
+			}
+		case ')':
+			depth--
+			if depth == 0 {
+				return str, i + 1
+			}
+		case '\\':
+			i++
+			if i >= len(content) {
+				return str, i
+			}
+			switch e := content[i]; e {
+			case 'n':
+				str = append(str, '\n')
+			case 'r':
+				str = append(str, '\r')
+			case 't':
+				str = append(str, '\t')
+			case 'b', 'f':
+			case '\r', '\n':
+				// line continuation
+			default:
+				if e >= '0' && e <= '7' {
+					value := 0
+					for j := 0; j < 3 && i < len(content) && content[i] >= '0' && content[i] <= '7'; j++ {
+						value = value*8 + int(content[i]-'0')
+						i++
+					}
+					i--
+					str = append(str, byte(value))
+				} else {
+					str = append(str, e)
+				}
+			}
+			continue
+		}
+		str = append(str, c)
+	}
+	return str, i
+}
+
+// readHex reads a <...> hex string starting at content[start]
+func readHex(content []byte, start int) ([]byte, int) {
+	var digits []byte
+	i := start + 1
+	for ; i < len(content) && content[i] != '>'; i++ {
+		if c := content[i]; (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F') {
+			digits = append(digits, c)
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	str := make([]byte, len(digits)/2)
+	for j := range str {
+		str[j] = hexValue(digits[2*j])<<4 | hexValue(digits[2*j+1])
+	}
+	return str, i + 1
+}
+
+func hexValue(c byte) byte {
+	switch {
+	case c >= 'a':
+		return c - 'a' + 10
+	case c >= 'A':
+		return c - 'A' + 10
+	default:
+		return c - '0'
+	}
+}
+
+// skipInlineImage skips binary inline image data up to its EI operator
+func skipInlineImage(content []byte, start int) int {
+	for i := start; i+2 < len(content); i++ {
+		if isSpace(content[i]) && content[i+1] == 'E' && content[i+2] == 'I' &&
+			(i+3 == len(content) || isSpace(content[i+3])) {
+			return i + 3
+		}
+	}
+	return len(content)
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '\f' || c == 0
+}
+
+func isDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+// normalize collapses runs of spaces and blank lines
+func normalize(text string) string {
+	lines := strings.Split(text, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
+
+func countLetters(text string) int {
+	count := 0
+	for _, r := range text {
+		if unicode.IsLetter(r) {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/s3"
+
+	"shared/awsclient"
+	"shared/failures"
+	"shared/preflight"
+)
+
+// preflightTimeout bounds the remote extractor reachability request
+const preflightTimeout = 5 * time.Second
+
+// preflightGuard verifies the extractor's resources on the first invocation of a container
+var preflightGuard preflight.Guard
+
+// runPreflight checks that the papers table, the full-text bucket and, when
+// FAILED_ITEMS_TABLE_NAME is set, the FailedItems table exist and, when EXTRACTOR_URL is
+// set, that the remote extractor answers
+func runPreflight(ctx context.Context, papersTable, textBucket string) error {
+	sess := awsclient.MustSession()
+	checks := []preflight.Check{
+		preflight.Table(dynamodb.New(sess), papersTable),
+		preflight.Bucket(s3.New(sess), textBucket),
+	}
+	if failedTable := os.Getenv(failures.TableEnv); failedTable != "" {
+		checks = append(checks, preflight.TableWithIndexes(dynamodb.New(sess), failedTable, preflight.Index{
+			Name:         failures.CodeIndex,
+			PartitionKey: failures.CodeIndexPartitionKey,
+			SortKey:      failures.CodeIndexSortKey,
+		}))
+	}
+	if url := os.Getenv("EXTRACTOR_URL"); url != "" {
+		checks = append(checks, preflight.Endpoint(&http.Client{Timeout: preflightTimeout}, url))
+	}
+	return preflightGuard.Run(ctx, checks)
+}
@@ -0,0 +1,90 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"pdf-extractor/pdftext"
+	"shared/awsclient"
+)
+
+// HTTPClient interface for making HTTP requests (allows mocking)
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Client sends PDFs to an external text extraction service (e.g. a Tika or GROBID wrapper).
+// The service receives the PDF as the request body and answers {"text": ..., "page_count": ...}.
+type Client struct {
+	url        string
+	httpClient HTTPClient
+}
+
+// extractResponse is the extraction service's response body
+type extractResponse struct {
+	Text      string `json:"text"`
+	PageCount int    `json:"page_count"`
+}
+
+// NewClient creates a client for the extraction service at url
+func NewClient(url string) *Client {
+	return &Client{
+		url: url,
+		httpClient: awsclient.HTTPClient(&http.Client{
+			Timeout: 120 * time.Second,
+		}),
+	}
+}
+
+// NewClientWithHTTPClient creates a client with a custom HTTP client (for testing)
+func NewClientWithHTTPClient(url string, httpClient HTTPClient) *Client {
+	return &Client{
+		url:        url,
+		httpClient: httpClient,
+	}
+}
+
+// Extract sends the PDF to the service and returns its text and page count
+func (c *Client) Extract(ctx context.Context, pdf []byte) (*pdftext.Document, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(pdf))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create extraction request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/pdf")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("extraction request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read extraction response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("extraction service returned status %d: %s", resp.StatusCode, truncate(string(body), 200))
+	}
+
+	var response extractResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse extraction response: %w", err)
+	}
+	if response.Text == "" {
+		return nil, pdftext.ErrNoText
+	}
+	return &pdftext.Document{Text: response.Text, PageCount: response.PageCount}, nil
+}
+
+func truncate(value string, max int) string {
+	if len(value) <= max {
+		return value
+	}
+	return value[:max] + "..."
+}
@@ -0,0 +1,142 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"shared/awsclient"
+)
+
+// MaxPDFBytes caps the size of a PDF read for extraction
+const MaxPDFBytes = 50 * 1024 * 1024
+
+// DefaultPrefix is the S3 prefix extracted text is written under
+const DefaultPrefix = "fulltext"
+
+// Store reads PDFs, writes extracted text and points papers at it
+type Store struct {
+	s3Client     s3iface.S3API
+	dynamoClient dynamodbiface.DynamoDBAPI
+	papersTable  string
+	textBucket   string
+	prefix       string
+}
+
+// NewStore creates a store writing text to textBucket under prefix
+func NewStore(papersTable, textBucket, prefix string) *Store {
+	sess := awsclient.MustSession()
+	return &Store{
+		s3Client:     s3.New(sess),
+		dynamoClient: dynamodb.New(sess),
+		papersTable:  papersTable,
+		textBucket:   textBucket,
+		prefix:       strings.Trim(prefix, "/"),
+	}
+}
+
+// NewStoreWithClients creates a store with custom clients (for testing)
+func NewStoreWithClients(s3Client s3iface.S3API, dynamoClient dynamodbiface.DynamoDBAPI, papersTable, textBucket, prefix string) *Store {
+	return &Store{
+		s3Client:     s3Client,
+		dynamoClient: dynamoClient,
+		papersTable:  papersTable,
+		textBucket:   textBucket,
+		prefix:       strings.Trim(prefix, "/"),
+	}
+}
+
+// ParseLocation splits an s3://bucket/key URI
+func ParseLocation(location string) (string, string, error) {
+	bucket, key, found := strings.Cut(strings.TrimPrefix(location, "s3://"), "/")
+	if !strings.HasPrefix(location, "s3://") || !found || bucket == "" || key == "" {
+		return "", "", fmt.Errorf("invalid S3 location %q, expected s3://bucket/key", location)
+	}
+	return bucket, key, nil
+}
+
+// GetPDF reads a PDF from an s3://bucket/key location
+func (s *Store) GetPDF(ctx context.Context, location string) ([]byte, error) {
+	bucket, key, err := ParseLocation(location)
+	if err != nil {
+		return nil, err
+	}
+	output, err := s.s3Client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get PDF %s/%s: %w", bucket, key, err)
+	}
+	defer output.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(output.Body, MaxPDFBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF %s/%s: %w", bucket, key, err)
+	}
+	if len(data) > MaxPDFBytes {
+		return nil, fmt.Errorf("PDF %s/%s exceeds %d bytes", bucket, key, MaxPDFBytes)
+	}
+	return data, nil
+}
+
+// TextKey returns the key a paper's text is stored under; the paper ID is escaped
+// because IDs such as arXiv URLs contain slashes
+func (s *Store) TextKey(paperID string) string {
+	return fmt.Sprintf("%s/%s.txt", s.prefix, url.PathEscape(paperID))
+}
+
+// PutText writes a paper's extracted text and returns its s3:// location
+func (s *Store) PutText(ctx context.Context, paperID, text string) (string, error) {
+	key := s.TextKey(paperID)
+	_, err := s.s3Client.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.textBucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader([]byte(text)),
+		ContentType: aws.String("text/plain; charset=utf-8"),
+		Metadata: map[string]*string{
+			"paper-id": aws.String(paperID),
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to write full text %s/%s: %w", s.textBucket, key, err)
+	}
+	return fmt.Sprintf("s3://%s/%s", s.textBucket, key), nil
+}
+
+// UpdatePaper records the text pointer and page count on an existing paper
+func (s *Store) UpdatePaper(ctx context.Context, paperID, textLocation string, pageCount int) error {
+	now := time.Now().UTC().Format(time.RFC3339)
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String(s.papersTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"paper_id": {S: aws.String(paperID)},
+		},
+		UpdateExpression:    aws.String("SET #key = :key, #pages = :pages, #eat = :now, #uat = :now"),
+		ConditionExpression: aws.String("attribute_exists(paper_id)"),
+		ExpressionAttributeNames: map[string]*string{
+			"#key":   aws.String("full_text_key"),
+			"#pages": aws.String("page_count"),
+			"#eat":   aws.String("full_text_extracted_at"),
+			"#uat":   aws.String("updated_at"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":key":   {S: aws.String(textLocation)},
+			":pages": {N: aws.String(fmt.Sprintf("%d", pageCount))},
+			":now":   {S: aws.String(now)},
+		},
+	}
+	if _, err := s.dynamoClient.UpdateItemWithContext(ctx, input); err != nil {
+		return fmt.Errorf("failed to update paper %s: %w", paperID, err)
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+package bm25
+
+import (
+	"container/heap"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// BM25 parameters: k1 saturates term frequency, b normalizes by document length
+const (
+	k1 = 1.2
+	b  = 0.75
+)
+
+// stopwords are dropped from documents and queries
+var stopwords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "by": true,
+	"for": true, "from": true, "in": true, "is": true, "it": true, "of": true, "on": true, "or": true,
+	"that": true, "the": true, "this": true, "to": true, "we": true, "with": true,
+}
+
+// Result is one keyword match
+type Result struct {
+	Doc   uint32  // document number, equal to the paper's node in the vector index
+	Score float64 // BM25 score
+}
+
+// posting records a term's frequency in one document
+type posting struct {
+	Doc uint32
+	TF  uint32
+}
+
+// Builder collects documents for a keyword index. Documents are numbered in insertion
+// order so the build can keep them aligned with the vector index nodes.
+type Builder struct {
+	postings map[string][]posting
+	docLens  []uint32
+}
+
+// NewBuilder creates an empty keyword index builder
+func NewBuilder() *Builder {
+	return &Builder{postings: make(map[string][]posting)}
+}
+
+// Add indexes text as the next document
+func (bd *Builder) Add(text string) {
+	doc := uint32(len(bd.docLens))
+	counts := make(map[string]uint32)
+	terms := Tokenize(text)
+	for _, term := range terms {
+		counts[term]++
+	}
+	for term, tf := range counts {
+		bd.postings[term] = append(bd.postings[term], posting{Doc: doc, TF: tf})
+	}
+	bd.docLens = append(bd.docLens, uint32(len(terms)))
+}
+
+// Len returns the number of documents added
+func (bd *Builder) Len() int {
+	return len(bd.docLens)
+}
+
+// serialized is the gob-encoded form of an index
+type serialized struct {
+	Postings map[string][]posting
+	DocLens  []uint32
+}
+
+// WriteTo gob-encodes the index
+func (bd *Builder) WriteTo(w io.Writer) (int64, error) {
+	counter := &countingWriter{w: w}
+	if err := gob.NewEncoder(counter).Encode(serialized{Postings: bd.postings, DocLens: bd.docLens}); err != nil {
+		return counter.n, fmt.Errorf("failed to encode keyword index: %w", err)
+	}
+	return counter.n, nil
+}
+
+// Index is a keyword index loaded for searching; it is read-only and safe for concurrent use
+type Index struct {
+	postings  map[string][]posting
+	docLens   []uint32
+	avgDocLen float64
+}
+
+// Open loads a keyword index file
+func Open(path string) (*Index, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var data serialized
+	if err := gob.NewDecoder(file).Decode(&data); err != nil {
+		return nil, fmt.Errorf("invalid keyword index %s: %w", path, err)
+	}
+
+	total := 0.0
+	for _, length := range data.DocLens {
+		total += float64(length)
+	}
+	index := &Index{postings: data.Postings, docLens: data.DocLens}
+	if len(data.DocLens) > 0 {
+		index.avgDocLen = total / float64(len(data.DocLens))
+	}
+	return index, nil
+}
+
+// Len returns the number of indexed documents
+func (x *Index) Len() int {
+	return len(x.docLens)
+}
+
+// Search returns the k best BM25 matches for query. A non-nil allowed restricts results
+// to the documents it marks, e.g. those passing a metadata filter.
+func (x *Index) Search(query string, k int, allowed []bool) []Result {
+	if k <= 0 || x.avgDocLen == 0 {
+		return []Result{}
+	}
+
+	n := float64(len(x.docLens))
+	scores := make(map[uint32]float64)
+	seen := make(map[string]bool)
+	for _, term := range Tokenize(query) {
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+
+		postings := x.postings[term]
+		if len(postings) == 0 {
+			continue
+		}
+		df := float64(len(postings))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		for _, p := range postings {
+			if allowed != nil && !allowed[p.Doc] {
+				continue
+			}
+			tf := float64(p.TF)
+			norm := 1 - b + b*float64(x.docLens[p.Doc])/x.avgDocLen
+			scores[p.Doc] += idf * tf * (k1 + 1) / (tf + k1*norm)
+		}
+	}
+
+	top := &resultHeap{}
+	for doc, score := range scores {
+		if top.Len() < k {
+			heap.Push(top, Result{Doc: doc, Score: score})
+		} else if score > (*top)[0].Score {
+			(*top)[0] = Result{Doc: doc, Score: score}
+			heap.Fix(top, 0)
+		}
+	}
+
+	results := []Result(*top)
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Doc < results[j].Doc
+	})
+	return results
+}
+
+// Tokenize lowercases text, splits it on non-alphanumeric runes and drops stopwords
+// and single-character tokens
+func Tokenize(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	terms := fields[:0]
+	for _, field := range fields {
+		if len(field) < 2 || stopwords[field] {
+			continue
+		}
+		terms = append(terms, field)
+	}
+	return terms
+}
+
+// resultHeap keeps the lowest score on top so it can be replaced by better matches
+type resultHeap []Result
+
+func (h resultHeap) Len() int            { return len(h) }
+func (h resultHeap) Less(i, j int) bool  { return h[i].Score < h[j].Score }
+func (h resultHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *resultHeap) Push(x interface{}) { *h = append(*h, x.(Result)) }
+func (h *resultHeap) Pop() interface{} {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
+
+// countingWriter tracks bytes written
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,100 @@
+package builder
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"shared/vectorarchive"
+)
+
+// archiveSource is the S3 location of the cold vector archive
+type archiveSource struct {
+	client s3iface.S3API
+	bucket string
+	prefix string
+}
+
+// SetArchive indexes the vectors moved to the cold archive under bucket/prefix as well as the
+// ones left in the table
+func (b *IndexBuilder) SetArchive(client s3iface.S3API, bucket, prefix string) error {
+	if bucket == "" {
+		return fmt.Errorf("archive bucket must not be empty")
+	}
+	if prefix == "" {
+		prefix = vectorarchive.DefaultPrefix
+	}
+	b.archive = &archiveSource{client: client, bucket: bucket, prefix: prefix}
+	return nil
+}
+
+// addArchivedVectors reads every archive shard and indexes its vectors of the configured type.
+// Papers already read from the table are skipped: an interrupted archive run leaves a vector
+// in both tiers, and the table copy is the current one.
+func (b *IndexBuilder) addArchivedVectors(ctx context.Context, state *buildState) error {
+	var keys []string
+	err := b.archive.client.ListObjectsV2PagesWithContext(ctx, &s3.ListObjectsV2Input{
+		Bucket: aws.String(b.archive.bucket),
+		Prefix: aws.String(vectorarchive.ShardsPrefix(b.archive.prefix)),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, object := range page.Contents {
+			keys = append(keys, aws.StringValue(object.Key))
+		}
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list archive shards: %w", err)
+	}
+
+	for _, key := range keys {
+		vectors, err := b.readShard(ctx, key, state.seen)
+		if err != nil {
+			return err
+		}
+		state.archived += len(vectors)
+		if err := b.addVectors(ctx, state, vectors, true); err != nil {
+			return fmt.Errorf("archive shard %s: %w", key, err)
+		}
+	}
+
+	b.logger.WithContext(ctx).Info("Indexed archived vectors", map[string]interface{}{
+		"shards":   len(keys),
+		"archived": state.archived,
+	})
+	return nil
+}
+
+// readShard returns the vectors of the configured type in a shard whose paper was not seen yet
+func (b *IndexBuilder) readShard(ctx context.Context, key string, seen map[string]bool) ([]storedVector, error) {
+	object, err := b.archive.client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(b.archive.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get archive shard %s: %w", key, err)
+	}
+	defer object.Body.Close()
+
+	var vectors []storedVector
+	err = vectorarchive.ReadShard(object.Body, func(item map[string]*dynamodb.AttributeValue) error {
+		if vectorType := item["vector_type"]; vectorType == nil || aws.StringValue(vectorType.S) != b.vectorType {
+			return nil
+		}
+		var vector storedVector
+		if err := dynamodbattribute.UnmarshalMap(item, &vector); err != nil {
+			return fmt.Errorf("failed to unmarshal archived vector: %w", err)
+		}
+		if !seen[vector.PaperID] {
+			vectors = append(vectors, vector)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("archive shard %s: %w", key, err)
+	}
+	return vectors, nil
+}
@@ -0,0 +1,364 @@
+package builder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"search-service/bm25"
+	"search-service/hnsw"
+	"search-service/indexstore"
+	"shared/awsclient"
+	"shared/dynamo"
+	"shared/logger"
+)
+
+// DefaultVectorType is the vector type indexed unless configured otherwise
+const DefaultVectorType = "title_abstract"
+
+// storedVector holds the vector table attributes read by the build
+type storedVector struct {
+	PaperID   string    `dynamodbav:"paper_id"`
+	Embedding []float64 `dynamodbav:"embedding"`
+	Status    string    `dynamodbav:"status"` // "pending" while the paper's vectors are being written
+}
+
+// vectorStatusPending marks a vector whose paper's vectors are not all written yet. Such
+// vectors are left out of the index; vectors without a status predate the flag and are ready.
+const vectorStatusPending = "pending"
+
+// paperMetadata holds the papers table attributes indexed for filtering and keyword search
+type paperMetadata struct {
+	PaperID       string   `dynamodbav:"paper_id"`
+	Title         string   `dynamodbav:"title"`
+	Abstract      string   `dynamodbav:"abstract"`
+	Source        string   `dynamodbav:"source"`
+	Categories    []string `dynamodbav:"categories"`
+	PublishedDate string   `dynamodbav:"published_date"`
+	Deleted       bool     `dynamodbav:"deleted"`
+}
+
+// maxBatchGetKeys is the DynamoDB limit on keys per BatchGetItem request
+const maxBatchGetKeys = 100
+
+// BuildResult summarizes an index build
+type BuildResult struct {
+	Manifest        *indexstore.Manifest `json:"manifest"`
+	PagesScanned    int                  `json:"pages_scanned"`
+	ArchivedVectors int                  `json:"archived_vectors"` // indexed from the cold archive
+	ScanTimeMs      int64                `json:"scan_time_ms"`
+}
+
+// IndexBuilder scans the vectors table and publishes an HNSW index of one vector type,
+// with each paper's source, categories and published date joined from the papers table.
+// A BM25 keyword index over the same papers' titles and abstracts is published alongside,
+// with documents in the same order as the graph nodes.
+type IndexBuilder struct {
+	client      dynamodbiface.DynamoDBAPI
+	tableName   string
+	papersTable string
+	vectorType  string
+	config      hnsw.Config
+	store       *indexstore.Store
+	workDir     string
+	archive     *archiveSource // nil without a cold archive
+	logger      *logger.Logger
+}
+
+// buildState accumulates the indexes and counters of a build across table pages and archive shards
+type buildState struct {
+	graph      *hnsw.Builder
+	keywords   *bm25.Builder
+	seen       map[string]bool // papers whose vector was read, so archived copies are not indexed twice
+	skipped    int
+	tombstoned int
+	pending    int
+	archived   int
+}
+
+// NewIndexBuilder creates a builder reading from the vectors and papers tables
+func NewIndexBuilder(tableName, papersTable, vectorType string, config hnsw.Config, store *indexstore.Store, workDir string) *IndexBuilder {
+	sess := awsclient.MustSession()
+	return NewIndexBuilderWithClient(dynamodb.New(sess), tableName, papersTable, vectorType, config, store, workDir)
+}
+
+// NewIndexBuilderWithClient creates a builder with a custom DynamoDB client (for testing)
+func NewIndexBuilderWithClient(client dynamodbiface.DynamoDBAPI, tableName, papersTable, vectorType string, config hnsw.Config, store *indexstore.Store, workDir string) *IndexBuilder {
+	return &IndexBuilder{
+		client:      client,
+		tableName:   tableName,
+		papersTable: papersTable,
+		vectorType:  vectorType,
+		config:      config,
+		store:       store,
+		workDir:     workDir,
+		logger:      logger.New("index-builder"),
+	}
+}
+
+// Build scans every vector of the configured type, builds the graph and publishes it
+func (b *IndexBuilder) Build(ctx context.Context) (*BuildResult, error) {
+	start := time.Now()
+	contextLogger := b.logger.WithContext(ctx)
+	contextLogger.Info("Starting vector index build", map[string]interface{}{
+		"table_name":      b.tableName,
+		"vector_type":     b.vectorType,
+		"m":               b.config.M,
+		"ef_construction": b.config.EfConstruction,
+	})
+
+	state := &buildState{keywords: bm25.NewBuilder(), seen: make(map[string]bool)}
+	input := &dynamodb.ScanInput{
+		TableName:            aws.String(b.tableName),
+		FilterExpression:     aws.String("#vt = :vt"),
+		ProjectionExpression: aws.String("#pid, #emb, #st"),
+		ExpressionAttributeNames: map[string]*string{
+			"#vt":  aws.String("vector_type"),
+			"#pid": aws.String("paper_id"),
+			"#emb": aws.String("embedding"),
+			"#st":  aws.String("status"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":vt": {S: aws.String(b.vectorType)},
+		},
+	}
+	scan, err := dynamo.Paginate(ctx, dynamo.Scan(b.client, input), dynamo.Options{}, func(page dynamo.Page) error {
+		var vectors []storedVector
+		if err := dynamodbattribute.UnmarshalListOfMaps(page.Items, &vectors); err != nil {
+			return fmt.Errorf("failed to unmarshal vectors on page %d: %w", page.Number, err)
+		}
+		if err := b.addVectors(ctx, state, vectors, false); err != nil {
+			return fmt.Errorf("page %d: %w", page.Number, err)
+		}
+
+		contextLogger.Debug("Scanned vector page", map[string]interface{}{
+			"page_number":   page.Number,
+			"items":         len(page.Items),
+			"indexed_total": indexedCount(state.graph),
+		})
+		return nil
+	})
+	if err != nil {
+		var fetchErr *dynamo.FetchError
+		if errors.As(err, &fetchErr) {
+			return nil, fmt.Errorf("failed to scan vectors on page %d: %w", fetchErr.Page, fetchErr.Err)
+		}
+		return nil, err
+	}
+	if b.archive != nil {
+		if err := b.addArchivedVectors(ctx, state); err != nil {
+			return nil, err
+		}
+	}
+	scanTime := time.Since(start)
+
+	graph, keywords := state.graph, state.keywords
+	skipped, tombstoned, pending := state.skipped, state.tombstoned, state.pending
+	if graph == nil || graph.Len() == 0 {
+		return nil, fmt.Errorf("no %s vectors found in %s", b.vectorType, b.tableName)
+	}
+
+	stamp := time.Now().UnixNano()
+	localPath := filepath.Join(b.workDir, fmt.Sprintf("vector-index-%d.hnsw", stamp))
+	size, err := writeIndex(graph, localPath)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(localPath)
+
+	keywordPath := filepath.Join(b.workDir, fmt.Sprintf("vector-index-%d.bm25", stamp))
+	keywordSize, err := writeIndex(keywords, keywordPath)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(keywordPath)
+
+	manifest, err := b.store.Publish(ctx, localPath, keywordPath, indexstore.Manifest{
+		VectorType:     b.vectorType,
+		Dimension:      graph.Dimension(),
+		VectorCount:    graph.Len(),
+		SkippedVectors: skipped,
+		Tombstoned:     tombstoned,
+		Pending:        pending,
+		M:              b.config.M,
+		EfConstruction: b.config.EfConstruction,
+		SizeBytes:      size,
+		KeywordBytes:   keywordSize,
+		BuildTimeMs:    time.Since(start).Milliseconds(),
+		BuiltAt:        time.Now().UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	contextLogger.InfoWithDuration("Vector index build completed", time.Since(start), map[string]interface{}{
+		"key":             manifest.Key,
+		"vector_count":    manifest.VectorCount,
+		"skipped_vectors": skipped,
+		"tombstoned":      tombstoned,
+		"pending":         pending,
+		"pages_scanned":   scan.Pages,
+		"archived":        state.archived,
+		"scan_time_ms":    scanTime.Milliseconds(),
+		"size_bytes":      size,
+		"keyword_bytes":   keywordSize,
+	})
+
+	return &BuildResult{
+		Manifest:        manifest,
+		PagesScanned:    scan.Pages,
+		ArchivedVectors: state.archived,
+		ScanTimeMs:      scanTime.Milliseconds(),
+	}, nil
+}
+
+// addVectors joins a batch of vectors with their papers' metadata and adds them to the
+// indexes, counting the ones left out. Archived vectors are only indexed while their paper
+// exists, as purges do not rewrite archive shards.
+func (b *IndexBuilder) addVectors(ctx context.Context, state *buildState, vectors []storedVector, archived bool) error {
+	metadata, err := b.fetchMetadata(ctx, vectors)
+	if err != nil {
+		return fmt.Errorf("failed to fetch paper metadata: %w", err)
+	}
+
+	for _, vector := range vectors {
+		state.seen[vector.PaperID] = true
+		if vector.Status == vectorStatusPending {
+			state.pending++
+			continue
+		}
+		paper, found := metadata[vector.PaperID]
+		if (found && paper.Deleted) || (!found && archived) {
+			state.tombstoned++
+			continue
+		}
+		if state.graph == nil && len(vector.Embedding) > 0 {
+			if state.graph, err = hnsw.NewBuilder(len(vector.Embedding), b.config); err != nil {
+				return err
+			}
+		}
+		if state.graph == nil {
+			state.skipped++
+			continue
+		}
+		if err := state.graph.Add(vector.PaperID, vector.Embedding, hnsw.Metadata{
+			Source:        paper.Source,
+			Categories:    paper.Categories,
+			PublishedDate: paper.PublishedDate,
+		}); err != nil {
+			state.skipped++
+			b.logger.WithContext(ctx).Warn("Skipping vector that cannot be indexed", map[string]interface{}{
+				"paper_id": vector.PaperID,
+				"error":    err.Error(),
+			})
+			continue
+		}
+		state.keywords.Add(paper.Title + "\n" + paper.Abstract)
+	}
+	return nil
+}
+
+// fetchMetadata batch-gets the papers of a scan page, keyed by paper_id. Papers missing
+// from the table are absent from the map and indexed without metadata.
+func (b *IndexBuilder) fetchMetadata(ctx context.Context, vectors []storedVector) (map[string]paperMetadata, error) {
+	metadata := make(map[string]paperMetadata, len(vectors))
+	seen := make(map[string]bool, len(vectors))
+	keys := make([]map[string]*dynamodb.AttributeValue, 0, maxBatchGetKeys)
+
+	flush := func() error {
+		if len(keys) == 0 {
+			return nil
+		}
+		requestItems := map[string]*dynamodb.KeysAndAttributes{
+			b.papersTable: {
+				Keys:                 keys,
+				ProjectionExpression: aws.String("#pid, #title, #abs, #src, #cat, #pub, #del"),
+				ExpressionAttributeNames: map[string]*string{
+					"#pid":   aws.String("paper_id"),
+					"#title": aws.String("title"),
+					"#abs":   aws.String("abstract"),
+					"#src":   aws.String("source"),
+					"#cat":   aws.String("categories"),
+					"#pub":   aws.String("published_date"),
+					"#del":   aws.String("deleted"),
+				},
+			},
+		}
+
+		maxRetries := 3
+		for attempt := 0; attempt < maxRetries && len(requestItems) > 0; attempt++ {
+			result, err := b.client.BatchGetItemWithContext(ctx, &dynamodb.BatchGetItemInput{
+				RequestItems: requestItems,
+			})
+			if err != nil {
+				return fmt.Errorf("batch get failed on attempt %d: %w", attempt+1, err)
+			}
+
+			var papers []paperMetadata
+			if err := dynamodbattribute.UnmarshalListOfMaps(result.Responses[b.papersTable], &papers); err != nil {
+				return fmt.Errorf("failed to unmarshal papers: %w", err)
+			}
+			for _, paper := range papers {
+				metadata[paper.PaperID] = paper
+			}
+			requestItems = result.UnprocessedKeys
+		}
+		if len(requestItems) > 0 {
+			return fmt.Errorf("unprocessed keys remained after %d retries", maxRetries)
+		}
+
+		keys = keys[:0]
+		return nil
+	}
+
+	for _, vector := range vectors {
+		if vector.PaperID == "" || seen[vector.PaperID] {
+			continue
+		}
+		seen[vector.PaperID] = true
+		keys = append(keys, map[string]*dynamodb.AttributeValue{
+			"paper_id": {S: aws.String(vector.PaperID)},
+		})
+		if len(keys) == maxBatchGetKeys {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
+
+// writeIndex serializes an index to localPath and returns the file size
+func writeIndex(index io.WriterTo, localPath string) (int64, error) {
+	file, err := os.Create(localPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create index file: %w", err)
+	}
+	size, err := index.WriteTo(file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(localPath)
+		return 0, err
+	}
+	return size, nil
+}
+
+func indexedCount(graph *hnsw.Builder) int {
+	if graph == nil {
+		return 0
+	}
+	return graph.Len()
+}
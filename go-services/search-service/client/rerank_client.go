@@ -0,0 +1,113 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"shared/awsclient"
+	"shared/logger"
+)
+
+// RerankAPIClient scores query-document relevance with a hosted cross-encoder. It speaks the
+// rerank request format shared by Cohere, Jina and Voyage style providers.
+type RerankAPIClient struct {
+	baseURL    string
+	apiKey     string
+	model      string
+	httpClient HTTPClient
+	logger     *logger.Logger
+}
+
+// RerankRequest represents the request payload for the re-ranking API
+type RerankRequest struct {
+	Model     string   `json:"model,omitempty"`
+	Query     string   `json:"query"`
+	Documents []string `json:"documents"`
+}
+
+// RerankResponse represents the response from the re-ranking API; results may come in any
+// order and refer to documents by index
+type RerankResponse struct {
+	Results []struct {
+		Index          int     `json:"index"`
+		RelevanceScore float64 `json:"relevance_score"`
+	} `json:"results"`
+}
+
+// NewRerankAPIClient creates a client for the re-ranking endpoint at baseURL. An empty apiKey
+// sends no Authorization header and an empty model leaves the choice to the provider.
+func NewRerankAPIClient(baseURL, apiKey, model string) *RerankAPIClient {
+	return &RerankAPIClient{
+		baseURL: baseURL,
+		apiKey:  apiKey,
+		model:   model,
+		httpClient: awsclient.HTTPClient(&http.Client{
+			Timeout: 10 * time.Second,
+		}),
+		logger: logger.New("rerank-api-client"),
+	}
+}
+
+// Score returns the relevance of each document to the query, in the order of documents
+func (c *RerankAPIClient) Score(ctx context.Context, query string, documents []string) ([]float64, error) {
+	contextLogger := c.logger.WithContext(ctx)
+	startTime := time.Now()
+
+	requestBody, err := json.Marshal(RerankRequest{Model: c.model, Query: query, Documents: documents})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL, bytes.NewBuffer(requestBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(responseBody))
+	}
+
+	var rerankResponse RerankResponse
+	if err := json.Unmarshal(responseBody, &rerankResponse); err != nil {
+		return nil, fmt.Errorf("failed to parse rerank response: %w", err)
+	}
+	if len(rerankResponse.Results) != len(documents) {
+		return nil, fmt.Errorf("invalid rerank response: %d results for %d documents", len(rerankResponse.Results), len(documents))
+	}
+
+	scores := make([]float64, len(documents))
+	seen := make([]bool, len(documents))
+	for _, result := range rerankResponse.Results {
+		if result.Index < 0 || result.Index >= len(documents) || seen[result.Index] {
+			return nil, fmt.Errorf("invalid rerank response: unexpected document index %d", result.Index)
+		}
+		seen[result.Index] = true
+		scores[result.Index] = result.RelevanceScore
+	}
+
+	contextLogger.InfoWithDuration("Scored documents with re-ranking API", time.Since(startTime), map[string]interface{}{
+		"documents": len(documents),
+		"model":     c.model,
+	})
+	return scores, nil
+}
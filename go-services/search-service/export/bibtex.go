@@ -0,0 +1,116 @@
+package export
+
+import (
+	"fmt"
+	"strings"
+
+	"search-service/papers"
+)
+
+// titleStopWords are skipped when picking the title word of a citation key
+var titleStopWords = map[string]bool{
+	"a": true, "an": true, "the": true, "on": true, "of": true, "for": true, "in": true, "to": true, "and": true,
+}
+
+// bibtexEscaper escapes the characters BibTeX and LaTeX treat specially
+var bibtexEscaper = strings.NewReplacer(
+	`\`, `\textbackslash{}`,
+	"{", `\{`,
+	"}", `\}`,
+	"&", `\&`,
+	"%", `\%`,
+	"$", `\$`,
+	"#", `\#`,
+	"_", `\_`,
+	"~", `\textasciitilde{}`,
+	"^", `\textasciicircum{}`,
+)
+
+// BibTeX renders the references as BibTeX entries. arXiv papers become @misc e-prints with
+// their arXiv ID and primary category; other papers become @article entries. Citation keys
+// follow the surname-year-titleword convention, suffixed a, b, ... when they collide.
+func BibTeX(references []papers.Reference) string {
+	var b strings.Builder
+	used := make(map[string]int)
+	for i, reference := range references {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		entryType := "article"
+		if isArxiv(reference) {
+			entryType = "misc"
+		}
+		fmt.Fprintf(&b, "@%s{%s,\n", entryType, citationKey(reference, used))
+
+		// Double braces keep reference managers from changing the title's case
+		writeBibField(&b, "title", "{"+bibtexEscaper.Replace(reference.Title)+"}")
+		if len(reference.Authors) > 0 {
+			authors := make([]string, len(reference.Authors))
+			for j, author := range reference.Authors {
+				authors[j] = bibtexEscaper.Replace(author)
+			}
+			writeBibField(&b, "author", strings.Join(authors, " and "))
+		}
+		if date, ok := published(reference.PublishedDate); ok {
+			writeBibField(&b, "year", fmt.Sprintf("%d", date.Year()))
+			writeBibField(&b, "month", strings.ToLower(date.Month().String()[:3]))
+		}
+		if isArxiv(reference) {
+			writeBibField(&b, "eprint", reference.PaperID)
+			writeBibField(&b, "archivePrefix", "arXiv")
+			if len(reference.Categories) > 0 {
+				writeBibField(&b, "primaryClass", reference.Categories[0])
+			}
+			writeBibField(&b, "url", arxivURL(reference.PaperID))
+		}
+		if reference.DOI != "" {
+			writeBibField(&b, "doi", reference.DOI)
+		}
+		if len(reference.Categories) > 0 {
+			writeBibField(&b, "keywords", bibtexEscaper.Replace(strings.Join(reference.Categories, ", ")))
+		}
+		if reference.Abstract != "" {
+			writeBibField(&b, "abstract", bibtexEscaper.Replace(reference.Abstract))
+		}
+		b.WriteString("}\n")
+	}
+	return b.String()
+}
+
+// writeBibField writes one braced field line, collapsing line breaks in the value
+func writeBibField(b *strings.Builder, name, value string) {
+	fmt.Fprintf(b, "  %s = {%s},\n", name, strings.Join(strings.Fields(value), " "))
+}
+
+// citationKey builds a unique key such as "vaswani2017attention"
+func citationKey(reference papers.Reference, used map[string]int) string {
+	var key string
+	if len(reference.Authors) > 0 {
+		key = keyPart(surname(reference.Authors[0]))
+	}
+	if date, ok := published(reference.PublishedDate); ok {
+		key += fmt.Sprintf("%d", date.Year())
+	}
+	for _, word := range strings.Fields(reference.Title) {
+		if word = keyPart(word); word != "" && !titleStopWords[word] {
+			key += word
+			break
+		}
+	}
+	if key == "" {
+		key = keyPart(reference.PaperID)
+	}
+	if key == "" {
+		key = "paper"
+	}
+
+	used[key]++
+	if count := used[key]; count > 1 {
+		// 2 -> "a", 3 -> "b", ...; the first paper keeps the bare key
+		key += string(rune('a' + (count-2)%26))
+		if count-2 >= 26 {
+			key += fmt.Sprintf("%d", (count-2)/26)
+		}
+	}
+	return key
+}
@@ -0,0 +1,100 @@
+// Package export renders stored paper metadata as BibTeX or RIS, so search results can be
+// imported directly into reference managers.
+package export
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"search-service/papers"
+)
+
+// Export formats
+const (
+	FormatBibTeX = "bibtex"
+	FormatRIS    = "ris"
+)
+
+// File is a rendered export
+type File struct {
+	Format      string `json:"format"`
+	ContentType string `json:"content_type"`
+	Filename    string `json:"filename"`
+	Count       int    `json:"count"`
+	Content     string `json:"content"`
+}
+
+// ParseFormat validates an export format name; "bib" is accepted for BibTeX
+func ParseFormat(name string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case FormatBibTeX, "bib":
+		return FormatBibTeX, nil
+	case FormatRIS:
+		return FormatRIS, nil
+	}
+	return "", fmt.Errorf("unsupported export format %q, expected bibtex or ris", name)
+}
+
+// Render formats the references in order, naming the file after basename
+func Render(format string, references []papers.Reference, basename string) (*File, error) {
+	file := &File{Format: format, Count: len(references)}
+	switch format {
+	case FormatBibTeX:
+		file.ContentType = "application/x-bibtex; charset=utf-8"
+		file.Filename = basename + ".bib"
+		file.Content = BibTeX(references)
+	case FormatRIS:
+		file.ContentType = "application/x-research-info-systems; charset=utf-8"
+		file.Filename = basename + ".ris"
+		file.Content = RIS(references)
+	default:
+		return nil, fmt.Errorf("unsupported export format %q", format)
+	}
+	return file, nil
+}
+
+// published parses a stored published_date (RFC 3339 or YYYY-MM-DD); ok is false when it is
+// missing or unreadable
+func published(value string) (time.Time, bool) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return parsed, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// isArxiv reports whether the paper came from arXiv, whose IDs are exported as e-prints
+func isArxiv(reference papers.Reference) bool {
+	return strings.EqualFold(reference.Source, "arxiv")
+}
+
+// arxivURL is the abstract page of an arXiv paper
+func arxivURL(paperID string) string {
+	return "https://arxiv.org/abs/" + paperID
+}
+
+// surname returns the family name of an author given as "First Last" or "Last, First"
+func surname(author string) string {
+	if last, _, found := strings.Cut(author, ","); found {
+		return strings.TrimSpace(last)
+	}
+	fields := strings.Fields(author)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[len(fields)-1]
+}
+
+// keyPart lowercases a value and keeps only ASCII letters and digits, for citation keys
+func keyPart(value string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(value) {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
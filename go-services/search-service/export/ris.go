@@ -0,0 +1,54 @@
+package export
+
+import (
+	"fmt"
+	"strings"
+
+	"search-service/papers"
+)
+
+// risLineEnd terminates RIS lines; CRLF is what the format's importers expect
+const risLineEnd = "\r\n"
+
+// RIS renders the references as RIS records. arXiv papers are typed GEN (preprint) with
+// their abstract page as UR; other papers are typed JOUR.
+func RIS(references []papers.Reference) string {
+	var b strings.Builder
+	for _, reference := range references {
+		recordType := "JOUR"
+		if isArxiv(reference) {
+			recordType = "GEN"
+		}
+		writeRISTag(&b, "TY", recordType)
+		writeRISTag(&b, "ID", reference.PaperID)
+		writeRISTag(&b, "TI", reference.Title)
+		for _, author := range reference.Authors {
+			writeRISTag(&b, "AU", author)
+		}
+		if date, ok := published(reference.PublishedDate); ok {
+			writeRISTag(&b, "PY", fmt.Sprintf("%d", date.Year()))
+			writeRISTag(&b, "DA", date.Format("2006/01/02"))
+		}
+		writeRISTag(&b, "AB", reference.Abstract)
+		for _, category := range reference.Categories {
+			writeRISTag(&b, "KW", category)
+		}
+		writeRISTag(&b, "DO", reference.DOI)
+		if isArxiv(reference) {
+			writeRISTag(&b, "UR", arxivURL(reference.PaperID))
+		}
+		writeRISTag(&b, "DB", reference.Source)
+		b.WriteString("ER  - " + risLineEnd)
+	}
+	return b.String()
+}
+
+// writeRISTag writes one "XX  - value" line, skipping empty values. Line breaks inside a
+// value would start a malformed tag, so whitespace is collapsed.
+func writeRISTag(b *strings.Builder, tag, value string) {
+	value = strings.Join(strings.Fields(value), " ")
+	if value == "" {
+		return
+	}
+	b.WriteString(tag + "  - " + value + risLineEnd)
+}
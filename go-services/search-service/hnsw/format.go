@@ -0,0 +1,454 @@
+package hnsw
+
+import (
+	"bufio"
+	"bytes"
+	"container/heap"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+)
+
+// File layout, little-endian throughout:
+//
+//	header       magic[8] dimension count M maxLevel entry reserved (uint32 each)
+//	vectors      count*dimension float32, L2-normalized
+//	nodeOffsets  count uint64, file offset of each node's link record
+//	idOffsets    count+1 uint64, file offsets delimiting each paper ID
+//	links        per node: level uint32, then per level 0..level: n uint32, n neighbor uint32
+//	ids          concatenated paper ID bytes
+//	strings      n uint32, n+1 uint64 file offsets, concatenated source and category bytes
+//	metaOffsets  count+1 uint64, file offsets delimiting each node's metadata record
+//	metadata     per node: source uint32, published YYYYMMDD uint32, n uint32, n category uint32
+//
+// Vectors, links and metadata are read in place, so a memory-mapped index is searched
+// without decoding it onto the heap. Version 02 added the strings and metadata sections.
+var magic = [8]byte{'P', 'P', 'H', 'N', 'S', 'W', '0', '2'}
+
+const headerSize = 32
+
+// WriteTo serializes the graph in the index file format
+func (b *Builder) WriteTo(w io.Writer) (int64, error) {
+	if len(b.ids) == 0 {
+		return 0, fmt.Errorf("cannot serialize an empty index")
+	}
+	count := uint64(len(b.ids))
+
+	var links bytes.Buffer
+	linkOffsets := make([]uint64, count)
+	for node, levels := range b.links {
+		linkOffsets[node] = uint64(links.Len())
+		writeUint32(&links, uint32(len(levels)-1))
+		for _, neighbors := range levels {
+			writeUint32(&links, uint32(len(neighbors)))
+			for _, n := range neighbors {
+				writeUint32(&links, n)
+			}
+		}
+	}
+
+	var metadata bytes.Buffer
+	metaOffsets := make([]uint64, count+1)
+	for node, meta := range b.metadata {
+		metaOffsets[node] = uint64(metadata.Len())
+		writeUint32(&metadata, meta.source)
+		writeUint32(&metadata, meta.published)
+		writeUint32(&metadata, uint32(len(meta.categories)))
+		for _, category := range meta.categories {
+			writeUint32(&metadata, category)
+		}
+	}
+	metaOffsets[count] = uint64(metadata.Len())
+
+	idBytes := uint64(0)
+	for _, id := range b.ids {
+		idBytes += uint64(len(id))
+	}
+	stringBytes := uint64(0)
+	for _, s := range b.strings.strings {
+		stringBytes += uint64(len(s))
+	}
+	stringCount := uint64(len(b.strings.strings))
+
+	vectorsStart := uint64(headerSize)
+	nodeOffsetsStart := vectorsStart + count*uint64(b.dimension)*4
+	idOffsetsStart := nodeOffsetsStart + count*8
+	linksStart := idOffsetsStart + (count+1)*8
+	idsStart := linksStart + uint64(links.Len())
+	stringsStart := idsStart + idBytes
+	stringBytesStart := stringsStart + 4 + (stringCount+1)*8
+	metaOffsetsStart := stringBytesStart + stringBytes
+	metaStart := metaOffsetsStart + (count+1)*8
+
+	bw := bufio.NewWriter(w)
+	counter := &countingWriter{w: bw}
+
+	header := make([]byte, headerSize)
+	copy(header, magic[:])
+	binary.LittleEndian.PutUint32(header[8:], uint32(b.dimension))
+	binary.LittleEndian.PutUint32(header[12:], uint32(count))
+	binary.LittleEndian.PutUint32(header[16:], uint32(b.cfg.M))
+	binary.LittleEndian.PutUint32(header[20:], uint32(b.maxLevel))
+	binary.LittleEndian.PutUint32(header[24:], b.entry)
+	counter.Write(header)
+
+	buf := make([]byte, 8)
+	for _, vector := range b.vectors {
+		for _, v := range vector {
+			binary.LittleEndian.PutUint32(buf, math.Float32bits(v))
+			counter.Write(buf[:4])
+		}
+	}
+	for _, offset := range linkOffsets {
+		binary.LittleEndian.PutUint64(buf, linksStart+offset)
+		counter.Write(buf)
+	}
+	idOffset := idsStart
+	for _, id := range b.ids {
+		binary.LittleEndian.PutUint64(buf, idOffset)
+		counter.Write(buf)
+		idOffset += uint64(len(id))
+	}
+	binary.LittleEndian.PutUint64(buf, idOffset)
+	counter.Write(buf)
+	counter.Write(links.Bytes())
+	for _, id := range b.ids {
+		counter.Write([]byte(id))
+	}
+
+	binary.LittleEndian.PutUint32(buf, uint32(stringCount))
+	counter.Write(buf[:4])
+	stringOffset := stringBytesStart
+	for _, s := range b.strings.strings {
+		binary.LittleEndian.PutUint64(buf, stringOffset)
+		counter.Write(buf)
+		stringOffset += uint64(len(s))
+	}
+	binary.LittleEndian.PutUint64(buf, stringOffset)
+	counter.Write(buf)
+	for _, s := range b.strings.strings {
+		counter.Write([]byte(s))
+	}
+	for _, offset := range metaOffsets {
+		binary.LittleEndian.PutUint64(buf, metaStart+offset)
+		counter.Write(buf)
+	}
+	counter.Write(metadata.Bytes())
+
+	if counter.err != nil {
+		return counter.n, fmt.Errorf("failed to write index: %w", counter.err)
+	}
+	if err := bw.Flush(); err != nil {
+		return counter.n, fmt.Errorf("failed to flush index: %w", err)
+	}
+	return counter.n, nil
+}
+
+// Index is a serialized HNSW graph opened for searching. It is safe for concurrent
+// searches; Close waits for in-flight searches before releasing the mapping.
+type Index struct {
+	mu        sync.RWMutex
+	data      []byte
+	release   func() error
+	dimension int
+	count     int
+	maxLevel  int
+	entry     uint32
+	vectors   int // byte offsets of the sections
+	nodeOffs  int
+	idOffs    int
+	metaOffs  int
+	strings   map[string]uint32
+}
+
+// Open memory-maps an index file
+func Open(path string) (*Index, error) {
+	data, release, err := mapFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to map index %s: %w", path, err)
+	}
+	index, err := newIndex(data, release)
+	if err != nil {
+		release()
+		return nil, fmt.Errorf("invalid index %s: %w", path, err)
+	}
+	return index, nil
+}
+
+// newIndex validates the header and section bounds of a serialized index
+func newIndex(data []byte, release func() error) (*Index, error) {
+	if len(data) < headerSize || !bytes.Equal(data[:8], magic[:]) {
+		return nil, fmt.Errorf("not an HNSW index file")
+	}
+
+	index := &Index{
+		data:      data,
+		release:   release,
+		dimension: int(binary.LittleEndian.Uint32(data[8:])),
+		count:     int(binary.LittleEndian.Uint32(data[12:])),
+		maxLevel:  int(binary.LittleEndian.Uint32(data[20:])),
+		entry:     binary.LittleEndian.Uint32(data[24:]),
+	}
+	if index.dimension == 0 || index.count == 0 || int(index.entry) >= index.count {
+		return nil, fmt.Errorf("corrupt header")
+	}
+
+	index.vectors = headerSize
+	index.nodeOffs = index.vectors + index.count*index.dimension*4
+	index.idOffs = index.nodeOffs + index.count*8
+	if index.idOffs+(index.count+1)*8 > len(data) {
+		return nil, fmt.Errorf("file is truncated")
+	}
+
+	stringsStart := int(index.offset(index.idOffs, index.count))
+	if stringsStart+4 > len(data) {
+		return nil, fmt.Errorf("file is truncated")
+	}
+	stringCount := int(binary.LittleEndian.Uint32(data[stringsStart:]))
+	stringOffs := stringsStart + 4
+	if stringOffs+(stringCount+1)*8 > len(data) {
+		return nil, fmt.Errorf("file is truncated")
+	}
+	index.strings = make(map[string]uint32, stringCount)
+	for i := 0; i < stringCount; i++ {
+		start, end := index.offset(stringOffs, i), index.offset(stringOffs, i+1)
+		if start > end || end > uint64(len(data)) {
+			return nil, fmt.Errorf("corrupt strings section")
+		}
+		index.strings[string(data[start:end])] = uint32(i)
+	}
+
+	index.metaOffs = int(index.offset(stringOffs, stringCount))
+	if index.metaOffs+(index.count+1)*8 > len(data) {
+		return nil, fmt.Errorf("file is truncated")
+	}
+	if int(index.offset(index.metaOffs, index.count)) != len(data) {
+		return nil, fmt.Errorf("file size does not match its sections")
+	}
+	return index, nil
+}
+
+// Close releases the index mapping
+func (x *Index) Close() error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.data == nil {
+		return nil
+	}
+	x.data = nil
+	return x.release()
+}
+
+// Len returns the number of indexed vectors
+func (x *Index) Len() int {
+	return x.count
+}
+
+// Dimension returns the vector dimension
+func (x *Index) Dimension() int {
+	return x.dimension
+}
+
+// exactSearchThreshold is the number of filter matches below which a filtered search
+// scores every match directly instead of walking the graph
+const exactSearchThreshold = 2048
+
+// Search returns the k papers whose vectors are most similar to query. ef bounds the
+// candidates explored; larger values trade latency for recall. A non-empty filter is
+// applied during the graph walk, and selective filters are answered by an exact scan.
+func (x *Index) Search(query []float64, k, ef int, filter *Filter) ([]Result, error) {
+	if k <= 0 {
+		return nil, fmt.Errorf("k must be positive, got %d", k)
+	}
+	if len(query) != x.dimension {
+		return nil, fmt.Errorf("query has dimension %d, index has %d", len(query), x.dimension)
+	}
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	normalized, err := normalize(query)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	if x.data == nil {
+		return nil, fmt.Errorf("index is closed")
+	}
+	if filter.IsEmpty() {
+		return search(x, x.entry, x.maxLevel, normalized, k, ef, nil, x.id), nil
+	}
+
+	allowed, matches := x.allowed(filter)
+	if matches == 0 {
+		return []Result{}, nil
+	}
+	allow := func(node uint32) bool { return allowed[node] }
+	if matches > exactSearchThreshold {
+		results := search(x, x.entry, x.maxLevel, normalized, k, ef, allow, x.id)
+		if len(results) >= k || len(results) == matches {
+			return results, nil
+		}
+		// The walk ran out of reachable matches; score them all instead
+	}
+	return x.exactSearch(normalized, k, allowed), nil
+}
+
+// Matching returns, per node, whether the paper passes filter, so another index built in
+// the same node order (such as the keyword index) can apply the same filter. It returns
+// nil for an empty filter.
+func (x *Index) Matching(filter *Filter) ([]bool, error) {
+	if filter.IsEmpty() {
+		return nil, nil
+	}
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	if x.data == nil {
+		return nil, fmt.Errorf("index is closed")
+	}
+	allowed, matches := x.allowed(filter)
+	if matches == 0 {
+		return make([]bool, x.count), nil
+	}
+	return allowed, nil
+}
+
+// PaperID returns the paper ID of a node
+func (x *Index) PaperID(node uint32) (string, error) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	if x.data == nil {
+		return "", fmt.Errorf("index is closed")
+	}
+	if int(node) >= x.count {
+		return "", fmt.Errorf("node %d out of range (%d vectors)", node, x.count)
+	}
+	return x.id(node), nil
+}
+
+// allowed evaluates the filter against every node's metadata
+func (x *Index) allowed(filter *Filter) ([]bool, int) {
+	compiled, ok := compileFilter(filter, func(s string) (uint32, bool) {
+		id, found := x.strings[s]
+		return id, found
+	})
+	if !ok {
+		return nil, 0
+	}
+
+	allowed := make([]bool, x.count)
+	matches := 0
+	for node := 0; node < x.count; node++ {
+		pos := int(x.offset(x.metaOffs, node))
+		source := binary.LittleEndian.Uint32(x.data[pos:])
+		published := binary.LittleEndian.Uint32(x.data[pos+4:])
+		n := int(binary.LittleEndian.Uint32(x.data[pos+8:]))
+		categories := func(match func(uint32) bool) bool {
+			for i := 0; i < n; i++ {
+				if match(binary.LittleEndian.Uint32(x.data[pos+12+i*4:])) {
+					return true
+				}
+			}
+			return false
+		}
+		if compiled.matches(source, published, categories) {
+			allowed[node] = true
+			matches++
+		}
+	}
+	return allowed, matches
+}
+
+// exactSearch scores every allowed node and returns the k closest
+func (x *Index) exactSearch(query []float32, k int, allowed []bool) []Result {
+	closest := &maxHeap{}
+	for node, ok := range allowed {
+		if !ok {
+			continue
+		}
+		dist := x.distance(query, uint32(node))
+		if closest.Len() < k {
+			heap.Push(closest, candidate{node: uint32(node), dist: dist})
+		} else if dist < (*closest)[0].dist {
+			(*closest)[0] = candidate{node: uint32(node), dist: dist}
+			heap.Fix(closest, 0)
+		}
+	}
+
+	candidates := []candidate(*closest)
+	sortCandidates(candidates)
+	results := make([]Result, len(candidates))
+	for i, c := range candidates {
+		results[i] = Result{PaperID: x.id(c.node), Score: float64(1 - c.dist)}
+	}
+	return results
+}
+
+func (x *Index) distance(query []float32, node uint32) float32 {
+	start := x.vectors + int(node)*x.dimension*4
+	var sum float32
+	for i := range query {
+		sum += query[i] * math.Float32frombits(binary.LittleEndian.Uint32(x.data[start+i*4:]))
+	}
+	return 1 - sum
+}
+
+func (x *Index) neighbors(node uint32, level int) []uint32 {
+	pos := int(x.offset(x.nodeOffs, int(node)))
+	if level > int(binary.LittleEndian.Uint32(x.data[pos:])) {
+		return nil
+	}
+	pos += 4
+	for l := 0; l < level; l++ {
+		pos += 4 + int(binary.LittleEndian.Uint32(x.data[pos:]))*4
+	}
+
+	n := int(binary.LittleEndian.Uint32(x.data[pos:]))
+	pos += 4
+	neighbors := make([]uint32, n)
+	for i := range neighbors {
+		neighbors[i] = binary.LittleEndian.Uint32(x.data[pos+i*4:])
+	}
+	return neighbors
+}
+
+func (x *Index) id(node uint32) string {
+	start := x.offset(x.idOffs, int(node))
+	end := x.offset(x.idOffs, int(node)+1)
+	return string(x.data[start:end])
+}
+
+// offset reads the i-th uint64 of the offset table starting at section
+func (x *Index) offset(section, i int) uint64 {
+	return binary.LittleEndian.Uint64(x.data[section+i*8:])
+}
+
+func writeUint32(buf *bytes.Buffer, v uint32) {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], v)
+	buf.Write(b[:])
+}
+
+// countingWriter tracks bytes written and keeps the first error
+type countingWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	c.err = err
+	return n, err
+}
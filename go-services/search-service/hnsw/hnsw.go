@@ -0,0 +1,334 @@
+package hnsw
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Config holds the HNSW construction parameters
+type Config struct {
+	M              int   `json:"m"`               // neighbors per node above layer 0; layer 0 keeps 2*M
+	EfConstruction int   `json:"ef_construction"` // candidate list size while inserting
+	Seed           int64 `json:"seed"`            // level assignment seed, so rebuilds are reproducible
+}
+
+// DefaultConfig returns construction parameters suited to sentence-embedding sized vectors
+func DefaultConfig() Config {
+	return Config{
+		M:              16,
+		EfConstruction: 200,
+		Seed:           42,
+	}
+}
+
+// Validate checks the construction parameters
+func (c Config) Validate() error {
+	if c.M < 2 {
+		return fmt.Errorf("M must be at least 2, got %d", c.M)
+	}
+	if c.EfConstruction < c.M {
+		return fmt.Errorf("ef_construction must be at least M (%d), got %d", c.M, c.EfConstruction)
+	}
+	return nil
+}
+
+// Result is one nearest neighbor returned by a search
+type Result struct {
+	PaperID string  `json:"paper_id"`
+	Score   float64 `json:"score"` // cosine similarity
+}
+
+// graph is the view of an index needed to search it, implemented by the in-memory
+// Builder and by the serialized Index
+type graph interface {
+	distance(query []float32, node uint32) float32
+	neighbors(node uint32, level int) []uint32
+}
+
+// Builder constructs an HNSW graph in memory. Vectors are L2-normalized on insert so
+// the distance is 1 - cosine similarity.
+type Builder struct {
+	cfg       Config
+	dimension int
+	ids       []string
+	vectors   [][]float32
+	metadata  []nodeMetadata
+	strings   *dictionary
+	links     [][][]uint32 // node -> level -> neighbors
+	entry     uint32
+	maxLevel  int
+	rng       *rand.Rand
+	levelMult float64
+}
+
+// NewBuilder creates a builder for vectors of the given dimension
+func NewBuilder(dimension int, cfg Config) (*Builder, error) {
+	if dimension <= 0 {
+		return nil, fmt.Errorf("dimension must be positive, got %d", dimension)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &Builder{
+		cfg:       cfg,
+		dimension: dimension,
+		strings:   newDictionary(),
+		rng:       rand.New(rand.NewSource(cfg.Seed)),
+		levelMult: 1 / math.Log(float64(cfg.M)),
+	}, nil
+}
+
+// Len returns the number of vectors added
+func (b *Builder) Len() int {
+	return len(b.ids)
+}
+
+// Dimension returns the vector dimension
+func (b *Builder) Dimension() int {
+	return b.dimension
+}
+
+// Add inserts a vector and the paper's filterable metadata into the graph
+func (b *Builder) Add(paperID string, vector []float64, metadata Metadata) error {
+	if paperID == "" {
+		return fmt.Errorf("paper ID is required")
+	}
+	if len(vector) != b.dimension {
+		return fmt.Errorf("vector for %s has dimension %d, expected %d", paperID, len(vector), b.dimension)
+	}
+	normalized, err := normalize(vector)
+	if err != nil {
+		return fmt.Errorf("vector for %s: %w", paperID, err)
+	}
+	if uint64(len(b.ids)) >= math.MaxUint32 {
+		return fmt.Errorf("index is full")
+	}
+
+	node := uint32(len(b.ids))
+	b.ids = append(b.ids, paperID)
+	b.vectors = append(b.vectors, normalized)
+	b.metadata = append(b.metadata, b.strings.encode(metadata))
+	b.insert(node)
+	return nil
+}
+
+// insert links a newly added node into every level up to its randomly drawn level
+func (b *Builder) insert(node uint32) {
+	level := int(-math.Log(1-b.rng.Float64()) * b.levelMult)
+	b.links = append(b.links, make([][]uint32, level+1))
+
+	if node == 0 {
+		b.entry = node
+		b.maxLevel = level
+		return
+	}
+
+	query := b.vectors[node]
+	entryPoints := []candidate{{node: b.entry, dist: b.distance(query, b.entry)}}
+	for l := b.maxLevel; l > level; l-- {
+		entryPoints = searchLayer(b, query, entryPoints, 1, l, nil)[:1]
+	}
+
+	for l := minInt(level, b.maxLevel); l >= 0; l-- {
+		candidates := searchLayer(b, query, entryPoints, b.cfg.EfConstruction, l, nil)
+		selected := candidates
+		if len(selected) > b.cfg.M {
+			selected = selected[:b.cfg.M]
+		}
+
+		b.links[node][l] = make([]uint32, 0, len(selected))
+		for _, c := range selected {
+			b.links[node][l] = append(b.links[node][l], c.node)
+			b.link(c.node, node, l)
+		}
+		entryPoints = candidates
+	}
+
+	if level > b.maxLevel {
+		b.maxLevel = level
+		b.entry = node
+	}
+}
+
+// link adds a back-link from node to neighbor, pruning node's list to its closest neighbors
+func (b *Builder) link(node, neighbor uint32, level int) {
+	links := append(b.links[node][level], neighbor)
+	maxLinks := b.cfg.M
+	if level == 0 {
+		maxLinks = 2 * b.cfg.M
+	}
+
+	if len(links) > maxLinks {
+		candidates := make([]candidate, len(links))
+		for i, n := range links {
+			candidates[i] = candidate{node: n, dist: b.distance(b.vectors[node], n)}
+		}
+		sortCandidates(candidates)
+		links = links[:0]
+		for _, c := range candidates[:maxLinks] {
+			links = append(links, c.node)
+		}
+	}
+	b.links[node][level] = links
+}
+
+func (b *Builder) distance(query []float32, node uint32) float32 {
+	return 1 - dot(query, b.vectors[node])
+}
+
+func (b *Builder) neighbors(node uint32, level int) []uint32 {
+	if level >= len(b.links[node]) {
+		return nil
+	}
+	return b.links[node][level]
+}
+
+// search returns the k nearest neighbors of query in g, exploring ef candidates at layer 0.
+// When allow is set, every node still guides the walk but only allowed nodes are returned.
+func search(g graph, entry uint32, maxLevel int, query []float32, k, ef int, allow func(uint32) bool, id func(uint32) string) []Result {
+	if ef < k {
+		ef = k
+	}
+
+	entryPoints := []candidate{{node: entry, dist: g.distance(query, entry)}}
+	for l := maxLevel; l > 0; l-- {
+		entryPoints = searchLayer(g, query, entryPoints, 1, l, nil)[:1]
+	}
+
+	candidates := searchLayer(g, query, entryPoints, ef, 0, allow)
+	if len(candidates) > k {
+		candidates = candidates[:k]
+	}
+
+	results := make([]Result, len(candidates))
+	for i, c := range candidates {
+		results[i] = Result{PaperID: id(c.node), Score: float64(1 - c.dist)}
+	}
+	return results
+}
+
+// searchLayer runs a best-first search on one layer and returns up to ef candidates, closest first.
+// A nil allow admits every node; otherwise rejected nodes are traversed but not returned.
+func searchLayer(g graph, query []float32, entryPoints []candidate, ef int, level int, allow func(uint32) bool) []candidate {
+	visited := make(map[uint32]bool, ef*4)
+	toVisit := &minHeap{}
+	found := &maxHeap{}
+	for _, ep := range entryPoints {
+		visited[ep.node] = true
+		heap.Push(toVisit, ep)
+		if allow == nil || allow(ep.node) {
+			heap.Push(found, ep)
+			if found.Len() > ef {
+				heap.Pop(found)
+			}
+		}
+	}
+
+	for toVisit.Len() > 0 {
+		current := heap.Pop(toVisit).(candidate)
+		if found.Len() >= ef && current.dist > (*found)[0].dist {
+			break
+		}
+
+		for _, n := range g.neighbors(current.node, level) {
+			if visited[n] {
+				continue
+			}
+			visited[n] = true
+
+			dist := g.distance(query, n)
+			if found.Len() < ef || dist < (*found)[0].dist {
+				heap.Push(toVisit, candidate{node: n, dist: dist})
+				if allow == nil || allow(n) {
+					heap.Push(found, candidate{node: n, dist: dist})
+					if found.Len() > ef {
+						heap.Pop(found)
+					}
+				}
+			}
+		}
+	}
+
+	result := []candidate(*found)
+	sortCandidates(result)
+	return result
+}
+
+// candidate is a node and its distance to the query
+type candidate struct {
+	node uint32
+	dist float32
+}
+
+func sortCandidates(candidates []candidate) {
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].dist != candidates[j].dist {
+			return candidates[i].dist < candidates[j].dist
+		}
+		return candidates[i].node < candidates[j].node
+	})
+}
+
+// minHeap pops the closest candidate first
+type minHeap []candidate
+
+func (h minHeap) Len() int            { return len(h) }
+func (h minHeap) Less(i, j int) bool  { return h[i].dist < h[j].dist }
+func (h minHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *minHeap) Push(x interface{}) { *h = append(*h, x.(candidate)) }
+func (h *minHeap) Pop() interface{} {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
+
+// maxHeap pops the farthest candidate first
+type maxHeap []candidate
+
+func (h maxHeap) Len() int            { return len(h) }
+func (h maxHeap) Less(i, j int) bool  { return h[i].dist > h[j].dist }
+func (h maxHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *maxHeap) Push(x interface{}) { *h = append(*h, x.(candidate)) }
+func (h *maxHeap) Pop() interface{} {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
+
+// normalize returns the vector scaled to unit length as float32
+func normalize(vector []float64) ([]float32, error) {
+	norm := 0.0
+	for _, v := range vector {
+		norm += v * v
+	}
+	if norm == 0 {
+		return nil, fmt.Errorf("vector has zero magnitude")
+	}
+	norm = math.Sqrt(norm)
+
+	normalized := make([]float32, len(vector))
+	for i, v := range vector {
+		normalized[i] = float32(v / norm)
+	}
+	return normalized, nil
+}
+
+func dot(a, b []float32) float32 {
+	var sum float32
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,163 @@
+package hnsw
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// noString marks a missing dictionary entry, e.g. a paper without a source
+const noString = ^uint32(0)
+
+// Metadata is the filterable paper metadata stored with each vector
+type Metadata struct {
+	Source        string
+	Categories    []string
+	PublishedDate string // YYYY-MM-DD, optionally followed by a time
+}
+
+// nodeMetadata is Metadata with strings replaced by dictionary IDs and the date as YYYYMMDD
+type nodeMetadata struct {
+	source     uint32
+	published  uint32 // 0 when unknown
+	categories []uint32
+}
+
+// dictionary assigns IDs to the source and category strings of a build
+type dictionary struct {
+	ids     map[string]uint32
+	strings []string
+}
+
+func newDictionary() *dictionary {
+	return &dictionary{ids: make(map[string]uint32)}
+}
+
+func (d *dictionary) id(s string) uint32 {
+	if s == "" {
+		return noString
+	}
+	if id, ok := d.ids[s]; ok {
+		return id
+	}
+	id := uint32(len(d.strings))
+	d.ids[s] = id
+	d.strings = append(d.strings, s)
+	return id
+}
+
+func (d *dictionary) encode(metadata Metadata) nodeMetadata {
+	encoded := nodeMetadata{
+		source:    d.id(metadata.Source),
+		published: dateKey(metadata.PublishedDate),
+	}
+	for _, category := range metadata.Categories {
+		if category != "" {
+			encoded.categories = append(encoded.categories, d.id(category))
+		}
+	}
+	return encoded
+}
+
+// dateKey turns a YYYY-MM-DD prefixed date into YYYYMMDD, or 0 if it cannot be parsed
+func dateKey(date string) uint32 {
+	if len(date) < 10 || date[4] != '-' || date[7] != '-' {
+		return 0
+	}
+	key, err := strconv.ParseUint(date[0:4]+date[5:7]+date[8:10], 10, 32)
+	if err != nil {
+		return 0
+	}
+	return uint32(key)
+}
+
+// Filter restricts a search to papers matching every set field. Categories and Sources
+// match when the paper has any of the listed values; dates are inclusive YYYY-MM-DD bounds
+// and exclude papers without a published date.
+type Filter struct {
+	Categories    []string `json:"categories,omitempty"`
+	Sources       []string `json:"sources,omitempty"`
+	PublishedFrom string   `json:"published_from,omitempty"`
+	PublishedTo   string   `json:"published_to,omitempty"`
+}
+
+// IsEmpty reports whether the filter admits every paper
+func (f *Filter) IsEmpty() bool {
+	return f == nil || (len(f.Categories) == 0 && len(f.Sources) == 0 && f.PublishedFrom == "" && f.PublishedTo == "")
+}
+
+// Validate checks the date bounds
+func (f *Filter) Validate() error {
+	if f == nil {
+		return nil
+	}
+	from, to := uint32(0), uint32(0)
+	if f.PublishedFrom != "" {
+		if from = dateKey(f.PublishedFrom); from == 0 {
+			return fmt.Errorf("published_from must be YYYY-MM-DD, got %q", f.PublishedFrom)
+		}
+	}
+	if f.PublishedTo != "" {
+		if to = dateKey(f.PublishedTo); to == 0 {
+			return fmt.Errorf("published_to must be YYYY-MM-DD, got %q", f.PublishedTo)
+		}
+	}
+	if from != 0 && to != 0 && from > to {
+		return fmt.Errorf("published_from %s is after published_to %s", f.PublishedFrom, f.PublishedTo)
+	}
+	return nil
+}
+
+// compiledFilter is a Filter resolved against an index dictionary
+type compiledFilter struct {
+	sources    map[uint32]bool
+	categories map[uint32]bool
+	from, to   uint32
+}
+
+// compile resolves filter strings to dictionary IDs. It reports false when a listed
+// value set matches nothing in the index, so the search can return early.
+func compileFilter(f *Filter, lookup func(string) (uint32, bool)) (*compiledFilter, bool) {
+	compiled := &compiledFilter{
+		from: dateKey(f.PublishedFrom),
+		to:   dateKey(f.PublishedTo),
+	}
+	resolve := func(values []string) (map[uint32]bool, bool) {
+		if len(values) == 0 {
+			return nil, true
+		}
+		ids := make(map[uint32]bool, len(values))
+		for _, value := range values {
+			if id, ok := lookup(strings.TrimSpace(value)); ok {
+				ids[id] = true
+			}
+		}
+		return ids, len(ids) > 0
+	}
+
+	var ok bool
+	if compiled.sources, ok = resolve(f.Sources); !ok {
+		return nil, false
+	}
+	if compiled.categories, ok = resolve(f.Categories); !ok {
+		return nil, false
+	}
+	return compiled, true
+}
+
+// matches reports whether a node's metadata satisfies the filter
+func (c *compiledFilter) matches(source, published uint32, categories func(func(uint32) bool) bool) bool {
+	if c.sources != nil && !c.sources[source] {
+		return false
+	}
+	if c.from != 0 && (published == 0 || published < c.from) {
+		return false
+	}
+	if c.to != 0 && (published == 0 || published > c.to) {
+		return false
+	}
+	if c.categories != nil && !categories(func(id uint32) bool { return c.categories[id] }) {
+		return false
+	}
+	return true
+}
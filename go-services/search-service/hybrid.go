@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"search-service/hnsw"
+	"search-service/indexstore"
+	"search-service/papers"
+)
+
+// Search modes
+const (
+	ModeVector = "vector" // cosine similarity only (default)
+	ModeHybrid = "hybrid" // vector and BM25 keyword rankings fused by reciprocal rank fusion
+)
+
+// Hybrid defaults. Each ranking contributes weight / (rrf_k + rank) to a paper's fused
+// score, and is cut at HybridDepthFactor * top_k candidates (at least MinHybridDepth).
+const (
+	DefaultRRFK       = 60
+	HybridDepthFactor = 4
+	MinHybridDepth    = 50
+)
+
+// HybridOptions tunes the fusion of a hybrid search. Unset weights default to 1; a weight
+// of 0 drops that ranking entirely.
+type HybridOptions struct {
+	VectorWeight  *float64 `json:"vector_weight,omitempty"`
+	KeywordWeight *float64 `json:"keyword_weight,omitempty"`
+	RRFK          int      `json:"rrf_k,omitempty"` // damps the lead of top ranks; higher flattens them
+}
+
+// SearchResult is one returned paper. Hybrid results carry each ranking's rank and raw
+// score; a zero rank means the paper was not in that ranking's candidates.
+type SearchResult struct {
+	PaperID      string            `json:"paper_id"`
+	Score        float64           `json:"score"` // cosine similarity, or the fused score in hybrid mode
+	VectorRank   int               `json:"vector_rank,omitempty"`
+	VectorScore  float64           `json:"vector_score,omitempty"`
+	KeywordRank  int               `json:"keyword_rank,omitempty"`
+	KeywordScore float64           `json:"keyword_score,omitempty"`
+	Paper        *papers.Reference `json:"paper,omitempty"` // set on hydrated requests
+	// Re-ranked results keep the retrieval score and carry the boost of each re-ranker by type
+	RetrievalScore float64            `json:"retrieval_score,omitempty"`
+	Rerank         map[string]float64 `json:"rerank,omitempty"`
+}
+
+// normalizeHybrid validates the hybrid options and fills in defaults
+func normalizeHybrid(request *SearchRequest) error {
+	if request.Mode == "" {
+		request.Mode = ModeVector
+	}
+	switch request.Mode {
+	case ModeVector:
+		return nil
+	case ModeHybrid:
+	default:
+		return fmt.Errorf("mode must be %q or %q, got %q", ModeVector, ModeHybrid, request.Mode)
+	}
+
+	if request.Query == "" {
+		return fmt.Errorf("hybrid search requires query text for keyword matching")
+	}
+	if request.Hybrid == nil {
+		request.Hybrid = &HybridOptions{}
+	}
+	options := request.Hybrid
+	if options.VectorWeight == nil {
+		options.VectorWeight = floatPtr(1)
+	}
+	if options.KeywordWeight == nil {
+		options.KeywordWeight = floatPtr(1)
+	}
+	if *options.VectorWeight < 0 || *options.KeywordWeight < 0 {
+		return fmt.Errorf("hybrid weights must not be negative")
+	}
+	if *options.VectorWeight == 0 && *options.KeywordWeight == 0 {
+		return fmt.Errorf("at least one hybrid weight must be positive")
+	}
+	if options.RRFK == 0 {
+		options.RRFK = DefaultRRFK
+	}
+	if options.RRFK < 0 {
+		return fmt.Errorf("rrf_k must be positive, got %d", options.RRFK)
+	}
+	return nil
+}
+
+// hybridSearch ranks candidates from the vector and keyword indexes separately, each under
+// the request filter, and fuses the two rankings with weighted reciprocal rank fusion
+func hybridSearch(loaded *indexstore.LoadedIndex, embedding []float64, request SearchRequest) ([]SearchResult, error) {
+	if loaded.Keywords == nil {
+		return nil, fmt.Errorf("index %s was built without a keyword index; rebuild it to use hybrid search", loaded.Manifest.Key)
+	}
+	options := request.Hybrid
+	depth := request.TopK * HybridDepthFactor
+	if depth < MinHybridDepth {
+		depth = MinHybridDepth
+	}
+
+	fused := make(map[string]*SearchResult)
+	entry := func(paperID string) *SearchResult {
+		result, ok := fused[paperID]
+		if !ok {
+			result = &SearchResult{PaperID: paperID}
+			fused[paperID] = result
+		}
+		return result
+	}
+
+	if *options.VectorWeight > 0 {
+		ef := request.Ef
+		if ef < depth {
+			ef = depth
+		}
+		vectorResults, err := loaded.Index.Search(embedding, depth, ef, request.Filter)
+		if err != nil {
+			return nil, fmt.Errorf("vector search failed: %w", err)
+		}
+		for i, hit := range vectorResults {
+			result := entry(hit.PaperID)
+			result.VectorRank = i + 1
+			result.VectorScore = hit.Score
+			result.Score += *options.VectorWeight / float64(options.RRFK+i+1)
+		}
+	}
+
+	if *options.KeywordWeight > 0 {
+		allowed, err := loaded.Index.Matching(request.Filter)
+		if err != nil {
+			return nil, fmt.Errorf("keyword search failed: %w", err)
+		}
+		for i, hit := range loaded.Keywords.Search(request.Query, depth, allowed) {
+			paperID, err := loaded.Index.PaperID(hit.Doc)
+			if err != nil {
+				return nil, fmt.Errorf("keyword search failed: %w", err)
+			}
+			result := entry(paperID)
+			result.KeywordRank = i + 1
+			result.KeywordScore = hit.Score
+			result.Score += *options.KeywordWeight / float64(options.RRFK+i+1)
+		}
+	}
+
+	results := make([]SearchResult, 0, len(fused))
+	for _, result := range fused {
+		results = append(results, *result)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].PaperID < results[j].PaperID
+	})
+	if len(results) > request.TopK {
+		results = results[:request.TopK]
+	}
+	return results, nil
+}
+
+// vectorResults converts plain vector search hits to search results
+func vectorResults(hits []hnsw.Result) []SearchResult {
+	results := make([]SearchResult, len(hits))
+	for i, hit := range hits {
+		results[i] = SearchResult{PaperID: hit.PaperID, Score: hit.Score}
+	}
+	return results
+}
+
+func floatPtr(v float64) *float64 {
+	return &v
+}
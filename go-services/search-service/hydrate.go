@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"search-service/papers"
+)
+
+// hydrateResults attaches each result's paper metadata, read through the store's cache in a
+// single batch rather than one read per result. Papers taken down since the index was built
+// are left out, as on every read path.
+func hydrateResults(ctx context.Context, store *papers.Store, results []SearchResult) ([]SearchResult, error) {
+	paperIDs := make([]string, len(results))
+	for i, result := range results {
+		paperIDs[i] = result.PaperID
+	}
+	references, err := store.References(ctx, paperIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hydrate search results: %w", err)
+	}
+	byID := make(map[string]*papers.Reference, len(references))
+	for i := range references {
+		byID[references[i].PaperID] = &references[i]
+	}
+
+	hydrated := make([]SearchResult, 0, len(results))
+	for _, result := range results {
+		if reference, ok := byID[result.PaperID]; ok {
+			result.Paper = reference
+			hydrated = append(hydrated, result)
+		}
+	}
+	return hydrated, nil
+}
@@ -0,0 +1,170 @@
+package indexstore
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"search-service/bm25"
+	"search-service/hnsw"
+	"shared/logger"
+)
+
+// LoadedIndex is a memory-mapped index and the manifest it was published with. Keywords
+// is nil when the index was published without a keyword index.
+type LoadedIndex struct {
+	Manifest Manifest
+	Index    *hnsw.Index
+	Keywords *bm25.Index
+}
+
+// Loader keeps the current index memory-mapped across warm invocations. The manifest
+// is re-checked at most once per refresh interval, and a newer index replaces the old one.
+type Loader struct {
+	store     *Store
+	dir       string
+	refresh   time.Duration
+	logger    *logger.Logger
+	mu        sync.Mutex
+	current   *LoadedIndex
+	checkedAt time.Time
+}
+
+// NewLoader creates a loader that downloads index files into dir (e.g. /tmp on Lambda)
+func NewLoader(store *Store, dir string, refresh time.Duration) *Loader {
+	return &Loader{
+		store:   store,
+		dir:     dir,
+		refresh: refresh,
+		logger:  logger.New("index-loader"),
+	}
+}
+
+// Get returns the current index, downloading and mapping a new one when the manifest changed.
+// If the manifest check fails but an index is already loaded, the loaded index is kept.
+func (l *Loader) Get(ctx context.Context) (*LoadedIndex, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.current != nil && time.Since(l.checkedAt) < l.refresh {
+		return l.current, nil
+	}
+
+	manifest, err := l.store.LatestManifest(ctx)
+	if err != nil {
+		if l.current != nil {
+			l.logger.Warn("Failed to check for a newer index, keeping the loaded one", map[string]interface{}{
+				"key":   l.current.Manifest.Key,
+				"error": err.Error(),
+			})
+			l.checkedAt = time.Now()
+			return l.current, nil
+		}
+		return nil, err
+	}
+	l.checkedAt = time.Now()
+
+	if l.current != nil && l.current.Manifest.Key == manifest.Key {
+		return l.current, nil
+	}
+
+	loaded, err := l.load(ctx, manifest)
+	if err != nil {
+		if l.current != nil {
+			l.logger.Warn("Failed to load newer index, keeping the loaded one", map[string]interface{}{
+				"key":       l.current.Manifest.Key,
+				"newer_key": manifest.Key,
+				"error":     err.Error(),
+			})
+			return l.current, nil
+		}
+		return nil, err
+	}
+
+	previous := l.current
+	l.current = loaded
+	if previous != nil {
+		// Close waits for searches still running on the previous index
+		go l.discard(previous)
+	}
+	return loaded, nil
+}
+
+// load downloads and maps the index named by manifest
+func (l *Loader) load(ctx context.Context, manifest *Manifest) (*LoadedIndex, error) {
+	start := time.Now()
+	localPath := l.localPath(manifest.Key)
+
+	if err := l.store.Download(ctx, manifest.Key, localPath); err != nil {
+		return nil, err
+	}
+	index, err := hnsw.Open(localPath)
+	if err != nil {
+		os.Remove(localPath)
+		return nil, err
+	}
+	if index.Len() != manifest.VectorCount || index.Dimension() != manifest.Dimension {
+		index.Close()
+		os.Remove(localPath)
+		return nil, fmt.Errorf("index %s does not match its manifest: %d vectors of dimension %d, manifest says %d of %d",
+			manifest.Key, index.Len(), index.Dimension(), manifest.VectorCount, manifest.Dimension)
+	}
+
+	keywords, err := l.loadKeywords(ctx, manifest)
+	if err != nil {
+		index.Close()
+		os.Remove(localPath)
+		return nil, err
+	}
+
+	l.logger.InfoWithDuration("Loaded vector index", time.Since(start), map[string]interface{}{
+		"key":          manifest.Key,
+		"vector_count": manifest.VectorCount,
+		"size_bytes":   manifest.SizeBytes,
+		"keywords":     keywords != nil,
+	})
+	return &LoadedIndex{Manifest: *manifest, Index: index, Keywords: keywords}, nil
+}
+
+// loadKeywords downloads and decodes the keyword index named by manifest, if any. The
+// keyword index is held in memory, so its local file is removed once read.
+func (l *Loader) loadKeywords(ctx context.Context, manifest *Manifest) (*bm25.Index, error) {
+	if manifest.KeywordKey == "" {
+		return nil, nil
+	}
+	localPath := l.localPath(manifest.KeywordKey)
+	if err := l.store.Download(ctx, manifest.KeywordKey, localPath); err != nil {
+		return nil, err
+	}
+	defer os.Remove(localPath)
+
+	keywords, err := bm25.Open(localPath)
+	if err != nil {
+		return nil, err
+	}
+	if keywords.Len() != manifest.VectorCount {
+		return nil, fmt.Errorf("keyword index %s has %d documents, manifest says %d",
+			manifest.KeywordKey, keywords.Len(), manifest.VectorCount)
+	}
+	return keywords, nil
+}
+
+// localPath is where the file for key is kept under the loader's directory
+func (l *Loader) localPath(key string) string {
+	return filepath.Join(l.dir, strings.ReplaceAll(key, "/", "_"))
+}
+
+// discard unmaps a replaced index and deletes its local file
+func (l *Loader) discard(old *LoadedIndex) {
+	if err := old.Index.Close(); err != nil {
+		l.logger.Warn("Failed to unmap replaced index", map[string]interface{}{
+			"key":   old.Manifest.Key,
+			"error": err.Error(),
+		})
+	}
+	os.Remove(l.localPath(old.Manifest.Key))
+}
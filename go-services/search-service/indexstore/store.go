@@ -0,0 +1,183 @@
+package indexstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"shared/awsclient"
+	"shared/logger"
+)
+
+// manifestName is the object under the prefix that points at the current index
+const manifestName = "latest.json"
+
+// Manifest describes a published index
+type Manifest struct {
+	Key            string `json:"key"`                   // S3 key of the index file
+	KeywordKey     string `json:"keyword_key,omitempty"` // S3 key of the BM25 keyword index, when built
+	VectorType     string `json:"vector_type"`
+	Dimension      int    `json:"dimension"`
+	VectorCount    int    `json:"vector_count"`
+	SkippedVectors int    `json:"skipped_vectors"`
+	Tombstoned     int    `json:"tombstoned"`        // vectors of soft-deleted papers left out
+	Pending        int    `json:"pending,omitempty"` // vectors of papers still being written, left out
+	M              int    `json:"m"`
+	EfConstruction int    `json:"ef_construction"`
+	SizeBytes      int64  `json:"size_bytes"`
+	KeywordBytes   int64  `json:"keyword_bytes,omitempty"`
+	BuildTimeMs    int64  `json:"build_time_ms"`
+	BuiltAt        string `json:"built_at"`
+}
+
+// Store publishes and fetches index files under an S3 prefix
+type Store struct {
+	client s3iface.S3API
+	bucket string
+	prefix string
+	logger *logger.Logger
+}
+
+// NewStore creates a store for the given bucket and prefix
+func NewStore(bucket, prefix string) (*Store, error) {
+	sess, err := awsclient.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"), // Default region
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AWS session: %w", err)
+	}
+	return NewStoreWithClient(s3.New(sess), bucket, prefix), nil
+}
+
+// NewStoreWithClient creates a store with a custom S3 client (for testing)
+func NewStoreWithClient(client s3iface.S3API, bucket, prefix string) *Store {
+	return &Store{
+		client: client,
+		bucket: bucket,
+		prefix: prefix,
+		logger: logger.New("index-store"),
+	}
+}
+
+// Publish uploads the index file, and the keyword index file when keywordPath is set, under
+// timestamped keys, then points the manifest at them. Readers only follow the manifest, so
+// they never see a partially uploaded index.
+func (s *Store) Publish(ctx context.Context, localPath, keywordPath string, manifest Manifest) (*Manifest, error) {
+	builtAt, err := time.Parse(time.RFC3339, manifest.BuiltAt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid built_at %q: %w", manifest.BuiltAt, err)
+	}
+	baseName := fmt.Sprintf("%s-%s", manifest.VectorType, builtAt.UTC().Format("20060102T150405Z"))
+
+	manifest.Key = path.Join(s.prefix, baseName+".hnsw")
+	if err := s.upload(ctx, localPath, manifest.Key); err != nil {
+		return nil, err
+	}
+	if keywordPath != "" {
+		manifest.KeywordKey = path.Join(s.prefix, baseName+".bm25")
+		if err := s.upload(ctx, keywordPath, manifest.KeywordKey); err != nil {
+			return nil, err
+		}
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	manifestKey := path.Join(s.prefix, manifestName)
+	if _, err := s.client.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(manifestKey),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String("application/json"),
+	}); err != nil {
+		return nil, fmt.Errorf("failed to write manifest s3://%s/%s: %w", s.bucket, manifestKey, err)
+	}
+
+	s.logger.Info("Published vector index", map[string]interface{}{
+		"bucket":       s.bucket,
+		"key":          manifest.Key,
+		"vector_count": manifest.VectorCount,
+		"size_bytes":   manifest.SizeBytes,
+		"keyword_key":  manifest.KeywordKey,
+	})
+	return &manifest, nil
+}
+
+// upload streams a local file to key
+func (s *Store) upload(ctx context.Context, localPath, key string) error {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to open index file: %w", err)
+	}
+	defer file.Close()
+
+	uploader := s3manager.NewUploaderWithClient(s.client)
+	if _, err := uploader.UploadWithContext(ctx, &s3manager.UploadInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(key),
+		Body:        file,
+		ContentType: aws.String("application/octet-stream"),
+	}); err != nil {
+		return fmt.Errorf("failed to upload index to s3://%s/%s: %w", s.bucket, key, err)
+	}
+	return nil
+}
+
+// LatestManifest reads the manifest of the current index
+func (s *Store) LatestManifest(ctx context.Context) (*Manifest, error) {
+	manifestKey := path.Join(s.prefix, manifestName)
+	result, err := s.client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(manifestKey),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get manifest s3://%s/%s: %w", s.bucket, manifestKey, err)
+	}
+	defer result.Body.Close()
+
+	data, err := io.ReadAll(result.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	if manifest.Key == "" {
+		return nil, fmt.Errorf("manifest does not name an index key")
+	}
+	return &manifest, nil
+}
+
+// Download copies the index file at key, one of a manifest's keys, to localPath
+func (s *Store) Download(ctx context.Context, key, localPath string) error {
+	file, err := os.Create(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", localPath, err)
+	}
+
+	downloader := s3manager.NewDownloaderWithClient(s.client)
+	_, err = downloader.DownloadWithContext(ctx, file, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(localPath)
+		return fmt.Errorf("failed to download index s3://%s/%s: %w", s.bucket, key, err)
+	}
+	return nil
+}
@@ -0,0 +1,448 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"search-service/builder"
+	"search-service/client"
+	"search-service/export"
+	"search-service/hnsw"
+	"search-service/indexstore"
+	"search-service/papers"
+	"search-service/rerank"
+	"shared/awsclient"
+	"shared/logger"
+	"shared/shutdown"
+)
+
+// Search defaults; ef is raised to top_k when smaller
+const (
+	DefaultTopK = 10
+	MaxTopK     = 100
+	DefaultEf   = 64
+)
+
+// SearchRequest asks for the papers most similar to a query text or embedding
+type SearchRequest struct {
+	Query     string         `json:"query,omitempty"`     // embedded through the embedding API; also the keyword query in hybrid mode
+	Embedding []float64      `json:"embedding,omitempty"` // used as-is when set
+	TopK      int            `json:"top_k,omitempty"`
+	Ef        int            `json:"ef,omitempty"` // candidates explored; higher trades latency for recall
+	Filter    *hnsw.Filter   `json:"filter,omitempty"`
+	Mode      string         `json:"mode,omitempty"` // "vector" (default) or "hybrid"
+	Hybrid    *HybridOptions `json:"hybrid,omitempty"`
+	Export    string         `json:"export,omitempty"`  // "bibtex" or "ris" also renders the results for reference managers
+	Hydrate   bool           `json:"hydrate,omitempty"` // attach each result's paper metadata
+	Rerank    *RerankOptions `json:"rerank,omitempty"`  // re-rank the top candidates before returning them
+}
+
+// SearchResponse holds the nearest papers and the index that answered
+type SearchResponse struct {
+	Results      []SearchResult `json:"results"`
+	Mode         string         `json:"mode"`
+	IndexKey     string         `json:"index_key"`
+	IndexBuiltAt string         `json:"index_built_at"`
+	VectorCount  int            `json:"vector_count"`
+	TookMs       int64          `json:"took_ms"`
+	Export       *export.File   `json:"export,omitempty"`
+}
+
+// searchComponents are built once per container and reused by warm invocations
+type searchComponents struct {
+	loader     *indexstore.Loader
+	apiClient  *client.VectorAPIClient
+	paperStore *papers.Store
+	scorer     rerank.Scorer // nil without RERANK_API_URL
+}
+
+var (
+	appLogger = logger.New("search-service")
+
+	componentsOnce sync.Once
+	components     *searchComponents
+	componentsErr  error
+)
+
+func main() {
+	if os.Getenv("AWS_LAMBDA_FUNCTION_NAME") != "" {
+		// The same binary runs the scheduled index build job
+		if os.Getenv("SERVICE_ROLE") == "index-builder" {
+			lambda.Start(handleBuildIndex)
+		} else {
+			lambda.Start(handleSearch)
+		}
+		return
+	}
+
+	serveAddr := flag.String("serve", "", "run an HTTP server on this address (e.g. :8080) instead of running a command")
+	flag.Parse()
+
+	watcher := shutdown.Watch(appLogger)
+	if *serveAddr != "" {
+		watcher.Exit(runServer(*serveAddr, watcher), false)
+	}
+
+	fmt.Println("Search Service - Local Development Mode")
+	watcher.Exit(runLocal(flag.Args()), false)
+}
+
+// runLocal runs "build-index", "query <text>", "export <bibtex|ris> <text>" or "paper <id>"
+// from the command line
+func runLocal(args []string) error {
+	if len(args) == 0 {
+		fmt.Println("Usage: search-service [--serve addr] build-index | query <text> | export <bibtex|ris> <text> | paper <id>")
+		return nil
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "build-index":
+		result, err := handleBuildIndex(ctx)
+		if err != nil {
+			return err
+		}
+		appLogger.Info("Index build finished", map[string]interface{}{
+			"result": result,
+		})
+		return nil
+	case "query":
+		response, err := handleSearch(ctx, SearchRequest{Query: strings.Join(args[1:], " ")})
+		if err != nil {
+			return err
+		}
+		for i, result := range response.Results {
+			fmt.Printf("%2d. %s  %.4f\n", i+1, result.PaperID, result.Score)
+		}
+		return nil
+	case "export":
+		if len(args) < 3 {
+			return fmt.Errorf("usage: export <bibtex|ris> <text>")
+		}
+		response, err := handleSearch(ctx, SearchRequest{Query: strings.Join(args[2:], " "), Export: args[1]})
+		if err != nil {
+			return err
+		}
+		fmt.Print(response.Export.Content)
+		return nil
+	case "paper":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: paper <id>")
+		}
+		detail, err := handlePaperDetail(ctx, args[1])
+		if err != nil {
+			return err
+		}
+		body, err := json.MarshalIndent(detail, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(body))
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+// handleBuildIndex scans the vectors table and publishes a new HNSW index
+func handleBuildIndex(ctx context.Context) (*builder.BuildResult, error) {
+	store, err := newIndexStore()
+	if err != nil {
+		return nil, err
+	}
+	if err := runPreflight(ctx, false); err != nil {
+		return nil, err
+	}
+	config, err := loadHNSWConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	indexBuilder := builder.NewIndexBuilder(
+		getEnvOrDefault("VECTORS_TABLE_NAME", "vectors-table"),
+		getEnvOrDefault("PAPERS_TABLE_NAME", "papers-table"),
+		getEnvOrDefault("INDEX_VECTOR_TYPE", builder.DefaultVectorType),
+		config,
+		store,
+		getEnvOrDefault("INDEX_DIR", os.TempDir()),
+	)
+	if archiveBucket := os.Getenv("VECTOR_ARCHIVE_BUCKET"); archiveBucket != "" {
+		if err := indexBuilder.SetArchive(s3.New(awsclient.MustSession()), archiveBucket, os.Getenv("VECTOR_ARCHIVE_PREFIX")); err != nil {
+			return nil, err
+		}
+	}
+	return indexBuilder.Build(ctx)
+}
+
+// handleSearch answers a top-k query from the memory-mapped index, fusing in keyword
+// matches in hybrid mode
+func handleSearch(ctx context.Context, request SearchRequest) (*SearchResponse, error) {
+	start := time.Now()
+	contextLogger := appLogger.WithContext(ctx)
+
+	if err := normalizeRequest(&request); err != nil {
+		return nil, err
+	}
+
+	c, err := getComponents()
+	if err != nil {
+		return nil, err
+	}
+	if err := runPreflight(ctx, true); err != nil {
+		return nil, err
+	}
+
+	loaded, err := c.loader.Get(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load vector index: %w", err)
+	}
+
+	embedding := request.Embedding
+	keywordOnly := request.Mode == ModeHybrid && *request.Hybrid.VectorWeight == 0
+	if len(embedding) == 0 && !keywordOnly {
+		response, err := c.apiClient.GenerateEmbedding(ctx, request.Query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed query: %w", err)
+		}
+		embedding = response.Embedding
+	}
+
+	// Re-ranking retrieves more candidates than top_k and cuts them after reordering
+	retrieval := request
+	if request.Rerank != nil {
+		retrieval.TopK = request.Rerank.Candidates
+	}
+
+	var results []SearchResult
+	if request.Mode == ModeHybrid {
+		if results, err = hybridSearch(loaded, embedding, retrieval); err != nil {
+			return nil, err
+		}
+	} else {
+		hits, err := loaded.Index.Search(embedding, retrieval.TopK, retrieval.Ef, request.Filter)
+		if err != nil {
+			return nil, fmt.Errorf("search failed: %w", err)
+		}
+		results = vectorResults(hits)
+	}
+	if request.Hydrate || request.Rerank != nil {
+		if results, err = hydrateResults(ctx, c.paperStore, results); err != nil {
+			return nil, err
+		}
+	}
+	if request.Rerank != nil {
+		if results, err = rerankResults(ctx, c.scorer, request, results); err != nil {
+			return nil, err
+		}
+		if !request.Hydrate {
+			for i := range results {
+				results[i].Paper = nil
+			}
+		}
+	}
+
+	response := &SearchResponse{
+		Results:      results,
+		Mode:         request.Mode,
+		IndexKey:     loaded.Manifest.Key,
+		IndexBuiltAt: loaded.Manifest.BuiltAt,
+		VectorCount:  loaded.Manifest.VectorCount,
+	}
+	if request.Export != "" {
+		paperIDs := make([]string, len(results))
+		for i, result := range results {
+			paperIDs[i] = result.PaperID
+		}
+		if response.Export, err = handleExport(ctx, paperIDs, request.Export, "search-results"); err != nil {
+			return nil, err
+		}
+	}
+	response.TookMs = time.Since(start).Milliseconds()
+	fields := map[string]interface{}{
+		"mode":         request.Mode,
+		"top_k":        request.TopK,
+		"ef":           request.Ef,
+		"result_count": len(results),
+		"filtered":     !request.Filter.IsEmpty(),
+		"index_key":    response.IndexKey,
+		"took_ms":      response.TookMs,
+		"export":       request.Export,
+		"hydrated":     request.Hydrate,
+	}
+	if request.Rerank != nil {
+		steps := make([]string, len(request.Rerank.Steps))
+		for i, step := range request.Rerank.Steps {
+			steps[i] = step.Type
+		}
+		fields["rerank_steps"] = steps
+		fields["rerank_candidates"] = request.Rerank.Candidates
+	}
+	if stats, ok := c.paperStore.CacheStats(); ok {
+		fields["paper_cache"] = stats
+	}
+	contextLogger.Info("Search completed", fields)
+	return response, nil
+}
+
+// handlePaperDetail returns a paper merged with its vector metadata and lineage
+func handlePaperDetail(ctx context.Context, paperID string) (*papers.PaperDetail, error) {
+	c, err := getComponents()
+	if err != nil {
+		return nil, err
+	}
+	if err := runPreflight(ctx, false); err != nil {
+		return nil, err
+	}
+	return c.paperStore.Detail(ctx, paperID)
+}
+
+// handleExport renders the stored metadata of the papers, in order, as a BibTeX or RIS file
+// named after basename; papers taken down since the index was built are left out
+func handleExport(ctx context.Context, paperIDs []string, format, basename string) (*export.File, error) {
+	format, err := export.ParseFormat(format)
+	if err != nil {
+		return nil, err
+	}
+	c, err := getComponents()
+	if err != nil {
+		return nil, err
+	}
+	references, err := c.paperStore.References(ctx, paperIDs)
+	if err != nil {
+		return nil, err
+	}
+	return export.Render(format, references, basename)
+}
+
+// normalizeRequest validates the request and fills in defaults
+func normalizeRequest(request *SearchRequest) error {
+	if request.Query == "" && len(request.Embedding) == 0 {
+		return fmt.Errorf("either query or embedding is required")
+	}
+	if request.TopK == 0 {
+		request.TopK = DefaultTopK
+	}
+	if request.TopK < 0 || request.TopK > MaxTopK {
+		return fmt.Errorf("top_k must be between 1 and %d, got %d", MaxTopK, request.TopK)
+	}
+	if request.Ef == 0 {
+		request.Ef = DefaultEf
+	}
+	if request.Ef < 0 {
+		return fmt.Errorf("ef must be positive, got %d", request.Ef)
+	}
+	if err := normalizeHybrid(request); err != nil {
+		return err
+	}
+	if err := normalizeRerank(request); err != nil {
+		return err
+	}
+	if request.Export != "" {
+		format, err := export.ParseFormat(request.Export)
+		if err != nil {
+			return err
+		}
+		request.Export = format
+	}
+	return request.Filter.Validate()
+}
+
+// getComponents builds the index loader and embedding client on the first invocation
+func getComponents() (*searchComponents, error) {
+	componentsOnce.Do(func() {
+		store, err := newIndexStore()
+		if err != nil {
+			componentsErr = err
+			return
+		}
+		refresh, err := strconv.Atoi(getEnvOrDefault("INDEX_REFRESH_SECONDS", "300"))
+		if err != nil || refresh < 0 {
+			componentsErr = fmt.Errorf("invalid INDEX_REFRESH_SECONDS %q", os.Getenv("INDEX_REFRESH_SECONDS"))
+			return
+		}
+
+		paperStore := papers.NewStore(
+			getEnvOrDefault("PAPERS_TABLE_NAME", "papers-table"),
+			getEnvOrDefault("VECTORS_TABLE_NAME", "vectors-table"),
+		)
+		if err := paperStore.SetVectorPartitionKey(getEnvOrDefault("VECTORS_PARTITION_KEY", papers.DefaultVectorPartitionKey)); err != nil {
+			componentsErr = err
+			return
+		}
+		cacheSize, err := strconv.Atoi(getEnvOrDefault("PAPER_CACHE_SIZE", "1000"))
+		if err != nil {
+			componentsErr = fmt.Errorf("invalid PAPER_CACHE_SIZE %q", os.Getenv("PAPER_CACHE_SIZE"))
+			return
+		}
+		cacheTTL, err := strconv.Atoi(getEnvOrDefault("PAPER_CACHE_TTL_SECONDS", "300"))
+		if err != nil {
+			componentsErr = fmt.Errorf("invalid PAPER_CACHE_TTL_SECONDS %q", os.Getenv("PAPER_CACHE_TTL_SECONDS"))
+			return
+		}
+		if err := paperStore.SetReferenceCache(cacheSize, time.Duration(cacheTTL)*time.Second); err != nil {
+			componentsErr = err
+			return
+		}
+		if archiveBucket := os.Getenv("VECTOR_ARCHIVE_BUCKET"); archiveBucket != "" {
+			if err := paperStore.SetArchive(s3.New(awsclient.MustSession()), archiveBucket); err != nil {
+				componentsErr = err
+				return
+			}
+		}
+
+		components = &searchComponents{
+			loader:     indexstore.NewLoader(store, getEnvOrDefault("INDEX_DIR", os.TempDir()), time.Duration(refresh)*time.Second),
+			apiClient:  client.NewVectorAPIClient(getEnvOrDefault("EMBEDDING_API_URL", "https://embedding-api.example.com/embed")),
+			paperStore: paperStore,
+		}
+		if rerankURL := os.Getenv("RERANK_API_URL"); rerankURL != "" {
+			components.scorer = client.NewRerankAPIClient(rerankURL, os.Getenv("RERANK_API_KEY"), os.Getenv("RERANK_MODEL"))
+		}
+	})
+	return components, componentsErr
+}
+
+// newIndexStore creates the S3 store for INDEX_BUCKET/INDEX_PREFIX
+func newIndexStore() (*indexstore.Store, error) {
+	bucket := os.Getenv("INDEX_BUCKET")
+	if bucket == "" {
+		return nil, fmt.Errorf("INDEX_BUCKET is required")
+	}
+	return indexstore.NewStore(bucket, getEnvOrDefault("INDEX_PREFIX", "vector-index"))
+}
+
+// loadHNSWConfig reads the HNSW_M and HNSW_EF_CONSTRUCTION overrides
+func loadHNSWConfig() (hnsw.Config, error) {
+	config := hnsw.DefaultConfig()
+	for name, target := range map[string]*int{
+		"HNSW_M":               &config.M,
+		"HNSW_EF_CONSTRUCTION": &config.EfConstruction,
+	} {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return config, fmt.Errorf("invalid %s %q: %w", name, value, err)
+		}
+		*target = parsed
+	}
+	return config, config.Validate()
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,109 @@
+package papers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"shared/vectorarchive"
+)
+
+// accessTouchInterval is how stale a paper's access time may get before a read refreshes it;
+// the archive job only needs day resolution, so most reads write nothing
+const accessTouchInterval = 24 * time.Hour
+
+// archiveStore reads vectors moved to the cold archive
+type archiveStore struct {
+	client s3iface.S3API
+	bucket string
+}
+
+// SetArchive falls back to the cold archive for vectors no longer in the vectors table and
+// records when each paper's vectors are read, which keeps them out of the archive job
+func (s *Store) SetArchive(client s3iface.S3API, bucket string) error {
+	if bucket == "" {
+		return fmt.Errorf("archive bucket must not be empty")
+	}
+	s.archive = &archiveStore{client: client, bucket: bucket}
+	return nil
+}
+
+// archivedVectors reads the paper's vectors from its archive shards, skipping vector types
+// still in the table
+func (s *Store) archivedVectors(ctx context.Context, paperID string, keys []string, stored []VectorSummary) ([]VectorSummary, error) {
+	present := make(map[string]bool, len(stored))
+	for _, vector := range stored {
+		present[vector.VectorType] = true
+	}
+
+	var summaries []VectorSummary
+	for _, key := range keys {
+		object, err := s.archive.client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+			Bucket: aws.String(s.archive.bucket),
+			Key:    aws.String(key),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get archive shard %s: %w", key, err)
+		}
+		err = vectorarchive.ReadShard(object.Body, func(item map[string]*dynamodb.AttributeValue) error {
+			if id := item[s.vectorPartitionKey]; id == nil || aws.StringValue(id.S) != paperID {
+				return nil
+			}
+			var vector vectorItem
+			if err := dynamodbattribute.UnmarshalMap(item, &vector); err != nil {
+				return fmt.Errorf("failed to unmarshal archived vector: %w", err)
+			}
+			if present[vector.VectorType] {
+				return nil
+			}
+			present[vector.VectorType] = true
+			summary := vector.summary()
+			summary.Archived = true
+			summaries = append(summaries, summary)
+			return nil
+		})
+		object.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("archive shard %s: %w", key, err)
+		}
+	}
+	return summaries, nil
+}
+
+// touchAccessed records that the paper's vectors were read, at most once per interval. It is
+// best effort: a failed write only makes the paper look idle to the archive job.
+func (s *Store) touchAccessed(ctx context.Context, paper paperItem) {
+	now := time.Now().UTC()
+	if accessed, err := time.Parse(time.RFC3339, paper.VectorsAccessedAt); err == nil && now.Sub(accessed) < accessTouchInterval {
+		return
+	}
+
+	_, err := s.client.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName:           aws.String(s.papersTable),
+		Key:                 map[string]*dynamodb.AttributeValue{"paper_id": {S: aws.String(paper.PaperID)}},
+		UpdateExpression:    aws.String("SET #acc = :now"),
+		ConditionExpression: aws.String("attribute_exists(paper_id) AND (attribute_not_exists(#acc) OR #acc < :stale)"),
+		ExpressionAttributeNames: map[string]*string{
+			"#acc": aws.String(vectorarchive.AccessedAtAttribute),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":now":   {S: aws.String(now.Format(time.RFC3339))},
+			":stale": {S: aws.String(now.Add(-accessTouchInterval).Format(time.RFC3339))},
+		},
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+			return
+		}
+		s.logger.WithContext(ctx).Warn("Failed to record vector access", map[string]interface{}{
+			"paper_id": paper.PaperID,
+			"error":    err.Error(),
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package papers
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// referenceCache is a least-recently-used cache of paper metadata, kept for the life of a
+// warm container so repeated top-k hydration reads the papers table only for papers it has
+// not seen recently. Entries expire after ttl, which bounds how long an edit or a takedown
+// takes to show up in results.
+type referenceCache struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	order    *list.List // most recently used at the front
+	entries  map[string]*list.Element
+	hits     int64
+	misses   int64
+}
+
+// cachedReference is one cache entry; taken-down papers are cached too, so they stay hidden
+// without a read
+type cachedReference struct {
+	reference Reference
+	expiresAt time.Time
+}
+
+func newReferenceCache(capacity int, ttl time.Duration) *referenceCache {
+	return &referenceCache{
+		capacity: capacity,
+		ttl:      ttl,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element, capacity),
+	}
+}
+
+// get returns the cached metadata of a paper that has not expired
+func (c *referenceCache) get(paperID string, now time.Time) (Reference, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[paperID]
+	if !ok {
+		c.misses++
+		return Reference{}, false
+	}
+	entry := element.Value.(*cachedReference)
+	if now.After(entry.expiresAt) {
+		c.order.Remove(element)
+		delete(c.entries, paperID)
+		c.misses++
+		return Reference{}, false
+	}
+	c.order.MoveToFront(element)
+	c.hits++
+	return entry.reference, true
+}
+
+// put caches a paper's metadata, evicting the least recently used paper when full
+func (c *referenceCache) put(reference Reference, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry := &cachedReference{reference: reference, expiresAt: now.Add(c.ttl)}
+	if element, ok := c.entries[reference.PaperID]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[reference.PaperID] = c.order.PushFront(entry)
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cachedReference).reference.PaperID)
+	}
+}
+
+// CacheStats reports the paper metadata cache's size and lookups since the container started
+type CacheStats struct {
+	Size   int   `json:"size"`
+	Hits   int64 `json:"hits"`
+	Misses int64 `json:"misses"`
+}
+
+func (c *referenceCache) stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return CacheStats{Size: c.order.Len(), Hits: c.hits, Misses: c.misses}
+}
+
+// SetReferenceCache caches the metadata References reads, up to size papers for ttl each.
+// A size of 0 disables the cache.
+func (s *Store) SetReferenceCache(size int, ttl time.Duration) error {
+	if size < 0 {
+		return fmt.Errorf("paper cache size must not be negative, got %d", size)
+	}
+	if size > 0 && ttl <= 0 {
+		return fmt.Errorf("paper cache ttl must be positive, got %s", ttl)
+	}
+	s.cache = nil
+	if size > 0 {
+		s.cache = newReferenceCache(size, ttl)
+	}
+	return nil
+}
+
+// CacheStats reports the paper metadata cache, and false when the cache is disabled
+func (s *Store) CacheStats() (CacheStats, bool) {
+	if s.cache == nil {
+		return CacheStats{}, false
+	}
+	return s.cache.stats(), true
+}
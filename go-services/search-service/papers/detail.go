@@ -0,0 +1,308 @@
+package papers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"shared/awsclient"
+	"shared/dynamo"
+	"shared/logger"
+)
+
+// DefaultVectorPartitionKey is the vectors table attribute holding the paper ID
+const DefaultVectorPartitionKey = "paper_id"
+
+// ErrNotFound is returned for papers that do not exist or have been taken down
+var ErrNotFound = errors.New("paper not found")
+
+// PaperDetail is a paper merged with its vectors' metadata and lineage, shaped for the UI
+type PaperDetail struct {
+	PaperID       string          `json:"paper_id"`
+	Source        string          `json:"source"`
+	Title         string          `json:"title"`
+	Abstract      string          `json:"abstract"`
+	Authors       []string        `json:"authors"`
+	PublishedDate string          `json:"published_date"`
+	Categories    []string        `json:"categories"`
+	Enrichment    Enrichment      `json:"enrichment"`
+	Vectors       []VectorSummary `json:"vectors"`
+	ModelVersions []string        `json:"model_versions"` // distinct model versions with a stored vector
+	Lineage       Lineage         `json:"lineage"`
+}
+
+// Enrichment holds fields added to the paper after collection
+type Enrichment struct {
+	DOI         string   `json:"doi,omitempty"`
+	AuthorIDs   []string `json:"author_ids,omitempty"`
+	FullTextKey string   `json:"full_text_key,omitempty"` // set by the PDF extractor
+	PageCount   int      `json:"page_count,omitempty"`
+}
+
+// VectorSummary describes one stored vector without its embedding
+type VectorSummary struct {
+	VectorType      string             `json:"vector_type"`
+	ModelName       string             `json:"model_name"`
+	ModelVersion    string             `json:"model_version"`
+	Dimension       int                `json:"dimension"`
+	FieldWeights    map[string]float64 `json:"field_weights,omitempty"`
+	TruncationRatio float64            `json:"truncation_ratio,omitempty"` // fraction of the source text embedded; unset when none was cut
+	TraceID         string             `json:"trace_id"`
+	CreatedAt       string             `json:"created_at"`
+	Status          string             `json:"status,omitempty"`   // "pending" until all the paper's vectors are written, then "ready"
+	Archived        bool               `json:"archived,omitempty"` // read from the cold archive rather than the vectors table
+}
+
+// Lineage traces a paper from the raw-data object it was ingested from through its revisions
+type Lineage struct {
+	TraceID          string         `json:"trace_id"`
+	BatchTimestamp   string         `json:"batch_timestamp"`
+	ProcessingStatus string         `json:"processing_status"`
+	RawDataBucket    string         `json:"raw_data_bucket,omitempty"`
+	RawDataKey       string         `json:"raw_data_key,omitempty"`
+	Version          int            `json:"version"`
+	VersionHistory   []PaperVersion `json:"version_history,omitempty"`
+	CreatedAt        string         `json:"created_at"`
+	UpdatedAt        string         `json:"updated_at"`
+}
+
+// PaperVersion records a superseded revision of the paper
+type PaperVersion struct {
+	Version      int    `json:"version" dynamodbav:"version"`
+	TitleHash    string `json:"title_hash" dynamodbav:"title_hash"`
+	AbstractHash string `json:"abstract_hash" dynamodbav:"abstract_hash"`
+	Source       string `json:"source" dynamodbav:"source"`
+	TraceID      string `json:"trace_id" dynamodbav:"trace_id"`
+	UpdatedAt    string `json:"updated_at" dynamodbav:"updated_at"`
+}
+
+// paperItem holds the papers table attributes read for a detail view
+type paperItem struct {
+	PaperID           string         `dynamodbav:"paper_id"`
+	Source            string         `dynamodbav:"source"`
+	Title             string         `dynamodbav:"title"`
+	Abstract          string         `dynamodbav:"abstract"`
+	Authors           []string       `dynamodbav:"authors"`
+	AuthorIDs         []string       `dynamodbav:"author_ids"`
+	PublishedDate     string         `dynamodbav:"published_date"`
+	Categories        []string       `dynamodbav:"categories"`
+	DOI               string         `dynamodbav:"doi"`
+	TraceID           string         `dynamodbav:"trace_id"`
+	BatchTimestamp    string         `dynamodbav:"batch_timestamp"`
+	ProcessingStatus  string         `dynamodbav:"processing_status"`
+	CreatedAt         string         `dynamodbav:"created_at"`
+	UpdatedAt         string         `dynamodbav:"updated_at"`
+	Version           int            `dynamodbav:"version"`
+	VersionHistory    []PaperVersion `dynamodbav:"version_history"`
+	RawDataBucket     string         `dynamodbav:"raw_data_bucket"`
+	RawDataKey        string         `dynamodbav:"raw_data_key"`
+	Deleted           bool           `dynamodbav:"deleted"`
+	FullTextKey       string         `dynamodbav:"full_text_key"`
+	PageCount         int            `dynamodbav:"page_count"`
+	ArchiveKeys       []string       `dynamodbav:"vector_archive_keys"`
+	VectorsAccessedAt string         `dynamodbav:"vectors_accessed_at"`
+}
+
+// vectorItem holds the vectors table attributes read for a detail view; the embedding is not fetched
+type vectorItem struct {
+	VectorType        string `dynamodbav:"vector_type"`
+	EmbeddingMetadata struct {
+		ModelName       string             `dynamodbav:"model_name"`
+		ModelVersion    string             `dynamodbav:"model_version"`
+		Dimension       int                `dynamodbav:"dimension"`
+		FieldWeights    map[string]float64 `dynamodbav:"field_weights"`
+		TruncationRatio float64            `dynamodbav:"truncation_ratio"`
+	} `dynamodbav:"embedding_metadata"`
+	ProcessingInfo struct {
+		CreatedAt string `dynamodbav:"created_at"`
+		TraceID   string `dynamodbav:"trace_id"`
+	} `dynamodbav:"processing_info"`
+	Status string `dynamodbav:"status"`
+}
+
+// summary describes the vector without its embedding
+func (item vectorItem) summary() VectorSummary {
+	return VectorSummary{
+		VectorType:      item.VectorType,
+		ModelName:       item.EmbeddingMetadata.ModelName,
+		ModelVersion:    item.EmbeddingMetadata.ModelVersion,
+		Dimension:       item.EmbeddingMetadata.Dimension,
+		FieldWeights:    item.EmbeddingMetadata.FieldWeights,
+		TruncationRatio: item.EmbeddingMetadata.TruncationRatio,
+		TraceID:         item.ProcessingInfo.TraceID,
+		CreatedAt:       item.ProcessingInfo.CreatedAt,
+		Status:          item.Status,
+	}
+}
+
+// Store reads paper details from the papers and vectors tables
+type Store struct {
+	client             dynamodbiface.DynamoDBAPI
+	papersTable        string
+	vectorsTable       string
+	vectorPartitionKey string
+	cache              *referenceCache // nil when disabled
+	archive            *archiveStore   // nil without a cold archive
+	logger             *logger.Logger
+}
+
+// NewStore creates a store over the papers and vectors tables
+func NewStore(papersTable, vectorsTable string) *Store {
+	sess := awsclient.MustSession()
+	return NewStoreWithClient(dynamodb.New(sess), papersTable, vectorsTable)
+}
+
+// NewStoreWithClient creates a store with a custom DynamoDB client (for testing)
+func NewStoreWithClient(client dynamodbiface.DynamoDBAPI, papersTable, vectorsTable string) *Store {
+	return &Store{
+		client:             client,
+		papersTable:        papersTable,
+		vectorsTable:       vectorsTable,
+		vectorPartitionKey: DefaultVectorPartitionKey,
+		logger:             logger.New("paper-store"),
+	}
+}
+
+// SetVectorPartitionKey sets the vectors table attribute holding the paper ID
+func (s *Store) SetVectorPartitionKey(name string) error {
+	if name == "" {
+		return fmt.Errorf("vector partition key must not be empty")
+	}
+	s.vectorPartitionKey = name
+	return nil
+}
+
+// Detail returns the paper merged with its vectors' metadata. Soft-deleted papers
+// report ErrNotFound, as they are hidden from every read path.
+func (s *Store) Detail(ctx context.Context, paperID string) (*PaperDetail, error) {
+	result, err := s.client.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(s.papersTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"paper_id": {S: aws.String(paperID)},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get paper %s: %w", paperID, err)
+	}
+	if len(result.Item) == 0 {
+		return nil, ErrNotFound
+	}
+
+	var paper paperItem
+	if err := dynamodbattribute.UnmarshalMap(result.Item, &paper); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal paper %s: %w", paperID, err)
+	}
+	if paper.Deleted {
+		return nil, ErrNotFound
+	}
+
+	vectors, err := s.vectors(ctx, paperID)
+	if err != nil {
+		return nil, err
+	}
+	if s.archive != nil {
+		if len(paper.ArchiveKeys) > 0 {
+			archived, err := s.archivedVectors(ctx, paperID, paper.ArchiveKeys, vectors)
+			if err != nil {
+				return nil, err
+			}
+			vectors = append(vectors, archived...)
+			sort.Slice(vectors, func(i, j int) bool {
+				return vectors[i].VectorType < vectors[j].VectorType
+			})
+		}
+		s.touchAccessed(ctx, paper)
+	}
+
+	detail := &PaperDetail{
+		PaperID:       paper.PaperID,
+		Source:        paper.Source,
+		Title:         paper.Title,
+		Abstract:      paper.Abstract,
+		Authors:       paper.Authors,
+		PublishedDate: paper.PublishedDate,
+		Categories:    paper.Categories,
+		Enrichment: Enrichment{
+			DOI:         paper.DOI,
+			AuthorIDs:   paper.AuthorIDs,
+			FullTextKey: paper.FullTextKey,
+			PageCount:   paper.PageCount,
+		},
+		Vectors:       vectors,
+		ModelVersions: modelVersions(vectors),
+		Lineage: Lineage{
+			TraceID:          paper.TraceID,
+			BatchTimestamp:   paper.BatchTimestamp,
+			ProcessingStatus: paper.ProcessingStatus,
+			RawDataBucket:    paper.RawDataBucket,
+			RawDataKey:       paper.RawDataKey,
+			Version:          paper.Version,
+			VersionHistory:   paper.VersionHistory,
+			CreatedAt:        paper.CreatedAt,
+			UpdatedAt:        paper.UpdatedAt,
+		},
+	}
+	return detail, nil
+}
+
+// vectors queries the metadata of every vector stored for a paper, sorted by vector type
+func (s *Store) vectors(ctx context.Context, paperID string) ([]VectorSummary, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(s.vectorsTable),
+		KeyConditionExpression: aws.String("#pk = :pid"),
+		ProjectionExpression:   aws.String("#vt, #em, #pi, #st"),
+		ExpressionAttributeNames: map[string]*string{
+			"#pk": aws.String(s.vectorPartitionKey),
+			"#vt": aws.String("vector_type"),
+			"#em": aws.String("embedding_metadata"),
+			"#pi": aws.String("processing_info"),
+			"#st": aws.String("status"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":pid": {S: aws.String(paperID)},
+		},
+	}
+
+	summaries := []VectorSummary{}
+	_, err := dynamo.Paginate(ctx, dynamo.Query(s.client, input), dynamo.Options{}, func(page dynamo.Page) error {
+		var items []vectorItem
+		if err := dynamodbattribute.UnmarshalListOfMaps(page.Items, &items); err != nil {
+			return fmt.Errorf("failed to unmarshal vectors for %s: %w", paperID, err)
+		}
+		for _, item := range items {
+			summaries = append(summaries, item.summary())
+		}
+		return nil
+	})
+	if err != nil {
+		var fetchErr *dynamo.FetchError
+		if errors.As(err, &fetchErr) {
+			return nil, fmt.Errorf("failed to query vectors for %s: %w", paperID, err)
+		}
+		return nil, err
+	}
+
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].VectorType < summaries[j].VectorType
+	})
+	return summaries, nil
+}
+
+// modelVersions returns the distinct model versions of the vectors, sorted
+func modelVersions(vectors []VectorSummary) []string {
+	seen := make(map[string]bool)
+	versions := []string{}
+	for _, vector := range vectors {
+		if vector.ModelVersion != "" && !seen[vector.ModelVersion] {
+			seen[vector.ModelVersion] = true
+			versions = append(versions, vector.ModelVersion)
+		}
+	}
+	sort.Strings(versions)
+	return versions
+}
@@ -0,0 +1,127 @@
+package papers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// maxBatchGetKeys is the BatchGetItem limit on keys per request
+const maxBatchGetKeys = 100
+
+// maxBatchGetAttempts bounds the retries of keys DynamoDB returns unprocessed under throttling
+const maxBatchGetAttempts = 5
+
+// Reference holds the stored bibliographic metadata of a paper, as exported to reference managers
+type Reference struct {
+	PaperID       string   `json:"paper_id" dynamodbav:"paper_id"`
+	Source        string   `json:"source" dynamodbav:"source"`
+	Title         string   `json:"title" dynamodbav:"title"`
+	Abstract      string   `json:"abstract" dynamodbav:"abstract"`
+	Authors       []string `json:"authors" dynamodbav:"authors"`
+	PublishedDate string   `json:"published_date" dynamodbav:"published_date"`
+	Categories    []string `json:"categories" dynamodbav:"categories"`
+	DOI           string   `json:"doi,omitempty" dynamodbav:"doi"`
+	Deleted       bool     `json:"-" dynamodbav:"deleted"`
+}
+
+// References reads the bibliographic metadata of the given papers in their order. Papers
+// that do not exist or have been taken down are left out. With the reference cache set,
+// only papers missing from it are read from the papers table.
+func (s *Store) References(ctx context.Context, paperIDs []string) ([]Reference, error) {
+	byID := make(map[string]Reference, len(paperIDs))
+	toRead := paperIDs
+	if s.cache != nil {
+		now := time.Now()
+		toRead = make([]string, 0, len(paperIDs))
+		for _, paperID := range paperIDs {
+			if reference, ok := s.cache.get(paperID, now); ok {
+				byID[paperID] = reference
+				continue
+			}
+			toRead = append(toRead, paperID)
+		}
+	}
+
+	read := make(map[string]Reference, len(toRead))
+	for start := 0; start < len(toRead); start += maxBatchGetKeys {
+		end := min(start+maxBatchGetKeys, len(toRead))
+		if err := s.batchGetReferences(ctx, toRead[start:end], read); err != nil {
+			return nil, err
+		}
+	}
+	now := time.Now()
+	for paperID, reference := range read {
+		byID[paperID] = reference
+		if s.cache != nil {
+			s.cache.put(reference, now)
+		}
+	}
+
+	references := make([]Reference, 0, len(byID))
+	for _, paperID := range paperIDs {
+		if reference, ok := byID[paperID]; ok && !reference.Deleted {
+			references = append(references, reference)
+			delete(byID, paperID)
+		}
+	}
+	return references, nil
+}
+
+// batchGetReferences reads one BatchGetItem chunk into byID, retrying unprocessed keys
+func (s *Store) batchGetReferences(ctx context.Context, paperIDs []string, byID map[string]Reference) error {
+	seen := make(map[string]bool, len(paperIDs))
+	keys := make([]map[string]*dynamodb.AttributeValue, 0, len(paperIDs))
+	for _, paperID := range paperIDs {
+		if seen[paperID] {
+			continue
+		}
+		seen[paperID] = true
+		keys = append(keys, map[string]*dynamodb.AttributeValue{
+			"paper_id": {S: aws.String(paperID)},
+		})
+	}
+
+	requestItems := map[string]*dynamodb.KeysAndAttributes{
+		s.papersTable: {
+			Keys:                 keys,
+			ProjectionExpression: aws.String("paper_id, #src, title, abstract, authors, published_date, categories, doi, deleted"),
+			ExpressionAttributeNames: map[string]*string{
+				"#src": aws.String("source"),
+			},
+		},
+	}
+	for attempt := 1; len(requestItems) > 0; attempt++ {
+		if attempt > maxBatchGetAttempts {
+			return fmt.Errorf("papers for export still unprocessed after %d attempts", maxBatchGetAttempts)
+		}
+		if attempt > 1 {
+			select {
+			case <-time.After(time.Duration(attempt*attempt) * 50 * time.Millisecond):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		output, err := s.client.BatchGetItemWithContext(ctx, &dynamodb.BatchGetItemInput{
+			RequestItems: requestItems,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to read papers for export: %w", err)
+		}
+
+		var items []Reference
+		if err := dynamodbattribute.UnmarshalListOfMaps(output.Responses[s.papersTable], &items); err != nil {
+			return fmt.Errorf("failed to unmarshal papers for export: %w", err)
+		}
+		for _, item := range items {
+			byID[item.PaperID] = item
+		}
+		requestItems = output.UnprocessedKeys
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/s3"
+
+	"shared/awsclient"
+	"shared/preflight"
+)
+
+// preflightTimeout bounds the embedding API health request
+const preflightTimeout = 5 * time.Second
+
+// preflightGuard verifies the service's resources on the first invocation of a container
+var preflightGuard preflight.Guard
+
+// runPreflight checks that the index bucket and the papers and vectors tables exist and,
+// when queries are embedded here, that the embedding API answers its health endpoint
+// (EMBEDDING_API_HEALTH_URL, by default the embed URL's /health)
+func runPreflight(ctx context.Context, embedding bool) error {
+	sess := awsclient.MustSession()
+	dynamoClient := dynamodb.New(sess)
+	checks := []preflight.Check{
+		preflight.Bucket(s3.New(sess), os.Getenv("INDEX_BUCKET")),
+		preflight.Table(dynamoClient, getEnvOrDefault("PAPERS_TABLE_NAME", "papers-table")),
+		preflight.Table(dynamoClient, getEnvOrDefault("VECTORS_TABLE_NAME", "vectors-table")),
+	}
+	if embedding {
+		embedURL := getEnvOrDefault("EMBEDDING_API_URL", "https://embedding-api.example.com/embed")
+		healthURL := getEnvOrDefault("EMBEDDING_API_HEALTH_URL", strings.TrimSuffix(strings.TrimRight(embedURL, "/"), "/embed")+"/health")
+		checks = append(checks, preflight.Endpoint(&http.Client{Timeout: preflightTimeout}, healthURL))
+	}
+	return preflightGuard.Run(ctx, checks)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"search-service/rerank"
+)
+
+// Re-ranking depth: RerankDepthFactor * top_k candidates are retrieved and re-ranked by
+// default, at most MaxRerankCandidates
+const (
+	RerankDepthFactor   = 3
+	MaxRerankCandidates = 300
+)
+
+// RerankOptions re-ranks the top candidates of a search before they are cut to top_k
+type RerankOptions struct {
+	Steps      []rerank.Step `json:"steps"`
+	Candidates int           `json:"candidates,omitempty"` // retrieved and re-ranked; at least top_k
+}
+
+// normalizeRerank validates the re-ranking options and fills in defaults
+func normalizeRerank(request *SearchRequest) error {
+	options := request.Rerank
+	if options == nil {
+		return nil
+	}
+	if len(options.Steps) == 0 {
+		return fmt.Errorf("rerank requires at least one step")
+	}
+	for i := range options.Steps {
+		if err := options.Steps[i].Validate(); err != nil {
+			return err
+		}
+		if options.Steps[i].Type == rerank.TypeCrossEncoder && request.Query == "" {
+			return fmt.Errorf("cross_encoder re-ranking requires query text")
+		}
+	}
+	if options.Candidates == 0 {
+		options.Candidates = request.TopK * RerankDepthFactor
+		if options.Candidates > MaxRerankCandidates {
+			options.Candidates = MaxRerankCandidates
+		}
+	}
+	if options.Candidates < request.TopK || options.Candidates > MaxRerankCandidates {
+		return fmt.Errorf("rerank candidates must be between top_k (%d) and %d, got %d", request.TopK, MaxRerankCandidates, options.Candidates)
+	}
+	return nil
+}
+
+// rerankResults re-ranks hydrated candidates with the request's steps and keeps the top_k.
+// Each result keeps its retrieval score and the boost of every re-ranker.
+func rerankResults(ctx context.Context, scorer rerank.Scorer, request SearchRequest, results []SearchResult) ([]SearchResult, error) {
+	rerankers, err := rerank.New(request.Rerank.Steps, scorer)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]rerank.Candidate, len(results))
+	byID := make(map[string]SearchResult, len(results))
+	for i, result := range results {
+		candidates[i] = rerank.Candidate{PaperID: result.PaperID, Score: result.Score, Paper: result.Paper}
+		byID[result.PaperID] = result
+	}
+	if err := rerank.Apply(ctx, rerankers, request.Query, candidates); err != nil {
+		return nil, err
+	}
+
+	if len(candidates) > request.TopK {
+		candidates = candidates[:request.TopK]
+	}
+	reranked := make([]SearchResult, len(candidates))
+	for i, candidate := range candidates {
+		result := byID[candidate.PaperID]
+		result.RetrievalScore = result.Score
+		result.Score = candidate.Score
+		result.Rerank = candidate.Boosts
+		reranked[i] = result
+	}
+	return reranked, nil
+}
@@ -0,0 +1,235 @@
+// Package rerank reorders the top candidates of a search before they are returned. Each
+// re-ranker adds a weighted boost to a candidate's score, so re-rankers combine with each
+// other and with the retrieval score in the order a request lists them.
+package rerank
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"search-service/papers"
+)
+
+// Re-ranker types
+const (
+	TypeRecency      = "recency"       // newer papers, decaying with a half-life
+	TypeCategory     = "category"      // papers in any of the listed categories
+	TypeCrossEncoder = "cross_encoder" // query-document relevance from the re-ranking API
+)
+
+// DefaultHalfLifeDays is the age at which the recency boost has halved
+const DefaultHalfLifeDays = 365
+
+// Candidate is a result being re-ranked. Paper is the metadata the re-rankers score; a
+// candidate without it gets no boost.
+type Candidate struct {
+	PaperID string
+	Score   float64
+	Paper   *papers.Reference
+	// Boosts records what each re-ranker added to Score, by type
+	Boosts map[string]float64
+}
+
+// Reranker adds its boosts to the candidates' scores
+type Reranker interface {
+	Type() string
+	Rerank(ctx context.Context, query string, candidates []Candidate) error
+}
+
+// Scorer rates how relevant each document is to the query, as a cross-encoder does
+type Scorer interface {
+	Score(ctx context.Context, query string, documents []string) ([]float64, error)
+}
+
+// Step configures one re-ranker of a request
+type Step struct {
+	Type         string   `json:"type"`
+	Weight       *float64 `json:"weight,omitempty"`         // multiplies the boost; defaults to 1
+	HalfLifeDays float64  `json:"half_life_days,omitempty"` // recency only
+	Categories   []string `json:"categories,omitempty"`     // category only
+}
+
+// Validate checks the step and fills in defaults
+func (s *Step) Validate() error {
+	if s.Weight == nil {
+		weight := 1.0
+		s.Weight = &weight
+	}
+	if *s.Weight < 0 {
+		return fmt.Errorf("rerank weight must not be negative, got %g", *s.Weight)
+	}
+	switch s.Type {
+	case TypeRecency:
+		if s.HalfLifeDays == 0 {
+			s.HalfLifeDays = DefaultHalfLifeDays
+		}
+		if s.HalfLifeDays < 0 {
+			return fmt.Errorf("half_life_days must be positive, got %g", s.HalfLifeDays)
+		}
+	case TypeCategory:
+		if len(s.Categories) == 0 {
+			return fmt.Errorf("category re-ranking requires categories")
+		}
+	case TypeCrossEncoder:
+	default:
+		return fmt.Errorf("unknown rerank type %q (expected %q, %q or %q)", s.Type, TypeRecency, TypeCategory, TypeCrossEncoder)
+	}
+	return nil
+}
+
+// New builds the re-rankers of validated steps, in order. scorer backs cross_encoder steps
+// and may be nil when no re-ranking API is configured.
+func New(steps []Step, scorer Scorer) ([]Reranker, error) {
+	rerankers := make([]Reranker, 0, len(steps))
+	for _, step := range steps {
+		switch step.Type {
+		case TypeRecency:
+			rerankers = append(rerankers, &recency{weight: *step.Weight, halfLife: step.HalfLifeDays * 24 * float64(time.Hour), now: time.Now})
+		case TypeCategory:
+			rerankers = append(rerankers, &category{weight: *step.Weight, categories: step.Categories})
+		case TypeCrossEncoder:
+			if scorer == nil {
+				return nil, fmt.Errorf("cross_encoder re-ranking is not configured; set RERANK_API_URL")
+			}
+			rerankers = append(rerankers, &crossEncoder{weight: *step.Weight, scorer: scorer})
+		}
+	}
+	return rerankers, nil
+}
+
+// Apply runs the re-rankers over the candidates and sorts them by their new scores, ties
+// keeping the retrieval order
+func Apply(ctx context.Context, rerankers []Reranker, query string, candidates []Candidate) error {
+	for _, reranker := range rerankers {
+		if err := reranker.Rerank(ctx, query, candidates); err != nil {
+			return fmt.Errorf("%s re-ranking failed: %w", reranker.Type(), err)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Score > candidates[j].Score
+	})
+	return nil
+}
+
+// boost adds value to the candidate's score and records it
+func (c *Candidate) boost(rerankType string, value float64) {
+	if c.Boosts == nil {
+		c.Boosts = make(map[string]float64)
+	}
+	c.Score += value
+	c.Boosts[rerankType] += value
+}
+
+// recency boosts by weight * 0.5^(age / half-life), the full weight for a paper published now
+type recency struct {
+	weight   float64
+	halfLife float64 // nanoseconds
+	now      func() time.Time
+}
+
+func (r *recency) Type() string { return TypeRecency }
+
+func (r *recency) Rerank(ctx context.Context, query string, candidates []Candidate) error {
+	now := r.now()
+	for i := range candidates {
+		if candidates[i].Paper == nil {
+			continue
+		}
+		published, ok := parseDate(candidates[i].Paper.PublishedDate)
+		if !ok {
+			continue
+		}
+		age := math.Max(float64(now.Sub(published)), 0)
+		candidates[i].boost(TypeRecency, r.weight*math.Pow(0.5, age/r.halfLife))
+	}
+	return nil
+}
+
+// parseDate reads a published date stored as RFC 3339 or as a plain date
+func parseDate(value string) (time.Time, bool) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return parsed, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// category boosts by weight papers with any of the categories
+type category struct {
+	weight     float64
+	categories []string
+}
+
+func (c *category) Type() string { return TypeCategory }
+
+func (c *category) Rerank(ctx context.Context, query string, candidates []Candidate) error {
+	for i := range candidates {
+		if candidates[i].Paper == nil {
+			continue
+		}
+		for _, paperCategory := range candidates[i].Paper.Categories {
+			if c.matches(paperCategory) {
+				candidates[i].boost(TypeCategory, c.weight)
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func (c *category) matches(paperCategory string) bool {
+	for _, wanted := range c.categories {
+		if strings.EqualFold(paperCategory, wanted) {
+			return true
+		}
+	}
+	return false
+}
+
+// crossEncoder boosts by weight * the relevance the scorer gives the paper's title and
+// abstract for the query, scored in one request for all candidates
+type crossEncoder struct {
+	weight float64
+	scorer Scorer
+}
+
+func (c *crossEncoder) Type() string { return TypeCrossEncoder }
+
+func (c *crossEncoder) Rerank(ctx context.Context, query string, candidates []Candidate) error {
+	if query == "" {
+		return fmt.Errorf("query text is required")
+	}
+	var documents []string
+	var positions []int
+	for i, candidate := range candidates {
+		if candidate.Paper == nil {
+			continue
+		}
+		document := strings.TrimSpace(candidate.Paper.Title + "\n" + candidate.Paper.Abstract)
+		if document == "" {
+			continue
+		}
+		documents = append(documents, document)
+		positions = append(positions, i)
+	}
+	if len(documents) == 0 {
+		return nil
+	}
+
+	scores, err := c.scorer.Score(ctx, query, documents)
+	if err != nil {
+		return err
+	}
+	if len(scores) != len(documents) {
+		return fmt.Errorf("scorer returned %d scores for %d documents", len(scores), len(documents))
+	}
+	for i, position := range positions {
+		candidates[position].boost(TypeCrossEncoder, c.weight*scores[i])
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"search-service/export"
+	"search-service/papers"
+	"shared/awsclient"
+	"shared/httpserver"
+	"shared/shutdown"
+)
+
+// serverShutdownTimeout bounds how long in-flight requests may drain after a shutdown signal
+const serverShutdownTimeout = 30 * time.Second
+
+// runServer exposes search over HTTP until a shutdown signal drains it
+func runServer(addr string, watcher *shutdown.Watcher) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", httpserver.Healthz(watcher, appLogger))
+	mux.HandleFunc("/search", searchHandler)
+	mux.HandleFunc("/papers/", paperHandler)
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           awsclient.Handler("search-service", mux),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	return httpserver.Serve(server, watcher, serverShutdownTimeout, appLogger, nil)
+}
+
+// searchHandler answers a top-k query; POST /search with a SearchRequest body. With
+// "export" set the results are downloaded as a BibTeX or RIS file instead of JSON.
+func searchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httpserver.WriteJSON(w, appLogger, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	var request SearchRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		httpserver.WriteJSON(w, appLogger, http.StatusBadRequest, map[string]string{"error": "invalid input: " + err.Error()})
+		return
+	}
+	if err := normalizeRequest(&request); err != nil {
+		httpserver.WriteJSON(w, appLogger, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	response, err := handleSearch(r.Context(), request)
+	if err != nil {
+		httpserver.WriteJSON(w, appLogger, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	if response.Export != nil {
+		writeExport(w, response.Export)
+		return
+	}
+	httpserver.WriteJSON(w, appLogger, http.StatusOK, response)
+}
+
+// paperHandler returns the detail view of one paper; GET /papers/{id}. IDs may contain
+// slashes, as old-style arXiv IDs do, so everything after the prefix is the ID.
+// ?format=bibtex or ?format=ris downloads the paper's citation instead.
+func paperHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httpserver.WriteJSON(w, appLogger, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	paperID := strings.TrimPrefix(r.URL.Path, "/papers/")
+	if paperID == "" {
+		httpserver.WriteJSON(w, appLogger, http.StatusBadRequest, map[string]string{"error": "paper id is required"})
+		return
+	}
+
+	if format := r.URL.Query().Get("format"); format != "" {
+		if _, err := export.ParseFormat(format); err != nil {
+			httpserver.WriteJSON(w, appLogger, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		file, err := handleExport(r.Context(), []string{paperID}, format, "paper")
+		if err != nil {
+			httpserver.WriteJSON(w, appLogger, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		if file.Count == 0 {
+			httpserver.WriteJSON(w, appLogger, http.StatusNotFound, map[string]string{"error": papers.ErrNotFound.Error()})
+			return
+		}
+		writeExport(w, file)
+		return
+	}
+
+	detail, err := handlePaperDetail(r.Context(), paperID)
+	if errors.Is(err, papers.ErrNotFound) {
+		httpserver.WriteJSON(w, appLogger, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		httpserver.WriteJSON(w, appLogger, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	httpserver.WriteJSON(w, appLogger, http.StatusOK, detail)
+}
+
+// writeExport sends a rendered export as a file download
+func writeExport(w http.ResponseWriter, file *export.File) {
+	w.Header().Set("Content-Type", file.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", file.Filename))
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.WriteString(w, file.Content); err != nil {
+		appLogger.Error("Failed to write HTTP response", err)
+	}
+}
@@ -0,0 +1,75 @@
+// Package awsclient builds the AWS sessions and HTTP clients used by the services.
+// When X-Ray tracing is enabled every AWS API call and outgoing HTTP request is
+// recorded as a subsegment of the current trace.
+package awsclient
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-xray-sdk-go/strategy/ctxmissing"
+	"github.com/aws/aws-xray-sdk-go/xray"
+)
+
+var configureOnce sync.Once
+
+// TracingEnabled reports whether X-Ray instrumentation is turned on.
+// Lambda functions trace by default; other runs opt in with XRAY_ENABLED=true.
+func TracingEnabled() bool {
+	if value := os.Getenv("XRAY_ENABLED"); value != "" {
+		enabled, err := strconv.ParseBool(value)
+		return err == nil && enabled
+	}
+	return os.Getenv("AWS_LAMBDA_FUNCTION_NAME") != ""
+}
+
+// configureTracing sets up the X-Ray recorder once. Calls made outside a traced
+// request (local runs, CLI tools) are ignored instead of logged as errors.
+func configureTracing() {
+	configureOnce.Do(func() {
+		xray.Configure(xray.Config{
+			ContextMissingStrategy: ctxmissing.NewDefaultIgnoreErrorStrategy(),
+		})
+	})
+}
+
+// NewSession creates an AWS session whose clients are instrumented when tracing is enabled
+func NewSession(cfgs ...*aws.Config) (*session.Session, error) {
+	sess, err := session.NewSession(cfgs...)
+	if err != nil {
+		return nil, err
+	}
+	if !TracingEnabled() {
+		return sess, nil
+	}
+	configureTracing()
+	return xray.AWSSession(sess), nil
+}
+
+// MustSession is like NewSession but panics if the session cannot be created
+func MustSession(cfgs ...*aws.Config) *session.Session {
+	return session.Must(NewSession(cfgs...))
+}
+
+// HTTPClient instruments an HTTP client when tracing is enabled
+func HTTPClient(client *http.Client) *http.Client {
+	if !TracingEnabled() {
+		return client
+	}
+	configureTracing()
+	return xray.Client(client)
+}
+
+// Handler opens a segment per incoming request when tracing is enabled, so calls
+// made while serving it are recorded as subsegments
+func Handler(name string, handler http.Handler) http.Handler {
+	if !TracingEnabled() {
+		return handler
+	}
+	configureTracing()
+	return xray.Handler(xray.NewFixedSegmentNamer(name), handler)
+}
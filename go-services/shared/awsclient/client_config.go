@@ -0,0 +1,92 @@
+package awsclient
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+// Retry modes for ClientConfig
+const (
+	// RetryModeStandard retries throttling and transient errors with the SDK's
+	// exponential backoff and jitter, up to MaxAttempts attempts
+	RetryModeStandard = "standard"
+	// RetryModeNone makes a single attempt and leaves retries to the caller
+	// (e.g. a Step Functions retry policy)
+	RetryModeNone = "none"
+)
+
+// ClientConfig tunes the retry and timeout behaviour of the SDK clients built from a
+// session. Zero values keep the SDK defaults.
+type ClientConfig struct {
+	RetryMode     string `yaml:"retry_mode" json:"retry_mode,omitempty"`           // "standard" (default) or "none"
+	MaxAttempts   int    `yaml:"max_attempts" json:"max_attempts,omitempty"`       // total attempts including the first; 0 keeps the SDK default
+	CallTimeoutMs int    `yaml:"call_timeout_ms" json:"call_timeout_ms,omitempty"` // bound on one API call across all its attempts; 0 disables
+}
+
+// Validate checks the retry mode and bounds
+func (c ClientConfig) Validate() error {
+	switch c.RetryMode {
+	case "", RetryModeStandard, RetryModeNone:
+	default:
+		return fmt.Errorf("retry_mode must be %q or %q, got %q", RetryModeStandard, RetryModeNone, c.RetryMode)
+	}
+	if c.MaxAttempts < 0 {
+		return fmt.Errorf("max_attempts must not be negative, got %d", c.MaxAttempts)
+	}
+	if c.CallTimeoutMs < 0 {
+		return fmt.Errorf("call_timeout_ms must not be negative, got %d", c.CallTimeoutMs)
+	}
+	return nil
+}
+
+// retryer returns the SDK retryer for the settings, or nil to keep the SDK default
+func (c ClientConfig) retryer() request.Retryer {
+	switch {
+	case c.RetryMode == RetryModeNone:
+		return client.DefaultRetryer{NumMaxRetries: 0}
+	case c.MaxAttempts > 0:
+		return client.DefaultRetryer{NumMaxRetries: c.MaxAttempts - 1}
+	}
+	return nil
+}
+
+// NewClientSession creates a session like NewSession with the retry and timeout settings
+// applied to every client built from it
+func NewClientSession(settings ClientConfig, cfgs ...*aws.Config) (*session.Session, error) {
+	if err := settings.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid AWS client config: %w", err)
+	}
+	if retryer := settings.retryer(); retryer != nil {
+		cfgs = append(cfgs, request.WithRetryer(aws.NewConfig(), retryer))
+	}
+
+	sess, err := NewSession(cfgs...)
+	if err != nil {
+		return nil, err
+	}
+
+	if settings.CallTimeoutMs > 0 {
+		timeout := time.Duration(settings.CallTimeoutMs) * time.Millisecond
+		// Build runs once per call, so the deadline covers every retry of the call
+		sess.Handlers.Build.PushFrontNamed(request.NamedHandler{
+			Name: "awsclient.CallTimeout",
+			Fn: func(r *request.Request) {
+				ctx, cancel := context.WithTimeout(r.Context(), timeout)
+				r.SetContext(ctx)
+				r.Handlers.Complete.PushBack(func(*request.Request) { cancel() })
+			},
+		})
+	}
+	return sess, nil
+}
+
+// MustClientSession is like NewClientSession but panics if the session cannot be created
+func MustClientSession(settings ClientConfig, cfgs ...*aws.Config) *session.Session {
+	return session.Must(NewClientSession(settings, cfgs...))
+}
@@ -0,0 +1,7 @@
+module shared/dynamo
+
+go 1.23
+
+require github.com/aws/aws-sdk-go v1.55.5
+
+require github.com/jmespath/go-jmespath v0.4.0 // indirect
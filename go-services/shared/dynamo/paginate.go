@@ -0,0 +1,157 @@
+// Package dynamo holds DynamoDB helpers shared by the services and admin tools. Paginate
+// drives the LastEvaluatedKey loop of queries and scans once, with a page limit, a margin
+// before the context deadline and the consumed read capacity added up across pages.
+package dynamo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// Reasons a pagination stopped with items left unread
+const (
+	StopPageLimit = "page_limit"
+	StopDeadline  = "deadline"
+)
+
+// Page is one page of a query or scan
+type Page struct {
+	Number           int // 1-based
+	Items            []map[string]*dynamodb.AttributeValue
+	Count            int     // items matched, also for Select COUNT requests that return none
+	ConsumedCapacity float64 // read capacity units the page consumed
+	Duration         time.Duration
+	HasMore          bool // the table has items after this page
+}
+
+// Options bound a pagination; zero values disable each bound
+type Options struct {
+	MaxPages int // pages read at most
+	// DeadlineMargin stops before a page when the context deadline is closer than this, so a
+	// Lambda can report what it read instead of being killed mid-page
+	DeadlineMargin time.Duration
+}
+
+// Result summarizes a pagination
+type Result struct {
+	Pages            int
+	Items            int
+	ConsumedCapacity float64
+	// HasMore is set when a bound stopped the pagination with items left; StartKey resumes it
+	HasMore   bool
+	StoppedBy string // StopPageLimit or StopDeadline when HasMore
+	StartKey  map[string]*dynamodb.AttributeValue
+}
+
+// FetchError is a page that could not be read, told apart from the errors of the page
+// handler, which Paginate returns as is
+type FetchError struct {
+	Page int
+	Err  error
+}
+
+func (e *FetchError) Error() string {
+	return fmt.Sprintf("page %d: %v", e.Page, e.Err)
+}
+
+func (e *FetchError) Unwrap() error {
+	return e.Err
+}
+
+// FetchFunc reads the page starting at startKey, nil for the first page
+type FetchFunc func(ctx context.Context, startKey map[string]*dynamodb.AttributeValue) (*Page, map[string]*dynamodb.AttributeValue, error)
+
+// Paginate reads pages with fetch and hands each to fn until the items run out, a bound in
+// opts is reached, or fn returns an error, which is returned as is. A failed fetch is a
+// *FetchError.
+func Paginate(ctx context.Context, fetch FetchFunc, opts Options, fn func(Page) error) (Result, error) {
+	var result Result
+	var startKey map[string]*dynamodb.AttributeValue
+
+	for {
+		if result.Pages > 0 {
+			if opts.MaxPages > 0 && result.Pages >= opts.MaxPages {
+				result.HasMore, result.StoppedBy, result.StartKey = true, StopPageLimit, startKey
+				return result, nil
+			}
+			if deadline, ok := ctx.Deadline(); ok && opts.DeadlineMargin > 0 && time.Until(deadline) < opts.DeadlineMargin {
+				result.HasMore, result.StoppedBy, result.StartKey = true, StopDeadline, startKey
+				return result, nil
+			}
+		}
+
+		pageStart := time.Now()
+		page, lastKey, err := fetch(ctx, startKey)
+		if err != nil {
+			return result, &FetchError{Page: result.Pages + 1, Err: err}
+		}
+		result.Pages++
+		page.Number = result.Pages
+		page.Duration = time.Since(pageStart)
+		page.HasMore = len(lastKey) > 0
+		result.Items += page.Count
+		result.ConsumedCapacity += page.ConsumedCapacity
+
+		if err := fn(*page); err != nil {
+			return result, err
+		}
+		if !page.HasMore {
+			return result, nil
+		}
+		startKey = lastKey
+	}
+}
+
+// Query pages through a query. The input is copied per page and asks for the total consumed
+// capacity unless it already sets ReturnConsumedCapacity.
+func Query(client dynamodbiface.DynamoDBAPI, input *dynamodb.QueryInput) FetchFunc {
+	return func(ctx context.Context, startKey map[string]*dynamodb.AttributeValue) (*Page, map[string]*dynamodb.AttributeValue, error) {
+		pageInput := *input
+		pageInput.ExclusiveStartKey = startKey
+		if pageInput.ReturnConsumedCapacity == nil {
+			pageInput.ReturnConsumedCapacity = aws.String(dynamodb.ReturnConsumedCapacityTotal)
+		}
+		output, err := client.QueryWithContext(ctx, &pageInput)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &Page{
+			Items:            output.Items,
+			Count:            int(aws.Int64Value(output.Count)),
+			ConsumedCapacity: capacityUnits(output.ConsumedCapacity),
+		}, output.LastEvaluatedKey, nil
+	}
+}
+
+// Scan pages through a scan, like Query
+func Scan(client dynamodbiface.DynamoDBAPI, input *dynamodb.ScanInput) FetchFunc {
+	return func(ctx context.Context, startKey map[string]*dynamodb.AttributeValue) (*Page, map[string]*dynamodb.AttributeValue, error) {
+		pageInput := *input
+		pageInput.ExclusiveStartKey = startKey
+		if pageInput.ReturnConsumedCapacity == nil {
+			pageInput.ReturnConsumedCapacity = aws.String(dynamodb.ReturnConsumedCapacityTotal)
+		}
+		output, err := client.ScanWithContext(ctx, &pageInput)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &Page{
+			Items:            output.Items,
+			Count:            int(aws.Int64Value(output.Count)),
+			ConsumedCapacity: capacityUnits(output.ConsumedCapacity),
+		}, output.LastEvaluatedKey, nil
+	}
+}
+
+// capacityUnits reads the total units of a consumed capacity report, 0 when there is none
+func capacityUnits(capacity *dynamodb.ConsumedCapacity) float64 {
+	if capacity == nil {
+		return 0
+	}
+	return aws.Float64Value(capacity.CapacityUnits)
+}
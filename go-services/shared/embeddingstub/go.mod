@@ -0,0 +1,3 @@
+module shared/embeddingstub
+
+go 1.23
@@ -0,0 +1,301 @@
+// Package embeddingstub is a stand-in for the Python embedding API. It answers the native
+// /embed contract, single and batch, and /embed/batch with deterministic unit vectors after a
+// configurable delay, so the coordinator and search service can be developed and tested
+// without a model server, and coordinator throughput measured without its variance.
+package embeddingstub
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaxBatchTexts is the largest batch accepted, as in the Python API
+const MaxBatchTexts = 128
+
+// Config shapes the stub's responses
+type Config struct {
+	Dimension    int
+	ModelName    string
+	ModelVersion string
+	Latency      time.Duration // added to every request
+	PerText      time.Duration // added per text, so batches cost more than single requests
+	Jitter       float64       // fraction of the delay randomized, 0 to 1
+	ErrorRate    float64       // fraction of requests answered 503, 0 to 1
+}
+
+// Error codes of the Python API's error responses
+const (
+	ErrorCodeValidation  = "VALIDATION_ERROR"
+	ErrorCodeUnavailable = "MODEL_LOAD_ERROR"
+)
+
+// Stats summarizes the requests served since the stub started or was reset
+type Stats struct {
+	Requests    int     `json:"requests"`
+	Texts       int     `json:"texts"`
+	Errors      int     `json:"errors"`
+	LatencyMean float64 `json:"latency_mean_ms"`
+	LatencyP50  float64 `json:"latency_p50_ms"`
+	LatencyP95  float64 `json:"latency_p95_ms"`
+	LatencyMax  float64 `json:"latency_max_ms"`
+	MaxInFlight int     `json:"max_in_flight"` // highest number of concurrent requests seen
+}
+
+// Server is the stub embedding API
+type Server struct {
+	config Config
+
+	mu          sync.Mutex
+	rng         *rand.Rand
+	latencies   []time.Duration
+	requests    int
+	texts       int
+	errors      int
+	inFlight    int
+	maxInFlight int
+}
+
+// embedRequest is a native single or batch request
+type embedRequest struct {
+	Text  string   `json:"text"`
+	Texts []string `json:"texts"`
+}
+
+// NewServer creates a stub with the given response shape and delays
+func NewServer(config Config) *Server {
+	return &Server{config: config, rng: rand.New(rand.NewSource(1))}
+}
+
+// Handler serves POST /embed (a "text" or a "texts" batch), POST /embed/batch ("texts"
+// only), GET /health, and GET /stats (DELETE /stats resets them)
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/embed", func(w http.ResponseWriter, r *http.Request) { s.handleEmbed(w, r, false) })
+	mux.HandleFunc("/embed/batch", func(w http.ResponseWriter, r *http.Request) { s.handleEmbed(w, r, true) })
+	mux.HandleFunc("/health", s.handleHealth)
+	mux.HandleFunc("/stats", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			s.Reset()
+		}
+		writeJSON(w, http.StatusOK, s.Stats())
+	})
+	return mux
+}
+
+// handleEmbed answers an embed request; batchOnly rejects single-text requests
+func (s *Server) handleEmbed(w http.ResponseWriter, r *http.Request, batchOnly bool) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, ErrorCodeValidation, "method not allowed")
+		return
+	}
+	start := time.Now()
+
+	var request embedRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, ErrorCodeValidation, "Invalid JSON in request body: "+err.Error())
+		return
+	}
+	batch := request.Texts != nil || batchOnly
+	texts, err := validate(request, batch)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrorCodeValidation, err.Error())
+		return
+	}
+
+	delay, fail := s.begin(len(texts))
+	defer func() { s.end(time.Since(start), fail) }()
+	time.Sleep(delay)
+	if fail {
+		writeError(w, http.StatusServiceUnavailable, ErrorCodeUnavailable, "Model temporarily unavailable")
+		return
+	}
+
+	embeddings := make([][]float64, len(texts))
+	for i, text := range texts {
+		embeddings[i] = Embedding(text, s.config.Dimension)
+	}
+	processingMs := time.Since(start).Milliseconds()
+	if batch {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"embeddings":         embeddings,
+			"dimension":          s.config.Dimension,
+			"model_name":         s.config.ModelName,
+			"model_version":      s.config.ModelVersion,
+			"processing_time_ms": processingMs,
+		})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"embedding":          embeddings[0],
+		"dimension":          s.config.Dimension,
+		"model_name":         s.config.ModelName,
+		"model_version":      s.config.ModelVersion,
+		"processing_time_ms": processingMs,
+	})
+}
+
+// validate extracts the texts of a request with the Python API's rules: texts are trimmed
+// and must not be empty, and batches hold at most MaxBatchTexts
+func validate(request embedRequest, batch bool) ([]string, error) {
+	if !batch {
+		text := strings.TrimSpace(request.Text)
+		if text == "" {
+			return nil, fmt.Errorf("Text field is required and cannot be empty")
+		}
+		return []string{text}, nil
+	}
+
+	if len(request.Texts) == 0 {
+		return nil, fmt.Errorf("Texts field must be a non-empty list")
+	}
+	if len(request.Texts) > MaxBatchTexts {
+		return nil, fmt.Errorf("Batch has %d texts, more than the maximum of %d", len(request.Texts), MaxBatchTexts)
+	}
+	texts := make([]string, len(request.Texts))
+	for i, text := range request.Texts {
+		texts[i] = strings.TrimSpace(text)
+		if texts[i] == "" {
+			return nil, fmt.Errorf("Text %d of the batch must be a non-empty string", i)
+		}
+	}
+	return texts, nil
+}
+
+// handleHealth reports the stub as a loaded model, in the Python API's health format
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	stats := s.Stats()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status": "healthy",
+		"model_info": map[string]interface{}{
+			"model_name":    s.config.ModelName,
+			"model_version": s.config.ModelVersion,
+			"model_loaded":  true,
+			"dimension":     s.config.Dimension,
+		},
+		"statistics": map[string]interface{}{
+			"request_count":              stats.Requests,
+			"average_processing_time_ms": stats.LatencyMean,
+		},
+		"timestamp": time.Now().Unix(),
+	})
+}
+
+// begin records a request and draws its delay and whether it fails
+func (s *Server) begin(texts int) (time.Duration, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.inFlight++
+	s.texts += texts
+	if s.inFlight > s.maxInFlight {
+		s.maxInFlight = s.inFlight
+	}
+
+	delay := s.config.Latency + time.Duration(texts)*s.config.PerText
+	if s.config.Jitter > 0 {
+		delay += time.Duration((s.rng.Float64()*2 - 1) * s.config.Jitter * float64(delay))
+	}
+	return delay, s.rng.Float64() < s.config.ErrorRate
+}
+
+// end records a finished request
+func (s *Server) end(elapsed time.Duration, failed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.inFlight--
+	s.requests++
+	if failed {
+		s.errors++
+	}
+	s.latencies = append(s.latencies, elapsed)
+}
+
+// Stats returns the requests served so far
+func (s *Server) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := Stats{Requests: s.requests, Texts: s.texts, Errors: s.errors, MaxInFlight: s.maxInFlight}
+	if len(s.latencies) > 0 {
+		sorted := make([]time.Duration, len(s.latencies))
+		copy(sorted, s.latencies)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		var total time.Duration
+		for _, latency := range sorted {
+			total += latency
+		}
+		stats.LatencyMean = milliseconds(total / time.Duration(len(sorted)))
+		stats.LatencyP50 = milliseconds(percentile(sorted, 50))
+		stats.LatencyP95 = milliseconds(percentile(sorted, 95))
+		stats.LatencyMax = milliseconds(sorted[len(sorted)-1])
+	}
+	return stats
+}
+
+// Reset clears the stats, e.g. between load test runs against one stub
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latencies = nil
+	s.requests, s.texts, s.errors, s.maxInFlight = 0, 0, 0, s.inFlight
+}
+
+// Embedding derives a unit vector from the text alone, so repeated runs store identical vectors
+func Embedding(text string, dimension int) []float64 {
+	hash := fnv.New64a()
+	hash.Write([]byte(text))
+	rng := rand.New(rand.NewSource(int64(hash.Sum64())))
+
+	vector := make([]float64, dimension)
+	var norm float64
+	for i := range vector {
+		vector[i] = rng.NormFloat64()
+		norm += vector[i] * vector[i]
+	}
+	norm = math.Sqrt(norm)
+	for i := range vector {
+		vector[i] /= norm
+	}
+	return vector
+}
+
+// percentile returns the nearest-rank percentile of sorted durations
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// writeError writes an error in the Python API's {"error": {"code", "message", "timestamp"}}
+// format, which the embedding clients parse
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":      code,
+			"message":   message,
+			"timestamp": time.Now().Unix(),
+		},
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
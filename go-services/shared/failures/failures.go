@@ -0,0 +1,278 @@
+// Package failures records every failed record of the pipeline, whatever its stage, in the
+// FailedItems table under a normalized error code. One item per record accumulates a retry
+// count across runs, and the code-index GSI (code, last_failed_at) lets recurring failure
+// modes be queried and charted over time instead of being dug out of logs.
+package failures
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+
+	"shared/awsclient"
+	"shared/logger"
+)
+
+// TableEnv names the FailedItems table; recording is disabled when it is unset
+const TableEnv = "FAILED_ITEMS_TABLE_NAME"
+
+// CodeIndex is the GSI (code, last_failed_at) used to chart a failure mode over time
+const (
+	CodeIndex             = "code-index"
+	CodeIndexPartitionKey = attributeCode
+	CodeIndexSortKey      = attributeLastFailed
+)
+
+// Stage names the part of the pipeline a record failed in
+type Stage string
+
+const (
+	StageCollection    Stage = "collection"
+	StageIngestion     Stage = "ingestion"
+	StageVectorization Stage = "vectorization"
+	StageExtraction    Stage = "pdf_extraction"
+)
+
+// Code is a normalized failure mode, shared by every stage
+type Code string
+
+const (
+	// Collection
+	CodeSourceAPI      Code = "SOURCE_API_ERROR"
+	CodeQuotaExhausted Code = "QUOTA_EXHAUSTED"
+	CodeConfig         Code = "CONFIG_ERROR"
+	CodePaused         Code = "PAUSED"
+
+	// Storage, read and write, in any stage
+	CodeS3Read     Code = "S3_READ_FAILED"
+	CodeS3Write    Code = "S3_WRITE_FAILED"
+	CodeStoreWrite Code = "STORE_WRITE_FAILED"
+
+	// Ingestion
+	CodeUnsupportedSchema Code = "UNSUPPORTED_SCHEMA"
+	CodeParse             Code = "PARSE_FAILED"
+	CodeIntegrity         Code = "INTEGRITY_MISMATCH"
+	CodeUpsert            Code = "UPSERT_FAILED"
+
+	// Vectorization
+	CodeEmbeddingRateLimited Code = "EMBEDDING_RATE_LIMITED"
+	CodeEmbeddingTimeout     Code = "EMBEDDING_TIMEOUT"
+	CodeEmbeddingInvalid     Code = "EMBEDDING_INVALID_INPUT"
+	CodeEmbeddingServer      Code = "EMBEDDING_SERVER_ERROR"
+	CodeFullText             Code = "FULL_TEXT_FAILED"
+
+	// PDF extraction
+	CodeInvalidInput Code = "INVALID_INPUT"
+	CodeNoText       Code = "NO_TEXT_EXTRACTED"
+
+	CodeUnknown Code = "UNKNOWN"
+)
+
+// Codes lists every failure code, for readers that query the code index code by code
+var Codes = []Code{
+	CodeSourceAPI, CodeQuotaExhausted, CodeConfig, CodePaused,
+	CodeS3Read, CodeS3Write, CodeStoreWrite,
+	CodeUnsupportedSchema, CodeParse, CodeIntegrity, CodeUpsert,
+	CodeEmbeddingRateLimited, CodeEmbeddingTimeout, CodeEmbeddingInvalid, CodeEmbeddingServer, CodeFullText,
+	CodeInvalidInput, CodeNoText,
+	CodeUnknown,
+}
+
+// CodeForAppError maps an AppError type to its failure code
+func CodeForAppError(errorType logger.ErrorType) Code {
+	switch errorType {
+	case logger.ErrorTypeAPI:
+		return CodeSourceAPI
+	case logger.ErrorTypeQuota:
+		return CodeQuotaExhausted
+	case logger.ErrorTypeConfig:
+		return CodeConfig
+	case logger.ErrorTypePaused:
+		return CodePaused
+	case logger.ErrorTypeS3:
+		return CodeS3Write
+	case logger.ErrorTypeData:
+		return CodeParse
+	}
+	return CodeUnknown
+}
+
+// Failure is one failed record
+type Failure struct {
+	Stage    Stage
+	RecordID string // the S3 object, paper or run that failed
+	TraceID  string
+	Code     Code
+	Message  string
+}
+
+// Table attributes; expires_at is the table's TTL attribute
+const (
+	attributeItemID      = "item_id"
+	attributeTraceID     = "trace_id"
+	attributeStage       = "stage"
+	attributeRecordID    = "record_id"
+	attributeCode        = "code"
+	attributeMessage     = "message"
+	attributeRetryCount  = "retry_count"
+	attributeFirstFailed = "first_failed_at"
+	attributeLastFailed  = "last_failed_at"
+	attributeFailedDay   = "failed_day" // YYYY-MM-DD in UTC, for daily charts
+	attributeExpiresAt   = "expires_at"
+)
+
+const (
+	// defaultRetention is how long an item is kept after its last failure
+	defaultRetention = 90 * 24 * time.Hour
+	// maxMessageLength bounds the stored error message
+	maxMessageLength = 1000
+	// maxConcurrentUpdates bounds the updates in flight, one per failed record
+	maxConcurrentUpdates = 8
+)
+
+// Recorder writes failures to the FailedItems table
+type Recorder struct {
+	client    dynamodbiface.DynamoDBAPI
+	tableName string
+	retention time.Duration
+	now       func() time.Time
+}
+
+// NewRecorder creates a recorder for the given table whose client uses clientConfig's retries and timeouts
+func NewRecorder(tableName string, clientConfig awsclient.ClientConfig) *Recorder {
+	sess := awsclient.MustClientSession(clientConfig)
+	return NewRecorderWithClient(dynamodb.New(sess), tableName)
+}
+
+// NewRecorderWithClient creates a recorder with a custom DynamoDB client (for testing)
+func NewRecorderWithClient(client dynamodbiface.DynamoDBAPI, tableName string) *Recorder {
+	return &Recorder{
+		client:    client,
+		tableName: tableName,
+		retention: defaultRetention,
+		now:       time.Now,
+	}
+}
+
+// SetRetention sets how long an item is kept after its last failure
+func (r *Recorder) SetRetention(retention time.Duration) {
+	if retention > 0 {
+		r.retention = retention
+	}
+}
+
+// ItemID is the table key of a record: its stage and ID, so a record failing again in a
+// later run updates the same item
+func ItemID(stage Stage, recordID string) string {
+	return string(stage) + "#" + recordID
+}
+
+// Record stores the failures. The first failure of a record has a retry count of 0 and each
+// later one increments it; the trace ID, code and message are those of the latest failure.
+// Every failure is attempted; the error reports how many could not be stored.
+func (r *Recorder) Record(ctx context.Context, failures []Failure) error {
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		failed   int
+		firstErr error
+	)
+	slots := make(chan struct{}, maxConcurrentUpdates)
+	for _, failure := range failures {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(failure Failure) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			if err := r.record(ctx, failure); err != nil {
+				mu.Lock()
+				failed++
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+			}
+		}(failure)
+	}
+	wg.Wait()
+
+	if failed > 0 {
+		return fmt.Errorf("failed to record %d of %d failed items: %w", failed, len(failures), firstErr)
+	}
+	return nil
+}
+
+// record upserts one failure
+func (r *Recorder) record(ctx context.Context, failure Failure) error {
+	if failure.RecordID == "" {
+		return fmt.Errorf("failure in stage %s has no record ID", failure.Stage)
+	}
+	code := failure.Code
+	if code == "" {
+		code = CodeUnknown
+	}
+	message := failure.Message
+	if len(message) > maxMessageLength {
+		cut := maxMessageLength
+		for cut > 0 && !utf8.RuneStart(message[cut]) {
+			cut--
+		}
+		message = message[:cut]
+	}
+	now := r.now().UTC()
+
+	_, err := r.client.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(r.tableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			attributeItemID: {S: aws.String(ItemID(failure.Stage, failure.RecordID))},
+		},
+		UpdateExpression: aws.String("SET #trace = :trace, #stage = :stage, #record = :record, #code = :code, #message = :message, " +
+			"#retries = if_not_exists(#retries, :minusOne) + :one, #first = if_not_exists(#first, :now), " +
+			"#last = :now, #day = :day, #expires = :expires"),
+		ExpressionAttributeNames: map[string]*string{
+			"#trace":   aws.String(attributeTraceID),
+			"#stage":   aws.String(attributeStage),
+			"#record":  aws.String(attributeRecordID),
+			"#code":    aws.String(attributeCode),
+			"#message": aws.String(attributeMessage),
+			"#retries": aws.String(attributeRetryCount),
+			"#first":   aws.String(attributeFirstFailed),
+			"#last":    aws.String(attributeLastFailed),
+			"#day":     aws.String(attributeFailedDay),
+			"#expires": aws.String(attributeExpiresAt),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":trace":    stringValue(failure.TraceID),
+			":stage":    {S: aws.String(string(failure.Stage))},
+			":record":   {S: aws.String(failure.RecordID)},
+			":code":     {S: aws.String(string(code))},
+			":message":  stringValue(message),
+			":minusOne": {N: aws.String("-1")},
+			":one":      {N: aws.String("1")},
+			":now":      {S: aws.String(now.Format(time.RFC3339))},
+			":day":      {S: aws.String(now.Format("2006-01-02"))},
+			":expires":  {N: aws.String(strconv.FormatInt(now.Add(r.retention).Unix(), 10))},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record failure of %s: %w", ItemID(failure.Stage, failure.RecordID), err)
+	}
+	return nil
+}
+
+// stringValue is an attribute value for s, NULL when s is empty
+func stringValue(s string) *dynamodb.AttributeValue {
+	if s == "" {
+		return &dynamodb.AttributeValue{NULL: aws.Bool(true)}
+	}
+	return &dynamodb.AttributeValue{S: aws.String(s)}
+}
@@ -0,0 +1,12 @@
+module shared/httpserver
+
+go 1.23
+
+require (
+	shared/logger v0.0.0
+	shared/shutdown v0.0.0
+)
+
+replace shared/logger => ../logger
+
+replace shared/shutdown => ../shutdown
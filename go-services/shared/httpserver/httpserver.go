@@ -0,0 +1,64 @@
+// Package httpserver holds the pieces the services' HTTP modes share: serving until a
+// shutdown signal drains the server, the /healthz handler and JSON responses.
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"shared/logger"
+	"shared/shutdown"
+)
+
+// Serve runs server until watcher reports a shutdown, then lets in-flight requests drain for
+// up to drainTimeout before returning. fields are logged with the listening address.
+func Serve(server *http.Server, watcher *shutdown.Watcher, drainTimeout time.Duration, log *logger.Logger, fields map[string]interface{}) error {
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		<-watcher.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			log.Error("HTTP server shutdown did not complete", err)
+		}
+	}()
+
+	listening := map[string]interface{}{
+		"addr": server.Addr,
+	}
+	for key, value := range fields {
+		listening[key] = value
+	}
+	log.Info("HTTP server listening", listening)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	// ListenAndServe returns as soon as shutdown starts; wait for in-flight requests
+	<-drained
+	return nil
+}
+
+// Healthz reports liveness, answering 503 once the server is draining; GET /healthz
+func Healthz(watcher *shutdown.Watcher, log *logger.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if watcher.Requested() {
+			WriteJSON(w, log, http.StatusServiceUnavailable, map[string]string{"status": "draining"})
+			return
+		}
+		WriteJSON(w, log, http.StatusOK, map[string]string{"status": "ok"})
+	}
+}
+
+// WriteJSON writes a JSON response with the given status code
+func WriteJSON(w http.ResponseWriter, log *logger.Logger, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Error("Failed to write HTTP response", err)
+	}
+}
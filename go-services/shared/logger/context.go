@@ -0,0 +1,45 @@
+package logger
+
+import (
+	"context"
+	"os"
+)
+
+// contextKey keys the values this package stores in a context
+type contextKey int
+
+const (
+	traceIDKey contextKey = iota
+	loggerKey
+)
+
+// ContextWithTraceID returns a context carrying the trace ID. Loggers derived with
+// WithContext or FromContext pick it up, so components called with the context log
+// under the trace without receiving it as a parameter.
+func ContextWithTraceID(ctx context.Context, traceID string) context.Context {
+	return context.WithValue(ctx, traceIDKey, traceID)
+}
+
+// TraceIDFromContext returns the trace ID carried by the context, or ""
+func TraceIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	traceID, _ := ctx.Value(traceIDKey).(string)
+	return traceID
+}
+
+// ContextWithLogger returns a context carrying the logger for FromContext
+func ContextWithLogger(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, l)
+}
+
+// FromContext returns the context's logger, bound to the context's request and trace IDs.
+// Without a stored logger it returns one named after the Lambda function.
+func FromContext(ctx context.Context) *Logger {
+	l, _ := ctx.Value(loggerKey).(*Logger)
+	if l == nil {
+		l = New(os.Getenv("AWS_LAMBDA_FUNCTION_NAME"))
+	}
+	return l.WithContext(ctx)
+}
@@ -13,6 +13,8 @@ const (
 	ErrorTypeConfig    ErrorType = "CONFIG_ERROR"
 	ErrorTypeData      ErrorType = "DATA_ERROR"
 	ErrorTypeInternal  ErrorType = "INTERNAL_ERROR"
+	ErrorTypeQuota     ErrorType = "QUOTA_ERROR"
+	ErrorTypePaused    ErrorType = "PAUSED"
 )
 
 // AppError represents an application-specific error with context
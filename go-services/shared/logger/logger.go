@@ -54,7 +54,7 @@ func New(serviceName string) *Logger {
 	}
 }
 
-// WithContext adds context information to the logger
+// WithContext adds context information to the logger, including a trace ID set with ContextWithTraceID
 func (l *Logger) WithContext(ctx context.Context) *Logger {
 	newLogger := &Logger{
 		serviceName: l.serviceName,
@@ -66,6 +66,9 @@ func (l *Logger) WithContext(ctx context.Context) *Logger {
 	if requestID := getRequestIDFromContext(ctx); requestID != "" {
 		newLogger.requestID = requestID
 	}
+	if traceID := TraceIDFromContext(ctx); traceID != "" {
+		newLogger.traceID = traceID
+	}
 	
 	return newLogger
 }
@@ -156,4 +159,8 @@ func getRequestIDFromContext(ctx context.Context) string {
 	
 	// For now, return empty string as we don't have AWS Lambda context in tests
 	return ""
-}
\ No newline at end of file
+}
+// Flush syncs log output to stdout; call it before a long-running process exits
+func Flush() {
+	os.Stdout.Sync()
+}
@@ -0,0 +1,159 @@
+// Package pauseflags lets operators halt collection, ingestion writes or vectorization
+// independently during incidents. A stage is paused when its flag is set in the pipeline
+// config or when its SSM Parameter Store parameter (<prefix>/<stage>) is "true"; the
+// parameters can be flipped without redeploying config or disabling triggers.
+package pauseflags
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+
+	"shared/awsclient"
+)
+
+// Stage names one independently pausable part of the pipeline; it is also the
+// parameter name under the configured prefix
+type Stage string
+
+const (
+	Collection      Stage = "collection"
+	IngestionWrites Stage = "ingestion_writes"
+	Vectorization   Stage = "vectorization"
+)
+
+// ErrPaused is returned by Check while a stage is paused
+var ErrPaused = errors.New("paused by operator flag")
+
+// defaultRefresh bounds how often the SSM parameters are re-read
+const defaultRefresh = 30 * time.Second
+
+// Config holds the static pause flags and where to read the SSM overrides
+type Config struct {
+	Collection      bool `yaml:"collection" json:"collection,omitempty"`
+	IngestionWrites bool `yaml:"ingestion_writes" json:"ingestion_writes,omitempty"`
+	Vectorization   bool `yaml:"vectorization" json:"vectorization,omitempty"`
+	// ParameterPrefix is the SSM path holding one parameter per stage; empty disables SSM
+	ParameterPrefix string `yaml:"parameter_prefix" json:"parameter_prefix,omitempty"`
+	// RefreshSeconds is how long SSM values are cached; 0 uses 30 seconds
+	RefreshSeconds int `yaml:"refresh_seconds" json:"refresh_seconds,omitempty"`
+}
+
+// configured reports the static flag of stage
+func (c Config) configured(stage Stage) bool {
+	switch stage {
+	case Collection:
+		return c.Collection
+	case IngestionWrites:
+		return c.IngestionWrites
+	case Vectorization:
+		return c.Vectorization
+	}
+	return false
+}
+
+// Checker answers whether a stage is paused. SSM values are cached for the refresh
+// interval, so checking between batches costs at most one call per interval.
+type Checker struct {
+	config  Config
+	client  ssmiface.SSMAPI
+	refresh time.Duration
+
+	mu        sync.Mutex
+	values    map[Stage]bool
+	fetchedAt time.Time
+}
+
+// NewChecker creates a checker, with an SSM client when a parameter prefix is configured
+func NewChecker(cfg Config) (*Checker, error) {
+	if cfg.ParameterPrefix == "" {
+		return NewCheckerWithClient(cfg, nil), nil
+	}
+	sess, err := awsclient.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"), // Default region
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AWS session: %w", err)
+	}
+	return NewCheckerWithClient(cfg, ssm.New(sess)), nil
+}
+
+// NewCheckerWithClient creates a checker with a custom SSM client (for testing); a nil
+// client only consults the static flags
+func NewCheckerWithClient(cfg Config, client ssmiface.SSMAPI) *Checker {
+	refresh := time.Duration(cfg.RefreshSeconds) * time.Second
+	if refresh <= 0 {
+		refresh = defaultRefresh
+	}
+	return &Checker{config: cfg, client: client, refresh: refresh}
+}
+
+// Paused reports whether stage is paused. When SSM cannot be read the last values read
+// are used and the error is returned once per refresh interval, so callers can log it
+// and carry on.
+func (c *Checker) Paused(ctx context.Context, stage Stage) (bool, error) {
+	if c == nil {
+		return false, nil
+	}
+	if c.config.configured(stage) {
+		return true, nil
+	}
+	if c.client == nil {
+		return false, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.values == nil || time.Since(c.fetchedAt) >= c.refresh {
+		values, err := c.fetch(ctx)
+		c.fetchedAt = time.Now()
+		if err != nil {
+			if c.values == nil {
+				c.values = make(map[Stage]bool)
+			}
+			return c.values[stage], err
+		}
+		c.values = values
+	}
+	return c.values[stage], nil
+}
+
+// Check returns an error wrapping ErrPaused while stage is paused. An SSM read failure is
+// returned without ErrPaused: a flag that cannot be read is logged, not treated as a pause.
+func (c *Checker) Check(ctx context.Context, stage Stage) error {
+	paused, err := c.Paused(ctx, stage)
+	if paused {
+		return fmt.Errorf("%s %w", stage, ErrPaused)
+	}
+	return err
+}
+
+// fetch reads every parameter under the prefix into per-stage values
+func (c *Checker) fetch(ctx context.Context) (map[Stage]bool, error) {
+	prefix := strings.TrimSuffix(c.config.ParameterPrefix, "/")
+	values := make(map[Stage]bool)
+
+	input := &ssm.GetParametersByPathInput{
+		Path: aws.String(prefix),
+	}
+	err := c.client.GetParametersByPathPagesWithContext(ctx, input, func(page *ssm.GetParametersByPathOutput, lastPage bool) bool {
+		for _, parameter := range page.Parameters {
+			name := strings.TrimPrefix(aws.StringValue(parameter.Name), prefix+"/")
+			paused, err := strconv.ParseBool(strings.TrimSpace(aws.StringValue(parameter.Value)))
+			values[Stage(name)] = err == nil && paused
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pause parameters under %s: %w", prefix, err)
+	}
+	return values, nil
+}
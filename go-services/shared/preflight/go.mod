@@ -0,0 +1,12 @@
+module shared/preflight
+
+go 1.23
+
+require (
+	github.com/aws/aws-sdk-go v1.55.5
+	shared/logger v0.0.0
+)
+
+require github.com/jmespath/go-jmespath v0.4.0 // indirect
+
+replace shared/logger => ../logger
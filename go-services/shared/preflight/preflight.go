@@ -0,0 +1,277 @@
+// Package preflight verifies on cold start that the resources a service depends on exist
+// and are reachable (tables and their indexes, buckets, HTTP endpoints), so a missing or
+// misnamed resource fails the first invocation with one CONFIG_ERROR listing every problem
+// instead of failing part way through a run.
+package preflight
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+
+	"shared/logger"
+)
+
+// DisableEnv turns the checks off when set to "off", e.g. for local runs against stubs
+const DisableEnv = "PREFLIGHT_CHECKS"
+
+// defaultTimeout bounds the whole set of checks
+const defaultTimeout = 10 * time.Second
+
+// Check verifies one resource; Resource names it in the error when Verify fails
+type Check struct {
+	Resource string
+	Verify   func(ctx context.Context) error
+}
+
+// Index is a global secondary index a service queries. Empty key attributes are not checked.
+type Index struct {
+	Name         string
+	PartitionKey string
+	SortKey      string
+}
+
+// Table checks that a DynamoDB table is active and has the given global secondary indexes
+func Table(client dynamodbiface.DynamoDBAPI, table string, indexes ...string) Check {
+	specs := make([]Index, len(indexes))
+	for i, index := range indexes {
+		specs[i] = Index{Name: index}
+	}
+	return TableWithIndexes(client, table, specs...)
+}
+
+// TableWithIndexes checks that a DynamoDB table is active and has the given global secondary
+// indexes, keyed on the expected attributes. When a configured index is missing, the error
+// names any other index with the expected key schema, so a misnamed index points at the
+// right one.
+func TableWithIndexes(client dynamodbiface.DynamoDBAPI, table string, indexes ...Index) Check {
+	resource := "dynamodb table " + table
+	if len(indexes) > 0 {
+		names := make([]string, len(indexes))
+		for i, index := range indexes {
+			names[i] = index.Name
+		}
+		resource += " with index " + strings.Join(names, ", ")
+	}
+	return Check{
+		Resource: resource,
+		Verify: func(ctx context.Context) error {
+			output, err := client.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{
+				TableName: aws.String(table),
+			})
+			if err != nil {
+				return err
+			}
+			if status := aws.StringValue(output.Table.TableStatus); status != dynamodb.TableStatusActive && status != dynamodb.TableStatusUpdating {
+				return fmt.Errorf("table status is %s", status)
+			}
+			var problems []string
+			for _, index := range indexes {
+				if err := verifyIndex(output.Table, index); err != nil {
+					problems = append(problems, err.Error())
+				}
+			}
+			if len(problems) > 0 {
+				return errors.New(strings.Join(problems, ", "))
+			}
+			return nil
+		},
+	}
+}
+
+// verifyIndex checks that the table has an active or building GSI named index.Name with the
+// expected key attributes
+func verifyIndex(table *dynamodb.TableDescription, index Index) error {
+	for _, gsi := range table.GlobalSecondaryIndexes {
+		if aws.StringValue(gsi.IndexName) != index.Name {
+			continue
+		}
+		if aws.StringValue(gsi.IndexStatus) == dynamodb.IndexStatusDeleting {
+			return fmt.Errorf("global secondary index %s is being deleted", index.Name)
+		}
+		if !index.keysMatch(gsi.KeySchema) {
+			return fmt.Errorf("global secondary index %s is keyed on %s, expected %s",
+				index.Name, describeKeys(gsi.KeySchema), index.describeKeys())
+		}
+		return nil
+	}
+
+	err := fmt.Errorf("missing global secondary index %s", index.Name)
+	if index.PartitionKey == "" {
+		return err
+	}
+	for _, gsi := range table.GlobalSecondaryIndexes {
+		if index.keysMatch(gsi.KeySchema) && aws.StringValue(gsi.IndexStatus) != dynamodb.IndexStatusDeleting {
+			return fmt.Errorf("%w (index %s has the expected key schema %s)", err, aws.StringValue(gsi.IndexName), index.describeKeys())
+		}
+	}
+	return err
+}
+
+// keysMatch reports whether schema has the expected partition and sort keys
+func (index Index) keysMatch(schema []*dynamodb.KeySchemaElement) bool {
+	partition, sort := keyAttributes(schema)
+	if index.PartitionKey != "" && partition != index.PartitionKey {
+		return false
+	}
+	return index.SortKey == "" || sort == index.SortKey
+}
+
+// describeKeys formats the expected key schema
+func (index Index) describeKeys() string {
+	return formatKeys(index.PartitionKey, index.SortKey)
+}
+
+// describeKeys formats a key schema as (partition) or (partition, sort)
+func describeKeys(schema []*dynamodb.KeySchemaElement) string {
+	return formatKeys(keyAttributes(schema))
+}
+
+// keyAttributes returns the partition and sort key attributes of a key schema
+func keyAttributes(schema []*dynamodb.KeySchemaElement) (partition, sort string) {
+	for _, key := range schema {
+		switch aws.StringValue(key.KeyType) {
+		case dynamodb.KeyTypeHash:
+			partition = aws.StringValue(key.AttributeName)
+		case dynamodb.KeyTypeRange:
+			sort = aws.StringValue(key.AttributeName)
+		}
+	}
+	return partition, sort
+}
+
+func formatKeys(partition, sort string) string {
+	if partition == "" {
+		partition = "?"
+	}
+	if sort == "" {
+		return "(" + partition + ")"
+	}
+	return "(" + partition + ", " + sort + ")"
+}
+
+// Bucket checks that an S3 bucket exists and is accessible
+func Bucket(client s3iface.S3API, bucket string) Check {
+	return Check{
+		Resource: "s3 bucket " + bucket,
+		Verify: func(ctx context.Context) error {
+			_, err := client.HeadBucketWithContext(ctx, &s3.HeadBucketInput{
+				Bucket: aws.String(bucket),
+			})
+			return err
+		},
+	}
+}
+
+// Endpoint checks that an HTTP endpoint answers GET without a server error
+func Endpoint(client *http.Client, url string) Check {
+	return Check{
+		Resource: "endpoint " + url,
+		Verify: func(ctx context.Context) error {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+			if err != nil {
+				return err
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				return err
+			}
+			resp.Body.Close()
+			if resp.StatusCode >= http.StatusInternalServerError {
+				return fmt.Errorf("status %d", resp.StatusCode)
+			}
+			return nil
+		},
+	}
+}
+
+// Run verifies every check concurrently and returns a CONFIG_ERROR listing each failed
+// resource, or nil when all pass
+func Run(ctx context.Context, checks []Check) error {
+	_, err := run(ctx, checks)
+	return err
+}
+
+// run verifies the checks and also reports which of them passed
+func run(ctx context.Context, checks []Check) ([]bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	failures := make([]string, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			if err := check.Verify(ctx); err != nil {
+				failures[i] = fmt.Sprintf("%s: %v", check.Resource, err)
+			}
+		}(i, check)
+	}
+	wg.Wait()
+
+	passed := make([]bool, len(checks))
+	var missing []string
+	for i, failure := range failures {
+		if failure == "" {
+			passed[i] = true
+			continue
+		}
+		missing = append(missing, failure)
+	}
+	if len(missing) == 0 {
+		return passed, nil
+	}
+	return passed, logger.NewAppErrorWithMetadata(logger.ErrorTypeConfig,
+		fmt.Sprintf("pre-flight check failed for %d of %d resources: %s", len(missing), len(checks), strings.Join(missing, "; ")),
+		nil, map[string]interface{}{"missing": missing})
+}
+
+// Guard verifies each resource once per process: a resource that passed is not checked
+// again, while a failed one is rechecked on the next call so fixing it does not need a
+// redeploy. Resources named by a changed config are checked when first seen.
+type Guard struct {
+	mu     sync.Mutex
+	passed map[string]bool
+}
+
+// Run verifies the checks whose resources have not passed yet, unless DisableEnv is "off"
+func (g *Guard) Run(ctx context.Context, checks []Check) error {
+	if os.Getenv(DisableEnv) == "off" {
+		return nil
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var pending []Check
+	for _, check := range checks {
+		if !g.passed[check.Resource] {
+			pending = append(pending, check)
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	passed, err := run(ctx, pending)
+	if g.passed == nil {
+		g.passed = make(map[string]bool)
+	}
+	for i, check := range pending {
+		if passed[i] {
+			g.passed[check.Resource] = true
+		}
+	}
+	return err
+}
@@ -0,0 +1,96 @@
+// Package shutdown turns SIGINT/SIGTERM into a drain request for the services' non-Lambda
+// runs (local commands and HTTP servers), and exits with a code reflecting how the run ended.
+package shutdown
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"shared/logger"
+)
+
+// Watcher turns termination signals into a drain request. The first signal closes Done so
+// no new work starts while in-flight work finishes; a second signal exits immediately.
+type Watcher struct {
+	done     chan struct{}
+	mu       sync.Mutex
+	received os.Signal
+	logger   *logger.Logger
+}
+
+// Watch starts listening for termination signals
+func Watch(log *logger.Logger) *Watcher {
+	w := &Watcher{
+		done:   make(chan struct{}),
+		logger: log,
+	}
+
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+	go func() {
+		sig := <-signals
+		w.mu.Lock()
+		w.received = sig
+		w.mu.Unlock()
+		w.logger.Warn("Shutdown requested, draining in-flight work", map[string]interface{}{
+			"signal": sig.String(),
+		})
+		close(w.done)
+
+		sig = <-signals
+		w.logger.Warn("Second shutdown signal received, exiting without draining", map[string]interface{}{
+			"signal": sig.String(),
+		})
+		logger.Flush()
+		os.Exit(signalExitCode(sig))
+	}()
+
+	return w
+}
+
+// Done is closed once shutdown has been requested
+func (w *Watcher) Done() <-chan struct{} {
+	return w.done
+}
+
+// Requested reports whether a termination signal has been received
+func (w *Watcher) Requested() bool {
+	select {
+	case <-w.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Exit flushes logs and exits: 1 on error, 128+signal if work was cut short by a signal, 0 otherwise
+func (w *Watcher) Exit(err error, interrupted bool) {
+	code := 0
+	switch {
+	case err != nil:
+		w.logger.Error("Run failed", err)
+		code = 1
+	case interrupted:
+		w.mu.Lock()
+		code = signalExitCode(w.received)
+		w.mu.Unlock()
+	}
+
+	w.logger.Info("Shutting down", map[string]interface{}{
+		"exit_code":   code,
+		"interrupted": interrupted,
+	})
+	logger.Flush()
+	os.Exit(code)
+}
+
+// signalExitCode follows the shell convention of 128 plus the signal number
+func signalExitCode(sig os.Signal) int {
+	if s, ok := sig.(syscall.Signal); ok {
+		return 128 + int(s)
+	}
+	return 1
+}
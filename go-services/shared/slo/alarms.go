@@ -0,0 +1,56 @@
+package slo
+
+import (
+	"fmt"
+	"time"
+)
+
+// BurnRateAlarm is one multiwindow burn-rate alarm. It fires when both windows burn the error
+// budget faster than BurnRate: the long window makes the alarm significant and the short one
+// lets it reset soon after the burn stops.
+type BurnRateAlarm struct {
+	Name        string
+	LongWindow  time.Duration
+	ShortWindow time.Duration
+	BurnRate    float64
+	Severity    string // "page" or "ticket"
+}
+
+// DefaultBurnRateAlarms are the usual alarms for a 30-day SLO period: 2% of the budget spent
+// in an hour or 5% in six hours pages, 10% in three days opens a ticket
+var DefaultBurnRateAlarms = []BurnRateAlarm{
+	{Name: "fast-burn", LongWindow: time.Hour, ShortWindow: 5 * time.Minute, BurnRate: 14.4, Severity: "page"},
+	{Name: "slow-burn", LongWindow: 6 * time.Hour, ShortWindow: 30 * time.Minute, BurnRate: 6, Severity: "page"},
+	{Name: "budget-leak", LongWindow: 72 * time.Hour, ShortWindow: 6 * time.Hour, BurnRate: 1, Severity: "ticket"},
+}
+
+// ErrorRateThreshold is the error rate over a window at which the alarm fires for target
+func (a BurnRateAlarm) ErrorRateThreshold(target float64) float64 {
+	return a.BurnRate * (1 - target)
+}
+
+// Firing reports whether both windows, each the sum of its measurements, burn faster than
+// the alarm's rate
+func (a BurnRateAlarm) Firing(target float64, long, short Measurement) bool {
+	return long.Total > 0 && short.Total > 0 &&
+		BurnRate(long.ErrorRate(), target) >= a.BurnRate &&
+		BurnRate(short.ErrorRate(), target) >= a.BurnRate
+}
+
+// AlarmName names the alarm of an objective, e.g. "vectorization_latency-fast-burn"
+func (a BurnRateAlarm) AlarmName(objective Objective) string {
+	return fmt.Sprintf("%s-%s", objective, a.Name)
+}
+
+// Sum adds up the measurements of one objective over a window
+func Sum(measurements ...Measurement) Measurement {
+	var total Measurement
+	for _, m := range measurements {
+		if total.Objective == "" {
+			total.Objective, total.Target = m.Objective, m.Target
+		}
+		total.Good += m.Good
+		total.Total += m.Total
+	}
+	return total
+}
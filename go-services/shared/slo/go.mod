@@ -0,0 +1,7 @@
+module shared/slo
+
+go 1.23
+
+require shared/logger v0.0.0
+
+replace shared/logger => ../logger
@@ -0,0 +1,153 @@
+// Package slo measures the pipeline against its service-level objectives. Every run reports
+// its good and total events per objective as a metric log line; CloudWatch metric filters sum
+// them into SLI ratios, and burn-rate alarms fire on how fast a window spends the error
+// budget, so alerting follows user-facing objectives instead of raw error counts.
+package slo
+
+import (
+	"fmt"
+	"time"
+
+	"shared/logger"
+)
+
+// Objective names one service-level objective; it is the metric_name of its measurements
+type Objective string
+
+const (
+	// VectorizationLatency counts traces vectorized within the threshold
+	VectorizationLatency Objective = "vectorization_latency"
+	// IngestionSuccess counts papers ingested without error
+	IngestionSuccess Objective = "ingestion_success"
+	// EndToEndLatency counts papers made searchable within the threshold of their collection
+	EndToEndLatency Objective = "end_to_end_latency"
+)
+
+// ObjectiveConfig is the target of one objective
+type ObjectiveConfig struct {
+	// Target is the share of good events, e.g. 0.99 for 99% of traces or a 1% error rate
+	Target float64 `yaml:"target" json:"target"`
+	// ThresholdSeconds is the slowest good run of a latency objective
+	ThresholdSeconds int `yaml:"threshold_seconds,omitempty" json:"threshold_seconds,omitempty"`
+}
+
+// Config holds the pipeline's objectives
+type Config struct {
+	Enabled              bool            `yaml:"enabled" json:"enabled"`
+	VectorizationLatency ObjectiveConfig `yaml:"vectorization_latency" json:"vectorization_latency"`
+	IngestionSuccess     ObjectiveConfig `yaml:"ingestion_success" json:"ingestion_success"`
+	EndToEndLatency      ObjectiveConfig `yaml:"end_to_end_latency" json:"end_to_end_latency"`
+}
+
+// DefaultConfig returns the default objectives: 99% of traces vectorized within 15 minutes,
+// an ingestion error rate under 1% and 95% of papers searchable within an hour of collection
+func DefaultConfig() Config {
+	return Config{
+		Enabled:              true,
+		VectorizationLatency: ObjectiveConfig{Target: 0.99, ThresholdSeconds: 900},
+		IngestionSuccess:     ObjectiveConfig{Target: 0.99},
+		EndToEndLatency:      ObjectiveConfig{Target: 0.95, ThresholdSeconds: 3600},
+	}
+}
+
+// Validate checks that every target is a share below 1, leaving an error budget to burn
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	for objective, cfg := range map[Objective]ObjectiveConfig{
+		VectorizationLatency: c.VectorizationLatency,
+		IngestionSuccess:     c.IngestionSuccess,
+		EndToEndLatency:      c.EndToEndLatency,
+	} {
+		if cfg.Target <= 0 || cfg.Target >= 1 {
+			return fmt.Errorf("slo.%s.target must be in (0, 1), got %v", objective, cfg.Target)
+		}
+	}
+	for objective, cfg := range map[Objective]ObjectiveConfig{
+		VectorizationLatency: c.VectorizationLatency,
+		EndToEndLatency:      c.EndToEndLatency,
+	} {
+		if cfg.ThresholdSeconds < 1 {
+			return fmt.Errorf("slo.%s.threshold_seconds must be positive, got %d", objective, cfg.ThresholdSeconds)
+		}
+	}
+	return nil
+}
+
+// Measurement is one run's events of an objective
+type Measurement struct {
+	Objective Objective
+	Target    float64
+	Good      int
+	Total     int
+}
+
+// Latency measures one run of a latency objective: good when it succeeded within the threshold
+func Latency(objective Objective, cfg ObjectiveConfig, elapsed time.Duration, succeeded bool) Measurement {
+	good := 0
+	if succeeded && elapsed <= time.Duration(cfg.ThresholdSeconds)*time.Second {
+		good = 1
+	}
+	return Measurement{Objective: objective, Target: cfg.Target, Good: good, Total: 1}
+}
+
+// Within measures a run of a latency objective counted per event: good are the latencies
+// within the threshold
+func Within(objective Objective, cfg ObjectiveConfig, latencies []time.Duration) Measurement {
+	threshold := time.Duration(cfg.ThresholdSeconds) * time.Second
+	good := 0
+	for _, latency := range latencies {
+		if latency <= threshold {
+			good++
+		}
+	}
+	return Measurement{Objective: objective, Target: cfg.Target, Good: good, Total: len(latencies)}
+}
+
+// Ratio measures a run of good out of total events
+func Ratio(objective Objective, cfg ObjectiveConfig, good, total int) Measurement {
+	return Measurement{Objective: objective, Target: cfg.Target, Good: good, Total: total}
+}
+
+// ErrorRate is the share of bad events, 0 when there were none
+func (m Measurement) ErrorRate() float64 {
+	if m.Total == 0 {
+		return 0
+	}
+	return float64(m.Total-m.Good) / float64(m.Total)
+}
+
+// BurnRate is how fast the measurement spends the error budget: 1 spends exactly the
+// budget over the SLO period, 14.4 spends a 30-day budget in about two days
+func (m Measurement) BurnRate() float64 {
+	return BurnRate(m.ErrorRate(), m.Target)
+}
+
+// BurnRate is the error rate relative to the error budget 1 - target
+func BurnRate(errorRate, target float64) float64 {
+	if target >= 1 {
+		return 0
+	}
+	return errorRate / (1 - target)
+}
+
+// Emit logs each measurement with events as a metric line (metric_type "slo"), for metric
+// filters to sum good and total per objective. Measurements without events are skipped.
+func Emit(log *logger.Logger, measurements ...Measurement) {
+	for _, m := range measurements {
+		if m.Total == 0 {
+			continue
+		}
+		log.Info("SLO measurement", map[string]interface{}{
+			"metric_type": "slo",
+			"metric_name": string(m.Objective),
+			"good":        m.Good,
+			"total":       m.Total,
+			"bad":         m.Total - m.Good,
+			"target":      m.Target,
+			"error_rate":  m.ErrorRate(),
+			"burn_rate":   m.BurnRate(),
+		})
+	}
+}
@@ -0,0 +1,112 @@
+// Package vectorarchive is the cold tier of the vectors table. Vectors that are old and not
+// accessed recently are moved out of DynamoDB into gzipped JSON-lines shards in S3, one vector
+// item per line; the paper keeps the keys of the shards holding its vectors so readers can
+// fall back to them at the cost of an S3 read.
+package vectorarchive
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// Papers table attributes of the archive
+const (
+	// ShardKeysAttribute is the string set of shard keys holding the paper's archived vectors
+	ShardKeysAttribute = "vector_archive_keys"
+	// AccessedAtAttribute is when the paper's vectors were last read, RFC 3339; papers read
+	// within the archive job's idle window keep their vectors in DynamoDB
+	AccessedAtAttribute = "vectors_accessed_at"
+)
+
+// DefaultPrefix is the S3 prefix of the archive unless configured otherwise
+const DefaultPrefix = "vector-archive"
+
+// ShardsPrefix is the prefix under which every shard of the archive is written
+func ShardsPrefix(prefix string) string {
+	return strings.TrimSuffix(prefix, "/") + "/shards/"
+}
+
+// ShardKey names shard n of an archive run
+func ShardKey(prefix, runID string, n int) string {
+	return fmt.Sprintf("%s%s-%05d.jsonl.gz", ShardsPrefix(prefix), runID, n)
+}
+
+// ShardWriter encodes vector items into a shard
+type ShardWriter struct {
+	gzip    *gzip.Writer
+	encoder *json.Encoder
+	count   int
+}
+
+// NewShardWriter writes a shard to w; Close must be called to flush it
+func NewShardWriter(w io.Writer) *ShardWriter {
+	gzipWriter := gzip.NewWriter(w)
+	return &ShardWriter{gzip: gzipWriter, encoder: json.NewEncoder(gzipWriter)}
+}
+
+// Write appends one vectors table item
+func (w *ShardWriter) Write(item map[string]*dynamodb.AttributeValue) error {
+	var value map[string]interface{}
+	if err := dynamodbattribute.UnmarshalMap(item, &value); err != nil {
+		return fmt.Errorf("failed to convert vector item: %w", err)
+	}
+	if err := w.encoder.Encode(value); err != nil {
+		return fmt.Errorf("failed to encode vector item: %w", err)
+	}
+	w.count++
+	return nil
+}
+
+// Count is the number of items written
+func (w *ShardWriter) Count() int {
+	return w.count
+}
+
+// Close flushes the shard
+func (w *ShardWriter) Close() error {
+	return w.gzip.Close()
+}
+
+// ReadShard hands every item of a shard to fn as a vectors table item, so readers unmarshal
+// archived vectors with the same dynamodbav structs as stored ones. fn stops the read by
+// returning an error, which is returned as is.
+func ReadShard(r io.Reader, fn func(item map[string]*dynamodb.AttributeValue) error) error {
+	gzipReader, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("failed to open shard: %w", err)
+	}
+	defer gzipReader.Close()
+
+	scanner := bufio.NewScanner(gzipReader)
+	// Lines hold whole vector items, embedding and source text included
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var value map[string]interface{}
+		if err := json.Unmarshal(line, &value); err != nil {
+			return fmt.Errorf("invalid shard line %d: %w", lineNumber, err)
+		}
+		item, err := dynamodbattribute.MarshalMap(value)
+		if err != nil {
+			return fmt.Errorf("invalid shard line %d: %w", lineNumber, err)
+		}
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read shard: %w", err)
+	}
+	return nil
+}
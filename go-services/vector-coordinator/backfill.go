@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// BackfillChecker is implemented by retrievers that can compare the trace-id index against
+// the papers the batch processor recorded for a trace
+type BackfillChecker interface {
+	ExpectedPapers(ctx context.Context, traceID string) (int, bool, error)
+	CountByTraceID(ctx context.Context, traceID string) (int, error)
+}
+
+// waitForIndexBackfill polls the trace-id index until it holds as many records of the trace
+// as the batch processor recorded writing, so a trace vectorized right after ingestion is not read
+// from a half-propagated GSI. It never fails the run: an index still behind when the wait
+// runs out only sets IndexLagged, and the run continues with the papers the index returns.
+func (vc *VectorCoordinator) waitForIndexBackfill(ctx context.Context, traceID string, result *ProcessingResult) {
+	if !vc.backfillCheck.Enabled {
+		return
+	}
+	checker, ok := vc.retriever.(BackfillChecker)
+	if !ok {
+		return
+	}
+	contextLogger := vc.logger.WithContext(ctx)
+
+	expected, found, err := checker.ExpectedPapers(ctx, traceID)
+	if err != nil {
+		contextLogger.Warn("Failed to read the recorded paper count, skipping the index backfill check", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+	if !found || expected == 0 {
+		return
+	}
+	result.ExpectedPapers = expected
+
+	start := time.Now()
+	defer func() {
+		result.StageTimings[TimingBackfillWaitMs] = time.Since(start).Milliseconds()
+	}()
+	deadline := start.Add(time.Duration(vc.backfillCheck.MaxWaitSeconds) * time.Second)
+	interval := time.Duration(vc.backfillCheck.PollIntervalSeconds) * time.Second
+
+	counts := 0
+	for !vc.shutdownRequested() {
+		counts++
+		indexed, err := checker.CountByTraceID(ctx, traceID)
+		if err == nil && indexed >= expected {
+			contextLogger.InfoWithDuration("Trace-id index caught up with ingestion", time.Since(start), map[string]interface{}{
+				"expected_papers": expected,
+				"indexed_papers":  indexed,
+				"index_counts":    counts,
+			})
+			return
+		}
+		if time.Now().Add(interval).After(deadline) {
+			result.IndexLagged = true
+			fields := map[string]interface{}{
+				"expected_papers": expected,
+				"index_counts":    counts,
+			}
+			if err != nil {
+				fields["error"] = err.Error()
+			} else {
+				fields["indexed_papers"] = indexed
+			}
+			contextLogger.Warn("Trace-id index still behind after the backfill wait, continuing", fields)
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// BatchEmbeddingRequest is the batch-mode request payload of the native embedding API
+type BatchEmbeddingRequest struct {
+	Texts []string `json:"texts"`
+}
+
+// SupportsBatch reports whether the endpoint's provider accepts batch requests. Only the
+// native embedding API does; other providers need their own request formats.
+func (c *VectorAPIClient) SupportsBatch() bool {
+	return c.provider == ProviderNative || c.provider == ProviderAuto
+}
+
+// GenerateEmbeddings embeds several texts in one request. The responses are in the order of
+// texts; the whole batch fails when any of its embeddings is missing or invalid.
+func (c *VectorAPIClient) GenerateEmbeddings(ctx context.Context, texts []string) ([]*EmbeddingResponse, error) {
+	if len(texts) == 0 {
+		return nil, &EmbeddingError{Cause: FailureInvalidInput, Err: fmt.Errorf("batch has no texts")}
+	}
+	if !c.SupportsBatch() {
+		return nil, &EmbeddingError{Cause: FailureInvalidInput, Err: fmt.Errorf("the %s provider does not support batch requests", c.provider)}
+	}
+
+	contextLogger := c.logger.WithContext(ctx)
+	startTime := time.Now()
+
+	truncations := make([]*Truncation, len(texts))
+	request := BatchEmbeddingRequest{Texts: make([]string, len(texts))}
+	for i, text := range texts {
+		if text == "" {
+			return nil, &EmbeddingError{Cause: FailureInvalidInput, Err: fmt.Errorf("text %d of the batch is empty", i)}
+		}
+		request.Texts[i], truncations[i] = TruncateText(text, c.maxTextLength)
+	}
+
+	responseBody, statusCode, err := c.post(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	duration := time.Since(startTime)
+
+	responses, err := NormalizeBatchResponse(responseBody, len(texts), c.defaultModel)
+	if err == nil && c.dimension > 0 && responses[0].Dimension != c.dimension {
+		err = fmt.Errorf("embedding dimension %d does not match the configured %d", responses[0].Dimension, c.dimension)
+	}
+	if err != nil {
+		contextLogger.Error("Invalid batch embedding response", err, map[string]interface{}{
+			"batch_size":    len(texts),
+			"response_size": len(responseBody),
+		})
+		return nil, &EmbeddingError{Cause: FailureServerError, StatusCode: statusCode, Err: fmt.Errorf("invalid batch embedding response: %w", err)}
+	}
+	for i, response := range responses {
+		response.Provenance.Endpoint = c.baseURL
+		response.Truncation = truncations[i]
+	}
+
+	contextLogger.InfoWithDuration("Successfully generated batch embeddings", duration, map[string]interface{}{
+		"batch_size":          len(texts),
+		"embedding_dimension": responses[0].Dimension,
+		"model_version":       responses[0].ModelVersion,
+	})
+	return responses, nil
+}
+
+// NormalizeBatchResponse reads a native batch response, {"embeddings": [[...], ...]} with the
+// model, dimension and processing time of the whole batch, into one canonical response per
+// text. Every embedding is decoded and validated like a single response.
+func NormalizeBatchResponse(body []byte, count int, defaultModel string) ([]*EmbeddingResponse, error) {
+	var object map[string]interface{}
+	if err := json.Unmarshal(body, &object); err != nil {
+		return nil, fmt.Errorf("failed to parse batch embedding response: %w", err)
+	}
+	rows, _ := object["embeddings"].([]interface{})
+	if len(rows) != count {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", count, len(rows))
+	}
+
+	modelVersion := stringField(object, modelFields)
+	modelSource := "response"
+	if modelVersion == "" && defaultModel != "" {
+		modelVersion, modelSource = defaultModel, "default"
+	}
+	dimension := intField(object, dimensionFields)
+	processingTimeMs := intField(object, processingTimeFields)
+
+	responses := make([]*EmbeddingResponse, count)
+	for i, row := range rows {
+		embedding, dtype, err := decodeEmbedding(row)
+		if err != nil {
+			return nil, fmt.Errorf("embedding %d: %w", i, err)
+		}
+		response := &EmbeddingResponse{
+			Embedding:        embedding,
+			ModelVersion:     modelVersion,
+			Dimension:        dimension,
+			ProcessingTimeMs: processingTimeMs,
+			Provenance:       &Provenance{Provider: ProviderNative, SourceDType: dtype, ModelSource: modelSource},
+		}
+		if response.Dimension == 0 {
+			response.Dimension = len(embedding)
+		}
+		if err := validateEmbeddingResponse(response); err != nil {
+			return nil, fmt.Errorf("embedding %d: %w", i, err)
+		}
+		responses[i] = response
+	}
+	return responses, nil
+}
@@ -0,0 +1,67 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+)
+
+// Embedding failure causes, so retries and alerts can tell capacity problems from data problems
+const (
+	FailureRateLimited  = "rate_limited"
+	FailureTimeout      = "timeout"
+	FailureInvalidInput = "invalid_input"
+	FailureServerError  = "server_error"
+)
+
+// EmbeddingError is a failed embedding request classified by cause
+type EmbeddingError struct {
+	Cause      string // one of the Failure* causes
+	StatusCode int    // 0 when no response was received
+	Err        error
+}
+
+func (e *EmbeddingError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *EmbeddingError) Unwrap() error {
+	return e.Err
+}
+
+// ClassifyFailure returns the cause of an embedding failure. Errors that did not come from
+// the API client are classified by timeout, and otherwise count as server errors.
+func ClassifyFailure(err error) string {
+	var embeddingErr *EmbeddingError
+	if errors.As(err, &embeddingErr) {
+		return embeddingErr.Cause
+	}
+	if isTimeout(err) {
+		return FailureTimeout
+	}
+	return FailureServerError
+}
+
+// isTimeout reports whether the request ran out of time rather than failing outright
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// causeForStatus classifies a non-200 response from the embedding API
+func causeForStatus(statusCode int) string {
+	switch {
+	case statusCode == http.StatusTooManyRequests:
+		return FailureRateLimited
+	case statusCode == http.StatusRequestTimeout || statusCode == http.StatusGatewayTimeout:
+		return FailureTimeout
+	case statusCode >= 400 && statusCode < 500:
+		return FailureInvalidInput
+	default:
+		return FailureServerError
+	}
+}
@@ -0,0 +1,225 @@
+package client
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Embedding providers whose responses NormalizeResponse understands
+const (
+	ProviderAuto        = "auto"        // detected from the response shape
+	ProviderNative      = "native"      // this repository's embedding-api
+	ProviderOpenAI      = "openai"      // {"data": [{"embedding": [...]}], "model": ...}
+	ProviderCohere      = "cohere"      // {"embeddings": [[...]]} or {"embeddings": {"float": [[...]]}}
+	ProviderHuggingFace = "huggingface" // a bare [...] or [[...]], as text-embeddings-inference returns
+)
+
+// Providers lists the accepted provider names
+var Providers = []string{ProviderAuto, ProviderNative, ProviderOpenAI, ProviderCohere, ProviderHuggingFace}
+
+// Source encodings of an embedding, recorded in its provenance
+const (
+	DTypeFloat         = "float"          // JSON numbers
+	DTypeString        = "string"         // JSON strings holding numbers
+	DTypeBase64Float32 = "base64_float32" // little-endian float32 bytes, base64 encoded
+)
+
+// Provenance records where an embedding came from and how it was converted
+type Provenance struct {
+	Provider    string `json:"provider"`     // provider whose format the response was read as
+	SourceDType string `json:"source_dtype"` // one of the DType* encodings
+	Endpoint    string `json:"endpoint,omitempty"`
+	ModelSource string `json:"model_source,omitempty"` // "response" or "default" when the provider omitted the model
+}
+
+// Field names accepted in native-style responses, in order of preference
+var (
+	embeddingFields      = []string{"embedding", "vector", "values"}
+	modelFields          = []string{"model_version", "model", "model_name", "model_id"}
+	dimensionFields      = []string{"dimension", "dimensions", "dim"}
+	processingTimeFields = []string{"processing_time_ms", "took_ms", "latency_ms"}
+)
+
+// ValidProvider reports whether name is one of the accepted providers
+func ValidProvider(name string) bool {
+	for _, provider := range Providers {
+		if name == provider {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeResponse converts a provider's embedding response into the canonical
+// EmbeddingResponse. The dimension is derived from the vector when the provider omits it,
+// and defaultModel stands in for a missing model. The result is validated once here, so
+// callers never see provider-specific shapes, dtypes or half-filled responses.
+func NormalizeResponse(provider string, body []byte, defaultModel string) (*EmbeddingResponse, error) {
+	var payload interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("failed to parse embedding response: %w", err)
+	}
+	if provider == "" || provider == ProviderAuto {
+		provider = detectProvider(payload)
+	}
+
+	response := &EmbeddingResponse{}
+	var rawEmbedding interface{}
+	object, _ := payload.(map[string]interface{})
+	switch provider {
+	case ProviderNative:
+		if object == nil {
+			return nil, fmt.Errorf("expected a JSON object from the %s provider", provider)
+		}
+		rawEmbedding = firstField(object, embeddingFields)
+		response.ModelVersion = stringField(object, modelFields)
+		response.Dimension = intField(object, dimensionFields)
+		response.ProcessingTimeMs = intField(object, processingTimeFields)
+	case ProviderOpenAI:
+		data, _ := object["data"].([]interface{})
+		if len(data) == 0 {
+			return nil, fmt.Errorf("%s response has no data", provider)
+		}
+		first, _ := data[0].(map[string]interface{})
+		rawEmbedding = first["embedding"]
+		response.ModelVersion = stringField(object, modelFields)
+	case ProviderCohere:
+		embeddings := object["embeddings"]
+		if byType, ok := embeddings.(map[string]interface{}); ok {
+			embeddings = byType["float"]
+		}
+		rawEmbedding = firstRow(embeddings)
+		response.ModelVersion = stringField(object, modelFields)
+	case ProviderHuggingFace:
+		rawEmbedding = firstRow(payload)
+	default:
+		return nil, fmt.Errorf("unknown embedding provider %q", provider)
+	}
+
+	embedding, dtype, err := decodeEmbedding(rawEmbedding)
+	if err != nil {
+		return nil, fmt.Errorf("%s response: %w", provider, err)
+	}
+	response.Embedding = embedding
+	if response.Dimension == 0 {
+		response.Dimension = len(embedding)
+	}
+	response.Provenance = &Provenance{Provider: provider, SourceDType: dtype, ModelSource: "response"}
+	if response.ModelVersion == "" && defaultModel != "" {
+		response.ModelVersion = defaultModel
+		response.Provenance.ModelSource = "default"
+	}
+
+	if err := validateEmbeddingResponse(response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// detectProvider infers the provider from the response shape
+func detectProvider(payload interface{}) string {
+	object, ok := payload.(map[string]interface{})
+	if !ok {
+		return ProviderHuggingFace
+	}
+	if _, ok := object["data"].([]interface{}); ok {
+		return ProviderOpenAI
+	}
+	if _, ok := object["embeddings"]; ok {
+		return ProviderCohere
+	}
+	return ProviderNative
+}
+
+// firstRow returns the first vector of a batch ([[...]]), or the value itself when it is a single vector
+func firstRow(value interface{}) interface{} {
+	rows, ok := value.([]interface{})
+	if !ok || len(rows) == 0 {
+		return value
+	}
+	if _, nested := rows[0].([]interface{}); nested {
+		return rows[0]
+	}
+	return value
+}
+
+// decodeEmbedding reads a vector given as JSON numbers, numeric strings or base64 float32 bytes
+func decodeEmbedding(value interface{}) ([]float64, string, error) {
+	switch v := value.(type) {
+	case []interface{}:
+		embedding := make([]float64, len(v))
+		dtype := DTypeFloat
+		for i, element := range v {
+			switch e := element.(type) {
+			case float64:
+				embedding[i] = e
+			case string:
+				parsed, err := strconv.ParseFloat(e, 64)
+				if err != nil {
+					return nil, "", fmt.Errorf("embedding element %d is not a number: %q", i, e)
+				}
+				embedding[i] = parsed
+				dtype = DTypeString
+			default:
+				return nil, "", fmt.Errorf("embedding element %d has unsupported type %T", i, element)
+			}
+		}
+		return embedding, dtype, nil
+	case string:
+		data, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			return nil, "", fmt.Errorf("embedding is neither an array nor base64: %w", err)
+		}
+		if len(data)%4 != 0 {
+			return nil, "", fmt.Errorf("base64 embedding has %d bytes, not a multiple of 4", len(data))
+		}
+		embedding := make([]float64, len(data)/4)
+		for i := range embedding {
+			embedding[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:])))
+		}
+		return embedding, DTypeBase64Float32, nil
+	case nil:
+		return nil, "", fmt.Errorf("embedding is missing")
+	default:
+		return nil, "", fmt.Errorf("embedding has unsupported type %T", value)
+	}
+}
+
+// firstField returns the value of the first of names present in object
+func firstField(object map[string]interface{}, names []string) interface{} {
+	for _, name := range names {
+		if value, ok := object[name]; ok {
+			return value
+		}
+	}
+	return nil
+}
+
+// stringField returns the first of names holding a non-empty string
+func stringField(object map[string]interface{}, names []string) string {
+	for _, name := range names {
+		if value, ok := object[name].(string); ok && value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// intField returns the first of names holding a number, or a numeric string
+func intField(object map[string]interface{}, names []string) int {
+	for _, name := range names {
+		switch value := object[name].(type) {
+		case float64:
+			return int(value)
+		case string:
+			if parsed, err := strconv.Atoi(value); err == nil {
+				return parsed
+			}
+		}
+	}
+	return 0
+}
@@ -0,0 +1,100 @@
+package client
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxTextLength is the default longest text, in bytes, sent to the embedding API
+const MaxTextLength = 10000
+
+// minKeptFraction is the least of the limit a sentence or word cut may keep; when the only
+// boundary is earlier than that, cutting there would discard more than the limit requires
+const minKeptFraction = 0.5
+
+// Truncation boundaries, from most to least preferred
+const (
+	BoundarySentence = "sentence"
+	BoundaryWord     = "word"
+	BoundaryRune     = "rune"
+)
+
+// Truncation records how an over-long text was cut before embedding
+type Truncation struct {
+	OriginalLength int     `json:"original_length"`
+	EmbeddedLength int     `json:"embedded_length"`
+	Boundary       string  `json:"boundary"`
+	Ratio          float64 `json:"ratio"` // embedded / original length
+}
+
+// TruncateText cuts text to at most maxLength bytes, at the last sentence end within the
+// limit, else the last word break, else the last whole rune, so the embedded text never ends
+// mid-word or with a broken UTF-8 sequence. The returned truncation is nil when text fits.
+func TruncateText(text string, maxLength int) (string, *Truncation) {
+	if len(text) <= maxLength {
+		return text, nil
+	}
+
+	// Back off to a rune boundary so the limit never splits a multi-byte character
+	limit := maxLength
+	for limit > 0 && !utf8.RuneStart(text[limit]) {
+		limit--
+	}
+	minKept := int(float64(maxLength) * minKeptFraction)
+
+	cut, boundary := limit, BoundaryRune
+	if end := lastSentenceEnd(text, limit); end >= minKept {
+		cut, boundary = end, BoundarySentence
+	} else if end := lastWordBreak(text, limit); end >= minKept {
+		cut, boundary = end, BoundaryWord
+	}
+
+	truncated := strings.TrimRightFunc(text[:cut], unicode.IsSpace)
+	return truncated, &Truncation{
+		OriginalLength: len(text),
+		EmbeddedLength: len(truncated),
+		Boundary:       boundary,
+		Ratio:          float64(len(truncated)) / float64(len(text)),
+	}
+}
+
+// lastSentenceEnd returns the end of the last sentence within text[:limit], or -1. A sentence
+// ends at ".", "!" or "?" (plus closing quotes or brackets) followed by whitespace, or at a
+// full-width terminator, which needs no space after it.
+func lastSentenceEnd(text string, limit int) int {
+	last := -1
+	for i := 0; i < limit; {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		i += size
+		switch r {
+		case '。', '！', '？':
+			if i <= limit {
+				last = i
+			}
+		case '.', '!', '?':
+			end := i
+			for end < limit {
+				closer, closerSize := utf8.DecodeRuneInString(text[end:])
+				if !strings.ContainsRune(sentenceClosers, closer) {
+					break
+				}
+				end += closerSize
+			}
+			if end < limit {
+				if next, _ := utf8.DecodeRuneInString(text[end:]); unicode.IsSpace(next) {
+					last = end
+				}
+			}
+		}
+	}
+	return last
+}
+
+// sentenceClosers may follow a sentence terminator, as in `He said "no." Then`
+const sentenceClosers = "\"')]\u201d\u2019"
+
+// lastWordBreak returns the start of the last whitespace within text[:limit], or -1
+func lastWordBreak(text string, limit int) int {
+	return strings.LastIndexFunc(text[:limit], unicode.IsSpace)
+}
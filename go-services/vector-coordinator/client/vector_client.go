@@ -6,9 +6,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
+	"strings"
 	"time"
 
+	"shared/awsclient"
 	"shared/logger"
 )
 
@@ -19,9 +22,14 @@ type HTTPClient interface {
 
 // VectorAPIClient handles HTTP communication with the Python vectorization API
 type VectorAPIClient struct {
-	baseURL    string
-	httpClient HTTPClient
-	logger     *logger.Logger
+	baseURL      string
+	healthURL    string
+	provider     string // response format, see the Provider* names
+	defaultModel string // model version for providers that omit it from responses
+	dimension    int    // expected embedding dimension; 0 accepts any
+	maxTextLength int   // longest text sent, in bytes
+	httpClient   HTTPClient
+	logger       *logger.Logger
 }
 
 // EmbeddingRequest represents the request payload for the vectorization API
@@ -29,12 +37,15 @@ type EmbeddingRequest struct {
 	Text string `json:"text"`
 }
 
-// EmbeddingResponse represents the response from the vectorization API
+// EmbeddingResponse is the canonical embedding response every provider's response is
+// normalized into (see NormalizeResponse)
 type EmbeddingResponse struct {
 	Embedding       []float64 `json:"embedding"`
 	ModelVersion    string    `json:"model_version"`
 	Dimension       int       `json:"dimension"`
 	ProcessingTimeMs int      `json:"processing_time_ms"`
+	Provenance      *Provenance `json:"provenance,omitempty"` // set by normalization
+	Truncation      *Truncation `json:"truncation,omitempty"` // set when the input was cut to the max text length
 }
 
 // APIError represents an error response from the vectorization API
@@ -46,11 +57,25 @@ type APIError struct {
 	} `json:"error"`
 }
 
+// HealthStatus is the embedding API's health response
+type HealthStatus struct {
+	Status    string                 `json:"status"` // "healthy" once the model is loaded, "initializing" before
+	ModelInfo map[string]interface{} `json:"model_info"`
+}
+
+// Healthy reports whether the model is loaded and ready to embed
+func (h *HealthStatus) Healthy() bool {
+	return h.Status == "healthy"
+}
+
 // NewVectorAPIClient creates a new HTTP client for the vectorization API
 func NewVectorAPIClient(baseURL string) *VectorAPIClient {
 	return &VectorAPIClient{
-		baseURL: baseURL,
-		httpClient: &http.Client{
+		baseURL:   baseURL,
+		healthURL: defaultHealthURL(baseURL),
+		provider:  ProviderAuto,
+		maxTextLength: MaxTextLength,
+		httpClient: awsclient.HTTPClient(&http.Client{
 			Timeout: 30 * time.Second,
 			Transport: &http.Transport{
 				MaxIdleConns:        10,
@@ -58,7 +83,7 @@ func NewVectorAPIClient(baseURL string) *VectorAPIClient {
 				DisableCompression:  false,
 				MaxIdleConnsPerHost: 5,
 			},
-		},
+		}),
 		logger: logger.New("vector-api-client"),
 	}
 }
@@ -67,15 +92,47 @@ func NewVectorAPIClient(baseURL string) *VectorAPIClient {
 func NewVectorAPIClientWithHTTPClient(baseURL string, httpClient HTTPClient) *VectorAPIClient {
 	return &VectorAPIClient{
 		baseURL:    baseURL,
+		healthURL:  defaultHealthURL(baseURL),
+		provider:   ProviderAuto,
+		maxTextLength: MaxTextLength,
 		httpClient: httpClient,
 		logger:     logger.New("vector-api-client"),
 	}
 }
 
+// SetProvider sets the provider whose response format the endpoint returns, and the model
+// version recorded when its responses carry none; ProviderAuto detects the format per response
+func (c *VectorAPIClient) SetProvider(provider, defaultModel string) error {
+	if provider == "" {
+		provider = ProviderAuto
+	}
+	if !ValidProvider(provider) {
+		return fmt.Errorf("unknown embedding provider %q (expected one of %v)", provider, Providers)
+	}
+	c.provider = provider
+	c.defaultModel = defaultModel
+	return nil
+}
+
+// SetExpectedDimension rejects embeddings whose dimension differs, as invalid responses, so a
+// model swapped behind the endpoint never mixes dimensions in the vectors table; 0 accepts any
+func (c *VectorAPIClient) SetExpectedDimension(dimension int) {
+	c.dimension = dimension
+}
+
+// SetMaxTextLength sets the longest text, in bytes, sent to the API (MaxTextLength by default)
+func (c *VectorAPIClient) SetMaxTextLength(maxLength int) error {
+	if maxLength < 1 {
+		return fmt.Errorf("max text length must be positive, got %d", maxLength)
+	}
+	c.maxTextLength = maxLength
+	return nil
+}
+
 // GenerateEmbedding calls the Python API to generate an embedding for the given text
 func (c *VectorAPIClient) GenerateEmbedding(ctx context.Context, text string) (*EmbeddingResponse, error) {
 	if text == "" {
-		return nil, fmt.Errorf("text cannot be empty")
+		return nil, &EmbeddingError{Cause: FailureInvalidInput, Err: fmt.Errorf("text cannot be empty")}
 	}
 
 	contextLogger := c.logger.WithContext(ctx)
@@ -86,32 +143,72 @@ func (c *VectorAPIClient) GenerateEmbedding(ctx context.Context, text string) (*
 		"api_url":     c.baseURL,
 	})
 
-	// Validate text length (prevent extremely long texts)
-	const maxTextLength = 10000 // Adjust based on model limits
-	if len(text) > maxTextLength {
-		contextLogger.Warn("Text length exceeds maximum", map[string]interface{}{
-			"text_length": len(text),
-			"max_length":  maxTextLength,
+	// Cut over-long texts at a sentence boundary rather than mid-word or mid-rune
+	text, truncation := TruncateText(text, c.maxTextLength)
+	if truncation != nil {
+		contextLogger.Warn("Text length exceeds maximum, truncated", map[string]interface{}{
+			"text_length":      truncation.OriginalLength,
+			"embedded_length":  truncation.EmbeddedLength,
+			"max_length":       c.maxTextLength,
+			"boundary":         truncation.Boundary,
+			"truncation_ratio": truncation.Ratio,
 		})
-		text = text[:maxTextLength] // Truncate text
 	}
 
-	// Prepare request payload
-	request := EmbeddingRequest{
-		Text: text,
+	responseBody, statusCode, err := c.post(ctx, EmbeddingRequest{Text: text})
+	if err != nil {
+		return nil, err
 	}
+	duration := time.Since(startTime)
+
+	// Normalize the provider's response into the canonical shape and validate it
+	embeddingResponse, err := NormalizeResponse(c.provider, responseBody, c.defaultModel)
+	if err != nil {
+		contextLogger.Error("Invalid embedding response", err, map[string]interface{}{
+			"provider":      c.provider,
+			"response_size": len(responseBody),
+		})
+		return nil, &EmbeddingError{Cause: FailureServerError, StatusCode: statusCode, Err: fmt.Errorf("invalid embedding response: %w", err)}
+	}
+	if c.dimension > 0 && embeddingResponse.Dimension != c.dimension {
+		err := fmt.Errorf("embedding dimension %d does not match the configured %d", embeddingResponse.Dimension, c.dimension)
+		contextLogger.Error("Invalid embedding response", err, map[string]interface{}{
+			"provider":      c.provider,
+			"model_version": embeddingResponse.ModelVersion,
+		})
+		return nil, &EmbeddingError{Cause: FailureServerError, StatusCode: statusCode, Err: fmt.Errorf("invalid embedding response: %w", err)}
+	}
+	embeddingResponse.Provenance.Endpoint = c.baseURL
+	embeddingResponse.Truncation = truncation
+
+	contextLogger.InfoWithDuration("Successfully generated embedding", duration, map[string]interface{}{
+		"embedding_dimension":    embeddingResponse.Dimension,
+		"model_version":          embeddingResponse.ModelVersion,
+		"api_processing_time_ms": embeddingResponse.ProcessingTimeMs,
+		"provider":               embeddingResponse.Provenance.Provider,
+		"source_dtype":           embeddingResponse.Provenance.SourceDType,
+	})
+
+	return embeddingResponse, nil
+}
+
+// post sends one request to the embedding endpoint and returns the body of a 200 response.
+// Failed requests and other statuses return an EmbeddingError classified by cause.
+func (c *VectorAPIClient) post(ctx context.Context, request interface{}) ([]byte, int, error) {
+	contextLogger := c.logger.WithContext(ctx)
+	startTime := time.Now()
 
 	requestBody, err := json.Marshal(request)
 	if err != nil {
 		contextLogger.Error("Failed to marshal request", err)
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+		return nil, 0, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
 	// Create HTTP request
 	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL, bytes.NewBuffer(requestBody))
 	if err != nil {
 		contextLogger.Error("Failed to create HTTP request", err)
-		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+		return nil, 0, fmt.Errorf("failed to create HTTP request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -123,7 +220,11 @@ func (c *VectorAPIClient) GenerateEmbedding(ctx context.Context, text string) (*
 		contextLogger.Error("HTTP request failed", err, map[string]interface{}{
 			"url": c.baseURL,
 		})
-		return nil, fmt.Errorf("HTTP request failed: %w", err)
+		cause := FailureServerError
+		if isTimeout(err) {
+			cause = FailureTimeout
+		}
+		return nil, 0, &EmbeddingError{Cause: cause, Err: fmt.Errorf("HTTP request failed: %w", err)}
 	}
 	defer resp.Body.Close()
 
@@ -131,15 +232,17 @@ func (c *VectorAPIClient) GenerateEmbedding(ctx context.Context, text string) (*
 	responseBody, err := io.ReadAll(resp.Body)
 	if err != nil {
 		contextLogger.Error("Failed to read response body", err)
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		cause := FailureServerError
+		if isTimeout(err) {
+			cause = FailureTimeout
+		}
+		return nil, resp.StatusCode, &EmbeddingError{Cause: cause, StatusCode: resp.StatusCode, Err: fmt.Errorf("failed to read response body: %w", err)}
 	}
 
-	duration := time.Since(startTime)
-
 	// Log request metrics
 	contextLogger.Debug("HTTP request completed", map[string]interface{}{
 		"status_code":         resp.StatusCode,
-		"request_duration_ms": duration.Milliseconds(),
+		"request_duration_ms": time.Since(startTime).Milliseconds(),
 		"response_size":       len(responseBody),
 	})
 
@@ -151,7 +254,11 @@ func (c *VectorAPIClient) GenerateEmbedding(ctx context.Context, text string) (*
 				"status_code":   resp.StatusCode,
 				"response_body": string(responseBody),
 			})
-			return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(responseBody))
+			return nil, resp.StatusCode, &EmbeddingError{
+				Cause:      causeForStatus(resp.StatusCode),
+				StatusCode: resp.StatusCode,
+				Err:        fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(responseBody)),
+			}
 		}
 
 		contextLogger.Error("API returned error", nil, map[string]interface{}{
@@ -159,35 +266,62 @@ func (c *VectorAPIClient) GenerateEmbedding(ctx context.Context, text string) (*
 			"error_code":    apiError.Error.Code,
 			"error_message": apiError.Error.Message,
 		})
-		return nil, fmt.Errorf("API error (%s): %s", apiError.Error.Code, apiError.Error.Message)
+		return nil, resp.StatusCode, &EmbeddingError{
+			Cause:      causeForStatus(resp.StatusCode),
+			StatusCode: resp.StatusCode,
+			Err:        fmt.Errorf("API error (%s): %s", apiError.Error.Code, apiError.Error.Message),
+		}
 	}
 
-	// Parse successful response
-	var embeddingResponse EmbeddingResponse
-	if err := json.Unmarshal(responseBody, &embeddingResponse); err != nil {
-		contextLogger.Error("Failed to parse embedding response", err, map[string]interface{}{
-			"response_body": string(responseBody),
-		})
-		return nil, fmt.Errorf("failed to parse embedding response: %w", err)
+
+	return responseBody, resp.StatusCode, nil
+}
+
+// defaultHealthURL derives the health endpoint from the embed endpoint: ".../embed" becomes ".../health"
+func defaultHealthURL(baseURL string) string {
+	base := strings.TrimSuffix(strings.TrimRight(baseURL, "/"), "/embed")
+	return base + "/health"
+}
+
+// SetHealthURL overrides the health endpoint derived from the embed endpoint
+func (c *VectorAPIClient) SetHealthURL(url string) {
+	if url != "" {
+		c.healthURL = url
 	}
+}
 
-	// Validate response
-	if err := c.validateEmbeddingResponse(&embeddingResponse); err != nil {
-		contextLogger.Error("Invalid embedding response", err)
-		return nil, fmt.Errorf("invalid embedding response: %w", err)
+// CheckHealth calls the health endpoint. An "initializing" status is not an error;
+// callers poll until Healthy.
+func (c *VectorAPIClient) CheckHealth(ctx context.Context) (*HealthStatus, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.healthURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create health request: %w", err)
 	}
+	req.Header.Set("Accept", "application/json")
 
-	contextLogger.InfoWithDuration("Successfully generated embedding", duration, map[string]interface{}{
-		"embedding_dimension":    embeddingResponse.Dimension,
-		"model_version":          embeddingResponse.ModelVersion,
-		"api_processing_time_ms": embeddingResponse.ProcessingTimeMs,
-	})
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("health request failed: %w", err)
+	}
+	defer resp.Body.Close()
 
-	return &embeddingResponse, nil
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read health response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("health check failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var status HealthStatus
+	if err := json.Unmarshal(body, &status); err != nil {
+		return nil, fmt.Errorf("failed to parse health response: %w", err)
+	}
+	return &status, nil
 }
 
-// validateEmbeddingResponse validates the structure and content of the embedding response
-func (c *VectorAPIClient) validateEmbeddingResponse(response *EmbeddingResponse) error {
+// validateEmbeddingResponse validates the structure and content of a normalized embedding response
+func validateEmbeddingResponse(response *EmbeddingResponse) error {
 	if response == nil {
 		return fmt.Errorf("response is nil")
 	}
@@ -209,8 +343,10 @@ func (c *VectorAPIClient) validateEmbeddingResponse(response *EmbeddingResponse)
 		if val != val { // Check for NaN
 			return fmt.Errorf("embedding contains NaN at index %d", i)
 		}
+		if math.IsInf(val, 0) {
+			return fmt.Errorf("embedding contains Inf at index %d", i)
+		}
 	}
 
 	return nil
 }
-
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"vector-coordinator/client"
+	"vector-coordinator/config"
+	"vector-coordinator/fulltext"
+	"vector-coordinator/retriever"
+	"vector-coordinator/storage"
+)
+
+// componentSettings holds everything the coordinator components are built from.
+// Its hash keys the component cache, so a changed config rebuilds the clients.
+type componentSettings struct {
+	DynamoDB           config.DynamoDBConfig  `json:"dynamodb"`
+	EmbeddingAPIURL    string                 `json:"embedding_api_url"`
+	EmbeddingHealthURL string                 `json:"embedding_health_url,omitempty"`
+	EmbeddingProvider  string                 `json:"embedding_provider,omitempty"`
+	EmbeddingModel     string                 `json:"embedding_model,omitempty"`
+	EmbeddingDimension int                    `json:"embedding_dimension,omitempty"`
+	MaxTextLength      int                    `json:"max_text_length,omitempty"`
+	WriteBatchSize     string                 `json:"write_batch_size,omitempty"`
+	MaxQueryPages      string                 `json:"max_query_pages,omitempty"`
+	FullTextBucket     string                 `json:"full_text_bucket,omitempty"`
+	RunsTable          string                 `json:"runs_table,omitempty"`
+	Retrieval          config.RetrievalConfig `json:"retrieval"`
+}
+
+// hash returns a stable digest of the settings
+func (s componentSettings) hash() string {
+	data, _ := json.Marshal(s)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// coordinatorComponents are the AWS and HTTP clients shared across warm invocations.
+// They hold no per-invocation state, so concurrent server requests can share them.
+type coordinatorComponents struct {
+	retriever     DataRetrieverInterface
+	apiClient     VectorAPIClientInterface
+	vectorStorage VectorStorageInterface
+	textStore     FullTextStoreInterface
+	spool         SpoolInterface // nil unless vector_write_spool is enabled
+	initDuration  time.Duration
+}
+
+// componentCacheEntry initializes its components at most once for one config hash
+type componentCacheEntry struct {
+	hash       string
+	once       sync.Once
+	components *coordinatorComponents
+	err        error
+}
+
+var (
+	componentCacheMu sync.Mutex
+	componentCache   *componentCacheEntry
+)
+
+// getComponents returns the cached components for settings, building them on the first
+// call or after the settings change. cached is false when this call paid the init cost.
+func getComponents(settings componentSettings) (components *coordinatorComponents, cached bool, err error) {
+	hash := settings.hash()
+
+	componentCacheMu.Lock()
+	entry := componentCache
+	if entry == nil || entry.hash != hash {
+		entry = &componentCacheEntry{hash: hash}
+		componentCache = entry
+	}
+	componentCacheMu.Unlock()
+
+	cached = true
+	entry.once.Do(func() {
+		cached = false
+		entry.components, entry.err = buildComponents(settings)
+	})
+
+	if entry.err != nil {
+		// Drop the failed entry so the next invocation retries initialization
+		componentCacheMu.Lock()
+		if componentCache == entry {
+			componentCache = nil
+		}
+		componentCacheMu.Unlock()
+		return nil, cached, entry.err
+	}
+	return entry.components, cached, nil
+}
+
+// buildComponents constructs the retriever, embedding client and vector storage
+func buildComponents(settings componentSettings) (*coordinatorComponents, error) {
+	start := time.Now()
+	dynamoConfig := settings.DynamoDB
+
+	vectorStorage := storage.NewVectorStorage(dynamoConfig.VectorsTable, dynamoConfig.Client)
+	if err := vectorStorage.SetKeySchema(dynamoConfig.VectorKeys.PartitionKey, dynamoConfig.VectorKeys.SortKey); err != nil {
+		return nil, &ProcessingError{
+			Stage:   "configuration",
+			Message: "invalid vector key schema",
+			Cause:   err,
+		}
+	}
+	if settings.WriteBatchSize != "" {
+		size, err := strconv.Atoi(settings.WriteBatchSize)
+		if err == nil {
+			err = vectorStorage.SetBatchSize(size)
+		}
+		if err != nil {
+			return nil, &ProcessingError{
+				Stage:   "configuration",
+				Message: fmt.Sprintf("invalid WRITE_BATCH_SIZE %q", settings.WriteBatchSize),
+				Cause:   err,
+			}
+		}
+	}
+
+	if capacity := dynamoConfig.VectorWriteCapacity; capacity.Adaptive {
+		if err := vectorStorage.SetAdaptiveCapacity(context.Background(), capacity.ProvisionedWCU, capacity.TargetUtilization); err != nil {
+			return nil, &ProcessingError{
+				Stage:   "configuration",
+				Message: "failed to enable adaptive write capacity",
+				Cause:   err,
+			}
+		}
+	}
+
+	var spool *storage.Spool
+	if spoolConfig := dynamoConfig.VectorWriteSpool; spoolConfig.Enabled {
+		spool = storage.NewSpool(spoolConfig.Bucket, spoolConfig.Prefix)
+		if err := vectorStorage.SetSpool(spool, spoolConfig.FailureThreshold); err != nil {
+			return nil, &ProcessingError{
+				Stage:   "configuration",
+				Message: "invalid vector write spool",
+				Cause:   err,
+			}
+		}
+	}
+
+	dataRetriever := retriever.NewDataRetriever(dynamoConfig.PapersTable, dynamoConfig.TraceIDIndex, dynamoConfig.Client)
+	if settings.MaxQueryPages != "" {
+		maxPages, err := strconv.Atoi(settings.MaxQueryPages)
+		if err == nil {
+			err = dataRetriever.SetMaxPages(maxPages)
+		}
+		if err != nil {
+			return nil, &ProcessingError{
+				Stage:   "configuration",
+				Message: fmt.Sprintf("invalid MAX_QUERY_PAGES %q", settings.MaxQueryPages),
+				Cause:   err,
+			}
+		}
+	}
+	dataRetriever.SetOrdering(settings.Retrieval.SortOrder == config.SortAscending)
+	dataRetriever.SetRecencyWindow(time.Duration(settings.Retrieval.WindowHours) * time.Hour)
+	dataRetriever.SetRunsTable(settings.RunsTable)
+
+	apiClient := client.NewVectorAPIClient(settings.EmbeddingAPIURL)
+	apiClient.SetHealthURL(settings.EmbeddingHealthURL)
+	if err := apiClient.SetProvider(settings.EmbeddingProvider, settings.EmbeddingModel); err != nil {
+		return nil, &ProcessingError{
+			Stage:   "configuration",
+			Message: fmt.Sprintf("invalid EMBEDDING_API_PROVIDER %q", settings.EmbeddingProvider),
+			Cause:   err,
+		}
+	}
+	apiClient.SetExpectedDimension(settings.EmbeddingDimension)
+	if settings.MaxTextLength > 0 {
+		if err := apiClient.SetMaxTextLength(settings.MaxTextLength); err != nil {
+			return nil, &ProcessingError{
+				Stage:   "configuration",
+				Message: "invalid vectorization.max_text_length",
+				Cause:   err,
+			}
+		}
+	}
+
+	components := &coordinatorComponents{
+		retriever:     dataRetriever,
+		apiClient:     apiClient,
+		vectorStorage: vectorStorage,
+		textStore:     fulltext.NewStore(settings.FullTextBucket),
+		initDuration:  time.Since(start),
+	}
+	// A nil *Spool in the interface would not compare equal to nil
+	if spool != nil {
+		components.spool = spool
+	}
+	return components, nil
+}
+
+// loadComponentSettings resolves the component settings from config and environment
+func loadComponentSettings(cfg *config.Config) componentSettings {
+	dynamoConfig := &cfg.AWS.DynamoDB
+	return componentSettings{
+		DynamoDB:           *dynamoConfig,
+		EmbeddingAPIURL:    getEnvOrDefault("EMBEDDING_API_URL", "https://embedding-api.example.com/embed"),
+		EmbeddingHealthURL: os.Getenv("EMBEDDING_API_HEALTH_URL"),                                   // defaults to the embed URL's /health
+		EmbeddingProvider:  os.Getenv("EMBEDDING_API_PROVIDER"),                                     // response format; detected when unset
+		EmbeddingModel:     getEnvOrDefault("EMBEDDING_MODEL_VERSION", cfg.Vectorization.ModelName), // for providers that omit the model
+		EmbeddingDimension: cfg.Vectorization.VectorDimension,
+		MaxTextLength:      cfg.Vectorization.MaxTextLength,
+		WriteBatchSize:     getEnvOrDefault("WRITE_BATCH_SIZE", strconv.Itoa(cfg.Vectorization.BatchSize)),
+		MaxQueryPages:      os.Getenv("MAX_QUERY_PAGES"),
+		FullTextBucket:     getEnvOrDefault("FULL_TEXT_BUCKET", cfg.Vectorization.FullText.Bucket),
+		RunsTable:          os.Getenv("PROCESSING_RUNS_TABLE_NAME"), // read by the backfill check
+		Retrieval:          cfg.Vectorization.Retrieval,
+	}
+}
@@ -0,0 +1,548 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"shared/awsclient"
+	"shared/pauseflags"
+	"shared/slo"
+	"vector-coordinator/preprocess"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"gopkg.in/yaml.v3"
+)
+
+// Config represents the parts of the pipeline configuration used by the vector coordinator
+type Config struct {
+	AWS           AWSConfig           `yaml:"aws"`
+	Vectorization VectorizationConfig `yaml:"vectorization"`
+	Pause         pauseflags.Config   `yaml:"pause"`
+	SLO           slo.Config          `yaml:"slo"`
+}
+
+// VectorizationConfig represents the vectorization settings used by the coordinator
+type VectorizationConfig struct {
+	// ModelName is recorded as the model version of responses that carry none
+	// (EMBEDDING_MODEL_VERSION overrides it)
+	ModelName string `yaml:"model_name"`
+	// VectorDimension rejects embeddings of any other dimension; 0 accepts any
+	VectorDimension int `yaml:"vector_dimension"`
+	// BatchSize is the number of vectors per batch write (WRITE_BATCH_SIZE overrides it)
+	BatchSize int `yaml:"batch_size"`
+	// MaxTextLength is the longest text, in bytes, sent to the embedding API; longer texts
+	// are cut at a sentence boundary
+	MaxTextLength int `yaml:"max_text_length"`
+	// Preprocessing lists the steps applied, in order, to texts before they are embedded
+	// (preprocess.Steps); the applied steps are recorded on every vector
+	Preprocessing []string `yaml:"preprocessing"`
+
+	WeightedEmbedding WeightedEmbeddingConfig `yaml:"weighted_embedding"`
+	WarmUp            WarmUpConfig            `yaml:"warm_up"`
+	EmbeddingBatch    EmbeddingBatchConfig    `yaml:"embedding_batch"`
+	FullText          FullTextConfig          `yaml:"full_text"`
+	Retrieval         RetrievalConfig         `yaml:"retrieval"`
+	Quotas            QuotaConfig             `yaml:"quotas"`
+}
+
+// maxWriteBatchSize is the DynamoDB limit on items per batch write
+const maxWriteBatchSize = 25
+
+// Validate checks the embedding and batch settings
+func (v VectorizationConfig) Validate() error {
+	if v.VectorDimension < 0 {
+		return fmt.Errorf("vectorization.vector_dimension must not be negative, got %d", v.VectorDimension)
+	}
+	if v.BatchSize < 1 || v.BatchSize > maxWriteBatchSize {
+		return fmt.Errorf("vectorization.batch_size must be between 1 and %d, got %d", maxWriteBatchSize, v.BatchSize)
+	}
+	if v.MaxTextLength < 1 {
+		return fmt.Errorf("vectorization.max_text_length must be positive, got %d", v.MaxTextLength)
+	}
+	if _, err := preprocess.New(v.Preprocessing); err != nil {
+		return fmt.Errorf("vectorization.preprocessing: %w", err)
+	}
+	return nil
+}
+
+// QuotaConfig bounds the embedding spend of one run, so a misconfigured query that matches
+// far more papers than intended stops instead of embedding all of them. 0 disables a limit.
+type QuotaConfig struct {
+	MaxPapersPerTrace       int `yaml:"max_papers_per_trace"`        // papers a trace may retrieve
+	MaxEmbeddingCharsPerRun int `yaml:"max_embedding_chars_per_run"` // characters sent to the embedding API
+}
+
+// Validate checks the quota bounds
+func (q QuotaConfig) Validate() error {
+	if q.MaxPapersPerTrace < 0 {
+		return fmt.Errorf("vectorization.quotas.max_papers_per_trace must not be negative, got %d", q.MaxPapersPerTrace)
+	}
+	if q.MaxEmbeddingCharsPerRun < 0 {
+		return fmt.Errorf("vectorization.quotas.max_embedding_chars_per_run must not be negative, got %d", q.MaxEmbeddingCharsPerRun)
+	}
+	return nil
+}
+
+// Retrieval sort orders of the trace-id index query
+const (
+	SortDescending = "descending"
+	SortAscending  = "ascending"
+)
+
+// RetrievalConfig controls which papers of a trace are read and in what order
+type RetrievalConfig struct {
+	// SortOrder orders papers by batch_timestamp; ascending gives chunked runs a stable order
+	SortOrder string `yaml:"sort_order" json:"sort_order"`
+	// WindowHours skips papers whose batch_timestamp is older than this many hours; 0 reads all
+	WindowHours int `yaml:"window_hours" json:"window_hours"`
+	// BackfillCheck waits for the trace-id index to catch up with ingestion before retrieval
+	BackfillCheck BackfillCheckConfig `yaml:"backfill_check" json:"backfill_check"`
+}
+
+// BackfillCheckConfig controls waiting for the trace-id index before retrieval. GSIs are
+// updated asynchronously, so a trace queried right after ingestion may be missing papers;
+// the index count is compared against the written_count the batch processor recorded in
+// the ProcessingRuns table and polled until it catches up or the wait runs out.
+type BackfillCheckConfig struct {
+	Enabled             bool `yaml:"enabled" json:"enabled"`
+	MaxWaitSeconds      int  `yaml:"max_wait_seconds" json:"max_wait_seconds"`           // how long to wait for the index
+	PollIntervalSeconds int  `yaml:"poll_interval_seconds" json:"poll_interval_seconds"` // between index counts
+}
+
+// Validate checks the wait bounds
+func (b BackfillCheckConfig) Validate() error {
+	if !b.Enabled {
+		return nil
+	}
+	if b.MaxWaitSeconds < 1 {
+		return fmt.Errorf("vectorization.retrieval.backfill_check.max_wait_seconds must be positive, got %d", b.MaxWaitSeconds)
+	}
+	if b.PollIntervalSeconds < 1 || b.PollIntervalSeconds > b.MaxWaitSeconds {
+		return fmt.Errorf("vectorization.retrieval.backfill_check.poll_interval_seconds must be between 1 and max_wait_seconds, got %d", b.PollIntervalSeconds)
+	}
+	return nil
+}
+
+// Validate checks the sort order and window
+func (r RetrievalConfig) Validate() error {
+	if r.SortOrder != SortDescending && r.SortOrder != SortAscending {
+		return fmt.Errorf("vectorization.retrieval.sort_order must be %q or %q, got %q", SortDescending, SortAscending, r.SortOrder)
+	}
+	if r.WindowHours < 0 {
+		return fmt.Errorf("vectorization.retrieval.window_hours must not be negative, got %d", r.WindowHours)
+	}
+	return r.BackfillCheck.Validate()
+}
+
+// FullTextConfig controls chunking of extracted full text into full_text vectors. Full-text
+// embedding runs only when a run requests it, for papers that have a full-text S3 key.
+type FullTextConfig struct {
+	Bucket       string `yaml:"bucket"`        // for full-text keys that are not s3:// URIs
+	ChunkWords   int    `yaml:"chunk_words"`   // words per chunk
+	OverlapWords int    `yaml:"overlap_words"` // words repeated from the previous chunk
+	MaxChunks    int    `yaml:"max_chunks"`    // per paper, 0 for no limit
+}
+
+// Validate checks the chunking bounds
+func (f FullTextConfig) Validate() error {
+	if f.ChunkWords < 1 {
+		return fmt.Errorf("vectorization.full_text.chunk_words must be positive, got %d", f.ChunkWords)
+	}
+	if f.OverlapWords < 0 || f.OverlapWords >= f.ChunkWords {
+		return fmt.Errorf("vectorization.full_text.overlap_words must be between 0 and chunk_words-1, got %d", f.OverlapWords)
+	}
+	if f.MaxChunks < 0 {
+		return fmt.Errorf("vectorization.full_text.max_chunks must not be negative, got %d", f.MaxChunks)
+	}
+	return nil
+}
+
+// WarmUpConfig controls waiting for the embedding API to load its model before a large run,
+// so a cold container does not fail the first papers' embeddings
+type WarmUpConfig struct {
+	Enabled        bool `yaml:"enabled"`
+	MinPapers      int  `yaml:"min_papers"`      // only runs with at least this many papers warm up
+	Embedding      bool `yaml:"embedding"`       // also send one throwaway embedding once healthy
+	TimeoutSeconds int  `yaml:"timeout_seconds"` // how long to poll the health endpoint
+}
+
+// Validate checks the warm-up bounds
+func (w WarmUpConfig) Validate() error {
+	if !w.Enabled {
+		return nil
+	}
+	if w.MinPapers < 0 {
+		return fmt.Errorf("vectorization.warm_up.min_papers must not be negative, got %d", w.MinPapers)
+	}
+	if w.TimeoutSeconds < 1 {
+		return fmt.Errorf("vectorization.warm_up.timeout_seconds must be positive, got %d", w.TimeoutSeconds)
+	}
+	return nil
+}
+
+// EmbeddingBatchConfig controls embedding papers through the batch endpoint. The number of
+// texts per request adapts to the API (additive increase, multiplicative decrease): it grows
+// by IncreaseStep after each request answered within TargetLatencyMs, and is cut by
+// DecreaseFactor after a slow, rate-limited, timed-out or failed one.
+type EmbeddingBatchConfig struct {
+	Enabled         bool    `yaml:"enabled"`
+	InitialSize     int     `yaml:"initial_size"`
+	MinSize         int     `yaml:"min_size"`
+	MaxSize         int     `yaml:"max_size"`
+	TargetLatencyMs int     `yaml:"target_latency_ms"`
+	IncreaseStep    int     `yaml:"increase_step"`
+	DecreaseFactor  float64 `yaml:"decrease_factor"`
+}
+
+// maxEmbeddingBatchSize is the embedding API's limit on texts per batch request
+const maxEmbeddingBatchSize = 128
+
+// Validate checks the batch size bounds and the tuning steps
+func (e EmbeddingBatchConfig) Validate() error {
+	if !e.Enabled {
+		return nil
+	}
+	if e.MinSize < 1 || e.MaxSize < e.MinSize || e.MaxSize > maxEmbeddingBatchSize {
+		return fmt.Errorf("vectorization.embedding_batch needs 1 <= min_size <= max_size <= %d, got %d and %d", maxEmbeddingBatchSize, e.MinSize, e.MaxSize)
+	}
+	if e.InitialSize < e.MinSize || e.InitialSize > e.MaxSize {
+		return fmt.Errorf("vectorization.embedding_batch.initial_size must be between %d and %d, got %d", e.MinSize, e.MaxSize, e.InitialSize)
+	}
+	if e.TargetLatencyMs < 1 {
+		return fmt.Errorf("vectorization.embedding_batch.target_latency_ms must be positive, got %d", e.TargetLatencyMs)
+	}
+	if e.IncreaseStep < 1 {
+		return fmt.Errorf("vectorization.embedding_batch.increase_step must be positive, got %d", e.IncreaseStep)
+	}
+	if e.DecreaseFactor <= 0 || e.DecreaseFactor >= 1 {
+		return fmt.Errorf("vectorization.embedding_batch.decrease_factor must be in (0, 1), got %v", e.DecreaseFactor)
+	}
+	return nil
+}
+
+// WeightedEmbeddingConfig controls the additional vector built by embedding each field
+// separately and combining the field embeddings with the configured weights
+type WeightedEmbeddingConfig struct {
+	Enabled bool               `yaml:"enabled"`
+	Weights map[string]float64 `yaml:"weights"` // keyed by field: title, abstract
+}
+
+// WeightedEmbeddingFields are the paper fields that can be embedded separately
+var WeightedEmbeddingFields = []string{"title", "abstract"}
+
+// Validate checks that the weights name known fields and are not all zero
+func (w WeightedEmbeddingConfig) Validate() error {
+	if !w.Enabled {
+		return nil
+	}
+	total := 0.0
+	for field, weight := range w.Weights {
+		if field != "title" && field != "abstract" {
+			return fmt.Errorf("vectorization.weighted_embedding.weights: unknown field %q (expected one of %v)", field, WeightedEmbeddingFields)
+		}
+		if weight < 0 {
+			return fmt.Errorf("vectorization.weighted_embedding.weights.%s must not be negative, got %v", field, weight)
+		}
+		total += weight
+	}
+	if total == 0 {
+		return fmt.Errorf("vectorization.weighted_embedding.weights must include a positive weight")
+	}
+	return nil
+}
+
+// AWSConfig represents AWS service configuration
+type AWSConfig struct {
+	DynamoDB DynamoDBConfig `yaml:"dynamodb"`
+}
+
+// DynamoDBConfig represents DynamoDB configuration
+type DynamoDBConfig struct {
+	PapersTable  string          `yaml:"papers_table"`
+	VectorsTable string          `yaml:"vectors_table"`
+	Region       string          `yaml:"region"`
+	TraceIDIndex string          `yaml:"trace_id_index"` // papers GSI keyed on trace_id
+	VectorKeys   VectorKeyConfig `yaml:"vector_keys"`
+	// VectorWriteCapacity paces vector writes against the vectors table's provisioned capacity
+	VectorWriteCapacity WriteCapacityConfig `yaml:"vector_write_capacity"`
+	// VectorWriteSpool moves the rest of a run's vector writes to S3 when the table keeps failing them
+	VectorWriteSpool WriteSpoolConfig `yaml:"vector_write_spool"`
+	// VectorReadBack reads a sample of each run's stored vectors back before they are marked ready
+	VectorReadBack ReadBackConfig `yaml:"vector_readback"`
+	// Client tunes SDK retries and call timeouts for throttling-prone tables
+	Client awsclient.ClientConfig `yaml:"client"`
+}
+
+// VectorKeyConfig names the key attributes of the vectors table
+type VectorKeyConfig struct {
+	PartitionKey string `yaml:"partition_key"`
+	SortKey      string `yaml:"sort_key"` // empty for tables keyed on the partition key alone
+}
+
+// WriteCapacityConfig controls adaptive pacing of batch writes by their consumed capacity
+type WriteCapacityConfig struct {
+	Adaptive          bool    `yaml:"adaptive"`
+	ProvisionedWCU    int     `yaml:"provisioned_wcu"`    // 0 reads the table's provisioned capacity
+	TargetUtilization float64 `yaml:"target_utilization"` // share of the capacity to stay under
+}
+
+// Validate checks the capacity and utilization bounds
+func (w WriteCapacityConfig) Validate() error {
+	if !w.Adaptive {
+		return nil
+	}
+	if w.ProvisionedWCU < 0 {
+		return fmt.Errorf("aws.dynamodb.vector_write_capacity.provisioned_wcu must not be negative, got %d", w.ProvisionedWCU)
+	}
+	if w.TargetUtilization <= 0 || w.TargetUtilization > 1 {
+		return fmt.Errorf("aws.dynamodb.vector_write_capacity.target_utilization must be in (0, 1], got %v", w.TargetUtilization)
+	}
+	return nil
+}
+
+// WriteSpoolConfig controls spooling vector records to S3 while the vectors table is throttled
+type WriteSpoolConfig struct {
+	Enabled          bool   `yaml:"enabled"`
+	Bucket           string `yaml:"bucket"`
+	Prefix           string `yaml:"prefix"`            // records go under <prefix>/<trace_id>/
+	FailureThreshold int    `yaml:"failure_threshold"` // failed vector writes in a run before the rest is spooled
+}
+
+// Validate checks that an enabled spool has a bucket and a threshold
+func (w WriteSpoolConfig) Validate() error {
+	if !w.Enabled {
+		return nil
+	}
+	if w.Bucket == "" {
+		return fmt.Errorf("aws.dynamodb.vector_write_spool.bucket is required when the spool is enabled")
+	}
+	if w.FailureThreshold < 1 {
+		return fmt.Errorf("aws.dynamodb.vector_write_spool.failure_threshold must be at least 1, got %d", w.FailureThreshold)
+	}
+	return nil
+}
+
+// maxReadBackSample is the most vectors one BatchGetItem reads back
+const maxReadBackSample = 100
+
+// ReadBackConfig controls the verification of stored vectors by reading a sample back
+type ReadBackConfig struct {
+	Enabled    bool `yaml:"enabled"`
+	SampleSize int  `yaml:"sample_size"` // vectors read back per write, at most 100
+}
+
+// Validate checks the sample size of an enabled read-back
+func (r ReadBackConfig) Validate() error {
+	if !r.Enabled {
+		return nil
+	}
+	if r.SampleSize < 1 || r.SampleSize > maxReadBackSample {
+		return fmt.Errorf("aws.dynamodb.vector_readback.sample_size must be between 1 and %d, got %d", maxReadBackSample, r.SampleSize)
+	}
+	return nil
+}
+
+// maxAttributeNameLength is the DynamoDB limit for key attribute names
+const maxAttributeNameLength = 255
+
+// Validate checks that table, index and key names are usable
+func (d DynamoDBConfig) Validate() error {
+	if d.PapersTable == "" {
+		return fmt.Errorf("aws.dynamodb.papers_table is required")
+	}
+	if d.VectorsTable == "" {
+		return fmt.Errorf("aws.dynamodb.vectors_table is required")
+	}
+	if d.TraceIDIndex == "" {
+		return fmt.Errorf("aws.dynamodb.trace_id_index is required")
+	}
+	if d.VectorKeys.PartitionKey == "" {
+		return fmt.Errorf("aws.dynamodb.vector_keys.partition_key is required")
+	}
+	if len(d.VectorKeys.PartitionKey) > maxAttributeNameLength || len(d.VectorKeys.SortKey) > maxAttributeNameLength {
+		return fmt.Errorf("vector key attribute names must be at most %d bytes", maxAttributeNameLength)
+	}
+	if d.VectorKeys.PartitionKey == d.VectorKeys.SortKey {
+		return fmt.Errorf("vector partition and sort keys must differ, both are %q", d.VectorKeys.PartitionKey)
+	}
+	if err := d.VectorWriteCapacity.Validate(); err != nil {
+		return err
+	}
+	if err := d.VectorWriteSpool.Validate(); err != nil {
+		return err
+	}
+	if err := d.VectorReadBack.Validate(); err != nil {
+		return err
+	}
+	if err := d.Client.Validate(); err != nil {
+		return fmt.Errorf("aws.dynamodb.client: %w", err)
+	}
+	return nil
+}
+
+// Manager handles configuration loading and management
+type Manager struct {
+	s3Client  *s3.S3
+	ssmClient *ssm.SSM
+}
+
+// NewManager creates a new configuration manager
+func NewManager() (*Manager, error) {
+	sess, err := awsclient.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"), // Default region
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AWS session: %w", err)
+	}
+
+	return &Manager{
+		s3Client:  s3.New(sess),
+		ssmClient: ssm.New(sess),
+	}, nil
+}
+
+// LoadFromS3 loads configuration from S3
+func (m *Manager) LoadFromS3(ctx context.Context, bucket, key string) (*Config, error) {
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}
+
+	result, err := m.s3Client.GetObjectWithContext(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config from S3: %w", err)
+	}
+	defer result.Body.Close()
+
+	data, err := io.ReadAll(result.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config data: %w", err)
+	}
+
+	return m.LoadFromBytes(data)
+}
+
+// LoadFromSSM loads configuration from an SSM parameter holding the YAML document; SecureString
+// parameters are decrypted
+func (m *Manager) LoadFromSSM(ctx context.Context, name string) (*Config, error) {
+	result, err := m.ssmClient.GetParameterWithContext(ctx, &ssm.GetParameterInput{
+		Name:           aws.String(name),
+		WithDecryption: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config from SSM parameter %s: %w", name, err)
+	}
+	return m.LoadFromBytes([]byte(aws.StringValue(result.Parameter.Value)))
+}
+
+// LoadFromBytes loads configuration from byte data, filling unset values from the defaults
+func (m *Manager) LoadFromBytes(data []byte) (*Config, error) {
+	config := GetDefaultConfig()
+	if err := yaml.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("failed to parse YAML config: %w", err)
+	}
+
+	if err := config.AWS.DynamoDB.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid dynamodb config: %w", err)
+	}
+	if err := config.Vectorization.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid vectorization config: %w", err)
+	}
+	if err := config.Vectorization.WeightedEmbedding.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid vectorization config: %w", err)
+	}
+	if err := config.Vectorization.WarmUp.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid vectorization config: %w", err)
+	}
+	if err := config.Vectorization.EmbeddingBatch.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid vectorization config: %w", err)
+	}
+	if err := config.Vectorization.FullText.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid vectorization config: %w", err)
+	}
+	if err := config.Vectorization.Retrieval.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid vectorization config: %w", err)
+	}
+	if err := config.Vectorization.Quotas.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid vectorization config: %w", err)
+	}
+	if err := config.SLO.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid slo config: %w", err)
+	}
+
+	return config, nil
+}
+
+// GetDefaultConfig returns a default configuration for fallback scenarios
+func GetDefaultConfig() *Config {
+	return &Config{
+		AWS: AWSConfig{
+			DynamoDB: DynamoDBConfig{
+				PapersTable:  "papers-table",
+				VectorsTable: "vectors-table",
+				Region:       "us-east-1",
+				TraceIDIndex: "trace-id-index",
+				VectorKeys: VectorKeyConfig{
+					PartitionKey: "paper_id",
+					SortKey:      "vector_type",
+				},
+				VectorWriteCapacity: WriteCapacityConfig{
+					TargetUtilization: 0.8,
+				},
+				VectorWriteSpool: WriteSpoolConfig{
+					Prefix:           "spool",
+					FailureThreshold: 50,
+				},
+				VectorReadBack: ReadBackConfig{
+					SampleSize: 5,
+				},
+			},
+		},
+		Vectorization: VectorizationConfig{
+			BatchSize:     maxWriteBatchSize,
+			MaxTextLength: 10000,
+			WeightedEmbedding: WeightedEmbeddingConfig{
+				Enabled: false,
+				Weights: map[string]float64{
+					"title":    0.3,
+					"abstract": 0.7,
+				},
+			},
+			WarmUp: WarmUpConfig{
+				Enabled:        false,
+				MinPapers:      100,
+				Embedding:      true,
+				TimeoutSeconds: 60,
+			},
+			EmbeddingBatch: EmbeddingBatchConfig{
+				Enabled:         false,
+				InitialSize:     8,
+				MinSize:         1,
+				MaxSize:         64,
+				TargetLatencyMs: 2000,
+				IncreaseStep:    2,
+				DecreaseFactor:  0.5,
+			},
+			FullText: FullTextConfig{
+				Bucket:       "pipeline-full-text",
+				ChunkWords:   200,
+				OverlapWords: 20,
+				MaxChunks:    50,
+			},
+			Retrieval: RetrievalConfig{
+				SortOrder: SortDescending,
+				BackfillCheck: BackfillCheckConfig{
+					MaxWaitSeconds:      30,
+					PollIntervalSeconds: 2,
+				},
+			},
+		},
+		Pause: pauseflags.Config{
+			ParameterPrefix: "/paper-pipeline/pause",
+			RefreshSeconds:  30,
+		},
+		SLO: slo.DefaultConfig(),
+	}
+}
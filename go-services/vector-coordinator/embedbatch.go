@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"time"
+	"unicode/utf8"
+
+	"vector-coordinator/client"
+	"vector-coordinator/config"
+	"vector-coordinator/retriever"
+)
+
+// BatchEmbeddingClient is implemented by embedding API clients that can embed several texts
+// in one request
+type BatchEmbeddingClient interface {
+	SupportsBatch() bool
+	GenerateEmbeddings(ctx context.Context, texts []string) ([]*client.EmbeddingResponse, error)
+}
+
+// batchSizeTuner adapts the texts per batch request to the API, additive increase and
+// multiplicative decrease, so throughput climbs while requests stay fast and backs off as
+// soon as the API slows down or pushes back
+type batchSizeTuner struct {
+	cfg  config.EmbeddingBatchConfig
+	size int
+}
+
+func newBatchSizeTuner(cfg config.EmbeddingBatchConfig) *batchSizeTuner {
+	return &batchSizeTuner{cfg: cfg, size: cfg.InitialSize}
+}
+
+// observe adjusts the size after a request that took latency and failed with err, nil on
+// success, and returns the new size. Invalid input says nothing about the API's load and
+// leaves the size as it is.
+func (t *batchSizeTuner) observe(latency time.Duration, err error) int {
+	switch {
+	case err != nil && client.ClassifyFailure(err) == client.FailureInvalidInput:
+	case err != nil || latency > time.Duration(t.cfg.TargetLatencyMs)*time.Millisecond:
+		t.size = max(t.cfg.MinSize, int(float64(t.size)*t.cfg.DecreaseFactor))
+	default:
+		t.size = min(t.cfg.MaxSize, t.size+t.cfg.IncreaseStep)
+	}
+	return t.size
+}
+
+// batchEmbedder embeds the run's papers a batch ahead of the embedding loop and hands the
+// loop each paper's embedding in order
+type batchEmbedder struct {
+	client   BatchEmbeddingClient
+	tuner    *batchSizeTuner
+	texts    []retriever.CombinedText
+	pending  map[int]*client.EmbeddingResponse // embeddings of the last batch, by paper index
+	requests int
+	failures int
+}
+
+// newBatchEmbedder returns nil when batch embedding is disabled or the API client cannot
+// batch, and the loop embeds one paper per request
+func (vc *VectorCoordinator) newBatchEmbedder(texts []retriever.CombinedText) *batchEmbedder {
+	if !vc.embeddingBatch.Enabled {
+		return nil
+	}
+	batchClient, ok := vc.apiClient.(BatchEmbeddingClient)
+	if !ok || !batchClient.SupportsBatch() {
+		vc.logger.Warn("Embedding API client cannot batch, embedding one paper per request")
+		return nil
+	}
+	return &batchEmbedder{
+		client: batchClient,
+		tuner:  newBatchSizeTuner(vc.embeddingBatch),
+		texts:  texts,
+	}
+}
+
+// embedPaper returns the embedding of combinedText, the i-th paper. With a batch embedder it
+// is served from the last batch, or a new batch starting at the paper is requested. A failed
+// batch falls back to embedding the paper alone, so its failure keeps its own cause, and the
+// papers after it go into the next, smaller batch.
+func (vc *VectorCoordinator) embedPaper(ctx context.Context, batcher *batchEmbedder, i int, combinedText retriever.CombinedText, result *ProcessingResult) (*client.EmbeddingResponse, error) {
+	if batcher == nil {
+		return vc.apiClient.GenerateEmbedding(ctx, combinedText.Text)
+	}
+	if response, ok := batcher.pending[i]; ok {
+		delete(batcher.pending, i)
+		return response, nil
+	}
+	contextLogger := vc.logger.WithContext(ctx)
+
+	texts := vc.nextBatch(batcher, i, result)
+	previous := batcher.tuner.size
+	start := time.Now()
+	responses, err := batcher.client.GenerateEmbeddings(ctx, texts)
+	latency := time.Since(start)
+	size := batcher.tuner.observe(latency, err)
+	batcher.requests++
+	if size != previous {
+		contextLogger.Info("Embedding batch size adjusted", map[string]interface{}{
+			"metric_type":      "embedding_batch",
+			"metric_name":      "embedding_batch_size",
+			"value":            size,
+			"previous_size":    previous,
+			"batch_latency_ms": latency.Milliseconds(),
+			"batch_failed":     err != nil,
+		})
+	}
+	if err != nil {
+		batcher.failures++
+		contextLogger.Warn("Batch embedding failed, embedding the paper alone", map[string]interface{}{
+			"batch_size":    len(texts),
+			"error":         err.Error(),
+			"failure_cause": client.ClassifyFailure(err),
+		})
+		return vc.apiClient.GenerateEmbedding(ctx, combinedText.Text)
+	}
+
+	batcher.pending = make(map[int]*client.EmbeddingResponse, len(responses)-1)
+	for j, response := range responses[1:] {
+		batcher.pending[i+1+j] = response
+	}
+	return responses[0], nil
+}
+
+// nextBatch returns the texts of the batch starting at the i-th paper: up to the tuned size,
+// and with a character quota only as many as the quota has left, so papers the loop would
+// stop before are not embedded ahead of it
+func (vc *VectorCoordinator) nextBatch(batcher *batchEmbedder, i int, result *ProcessingResult) []string {
+	end := min(i+batcher.tuner.size, len(batcher.texts))
+	texts := []string{batcher.texts[i].Text}
+	remaining := vc.quotas.MaxEmbeddingCharsPerRun - result.EmbeddedChars
+	for j := i + 1; j < end; j++ {
+		if vc.quotas.MaxEmbeddingCharsPerRun > 0 {
+			remaining -= utf8.RuneCountInString(batcher.texts[j].Text)
+			if remaining < 0 {
+				break
+			}
+		}
+		texts = append(texts, batcher.texts[j].Text)
+	}
+	return texts
+}
+
+// recordBatchStats adds the batch requests of the run to its result
+func (b *batchEmbedder) recordBatchStats(result *ProcessingResult) {
+	if b == nil {
+		return
+	}
+	result.EmbeddingBatches = b.requests
+	result.FailedEmbeddingBatches = b.failures
+	result.EmbeddingBatchSize = b.tuner.size
+}
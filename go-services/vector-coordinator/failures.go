@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"shared/awsclient"
+	"shared/failures"
+	"shared/logger"
+	"vector-coordinator/client"
+	"vector-coordinator/storage"
+)
+
+// recordEmbeddingFailure counts a failed title/abstract embedding under its cause, notes it
+// for the FailedItems table and returns the cause
+func recordEmbeddingFailure(result *ProcessingResult, paperID, vectorType string, err error) string {
+	cause := client.ClassifyFailure(err)
+	result.FailedEmbeddings++
+	if result.FailedEmbeddingsByCause == nil {
+		result.FailedEmbeddingsByCause = make(map[string]int)
+	}
+	result.FailedEmbeddingsByCause[cause]++
+	addFailedItem(result, paperID, vectorType, embeddingFailureCode(cause), err.Error())
+	return cause
+}
+
+// embeddingFailuresPermanent reports whether every failed embedding was caused by invalid
+// input, which fails again on every attempt; rate limits, timeouts and server errors may not
+func embeddingFailuresPermanent(result *ProcessingResult) bool {
+	return result.FailedEmbeddings > 0 && result.FailedEmbeddings == result.FailedEmbeddingsByCause[client.FailureInvalidInput]
+}
+
+// embeddingFailureCode maps an embedding failure cause to its failure code
+func embeddingFailureCode(cause string) failures.Code {
+	switch cause {
+	case client.FailureRateLimited:
+		return failures.CodeEmbeddingRateLimited
+	case client.FailureTimeout:
+		return failures.CodeEmbeddingTimeout
+	case client.FailureInvalidInput:
+		return failures.CodeEmbeddingInvalid
+	}
+	return failures.CodeEmbeddingServer
+}
+
+// addFailedItem notes a paper's failed vector. The record ID includes the vector type, so a
+// paper whose full text fails keeps separate entries from its title/abstract vector.
+func addFailedItem(result *ProcessingResult, paperID, vectorType string, code failures.Code, message string) {
+	result.FailedItems = append(result.FailedItems, failures.Failure{
+		Stage:    failures.StageVectorization,
+		RecordID: paperID + "#" + vectorType,
+		TraceID:  result.TraceID,
+		Code:     code,
+		Message:  message,
+	})
+}
+
+// addStorageFailures notes the vector records that failed to store; full-text chunks are
+// noted once per paper
+func addStorageFailures(result *ProcessingResult, batchResult *storage.BatchWriteResult) {
+	noted := make(map[string]bool)
+	for _, record := range batchResult.FailedItems {
+		vectorType := record.VectorType
+		if storage.IsFullTextVectorType(vectorType) {
+			vectorType = storage.VectorTypeFullText
+		}
+		if key := record.PaperID + "#" + vectorType; !noted[key] {
+			noted[key] = true
+			addFailedItem(result, record.PaperID, vectorType, failures.CodeStoreWrite, "vector record failed to store")
+		}
+	}
+}
+
+// failedChunkIndexes returns the indexes of the write chunks that were not fully stored,
+// the ones to replay
+func failedChunkIndexes(chunks []storage.BatchChunk) []int {
+	indexes := []int{}
+	for _, chunk := range chunks {
+		if chunk.Status != storage.ChunkWritten {
+			indexes = append(indexes, chunk.Index)
+		}
+	}
+	return indexes
+}
+
+// recordFailedItems stores the run's failed vectors in the FailedItems table, when
+// FAILED_ITEMS_TABLE_NAME is set; a failed write is only logged
+func recordFailedItems(ctx context.Context, contextLogger *logger.Logger, clientConfig awsclient.ClientConfig, result *ProcessingResult) {
+	tableName := os.Getenv(failures.TableEnv)
+	if tableName == "" || result == nil || len(result.FailedItems) == 0 {
+		return
+	}
+	if err := failures.NewRecorder(tableName, clientConfig).Record(ctx, result.FailedItems); err != nil {
+		contextLogger.Warn("Failed to record failed items", map[string]interface{}{
+			"table": tableName,
+			"error": err.Error(),
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"sort"
+	"time"
+
+	"shared/logger"
+	"shared/slo"
+	"vector-coordinator/storage"
+)
+
+// FreshnessReport summarizes how long the papers a run made ready took from their collection
+// to search. Papers ingested before collection times were recorded are not counted.
+type FreshnessReport struct {
+	Papers  int          `json:"papers"`
+	P50Ms   int64        `json:"p50_ms"`
+	P95Ms   int64        `json:"p95_ms"`
+	MaxMs   int64        `json:"max_ms"`
+	Slowest PaperLatency `json:"slowest"` // the stages of the slowest paper
+
+	latencies []time.Duration
+}
+
+// PaperLatency breaks one paper's end-to-end latency down by pipeline stage; a stage whose
+// start was not recorded is left out
+type PaperLatency struct {
+	PaperID                    string `json:"paper_id"`
+	EndToEndMs                 int64  `json:"end_to_end_ms"`
+	CollectionToIngestionMs    int64  `json:"collection_to_ingestion_ms,omitempty"`
+	IngestionToVectorizationMs int64  `json:"ingestion_to_vectorization_ms,omitempty"`
+	VectorizationToReadyMs     int64  `json:"vectorization_to_ready_ms,omitempty"`
+}
+
+// measurePaperLatency measures a paper made ready at readyAt from the pipeline timestamps on
+// one of its records, reporting false when its collection time is unknown
+func measurePaperLatency(record storage.VectorRecord, readyAt time.Time) (PaperLatency, bool) {
+	info := record.ProcessingInfo
+	collectedAt, ok := parseStamp(info.CollectedAt)
+	if !ok {
+		return PaperLatency{}, false
+	}
+	latency := PaperLatency{
+		PaperID:    record.PaperID,
+		EndToEndMs: elapsedMs(collectedAt, readyAt),
+	}
+	ingestedAt, ingested := parseStamp(info.IngestedAt)
+	vectorizedAt, vectorized := parseStamp(info.CreatedAt)
+	if ingested {
+		latency.CollectionToIngestionMs = elapsedMs(collectedAt, ingestedAt)
+	}
+	if ingested && vectorized {
+		latency.IngestionToVectorizationMs = elapsedMs(ingestedAt, vectorizedAt)
+	}
+	if vectorized {
+		latency.VectorizationToReadyMs = elapsedMs(vectorizedAt, readyAt)
+	}
+	return latency, true
+}
+
+// add counts one paper made ready; summarize updates the percentiles once all are added
+func (r *FreshnessReport) add(latency PaperLatency) {
+	r.latencies = append(r.latencies, time.Duration(latency.EndToEndMs)*time.Millisecond)
+	if r.Papers == 0 || latency.EndToEndMs > r.MaxMs {
+		r.MaxMs = latency.EndToEndMs
+		r.Slowest = latency
+	}
+	r.Papers++
+}
+
+// summarize updates the percentiles after papers were added
+func (r *FreshnessReport) summarize() {
+	if len(r.latencies) == 0 {
+		return
+	}
+	sorted := make([]time.Duration, len(r.latencies))
+	copy(sorted, r.latencies)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	r.P50Ms = percentile(sorted, 50).Milliseconds()
+	r.P95Ms = percentile(sorted, 95).Milliseconds()
+}
+
+// emitFreshness logs the end-to-end latency of the papers the run made ready as a metric
+// line, valued at the slowest paper, and reports their end_to_end_latency SLO events.
+// Re-embeds refresh papers collected long ago and are not measured.
+func emitFreshness(contextLogger *logger.Logger, cfg slo.Config, result *ProcessingResult) {
+	if result == nil || result.PaperID != "" || result.Freshness == nil || result.Freshness.Papers == 0 {
+		return
+	}
+	freshness := result.Freshness
+	contextLogger.Info("End-to-end latency", map[string]interface{}{
+		"metric_type": "latency",
+		"metric_name": "end_to_end_latency_ms",
+		"value":       freshness.MaxMs,
+		"papers":      freshness.Papers,
+		"p50_ms":      freshness.P50Ms,
+		"p95_ms":      freshness.P95Ms,
+		"slowest":     freshness.Slowest,
+	})
+	if cfg.Enabled {
+		slo.Emit(contextLogger, slo.Within(slo.EndToEndLatency, cfg.EndToEndLatency, freshness.latencies))
+	}
+}
+
+// parseStamp parses an RFC 3339 pipeline timestamp, reporting false when it is unset or malformed
+func parseStamp(stamp string) (time.Time, bool) {
+	if stamp == "" {
+		return time.Time{}, false
+	}
+	parsed, err := time.Parse(time.RFC3339, stamp)
+	return parsed, err == nil
+}
+
+// elapsedMs is the time from start to end in milliseconds, 0 when clock skew puts end first
+func elapsedMs(start, end time.Time) int64 {
+	if elapsed := end.Sub(start).Milliseconds(); elapsed > 0 {
+		return elapsed
+	}
+	return 0
+}
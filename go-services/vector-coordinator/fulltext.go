@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"shared/failures"
+	"vector-coordinator/fulltext"
+	"vector-coordinator/retriever"
+	"vector-coordinator/storage"
+)
+
+// FullTextStoreInterface defines the interface for reading extracted full text
+type FullTextStoreInterface interface {
+	GetText(ctx context.Context, location string) (string, error)
+}
+
+// generateFullTextRecords embeds the chunked full text of every paper that has a full-text
+// key. Full-text vectors are additional, so a paper whose text cannot be read or embedded
+// keeps its title/abstract vector and only counts towards FailedFullText.
+func (vc *VectorCoordinator) generateFullTextRecords(ctx context.Context, combinedTexts []retriever.CombinedText, traceID string, result *ProcessingResult) []storage.VectorRecord {
+	contextLogger := vc.logger.WithContext(ctx)
+	start := time.Now()
+	defer func() {
+		result.StageTimings[TimingFullTextMs] = time.Since(start).Milliseconds()
+	}()
+
+	var records []storage.VectorRecord
+	for _, combinedText := range combinedTexts {
+		if combinedText.FullTextKey == "" {
+			continue
+		}
+		if reason := vc.haltReason(ctx); reason != "" {
+			result.Interrupted = true
+			result.Paused = reason == haltPaused
+			contextLogger.Warn(reason+", stopping full-text embedding", map[string]interface{}{
+				"full_text_papers": result.FullTextPapers,
+			})
+			break
+		}
+
+		paperRecords, err := vc.embedFullText(ctx, combinedText, traceID, result)
+		if errors.Is(err, errQuotaExceeded) {
+			contextLogger.Warn("Embedding character quota reached, stopping full-text embedding", map[string]interface{}{
+				"full_text_papers": result.FullTextPapers,
+				"embedded_chars":   result.EmbeddedChars,
+			})
+			break
+		}
+		if err != nil {
+			result.FailedFullText++
+			addFailedItem(result, combinedText.PaperID, storage.VectorTypeFullText, failures.CodeFullText, err.Error())
+			contextLogger.Warn("Failed to generate full-text embeddings", map[string]interface{}{
+				"paper_id":      combinedText.PaperID,
+				"full_text_key": combinedText.FullTextKey,
+				"error":         err.Error(),
+			})
+			continue
+		}
+		if len(paperRecords) == 0 {
+			continue
+		}
+		records = append(records, paperRecords...)
+		result.FullTextPapers++
+		result.FullTextChunks += len(paperRecords)
+	}
+
+	contextLogger.InfoWithCount("Completed full-text embedding", result.FullTextChunks, map[string]interface{}{
+		"full_text_papers": result.FullTextPapers,
+		"failed_full_text": result.FailedFullText,
+	})
+	return records
+}
+
+// embedFullText reads, chunks and embeds one paper's full text. All chunks must embed for the
+// paper's chunks to be stored, so a paper never ends up with a partial set of chunks. A paper
+// whose chunks would exceed the character quota is not embedded at all.
+func (vc *VectorCoordinator) embedFullText(ctx context.Context, combinedText retriever.CombinedText, traceID string, result *ProcessingResult) ([]storage.VectorRecord, error) {
+	text, err := vc.textStore.GetText(ctx, combinedText.FullTextKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// Word offsets of the chunks refer to the preprocessed text
+	text = vc.preprocessing.Apply(text)
+	chunks := fulltext.Split(text, vc.fullText.ChunkWords, vc.fullText.OverlapWords, vc.fullText.MaxChunks)
+	chunkTexts := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		chunkTexts[i] = chunk.Text
+	}
+	if !vc.reserveEmbeddingChars(result, chunkTexts...) {
+		return nil, errQuotaExceeded
+	}
+
+	records := make([]storage.VectorRecord, 0, len(chunks))
+	for _, chunk := range chunks {
+		chunkStart := time.Now()
+		response, err := vc.apiClient.GenerateEmbedding(ctx, chunk.Text)
+		if err != nil {
+			return nil, err
+		}
+		info := storage.ChunkInfo{
+			Index:     chunk.Index,
+			Count:     len(chunks),
+			WordStart: chunk.WordStart,
+			WordEnd:   chunk.WordEnd,
+			SourceKey: combinedText.FullTextKey,
+		}
+		record := storage.CreateFullTextVectorRecord(
+			combinedText.PaperID,
+			chunk.Text,
+			traceID,
+			response.Embedding,
+			response.ModelVersion,
+			info,
+			time.Since(chunkStart).Milliseconds(),
+		)
+		recordProvenance(record, response)
+		recordSource(record, combinedText)
+		vc.recordPreprocessing(record)
+		records = append(records, *record)
+	}
+	return records, nil
+}
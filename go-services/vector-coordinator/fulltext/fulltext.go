@@ -0,0 +1,129 @@
+package fulltext
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"shared/awsclient"
+)
+
+// MaxTextBytes caps how much extracted text is read for one paper
+const MaxTextBytes = 8 * 1024 * 1024
+
+// Store reads extracted full text from S3
+type Store struct {
+	s3Client      s3iface.S3API
+	defaultBucket string
+}
+
+// NewStore creates a full-text store; defaultBucket is used for keys that are not s3:// URIs
+func NewStore(defaultBucket string) *Store {
+	return &Store{
+		s3Client:      s3.New(awsclient.MustSession()),
+		defaultBucket: defaultBucket,
+	}
+}
+
+// NewStoreWithClient creates a full-text store with custom client (for testing)
+func NewStoreWithClient(client s3iface.S3API, defaultBucket string) *Store {
+	return &Store{
+		s3Client:      client,
+		defaultBucket: defaultBucket,
+	}
+}
+
+// GetText reads the text object at location, an s3://bucket/key URI or a key in the default
+// bucket. Gzipped objects are decompressed.
+func (s *Store) GetText(ctx context.Context, location string) (string, error) {
+	bucket, key := s.resolve(location)
+	if bucket == "" || key == "" {
+		return "", fmt.Errorf("invalid full-text location %q", location)
+	}
+
+	output, err := s.s3Client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to get full text %s/%s: %w", bucket, key, err)
+	}
+	defer output.Body.Close()
+
+	body := bufio.NewReader(output.Body)
+	var reader io.Reader = body
+	if magic, err := body.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gzipReader, err := gzip.NewReader(body)
+		if err != nil {
+			return "", fmt.Errorf("failed to decompress full text %s/%s: %w", bucket, key, err)
+		}
+		defer gzipReader.Close()
+		reader = gzipReader
+	}
+
+	data, err := io.ReadAll(io.LimitReader(reader, MaxTextBytes+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read full text %s/%s: %w", bucket, key, err)
+	}
+	if len(data) > MaxTextBytes {
+		return "", fmt.Errorf("full text %s/%s exceeds %d bytes", bucket, key, MaxTextBytes)
+	}
+	return string(data), nil
+}
+
+// resolve splits a location into bucket and key
+func (s *Store) resolve(location string) (string, string) {
+	if rest, ok := strings.CutPrefix(location, "s3://"); ok {
+		bucket, key, _ := strings.Cut(rest, "/")
+		return bucket, key
+	}
+	return s.defaultBucket, strings.TrimPrefix(location, "/")
+}
+
+// Chunk is a window of words from a paper's full text
+type Chunk struct {
+	Index     int // position of the chunk in the text
+	WordStart int // index of the chunk's first word
+	WordEnd   int // index after the chunk's last word
+	Text      string
+}
+
+// Split divides text into chunks of chunkWords words, each overlapping the previous one by
+// overlapWords, stopping after maxChunks chunks (0 for no limit). Whitespace is normalized.
+func Split(text string, chunkWords, overlapWords, maxChunks int) []Chunk {
+	words := strings.Fields(text)
+	if len(words) == 0 || chunkWords < 1 {
+		return nil
+	}
+	step := chunkWords - overlapWords
+	if step < 1 {
+		step = 1
+	}
+
+	var chunks []Chunk
+	for start := 0; start < len(words); start += step {
+		if maxChunks > 0 && len(chunks) == maxChunks {
+			break
+		}
+		end := start + chunkWords
+		if end > len(words) {
+			end = len(words)
+		}
+		chunks = append(chunks, Chunk{
+			Index:     len(chunks),
+			WordStart: start,
+			WordEnd:   end,
+			Text:      strings.Join(words[start:end], " "),
+		})
+		if end == len(words) {
+			break
+		}
+	}
+	return chunks
+}
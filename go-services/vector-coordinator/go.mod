@@ -5,17 +5,52 @@ go 1.23
 require (
 	github.com/aws/aws-lambda-go v1.47.0
 	github.com/aws/aws-sdk-go v1.55.5
-	github.com/stretchr/testify v1.9.0
+	github.com/stretchr/testify v1.9.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
-	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/andybalholm/brotli v1.1.0 // indirect
+	github.com/aws/aws-xray-sdk-go v1.8.5 // indirect
 	github.com/jmespath/go-jmespath v0.4.0 // indirect
-	github.com/pmezard/go-difflib v1.0.0 // indirect
-	github.com/stretchr/objx v0.5.2 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
+	github.com/klauspost/compress v1.17.6 // indirect
+	github.com/pkg/errors v0.9.1 // indirect
+	github.com/valyala/bytebufferpool v1.0.0 // indirect
+	github.com/valyala/fasthttp v1.52.0 // indirect
+	golang.org/x/net v0.26.0 // indirect
+	golang.org/x/sys v0.21.0 // indirect
+	golang.org/x/text v0.16.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20240318140521-94a12d6c2237 // indirect
+	google.golang.org/grpc v1.64.1 // indirect
+	google.golang.org/protobuf v1.33.0 // indirect
 )
 
 replace shared/logger => ../shared/logger
 
-require shared/logger v0.0.0
+require (
+	shared/awsclient v0.0.0
+	shared/dynamo v0.0.0
+	shared/failures v0.0.0
+	shared/httpserver v0.0.0
+	shared/logger v0.0.0
+	shared/pauseflags v0.0.0
+	shared/preflight v0.0.0
+	shared/shutdown v0.0.0
+	shared/slo v0.0.0
+)
+
+replace shared/awsclient => ../shared/awsclient
+
+replace shared/pauseflags => ../shared/pauseflags
+
+replace shared/preflight => ../shared/preflight
+
+replace shared/failures => ../shared/failures
+
+replace shared/slo => ../shared/slo
+
+replace shared/dynamo => ../shared/dynamo
+
+replace shared/shutdown => ../shared/shutdown
+
+replace shared/httpserver => ../shared/httpserver
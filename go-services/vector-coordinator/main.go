@@ -2,24 +2,44 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"os"
 	"time"
 
 	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-lambda-go/lambda/messages"
+	"shared/failures"
 	"shared/logger"
+	"shared/pauseflags"
+	"shared/shutdown"
 	"vector-coordinator/client"
+	"vector-coordinator/config"
+	"vector-coordinator/preprocess"
 	"vector-coordinator/retriever"
 	"vector-coordinator/storage"
 )
 
 type StepFunctionInput struct {
 	TraceID string `json:"trace_id"`
+	Mode    string `json:"mode,omitempty"` // "validate" skips vector storage writes
+	// FullText also embeds the chunked full text of papers that have a full-text S3 key
+	FullText bool `json:"full_text,omitempty"`
+	// PaperID re-embeds this one paper instead of a trace; Model picks an entry of
+	// EMBEDDING_MODEL_URLS and VectorType the vector to regenerate (default title_abstract)
+	PaperID    string `json:"paper_id,omitempty"`
+	Model      string `json:"model,omitempty"`
+	VectorType string `json:"vector_type,omitempty"`
+	// DrainSpool writes the trace's spooled vector records to the vectors table instead of
+	// vectorizing it
+	DrainSpool bool `json:"drain_spool,omitempty"`
 }
 
 // DataRetrieverInterface defines the interface for data retrieval
 type DataRetrieverInterface interface {
 	GetCombinedTextsByTraceID(ctx context.Context, traceID string) ([]retriever.CombinedText, error)
+	GetPapersByIDs(ctx context.Context, paperIDs []string) ([]retriever.Paper, error)
 }
 
 // VectorAPIClientInterface defines the interface for vector API client
@@ -30,13 +50,52 @@ type VectorAPIClientInterface interface {
 // VectorStorageInterface defines the interface for vector storage
 type VectorStorageInterface interface {
 	BatchStoreVectors(ctx context.Context, records []storage.VectorRecord) (*storage.BatchWriteResult, error)
+	MarkReady(ctx context.Context, records []storage.VectorRecord) (*storage.BatchWriteResult, error)
+	ReadBack(ctx context.Context, records []storage.VectorRecord) ([]storage.ReadBackMismatch, error)
 }
 
 type VectorCoordinator struct {
-	retriever     DataRetrieverInterface
-	apiClient     VectorAPIClientInterface
-	vectorStorage VectorStorageInterface
-	logger        *logger.Logger
+	retriever       DataRetrieverInterface
+	apiClient       VectorAPIClientInterface
+	vectorStorage   VectorStorageInterface
+	logger          *logger.Logger
+	validateOnly    bool
+	shutdown        <-chan struct{} // closed to stop generating new embeddings; generated ones are still stored
+	pageLimitPolicy PageLimitPolicy
+	weighted        config.WeightedEmbeddingConfig
+	warmUp          config.WarmUpConfig
+	fullTextRun     bool // set per run by StepFunctionInput.FullText
+	fullText        config.FullTextConfig
+	textStore       FullTextStoreInterface
+	pause           *pauseflags.Checker // stops generating new embeddings like a shutdown while vectorization is paused
+	quotas          config.QuotaConfig
+	backfillCheck   config.BackfillCheckConfig
+	embeddingBatch  config.EmbeddingBatchConfig
+	preprocessing   *preprocess.Chain // applied to texts before they are embedded
+	spool           SpoolInterface    // nil unless vector writes spool to S3
+	readBack        config.ReadBackConfig
+}
+
+// PageLimitPolicy decides what happens when retrieval hits the query page limit
+type PageLimitPolicy string
+
+const (
+	// PageLimitFail fails the run rather than embedding an incomplete set of papers
+	PageLimitFail PageLimitPolicy = "fail"
+	// PageLimitContinue embeds the papers that were read and reports the run as partial
+	PageLimitContinue PageLimitPolicy = "continue"
+)
+
+// parsePageLimitPolicy reads the PAGE_LIMIT_POLICY value, defaulting to fail
+func parsePageLimitPolicy(value string) (PageLimitPolicy, error) {
+	switch PageLimitPolicy(value) {
+	case "", PageLimitFail:
+		return PageLimitFail, nil
+	case PageLimitContinue:
+		return PageLimitContinue, nil
+	default:
+		return "", fmt.Errorf("unknown page limit policy %q (expected %q or %q)", value, PageLimitFail, PageLimitContinue)
+	}
 }
 
 // ProcessingStatus represents the status of vectorization processing
@@ -48,27 +107,101 @@ const (
 	StatusCompleted  ProcessingStatus = "completed"
 	StatusFailed     ProcessingStatus = "failed"
 	StatusPartial    ProcessingStatus = "partial_success"
+	StatusValidated  ProcessingStatus = "validated"
+	// StatusQuotaExceeded marks a run stopped by vectorization.quotas
+	StatusQuotaExceeded ProcessingStatus = "quota_exceeded"
+	// StatusPartialSpooled marks a run whose vectors were all generated, some of them spooled
+	// to S3 by a throttled vectors table; a drain_spool run writes them later
+	StatusPartialSpooled ProcessingStatus = "partial_with_spool"
 )
 
 // ProcessingResult represents the result of vectorization processing
 type ProcessingResult struct {
 	TraceID           string           `json:"trace_id"`
+	PaperID           string           `json:"paper_id,omitempty"` // set on single-paper re-embeds
 	Status            ProcessingStatus `json:"status"`
 	TotalPapers       int              `json:"total_papers"`
 	EmbeddingsGenerated int            `json:"embeddings_generated"`
 	VectorsStored     int              `json:"vectors_stored"`
 	FailedEmbeddings  int              `json:"failed_embeddings"`
+	FailedEmbeddingsByCause map[string]int `json:"failed_embeddings_by_cause,omitempty"` // keyed by client.Failure* cause
 	FailedStorage     int              `json:"failed_storage"`
+	ReadyPapers       int              `json:"ready_papers"`             // papers whose vectors were all stored and flipped to ready
+	PendingPapers     int              `json:"pending_papers,omitempty"` // papers left pending, and out of search, by a failed write
+	SpooledPapers     int              `json:"spooled_papers,omitempty"`  // papers left pending until their spooled vectors are drained
+	SpooledVectors    int              `json:"spooled_vectors,omitempty"` // vectors written to the spool instead of the table
+	SpoolKeys         []string         `json:"spool_keys,omitempty"`
+	ConsumedWriteCapacity float64      `json:"consumed_write_capacity,omitempty"` // write capacity units reported by the vector batch writes
+	StorageThrottleMs int64            `json:"storage_throttle_ms,omitempty"`     // time vector writes waited for capacity under adaptive pacing
+	WriteChunks       []storage.BatchChunk `json:"write_chunks,omitempty"`          // the vector batch writes in order, for replaying failed chunks
 	ProcessingTimeMs  int64            `json:"processing_time_ms"`
 	ErrorMessage      string           `json:"error_message,omitempty"`
 	Timestamp         string           `json:"timestamp"`
+	Validation        *ValidationReport `json:"validation,omitempty"`
+	Interrupted       bool             `json:"interrupted,omitempty"`
+	Paused            bool             `json:"paused,omitempty"` // the run was interrupted by the vectorization pause flag
+	SkippedPapers     int              `json:"skipped_papers,omitempty"`
+	EmbeddedChars     int              `json:"embedded_chars"`                  // characters sent to the embedding API, counted against the quota
+	QuotaExceeded     bool             `json:"quota_exceeded,omitempty"`        // the run was stopped by vectorization.quotas
+	EmbeddingBatches  int              `json:"embedding_batches,omitempty"`     // batch embedding requests, when vectorization.embedding_batch is enabled
+	FailedEmbeddingBatches int         `json:"failed_embedding_batches,omitempty"` // batches whose first paper was embedded alone instead
+	EmbeddingBatchSize int             `json:"embedding_batch_size,omitempty"`  // the tuned batch size at the end of the run
+	WeightedEmbeddings int             `json:"weighted_embeddings,omitempty"`        // weighted multi-field vectors generated
+	FailedWeightedEmbeddings int       `json:"failed_weighted_embeddings,omitempty"`
+	WeightedVectorsStored int          `json:"weighted_vectors_stored,omitempty"`
+	FullTextPapers    int              `json:"full_text_papers,omitempty"`   // papers whose full text was chunked and embedded
+	FullTextChunks    int              `json:"full_text_chunks,omitempty"`   // full_text vectors generated
+	FailedFullText    int              `json:"failed_full_text,omitempty"`   // papers whose full text could not be read or embedded
+	FullTextVectorsStored int          `json:"full_text_vectors_stored,omitempty"`
+	RetrievalTruncated bool            `json:"retrieval_truncated,omitempty"` // the page limit was hit before all papers were read
+	ExpectedPapers    int              `json:"expected_papers,omitempty"`    // papers the batch processor recorded writing, when the backfill check ran
+	IndexLagged       bool             `json:"index_lagged,omitempty"`       // the trace-id index was still behind ExpectedPapers when retrieval started
+	ColdStart         bool             `json:"cold_start"`
+	InitTimeMs        int64            `json:"init_time_ms"` // time spent building the components; saved on warm invocations
+	StageTimings      map[string]int64 `json:"stage_timings,omitempty"` // per-stage and per-paper timings, see the Timing* keys
+	Freshness         *FreshnessReport `json:"freshness,omitempty"`     // end-to-end latency of the papers made ready
+	ReadBack          *ReadBackReport  `json:"readback,omitempty"`      // stored vectors read back, when aws.dynamodb.vector_readback is enabled
+	FailedItems       []failures.Failure `json:"-"` // failed vectors, written to the FailedItems table after the run
 }
 
+// ValidationReport describes what a validate-mode run would have written
+type ValidationReport struct {
+	Mode             string   `json:"mode"`
+	RecordsToStore   int      `json:"records_to_store"`
+	InvalidRecords   int      `json:"invalid_records"`
+	ValidationErrors []string `json:"validation_errors,omitempty"`
+	SkippedWrites    []string `json:"skipped_writes"`
+}
+
+// Error names reported to Step Functions so Retry rules can target transient failures only
+const (
+	ErrorNameRetryable = "RetryableProcessingError"
+	ErrorNameTerminal  = "TerminalProcessingError"
+)
+
 // ProcessingError represents a structured error with context
 type ProcessingError struct {
-	Stage   string `json:"stage"`
-	Message string `json:"message"`
-	Cause   error  `json:"-"`
+	Stage     string `json:"stage"`
+	Message   string `json:"message"`
+	Cause     error  `json:"-"`
+	Retryable bool   `json:"retryable"` // transient failures (DynamoDB, embedding API) that a retry may fix
+}
+
+// ErrorName returns the error type reported to Lambda and matched by Step Functions;
+// runs halted by the pause flag report PAUSED, failed pre-flight checks CONFIG_ERROR and
+// runs stopped by a quota QUOTA_ERROR, like the other services
+func (e *ProcessingError) ErrorName() string {
+	if errors.Is(e.Cause, pauseflags.ErrPaused) {
+		return string(logger.ErrorTypePaused)
+	}
+	var appErr *logger.AppError
+	if errors.As(e.Cause, &appErr) && (appErr.Type == logger.ErrorTypeConfig || appErr.Type == logger.ErrorTypeQuota) {
+		return string(appErr.Type)
+	}
+	if e.Retryable {
+		return ErrorNameRetryable
+	}
+	return ErrorNameTerminal
 }
 
 func (e *ProcessingError) Error() string {
@@ -86,25 +219,221 @@ func main() {
 	if os.Getenv("AWS_LAMBDA_FUNCTION_NAME") != "" {
 		lambda.Start(handleStepFunction)
 	} else {
+		serveAddr := flag.String("serve", "", "run an HTTP server on this address (e.g. :8080) instead of processing arguments")
+		fullText := flag.Bool("full-text", false, "also embed the full text of papers that have a full-text S3 key")
+		flag.Parse()
+
+		appLogger := logger.New("vector-coordinator")
+		watcher := shutdown.Watch(appLogger)
+		if *serveAddr != "" {
+			watcher.Exit(runServer(*serveAddr, watcher, appLogger), false)
+		}
+
 		fmt.Println("Vector Coordinator Service - Local Development Mode")
+		if args := flag.Args(); len(args) > 0 && args[0] == reembedCommand {
+			runReembed(args[1:], watcher)
+			return
+		}
+		if args := flag.Args(); len(args) > 0 && args[0] == metadataCommand {
+			watcher.Exit(runMetadata(args[1:]), false)
+		}
+		if args := flag.Args(); len(args) > 0 && args[0] == drainSpoolCommand {
+			watcher.Exit(runDrainSpool(args[1:]), false)
+		}
+		runLocal(flag.Args(), *fullText, watcher, appLogger)
 	}
 }
 
+// runLocal vectorizes the trace IDs given on the command line.
+// SIGINT/SIGTERM stop new embeddings and remaining trace IDs while generated vectors are stored.
+func runLocal(traceIDs []string, fullText bool, watcher *shutdown.Watcher, appLogger *logger.Logger) {
+	if len(traceIDs) == 0 {
+		fmt.Println("Usage: vector-coordinator [--serve addr] [--full-text] <trace-id> [trace-id ...]")
+		fmt.Println("       vector-coordinator reembed [--model name] [--vector-type type] <paper-id>")
+		fmt.Println("       vector-coordinator metadata <paper-id> [paper-id ...]")
+		fmt.Println("       vector-coordinator drain-spool <trace-id> [trace-id ...]")
+		return
+	}
+
+	interrupted := false
+	for i, traceID := range traceIDs {
+		if watcher.Requested() {
+			appLogger.Warn("Shutdown requested, skipping remaining trace IDs", map[string]interface{}{
+				"skipped_trace_ids": traceIDs[i:],
+			})
+			interrupted = true
+			break
+		}
+
+		result, err := runVectorization(context.Background(), StepFunctionInput{TraceID: traceID, FullText: fullText}, watcher.Done())
+		if result != nil && result.Interrupted {
+			interrupted = true
+		}
+		if err != nil {
+			watcher.Exit(err, interrupted)
+		}
+	}
+	watcher.Exit(nil, interrupted)
+}
+
 func handleStepFunction(ctx context.Context, input StepFunctionInput) (*ProcessingResult, error) {
+	result, err := runVectorization(ctx, input, nil)
+	if err != nil {
+		return result, lambdaError(err)
+	}
+	return result, nil
+}
+
+// lambdaError reports ProcessingErrors as RetryableProcessingError or TerminalProcessingError,
+// so Step Functions stop retrying validation failures while retrying transient ones
+func lambdaError(err error) error {
+	var processingErr *ProcessingError
+	if errors.As(err, &processingErr) {
+		return messages.InvokeResponse_Error{
+			Type:    processingErr.ErrorName(),
+			Message: processingErr.Error(),
+		}
+	}
+	return err
+}
+
+// runVectorization wires the coordinator and processes one trace ID; shutdown may be nil
+func runVectorization(ctx context.Context, input StepFunctionInput, shutdown <-chan struct{}) (*ProcessingResult, error) {
 	// Initialize components
-	papersTableName := getEnvOrDefault("PAPERS_TABLE_NAME", "papers-table")
-	indexName := getEnvOrDefault("TRACE_ID_INDEX_NAME", "trace-id-index")
-	vectorsTableName := getEnvOrDefault("VECTORS_TABLE_NAME", "vectors-table")
-	embeddingAPIURL := getEnvOrDefault("EMBEDDING_API_URL", "https://embedding-api.example.com/embed")
+	cfg, err := loadCoordinatorConfig(ctx)
+	if err != nil {
+		return nil, &ProcessingError{
+			Stage:   "configuration",
+			Message: "invalid coordinator configuration",
+			Cause:   err,
+		}
+	}
+	if input.PaperID != "" {
+		if input.VectorType == "" {
+			input.VectorType = storage.VectorTypeTitleAbstract
+		}
+		if !validReembedVectorType(input.VectorType) {
+			return nil, &ProcessingError{
+				Stage:   "validation",
+				Message: fmt.Sprintf("unknown vector_type %q", input.VectorType),
+			}
+		}
+	}
+	// Chunks are stored under per-chunk vector types, which need a sort key to coexist
+	reembedExtra := input.PaperID != "" && input.VectorType != storage.VectorTypeTitleAbstract
+	if (input.FullText || reembedExtra) && cfg.AWS.DynamoDB.VectorKeys.SortKey == "" {
+		return nil, &ProcessingError{
+			Stage:   "configuration",
+			Message: "full-text and weighted vectors require a vectors table sort key",
+		}
+	}
+
+	settings := loadComponentSettings(cfg)
+	components, cached, err := getComponents(settings)
+	if err != nil {
+		return nil, err
+	}
+	if err := runPreflight(ctx, settings, components, input.FullText || input.VectorType == storage.VectorTypeFullText); err != nil {
+		return nil, err
+	}
+	pageLimitPolicy, err := parsePageLimitPolicy(os.Getenv("PAGE_LIMIT_POLICY"))
+	if err != nil {
+		return nil, &ProcessingError{
+			Stage:   "configuration",
+			Message: "invalid PAGE_LIMIT_POLICY",
+			Cause:   err,
+		}
+	}
 	
+	appLogger := logger.New("vector-coordinator")
+	ctx = logger.ContextWithTraceID(ctx, input.TraceID)
+
+	// Operators halt vectorization spend through the pause flags, checked here and between papers
+	pauseChecker, err := pauseflags.NewChecker(cfg.Pause)
+	if err != nil {
+		return nil, &ProcessingError{
+			Stage:   "configuration",
+			Message: "failed to initialize pause flags",
+			Cause:   err,
+		}
+	}
+	if err := pauseChecker.Check(ctx, pauseflags.Vectorization); errors.Is(err, pauseflags.ErrPaused) {
+		appLogger.WithContext(ctx).Warn("Vectorization paused by operator flag, stopping")
+		return nil, &ProcessingError{
+			Stage:   "paused",
+			Message: "vectorization paused",
+			Cause:   err,
+		}
+	} else if err != nil {
+		appLogger.WithContext(ctx).Warn("Failed to read pause flags, continuing", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+
+	preprocessing, err := preprocess.New(cfg.Vectorization.Preprocessing)
+	if err != nil {
+		return nil, &ProcessingError{
+			Stage:   "configuration",
+			Message: "invalid preprocessing steps",
+			Cause:   err,
+		}
+	}
+
 	coordinator := &VectorCoordinator{
-		retriever:     retriever.NewDataRetriever(papersTableName, indexName),
-		apiClient:     client.NewVectorAPIClient(embeddingAPIURL),
-		vectorStorage: storage.NewVectorStorage(vectorsTableName),
-		logger:        logger.New("vector-coordinator"),
+		retriever:       components.retriever,
+		apiClient:       components.apiClient,
+		vectorStorage:   components.vectorStorage,
+		logger:          appLogger,
+		validateOnly:    input.Mode == "validate" || os.Getenv("RUN_MODE") == "validate",
+		shutdown:        shutdown,
+		pageLimitPolicy: pageLimitPolicy,
+		weighted:        cfg.Vectorization.WeightedEmbedding,
+		warmUp:          cfg.Vectorization.WarmUp,
+		fullTextRun:     input.FullText,
+		quotas:          cfg.Vectorization.Quotas,
+		backfillCheck:   cfg.Vectorization.Retrieval.BackfillCheck,
+		embeddingBatch:  cfg.Vectorization.EmbeddingBatch,
+		fullText:        cfg.Vectorization.FullText,
+		textStore:       components.textStore,
+		pause:           pauseChecker,
+		preprocessing:   preprocessing,
+		spool:           components.spool,
+		readBack:        settings.DynamoDB.VectorReadBack,
 	}
 	
-	result, err := coordinator.processVectorization(ctx, input.TraceID)
+	// A warm invocation reuses the clients, saving the init time measured when they were built
+	initTimeMs := components.initDuration.Milliseconds()
+	savedInitMs := int64(0)
+	if cached {
+		savedInitMs = initTimeMs
+	}
+	appLogger.WithContext(ctx).Info("Coordinator components ready", map[string]interface{}{
+		"metric_type":   "initialization",
+		"cold_start":    !cached,
+		"init_time_ms":  initTimeMs,
+		"saved_init_ms": savedInitMs,
+	})
+	
+	var result *ProcessingResult
+	if input.DrainSpool {
+		result, err = coordinator.drainSpool(ctx, input.TraceID)
+	} else if input.PaperID != "" {
+		result, err = coordinator.reembedPaper(ctx, input.PaperID, input.Model, input.VectorType)
+	} else {
+		result, err = coordinator.processVectorization(ctx, input.TraceID)
+	}
+	if result != nil {
+		result.ColdStart = !cached
+		result.InitTimeMs = initTimeMs
+	}
+	if !coordinator.validateOnly {
+		recordFailedItems(ctx, appLogger.WithContext(ctx), settings.DynamoDB.Client, result)
+		// A drain writes vectors generated earlier, so it says nothing about vectorization latency
+		if !input.DrainSpool {
+			emitSLO(appLogger.WithContext(ctx), cfg.SLO, result)
+		}
+		emitFreshness(appLogger.WithContext(ctx), cfg.SLO, result)
+	}
 	if err != nil {
 		// Return both result (for partial success) and error
 		return result, err
@@ -115,13 +444,16 @@ func handleStepFunction(ctx context.Context, input StepFunctionInput) (*Processi
 
 func (vc *VectorCoordinator) processVectorization(ctx context.Context, traceID string) (*ProcessingResult, error) {
 	startTime := time.Now()
-	contextLogger := vc.logger.WithContext(ctx).WithTraceID(traceID)
+	// The retriever, embedding client and storage log under the trace ID carried by ctx
+	ctx = logger.ContextWithTraceID(ctx, traceID)
+	contextLogger := vc.logger.WithContext(ctx)
 	
 	// Initialize result tracking
 	result := &ProcessingResult{
-		TraceID:   traceID,
-		Status:    StatusStarted,
-		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		TraceID:      traceID,
+		Status:       StatusStarted,
+		Timestamp:    time.Now().UTC().Format(time.RFC3339),
+		StageTimings: make(map[string]int64),
 	}
 	
 	contextLogger.Info("Starting vectorization processing", map[string]interface{}{
@@ -146,13 +478,36 @@ func (vc *VectorCoordinator) processVectorization(ctx context.Context, traceID s
 		"status": result.Status,
 	})
 	
+	// A trace queried right after ingestion may not have reached the trace-id index yet
+	vc.waitForIndexBackfill(ctx, traceID, result)
+	
 	// Retrieve papers and combine text with error handling
+	retrievalStart := time.Now()
 	combinedTexts, err := vc.retriever.GetCombinedTextsByTraceID(ctx, traceID)
+	result.StageTimings[TimingRetrievalMs] = time.Since(retrievalStart).Milliseconds()
+	var partialErr *retriever.PartialRetrievalError
+	if errors.As(err, &partialErr) {
+		result.RetrievalTruncated = true
+		if vc.pageLimitPolicy == PageLimitContinue {
+			contextLogger.Warn("Page limit reached, continuing with the papers retrieved so far", map[string]interface{}{
+				"max_pages":        partialErr.MaxPages,
+				"papers_retrieved": partialErr.PapersRetrieved,
+			})
+			combinedTexts = partialErr.Texts
+			err = nil
+		}
+	}
 	if err != nil {
 		processingErr := &ProcessingError{
-			Stage:   "data_retrieval",
-			Message: "failed to retrieve papers for vectorization",
-			Cause:   err,
+			Stage:     "data_retrieval",
+			Message:   "failed to retrieve papers for vectorization",
+			Cause:     err,
+			Retryable: true,
+		}
+		if partialErr != nil {
+			// Re-reading the same trace would hit the same limit, so this is not retried
+			processingErr.Message = "page limit reached before all papers were retrieved"
+			processingErr.Retryable = false
 		}
 		result.Status = StatusFailed
 		result.ErrorMessage = processingErr.Error()
@@ -163,12 +518,32 @@ func (vc *VectorCoordinator) processVectorization(ctx context.Context, traceID s
 	
 	result.TotalPapers = len(combinedTexts)
 	contextLogger.InfoWithCount("Retrieved papers for vectorization", result.TotalPapers, map[string]interface{}{
-		"status": result.Status,
+		"status":        result.Status,
+		"preprocessing": vc.preprocessing.Steps(),
 	})
+	for i := range combinedTexts {
+		combinedTexts[i] = vc.preprocessText(combinedTexts[i])
+	}
+	
+	// A trace far larger than expected points at a misconfigured query; fail before embedding it
+	if quotaErr := vc.paperQuotaError(result); quotaErr != nil {
+		result.Status = StatusQuotaExceeded
+		result.QuotaExceeded = true
+		result.ErrorMessage = quotaErr.Error()
+		result.ProcessingTimeMs = time.Since(startTime).Milliseconds()
+		contextLogger.Error("Paper quota exceeded, skipping vectorization", quotaErr, map[string]interface{}{
+			"total_papers": result.TotalPapers,
+			"max_papers":   vc.quotas.MaxPapersPerTrace,
+		})
+		return result, quotaErr
+	}
 	
 	// Handle case where no papers are found
 	if result.TotalPapers == 0 {
 		result.Status = StatusCompleted
+		if result.RetrievalTruncated {
+			result.Status = StatusPartial
+		}
 		result.ProcessingTimeMs = time.Since(startTime).Milliseconds()
 		contextLogger.Info("No papers found for vectorization - processing completed", map[string]interface{}{
 			"status": result.Status,
@@ -177,6 +552,9 @@ func (vc *VectorCoordinator) processVectorization(ctx context.Context, traceID s
 		return result, nil
 	}
 	
+	// Large runs wait for a cold embedding API so its start-up does not fail the first papers
+	vc.warmUpEmbeddingAPI(ctx, result)
+	
 	// Generate embeddings with progress tracking and error handling
 	contextLogger.Info("Starting embedding generation", map[string]interface{}{
 		"total_papers": result.TotalPapers,
@@ -184,22 +562,46 @@ func (vc *VectorCoordinator) processVectorization(ctx context.Context, traceID s
 	})
 	
 	vectorRecords := make([]storage.VectorRecord, 0, len(combinedTexts))
+	weightedRecords := make([]storage.VectorRecord, 0)
 	embeddingErrors := make([]error, 0)
+	paperDurations := make([]time.Duration, 0, len(combinedTexts))
+	embeddingStart := time.Now()
+	batcher := vc.newBatchEmbedder(combinedTexts)
+	// Papers are embedded one at a time; progress is logged as periodic snapshots, not per paper
+	progress := startProgress(ctx, vc.logger, len(combinedTexts), progressLogInterval)
 	
 	for i, combinedText := range combinedTexts {
-		embeddingStartTime := time.Now()
-		
-		// Log progress every 10 papers or at the end
-		if (i+1)%10 == 0 || i == len(combinedTexts)-1 {
-			contextLogger.Info("Embedding generation progress", map[string]interface{}{
-				"processed": i + 1,
-				"total":     len(combinedTexts),
-				"progress_percent": float64(i+1) / float64(len(combinedTexts)) * 100,
+		if reason := vc.haltReason(ctx); reason != "" {
+			result.Interrupted = true
+			result.Paused = reason == haltPaused
+			result.SkippedPapers = len(combinedTexts) - i
+			contextLogger.Warn(reason+", stopping embedding generation", map[string]interface{}{
+				"processed": i,
+				"skipped":   result.SkippedPapers,
 			})
+			break
 		}
+
+		texts := []string{combinedText.Text}
+		if vc.weighted.Enabled {
+			texts = append(texts, combinedText.Title, combinedText.Abstract)
+		}
+		if !vc.reserveEmbeddingChars(result, texts...) {
+			result.SkippedPapers = len(combinedTexts) - i
+			contextLogger.Warn("Embedding character quota reached, stopping embedding generation", map[string]interface{}{
+				"processed":      i,
+				"skipped":        result.SkippedPapers,
+				"embedded_chars": result.EmbeddedChars,
+				"max_chars":      vc.quotas.MaxEmbeddingCharsPerRun,
+			})
+			break
+		}
+
+		embeddingStartTime := time.Now()
+		progress.begin()
 		
 		// Generate embedding using the API client with error handling
-		embeddingResponse, err := vc.apiClient.GenerateEmbedding(ctx, combinedText.Text)
+		embeddingResponse, err := vc.embedPaper(ctx, batcher, i, combinedText, result)
 		if err != nil {
 			embeddingErr := &ProcessingError{
 				Stage:   "embedding_generation",
@@ -207,12 +609,15 @@ func (vc *VectorCoordinator) processVectorization(ctx context.Context, traceID s
 				Cause:   err,
 			}
 			embeddingErrors = append(embeddingErrors, embeddingErr)
-			result.FailedEmbeddings++
+			cause := recordEmbeddingFailure(result, combinedText.PaperID, storage.VectorTypeTitleAbstract, err)
 			
 			contextLogger.Error("Failed to generate embedding", embeddingErr, map[string]interface{}{
-				"paper_id": combinedText.PaperID,
-				"progress": fmt.Sprintf("%d/%d", i+1, len(combinedTexts)),
+				"paper_id":      combinedText.PaperID,
+				"progress":      fmt.Sprintf("%d/%d", i+1, len(combinedTexts)),
+				"failure_cause": cause,
 			})
+			paperDurations = append(paperDurations, time.Since(embeddingStartTime))
+			progress.finish(true)
 			// Continue with other papers instead of failing the entire batch
 			continue
 		}
@@ -228,9 +633,30 @@ func (vc *VectorCoordinator) processVectorization(ctx context.Context, traceID s
 			embeddingResponse.ModelVersion,
 			processingTimeMs,
 		)
+		recordProvenance(vectorRecord, embeddingResponse)
+		recordSource(vectorRecord, combinedText)
+		vc.recordPreprocessing(vectorRecord)
 		
 		vectorRecords = append(vectorRecords, *vectorRecord)
 		result.EmbeddingsGenerated++
+
+		// The weighted vector is additional, so its failure leaves the paper's main vector in place
+		if vc.weighted.Enabled {
+			weightedRecord, err := vc.generateWeightedRecord(ctx, combinedText, traceID)
+			if err != nil {
+				result.FailedWeightedEmbeddings++
+				addFailedItem(result, combinedText.PaperID, storage.VectorTypeWeighted, embeddingFailureCode(client.ClassifyFailure(err)), err.Error())
+				contextLogger.Warn("Failed to generate weighted embedding", map[string]interface{}{
+					"paper_id": combinedText.PaperID,
+					"error":    err.Error(),
+				})
+			} else {
+				weightedRecords = append(weightedRecords, *weightedRecord)
+				result.WeightedEmbeddings++
+			}
+		}
+		paperDurations = append(paperDurations, time.Since(embeddingStartTime))
+		progress.finish(false)
 		
 		contextLogger.Debug("Generated embedding", map[string]interface{}{
 			"paper_id":            combinedText.PaperID,
@@ -239,19 +665,65 @@ func (vc *VectorCoordinator) processVectorization(ctx context.Context, traceID s
 			"progress":            fmt.Sprintf("%d/%d", i+1, len(combinedTexts)),
 		})
 	}
+	progress.stop()
+	result.StageTimings[TimingEmbeddingMs] = time.Since(embeddingStart).Milliseconds()
+	batcher.recordBatchStats(result)
+	recordPaperPercentiles(result.StageTimings, paperDurations)
+
+	// Full-text chunks are only embedded for papers whose title/abstract vector succeeded,
+	// so a paper never has chunk vectors without its main vector
+	fullTextRecords := make([]storage.VectorRecord, 0)
+	if vc.fullTextRun && !result.Interrupted && !result.QuotaExceeded && len(vectorRecords) > 0 {
+		embedded := make([]retriever.CombinedText, 0, len(vectorRecords))
+		succeeded := make(map[string]bool, len(vectorRecords))
+		for _, record := range vectorRecords {
+			succeeded[record.PaperID] = true
+		}
+		for _, combinedText := range combinedTexts {
+			if succeeded[combinedText.PaperID] {
+				embedded = append(embedded, combinedText)
+			}
+		}
+		fullTextRecords = vc.generateFullTextRecords(ctx, embedded, traceID, result)
+	}
 	
 	contextLogger.InfoWithCount("Completed embedding generation", result.EmbeddingsGenerated, map[string]interface{}{
 		"total_papers":       result.TotalPapers,
 		"successful_embeddings": result.EmbeddingsGenerated,
 		"failed_embeddings":  result.FailedEmbeddings,
+		"failed_embeddings_by_cause": result.FailedEmbeddingsByCause,
 		"success_rate":       float64(result.EmbeddingsGenerated) / float64(result.TotalPapers) * 100,
+		"weighted_embeddings":        result.WeightedEmbeddings,
+		"failed_weighted_embeddings": result.FailedWeightedEmbeddings,
 	})
 	
+	// A quota reached before the first embedding leaves nothing to store
+	if len(vectorRecords) == 0 && result.QuotaExceeded {
+		quotaErr := vc.charQuotaError(result)
+		result.Status = StatusQuotaExceeded
+		result.ErrorMessage = quotaErr.Error()
+		result.ProcessingTimeMs = time.Since(startTime).Milliseconds()
+		return result, quotaErr
+	}
+
+	// A shutdown before the first embedding leaves nothing to store, which is not a failure
+	if len(vectorRecords) == 0 && result.Interrupted && result.FailedEmbeddings == 0 {
+		result.Status = StatusPartial
+		result.ErrorMessage = "shutdown requested before any embeddings were generated"
+		result.ProcessingTimeMs = time.Since(startTime).Milliseconds()
+		if result.Paused {
+			result.ErrorMessage = "vectorization paused before any embeddings were generated"
+			return result, pausedError(traceID)
+		}
+		return result, nil
+	}
+
 	// Check if we have any embeddings to store
 	if len(vectorRecords) == 0 {
 		processingErr := &ProcessingError{
-			Stage:   "embedding_generation",
-			Message: "no embeddings were generated successfully",
+			Stage:     "embedding_generation",
+			Message:   "no embeddings were generated successfully",
+			Retryable: !embeddingFailuresPermanent(result),
 		}
 		result.Status = StatusFailed
 		result.ErrorMessage = processingErr.Error()
@@ -259,18 +731,36 @@ func (vc *VectorCoordinator) processVectorization(ctx context.Context, traceID s
 		contextLogger.Error("No embeddings generated", processingErr, map[string]interface{}{
 			"total_papers": result.TotalPapers,
 			"failed_embeddings": result.FailedEmbeddings,
+			"failed_embeddings_by_cause": result.FailedEmbeddingsByCause,
 		})
 		return result, processingErr
 	}
 	
+	vectorRecords = append(vectorRecords, weightedRecords...)
+	vectorRecords = append(vectorRecords, fullTextRecords...)
+
+	// In validate mode, check the records but skip the DynamoDB write
+	if vc.validateOnly {
+		result.Validation = buildValidationReport(vectorRecords)
+		result.Status = StatusValidated
+		result.ProcessingTimeMs = time.Since(startTime).Milliseconds()
+		contextLogger.Info("Validation run completed, vector storage skipped", map[string]interface{}{
+			"validation": result.Validation,
+		})
+		return result, nil
+	}
+	
 	// Store vector records in batch with progress tracking
 	contextLogger.InfoWithCount("Starting vector storage", len(vectorRecords))
+	storageStart := time.Now()
 	batchResult, err := vc.vectorStorage.BatchStoreVectors(ctx, vectorRecords)
+	result.StageTimings[TimingStorageMs] = time.Since(storageStart).Milliseconds()
 	if err != nil {
 		processingErr := &ProcessingError{
-			Stage:   "vector_storage",
-			Message: "failed to store vector records",
-			Cause:   err,
+			Stage:     "vector_storage",
+			Message:   "failed to store vector records",
+			Cause:     err,
+			Retryable: true,
 		}
 		result.Status = StatusFailed
 		result.ErrorMessage = processingErr.Error()
@@ -279,20 +769,40 @@ func (vc *VectorCoordinator) processVectorization(ctx context.Context, traceID s
 		return result, processingErr
 	}
 	
-	// Update result with storage statistics
-	result.VectorsStored = batchResult.SuccessCount
-	result.FailedStorage = len(batchResult.FailedItems)
+	// Update result with storage statistics; weighted and full-text vectors are counted
+	// separately so the success rates stay per paper
+	weightedFailed, fullTextFailed := countExtraVectors(batchResult.FailedItems)
+	weightedSpooled, fullTextSpooled := countExtraVectors(batchResult.Spooled)
+	result.WeightedVectorsStored = len(weightedRecords) - weightedFailed - weightedSpooled
+	result.FullTextVectorsStored = len(fullTextRecords) - fullTextFailed - fullTextSpooled
+	result.VectorsStored = batchResult.SuccessCount - result.WeightedVectorsStored - result.FullTextVectorsStored
+	result.FailedStorage = len(batchResult.FailedItems) - weightedFailed - fullTextFailed
+	result.SpooledVectors = len(batchResult.Spooled)
+	result.SpoolKeys = batchResult.SpoolKeys
+	addStorageFailures(result, batchResult)
+	vc.verifyStored(ctx, vectorRecords, batchResult, result)
+	vc.markPapersReady(ctx, vectorRecords, batchResult, result)
+	result.ConsumedWriteCapacity = batchResult.ConsumedCapacity
+	result.StorageThrottleMs = batchResult.ThrottleWait.Milliseconds()
+	result.WriteChunks = batchResult.Chunks
 	result.ProcessingTimeMs = time.Since(startTime).Milliseconds()
 	
 	// Determine final status based on success/failure rates
-	if result.FailedEmbeddings == 0 && result.FailedStorage == 0 {
+	if result.FailedEmbeddings == 0 && result.FailedStorage == 0 && result.PendingPapers == 0 && !result.Interrupted && !result.RetrievalTruncated {
 		result.Status = StatusCompleted
-	} else if result.VectorsStored > 0 {
+		if result.SpooledVectors > 0 {
+			result.Status = StatusPartialSpooled
+		}
+	} else if result.VectorsStored > 0 || result.SpooledVectors > 0 {
 		result.Status = StatusPartial
 	} else {
 		result.Status = StatusFailed
 		result.ErrorMessage = "all vectorization operations failed"
 	}
+	if result.QuotaExceeded && result.Status != StatusFailed {
+		result.Status = StatusQuotaExceeded
+		result.ErrorMessage = vc.charQuotaError(result).Error()
+	}
 	
 	// Log comprehensive final results
 	contextLogger.InfoWithCount("Vectorization processing completed", result.VectorsStored, map[string]interface{}{
@@ -302,7 +812,12 @@ func (vc *VectorCoordinator) processVectorization(ctx context.Context, traceID s
 		"vectors_stored":       result.VectorsStored,
 		"failed_embeddings":    result.FailedEmbeddings,
 		"failed_storage":       result.FailedStorage,
+		"ready_papers":         result.ReadyPapers,
+		"pending_papers":       result.PendingPapers,
+		"spooled_vectors":      result.SpooledVectors,
+		"embedded_chars":       result.EmbeddedChars,
 		"processing_time_ms":   result.ProcessingTimeMs,
+		"stage_timings":        result.StageTimings,
 		"embedding_success_rate": float64(result.EmbeddingsGenerated) / float64(result.TotalPapers) * 100,
 		"storage_success_rate":   float64(result.VectorsStored) / float64(result.EmbeddingsGenerated) * 100,
 		"overall_success_rate":   float64(result.VectorsStored) / float64(result.TotalPapers) * 100,
@@ -313,13 +828,15 @@ func (vc *VectorCoordinator) processVectorization(ctx context.Context, traceID s
 		contextLogger.Warn("Some embeddings failed to generate", map[string]interface{}{
 			"failed_count": result.FailedEmbeddings,
 			"total_count":  result.TotalPapers,
+			"by_cause":     result.FailedEmbeddingsByCause,
 		})
 	}
 	
 	if result.FailedStorage > 0 {
 		contextLogger.Warn("Some vector records failed to store", map[string]interface{}{
-			"failed_count": result.FailedStorage,
-			"total_count":  result.EmbeddingsGenerated,
+			"failed_count":  result.FailedStorage,
+			"total_count":   result.EmbeddingsGenerated,
+			"failed_chunks": failedChunkIndexes(result.WriteChunks),
 		})
 		
 		// Log details of storage errors
@@ -331,23 +848,46 @@ func (vc *VectorCoordinator) processVectorization(ctx context.Context, traceID s
 		}
 	}
 	
+	if result.SpooledVectors > 0 {
+		contextLogger.Warn("Vector records spooled to S3 while the vectors table was throttled", map[string]interface{}{
+			"spooled_vectors": result.SpooledVectors,
+			"spooled_papers":  result.SpooledPapers,
+			"spool_keys":      result.SpoolKeys,
+		})
+	}
+
 	// Log system metrics for monitoring
 	vc.logSystemMetrics(ctx, result)
 	
-	// Return error for failures (Step Function will handle retries)
+	// Return error for failures (Step Function will handle retries). Storage failures and
+	// papers left pending are always worth a retry; embedding failures only when some were not caused by invalid input.
+	retryable := result.FailedStorage > 0 || result.PendingPapers > 0 || !embeddingFailuresPermanent(result)
 	if result.Status == StatusFailed {
 		return result, &ProcessingError{
-			Stage:   "overall_processing",
-			Message: fmt.Sprintf("vectorization failed for traceID %s: %s", traceID, result.ErrorMessage),
+			Stage:     "overall_processing",
+			Message:   fmt.Sprintf("vectorization failed for traceID %s: %s", traceID, result.ErrorMessage),
+			Retryable: retryable,
 		}
 	}
 	
-	// Also return error for partial failures to let Step Function decide on retry
-	if result.Status == StatusPartial {
+	// The vectors generated before the quota was reached are stored, as with a pause
+	if result.QuotaExceeded {
+		return result, vc.charQuotaError(result)
+	}
+
+	// The vectors generated before a pause are stored; the run still stops the execution
+	if result.Paused {
+		return result, pausedError(traceID)
+	}
+
+	// Also return error for partial failures to let Step Function decide on retry;
+	// a drain on shutdown is reported through Interrupted instead
+	if result.Status == StatusPartial && (result.FailedEmbeddings > 0 || result.FailedStorage > 0 || result.PendingPapers > 0) {
 		return result, &ProcessingError{
-			Stage:   "partial_processing",
-			Message: fmt.Sprintf("partial vectorization failure for traceID %s: %d/%d papers processed successfully", 
+			Stage:     "partial_processing",
+			Message:   fmt.Sprintf("partial vectorization failure for traceID %s: %d/%d papers processed successfully", 
 				traceID, result.VectorsStored, result.TotalPapers),
+			Retryable: retryable,
 		}
 	}
 	
@@ -355,10 +895,163 @@ func (vc *VectorCoordinator) processVectorization(ctx context.Context, traceID s
 }
 
 
+// generateWeightedRecord embeds the title and abstract separately and stores their weighted
+// combination. Fields that are empty or weighted zero are skipped and the weights renormalize.
+func (vc *VectorCoordinator) generateWeightedRecord(ctx context.Context, combinedText retriever.CombinedText, traceID string) (*storage.VectorRecord, error) {
+	startTime := time.Now()
+	fieldTexts := map[string]string{
+		"title":    combinedText.Title,
+		"abstract": combinedText.Abstract,
+	}
+
+	embeddings := make(map[string][]float64)
+	weights := make(map[string]float64)
+	modelVersion := ""
+	var truncation *client.Truncation // the most heavily truncated field, if any
+	for _, field := range config.WeightedEmbeddingFields {
+		weight := vc.weighted.Weights[field]
+		if weight <= 0 || fieldTexts[field] == "" {
+			continue
+		}
+		response, err := vc.apiClient.GenerateEmbedding(ctx, fieldTexts[field])
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed %s: %w", field, err)
+		}
+		if modelVersion != "" && response.ModelVersion != modelVersion {
+			return nil, fmt.Errorf("field embeddings came from different models: %s and %s", modelVersion, response.ModelVersion)
+		}
+		modelVersion = response.ModelVersion
+		embeddings[field] = response.Embedding
+		weights[field] = weight
+		if response.Truncation != nil && (truncation == nil || response.Truncation.Ratio < truncation.Ratio) {
+			truncation = response.Truncation
+		}
+	}
+
+	embedding, err := storage.ComposeWeightedEmbedding(embeddings, weights)
+	if err != nil {
+		return nil, err
+	}
+
+	record := storage.CreateWeightedVectorRecord(
+		combinedText.PaperID,
+		combinedText.Text,
+		traceID,
+		embedding,
+		modelVersion,
+		weights,
+		time.Since(startTime).Milliseconds(),
+	)
+	recordProvenance(record, &client.EmbeddingResponse{Truncation: truncation})
+	recordSource(record, combinedText)
+	vc.recordPreprocessing(record)
+	return record, nil
+}
+
+// Reasons for stopping embedding generation early, used as log message prefixes
+const (
+	haltShutdown = "Shutdown requested"
+	haltPaused   = "Vectorization paused"
+)
+
+// haltReason reports why embedding generation should stop, or "" to continue
+func (vc *VectorCoordinator) haltReason(ctx context.Context) string {
+	if vc.shutdownRequested() {
+		return haltShutdown
+	}
+	err := vc.pause.Check(ctx, pauseflags.Vectorization)
+	if errors.Is(err, pauseflags.ErrPaused) {
+		return haltPaused
+	}
+	if err != nil {
+		vc.logger.WithContext(ctx).Warn("Failed to read pause flags, continuing", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	return ""
+}
+
+// pausedError reports a run stopped by the vectorization pause flag
+func pausedError(traceID string) *ProcessingError {
+	return &ProcessingError{
+		Stage:   "paused",
+		Message: fmt.Sprintf("vectorization paused during traceID %s", traceID),
+		Cause:   pauseflags.ErrPaused,
+	}
+}
+
+// shutdownRequested reports whether the shutdown channel has been closed
+func (vc *VectorCoordinator) shutdownRequested() bool {
+	if vc.shutdown == nil {
+		return false
+	}
+	select {
+	case <-vc.shutdown:
+		return true
+	default:
+		return false
+	}
+}
+
+// buildValidationReport validates the records that would have been stored
+func buildValidationReport(records []storage.VectorRecord) *ValidationReport {
+	report := &ValidationReport{
+		Mode:          "validate",
+		SkippedWrites: []string{"dynamodb:BatchWriteItem"},
+	}
+	for i := range records {
+		if err := storage.ValidateVectorRecord(&records[i]); err != nil {
+			report.InvalidRecords++
+			report.ValidationErrors = append(report.ValidationErrors, fmt.Sprintf("%s: %v", records[i].PaperID, err))
+			continue
+		}
+		report.RecordsToStore++
+	}
+	return report
+}
+
+// recordProvenance copies the embedding's provider, and how its input was truncated, onto
+// the vector record it produced
+func recordProvenance(record *storage.VectorRecord, response *client.EmbeddingResponse) {
+	if response.Provenance != nil {
+		record.EmbeddingMetadata.Provider = response.Provenance.Provider
+	}
+	if response.Truncation != nil {
+		record.EmbeddingMetadata.TruncationRatio = response.Truncation.Ratio
+		record.EmbeddingMetadata.TruncationBoundary = response.Truncation.Boundary
+	}
+}
+
+// recordSource stamps the record with the raw-data object, collection run and pipeline
+// timestamps of its paper and the coordinator build that produced it, so any vector can be
+// traced to its code and input
+func recordSource(record *storage.VectorRecord, combinedText retriever.CombinedText) {
+	record.ProcessingInfo.SourceS3Key = combinedText.SourceS3Key
+	record.ProcessingInfo.CollectionRunID = combinedText.CollectionRunID
+	record.ProcessingInfo.CollectedAt = combinedText.CollectedAt
+	record.ProcessingInfo.IngestedAt = combinedText.IngestedAt
+	record.ProcessingInfo.CoordinatorVersion = version
+	record.ProcessingInfo.CoordinatorCommit = commit
+}
+
+// preprocessText applies the preprocessing steps to the combined text and to the fields
+// embedded separately for the weighted vector
+func (vc *VectorCoordinator) preprocessText(combinedText retriever.CombinedText) retriever.CombinedText {
+	combinedText.Text = vc.preprocessing.Apply(combinedText.Text)
+	combinedText.Title = vc.preprocessing.Apply(combinedText.Title)
+	combinedText.Abstract = vc.preprocessing.Apply(combinedText.Abstract)
+	return combinedText
+}
+
+// recordPreprocessing appends the applied preprocessing steps to the record's preprocessing,
+// so experiments on preprocessing can tell their vectors apart and reproduce them
+func (vc *VectorCoordinator) recordPreprocessing(record *storage.VectorRecord) {
+	record.EmbeddingMetadata.Preprocessing = vc.preprocessing.Describe(record.EmbeddingMetadata.Preprocessing)
+}
 
 // logSystemMetrics logs system-level metrics for monitoring
 func (vc *VectorCoordinator) logSystemMetrics(ctx context.Context, result *ProcessingResult) {
-	contextLogger := vc.logger.WithContext(ctx).WithTraceID(result.TraceID)
+	contextLogger := vc.logger.WithContext(ctx)
 	
 	// Log processing metrics
 	contextLogger.Info("Processing metrics", map[string]interface{}{
@@ -368,8 +1061,10 @@ func (vc *VectorCoordinator) logSystemMetrics(ctx context.Context, result *Proce
 		"embeddings_generated": result.EmbeddingsGenerated,
 		"vectors_stored":       result.VectorsStored,
 		"failed_embeddings":    result.FailedEmbeddings,
+		"failed_embeddings_by_cause": result.FailedEmbeddingsByCause,
 		"failed_storage":       result.FailedStorage,
 		"processing_time_ms":   result.ProcessingTimeMs,
+		"stage_timings":        result.StageTimings,
 		"status":               result.Status,
 	})
 	
@@ -408,6 +1103,17 @@ func (vc *VectorCoordinator) logSystemMetrics(ctx context.Context, result *Proce
 		})
 	}
 	
+	// Log write capacity consumed by vector storage, and how long adaptive pacing held writes back
+	if result.ConsumedWriteCapacity > 0 {
+		contextLogger.Info("Vector storage write capacity", map[string]interface{}{
+			"metric_type":         "capacity",
+			"metric_name":         "vector_write_capacity_units",
+			"value":               result.ConsumedWriteCapacity,
+			"storage_ms":          result.StageTimings[TimingStorageMs],
+			"storage_throttle_ms": result.StorageThrottleMs,
+		})
+	}
+	
 	// Log processing throughput
 	if result.ProcessingTimeMs > 0 {
 		throughputPerSecond := float64(result.VectorsStored) / (float64(result.ProcessingTimeMs) / 1000.0)
@@ -421,6 +1127,44 @@ func (vc *VectorCoordinator) logSystemMetrics(ctx context.Context, result *Proce
 	}
 }
 
+// loadCoordinatorConfig loads table, index and key names and the vectorization settings from the
+// shared pipeline configuration, in S3 (CONFIG_BUCKET/CONFIG_KEY) or in the SSM parameter
+// CONFIG_SSM_PARAMETER, falling back to defaults. The PAPERS_TABLE_NAME, VECTORS_TABLE_NAME and
+// TRACE_ID_INDEX_NAME environment variables still override the configured names.
+func loadCoordinatorConfig(ctx context.Context) (*config.Config, error) {
+	cfg := config.GetDefaultConfig()
+	bucket, key := os.Getenv("CONFIG_BUCKET"), os.Getenv("CONFIG_KEY")
+	parameter := os.Getenv("CONFIG_SSM_PARAMETER")
+	if (bucket != "" && key != "") || parameter != "" {
+		configManager, err := config.NewManager()
+		if err != nil {
+			return nil, err
+		}
+		if bucket != "" && key != "" {
+			cfg, err = configManager.LoadFromS3(ctx, bucket, key)
+		} else {
+			cfg, err = configManager.LoadFromSSM(ctx, parameter)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	dynamoConfig := &cfg.AWS.DynamoDB
+	dynamoConfig.PapersTable = getEnvOrDefault("PAPERS_TABLE_NAME", dynamoConfig.PapersTable)
+	dynamoConfig.VectorsTable = getEnvOrDefault("VECTORS_TABLE_NAME", dynamoConfig.VectorsTable)
+	dynamoConfig.TraceIDIndex = getEnvOrDefault("TRACE_ID_INDEX_NAME", dynamoConfig.TraceIDIndex)
+	dynamoConfig.VectorWriteSpool.Bucket = getEnvOrDefault("VECTOR_SPOOL_BUCKET", dynamoConfig.VectorWriteSpool.Bucket)
+	if err := dynamoConfig.Validate(); err != nil {
+		return nil, err
+	}
+	// Without a sort key the weighted vector would overwrite the paper's main vector
+	if cfg.Vectorization.WeightedEmbedding.Enabled && dynamoConfig.VectorKeys.SortKey == "" {
+		return nil, fmt.Errorf("vectorization.weighted_embedding requires a vectors table sort key")
+	}
+	return cfg, nil
+}
+
 func getEnvOrDefault(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
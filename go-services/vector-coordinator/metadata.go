@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"vector-coordinator/storage"
+)
+
+// metadataCommand is the local subcommand that prints the stored vectors' metadata
+const metadataCommand = "metadata"
+
+// runMetadata prints the embedding metadata and processing info of each paper's stored
+// vectors, read without their embeddings
+func runMetadata(args []string) error {
+	if len(args) == 0 {
+		fmt.Println("Usage: vector-coordinator metadata <paper-id> [paper-id ...]")
+		return nil
+	}
+
+	ctx := context.Background()
+	cfg, err := loadCoordinatorConfig(ctx)
+	if err != nil {
+		return err
+	}
+	dynamoConfig := cfg.AWS.DynamoDB
+	vectorStorage := storage.NewVectorStorage(dynamoConfig.VectorsTable, dynamoConfig.Client)
+	if err := vectorStorage.SetKeySchema(dynamoConfig.VectorKeys.PartitionKey, dynamoConfig.VectorKeys.SortKey); err != nil {
+		return err
+	}
+
+	metadata := make(map[string][]storage.VectorMetadata, len(args))
+	for _, paperID := range args {
+		vectors, err := vectorStorage.GetVectorMetadata(ctx, paperID)
+		if err != nil {
+			return err
+		}
+		metadata[paperID] = vectors
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(metadata)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/s3"
+
+	"shared/awsclient"
+	"shared/failures"
+	"shared/preflight"
+	"vector-coordinator/retriever"
+)
+
+// preflightGuard verifies the coordinator's resources on the first invocation of a container
+var preflightGuard preflight.Guard
+
+// runPreflight checks that the papers table and its trace index (keyed on trace_id and
+// batch_timestamp), the vectors table, the embedding API, the optional FailedItems table,
+// the ProcessingRuns table when the backfill check reads it, the spool bucket when vector
+// writes spool and, for full-text runs, the full-text bucket exist and answer. The embedding API only has
+// to respond: a model that is still loading is left to the warm-up.
+func runPreflight(ctx context.Context, settings componentSettings, components *coordinatorComponents, fullText bool) error {
+	sess := awsclient.MustClientSession(settings.DynamoDB.Client)
+	dynamoClient := dynamodb.New(sess)
+	checks := []preflight.Check{
+		preflight.TableWithIndexes(dynamoClient, settings.DynamoDB.PapersTable, preflight.Index{
+			Name:         settings.DynamoDB.TraceIDIndex,
+			PartitionKey: retriever.TraceIDIndexPartitionKey,
+			SortKey:      retriever.TraceIDIndexSortKey,
+		}),
+		preflight.Table(dynamoClient, settings.DynamoDB.VectorsTable),
+	}
+	if checker, ok := components.apiClient.(HealthChecker); ok {
+		checks = append(checks, preflight.Check{
+			Resource: "embedding api " + settings.EmbeddingAPIURL,
+			Verify: func(ctx context.Context) error {
+				_, err := checker.CheckHealth(ctx)
+				return err
+			},
+		})
+	}
+	if settings.Retrieval.BackfillCheck.Enabled && settings.RunsTable != "" {
+		checks = append(checks, preflight.Table(dynamoClient, settings.RunsTable))
+	}
+	if fullText {
+		checks = append(checks, preflight.Bucket(s3.New(sess), settings.FullTextBucket))
+	}
+	if spool := settings.DynamoDB.VectorWriteSpool; spool.Enabled {
+		checks = append(checks, preflight.Bucket(s3.New(sess), spool.Bucket))
+	}
+	if failedTable := os.Getenv(failures.TableEnv); failedTable != "" {
+		checks = append(checks, preflight.TableWithIndexes(dynamoClient, failedTable, preflight.Index{
+			Name:         failures.CodeIndex,
+			PartitionKey: failures.CodeIndexPartitionKey,
+			SortKey:      failures.CodeIndexSortKey,
+		}))
+	}
+
+	if err := preflightGuard.Run(ctx, checks); err != nil {
+		return &ProcessingError{
+			Stage:   "preflight",
+			Message: "required resources are missing or unreachable",
+			Cause:   err,
+		}
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package preprocess
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Preprocessing steps, applied to texts before they are embedded in the configured order
+const (
+	StepLowercase     = "lowercase"
+	StepStripLaTeX    = "strip_latex"    // math delimiters and commands, keeping command arguments
+	StepTrimStopwords = "trim_stopwords" // common English function words
+	StepRemoveURLs    = "remove_urls"
+)
+
+// Steps lists the accepted step names
+var Steps = []string{StepLowercase, StepStripLaTeX, StepTrimStopwords, StepRemoveURLs}
+
+var stepFuncs = map[string]func(string) string{
+	StepLowercase:     strings.ToLower,
+	StepStripLaTeX:    stripLaTeX,
+	StepTrimStopwords: trimStopwords,
+	StepRemoveURLs:    removeURLs,
+}
+
+// Chain is an ordered list of preprocessing steps. A nil or empty chain leaves texts unchanged.
+type Chain struct {
+	steps []string
+}
+
+// New builds the chain of steps, rejecting unknown and repeated step names
+func New(steps []string) (*Chain, error) {
+	seen := make(map[string]bool, len(steps))
+	for _, step := range steps {
+		if _, ok := stepFuncs[step]; !ok {
+			return nil, fmt.Errorf("unknown preprocessing step %q (expected one of %v)", step, Steps)
+		}
+		if seen[step] {
+			return nil, fmt.Errorf("preprocessing step %q is listed twice", step)
+		}
+		seen[step] = true
+	}
+	return &Chain{steps: append([]string(nil), steps...)}, nil
+}
+
+// Steps returns the step names in the order they are applied
+func (c *Chain) Steps() []string {
+	if c == nil {
+		return nil
+	}
+	return append([]string(nil), c.steps...)
+}
+
+// Apply runs every step over text and collapses the whitespace the steps leave behind
+func (c *Chain) Apply(text string) string {
+	if c == nil || len(c.steps) == 0 {
+		return text
+	}
+	for _, step := range c.steps {
+		text = stepFuncs[step](text)
+	}
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// Describe appends the applied steps to base, the preprocessing a record's text went through
+// to be assembled (e.g. "title_abstract_combination+lowercase+strip_latex"), so the exact
+// preprocessing of every vector can be reproduced
+func (c *Chain) Describe(base string) string {
+	if c == nil || len(c.steps) == 0 {
+		return base
+	}
+	return base + "+" + strings.Join(c.steps, "+")
+}
+
+var (
+	urlPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)\S+`)
+
+	// Commands whose argument is a reference, not prose
+	latexReferencePattern = regexp.MustCompile(`\\(?:cite[a-z]*|ref|eqref|label|url)\*?(?:\[[^\]]*\])?\{[^{}]*\}`)
+	// A command with a braced argument, e.g. \emph{word}; the argument is kept
+	latexArgumentPattern = regexp.MustCompile(`\\[a-zA-Z]+\*?(?:\[[^\]]*\])?\{([^{}]*)\}`)
+	// A command without an argument, e.g. \alpha; the name is kept as a word
+	latexCommandPattern = regexp.MustCompile(`\\([a-zA-Z]+)\*?`)
+	// An escaped character, e.g. \% or \&
+	latexEscapePattern = regexp.MustCompile(`\\([^a-zA-Z])`)
+)
+
+// removeURLs drops http(s) and www. links
+func removeURLs(text string) string {
+	return urlPattern.ReplaceAllString(text, " ")
+}
+
+// stripLaTeX reduces LaTeX markup to its words: references are dropped, command arguments
+// and names are kept, and math delimiters, braces and subscript markers are removed
+func stripLaTeX(text string) string {
+	text = latexReferencePattern.ReplaceAllString(text, " ")
+	// Unwrap nested commands from the inside out
+	for {
+		unwrapped := latexArgumentPattern.ReplaceAllString(text, "$1")
+		if unwrapped == text {
+			break
+		}
+		text = unwrapped
+	}
+	text = latexCommandPattern.ReplaceAllString(text, " $1 ")
+	text = latexEscapePattern.ReplaceAllString(text, "$1")
+	return strings.NewReplacer("$", " ", "{", "", "}", "", "^", " ", "_", " ", "~", " ").Replace(text)
+}
+
+// stopwords are the English function words trim_stopwords removes
+var stopwords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true,
+	"by": true, "for": true, "from": true, "has": true, "have": true, "in": true, "is": true,
+	"it": true, "its": true, "of": true, "on": true, "or": true, "that": true, "the": true,
+	"this": true, "these": true, "to": true, "was": true, "we": true, "were": true,
+	"which": true, "with": true, "our": true, "can": true, "into": true, "than": true,
+}
+
+// trimStopwords drops stopwords, compared case-insensitively without surrounding punctuation
+func trimStopwords(text string) string {
+	words := strings.Fields(text)
+	kept := words[:0]
+	for _, word := range words {
+		bare := strings.TrimFunc(word, func(r rune) bool {
+			return unicode.IsPunct(r) || unicode.IsSymbol(r)
+		})
+		if !stopwords[strings.ToLower(bare)] {
+			kept = append(kept, word)
+		}
+	}
+	return strings.Join(kept, " ")
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"shared/logger"
+)
+
+// progressLogInterval is how often a running embedding loop logs a progress snapshot
+const progressLogInterval = 10 * time.Second
+
+// progressTracker counts the papers of an embedding loop with atomic counters, which its
+// logging goroutine reads while the loop updates them, and logs consolidated snapshots at a
+// fixed interval instead of each paper logging its own position
+type progressTracker struct {
+	total     int
+	processed atomic.Int64 // papers finished, failed ones included
+	failed    atomic.Int64
+	inFlight  atomic.Int64
+	started   time.Time
+	logger    *logger.Logger
+	stopCh    chan struct{}
+	stopOnce  sync.Once
+	done      chan struct{}
+}
+
+// startProgress starts logging snapshots of a loop over total papers every interval until stop
+func startProgress(ctx context.Context, log *logger.Logger, total int, interval time.Duration) *progressTracker {
+	p := &progressTracker{
+		total:   total,
+		started: time.Now(),
+		logger:  log.WithContext(ctx),
+		stopCh:  make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	go func() {
+		defer close(p.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				p.log(false)
+			case <-p.stopCh:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return p
+}
+
+// begin marks a paper as in flight
+func (p *progressTracker) begin() {
+	p.inFlight.Add(1)
+}
+
+// finish marks an in-flight paper as done, failed or not
+func (p *progressTracker) finish(failed bool) {
+	if failed {
+		p.failed.Add(1)
+	}
+	p.processed.Add(1)
+	p.inFlight.Add(-1)
+}
+
+// stop ends the periodic snapshots and logs the final one
+func (p *progressTracker) stop() {
+	p.stopOnce.Do(func() {
+		close(p.stopCh)
+		<-p.done
+		p.log(true)
+	})
+}
+
+// log writes one snapshot of the counters; the final one is written once the loop has ended
+func (p *progressTracker) log(final bool) {
+	processed := p.processed.Load()
+	fields := map[string]interface{}{
+		"processed": processed,
+		"failed":    p.failed.Load(),
+		"in_flight": p.inFlight.Load(),
+		"total":     p.total,
+		"final":     final,
+	}
+	if p.total > 0 {
+		fields["progress_percent"] = float64(processed) / float64(p.total) * 100
+	}
+	if elapsed := time.Since(p.started).Seconds(); elapsed > 0 {
+		fields["papers_per_second"] = float64(processed) / elapsed
+	}
+	p.logger.Info("Embedding generation progress", fields)
+}
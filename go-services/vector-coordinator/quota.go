@@ -0,0 +1,54 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf8"
+
+	"shared/logger"
+)
+
+// errQuotaExceeded stops full-text embedding once the run's character quota is spent
+var errQuotaExceeded = errors.New("embedding character quota exceeded")
+
+// paperQuotaError reports a trace that retrieved more papers than max_papers_per_trace
+// allows, or nil when it is within the quota
+func (vc *VectorCoordinator) paperQuotaError(result *ProcessingResult) *ProcessingError {
+	limit := vc.quotas.MaxPapersPerTrace
+	if limit == 0 || result.TotalPapers <= limit {
+		return nil
+	}
+	message := fmt.Sprintf("trace %s retrieved %d papers, more than the quota of %d", result.TraceID, result.TotalPapers, limit)
+	return &ProcessingError{
+		Stage:   "quota",
+		Message: message,
+		Cause:   logger.NewAppError(logger.ErrorTypeQuota, message, nil),
+	}
+}
+
+// reserveEmbeddingChars adds the characters of texts to the run's embedded total. It adds
+// nothing and returns false when they would take the total past max_embedding_chars_per_run.
+func (vc *VectorCoordinator) reserveEmbeddingChars(result *ProcessingResult, texts ...string) bool {
+	chars := 0
+	for _, text := range texts {
+		chars += utf8.RuneCountInString(text)
+	}
+	if limit := vc.quotas.MaxEmbeddingCharsPerRun; limit > 0 && result.EmbeddedChars+chars > limit {
+		result.QuotaExceeded = true
+		return false
+	}
+	result.EmbeddedChars += chars
+	return true
+}
+
+// charQuotaError reports a run stopped by max_embedding_chars_per_run. The vectors generated
+// before the stop are stored; retrying would only spend the quota again, so it is terminal.
+func (vc *VectorCoordinator) charQuotaError(result *ProcessingResult) *ProcessingError {
+	message := fmt.Sprintf("trace %s reached the quota of %d embedded characters with %d papers skipped",
+		result.TraceID, vc.quotas.MaxEmbeddingCharsPerRun, result.SkippedPapers)
+	return &ProcessingError{
+		Stage:   "quota",
+		Message: message,
+		Cause:   logger.NewAppError(logger.ErrorTypeQuota, message, nil),
+	}
+}
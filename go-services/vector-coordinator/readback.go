@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+
+	"shared/failures"
+	"vector-coordinator/storage"
+)
+
+// ReadBackReport records the stored vectors a run read back and the ones that differed from
+// what was written
+type ReadBackReport struct {
+	Sampled    int                        `json:"sampled"`
+	Mismatches []storage.ReadBackMismatch `json:"mismatches,omitempty"`
+	Error      string                     `json:"error,omitempty"` // set when a read-back could not be made
+}
+
+// verifyStored reads a random sample of the records a write stored back from the vectors
+// table, before they are marked ready, to catch marshaling bugs that would corrupt the table
+// silently. A mismatched record is added to the batch's failed items, so markPapersReady
+// keeps its paper pending and out of search, and noted as an integrity failure. A read-back
+// that fails is logged and does not hold any paper back.
+func (vc *VectorCoordinator) verifyStored(ctx context.Context, records []storage.VectorRecord, batchResult *storage.BatchWriteResult, result *ProcessingResult) {
+	if !vc.readBack.Enabled {
+		return
+	}
+	contextLogger := vc.logger.WithContext(ctx)
+
+	unstored := make(map[string]bool)
+	for _, record := range batchResult.FailedItems {
+		unstored[record.PaperID+"#"+record.VectorType] = true
+	}
+	for _, record := range batchResult.Spooled {
+		unstored[record.PaperID+"#"+record.VectorType] = true
+	}
+	stored := make([]storage.VectorRecord, 0, len(records))
+	for _, record := range records {
+		if !unstored[record.PaperID+"#"+record.VectorType] {
+			stored = append(stored, record)
+		}
+	}
+	rand.Shuffle(len(stored), func(i, j int) { stored[i], stored[j] = stored[j], stored[i] })
+	if len(stored) > vc.readBack.SampleSize {
+		stored = stored[:vc.readBack.SampleSize]
+	}
+	if len(stored) == 0 {
+		return
+	}
+
+	if result.ReadBack == nil {
+		result.ReadBack = &ReadBackReport{}
+	}
+	report := result.ReadBack
+	mismatches, err := vc.vectorStorage.ReadBack(ctx, stored)
+	if err != nil {
+		report.Error = err.Error()
+		contextLogger.Warn("Failed to read back stored vectors", map[string]interface{}{
+			"sample_size": len(stored),
+			"error":       err.Error(),
+		})
+		return
+	}
+	report.Sampled += len(stored)
+	report.Mismatches = append(report.Mismatches, mismatches...)
+
+	sampled := make(map[string]storage.VectorRecord, len(stored))
+	for _, record := range stored {
+		sampled[record.PaperID+"#"+record.VectorType] = record
+	}
+	for _, mismatch := range mismatches {
+		batchResult.FailedItems = append(batchResult.FailedItems, sampled[mismatch.PaperID+"#"+mismatch.VectorType])
+		addFailedItem(result, mismatch.PaperID, mismatch.VectorType, failures.CodeIntegrity, "stored vector does not match the written one: "+mismatch.Reason)
+	}
+
+	contextLogger.Info("Stored vectors read back", map[string]interface{}{
+		"metric_type": "storage",
+		"metric_name": "vector_readback_mismatches",
+		"value":       len(mismatches),
+		"sampled":     len(stored),
+	})
+	if len(mismatches) > 0 {
+		contextLogger.Error("Stored vectors do not match what was written", fmt.Errorf("%d of %d sampled vectors differ, first: %s/%s: %s",
+			len(mismatches), len(stored), mismatches[0].PaperID, mismatches[0].VectorType, mismatches[0].Reason))
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"shared/failures"
+	"vector-coordinator/storage"
+)
+
+// markPapersReady flips the records of every paper whose records all stored to ready, the
+// second phase of the vector write. A paper with any record that failed to store keeps all
+// its records pending, hidden from search, until a retry writes them again. Each paper made
+// ready adds its end-to-end latency to the result's freshness report.
+func (vc *VectorCoordinator) markPapersReady(ctx context.Context, records []storage.VectorRecord, batchResult *storage.BatchWriteResult, result *ProcessingResult) {
+	start := time.Now()
+	defer func() {
+		result.StageTimings[TimingReadyMs] = time.Since(start).Milliseconds()
+	}()
+
+	pending := make(map[string]bool)
+	for _, failed := range batchResult.FailedItems {
+		pending[failed.PaperID] = true
+	}
+	// Spooled papers stay pending until a drain stores and marks them
+	spooled := make(map[string]bool)
+	for _, record := range batchResult.Spooled {
+		if !pending[record.PaperID] {
+			spooled[record.PaperID] = true
+		}
+	}
+	papers := make(map[string]bool)
+	ready := make([]storage.VectorRecord, 0, len(records))
+	for _, record := range records {
+		papers[record.PaperID] = true
+		if !pending[record.PaperID] && !spooled[record.PaperID] {
+			ready = append(ready, record)
+		}
+	}
+
+	readyResult, err := vc.vectorStorage.MarkReady(ctx, ready)
+	if err != nil {
+		for _, record := range ready {
+			pending[record.PaperID] = true
+		}
+		vc.logger.WithContext(ctx).Error("Failed to mark vectors ready", err)
+	} else {
+		for i, failed := range readyResult.FailedItems {
+			pending[failed.PaperID] = true
+			addFailedItem(result, failed.PaperID, failed.VectorType, failures.CodeStoreWrite, readyResult.Errors[i].Error())
+		}
+	}
+
+	result.PendingPapers = len(pending)
+	result.SpooledPapers = len(spooled)
+	result.ReadyPapers = len(papers) - len(pending) - len(spooled)
+	recordFreshness(ready, pending, result)
+}
+
+// recordFreshness measures the end-to-end latency of every paper in ready that was not left
+// pending, from the first of its records
+func recordFreshness(ready []storage.VectorRecord, pending map[string]bool, result *ProcessingResult) {
+	readyAt := time.Now()
+	measured := make(map[string]bool)
+	for _, record := range ready {
+		if pending[record.PaperID] || measured[record.PaperID] {
+			continue
+		}
+		measured[record.PaperID] = true
+		latency, ok := measurePaperLatency(record, readyAt)
+		if !ok {
+			continue
+		}
+		if result.Freshness == nil {
+			result.Freshness = &FreshnessReport{}
+		}
+		result.Freshness.add(latency)
+	}
+	if result.Freshness != nil {
+		result.Freshness.summarize()
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"shared/logger"
+	"shared/shutdown"
+	"vector-coordinator/client"
+	"vector-coordinator/retriever"
+	"vector-coordinator/storage"
+)
+
+// reembedCommand is the local subcommand that re-embeds a single paper
+const reembedCommand = "reembed"
+
+// runReembed re-embeds the paper given on the command line
+func runReembed(args []string, watcher *shutdown.Watcher) {
+	flags := flag.NewFlagSet(reembedCommand, flag.ExitOnError)
+	model := flags.String("model", "", "embedding model to use, one of EMBEDDING_MODEL_URLS (default: EMBEDDING_API_URL)")
+	vectorType := flags.String("vector-type", storage.VectorTypeTitleAbstract, "vector type to regenerate: title_abstract, weighted_title_abstract or full_text")
+	validate := flags.Bool("validate", false, "generate the vector but skip the write")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		fmt.Println("Usage: vector-coordinator reembed [--model name] [--vector-type type] [--validate] <paper-id>")
+		return
+	}
+
+	input := StepFunctionInput{PaperID: flags.Arg(0), Model: *model, VectorType: *vectorType}
+	if *validate {
+		input.Mode = "validate"
+	}
+	_, err := runVectorization(context.Background(), input, watcher.Done())
+	watcher.Exit(err, false)
+}
+
+// reembedPaper regenerates one vector of a single paper without reading its trace, for
+// debugging bad vectors. The vector keeps the paper's trace ID so lineage is unchanged.
+func (vc *VectorCoordinator) reembedPaper(ctx context.Context, paperID, model, vectorType string) (*ProcessingResult, error) {
+	startTime := time.Now()
+	contextLogger := vc.logger.WithContext(ctx)
+	result := &ProcessingResult{
+		PaperID:      paperID,
+		Status:       StatusInProgress,
+		Timestamp:    time.Now().UTC().Format(time.RFC3339),
+		TotalPapers:  1,
+		StageTimings: make(map[string]int64),
+	}
+	fail := func(err *ProcessingError) (*ProcessingResult, error) {
+		result.Status = StatusFailed
+		result.ErrorMessage = err.Error()
+		result.ProcessingTimeMs = time.Since(startTime).Milliseconds()
+		contextLogger.Error("Re-embedding failed", err, map[string]interface{}{
+			"paper_id":    paperID,
+			"vector_type": vectorType,
+		})
+		return result, err
+	}
+
+	if model != "" {
+		apiClient, err := modelClient(model)
+		if err != nil {
+			return fail(&ProcessingError{Stage: "configuration", Message: "unknown embedding model", Cause: err})
+		}
+		vc.apiClient = apiClient
+	}
+
+	retrievalStart := time.Now()
+	papers, err := vc.retriever.GetPapersByIDs(ctx, []string{paperID})
+	result.StageTimings[TimingRetrievalMs] = time.Since(retrievalStart).Milliseconds()
+	if err != nil {
+		return fail(&ProcessingError{Stage: "data_retrieval", Message: "failed to read paper", Cause: err, Retryable: true})
+	}
+	if len(papers) == 0 {
+		return fail(&ProcessingError{Stage: "data_retrieval", Message: fmt.Sprintf("paper %s not found", paperID)})
+	}
+	paper := papers[0]
+	result.TraceID = paper.TraceID
+	ctx = logger.ContextWithTraceID(ctx, paper.TraceID)
+	contextLogger = vc.logger.WithContext(ctx)
+	combinedText, ok := retriever.CombineText(paper)
+	if !ok {
+		return fail(&ProcessingError{Stage: "validation", Message: fmt.Sprintf("paper %s has no title or abstract", paperID)})
+	}
+	combinedText = vc.preprocessText(combinedText)
+
+	embeddingStart := time.Now()
+	records, err := vc.reembedRecords(ctx, combinedText, paper.TraceID, vectorType, result)
+	result.StageTimings[TimingEmbeddingMs] = time.Since(embeddingStart).Milliseconds()
+	if errors.Is(err, errQuotaExceeded) {
+		quotaErr := vc.charQuotaError(result)
+		result.Status = StatusQuotaExceeded
+		result.ErrorMessage = quotaErr.Error()
+		result.ProcessingTimeMs = time.Since(startTime).Milliseconds()
+		return result, quotaErr
+	}
+	if err != nil {
+		recordEmbeddingFailure(result, paperID, vectorType, err)
+		return fail(&ProcessingError{Stage: "embedding_generation", Message: fmt.Sprintf("failed to generate %s embedding", vectorType), Cause: err, Retryable: !embeddingFailuresPermanent(result)})
+	}
+	result.EmbeddingsGenerated = len(records)
+	if model != "" && !strings.Contains(records[0].EmbeddingMetadata.ModelVersion, model) {
+		contextLogger.Warn("Embedding API reported a different model than requested", map[string]interface{}{
+			"requested_model": model,
+			"model_version":   records[0].EmbeddingMetadata.ModelVersion,
+		})
+	}
+
+	if vc.validateOnly {
+		result.Validation = buildValidationReport(records)
+		result.Status = StatusValidated
+		result.ProcessingTimeMs = time.Since(startTime).Milliseconds()
+		return result, nil
+	}
+
+	storageStart := time.Now()
+	batchResult, err := vc.vectorStorage.BatchStoreVectors(ctx, records)
+	result.StageTimings[TimingStorageMs] = time.Since(storageStart).Milliseconds()
+	if err != nil {
+		return fail(&ProcessingError{Stage: "vector_storage", Message: "failed to store vector record", Cause: err, Retryable: true})
+	}
+	result.VectorsStored = batchResult.SuccessCount
+	result.FailedStorage = len(batchResult.FailedItems)
+	result.SpooledVectors = len(batchResult.Spooled)
+	result.SpoolKeys = batchResult.SpoolKeys
+	result.WriteChunks = batchResult.Chunks
+	addStorageFailures(result, batchResult)
+	vc.verifyStored(ctx, records, batchResult, result)
+	vc.markPapersReady(ctx, records, batchResult, result)
+	result.ProcessingTimeMs = time.Since(startTime).Milliseconds()
+	if result.FailedStorage > 0 {
+		return fail(&ProcessingError{Stage: "vector_storage", Message: fmt.Sprintf("%d of %d records failed to store", result.FailedStorage, len(records)), Retryable: true})
+	}
+	if result.ReadBack != nil && len(result.ReadBack.Mismatches) > 0 {
+		return fail(&ProcessingError{Stage: "vector_readback", Message: fmt.Sprintf("%d stored vectors do not match what was written", len(result.ReadBack.Mismatches))})
+	}
+	if result.PendingPapers > 0 {
+		return fail(&ProcessingError{Stage: "vector_storage", Message: "stored vectors could not be marked ready", Retryable: true})
+	}
+
+	result.Status = StatusCompleted
+	if result.SpooledVectors > 0 {
+		result.Status = StatusPartialSpooled
+	}
+	contextLogger.Info("Paper re-embedded", map[string]interface{}{
+		"paper_id":           paperID,
+		"trace_id":           paper.TraceID,
+		"vector_type":        vectorType,
+		"model_version":      records[0].EmbeddingMetadata.ModelVersion,
+		"vectors_stored":     result.VectorsStored,
+		"processing_time_ms": result.ProcessingTimeMs,
+	})
+	return result, nil
+}
+
+// reembedRecords generates the records of one vector type for a paper
+func (vc *VectorCoordinator) reembedRecords(ctx context.Context, combinedText retriever.CombinedText, traceID, vectorType string, result *ProcessingResult) ([]storage.VectorRecord, error) {
+	switch vectorType {
+	case storage.VectorTypeTitleAbstract:
+		start := time.Now()
+		response, err := vc.apiClient.GenerateEmbedding(ctx, combinedText.Text)
+		if err != nil {
+			return nil, err
+		}
+		record := storage.CreateVectorRecord(combinedText.PaperID, combinedText.Text, traceID,
+			response.Embedding, response.ModelVersion, time.Since(start).Milliseconds())
+		recordProvenance(record, response)
+		recordSource(record, combinedText)
+		vc.recordPreprocessing(record)
+		return []storage.VectorRecord{*record}, nil
+	case storage.VectorTypeWeighted:
+		record, err := vc.generateWeightedRecord(ctx, combinedText, traceID)
+		if err != nil {
+			return nil, err
+		}
+		return []storage.VectorRecord{*record}, nil
+	case storage.VectorTypeFullText:
+		if combinedText.FullTextKey == "" {
+			return nil, fmt.Errorf("paper has no full-text key")
+		}
+		records, err := vc.embedFullText(ctx, combinedText, traceID, result)
+		if err == nil && len(records) == 0 {
+			err = fmt.Errorf("full text is empty")
+		}
+		return records, err
+	default:
+		return nil, fmt.Errorf("unknown vector type %q", vectorType)
+	}
+}
+
+// validReembedVectorType reports whether vectorType can be re-embedded
+func validReembedVectorType(vectorType string) bool {
+	switch vectorType {
+	case storage.VectorTypeTitleAbstract, storage.VectorTypeWeighted, storage.VectorTypeFullText:
+		return true
+	}
+	return false
+}
+
+// modelClient returns an embedding client for a model named in EMBEDDING_MODEL_URLS, a
+// comma-separated list of model=url pairs. Each embedding API deployment serves one model.
+func modelClient(model string) (*client.VectorAPIClient, error) {
+	var known []string
+	for _, pair := range strings.Split(os.Getenv("EMBEDDING_MODEL_URLS"), ",") {
+		name, url, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if !found || name == "" || url == "" {
+			continue
+		}
+		if name == model {
+			// The response format is detected; the model name stands in when a response omits it
+			modelAPIClient := client.NewVectorAPIClient(url)
+			if err := modelAPIClient.SetProvider(client.ProviderAuto, model); err != nil {
+				return nil, err
+			}
+			return modelAPIClient, nil
+		}
+		known = append(known, name)
+	}
+	return nil, fmt.Errorf("model %q is not in EMBEDDING_MODEL_URLS (known: %v)", model, known)
+}
@@ -0,0 +1,77 @@
+package retriever
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"shared/dynamo"
+)
+
+// ProcessingRuns attributes, as the batch processor records them
+const (
+	runsAttributeTraceID      = "trace_id"
+	runsAttributeWrittenCount = "written_count"
+)
+
+// SetRunsTable names the batch processor's ProcessingRuns table, which ExpectedPapers reads
+func (r *DataRetriever) SetRunsTable(tableName string) {
+	r.runsTable = tableName
+}
+
+// ExpectedPapers returns the written_count the batch processor recorded for the trace: the
+// new, changed and refreshed papers it wrote, which are the ones stamped with the trace ID.
+// Unchanged and tombstoned papers keep their earlier trace and are not counted. ok is false
+// when no runs table is set or the trace has no recorded count.
+func (r *DataRetriever) ExpectedPapers(ctx context.Context, traceID string) (count int, ok bool, err error) {
+	if r.runsTable == "" {
+		return 0, false, nil
+	}
+	output, err := r.client.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(r.runsTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			runsAttributeTraceID: {S: aws.String(traceID)},
+		},
+		ProjectionExpression:     aws.String("#count"),
+		ExpressionAttributeNames: map[string]*string{"#count": aws.String(runsAttributeWrittenCount)},
+	})
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to read the processing run of %s: %w", traceID, err)
+	}
+	value, found := output.Item[runsAttributeWrittenCount]
+	if !found || value.N == nil {
+		return 0, false, nil
+	}
+	count, err = strconv.Atoi(aws.StringValue(value.N))
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid written_count %q for %s: %w", aws.StringValue(value.N), traceID, err)
+	}
+	return count, true, nil
+}
+
+// CountByTraceID counts the trace's records visible in the trace-id index without reading
+// them, across the whole trace regardless of the recency window
+func (r *DataRetriever) CountByTraceID(ctx context.Context, traceID string) (int, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(r.tableName),
+		IndexName:              aws.String(r.indexName),
+		KeyConditionExpression: aws.String("#trace = :trace_id"),
+		ExpressionAttributeNames: map[string]*string{
+			"#trace": aws.String(TraceIDIndexPartitionKey),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":trace_id": {S: aws.String(traceID)},
+		},
+		Select: aws.String(dynamodb.SelectCount),
+	}
+
+	result, err := dynamo.Paginate(ctx, dynamo.Query(r.client, input), dynamo.Options{}, func(dynamo.Page) error {
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count papers of %s: %w", traceID, err)
+	}
+	return result.Items, nil
+}
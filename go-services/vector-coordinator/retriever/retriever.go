@@ -2,15 +2,17 @@ package retriever
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"shared/awsclient"
+	"shared/dynamo"
 	"shared/logger"
 )
 
@@ -25,12 +27,50 @@ type Paper struct {
 	Categories    []string `json:"categories" dynamodbav:"categories"`
 	TraceID       string   `json:"trace_id" dynamodbav:"trace_id"`
 	BatchTimestamp string  `json:"batch_timestamp" dynamodbav:"batch_timestamp"`
+	FullTextKey   string   `json:"full_text_key,omitempty" dynamodbav:"full_text_key,omitempty"` // S3 location of extracted full text
+	RawDataBucket string   `json:"raw_data_bucket,omitempty" dynamodbav:"raw_data_bucket,omitempty"` // raw-data object the paper was parsed from
+	RawDataKey    string   `json:"raw_data_key,omitempty" dynamodbav:"raw_data_key,omitempty"`
+	CollectionRunID string `json:"collection_run_id,omitempty" dynamodbav:"collection_run_id,omitempty"` // data collector run that fetched the paper
+	CollectedAt   string   `json:"collected_at,omitempty" dynamodbav:"collected_at,omitempty"` // when that run started
+	IngestedAt    string   `json:"ingested_at,omitempty" dynamodbav:"ingested_at,omitempty"`   // when the batch processor parsed the paper
+	Deleted       bool     `json:"deleted,omitempty" dynamodbav:"deleted,omitempty"`           // taken down; never vectorized again
 }
 
 // CombinedText represents the combined title and abstract for vectorization
 type CombinedText struct {
-	PaperID string `json:"paper_id"`
-	Text    string `json:"text"`
+	PaperID  string `json:"paper_id"`
+	Text     string `json:"text"`
+	Title    string `json:"title,omitempty"`    // trimmed title, for per-field embeddings
+	Abstract string `json:"abstract,omitempty"` // trimmed abstract, for per-field embeddings
+	FullTextKey string `json:"full_text_key,omitempty"` // set when the paper has extracted full text
+	SourceS3Key string `json:"source_s3_key,omitempty"` // s3://bucket/key of the raw-data object, when recorded
+	CollectionRunID string `json:"collection_run_id,omitempty"`
+	CollectedAt     string `json:"collected_at,omitempty"`
+	IngestedAt      string `json:"ingested_at,omitempty"`
+}
+
+// DefaultMaxPages is the default cap on query pages read for one traceID
+const DefaultMaxPages = 100
+
+// Key attributes of the papers trace ID index the retriever queries
+const (
+	TraceIDIndexPartitionKey = "trace_id"
+	TraceIDIndexSortKey      = "batch_timestamp"
+)
+
+// PartialRetrievalError reports that the page limit was reached before all papers for a
+// traceID were read. Texts holds the combined texts of the pages that were read, so callers
+// can choose to continue with the incomplete set instead of failing.
+type PartialRetrievalError struct {
+	TraceID         string
+	MaxPages        int
+	PapersRetrieved int
+	Texts           []CombinedText
+}
+
+func (e *PartialRetrievalError) Error() string {
+	return fmt.Sprintf("page limit of %d reached for traceID %s after %d papers; more papers remain",
+		e.MaxPages, e.TraceID, e.PapersRetrieved)
 }
 
 // DataRetriever handles retrieving papers from DynamoDB by traceID
@@ -38,16 +78,21 @@ type DataRetriever struct {
 	client    dynamodbiface.DynamoDBAPI
 	tableName string
 	indexName string
+	maxPages  int
+	ascending bool          // sort by batch_timestamp oldest first
+	window    time.Duration // skip records older than this; 0 reads the whole trace
+	runsTable string        // the batch processor's ProcessingRuns table, for ExpectedPapers
 	logger    *logger.Logger
 }
 
-// NewDataRetriever creates a new data retriever instance
-func NewDataRetriever(tableName, indexName string) *DataRetriever {
-	sess := session.Must(session.NewSession())
+// NewDataRetriever creates a new data retriever instance whose client uses clientConfig's retries and timeouts
+func NewDataRetriever(tableName, indexName string, clientConfig awsclient.ClientConfig) *DataRetriever {
+	sess := awsclient.MustClientSession(clientConfig)
 	return &DataRetriever{
 		client:    dynamodb.New(sess),
 		tableName: tableName,
 		indexName: indexName,
+		maxPages:  DefaultMaxPages,
 		logger:    logger.New("data-retriever"),
 	}
 }
@@ -58,11 +103,31 @@ func NewDataRetrieverWithClient(client dynamodbiface.DynamoDBAPI, tableName, ind
 		client:    client,
 		tableName: tableName,
 		indexName: indexName,
+		maxPages:  DefaultMaxPages,
 		logger:    logger.New("data-retriever"),
 	}
 }
 
+// SetMaxPages overrides the cap on query pages read for one traceID
+func (r *DataRetriever) SetMaxPages(maxPages int) error {
+	if maxPages <= 0 {
+		return fmt.Errorf("max pages must be positive, got %d", maxPages)
+	}
+	r.maxPages = maxPages
+	return nil
+}
+
+// SetOrdering sets the batch_timestamp sort order, ascending for a deterministic order across
+// chunked runs
+func (r *DataRetriever) SetOrdering(ascending bool) {
+	r.ascending = ascending
+}
 
+// SetRecencyWindow limits retrieval to records whose batch_timestamp is within window of now;
+// 0 reads the whole trace
+func (r *DataRetriever) SetRecencyWindow(window time.Duration) {
+	r.window = window
+}
 
 // validatePaper validates the structure and content of a paper record
 func (r *DataRetriever) validatePaper(paper *Paper) error {
@@ -83,82 +148,66 @@ func (r *DataRetriever) validatePaper(paper *Paper) error {
 
 
 
-// GetCombinedTextsByTraceID retrieves papers by traceID and returns combined text for vectorization
-func (r *DataRetriever) GetCombinedTextsByTraceID(ctx context.Context, traceID string) ([]CombinedText, error) {
-	if traceID == "" {
-		return nil, fmt.Errorf("traceID cannot be empty")
-	}
-
-	contextLogger := r.logger.WithContext(ctx).WithTraceID(traceID)
-	startTime := time.Now()
-	
-	contextLogger.Info("Starting paper retrieval by traceID", map[string]interface{}{
-		"table_name": r.tableName,
-		"index_name": r.indexName,
-	})
-
-	var allPapers []Paper
-	var lastEvaluatedKey map[string]*dynamodb.AttributeValue
-	pageCount := 0
-	maxPages := 100 // Prevent infinite loops
-
-	// Query with pagination support and error handling
-	for pageCount < maxPages {
-		pageCount++
-		
-		input := &dynamodb.QueryInput{
-			TableName: aws.String(r.tableName),
-			IndexName: aws.String(r.indexName),
-			KeyConditionExpression: aws.String("trace_id = :trace_id"),
-			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
-				":trace_id": {
-					S: aws.String(traceID),
-				},
-			},
-			// Sort by batch_timestamp in descending order (newest first)
-			ScanIndexForward: aws.Bool(false),
-		}
+// traceQueryStats describes one pass over a trace's query pages
+type traceQueryStats struct {
+	pages   int
+	papers  int
+	hasMore bool // the page limit was reached with papers left unread
+}
 
-		// Add pagination token if available
-		if lastEvaluatedKey != nil {
-			input.ExclusiveStartKey = lastEvaluatedKey
+// queryTrace reads the trace's pages up to the page limit and hands each valid paper to fn
+func (r *DataRetriever) queryTrace(ctx context.Context, contextLogger *logger.Logger, traceID string, fn func(Paper) error) (traceQueryStats, error) {
+	keyCondition := "trace_id = :trace_id"
+	values := map[string]*dynamodb.AttributeValue{
+		":trace_id": {
+			S: aws.String(traceID),
+		},
+	}
+	if r.window > 0 {
+		// RFC 3339 timestamps in UTC sort chronologically, so the window is a range on the sort key
+		keyCondition += " AND batch_timestamp >= :since"
+		values[":since"] = &dynamodb.AttributeValue{
+			S: aws.String(time.Now().Add(-r.window).UTC().Format(time.RFC3339)),
 		}
+	}
 
-		queryStartTime := time.Now()
-		result, err := r.client.QueryWithContext(ctx, input)
-		queryDuration := time.Since(queryStartTime)
-		
-		if err != nil {
-			contextLogger.Error("Failed to query papers by traceID", err, map[string]interface{}{
-				"table_name":     r.tableName,
-				"index_name":     r.indexName,
-				"page_number":    pageCount,
-				"query_duration_ms": queryDuration.Milliseconds(),
-			})
-			return nil, fmt.Errorf("failed to query papers by traceID on page %d: %w", pageCount, err)
-		}
+	input := &dynamodb.QueryInput{
+		TableName:                 aws.String(r.tableName),
+		IndexName:                 aws.String(r.indexName),
+		KeyConditionExpression:    aws.String(keyCondition),
+		ExpressionAttributeValues: values,
+		// Sort by batch_timestamp, newest first unless ascending order is configured
+		ScanIndexForward: aws.Bool(r.ascending),
+	}
 
+	var stats traceQueryStats
+	result, err := dynamo.Paginate(ctx, dynamo.Query(r.client, input), dynamo.Options{MaxPages: r.maxPages}, func(page dynamo.Page) error {
 		// Log query performance metrics
 		contextLogger.Debug("DynamoDB query completed", map[string]interface{}{
-			"page_number":       pageCount,
-			"items_returned":    len(result.Items),
-			"query_duration_ms": queryDuration.Milliseconds(),
-			"consumed_capacity": result.ConsumedCapacity,
+			"page_number":       page.Number,
+			"items_returned":    len(page.Items),
+			"query_duration_ms": page.Duration.Milliseconds(),
+			"consumed_capacity": page.ConsumedCapacity,
 		})
 
 		// Convert DynamoDB items to Paper structs with error handling
 		var papers []Paper
-		if err := dynamodbattribute.UnmarshalListOfMaps(result.Items, &papers); err != nil {
+		if err := dynamodbattribute.UnmarshalListOfMaps(page.Items, &papers); err != nil {
 			contextLogger.Error("Failed to unmarshal papers", err, map[string]interface{}{
-				"item_count":  len(result.Items),
-				"page_number": pageCount,
+				"item_count":  len(page.Items),
+				"page_number": page.Number,
 			})
-			return nil, fmt.Errorf("failed to unmarshal papers on page %d: %w", pageCount, err)
+			return fmt.Errorf("failed to unmarshal papers on page %d: %w", page.Number, err)
 		}
 
-		// Validate paper data
-		validPapers := make([]Paper, 0, len(papers))
+		// Validate paper data; soft-deleted papers stay in the index during their grace period
+		// but are not vectorized again
+		validPapers, deletedPapers := 0, 0
 		for i, paper := range papers {
+			if paper.Deleted {
+				deletedPapers++
+				continue
+			}
 			if err := r.validatePaper(&paper); err != nil {
 				contextLogger.Warn("Invalid paper data found", map[string]interface{}{
 					"paper_index": i,
@@ -167,45 +216,91 @@ func (r *DataRetriever) GetCombinedTextsByTraceID(ctx context.Context, traceID s
 				})
 				continue
 			}
-			validPapers = append(validPapers, paper)
+			if err := fn(paper); err != nil {
+				return err
+			}
+			validPapers++
 		}
-
-		allPapers = append(allPapers, validPapers...)
+		stats.papers += validPapers
 
 		contextLogger.Info("Retrieved paper batch", map[string]interface{}{
-			"page_number":       pageCount,
+			"page_number":       page.Number,
 			"batch_size":        len(papers),
-			"valid_papers":      len(validPapers),
-			"invalid_papers":    len(papers) - len(validPapers),
-			"total_so_far":      len(allPapers),
-			"has_more":          result.LastEvaluatedKey != nil,
-			"query_duration_ms": queryDuration.Milliseconds(),
+			"valid_papers":      validPapers,
+			"deleted_papers":    deletedPapers,
+			"invalid_papers":    len(papers) - validPapers - deletedPapers,
+			"total_so_far":      stats.papers,
+			"has_more":          page.HasMore,
+			"query_duration_ms": page.Duration.Milliseconds(),
 		})
-
-		// Check if there are more items to retrieve
-		if result.LastEvaluatedKey == nil {
-			break
+		return nil
+	})
+	stats.pages, stats.hasMore = result.Pages, result.HasMore
+	if err != nil {
+		var queryErr *dynamo.FetchError
+		if errors.As(err, &queryErr) {
+			contextLogger.Error("Failed to query papers by traceID", queryErr.Err, map[string]interface{}{
+				"table_name":  r.tableName,
+				"index_name":  r.indexName,
+				"page_number": queryErr.Page,
+			})
+			return stats, fmt.Errorf("failed to query papers by traceID on page %d: %w", queryErr.Page, queryErr.Err)
 		}
-		lastEvaluatedKey = result.LastEvaluatedKey
+		return stats, err
 	}
 
-	// Check if we hit the page limit
-	if pageCount >= maxPages {
-		contextLogger.Warn("Hit maximum page limit during retrieval", map[string]interface{}{
-			"max_pages":     maxPages,
-			"papers_found":  len(allPapers),
+	contextLogger.Debug("Completed traceID query", map[string]interface{}{
+		"pages":             result.Pages,
+		"consumed_capacity": result.ConsumedCapacity,
+	})
+	if stats.hasMore {
+		contextLogger.Warn("Hit maximum page limit during retrieval, remaining papers not read", map[string]interface{}{
+			"max_pages":    r.maxPages,
+			"papers_found": stats.papers,
 		})
 	}
+	return stats, nil
+}
+
+// GetCombinedTextsByTraceID retrieves papers by traceID and returns combined text for vectorization
+func (r *DataRetriever) GetCombinedTextsByTraceID(ctx context.Context, traceID string) ([]CombinedText, error) {
+	if traceID == "" {
+		return nil, fmt.Errorf("traceID cannot be empty")
+	}
+
+	contextLogger := r.logger.WithContext(ctx).WithTraceID(traceID)
+	startTime := time.Now()
+	
+	contextLogger.Info("Starting paper retrieval by traceID", map[string]interface{}{
+		"table_name": r.tableName,
+		"index_name": r.indexName,
+	})
+
+	var allPapers []Paper
+	stats, err := r.queryTrace(ctx, contextLogger, traceID, func(paper Paper) error {
+		allPapers = append(allPapers, paper)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	pageCount, maxPages, hasMore := stats.pages, r.maxPages, stats.hasMore
+
 
 	totalDuration := time.Since(startTime)
 	contextLogger.InfoWithDuration("Completed paper retrieval by traceID", totalDuration, map[string]interface{}{
 		"total_papers":      len(allPapers),
+		"ascending":         r.ascending,
+		"window_hours":      r.window.Hours(),
 		"pages_processed":   pageCount,
 		"avg_query_time_ms": totalDuration.Milliseconds() / int64(pageCount),
 	})
 
 	// Combine title and abstract text for vectorization
 	if len(allPapers) == 0 {
+		if hasMore {
+			return nil, &PartialRetrievalError{TraceID: traceID, MaxPages: maxPages}
+		}
 		return nil, nil
 	}
 
@@ -214,27 +309,14 @@ func (r *DataRetriever) GetCombinedTextsByTraceID(ctx context.Context, traceID s
 	var combinedTexts []CombinedText
 	for _, paper := range allPapers {
 		// Skip papers without title or abstract
-		if paper.Title == "" && paper.Abstract == "" {
+		combinedText, ok := CombineText(paper)
+		if !ok {
 			contextLogger.Warn("Skipping paper with empty title and abstract", map[string]interface{}{
 				"paper_id": paper.PaperID,
 			})
 			continue
 		}
 
-		// Combine title and abstract with proper formatting
-		var textParts []string
-		if paper.Title != "" {
-			textParts = append(textParts, strings.TrimSpace(paper.Title))
-		}
-		if paper.Abstract != "" {
-			textParts = append(textParts, strings.TrimSpace(paper.Abstract))
-		}
-
-		combinedText := CombinedText{
-			PaperID: paper.PaperID,
-			Text:    strings.Join(textParts, ". "),
-		}
-
 		combinedTexts = append(combinedTexts, combinedText)
 	}
 
@@ -244,5 +326,178 @@ func (r *DataRetriever) GetCombinedTextsByTraceID(ctx context.Context, traceID s
 		"skipped_count":  len(allPapers) - len(combinedTexts),
 	})
 
+	if hasMore {
+		return combinedTexts, &PartialRetrievalError{
+			TraceID:         traceID,
+			MaxPages:        maxPages,
+			PapersRetrieved: len(allPapers),
+			Texts:           combinedTexts,
+		}
+	}
+
 	return combinedTexts, nil
-}
\ No newline at end of file
+}
+
+// CombineText joins a paper's title and abstract into the text that is embedded.
+// ok is false for papers with neither.
+func CombineText(paper Paper) (CombinedText, bool) {
+	if paper.Title == "" && paper.Abstract == "" {
+		return CombinedText{}, false
+	}
+
+	// Combine title and abstract with proper formatting
+	var textParts []string
+	if paper.Title != "" {
+		textParts = append(textParts, strings.TrimSpace(paper.Title))
+	}
+	if paper.Abstract != "" {
+		textParts = append(textParts, strings.TrimSpace(paper.Abstract))
+	}
+
+	return CombinedText{
+		PaperID:     paper.PaperID,
+		Text:        strings.Join(textParts, ". "),
+		Title:       strings.TrimSpace(paper.Title),
+		Abstract:    strings.TrimSpace(paper.Abstract),
+		FullTextKey: paper.FullTextKey,
+		SourceS3Key: rawDataURI(paper),
+		CollectionRunID: paper.CollectionRunID,
+		CollectedAt: paper.CollectedAt,
+		IngestedAt:  paper.IngestedAt,
+	}, true
+}
+
+// rawDataURI returns the s3:// URI of the raw-data object the paper was parsed from, or ""
+// for papers ingested before the object was recorded
+func rawDataURI(paper Paper) string {
+	if paper.RawDataBucket == "" || paper.RawDataKey == "" {
+		return ""
+	}
+	return fmt.Sprintf("s3://%s/%s", paper.RawDataBucket, paper.RawDataKey)
+}
+
+// maxBatchGetKeys is the DynamoDB limit on keys per BatchGetItem request
+const maxBatchGetKeys = 100
+
+// GetPapersByIDs retrieves papers by explicit paper_id list using BatchGetItem.
+// Unprocessed keys are retried with backoff; IDs not found in the table and soft-deleted papers are omitted.
+func (r *DataRetriever) GetPapersByIDs(ctx context.Context, paperIDs []string) ([]Paper, error) {
+	if len(paperIDs) == 0 {
+		return nil, fmt.Errorf("paperIDs cannot be empty")
+	}
+
+	contextLogger := r.logger.WithContext(ctx)
+	startTime := time.Now()
+
+	// Deduplicate IDs since BatchGetItem rejects duplicate keys
+	uniqueIDs := make([]string, 0, len(paperIDs))
+	seen := make(map[string]bool)
+	for _, id := range paperIDs {
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		uniqueIDs = append(uniqueIDs, id)
+	}
+
+	contextLogger.InfoWithCount("Starting paper retrieval by IDs", len(uniqueIDs), map[string]interface{}{
+		"table_name": r.tableName,
+	})
+
+	var allPapers []Paper
+	deleted := 0
+	for i := 0; i < len(uniqueIDs); i += maxBatchGetKeys {
+		end := i + maxBatchGetKeys
+		if end > len(uniqueIDs) {
+			end = len(uniqueIDs)
+		}
+
+		papers, err := r.batchGetPapers(ctx, uniqueIDs[i:end])
+		if err != nil {
+			contextLogger.Error("Failed to batch get papers", err, map[string]interface{}{
+				"chunk_start": i,
+				"chunk_size":  end - i,
+			})
+			return nil, fmt.Errorf("failed to get papers %d-%d: %w", i, end-1, err)
+		}
+
+		for j, paper := range papers {
+			if paper.Deleted {
+				deleted++
+				continue
+			}
+			if err := r.validatePaper(&paper); err != nil {
+				contextLogger.Warn("Invalid paper data found", map[string]interface{}{
+					"paper_index": i + j,
+					"paper_id":    paper.PaperID,
+					"error":       err.Error(),
+				})
+				continue
+			}
+			allPapers = append(allPapers, paper)
+		}
+	}
+
+	contextLogger.InfoWithDuration("Completed paper retrieval by IDs", time.Since(startTime), map[string]interface{}{
+		"requested_count": len(uniqueIDs),
+		"found_count":     len(allPapers),
+		"deleted_count":   deleted,
+		"missing_count":   len(uniqueIDs) - len(allPapers) - deleted,
+	})
+
+	return allPapers, nil
+}
+
+// batchGetPapers fetches a single chunk of up to maxBatchGetKeys papers, retrying unprocessed keys
+func (r *DataRetriever) batchGetPapers(ctx context.Context, paperIDs []string) ([]Paper, error) {
+	keys := make([]map[string]*dynamodb.AttributeValue, 0, len(paperIDs))
+	for _, id := range paperIDs {
+		keys = append(keys, map[string]*dynamodb.AttributeValue{
+			"paper_id": {S: aws.String(id)},
+		})
+	}
+
+	requestItems := map[string]*dynamodb.KeysAndAttributes{
+		r.tableName: {Keys: keys},
+	}
+
+	var papers []Paper
+	maxRetries := 5
+	backoff := 100 * time.Millisecond
+
+	for attempt := 0; attempt < maxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+
+		result, err := r.client.BatchGetItemWithContext(ctx, &dynamodb.BatchGetItemInput{
+			RequestItems: requestItems,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("batch get failed on attempt %d: %w", attempt+1, err)
+		}
+
+		var page []Paper
+		if err := dynamodbattribute.UnmarshalListOfMaps(result.Responses[r.tableName], &page); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal papers: %w", err)
+		}
+		papers = append(papers, page...)
+
+		if len(result.UnprocessedKeys) == 0 {
+			return papers, nil
+		}
+
+		requestItems = result.UnprocessedKeys
+		r.logger.Info("Retrying unprocessed keys", map[string]interface{}{
+			"attempt":          attempt + 1,
+			"unprocessed_keys": len(requestItems[r.tableName].Keys),
+		})
+	}
+
+	return nil, fmt.Errorf("unprocessed keys remained after %d attempts", maxRetries)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"shared/awsclient"
+	"shared/httpserver"
+	"shared/logger"
+	"shared/shutdown"
+)
+
+// serverShutdownTimeout bounds how long in-flight requests may drain after a shutdown signal
+const serverShutdownTimeout = 5 * time.Minute
+
+// runServer exposes vectorization over HTTP until a shutdown signal drains it
+func runServer(addr string, watcher *shutdown.Watcher, appLogger *logger.Logger) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", httpserver.Healthz(watcher, appLogger))
+	mux.HandleFunc("/vectorize", vectorizeHandler(watcher, appLogger))
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           awsclient.Handler("vector-coordinator", mux),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	return httpserver.Serve(server, watcher, serverShutdownTimeout, appLogger, nil)
+}
+
+// vectorizeHandler vectorizes the papers of one trace ID; POST /vectorize with a Step Function input body
+func vectorizeHandler(watcher *shutdown.Watcher, appLogger *logger.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			httpserver.WriteJSON(w, appLogger, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+			return
+		}
+
+		var input StepFunctionInput
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			httpserver.WriteJSON(w, appLogger, http.StatusBadRequest, map[string]string{"error": "invalid input: " + err.Error()})
+			return
+		}
+
+		// Detach from the request so a dropped client does not abort vector storage midway
+		result, err := runVectorization(context.WithoutCancel(r.Context()), input, watcher.Done())
+		if err != nil {
+			if result == nil {
+				httpserver.WriteJSON(w, appLogger, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+				return
+			}
+			httpserver.WriteJSON(w, appLogger, http.StatusInternalServerError, result)
+			return
+		}
+		httpserver.WriteJSON(w, appLogger, http.StatusOK, result)
+	}
+}
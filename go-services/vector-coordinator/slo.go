@@ -0,0 +1,19 @@
+package main
+
+import (
+	"time"
+
+	"shared/logger"
+	"shared/slo"
+)
+
+// emitSLO reports the run's vectorization_latency event: a trace is good when every paper
+// was vectorized within the threshold. Re-embeds and runs stopped by a pause or shutdown
+// are operator actions, not user-facing traces, and are not measured.
+func emitSLO(contextLogger *logger.Logger, cfg slo.Config, result *ProcessingResult) {
+	if !cfg.Enabled || result == nil || result.PaperID != "" || result.Interrupted {
+		return
+	}
+	elapsed := time.Duration(result.ProcessingTimeMs) * time.Millisecond
+	slo.Emit(contextLogger, slo.Latency(slo.VectorizationLatency, cfg.VectorizationLatency, elapsed, result.Status == StatusCompleted))
+}
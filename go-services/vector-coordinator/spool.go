@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"vector-coordinator/storage"
+)
+
+// drainSpoolCommand is the local subcommand that drains the spooled records of traces
+const drainSpoolCommand = "drain-spool"
+
+// SpoolInterface defines the interface for reading and clearing spooled vector records
+type SpoolInterface interface {
+	List(ctx context.Context, traceID string) ([]string, error)
+	Read(ctx context.Context, key string) ([]storage.VectorRecord, error)
+	Replace(ctx context.Context, key string, records []storage.VectorRecord) error
+}
+
+// countExtraVectors counts the weighted and full-text records among records, which runs
+// report apart from the title/abstract vectors
+func countExtraVectors(records []storage.VectorRecord) (weighted, fullText int) {
+	for _, record := range records {
+		if record.VectorType == storage.VectorTypeWeighted {
+			weighted++
+		} else if storage.IsFullTextVectorType(record.VectorType) {
+			fullText++
+		}
+	}
+	return weighted, fullText
+}
+
+// runDrainSpool drains the spool of each trace ID given on the command line
+func runDrainSpool(args []string) error {
+	if len(args) == 0 {
+		fmt.Println("Usage: vector-coordinator drain-spool <trace-id> [trace-id ...]")
+		return nil
+	}
+	for _, traceID := range args {
+		if _, err := runVectorization(context.Background(), StepFunctionInput{TraceID: traceID, DrainSpool: true}, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// drainSpool writes the spooled records of a trace to the vectors table and marks their
+// papers ready. Each spool object is rewritten with the records that failed again, or deleted
+// once all of them are stored, so a drain can be rerun until the trace's spool is empty.
+// Records the drain itself spools, when the table is still throttled, go to a new object.
+func (vc *VectorCoordinator) drainSpool(ctx context.Context, traceID string) (*ProcessingResult, error) {
+	startTime := time.Now()
+	contextLogger := vc.logger.WithContext(ctx)
+	result := &ProcessingResult{
+		TraceID:      traceID,
+		Status:       StatusInProgress,
+		Timestamp:    time.Now().UTC().Format(time.RFC3339),
+		StageTimings: make(map[string]int64),
+	}
+	fail := func(err *ProcessingError) (*ProcessingResult, error) {
+		result.Status = StatusFailed
+		result.ErrorMessage = err.Error()
+		result.ProcessingTimeMs = time.Since(startTime).Milliseconds()
+		contextLogger.Error("Spool drain failed", err)
+		return result, err
+	}
+
+	if traceID == "" {
+		return fail(&ProcessingError{Stage: "validation", Message: "traceID cannot be empty"})
+	}
+	if vc.spool == nil {
+		return fail(&ProcessingError{Stage: "configuration", Message: "aws.dynamodb.vector_write_spool is not enabled"})
+	}
+
+	keys, err := vc.spool.List(ctx, traceID)
+	if err != nil {
+		return fail(&ProcessingError{Stage: "spool_drain", Message: "failed to list spooled records", Cause: err, Retryable: true})
+	}
+
+	var drainErrors []error
+	for _, key := range keys {
+		records, err := vc.spool.Read(ctx, key)
+		if err != nil {
+			drainErrors = append(drainErrors, err)
+			continue
+		}
+		result.EmbeddingsGenerated += len(records)
+
+		if vc.validateOnly {
+			report := buildValidationReport(records)
+			if result.Validation == nil {
+				result.Validation = report
+			} else {
+				result.Validation.RecordsToStore += report.RecordsToStore
+				result.Validation.InvalidRecords += report.InvalidRecords
+				result.Validation.ValidationErrors = append(result.Validation.ValidationErrors, report.ValidationErrors...)
+			}
+			continue
+		}
+
+		batchResult, err := vc.vectorStorage.BatchStoreVectors(ctx, records)
+		if err != nil {
+			drainErrors = append(drainErrors, fmt.Errorf("failed to store spooled records %s: %w", key, err))
+			continue
+		}
+		vc.verifyStored(ctx, records, batchResult, result)
+		ready := &ProcessingResult{StageTimings: result.StageTimings, Freshness: result.Freshness}
+		vc.markPapersReady(ctx, records, batchResult, ready)
+		result.Freshness = ready.Freshness
+		result.VectorsStored += batchResult.SuccessCount
+		result.FailedStorage += len(batchResult.FailedItems)
+		result.SpooledVectors += len(batchResult.FailedItems) + len(batchResult.Spooled)
+		result.SpoolKeys = append(result.SpoolKeys, batchResult.SpoolKeys...)
+		result.ReadyPapers += ready.ReadyPapers
+		result.PendingPapers += ready.PendingPapers
+		result.SpooledPapers += ready.SpooledPapers
+		result.WriteChunks = append(result.WriteChunks, batchResult.Chunks...)
+
+		// Records that failed again stay in this object; re-spooled ones are in a new one
+		if err := vc.spool.Replace(ctx, key, batchResult.FailedItems); err != nil {
+			drainErrors = append(drainErrors, err)
+			continue
+		}
+		if len(batchResult.FailedItems) > 0 {
+			result.SpoolKeys = append(result.SpoolKeys, key)
+		}
+	}
+	result.TotalPapers = result.ReadyPapers + result.PendingPapers + result.SpooledPapers
+	result.ProcessingTimeMs = time.Since(startTime).Milliseconds()
+
+	switch {
+	case vc.validateOnly:
+		result.Status = StatusValidated
+	case len(drainErrors) > 0:
+		result.Status = StatusPartial
+		result.ErrorMessage = fmt.Sprintf("%d spool objects could not be drained: %v", len(drainErrors), drainErrors[0])
+	case result.SpooledVectors > 0:
+		result.Status = StatusPartialSpooled
+	default:
+		result.Status = StatusCompleted
+	}
+	contextLogger.Info("Spool drain completed", map[string]interface{}{
+		"status":          result.Status,
+		"spool_objects":   len(keys),
+		"records":         result.EmbeddingsGenerated,
+		"vectors_stored":  result.VectorsStored,
+		"ready_papers":    result.ReadyPapers,
+		"spooled_vectors": result.SpooledVectors,
+	})
+
+	if len(drainErrors) > 0 {
+		return result, &ProcessingError{
+			Stage:     "spool_drain",
+			Message:   result.ErrorMessage,
+			Cause:     drainErrors[0],
+			Retryable: true,
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,166 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// DefaultTargetUtilization is the share of the provisioned write capacity adaptive writes aim for
+const DefaultTargetUtilization = 0.8
+
+// capacityWindow is the interval write consumption is budgeted over; provisioned capacity is per second
+const capacityWindow = time.Second
+
+// writeUnitBytes is the item size covered by one write capacity unit
+const writeUnitBytes = 1024
+
+// capacityThrottle paces batch writes against the table's provisioned write capacity. It
+// budgets the consumed capacity reported by each batch per one-second window, waits for the
+// next window once the budget is spent, and shrinks the batch size while writes run over
+// the budget or come back unprocessed, growing it again when consumption falls off.
+// The throttle is shared by concurrent runs, since they draw on the same table capacity.
+type capacityThrottle struct {
+	mu          sync.Mutex
+	limit       float64 // provisioned write capacity units per second
+	budget      float64 // units per window: limit * target utilization
+	maxBatch    int
+	batchSize   int
+	windowStart time.Time
+	windowUnits float64
+}
+
+// newCapacityThrottle creates a throttle for limit units per second, starting at maxBatch items per batch
+func newCapacityThrottle(limit, targetUtilization float64, maxBatch int) *capacityThrottle {
+	return &capacityThrottle{
+		limit:     limit,
+		budget:    limit * targetUtilization,
+		maxBatch:  maxBatch,
+		batchSize: maxBatch,
+	}
+}
+
+// size returns the current adaptive batch size
+func (t *capacityThrottle) size() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.batchSize
+}
+
+// wait blocks until the current window has budget left for estimated units, returning how
+// long it waited
+func (t *capacityThrottle) wait(ctx context.Context, estimated float64) (time.Duration, error) {
+	t.mu.Lock()
+	now := time.Now()
+	if now.Sub(t.windowStart) >= capacityWindow {
+		t.windowStart, t.windowUnits = now, 0
+	}
+	if t.windowUnits == 0 || t.windowUnits+estimated <= t.budget {
+		t.mu.Unlock()
+		return 0, nil
+	}
+	delay := t.windowStart.Add(capacityWindow).Sub(now)
+	t.windowStart, t.windowUnits = t.windowStart.Add(capacityWindow), 0
+	t.mu.Unlock()
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return delay, nil
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
+
+// record adds the units a batch consumed to the current window and adapts the batch size
+func (t *capacityThrottle) record(units float64, unprocessed int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.windowUnits += units
+
+	switch {
+	case unprocessed > 0 || t.windowUnits > t.budget:
+		if t.batchSize > 1 {
+			t.batchSize /= 2
+		}
+	case t.windowUnits < t.budget/2 && t.batchSize < t.maxBatch:
+		t.batchSize++
+	}
+}
+
+// utilization is the share of the provisioned capacity consumed in the current window
+func (t *capacityThrottle) utilization() float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.windowUnits / t.limit
+}
+
+// SetAdaptiveCapacity paces writes to stay under targetUtilization of the vectors table's
+// provisioned write capacity. A provisionedWCU of 0 reads the capacity from DescribeTable;
+// on-demand tables report none and are written without pacing.
+func (s *VectorStorage) SetAdaptiveCapacity(ctx context.Context, provisionedWCU int, targetUtilization float64) error {
+	if provisionedWCU < 0 {
+		return fmt.Errorf("provisioned write capacity must not be negative, got %d", provisionedWCU)
+	}
+	if targetUtilization <= 0 || targetUtilization > 1 {
+		return fmt.Errorf("target utilization must be in (0, 1], got %v", targetUtilization)
+	}
+
+	limit := float64(provisionedWCU)
+	if limit == 0 {
+		output, err := s.client.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{
+			TableName: aws.String(s.tableName),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to describe table %s: %w", s.tableName, err)
+		}
+		if throughput := output.Table.ProvisionedThroughput; throughput != nil {
+			limit = float64(aws.Int64Value(throughput.WriteCapacityUnits))
+		}
+	}
+	if limit == 0 {
+		s.logger.Info("Vectors table has no provisioned write capacity, adaptive pacing disabled", map[string]interface{}{
+			"table": s.tableName,
+		})
+		s.throttle = nil
+		return nil
+	}
+
+	s.throttle = newCapacityThrottle(limit, targetUtilization, s.batchSize)
+	s.logger.Info("Adaptive write pacing enabled", map[string]interface{}{
+		"table":              s.tableName,
+		"provisioned_wcu":    limit,
+		"target_utilization": targetUtilization,
+	})
+	return nil
+}
+
+// estimateUnits is the write capacity a batch is expected to consume: one unit per started KB per item
+func estimateUnits(items []pendingItem) float64 {
+	units := 0
+	for _, item := range items {
+		units += (item.size + writeUnitBytes - 1) / writeUnitBytes
+	}
+	return float64(units)
+}
+
+// consumedUnits sums the write capacity reported for the table, falling back to estimated
+// when the response carries none
+func consumedUnits(capacities []*dynamodb.ConsumedCapacity, tableName string, estimated float64) float64 {
+	units, reported := 0.0, false
+	for _, capacity := range capacities {
+		if aws.StringValue(capacity.TableName) == tableName {
+			units += aws.Float64Value(capacity.CapacityUnits)
+			reported = true
+		}
+	}
+	if !reported {
+		return estimated
+	}
+	return units
+}
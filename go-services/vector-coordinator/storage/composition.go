@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Vector types stored per paper
+const (
+	VectorTypeTitleAbstract = "title_abstract"
+	VectorTypeWeighted      = "weighted_title_abstract"
+)
+
+// ComposeWeightedEmbedding returns the L2-normalized weighted sum of the field embeddings.
+// Weights of fields without an embedding are ignored, so a paper missing its title is
+// represented by the abstract alone. All embeddings must have the same dimension.
+func ComposeWeightedEmbedding(embeddings map[string][]float64, weights map[string]float64) ([]float64, error) {
+	fields := make([]string, 0, len(embeddings))
+	for field := range embeddings {
+		if weights[field] > 0 {
+			fields = append(fields, field)
+		}
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("no field embeddings with a positive weight")
+	}
+	sort.Strings(fields)
+
+	dimension := len(embeddings[fields[0]])
+	composed := make([]float64, dimension)
+	for _, field := range fields {
+		embedding := embeddings[field]
+		if len(embedding) != dimension {
+			return nil, fmt.Errorf("embedding dimension mismatch: %s has %d, expected %d", field, len(embedding), dimension)
+		}
+		for i, value := range embedding {
+			composed[i] += weights[field] * value
+		}
+	}
+
+	norm := 0.0
+	for _, value := range composed {
+		norm += value * value
+	}
+	if norm == 0 {
+		return nil, fmt.Errorf("weighted embedding has zero magnitude")
+	}
+	norm = math.Sqrt(norm)
+	for i := range composed {
+		composed[i] /= norm
+	}
+
+	return composed, nil
+}
+
+// CreateWeightedVectorRecord creates the weighted multi-field VectorRecord for a paper.
+// text is the combined source text; weights holds the weights of the fields that were used.
+func CreateWeightedVectorRecord(paperID, text, traceID string, embedding []float64, modelVersion string, weights map[string]float64, processingTimeMs int64) *VectorRecord {
+	record := CreateVectorRecord(paperID, text, traceID, embedding, modelVersion, processingTimeMs)
+	record.VectorType = VectorTypeWeighted
+	record.EmbeddingMetadata.Preprocessing = "weighted_field_composition"
+	record.EmbeddingMetadata.FieldWeights = weights
+
+	fields := make([]string, 0, len(weights))
+	for field := range weights {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	record.SourceText.SourceFields = fields
+
+	return record
+}
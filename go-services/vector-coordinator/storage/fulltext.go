@@ -0,0 +1,40 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+)
+
+// VectorTypeFullText prefixes the vector types of full-text chunk vectors. Each chunk is
+// stored under its own type ("full_text#0000", "full_text#0001", ...) so the chunks of one
+// paper share its partition without overwriting each other.
+const VectorTypeFullText = "full_text"
+
+// ChunkInfo locates a full-text chunk vector within the paper's extracted text
+type ChunkInfo struct {
+	Index     int    `json:"index" dynamodbav:"index"`
+	Count     int    `json:"count" dynamodbav:"count"`
+	WordStart int    `json:"word_start" dynamodbav:"word_start"`
+	WordEnd   int    `json:"word_end" dynamodbav:"word_end"`
+	SourceKey string `json:"source_key" dynamodbav:"source_key"` // S3 location of the extracted text
+}
+
+// FullTextVectorType returns the vector type of the chunk at index
+func FullTextVectorType(index int) string {
+	return fmt.Sprintf("%s#%04d", VectorTypeFullText, index)
+}
+
+// IsFullTextVectorType reports whether vectorType belongs to a full-text chunk
+func IsFullTextVectorType(vectorType string) bool {
+	return vectorType == VectorTypeFullText || strings.HasPrefix(vectorType, VectorTypeFullText+"#")
+}
+
+// CreateFullTextVectorRecord creates the VectorRecord for one chunk of a paper's full text
+func CreateFullTextVectorRecord(paperID, text, traceID string, embedding []float64, modelVersion string, chunk ChunkInfo, processingTimeMs int64) *VectorRecord {
+	record := CreateVectorRecord(paperID, text, traceID, embedding, modelVersion, processingTimeMs)
+	record.VectorType = FullTextVectorType(chunk.Index)
+	record.EmbeddingMetadata.Preprocessing = "full_text_chunk"
+	record.SourceText.SourceFields = []string{VectorTypeFullText}
+	record.Chunk = &chunk
+	return record
+}
@@ -0,0 +1,57 @@
+package storage
+
+import "sort"
+
+// Chunk statuses in the write manifest
+const (
+	ChunkWritten = "written" // every item was stored
+	ChunkPartial = "partial" // some items were stored and the rest failed or went unprocessed
+	ChunkFailed  = "failed"  // no item was stored
+	ChunkSkipped = "skipped" // never sent, because writing stopped before it
+)
+
+// BatchChunk is one batch write of a BatchStoreVectors call. Records are written in
+// (paper ID, vector type) order, so the same records always split into the same chunks at
+// a given batch size, and a failed chunk can be replayed by rewriting its papers' vectors.
+type BatchChunk struct {
+	Index    int      `json:"index"`
+	PaperIDs []string `json:"paper_ids"` // the papers with a vector in the chunk, in write order
+	Items    int      `json:"items"`
+	Failed   int      `json:"failed,omitempty"`
+	Status   string   `json:"status"`
+}
+
+// sortForWrite orders items by paper ID, then vector type, keeping the input order of
+// duplicates, so chunking does not depend on the order embeddings finished in
+func sortForWrite(items []pendingItem) {
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i].record, items[j].record
+		if a.PaperID != b.PaperID {
+			return a.PaperID < b.PaperID
+		}
+		return a.VectorType < b.VectorType
+	})
+}
+
+// newBatchChunk describes a chunk of items of which failed were not stored
+func newBatchChunk(index int, items []pendingItem, failed int) BatchChunk {
+	chunk := BatchChunk{
+		Index:    index,
+		PaperIDs: make([]string, 0, len(items)),
+		Items:    len(items),
+		Failed:   failed,
+		Status:   ChunkWritten,
+	}
+	for i, item := range items {
+		if i == 0 || item.record.PaperID != items[i-1].record.PaperID {
+			chunk.PaperIDs = append(chunk.PaperIDs, item.record.PaperID)
+		}
+	}
+	switch {
+	case failed >= len(items):
+		chunk.Status = ChunkFailed
+	case failed > 0:
+		chunk.Status = ChunkPartial
+	}
+	return chunk
+}
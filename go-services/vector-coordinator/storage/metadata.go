@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"shared/dynamo"
+)
+
+// VectorMetadata is a stored vector without its embedding and source text, for status,
+// drift and cost reporting that would otherwise read kilobytes of floats per item
+type VectorMetadata struct {
+	PaperID           string            `json:"paper_id" dynamodbav:"paper_id"`
+	VectorType        string            `json:"vector_type" dynamodbav:"vector_type"`
+	EmbeddingMetadata EmbeddingMetadata `json:"embedding_metadata" dynamodbav:"embedding_metadata"`
+	ProcessingInfo    ProcessingInfo    `json:"processing_info" dynamodbav:"processing_info"`
+	Chunk             *ChunkInfo        `json:"chunk,omitempty" dynamodbav:"chunk,omitempty"`
+	Status            string            `json:"status,omitempty" dynamodbav:"status,omitempty"`
+}
+
+// errStopScan ends a metadata scan that its page function stopped
+var errStopScan = errors.New("scan stopped")
+
+// metadataProjection reads only the VectorMetadata attributes, named by metadataAttributeNames
+const metadataProjection = "#pid, #vt, #em, #pi, #ch, #st"
+
+// metadataAttributeNames names the projected attributes. Records always carry paper_id and
+// vector_type, whatever the table's key attributes are named.
+func metadataAttributeNames() map[string]*string {
+	return map[string]*string{
+		"#pid": aws.String(DefaultPartitionKey),
+		"#vt":  aws.String(DefaultSortKey),
+		"#em":  aws.String("embedding_metadata"),
+		"#pi":  aws.String("processing_info"),
+		"#ch":  aws.String("chunk"),
+		"#st":  aws.String("status"),
+	}
+}
+
+// GetVectorMetadata returns the metadata of every vector stored for a paper, sorted by
+// vector type, without reading the embeddings
+func (s *VectorStorage) GetVectorMetadata(ctx context.Context, paperID string) ([]VectorMetadata, error) {
+	if paperID == "" {
+		return nil, fmt.Errorf("paper ID cannot be empty")
+	}
+
+	names := metadataAttributeNames()
+	names["#key"] = aws.String(s.partitionKey)
+	input := &dynamodb.QueryInput{
+		TableName:                aws.String(s.tableName),
+		KeyConditionExpression:   aws.String("#key = :pid"),
+		ProjectionExpression:     aws.String(metadataProjection),
+		ExpressionAttributeNames: names,
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":pid": {S: aws.String(paperID)},
+		},
+	}
+
+	vectors := []VectorMetadata{}
+	_, err := dynamo.Paginate(ctx, dynamo.Query(s.client, input), dynamo.Options{}, func(page dynamo.Page) error {
+		var items []VectorMetadata
+		if err := dynamodbattribute.UnmarshalListOfMaps(page.Items, &items); err != nil {
+			return fmt.Errorf("failed to unmarshal vector metadata for %s: %w", paperID, err)
+		}
+		vectors = append(vectors, items...)
+		return nil
+	})
+	if err != nil {
+		var fetchErr *dynamo.FetchError
+		if errors.As(err, &fetchErr) {
+			return nil, fmt.Errorf("failed to query vector metadata for %s: %w", paperID, err)
+		}
+		return nil, err
+	}
+
+	sort.Slice(vectors, func(i, j int) bool {
+		return vectors[i].VectorType < vectors[j].VectorType
+	})
+	return vectors, nil
+}
+
+// ScanVectorMetadata reads the metadata of every stored vector, one page at a time, without
+// reading the embeddings. fn is called with each page and stops the scan by returning false.
+func (s *VectorStorage) ScanVectorMetadata(ctx context.Context, fn func(page []VectorMetadata) bool) error {
+	input := &dynamodb.ScanInput{
+		TableName:                aws.String(s.tableName),
+		ProjectionExpression:     aws.String(metadataProjection),
+		ExpressionAttributeNames: metadataAttributeNames(),
+	}
+
+	_, err := dynamo.Paginate(ctx, dynamo.Scan(s.client, input), dynamo.Options{}, func(page dynamo.Page) error {
+		var items []VectorMetadata
+		if err := dynamodbattribute.UnmarshalListOfMaps(page.Items, &items); err != nil {
+			return fmt.Errorf("failed to unmarshal vector metadata: %w", err)
+		}
+		if !fn(items) {
+			return errStopScan
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errStopScan) {
+		var fetchErr *dynamo.FetchError
+		if errors.As(err, &fetchErr) {
+			return fmt.Errorf("failed to scan vector metadata: %w", err)
+		}
+		return err
+	}
+	return nil
+}
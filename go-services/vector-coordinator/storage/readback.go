@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// maxBatchGetKeys is the DynamoDB limit on keys per BatchGetItem request
+const maxBatchGetKeys = 100
+
+// maxReadBackAttempts bounds the BatchGetItem calls spent on unprocessed keys
+const maxReadBackAttempts = 3
+
+// ReadBackMismatch is a stored vector that did not read back as it was written
+type ReadBackMismatch struct {
+	PaperID    string `json:"paper_id"`
+	VectorType string `json:"vector_type"`
+	Reason     string `json:"reason"`
+}
+
+// ReadBack reads records back from the vectors table with strongly consistent reads and
+// compares each with what was written, returning the records whose stored item is missing or
+// differs. At most 100 records are read; an error means the read itself failed.
+func (s *VectorStorage) ReadBack(ctx context.Context, records []VectorRecord) ([]ReadBackMismatch, error) {
+	if len(records) > maxBatchGetKeys {
+		return nil, fmt.Errorf("cannot read back %d records, the limit is %d", len(records), maxBatchGetKeys)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	keys := make([]map[string]*dynamodb.AttributeValue, 0, len(records))
+	for _, record := range records {
+		keys = append(keys, s.recordKey(record))
+	}
+
+	stored := make(map[string]VectorRecord, len(records))
+	request := map[string]*dynamodb.KeysAndAttributes{
+		s.tableName: {Keys: keys, ConsistentRead: aws.Bool(true)},
+	}
+	for attempt := 1; len(request) > 0; attempt++ {
+		if attempt > maxReadBackAttempts {
+			return nil, fmt.Errorf("%d keys were still unprocessed after %d attempts", len(request[s.tableName].Keys), maxReadBackAttempts)
+		}
+		output, err := s.client.BatchGetItemWithContext(ctx, &dynamodb.BatchGetItemInput{RequestItems: request})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read back vector records: %w", err)
+		}
+		for _, item := range output.Responses[s.tableName] {
+			var record VectorRecord
+			if err := dynamodbattribute.UnmarshalMap(item, &record); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal read-back vector record: %w", err)
+			}
+			stored[record.PaperID+"#"+record.VectorType] = record
+		}
+		request = output.UnprocessedKeys
+	}
+
+	var mismatches []ReadBackMismatch
+	for _, record := range records {
+		got, ok := stored[record.PaperID+"#"+record.VectorType]
+		reason := "not found"
+		if ok {
+			reason = compareStored(record, got)
+		}
+		if reason != "" {
+			mismatches = append(mismatches, ReadBackMismatch{PaperID: record.PaperID, VectorType: record.VectorType, Reason: reason})
+		}
+	}
+	return mismatches, nil
+}
+
+// recordKey builds the table key of a record
+func (s *VectorStorage) recordKey(record VectorRecord) map[string]*dynamodb.AttributeValue {
+	key := map[string]*dynamodb.AttributeValue{
+		s.partitionKey: {S: aws.String(record.PaperID)},
+	}
+	if s.sortKey != "" {
+		key[s.sortKey] = &dynamodb.AttributeValue{S: aws.String(record.VectorType)}
+	}
+	return key
+}
+
+// compareStored describes how a stored record differs from the one written, or returns ""
+// when the embedding and the fields search depends on survived the round trip
+func compareStored(written, stored VectorRecord) string {
+	if len(stored.Embedding) != len(written.Embedding) {
+		return fmt.Sprintf("embedding has %d values, wrote %d", len(stored.Embedding), len(written.Embedding))
+	}
+	if stored.EmbeddingMetadata.Dimension != len(stored.Embedding) {
+		return fmt.Sprintf("dimension is %d for an embedding of %d values", stored.EmbeddingMetadata.Dimension, len(stored.Embedding))
+	}
+	for i, value := range written.Embedding {
+		if stored.Embedding[i] != value {
+			return fmt.Sprintf("embedding value %d is %v, wrote %v", i, stored.Embedding[i], value)
+		}
+	}
+	if stored.EmbeddingMetadata.ModelVersion != written.EmbeddingMetadata.ModelVersion {
+		return fmt.Sprintf("model_version is %q, wrote %q", stored.EmbeddingMetadata.ModelVersion, written.EmbeddingMetadata.ModelVersion)
+	}
+	if stored.ProcessingInfo.TraceID != written.ProcessingInfo.TraceID {
+		return fmt.Sprintf("trace_id is %q, wrote %q", stored.ProcessingInfo.TraceID, written.ProcessingInfo.TraceID)
+	}
+	return ""
+}
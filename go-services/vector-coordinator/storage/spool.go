@@ -0,0 +1,173 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"shared/awsclient"
+)
+
+// DefaultSpoolPrefix is the key prefix spooled records are written under
+const DefaultSpoolPrefix = "spool"
+
+// ChunkSpooled marks write chunks that were spooled to S3 instead of written
+const ChunkSpooled = "spooled"
+
+// Spool holds vector records the vectors table could not take while it was throttled. Each
+// spool writes one gzip JSON lines object per trace, at <prefix>/<trace_id>/<timestamp>.jsonl.gz,
+// until a drain writes the records to DynamoDB and deletes the object.
+type Spool struct {
+	s3Client s3iface.S3API
+	bucket   string
+	prefix   string
+}
+
+// NewSpool creates a spool in bucket under prefix (DefaultSpoolPrefix when empty)
+func NewSpool(bucket, prefix string) *Spool {
+	return NewSpoolWithClient(s3.New(awsclient.MustSession()), bucket, prefix)
+}
+
+// NewSpoolWithClient creates a spool with custom client (for testing)
+func NewSpoolWithClient(client s3iface.S3API, bucket, prefix string) *Spool {
+	if prefix == "" {
+		prefix = DefaultSpoolPrefix
+	}
+	return &Spool{
+		s3Client: client,
+		bucket:   bucket,
+		prefix:   strings.TrimSuffix(prefix, "/"),
+	}
+}
+
+// Write spools records grouped by trace ID and returns the keys written. A failed write
+// returns the keys already written with the error; their records are safely spooled.
+func (s *Spool) Write(ctx context.Context, records []VectorRecord) ([]string, error) {
+	byTrace := make(map[string][]VectorRecord)
+	for _, record := range records {
+		byTrace[record.ProcessingInfo.TraceID] = append(byTrace[record.ProcessingInfo.TraceID], record)
+	}
+	traceIDs := make([]string, 0, len(byTrace))
+	for traceID := range byTrace {
+		traceIDs = append(traceIDs, traceID)
+	}
+	sort.Strings(traceIDs)
+
+	stamp := time.Now().UTC().Format("20060102T150405.000000000Z")
+	var keys []string
+	for _, traceID := range traceIDs {
+		key := fmt.Sprintf("%s/%s.jsonl.gz", s.tracePrefix(traceID), stamp)
+		if err := s.put(ctx, key, byTrace[traceID]); err != nil {
+			return keys, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// List returns the spooled object keys of a trace, oldest first
+func (s *Spool) List(ctx context.Context, traceID string) ([]string, error) {
+	var keys []string
+	err := s.s3Client.ListObjectsV2PagesWithContext(ctx, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(s.tracePrefix(traceID) + "/"),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, object := range page.Contents {
+			keys = append(keys, aws.StringValue(object.Key))
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list spool of trace %s: %w", traceID, err)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Read returns the records of a spooled object
+func (s *Spool) Read(ctx context.Context, key string) ([]VectorRecord, error) {
+	output, err := s.s3Client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get spooled records %s: %w", key, err)
+	}
+	defer output.Body.Close()
+
+	gzipReader, err := gzip.NewReader(output.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress spooled records %s: %w", key, err)
+	}
+	defer gzipReader.Close()
+
+	var records []VectorRecord
+	decoder := json.NewDecoder(gzipReader)
+	for {
+		var record VectorRecord
+		if err := decoder.Decode(&record); err == io.EOF {
+			return records, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to decode spooled record %d of %s: %w", len(records)+1, key, err)
+		}
+		records = append(records, record)
+	}
+}
+
+// Replace rewrites a spooled object with the records still to be written, deleting it when
+// none are left
+func (s *Spool) Replace(ctx context.Context, key string, records []VectorRecord) error {
+	if len(records) > 0 {
+		return s.put(ctx, key, records)
+	}
+	_, err := s.s3Client.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete drained spool %s: %w", key, err)
+	}
+	return nil
+}
+
+// put writes records as gzip JSON lines to key
+func (s *Spool) put(ctx context.Context, key string, records []VectorRecord) error {
+	var buf bytes.Buffer
+	gzipWriter := gzip.NewWriter(&buf)
+	encoder := json.NewEncoder(gzipWriter)
+	for i := range records {
+		if err := encoder.Encode(&records[i]); err != nil {
+			return fmt.Errorf("failed to encode spooled record %s: %w", records[i].PaperID, err)
+		}
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return fmt.Errorf("failed to compress spooled records: %w", err)
+	}
+
+	_, err := s.s3Client.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket:          aws.String(s.bucket),
+		Key:             aws.String(key),
+		Body:            bytes.NewReader(buf.Bytes()),
+		ContentType:     aws.String("application/x-ndjson"),
+		ContentEncoding: aws.String("gzip"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write spooled records %s: %w", key, err)
+	}
+	return nil
+}
+
+// tracePrefix is the key prefix of a trace's spooled objects
+func (s *Spool) tracePrefix(traceID string) string {
+	return path.Join(s.prefix, traceID)
+}
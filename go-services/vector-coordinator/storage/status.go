@@ -0,0 +1,157 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// Vector statuses. BatchStoreVectors writes every record pending; MarkReady flips a paper's
+// records to ready once all of them are stored, and search only indexes ready vectors, so a
+// paper whose chunks are partly written never appears in results. Vectors written before
+// statuses existed have none and count as ready.
+const (
+	VectorStatusPending = "pending"
+	VectorStatusReady   = "ready"
+)
+
+// maxConcurrentStatusUpdates bounds the papers whose status updates are in flight
+const maxConcurrentStatusUpdates = 10
+
+// maxTransactItems is the DynamoDB limit on actions per TransactWriteItems request
+const maxTransactItems = 100
+
+// MarkReady flips stored records to ready, one paper at a time: a paper's records are updated
+// together in a transaction, so search never indexes part of a paper. Only records that exist
+// are updated, so a record whose write failed is not recreated as an empty item. Every paper
+// is attempted; all records of a paper that could not be flipped are reported failed on the
+// result and left pending.
+func (s *VectorStorage) MarkReady(ctx context.Context, records []VectorRecord) (*BatchWriteResult, error) {
+	result := &BatchWriteResult{FailedItems: []VectorRecord{}}
+	if len(records) == 0 {
+		return result, nil
+	}
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		slots = make(chan struct{}, maxConcurrentStatusUpdates)
+	)
+	for _, paperRecords := range groupByPaper(records) {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(paperRecords []VectorRecord) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			err := s.markPaperReady(ctx, paperRecords)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				for _, record := range paperRecords {
+					result.FailedItems = append(result.FailedItems, record)
+					result.Errors = append(result.Errors, err)
+				}
+				return
+			}
+			result.SuccessCount += len(paperRecords)
+		}(paperRecords)
+	}
+	wg.Wait()
+
+	if len(result.FailedItems) > 0 {
+		s.logger.WithContext(ctx).Warn("Some vector records could not be marked ready", map[string]interface{}{
+			"failed_count": len(result.FailedItems),
+			"total_count":  len(records),
+			"first_error":  result.Errors[0].Error(),
+		})
+	}
+	return result, nil
+}
+
+// groupByPaper splits records into the records of each paper, in order of first appearance
+func groupByPaper(records []VectorRecord) [][]VectorRecord {
+	index := make(map[string]int)
+	var groups [][]VectorRecord
+	for _, record := range records {
+		i, ok := index[record.PaperID]
+		if !ok {
+			i = len(groups)
+			index[record.PaperID] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], record)
+	}
+	return groups
+}
+
+// markPaperReady flips one paper's records to ready in a transaction. A paper with more
+// records than one transaction holds is flipped in several; when a later one fails, the
+// records already flipped are set back to pending.
+func (s *VectorStorage) markPaperReady(ctx context.Context, records []VectorRecord) error {
+	for start := 0; start < len(records); start += maxTransactItems {
+		end := start + maxTransactItems
+		if end > len(records) {
+			end = len(records)
+		}
+		err := s.setStatus(ctx, records[start:end], VectorStatusReady)
+		if err == nil {
+			continue
+		}
+		err = fmt.Errorf("failed to mark %s ready: %w", records[0].PaperID, err)
+		if start > 0 {
+			if revertErr := s.revertToPending(ctx, records[:start]); revertErr != nil {
+				s.logger.WithContext(ctx).Error("Failed to set partly flipped paper back to pending", revertErr, map[string]interface{}{
+					"paper_id": records[0].PaperID,
+				})
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+// revertToPending sets records flipped by an earlier transaction back to pending
+func (s *VectorStorage) revertToPending(ctx context.Context, records []VectorRecord) error {
+	for start := 0; start < len(records); start += maxTransactItems {
+		end := start + maxTransactItems
+		if end > len(records) {
+			end = len(records)
+		}
+		if err := s.setStatus(ctx, records[start:end], VectorStatusPending); err != nil {
+			return fmt.Errorf("failed to set %s back to pending: %w", records[0].PaperID, err)
+		}
+	}
+	return nil
+}
+
+// setStatus sets the status of up to maxTransactItems stored records in one transaction;
+// it fails as a whole when any record does not exist
+func (s *VectorStorage) setStatus(ctx context.Context, records []VectorRecord, status string) error {
+	items := make([]*dynamodb.TransactWriteItem, 0, len(records))
+	for _, record := range records {
+		items = append(items, &dynamodb.TransactWriteItem{
+			Update: &dynamodb.Update{
+				TableName:           aws.String(s.tableName),
+				Key:                 s.recordKey(record),
+				UpdateExpression:    aws.String("SET #status = :status"),
+				ConditionExpression: aws.String("attribute_exists(#key)"),
+				ExpressionAttributeNames: map[string]*string{
+					"#status": aws.String("status"),
+					"#key":    aws.String(s.partitionKey),
+				},
+				ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+					":status": {S: aws.String(status)},
+				},
+			},
+		})
+	}
+	_, err := s.client.TransactWriteItemsWithContext(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: items,
+	})
+	return err
+}
@@ -5,10 +5,11 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"shared/awsclient"
 	"shared/logger"
 )
 
@@ -20,6 +21,8 @@ type VectorRecord struct {
 	EmbeddingMetadata EmbeddingMetadata `json:"embedding_metadata" dynamodbav:"embedding_metadata"`
 	SourceText SourceText `json:"source_text" dynamodbav:"source_text"`
 	ProcessingInfo ProcessingInfo `json:"processing_info" dynamodbav:"processing_info"`
+	Chunk *ChunkInfo `json:"chunk,omitempty" dynamodbav:"chunk,omitempty"` // set on full-text chunk vectors
+	Status string `json:"status,omitempty" dynamodbav:"status,omitempty"` // VectorStatusPending until MarkReady
 }
 
 // EmbeddingMetadata contains metadata about the embedding model and process
@@ -29,6 +32,12 @@ type EmbeddingMetadata struct {
 	Dimension      int    `json:"dimension" dynamodbav:"dimension"`
 	TextLength     int    `json:"text_length" dynamodbav:"text_length"`
 	Preprocessing  string `json:"preprocessing" dynamodbav:"preprocessing"`
+	FieldWeights   map[string]float64 `json:"field_weights,omitempty" dynamodbav:"field_weights,omitempty"` // set on weighted vectors
+	Provider       string `json:"provider,omitempty" dynamodbav:"provider,omitempty"` // embedding provider whose response format was normalized
+	// TruncationRatio is the fraction of the source text that was embedded when it had to be
+	// cut to the embedding limit; unset when the whole text was embedded
+	TruncationRatio float64 `json:"truncation_ratio,omitempty" dynamodbav:"truncation_ratio,omitempty"`
+	TruncationBoundary string `json:"truncation_boundary,omitempty" dynamodbav:"truncation_boundary,omitempty"` // "sentence", "word" or "rune"
 }
 
 // SourceText contains information about the source text used for vectorization
@@ -40,16 +49,47 @@ type SourceText struct {
 
 // ProcessingInfo contains information about the processing context
 type ProcessingInfo struct {
-	CreatedAt        string `json:"created_at" dynamodbav:"created_at"`
+	CreatedAt        string `json:"created_at" dynamodbav:"created_at"` // when the vector was generated
 	TraceID          string `json:"trace_id" dynamodbav:"trace_id"`
 	ProcessingTimeMs int64  `json:"processing_time_ms" dynamodbav:"processing_time_ms"`
+	// Provenance of the record: the raw-data object and collection run of its paper, and the
+	// coordinator build that wrote it. Unset on records written before they were tracked.
+	SourceS3Key        string `json:"source_s3_key,omitempty" dynamodbav:"source_s3_key,omitempty"`
+	CollectionRunID    string `json:"collection_run_id,omitempty" dynamodbav:"collection_run_id,omitempty"`
+	CoordinatorVersion string `json:"coordinator_version,omitempty" dynamodbav:"coordinator_version,omitempty"`
+	CoordinatorCommit  string `json:"coordinator_commit,omitempty" dynamodbav:"coordinator_commit,omitempty"`
+	// When the paper was collected and ingested, which with CreatedAt break its end-to-end
+	// latency down by stage. Unset on papers ingested before they were stamped.
+	CollectedAt string `json:"collected_at,omitempty" dynamodbav:"collected_at,omitempty"`
+	IngestedAt  string `json:"ingested_at,omitempty" dynamodbav:"ingested_at,omitempty"`
 }
 
+// MaxBatchSize is the maximum number of items per batch write request
+const MaxBatchSize = 25
+
+// DynamoDB size limits: a batch write request carries at most 16 MB and one item at most 400 KB
+const (
+	MaxBatchBytes = 16 * 1024 * 1024
+	MaxItemBytes  = 400 * 1024
+)
+
+// Default key attributes of the vectors table
+const (
+	DefaultPartitionKey = "paper_id"
+	DefaultSortKey      = "vector_type"
+)
+
 // VectorStorage handles storing vector records in DynamoDB
 type VectorStorage struct {
-	client    dynamodbiface.DynamoDBAPI
-	tableName string
-	logger    *logger.Logger
+	client       dynamodbiface.DynamoDBAPI
+	tableName    string
+	batchSize    int
+	partitionKey string
+	sortKey      string
+	throttle     *capacityThrottle // nil unless adaptive capacity pacing is enabled
+	spool        *Spool            // nil unless failed writes switch the rest of a call to S3
+	spoolAfter   int               // failed writes in one call before the rest is spooled
+	logger       *logger.Logger
 }
 
 // BatchWriteResult contains the results of a batch write operation
@@ -57,24 +97,74 @@ type BatchWriteResult struct {
 	SuccessCount int
 	FailedItems  []VectorRecord
 	Errors       []error
+	// ConsumedCapacity is the write capacity units the batches reported consuming
+	ConsumedCapacity float64
+	// ThrottleWait is the time spent waiting for write capacity under adaptive pacing
+	ThrottleWait time.Duration
+	// Chunks is the write manifest: each batch write in order, with the papers it carried
+	Chunks []BatchChunk
+	// Spooled are the records written to the spool instead of the table; they are not in
+	// FailedItems. The spool also carries the stored records of their papers, so that a
+	// drain marks each paper ready as a whole.
+	Spooled   []VectorRecord
+	SpoolKeys []string
 }
 
-// NewVectorStorage creates a new vector storage instance
-func NewVectorStorage(tableName string) *VectorStorage {
-	sess := session.Must(session.NewSession())
+// NewVectorStorage creates a new vector storage instance whose client uses clientConfig's retries and timeouts
+func NewVectorStorage(tableName string, clientConfig awsclient.ClientConfig) *VectorStorage {
+	sess := awsclient.MustClientSession(clientConfig)
 	return &VectorStorage{
-		client:    dynamodb.New(sess),
-		tableName: tableName,
-		logger:    logger.New("vector-storage"),
+		client:       dynamodb.New(sess),
+		tableName:    tableName,
+		batchSize:    MaxBatchSize,
+		partitionKey: DefaultPartitionKey,
+		sortKey:      DefaultSortKey,
+		logger:       logger.New("vector-storage"),
 	}
 }
 
 // NewVectorStorageWithClient creates a new vector storage with custom client (for testing)
 func NewVectorStorageWithClient(client dynamodbiface.DynamoDBAPI, tableName string) *VectorStorage {
 	return &VectorStorage{
-		client:    client,
-		tableName: tableName,
-		logger:    logger.New("vector-storage"),
+		client:       client,
+		tableName:    tableName,
+		batchSize:    MaxBatchSize,
+		partitionKey: DefaultPartitionKey,
+		sortKey:      DefaultSortKey,
+		logger:       logger.New("vector-storage"),
+	}
+}
+
+// SetBatchSize sets the number of items per batch write; smaller batches smooth WCU consumption
+func (s *VectorStorage) SetBatchSize(size int) error {
+	if size < 1 || size > MaxBatchSize {
+		return fmt.Errorf("batch size must be between 1 and %d, got %d", MaxBatchSize, size)
+	}
+	s.batchSize = size
+	return nil
+}
+
+// SetKeySchema sets the vectors table key attributes. The paper ID is written to partitionKey
+// and the vector type to sortKey; an empty sortKey suits tables keyed on the partition key alone.
+func (s *VectorStorage) SetKeySchema(partitionKey, sortKey string) error {
+	if partitionKey == "" {
+		return fmt.Errorf("partition key is required")
+	}
+	if partitionKey == sortKey {
+		return fmt.Errorf("partition and sort keys must differ, both are %q", partitionKey)
+	}
+	s.partitionKey = partitionKey
+	s.sortKey = sortKey
+	return nil
+}
+
+// applyKeySchema copies the record's identifiers into the configured key attributes
+func (s *VectorStorage) applyKeySchema(item map[string]*dynamodb.AttributeValue) {
+	if s.partitionKey != DefaultPartitionKey {
+		item[s.partitionKey] = item[DefaultPartitionKey]
+	}
+	if s.sortKey != "" && s.sortKey != DefaultSortKey {
+		item[s.sortKey] = item[DefaultSortKey]
 	}
 }
 
@@ -84,7 +174,7 @@ func CreateVectorRecord(paperID, text, traceID string, embedding []float64, mode
 	
 	return &VectorRecord{
 		PaperID:    paperID,
-		VectorType: "title_abstract", // Default vector type for title+abstract combination
+		VectorType: VectorTypeTitleAbstract, // Default vector type for title+abstract combination
 		Embedding:  embedding,
 		EmbeddingMetadata: EmbeddingMetadata{
 			ModelName:     extractModelName(modelVersion),
@@ -115,7 +205,9 @@ func extractModelName(modelVersion string) string {
 
 
 
-// BatchStoreVectors stores multiple vector records in batches
+// BatchStoreVectors stores multiple vector records in batches, in (paper ID, vector type)
+// order. A batch closes at the configured item count or when the next item would exceed
+// MaxBatchBytes, whichever comes first; every batch is listed in the result's Chunks.
 func (s *VectorStorage) BatchStoreVectors(ctx context.Context, records []VectorRecord) (*BatchWriteResult, error) {
 	if len(records) == 0 {
 		return &BatchWriteResult{}, nil
@@ -130,55 +222,167 @@ func (s *VectorStorage) BatchStoreVectors(ctx context.Context, records []VectorR
 		Errors:       []error{},
 	}
 
-	// Process records in batches of 25 (DynamoDB limit)
-	const batchSize = 25
-	for i := 0; i < len(records); i += batchSize {
-		end := i + batchSize
-		if end > len(records) {
-			end = len(records)
+	items := s.prepareItems(ctx, records, result)
+	sortForWrite(items)
+
+	// Batches are taken one at a time so adaptive pacing can shrink the next one
+	batchCount := 0
+	var writeFailed []VectorRecord // records of sent batches that were not stored
+	spoolFailed := false
+	for remaining := items; len(remaining) > 0; batchCount++ {
+		batchSize := s.batchSize
+		if s.throttle != nil {
+			batchSize = s.throttle.size()
+		}
+		var batch []pendingItem
+		batch, remaining = nextBatch(remaining, batchSize, MaxBatchBytes)
+
+		if s.throttle != nil {
+			waited, err := s.throttle.wait(ctx, estimateUnits(batch))
+			result.ThrottleWait += waited
+			if err != nil {
+				result.Errors = append(result.Errors, fmt.Errorf("stopped waiting for write capacity: %w", err))
+				for _, item := range items[len(items)-len(batch)-len(remaining):] {
+					result.FailedItems = append(result.FailedItems, item.record)
+				}
+				// The unsent items are still listed, in the chunks they would have been sent in
+				for unsent := batch; len(unsent) > 0; batchCount++ {
+					chunk := newBatchChunk(batchCount, unsent, len(unsent))
+					chunk.Status = ChunkSkipped
+					result.Chunks = append(result.Chunks, chunk)
+					unsent, remaining = nextBatch(remaining, batchSize, MaxBatchBytes)
+				}
+				break
+			}
 		}
 
-		batch := records[i:end]
 		batchResult, err := s.processBatch(ctx, batch)
 		if err != nil {
 			contextLogger.Error("Batch processing failed", err, map[string]interface{}{
-				"batch_start": i,
+				"batch_index": batchCount,
 				"batch_size":  len(batch),
 			})
 			result.Errors = append(result.Errors, err)
-			result.FailedItems = append(result.FailedItems, batch...)
-			continue
+			for _, item := range batch {
+				writeFailed = append(writeFailed, item.record)
+			}
+			result.Chunks = append(result.Chunks, newBatchChunk(batchCount, batch, len(batch)))
+		} else {
+			result.Chunks = append(result.Chunks, newBatchChunk(batchCount, batch, len(batchResult.FailedItems)))
+
+			result.SuccessCount += batchResult.SuccessCount
+			writeFailed = append(writeFailed, batchResult.FailedItems...)
+			result.Errors = append(result.Errors, batchResult.Errors...)
+			result.ConsumedCapacity += batchResult.ConsumedCapacity
+			if s.throttle != nil {
+				s.throttle.record(batchResult.ConsumedCapacity, len(batchResult.FailedItems))
+			}
 		}
 
-		result.SuccessCount += batchResult.SuccessCount
-		result.FailedItems = append(result.FailedItems, batchResult.FailedItems...)
-		result.Errors = append(result.Errors, batchResult.Errors...)
+		// Past the failure threshold the table is taking writes too slowly to finish the call,
+		// so the failed and remaining records go to the spool for a drain to write later
+		if s.spool != nil && !spoolFailed && len(writeFailed) >= s.spoolAfter && len(remaining) > 0 {
+			sent := items[:len(items)-len(remaining)]
+			if s.spoolRest(ctx, sent, writeFailed, remaining, result) {
+				writeFailed = nil
+				for batchCount++; len(remaining) > 0; batchCount++ {
+					var unsent []pendingItem
+					unsent, remaining = nextBatch(remaining, batchSize, MaxBatchBytes)
+					chunk := newBatchChunk(batchCount, unsent, 0)
+					chunk.Status = ChunkSpooled
+					result.Chunks = append(result.Chunks, chunk)
+				}
+				break
+			}
+			spoolFailed = true
+		}
 	}
+	result.FailedItems = append(result.FailedItems, writeFailed...)
 
 	contextLogger.InfoWithCount("Completed batch vector storage", result.SuccessCount, map[string]interface{}{
 		"total_records":  len(records),
+		"batch_count":    batchCount,
 		"success_count":  result.SuccessCount,
 		"failed_count":   len(result.FailedItems),
 		"error_count":    len(result.Errors),
+		"consumed_capacity": result.ConsumedCapacity,
+		"throttle_wait_ms":  result.ThrottleWait.Milliseconds(),
+		"spooled_count":     len(result.Spooled),
 	})
 
 	return result, nil
 }
 
-// processBatch processes a single batch of vector records
-func (s *VectorStorage) processBatch(ctx context.Context, records []VectorRecord) (*BatchWriteResult, error) {
+// SetSpool spools the rest of a BatchStoreVectors call once failureThreshold of its writes
+// have failed, instead of writing into a throttled table; a nil spool writes everything
+func (s *VectorStorage) SetSpool(spool *Spool, failureThreshold int) error {
+	if spool != nil && failureThreshold < 1 {
+		return fmt.Errorf("spool failure threshold must be at least 1, got %d", failureThreshold)
+	}
+	s.spool = spool
+	s.spoolAfter = failureThreshold
+	return nil
+}
+
+// spoolRest spools the records that failed to write and the unsent ones, with the stored
+// records of the same papers. It reports whether they were spooled; on failure the caller
+// keeps writing to the table.
+func (s *VectorStorage) spoolRest(ctx context.Context, sent []pendingItem, writeFailed []VectorRecord, unsent []pendingItem, result *BatchWriteResult) bool {
+	papers := make(map[string]bool)
+	failedKeys := make(map[string]bool)
+	unstored := make([]VectorRecord, 0, len(writeFailed)+len(unsent))
+	for _, record := range writeFailed {
+		papers[record.PaperID] = true
+		failedKeys[record.PaperID+"#"+record.VectorType] = true
+		unstored = append(unstored, record)
+	}
+	for _, item := range unsent {
+		papers[item.record.PaperID] = true
+		unstored = append(unstored, item.record)
+	}
+	records := append([]VectorRecord(nil), unstored...)
+	for _, item := range sent {
+		if papers[item.record.PaperID] && !failedKeys[item.record.PaperID+"#"+item.record.VectorType] {
+			records = append(records, item.record)
+		}
+	}
+
 	contextLogger := s.logger.WithContext(ctx)
-	
-	result := &BatchWriteResult{
-		SuccessCount: 0,
-		FailedItems:  []VectorRecord{},
-		Errors:       []error{},
+	keys, err := s.spool.Write(ctx, records)
+	if err != nil {
+		contextLogger.Error("Failed to spool vector records, continuing to write to the table", err, map[string]interface{}{
+			"records":      len(records),
+			"written_keys": keys,
+		})
+		result.Errors = append(result.Errors, fmt.Errorf("failed to spool vector records: %w", err))
+		return false
 	}
 
-	// Validate records before processing
-	validRecords := make([]VectorRecord, 0, len(records))
+	contextLogger.Warn("Vector writes failing, spooled the remaining records to S3", map[string]interface{}{
+		"failed_writes":  len(writeFailed),
+		"unsent_records": len(unsent),
+		"spooled":        len(records),
+		"spool_keys":     keys,
+	})
+	result.Spooled = unstored
+	result.SpoolKeys = keys
+	return true
+}
+
+// pendingItem is a validated record marshaled for writing, with its DynamoDB item size
+type pendingItem struct {
+	record VectorRecord
+	item   map[string]*dynamodb.AttributeValue
+	size   int
+}
+
+// prepareItems validates and marshals records, recording rejected ones on result
+func (s *VectorStorage) prepareItems(ctx context.Context, records []VectorRecord, result *BatchWriteResult) []pendingItem {
+	contextLogger := s.logger.WithContext(ctx)
+
+	items := make([]pendingItem, 0, len(records))
 	for i, record := range records {
-		if err := s.validateVectorRecord(&record); err != nil {
+		if err := ValidateVectorRecord(&record); err != nil {
 			contextLogger.Warn("Invalid vector record found", map[string]interface{}{
 				"record_index": i,
 				"paper_id":     record.PaperID,
@@ -188,18 +392,9 @@ func (s *VectorStorage) processBatch(ctx context.Context, records []VectorRecord
 			result.Errors = append(result.Errors, fmt.Errorf("invalid record %s: %w", record.PaperID, err))
 			continue
 		}
-		validRecords = append(validRecords, record)
-	}
 
-	if len(validRecords) == 0 {
-		contextLogger.Warn("No valid records to process in batch")
-		return result, nil
-	}
-
-	// Prepare batch write request
-	writeRequests := make([]*dynamodb.WriteRequest, 0, len(validRecords))
-	
-	for _, record := range validRecords {
+		// Records are written pending and only become searchable once MarkReady flips them
+		record.Status = VectorStatusPending
 		item, err := dynamodbattribute.MarshalMap(record)
 		if err != nil {
 			contextLogger.Error("Failed to marshal record in batch", err, map[string]interface{}{
@@ -209,25 +404,122 @@ func (s *VectorStorage) processBatch(ctx context.Context, records []VectorRecord
 			result.Errors = append(result.Errors, fmt.Errorf("failed to marshal record %s: %w", record.PaperID, err))
 			continue
 		}
+		s.applyKeySchema(item)
+
+		// DynamoDB would reject the whole batch for one oversized item
+		size := ItemSize(item)
+		if size > MaxItemBytes {
+			contextLogger.Warn("Vector record exceeds DynamoDB item size limit", map[string]interface{}{
+				"paper_id":   record.PaperID,
+				"item_bytes": size,
+				"max_bytes":  MaxItemBytes,
+			})
+			result.FailedItems = append(result.FailedItems, record)
+			result.Errors = append(result.Errors, fmt.Errorf("record %s is %d bytes, over the %d byte item limit", record.PaperID, size, MaxItemBytes))
+			continue
+		}
 
-		writeRequest := &dynamodb.WriteRequest{
-			PutRequest: &dynamodb.PutRequest{
-				Item: item,
-			},
+		items = append(items, pendingItem{record: record, item: item, size: size})
+	}
+	return items
+}
+
+// nextBatch takes the leading items that fit in one batch of at most maxItems items and
+// maxBytes bytes (always at least one item) and returns them with the rest
+func nextBatch(items []pendingItem, maxItems, maxBytes int) (batch, rest []pendingItem) {
+	count, batchBytes := 0, 0
+	for _, item := range items {
+		if count > 0 && (count >= maxItems || batchBytes+item.size > maxBytes) {
+			break
 		}
-		writeRequests = append(writeRequests, writeRequest)
+		count++
+		batchBytes += item.size
+	}
+	return items[:count], items[count:]
+}
+
+// ItemSize estimates the size DynamoDB counts against its item and request limits:
+// attribute name lengths plus value sizes. Numbers are counted by their string length,
+// which slightly overestimates and so keeps batches on the safe side of the limit.
+func ItemSize(item map[string]*dynamodb.AttributeValue) int {
+	size := 0
+	for name, value := range item {
+		size += len(name) + attributeSize(value)
+	}
+	return size
+}
+
+// attributeSize returns the size of one attribute value; lists and maps carry 3 bytes of overhead
+// plus 1 byte per element
+func attributeSize(value *dynamodb.AttributeValue) int {
+	if value == nil {
+		return 0
+	}
+	switch {
+	case value.S != nil:
+		return len(*value.S)
+	case value.N != nil:
+		return len(*value.N)
+	case value.B != nil:
+		return len(value.B)
+	case value.BOOL != nil, value.NULL != nil:
+		return 1
+	case value.L != nil:
+		size := 3
+		for _, element := range value.L {
+			size += 1 + attributeSize(element)
+		}
+		return size
+	case value.M != nil:
+		return 3 + len(value.M) + ItemSize(value.M)
+	}
+
+	size := 0
+	for _, s := range value.SS {
+		size += len(*s)
+	}
+	for _, n := range value.NS {
+		size += len(*n)
+	}
+	for _, b := range value.BS {
+		size += len(b)
+	}
+	return size
+}
+
+// processBatch writes a single batch of prepared items
+func (s *VectorStorage) processBatch(ctx context.Context, items []pendingItem) (*BatchWriteResult, error) {
+	contextLogger := s.logger.WithContext(ctx)
+	
+	result := &BatchWriteResult{
+		SuccessCount: 0,
+		FailedItems:  []VectorRecord{},
+		Errors:       []error{},
 	}
 
-	if len(writeRequests) == 0 {
+	if len(items) == 0 {
 		contextLogger.Warn("No valid write requests to process")
 		return result, nil
 	}
 
+	// Prepare batch write request
+	writeRequests := make([]*dynamodb.WriteRequest, 0, len(items))
+	batchBytes := 0
+	for _, item := range items {
+		writeRequests = append(writeRequests, &dynamodb.WriteRequest{
+			PutRequest: &dynamodb.PutRequest{
+				Item: item.item,
+			},
+		})
+		batchBytes += item.size
+	}
+
 	// Execute batch write (single attempt, let Step Function handle retries)
 	input := &dynamodb.BatchWriteItemInput{
 		RequestItems: map[string][]*dynamodb.WriteRequest{
 			s.tableName: writeRequests,
 		},
+		ReturnConsumedCapacity: aws.String(dynamodb.ReturnConsumedCapacityTotal),
 	}
 
 	startTime := time.Now()
@@ -236,6 +528,7 @@ func (s *VectorStorage) processBatch(ctx context.Context, records []VectorRecord
 
 	contextLogger.Debug("Batch write completed", map[string]interface{}{
 		"items_requested":   len(writeRequests),
+		"batch_bytes":       batchBytes,
 		"duration_ms":       duration.Milliseconds(),
 		"consumed_capacity": output.ConsumedCapacity,
 	})
@@ -243,13 +536,18 @@ func (s *VectorStorage) processBatch(ctx context.Context, records []VectorRecord
 	if err != nil {
 		contextLogger.Error("Batch write failed", err, map[string]interface{}{
 			"items_requested": len(writeRequests),
+			"batch_bytes":     batchBytes,
 		})
 		// Add all items to failed items
-		result.FailedItems = append(result.FailedItems, validRecords...)
+		for _, item := range items {
+			result.FailedItems = append(result.FailedItems, item.record)
+		}
 		result.Errors = append(result.Errors, fmt.Errorf("batch write failed: %w", err))
 		return result, nil
 	}
 
+	result.ConsumedCapacity = consumedUnits(output.ConsumedCapacity, s.tableName, estimateUnits(items))
+
 	// Calculate success count
 	totalRequested := len(writeRequests)
 	unprocessedCount := 0
@@ -267,16 +565,24 @@ func (s *VectorStorage) processBatch(ctx context.Context, records []VectorRecord
 			"total_count":       totalRequested,
 		})
 		
-		// Add unprocessed items to failed items (approximate mapping)
-		startIndex := result.SuccessCount
-		for i := 0; i < unprocessedCount && startIndex+i < len(validRecords); i++ {
-			result.FailedItems = append(result.FailedItems, validRecords[startIndex+i])
+		// DynamoDB returns the unprocessed requests in no particular order, so they are matched
+		// back to their records by key
+		unprocessed := make(map[string]bool, unprocessedCount)
+		for _, request := range output.UnprocessedItems[s.tableName] {
+			if request.PutRequest != nil {
+				unprocessed[itemKey(request.PutRequest.Item)] = true
+			}
+		}
+		for _, item := range items {
+			if unprocessed[itemKey(item.item)] {
+				result.FailedItems = append(result.FailedItems, item.record)
+			}
 		}
 	}
 
 	contextLogger.InfoWithDuration("Batch write completed", duration, map[string]interface{}{
-		"total_records":     len(records),
-		"valid_records":     len(validRecords),
+		"total_records":     len(items),
+		"batch_bytes":       batchBytes,
 		"success_count":     result.SuccessCount,
 		"unprocessed_count": unprocessedCount,
 		"failed_count":      len(result.FailedItems),
@@ -285,8 +591,20 @@ func (s *VectorStorage) processBatch(ctx context.Context, records []VectorRecord
 	return result, nil
 }
 
-// validateVectorRecord validates the structure and content of a vector record
-func (s *VectorStorage) validateVectorRecord(record *VectorRecord) error {
+// itemKey identifies a vector item by its paper ID and vector type
+func itemKey(item map[string]*dynamodb.AttributeValue) string {
+	var paperID, vectorType string
+	if value := item[DefaultPartitionKey]; value != nil {
+		paperID = aws.StringValue(value.S)
+	}
+	if value := item[DefaultSortKey]; value != nil {
+		vectorType = aws.StringValue(value.S)
+	}
+	return paperID + "\x00" + vectorType
+}
+
+// ValidateVectorRecord validates the structure and content of a vector record
+func ValidateVectorRecord(record *VectorRecord) error {
 	if record.PaperID == "" {
 		return fmt.Errorf("paper_id is empty")
 	}
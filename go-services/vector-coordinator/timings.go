@@ -0,0 +1,42 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Keys of ProcessingResult.StageTimings, all in milliseconds
+const (
+	TimingRetrievalMs    = "retrieval_ms"     // reading and combining the trace's papers
+	TimingBackfillWaitMs = "backfill_wait_ms" // waiting for the trace-id index, only set when the backfill check ran
+	TimingWarmUpMs       = "warm_up_ms"       // waiting for the embedding API, only set when warm-up ran
+	TimingEmbeddingMs    = "embedding_ms"     // the whole embedding loop, weighted vectors included
+	TimingFullTextMs     = "full_text_ms"     // reading, chunking and embedding full text, only set on full-text runs
+	TimingStorageMs      = "storage_ms"       // batch writes to the vectors table
+	TimingReadyMs        = "ready_ms"         // flipping the stored vectors to ready
+	TimingPaperP50Ms     = "paper_p50_ms"     // median embedding time of a single paper
+	TimingPaperP95Ms     = "paper_p95_ms"
+)
+
+// recordPaperPercentiles adds the per-paper p50/p95 embedding times to timings
+func recordPaperPercentiles(timings map[string]int64, durations []time.Duration) {
+	if len(durations) == 0 {
+		return
+	}
+	sorted := make([]time.Duration, len(durations))
+	copy(sorted, durations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	timings[TimingPaperP50Ms] = percentile(sorted, 50).Milliseconds()
+	timings[TimingPaperP95Ms] = percentile(sorted, 95).Milliseconds()
+}
+
+// percentile returns the nearest-rank percentile of sorted durations
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
@@ -0,0 +1,29 @@
+package main
+
+import "runtime/debug"
+
+// Build identity of the coordinator, set at link time by the Makefile
+// (-X main.version=... -X main.commit=...)
+var (
+	version = "dev"
+	commit  = ""
+)
+
+// Binaries built without the Makefile fall back to the VCS revision Go stamps into the build info
+func init() {
+	if commit == "" {
+		commit = vcsRevision()
+	}
+}
+
+// vcsRevision returns the build info's VCS revision, or "unknown" when it was not recorded
+func vcsRevision() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" {
+				return setting.Value
+			}
+		}
+	}
+	return "unknown"
+}
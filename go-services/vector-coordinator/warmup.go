@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"vector-coordinator/client"
+)
+
+// warmUpPollInterval is the pause between health checks while the embedding API loads its model
+const warmUpPollInterval = 2 * time.Second
+
+// warmUpText is embedded once to load the model and its weights before the real papers
+const warmUpText = "warm-up request"
+
+// HealthChecker is implemented by embedding API clients that expose a health endpoint
+type HealthChecker interface {
+	CheckHealth(ctx context.Context) (*client.HealthStatus, error)
+}
+
+// warmUpEmbeddingAPI waits for the embedding API to report its model loaded and optionally
+// sends one throwaway embedding. It never fails the run: a still-cold API only costs the
+// first papers the retries they would have needed anyway.
+func (vc *VectorCoordinator) warmUpEmbeddingAPI(ctx context.Context, result *ProcessingResult) {
+	if !vc.warmUp.Enabled || result.TotalPapers < vc.warmUp.MinPapers {
+		return
+	}
+	checker, ok := vc.apiClient.(HealthChecker)
+	if !ok {
+		return
+	}
+	contextLogger := vc.logger.WithContext(ctx)
+	start := time.Now()
+	defer func() {
+		result.StageTimings[TimingWarmUpMs] = time.Since(start).Milliseconds()
+	}()
+
+	deadline := start.Add(time.Duration(vc.warmUp.TimeoutSeconds) * time.Second)
+	attempts := 0
+	healthy := false
+	for !healthy && !vc.shutdownRequested() {
+		attempts++
+		status, err := checker.CheckHealth(ctx)
+		if err == nil && status.Healthy() {
+			healthy = true
+			break
+		}
+		if time.Now().Add(warmUpPollInterval).After(deadline) {
+			fields := map[string]interface{}{"attempts": attempts}
+			if err != nil {
+				fields["error"] = err.Error()
+			} else {
+				fields["status"] = status.Status
+			}
+			contextLogger.Warn("Embedding API not healthy after warm-up timeout, continuing", fields)
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(warmUpPollInterval):
+		}
+	}
+
+	if healthy && vc.warmUp.Embedding {
+		if _, err := vc.apiClient.GenerateEmbedding(ctx, warmUpText); err != nil {
+			contextLogger.Warn("Warm-up embedding failed", map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+	}
+
+	contextLogger.InfoWithDuration("Embedding API warm-up finished", time.Since(start), map[string]interface{}{
+		"healthy":       healthy,
+		"health_checks": attempts,
+		"total_papers":  result.TotalPapers,
+	})
+}
@@ -4,11 +4,14 @@ API endpoints and request handling for embedding service
 import json
 import logging
 import time
-from typing import Dict, Any, Optional
+from typing import Dict, Any, List, Optional
 from models.embedding import ModelManager, ModelLoadError, ValidationError, EmbeddingError
 
 logger = logging.getLogger(__name__)
 
+# Most texts accepted in one batch request
+MAX_BATCH_TEXTS = 128
+
 
 class EmbeddingAPI:
     """Main API class for handling embedding requests"""
@@ -32,6 +35,8 @@ class EmbeddingAPI:
             
             # Parse and validate request
             request_data = self._parse_request(event)
+            if isinstance(request_data, dict) and 'texts' in request_data:
+                return self._process_batch(request_data, request_id, start_time)
             text = self._validate_request(request_data)
             
             # Generate embedding
@@ -78,6 +83,46 @@ class EmbeddingAPI:
             })
             return self._create_error_response(500, "INTERNAL_ERROR", "Internal server error")
     
+    def _process_batch(self, request_data: Dict[str, Any], request_id: str, start_time: float) -> Dict[str, Any]:
+        """Embed every text of a batch request ({"texts": [...]}) in one pass"""
+        texts = self._validate_batch_request(request_data)
+        embeddings = self.model_manager.generate_embeddings(texts)
+        
+        response_data = {
+            "embeddings": embeddings,
+            "model_version": self.model_manager.model_name,
+            "dimension": len(embeddings[0]),
+            "processing_time_ms": int((time.time() - start_time) * 1000)
+        }
+        
+        self.request_count += 1
+        self.total_processing_time += time.time() - start_time
+        
+        logger.info(f"Successfully generated batch embeddings", extra={
+            "request_id": request_id,
+            "batch_size": len(texts),
+            "embedding_dimension": response_data["dimension"],
+            "processing_time_ms": response_data["processing_time_ms"]
+        })
+        
+        return self._create_response(200, response_data)
+    
+    def _validate_batch_request(self, request_data: Dict[str, Any]) -> List[str]:
+        """Validate a batch request and extract its texts"""
+        texts = request_data.get('texts')
+        
+        if not isinstance(texts, list) or not texts:
+            raise ValidationError("Texts field must be a non-empty list")
+        
+        if len(texts) > MAX_BATCH_TEXTS:
+            raise ValidationError(f"Batch has {len(texts)} texts, more than the maximum of {MAX_BATCH_TEXTS}")
+        
+        for i, text in enumerate(texts):
+            if not isinstance(text, str) or not text.strip():
+                raise ValidationError(f"Text {i} of the batch must be a non-empty string")
+        
+        return [text.strip() for text in texts]
+    
     def _parse_request(self, event: Dict[str, Any]) -> Dict[str, Any]:
         """Parse request from Lambda event"""
         try:
@@ -167,6 +167,67 @@ class ModelManager:
             logger.error(f"Unexpected error during embedding generation: {str(e)}")
             raise EmbeddingError(f"Unexpected error: {str(e)}")
     
+    def generate_embeddings(self, texts: List[str]) -> List[List[float]]:
+        """Generate embedding vectors for several texts in one forward pass"""
+        if not texts:
+            raise ValidationError("Input texts cannot be empty")
+        if any(not text or not text.strip() for text in texts):
+            raise ValidationError("Input texts cannot contain empty text")
+        
+        max_length = 8192
+        texts = [text[:max_length] for text in texts]
+        
+        try:
+            model, tokenizer = self.load_model()
+            
+            start_time = time.time()
+            
+            try:
+                inputs = tokenizer(
+                    texts,
+                    return_tensors="pt",
+                    truncation=True,
+                    padding=True,
+                    max_length=512
+                )
+            except Exception as e:
+                raise ValidationError(f"Text tokenization failed: {str(e)}")
+            
+            try:
+                with torch.no_grad():
+                    outputs = model(**inputs)
+                    # Mean pooling over real tokens only, so padding to the longest text in
+                    # the batch leaves each embedding as it would be on its own
+                    mask = inputs["attention_mask"].unsqueeze(-1).to(outputs.last_hidden_state.dtype)
+                    summed = (outputs.last_hidden_state * mask).sum(dim=1)
+                    embeddings = summed / mask.sum(dim=1).clamp(min=1e-9)
+                    embeddings = torch.nn.functional.normalize(embeddings, p=2, dim=1)
+                
+                embedding_lists = embeddings.numpy().tolist()
+                if len(embedding_lists) != len(texts):
+                    raise EmbeddingError("Generated embeddings do not match the input texts")
+                
+                processing_time = time.time() - start_time
+                logger.info(f"Generated {len(embedding_lists)} embeddings in {processing_time:.3f}s")
+                
+                return embedding_lists
+                
+            except torch.cuda.OutOfMemoryError:
+                logger.error("CUDA out of memory during batch inference")
+                self._cleanup_model()
+                raise EmbeddingError("GPU memory exhausted during embedding generation")
+            except EmbeddingError:
+                raise
+            except Exception as e:
+                logger.error(f"Batch model inference failed: {str(e)}")
+                raise EmbeddingError(f"Embedding generation failed: {str(e)}")
+                
+        except (ModelLoadError, ValidationError, EmbeddingError):
+            raise
+        except Exception as e:
+            logger.error(f"Unexpected error during batch embedding generation: {str(e)}")
+            raise EmbeddingError(f"Unexpected error: {str(e)}")
+    
     def get_model_info(self) -> dict:
         """Get information about the current model"""
         return {
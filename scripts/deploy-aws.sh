@@ -18,7 +18,7 @@ ENVIRONMENT=${ENVIRONMENT:-dev}
 PROJECT_NAME="pipeline-api-dynamodb"
 
 # Service configurations
-GO_SERVICES="data-collector batch-processor vector-coordinator"
+GO_SERVICES="data-collector batch-processor vector-coordinator search-service pdf-extractor"
 PYTHON_SERVICES="embedding-api"
 
 # Memory configurations (in MB)
@@ -27,6 +27,8 @@ get_memory_size() {
         "data-collector") echo "128" ;;
         "batch-processor") echo "256" ;;
         "vector-coordinator") echo "256" ;;
+        "search-service") echo "1024" ;;  # holds the memory-mapped index
+        "pdf-extractor") echo "512" ;;  # holds one PDF and its text in memory
         "embedding-api") echo "512" ;;
         *) echo "256" ;;  # default
     esac
@@ -361,30 +363,37 @@ setup_s3_event_trigger() {
         --statement-id "s3-trigger-$(date +%s)" \
         &> /dev/null || true
     
-    # Create notification configuration
-    local notification_config='{
-        "LambdaConfigurations": [
+    # Create notification configuration, one rule per raw-data codec extension
+    # (gzip .gz, zstd .zst, uncompressed .json)
+    local function_arn="arn:aws:lambda:$AWS_REGION:$(aws sts get-caller-identity --query Account --output text):function:$function_name"
+    local configurations=""
+    for suffix in gz zst json; do
+        [ -n "$configurations" ] && configurations="$configurations,"
+        configurations="$configurations
             {
-                "Id": "batch-processor-trigger",
-                "LambdaFunctionArn": "arn:aws:lambda:'$AWS_REGION':'$(aws sts get-caller-identity --query Account --output text)':function:'$function_name'",
-                "Events": ["s3:ObjectCreated:*"],
-                "Filter": {
-                    "Key": {
-                        "FilterRules": [
+                \"Id\": \"batch-processor-trigger-$suffix\",
+                \"LambdaFunctionArn\": \"$function_arn\",
+                \"Events\": [\"s3:ObjectCreated:*\"],
+                \"Filter\": {
+                    \"Key\": {
+                        \"FilterRules\": [
                             {
-                                "Name": "prefix",
-                                "Value": "raw-data/"
+                                \"Name\": \"prefix\",
+                                \"Value\": \"raw-data/\"
                             },
                             {
-                                "Name": "suffix",
-                                "Value": ".gz"
+                                \"Name\": \"suffix\",
+                                \"Value\": \".$suffix\"
                             }
                         ]
                     }
                 }
-            }
+            }"
+    done
+    local notification_config="{
+        \"LambdaConfigurations\": [$configurations
         ]
-    }'
+    }"
     
     # Apply notification configuration
     echo "$notification_config" > /tmp/s3-notification.json
@@ -397,6 +406,51 @@ setup_s3_event_trigger() {
     log_info "S3 event trigger configured successfully"
 }
 
+# Attach the raw-data SQS queue to batch-processor. ReportBatchItemFailures lets the
+# handler's batchItemFailures redrive only the failed messages instead of the whole batch.
+setup_sqs_event_source() {
+    if [ -z "$BATCH_PROCESSOR_QUEUE_ARN" ]; then
+        log_info "BATCH_PROCESSOR_QUEUE_ARN not set, skipping SQS event source mapping"
+        return
+    fi
+
+    local function_name="${PROJECT_NAME}-batch-processor-${ENVIRONMENT}"
+
+    if ! aws lambda get-function --function-name "$function_name" --region "$AWS_REGION" &> /dev/null; then
+        log_warn "Function $function_name not found, skipping SQS event source mapping"
+        return
+    fi
+
+    log_info "Configuring SQS event source mapping for $function_name..."
+
+    local mapping_uuid
+    mapping_uuid=$(aws lambda list-event-source-mappings \
+        --function-name "$function_name" \
+        --event-source-arn "$BATCH_PROCESSOR_QUEUE_ARN" \
+        --region "$AWS_REGION" \
+        --query 'EventSourceMappings[0].UUID' \
+        --output text)
+
+    if [ -n "$mapping_uuid" ] && [ "$mapping_uuid" != "None" ]; then
+        aws lambda update-event-source-mapping \
+            --uuid "$mapping_uuid" \
+            --batch-size "${SQS_BATCH_SIZE:-10}" \
+            --maximum-batching-window-in-seconds "${SQS_BATCHING_WINDOW_SECONDS:-0}" \
+            --function-response-types ReportBatchItemFailures \
+            --region "$AWS_REGION" > /dev/null
+        log_info "SQS event source mapping $mapping_uuid updated"
+    else
+        aws lambda create-event-source-mapping \
+            --function-name "$function_name" \
+            --event-source-arn "$BATCH_PROCESSOR_QUEUE_ARN" \
+            --batch-size "${SQS_BATCH_SIZE:-10}" \
+            --maximum-batching-window-in-seconds "${SQS_BATCHING_WINDOW_SECONDS:-0}" \
+            --function-response-types ReportBatchItemFailures \
+            --region "$AWS_REGION" > /dev/null
+        log_info "SQS event source mapping created"
+    fi
+}
+
 # Health check for deployed functions
 health_check() {
     log_info "Performing health checks..."
@@ -508,6 +562,7 @@ main() {
     deploy_go_services
     deploy_python_services
     setup_s3_event_trigger
+    setup_sqs_event_source
     health_check
     show_deployment_summary
     
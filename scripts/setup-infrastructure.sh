@@ -118,6 +118,36 @@ create_vectors_table() {
     log_info "Vectors table created successfully"
 }
 
+# Create DynamoDB Authors table
+create_authors_table() {
+    local table_name="${PROJECT_NAME}-authors-${ENVIRONMENT}"
+    
+    log_setup "Creating DynamoDB Authors table: $table_name"
+    
+    if aws dynamodb describe-table --table-name "$table_name" &> /dev/null; then
+        log_info "Table $table_name already exists"
+        return
+    fi
+    
+    aws dynamodb create-table \
+        --table-name "$table_name" \
+        --attribute-definitions \
+            AttributeName=author_id,AttributeType=S \
+            AttributeName=name_key,AttributeType=S \
+        --key-schema \
+            AttributeName=author_id,KeyType=HASH \
+        --global-secondary-indexes \
+            'IndexName=name-key-index,KeySchema=[{AttributeName=name_key,KeyType=HASH}],Projection={ProjectionType=ALL},ProvisionedThroughput={ReadCapacityUnits=5,WriteCapacityUnits=5}' \
+        --provisioned-throughput \
+            ReadCapacityUnits=5,WriteCapacityUnits=5 \
+        --region "$AWS_REGION"
+    
+    log_info "Waiting for table to be active..."
+    aws dynamodb wait table-exists --table-name "$table_name" --region "$AWS_REGION"
+    
+    log_info "Authors table created successfully"
+}
+
 # Create S3 buckets
 create_s3_buckets() {
     log_setup "Creating S3 buckets..."
@@ -226,6 +256,51 @@ create_log_groups() {
     done
 }
 
+# Attach the raw-data SQS queue to batch-processor. ReportBatchItemFailures lets the
+# handler's batchItemFailures redrive only the failed messages instead of the whole batch.
+setup_sqs_event_source() {
+    if [ -z "$BATCH_PROCESSOR_QUEUE_ARN" ]; then
+        log_info "BATCH_PROCESSOR_QUEUE_ARN not set, skipping SQS event source mapping"
+        return
+    fi
+
+    local function_name="${PROJECT_NAME}-batch-processor-${ENVIRONMENT}"
+
+    if ! aws lambda get-function --function-name "$function_name" --region "$AWS_REGION" &> /dev/null; then
+        log_warn "Function $function_name not found, skipping SQS event source mapping"
+        return
+    fi
+
+    log_info "Configuring SQS event source mapping for $function_name..."
+
+    local mapping_uuid
+    mapping_uuid=$(aws lambda list-event-source-mappings \
+        --function-name "$function_name" \
+        --event-source-arn "$BATCH_PROCESSOR_QUEUE_ARN" \
+        --region "$AWS_REGION" \
+        --query 'EventSourceMappings[0].UUID' \
+        --output text)
+
+    if [ -n "$mapping_uuid" ] && [ "$mapping_uuid" != "None" ]; then
+        aws lambda update-event-source-mapping \
+            --uuid "$mapping_uuid" \
+            --batch-size "${SQS_BATCH_SIZE:-10}" \
+            --maximum-batching-window-in-seconds "${SQS_BATCHING_WINDOW_SECONDS:-0}" \
+            --function-response-types ReportBatchItemFailures \
+            --region "$AWS_REGION" > /dev/null
+        log_info "SQS event source mapping $mapping_uuid updated"
+    else
+        aws lambda create-event-source-mapping \
+            --function-name "$function_name" \
+            --event-source-arn "$BATCH_PROCESSOR_QUEUE_ARN" \
+            --batch-size "${SQS_BATCH_SIZE:-10}" \
+            --maximum-batching-window-in-seconds "${SQS_BATCHING_WINDOW_SECONDS:-0}" \
+            --function-response-types ReportBatchItemFailures \
+            --region "$AWS_REGION" > /dev/null
+        log_info "SQS event source mapping created"
+    fi
+}
+
 # Create Step Function state machine
 create_step_function() {
     log_setup "Creating Step Function state machine..."
@@ -421,10 +496,12 @@ main() {
     check_prerequisites
     create_papers_table
     create_vectors_table
+    create_authors_table
     create_s3_buckets
     upload_config_files
     create_log_groups
     create_step_function
+    setup_sqs_event_source
     show_infrastructure_summary
     
     echo "================================="